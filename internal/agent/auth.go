@@ -0,0 +1,195 @@
+package agent
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/galilio/otter/internal/session"
+	"github.com/gorilla/mux"
+	"google.golang.org/adk/cmd/launcher"
+	"google.golang.org/adk/cmd/launcher/web"
+)
+
+// maxRequestBodySize 认证时需要检查的请求体（运行 Agent、创建会话）的大小上限
+const maxRequestBodySize = 1 << 20
+
+var (
+	// sessionUserPath 匹配 ADK REST API 中带用户的路径：/api/apps/{app_name}/users/{user_id}/...
+	sessionUserPath = regexp.MustCompile(`^/api/apps/[^/]+/users/([^/]+)(/|$)`)
+	// createSessionPath 匹配 ADK REST API 创建会话的路径，session_id 可选
+	createSessionPath = regexp.MustCompile(`^/api/apps/[^/]+/users/[^/]+/sessions(/[^/]+)?$`)
+)
+
+// authLauncher 为 Agent 服务的 API 启用 JWT 认证的 web 子启动器
+type authLauncher struct {
+	jwtConfig *config.JWTConfig
+}
+
+// newAuthLauncher 创建认证子启动器，使用与 REST API 相同的 JWT 配置
+func newAuthLauncher(jwtConfig *config.JWTConfig) web.Sublauncher {
+	return &authLauncher{jwtConfig: jwtConfig}
+}
+
+func (l *authLauncher) Keyword() string {
+	return "auth"
+}
+
+func (l *authLauncher) Parse(args []string) ([]string, error) {
+	return args, nil
+}
+
+func (l *authLauncher) CommandLineSyntax() string {
+	return ""
+}
+
+func (l *authLauncher) SimpleDescription() string {
+	return "requires a JWT access token for the ADK REST API and binds sessions to the token's user"
+}
+
+func (l *authLauncher) SetupSubrouters(router *mux.Router, config *launcher.Config) error {
+	router.Use(authenticate(l.jwtConfig))
+	return nil
+}
+
+func (l *authLauncher) UserMessage(webURL string, printer func(v ...any)) {
+	printer("      auth:  API requests require 'Authorization: Bearer <access token>'")
+}
+
+// authenticate Agent 服务的认证中间件
+// /api/ 下的请求必须携带有效的 JWT（与 middleware.AuthRequired 相同），且只能访问 token 中用户的会话：
+// 路径中的 user_id 和运行请求中的 userId 必须是该用户；调试和评估接口只对管理员开放；
+// 创建会话的 state 和运行请求的 stateDelta 不能包含 app: 和 temp: 前缀的键（与 REST API 的会话接口相同）。
+// 这样 ADK 会话的用户就是已认证的用户，日历工具通过 tool.Context 的 UserID 得到它
+func authenticate(jwtConfig *config.JWTConfig) mux.MiddlewareFunc {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			// 开发环境的 Web UI 和 CORS 预检请求不需要认证
+			if !strings.HasPrefix(r.URL.Path, "/api/") || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			claims, err := middleware.ParseAuthorization(r.Header.Get("Authorization"), jwtConfig)
+			if err != nil {
+				writeJSONError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+			userKey := session.UserKey(claims.UserID)
+
+			if isAdminPath(r.URL.Path) && !claims.IsAdmin {
+				writeJSONError(w, http.StatusForbidden, "需要管理员权限")
+				return
+			}
+			if m := sessionUserPath.FindStringSubmatch(r.URL.Path); m != nil && m[1] != userKey {
+				writeJSONError(w, http.StatusForbidden, "无权访问其他用户的会话")
+				return
+			}
+			if r.Method == http.MethodPost && createSessionPath.MatchString(r.URL.Path) {
+				if status, msg := checkCreateSessionState(r); status != 0 {
+					writeJSONError(w, status, msg)
+					return
+				}
+			}
+			if r.Method == http.MethodPost && (r.URL.Path == "/api/run" || r.URL.Path == "/api/run_sse") {
+				if status, msg := checkRunRequestUser(r, userKey); status != 0 {
+					writeJSONError(w, status, msg)
+					return
+				}
+				// 对话可能持续较长时间，不受 write-timeout 限制
+				_ = http.NewResponseController(w).SetWriteDeadline(time.Time{})
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// isAdminPath 调试和评估接口不区分用户，只对管理员开放
+func isAdminPath(path string) bool {
+	if strings.HasPrefix(path, "/api/debug/") {
+		return true
+	}
+	return strings.HasPrefix(path, "/api/apps/") &&
+		(strings.Contains(path, "/eval_sets") || strings.Contains(path, "/eval_results"))
+}
+
+// checkRunRequestUser 检查运行请求中的 userId 是否为当前用户、stateDelta 是否只包含允许客户端设置的状态，
+// 读取后恢复请求体。返回非 0 的状态码表示拒绝请求
+func checkRunRequestUser(r *http.Request, userKey string) (int, string) {
+	body, err := readRequestBody(r)
+	if err != nil {
+		return http.StatusRequestEntityTooLarge, "请求体过大"
+	}
+
+	var req struct {
+		UserID     string         `json:"userId"`
+		StateDelta map[string]any `json:"stateDelta"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return http.StatusBadRequest, "请求体不是有效的 JSON"
+	}
+	if req.UserID != userKey {
+		return http.StatusForbidden, "无权访问其他用户的会话"
+	}
+	if err := session.ValidateClientState(req.StateDelta); err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	return 0, ""
+}
+
+// checkCreateSessionState 检查创建会话请求的初始状态和事件的状态变更，读取后恢复请求体（可以为空）
+// 返回非 0 的状态码表示拒绝请求
+func checkCreateSessionState(r *http.Request) (int, string) {
+	body, err := readRequestBody(r)
+	if err != nil {
+		return http.StatusRequestEntityTooLarge, "请求体过大"
+	}
+	if len(bytes.TrimSpace(body)) == 0 {
+		return 0, ""
+	}
+
+	var req struct {
+		State  map[string]any `json:"state"`
+		Events []struct {
+			Actions struct {
+				StateDelta map[string]any `json:"stateDelta"`
+			} `json:"actions"`
+		} `json:"events"`
+	}
+	if err := json.Unmarshal(body, &req); err != nil {
+		return http.StatusBadRequest, "请求体不是有效的 JSON"
+	}
+	if err := session.ValidateClientState(req.State); err != nil {
+		return http.StatusBadRequest, err.Error()
+	}
+	for _, event := range req.Events {
+		if err := session.ValidateClientState(event.Actions.StateDelta); err != nil {
+			return http.StatusBadRequest, err.Error()
+		}
+	}
+	return 0, ""
+}
+
+// readRequestBody 读取请求体并恢复，之后的处理器仍然可以读取
+func readRequestBody(r *http.Request) ([]byte, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(nil, r.Body, maxRequestBodySize))
+	if err != nil {
+		return nil, err
+	}
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// writeJSONError 返回与 REST API 相同格式的错误
+func writeJSONError(w http.ResponseWriter, status int, msg string) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(map[string]string{"error": msg})
+}
@@ -0,0 +1,73 @@
+package agent
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/auth"
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAuthenticate 测试 Agent API 需要 JWT，且只能访问 token 中用户的会话
+func TestAuthenticate(t *testing.T) {
+	jwtConfig := &config.JWTConfig{Secret: "test-secret", Expiration: 15 * time.Minute}
+	token, err := auth.GenerateAccessToken(7, "alice", false, jwtConfig.Secret, jwtConfig.Expiration)
+	require.NoError(t, err)
+
+	var body string
+	handler := authenticate(jwtConfig)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/run_sse" && r.Method == http.MethodPost {
+			data, _ := io.ReadAll(r.Body)
+			body = string(data)
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		token  string
+		body   string
+		status int
+	}{
+		{"缺少 token", http.MethodGet, "/api/list-apps", "", "", http.StatusUnauthorized},
+		{"无效的 token", http.MethodGet, "/api/list-apps", "invalid", "", http.StatusUnauthorized},
+		{"列出应用", http.MethodGet, "/api/list-apps", token, "", http.StatusOK},
+		{"自己的会话", http.MethodGet, "/api/apps/calendar_agent/users/7/sessions", token, "", http.StatusOK},
+		{"其他用户的会话", http.MethodGet, "/api/apps/calendar_agent/users/2/sessions/s1", token, "", http.StatusForbidden},
+		{"运行自己的会话", http.MethodPost, "/api/run_sse", token, `{"appName":"calendar_agent","userId":"7","sessionId":"s1"}`, http.StatusOK},
+		{"运行其他用户的会话", http.MethodPost, "/api/run", token, `{"appName":"calendar_agent","userId":"2","sessionId":"s1"}`, http.StatusForbidden},
+		{"运行时设置应用状态", http.MethodPost, "/api/run", token, `{"appName":"calendar_agent","userId":"7","sessionId":"s1","stateDelta":{"app:x":1}}`, http.StatusBadRequest},
+		{"运行时设置临时状态", http.MethodPost, "/api/run", token, `{"appName":"calendar_agent","userId":"7","sessionId":"s1","stateDelta":{"temp:x":1}}`, http.StatusBadRequest},
+		{"运行时设置用户状态", http.MethodPost, "/api/run", token, `{"appName":"calendar_agent","userId":"7","sessionId":"s1","stateDelta":{"user:x":1}}`, http.StatusOK},
+		{"创建会话", http.MethodPost, "/api/apps/calendar_agent/users/7/sessions", token, "", http.StatusOK},
+		{"创建会话设置会话状态", http.MethodPost, "/api/apps/calendar_agent/users/7/sessions/s2", token, `{"state":{"x":1,"user:y":2}}`, http.StatusOK},
+		{"创建会话设置应用状态", http.MethodPost, "/api/apps/calendar_agent/users/7/sessions", token, `{"state":{"app:x":1}}`, http.StatusBadRequest},
+		{"创建会话设置临时状态", http.MethodPost, "/api/apps/calendar_agent/users/7/sessions/s2", token, `{"state":{"temp:x":1}}`, http.StatusBadRequest},
+		{"创建会话的事件设置应用状态", http.MethodPost, "/api/apps/calendar_agent/users/7/sessions", token, `{"events":[{"actions":{"stateDelta":{"app:x":1}}}]}`, http.StatusBadRequest},
+		{"调试接口", http.MethodGet, "/api/debug/trace/session/s1", token, "", http.StatusForbidden},
+		{"CORS 预检", http.MethodOptions, "/api/run_sse", "", "", http.StatusOK},
+		{"Web UI", http.MethodGet, "/dev-ui/", "", "", http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.token != "" {
+				req.Header.Set("Authorization", "Bearer "+tt.token)
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, req)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+
+	// 检查 userId 后请求体仍然完整地传给 ADK
+	assert.Equal(t, `{"appName":"calendar_agent","userId":"7","sessionId":"s1"}`, body)
+}
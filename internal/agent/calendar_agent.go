@@ -4,24 +4,35 @@ import (
 	"context"
 	"log/slog"
 	"os"
+	"strconv"
 	"strings"
 
+	"github.com/galilio/otter/internal/agent/tools"
 	calendartools "github.com/galilio/otter/internal/agent/tools/calendar"
 	"github.com/galilio/otter/internal/calendar"
 	"github.com/galilio/otter/internal/common/config"
 	"github.com/galilio/otter/internal/llm"
+	"github.com/galilio/otter/internal/usage"
 	"github.com/galilio/otter/internal/user"
 	adkagent "google.golang.org/adk/agent"
 	"google.golang.org/adk/agent/llmagent"
 	"google.golang.org/adk/cmd/launcher"
-	"google.golang.org/adk/cmd/launcher/full"
+	"google.golang.org/adk/cmd/launcher/universal"
+	"google.golang.org/adk/cmd/launcher/web"
+	"google.golang.org/adk/cmd/launcher/web/api"
+	"google.golang.org/adk/cmd/launcher/web/webui"
 	"google.golang.org/adk/model"
+	adksession "google.golang.org/adk/session"
 	"google.golang.org/adk/tool"
 )
 
+// AppName Agent 名称，也是 ADK 会话中的应用名
+const AppName = "calendar_agent"
+
 type AgentConfig struct {
 	Model           model.LLM
 	CalendarService calendar.Service
+	UsageService    usage.Service
 }
 
 type Option func(*AgentConfig)
@@ -38,27 +49,41 @@ func WithCalendarService(service calendar.Service) Option {
 	}
 }
 
-func setupAgent(cfg *config.DeepSeekConfig, calendarService calendar.Service, userService user.Service) (adkagent.Agent, error) {
-	// 设置用户服务，供 InstructionProvider 使用
+// WithUsageService 记录每次模型调用的 token 用量，用户的额度用完后 Agent 拒绝继续对话
+func WithUsageService(service usage.Service) Option {
+	return func(cfg *AgentConfig) {
+		cfg.UsageService = service
+	}
+}
+
+// New 创建日历 Agent，使用 registry 中为 Agent 或用户选择的 LLM 提供方
+func New(registry *llm.Registry, calendarService calendar.Service, userService user.Service, opts ...Option) (adkagent.Agent, error) {
+	// 设置用户服务，供 InstructionProvider 和模型选择使用
 	SetUserService(userService)
 
-	model, err := llm.NewDeepSeekModel(cfg)
-	if err != nil {
-		slog.Error("Failed to create DeepSeek model", "error", err)
-		return nil, err
+	cfg := &AgentConfig{}
+	for _, opt := range opts {
+		opt(cfg)
 	}
 
 	ts := []tool.Tool{}
-	calendarTools, err := calendartools.SetupTools(calendarService)
+	calendarTools, err := calendartools.SetupTools(calendarService, userService)
 	if err != nil {
 		slog.Error("Failed to create calendar tools", "error", err)
 		return nil, err
 	}
 	ts = append(ts, calendarTools...)
 
+	timeTools, err := tools.NewTimeTools(userService)
+	if err != nil {
+		slog.Error("Failed to create time tools", "error", err)
+		return nil, err
+	}
+	ts = append(ts, timeTools...)
+
 	a, err := llmagent.New(llmagent.Config{
-		Name:                "calendar_agent",
-		Model:               model,
+		Name:                AppName,
+		Model:               newAgentModel(registry, AppName, cfg.UsageService),
 		Description:         "A calendar agent that can help you manage your calendar and schedule your events.",
 		InstructionProvider: InstructionProvider, // 使用 InstructionProvider 替代静态 Instruction
 		Tools:               ts,
@@ -77,24 +102,40 @@ func isDevelopment() bool {
 	return env == "development" || env == "dev"
 }
 
-func Launch(ctx context.Context, cfg *config.DeepSeekConfig, calendarService calendar.Service, userService user.Service) error {
-	otter, err := setupAgent(cfg, calendarService, userService)
+// Launch 以独立服务的方式启动 Agent（ADK 启动器，开发环境下包含 Web UI），监听 server.agent_port，
+// 使用 server 中的超时设置；对话会话保存在 sessionService 中（服务重启后仍可继续）。
+// API 使用与 REST API 相同的 JWT 认证，每个用户只能访问自己的会话。
+// 主服务中的 /api/v1/agent 接口（见 Handler）提供相同的对话功能，不需要单独启动
+func Launch(ctx context.Context, cfg *config.Config, calendarService calendar.Service, userService user.Service, sessionService adksession.Service, opts ...Option) error {
+	registry, err := llm.NewRegistry(ctx, &cfg.LLM)
+	if err != nil {
+		return err
+	}
+	otter, err := New(registry, calendarService, userService, opts...)
 	if err != nil {
 		return err
 	}
 
-	config := &launcher.Config{
-		AgentLoader: adkagent.NewSingleLoader(otter),
+	launcherConfig := &launcher.Config{
+		AgentLoader:    adkagent.NewSingleLoader(otter),
+		SessionService: sessionService,
 	}
 
-	// 构建启动选项：基础选项包含 web 和 api
-	// 设置 write-timeout 为 5 分钟，以支持长时间的 SSE 流式响应
-	options := []string{"web", "-port", "8081", "-read-timeout", "60s", "-write-timeout", "5m", "-idle-timeout", "60s", "api"}
+	// 构建启动选项：基础选项包含 web、auth 和 api
+	// SSE 流式响应不受 write-timeout 限制（见 authenticate）
+	port := strconv.Itoa(cfg.Server.AgentPort)
+	options := []string{
+		"web", "-port", port,
+		"-read-timeout", cfg.Server.ReadTimeout.String(),
+		"-write-timeout", cfg.Server.WriteTimeout.String(),
+		"-idle-timeout", cfg.Server.IdleTimeout.String(),
+		"auth", "api",
+	}
 	// 开发环境下额外添加 webui 选项
 	if isDevelopment() {
-		options = append(options, "-webui_address", "localhost:8081", "webui", "-api_server_address", "http://localhost:8081/api")
+		options = append(options, "-webui_address", "localhost:"+port, "webui", "-api_server_address", "http://localhost:"+port+"/api")
 	}
 
-	l := full.NewLauncher()
-	return l.Execute(ctx, config, options)
+	l := universal.NewLauncher(web.NewLauncher(newAuthLauncher(&cfg.JWT), api.NewLauncher(), webui.NewLauncher()))
+	return l.Execute(ctx, launcherConfig, options)
 }
@@ -0,0 +1,105 @@
+package agent
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/galilio/otter/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// SendMessageRequest 发送消息请求
+type SendMessageRequest struct {
+	Text      string `json:"text" binding:"required"`
+	Streaming bool   `json:"streaming"` // 为 true 时逐段推送模型输出（partial 事件）
+}
+
+// SendMessage 发送消息，以 SSE 推送 Agent 的回复
+// POST /api/v1/agent/sessions/:id/messages
+//
+// 每个 Agent 事件（回复、工具调用及其结果）是一个 event 类型的 SSE 事件，出错时推送 error 事件，
+// 结束时推送 done 事件。客户端断开连接时中止对话
+func (h *Handler) SendMessage(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	var req SendMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	events, err := h.service.Run(c.Request.Context(), *userID, c.Param("id"), req.Text, req.Streaming)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	// 对话可能持续较长时间，不受服务器 write_timeout 限制
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for event, err := range events {
+		if err != nil {
+			c.SSEvent("error", ErrorResponse{Error: err.Error()})
+			c.Writer.Flush()
+			return
+		}
+		c.SSEvent("event", session.NewEventResponse(event))
+		c.Writer.Flush()
+	}
+	c.SSEvent("done", gin.H{"session_id": c.Param("id")})
+	c.Writer.Flush()
+}
+
+// CancelRun 中止正在进行的对话
+// POST /api/v1/agent/sessions/:id/cancel
+func (h *Handler) CancelRun(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	if err := h.service.Cancel(*userID, c.Param("id")); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "对话已中止"})
+}
+
+// writeError 根据错误类型返回对应的状态码
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, session.ErrSessionNotFound), errors.Is(err, ErrRunNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrRunInProgress), errors.Is(err, session.ErrStaleSession):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrEmptyMessage), errors.Is(err, session.ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
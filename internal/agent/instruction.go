@@ -6,10 +6,10 @@ import (
 	"log/slog"
 	"os"
 	"path/filepath"
-	"strconv"
 	"text/template"
 	"time"
 
+	"github.com/galilio/otter/internal/session"
 	"github.com/galilio/otter/internal/user"
 	adkagent "google.golang.org/adk/agent"
 	"gopkg.in/yaml.v3"
@@ -56,6 +56,7 @@ type instructionData struct {
 	SpeakingStyle string
 	Examples      []Example
 	Now           string
+	TimeZone      string
 }
 
 // loadCharactersConfig 加载所有角色配置
@@ -128,18 +129,17 @@ func InstructionProvider(ctx adkagent.ReadonlyContext) (string, error) {
 		return instruction, err
 	}
 
-	// 获取用户偏好角色代号
+	// 获取用户偏好角色代号和时区
 	var characterCode string
+	loc := time.UTC
 	if userService != nil {
-		userID, err := strconv.ParseUint(ctx.UserID(), 10, 64)
-		if err != nil {
-			userID = 2
-		}
-		profile, err := userService.GetUserProfile(uint(userID))
-		if err != nil {
-			characterCode = "default" // 默认角色
+		// 会话的用户由认证绑定到 JWT 中的用户；无法识别时使用默认角色和 UTC
+		if userID, err := session.ParseUserKey(ctx.UserID()); err == nil {
+			if profile, err := userService.GetUserProfile(userID); err == nil {
+				characterCode = profile.PreferredCharacterCode
+				loc = profile.Location()
+			}
 		}
-		characterCode = profile.PreferredCharacterCode
 	}
 
 	// 根据角色代号加载角色数据
@@ -148,9 +148,10 @@ func InstructionProvider(ctx adkagent.ReadonlyContext) (string, error) {
 		slog.Warn("Failed to load character, using default", "error", err, "code", characterCode)
 	}
 
-	// 准备模板数据
+	// 准备模板数据，当前时间按用户的时区显示
 	data := instructionData{
-		Now: time.Now().UTC().Format(time.RFC3339),
+		Now:      time.Now().In(loc).Format(time.RFC3339),
+		TimeZone: loc.String(),
 	}
 
 	// 如果成功加载角色，填充角色数据
@@ -180,6 +181,7 @@ func InstructionProvider(ctx adkagent.ReadonlyContext) (string, error) {
 		"user_id", ctx.UserID(),
 		"session_id", ctx.SessionID(),
 		"current_time", data.Now,
+		"time_zone", data.TimeZone,
 		"character", data.Character)
 
 	return result, nil
@@ -0,0 +1,51 @@
+package agent
+
+import (
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/session"
+	"github.com/galilio/otter/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	adkagent "google.golang.org/adk/agent"
+)
+
+// stubUserService 返回固定的用户配置
+type stubUserService struct {
+	user.Service
+	profile *user.UserProfile
+}
+
+func (s *stubUserService) GetUserProfile(userID uint) (*user.UserProfile, error) {
+	return s.profile, nil
+}
+
+// stubReadonlyContext 只提供指令生成用到的用户和会话
+type stubReadonlyContext struct {
+	adkagent.ReadonlyContext
+	userID string
+}
+
+func (c *stubReadonlyContext) UserID() string    { return c.userID }
+func (c *stubReadonlyContext) SessionID() string { return "s1" }
+
+// TestInstructionProvider_TimeZone 测试指令中的当前时间和时区使用用户配置的时区
+func TestInstructionProvider_TimeZone(t *testing.T) {
+	SetUserService(&stubUserService{profile: &user.UserProfile{Timezone: "America/New_York"}})
+	t.Cleanup(func() { SetUserService(nil) })
+
+	instruction, err := InstructionProvider(&stubReadonlyContext{userID: session.UserKey(7)})
+	require.NoError(t, err)
+
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	_, offset := time.Now().In(newYork).Zone()
+	assert.Contains(t, instruction, "Time Zone: America/New_York")
+	assert.NotContains(t, instruction, "UTC+8")
+	if offset == -4*3600 {
+		assert.Contains(t, instruction, "-04:00, Time Zone")
+	} else {
+		assert.Contains(t, instruction, "-05:00, Time Zone")
+	}
+}
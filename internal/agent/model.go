@@ -0,0 +1,127 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"iter"
+	"log/slog"
+
+	"github.com/galilio/otter/internal/llm"
+	"github.com/galilio/otter/internal/session"
+	"github.com/galilio/otter/internal/usage"
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// 额度用完时 Agent 的回复
+const (
+	dailyQuotaReply   = "抱歉，你今天的对话额度已经用完了，明天再来找我吧。"
+	monthlyQuotaReply = "抱歉，你本月的对话额度已经用完了，下个月再来找我吧。"
+)
+
+// agentModel 按用户选择提供方的模型
+// 用户在个人配置中选择了提供方（llm_provider）时使用它，否则使用 Agent 的提供方（llm.agents 或 llm.default）。
+// 设置了 usage 时记录每次调用的 token 用量，用户的额度用完后直接回复提示而不调用模型
+type agentModel struct {
+	registry  *llm.Registry
+	agentName string
+	usage     usage.Service
+}
+
+// newAgentModel 创建 Agent 使用的模型，usageService 可以为 nil
+func newAgentModel(registry *llm.Registry, agentName string, usageService usage.Service) model.LLM {
+	return &agentModel{registry: registry, agentName: agentName, usage: usageService}
+}
+
+func (m *agentModel) Name() string {
+	return m.registry.ForAgent(m.agentName).Name()
+}
+
+func (m *agentModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	userID, sessionID, ok := invocationUser(ctx)
+	if !ok || m.usage == nil {
+		return m.resolve(userID, ok).GenerateContent(ctx, req, stream)
+	}
+
+	return func(yield func(*model.LLMResponse, error) bool) {
+		if err := m.usage.CheckQuota(userID); err != nil {
+			if reply, exceeded := quotaReply(err); exceeded {
+				yield(&model.LLMResponse{
+					Content:      genai.NewContentFromText(reply, genai.RoleModel),
+					TurnComplete: true,
+					FinishReason: genai.FinishReasonStop,
+				}, nil)
+				return
+			}
+			// 无法查询用量时不影响对话
+			slog.Error("Failed to check token quota", "user_id", userID, "error", err)
+		}
+
+		selected := m.resolve(userID, true)
+		for resp, err := range selected.GenerateContent(ctx, req, stream) {
+			if err == nil && resp != nil && !resp.Partial && resp.UsageMetadata != nil {
+				m.record(userID, sessionID, selected, resp)
+			}
+			if !yield(resp, err) {
+				return
+			}
+		}
+	}
+}
+
+// resolve 选择用户使用的模型，hasUser 为 false 时使用 Agent 的提供方
+func (m *agentModel) resolve(userID uint, hasUser bool) model.LLM {
+	if !hasUser || userService == nil {
+		return m.registry.ForAgent(m.agentName)
+	}
+
+	profile, err := userService.GetUserProfile(userID)
+	if err != nil || profile.LLMProvider == "" {
+		return m.registry.ForAgent(m.agentName)
+	}
+
+	selected, err := m.registry.Get(profile.LLMProvider)
+	if err != nil {
+		slog.Warn("User LLM provider not found, using agent provider", "user_id", userID, "provider", profile.LLMProvider)
+		return m.registry.ForAgent(m.agentName)
+	}
+	return selected
+}
+
+// record 记录一次调用的用量，提供方和模型取自回复中实际使用的提供方
+func (m *agentModel) record(userID uint, sessionID string, selected model.LLM, resp *model.LLMResponse) {
+	provider, _ := resp.CustomMetadata[llm.MetadataProvider].(string)
+	modelName, _ := resp.CustomMetadata[llm.MetadataModel].(string)
+	if modelName == "" {
+		modelName = selected.Name()
+	}
+	if err := m.usage.Record(userID, sessionID, provider, modelName, usage.FromMetadata(resp.UsageMetadata)); err != nil {
+		slog.Error("Failed to record token usage", "user_id", userID, "session_id", sessionID, "error", err)
+	}
+}
+
+// invocationUser 从 ADK 调用模型时传入的 InvocationContext 中取得会话的用户和会话 ID
+func invocationUser(ctx context.Context) (uint, string, bool) {
+	invocation, ok := ctx.(adkagent.InvocationContext)
+	if !ok {
+		return 0, "", false
+	}
+	userID, err := session.ParseUserKey(invocation.Session().UserID())
+	if err != nil {
+		return 0, "", false
+	}
+	return userID, invocation.Session().ID(), true
+}
+
+// quotaReply 额度用完时返回给用户的回复
+func quotaReply(err error) (string, bool) {
+	switch {
+	case errors.Is(err, usage.ErrDailyQuotaExceeded):
+		return dailyQuotaReply, true
+	case errors.Is(err, usage.ErrMonthlyQuotaExceeded):
+		return monthlyQuotaReply, true
+	default:
+		return "", false
+	}
+}
@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/galilio/otter/internal/llm"
+	"github.com/galilio/otter/internal/usage"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	adksession "google.golang.org/adk/session"
+)
+
+// stubUsageService 记录用量并返回指定的额度检查结果
+type stubUsageService struct {
+	usage.Service
+	quotaErr error
+
+	mu      sync.Mutex
+	records []string
+	tokens  int64
+}
+
+func (s *stubUsageService) CheckQuota(userID uint) error {
+	return s.quotaErr
+}
+
+func (s *stubUsageService) Record(userID uint, sessionID, provider, model string, totals usage.Totals) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.records = append(s.records, fmt.Sprintf("%d/%s/%s", userID, provider, model))
+	s.tokens += totals.TotalTokens
+	return nil
+}
+
+// newUsageTestService 创建使用 OpenAI 兼容测试服务的日历 Agent 对话服务，返回模型被调用的次数
+func newUsageTestService(t *testing.T, usageService usage.Service) (Service, *int) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"好的"},"finish_reason":"stop"}],
+			"usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120}}`)
+	}))
+	t.Cleanup(server.Close)
+
+	registry, err := llm.NewRegistry(context.Background(), &config.LLMConfig{
+		Default: "test",
+		Providers: map[string]config.ProviderConfig{
+			"test": {Type: config.ProviderOpenAI, APIKey: "sk-test", Model: "test-model", BaseURL: server.URL},
+		},
+	})
+	require.NoError(t, err)
+
+	a, err := New(registry, nil, nil, WithUsageService(usageService))
+	require.NoError(t, err)
+	service, err := NewService(a, adksession.InMemoryService())
+	require.NoError(t, err)
+	return service, &calls
+}
+
+// run 发送消息并返回 Agent 的回复
+func run(t *testing.T, service Service, userID uint, text string) []string {
+	ctx := context.Background()
+	s := createSession(t, service, userID)
+
+	events, err := service.Run(ctx, userID, s.ID(), text, false)
+	require.NoError(t, err)
+	var replies []string
+	for event, err := range events {
+		require.NoError(t, err)
+		if event.Content != nil && len(event.Content.Parts) > 0 {
+			replies = append(replies, event.Content.Parts[0].Text)
+		}
+	}
+	return replies
+}
+
+// TestAgentModel_Usage 测试记录每次模型调用的用量，额度用完时不调用模型并礼貌地拒绝
+func TestAgentModel_Usage(t *testing.T) {
+	usageService := &stubUsageService{}
+	service, calls := newUsageTestService(t, usageService)
+
+	assert.Equal(t, []string{"好的"}, run(t, service, 7, "明天下午开会"))
+	assert.Equal(t, 1, *calls)
+	assert.Equal(t, []string{"7/test/test-model"}, usageService.records)
+	assert.Equal(t, int64(120), usageService.tokens)
+
+	usageService.quotaErr = usage.ErrDailyQuotaExceeded
+	assert.Equal(t, []string{dailyQuotaReply}, run(t, service, 7, "再加一个"))
+	assert.Equal(t, 1, *calls, "额度用完后不调用模型")
+	assert.Len(t, usageService.records, 1)
+}
@@ -0,0 +1,123 @@
+package agent
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"strings"
+	"sync"
+
+	"github.com/galilio/otter/internal/session"
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/runner"
+	adksession "google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+var (
+	ErrEmptyMessage  = errors.New("消息不能为空")
+	ErrRunInProgress = errors.New("会话中已有正在进行的对话")
+	ErrRunNotFound   = errors.New("会话中没有正在进行的对话")
+)
+
+// Service Agent 对话服务
+// 会话属于用户，通过会话接口（/api/v1/apps/calendar_agent/sessions）创建和管理；
+// 同一会话同一时间只能有一个正在进行的对话，可以通过 Cancel 中止
+type Service interface {
+	// Run 发送用户消息并运行 Agent，返回 Agent 产生的事件；streaming 为 true 时包含流式输出的中间事件
+	Run(ctx context.Context, userID uint, sessionID string, text string, streaming bool) (iter.Seq2[*adksession.Event, error], error)
+	Cancel(userID uint, sessionID string) error
+}
+
+type service struct {
+	runner   *runner.Runner
+	sessions adksession.Service
+
+	mu   sync.Mutex
+	runs map[string]context.CancelFunc
+}
+
+// NewService 创建 Agent 对话服务，会话保存在 sessions 中
+func NewService(a adkagent.Agent, sessions adksession.Service) (Service, error) {
+	r, err := runner.New(runner.Config{
+		AppName:        AppName,
+		Agent:          a,
+		SessionService: sessions,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建 Agent 运行器失败: %w", err)
+	}
+	return &service{runner: r, sessions: sessions, runs: map[string]context.CancelFunc{}}, nil
+}
+
+// Run 发送用户消息并运行 Agent
+// 会话不存在或已有正在进行的对话时直接返回错误；返回的事件序列必须被遍历，遍历结束后对话才结束。
+// ctx 取消（例如客户端断开）或调用 Cancel 时中止对话
+func (s *service) Run(ctx context.Context, userID uint, sessionID string, text string, streaming bool) (iter.Seq2[*adksession.Event, error], error) {
+	if strings.TrimSpace(text) == "" {
+		return nil, ErrEmptyMessage
+	}
+	_, err := s.sessions.Get(ctx, &adksession.GetRequest{
+		AppName:   AppName,
+		UserID:    session.UserKey(userID),
+		SessionID: sessionID,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	key := runKey(userID, sessionID)
+	runCtx, cancel := context.WithCancel(ctx)
+	s.mu.Lock()
+	if _, ok := s.runs[key]; ok {
+		s.mu.Unlock()
+		cancel()
+		return nil, ErrRunInProgress
+	}
+	s.runs[key] = cancel
+	s.mu.Unlock()
+
+	cfg := adkagent.RunConfig{StreamingMode: adkagent.StreamingModeNone}
+	if streaming {
+		cfg.StreamingMode = adkagent.StreamingModeSSE
+	}
+	msg := genai.NewContentFromText(text, genai.RoleUser)
+
+	return func(yield func(*adksession.Event, error) bool) {
+		defer s.finish(key, cancel)
+		for event, err := range s.runner.Run(runCtx, session.UserKey(userID), sessionID, msg, cfg) {
+			if err == nil && runCtx.Err() != nil {
+				err = runCtx.Err()
+			}
+			if !yield(event, err) || err != nil {
+				return
+			}
+		}
+	}, nil
+}
+
+// Cancel 中止会话中正在进行的对话
+func (s *service) Cancel(userID uint, sessionID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cancel, ok := s.runs[runKey(userID, sessionID)]
+	if !ok {
+		return ErrRunNotFound
+	}
+	cancel()
+	return nil
+}
+
+// finish 对话结束后释放会话
+func (s *service) finish(key string, cancel context.CancelFunc) {
+	cancel()
+	s.mu.Lock()
+	delete(s.runs, key)
+	s.mu.Unlock()
+}
+
+func runKey(userID uint, sessionID string) string {
+	return session.UserKey(userID) + "/" + sessionID
+}
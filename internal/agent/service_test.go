@@ -0,0 +1,136 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/galilio/otter/internal/session"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	adkagent "google.golang.org/adk/agent"
+	"google.golang.org/adk/model"
+	adksession "google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+// newTestService 创建使用回声 Agent 的对话服务，block 不为空时 Agent 回复后等待 block 关闭或对话被中止
+func newTestService(t *testing.T, block chan struct{}) Service {
+	a, err := adkagent.New(adkagent.Config{
+		Name: AppName,
+		Run: func(ctx adkagent.InvocationContext) iter.Seq2[*adksession.Event, error] {
+			return func(yield func(*adksession.Event, error) bool) {
+				event := adksession.NewEvent(ctx.InvocationID())
+				event.Author = AppName
+				event.LLMResponse = model.LLMResponse{
+					Content: genai.NewContentFromText("收到："+ctx.UserContent().Parts[0].Text, genai.RoleModel),
+				}
+				if !yield(event, nil) || block == nil {
+					return
+				}
+				select {
+				case <-block:
+				case <-ctx.Done():
+					yield(nil, ctx.Err())
+				}
+			}
+		},
+	})
+	require.NoError(t, err)
+
+	service, err := NewService(a, adksession.InMemoryService())
+	require.NoError(t, err)
+	return service
+}
+
+// sessionsOf 对话服务使用的会话服务，即会话接口（/api/v1/apps/calendar_agent/sessions）背后的服务
+func sessionsOf(s Service) adksession.Service {
+	return s.(*service).sessions
+}
+
+// createSession 像会话接口一样为用户创建 Agent 的会话
+func createSession(t *testing.T, s Service, userID uint) adksession.Session {
+	resp, err := sessionsOf(s).Create(context.Background(), &adksession.CreateRequest{
+		AppName: AppName,
+		UserID:  session.UserKey(userID),
+	})
+	require.NoError(t, err)
+	return resp.Session
+}
+
+// TestService_Run 测试对话的回复保存在会话中，同一会话同一时间只能有一个对话，Cancel 中止对话
+func TestService_Run(t *testing.T) {
+	block := make(chan struct{})
+	service := newTestService(t, block)
+	ctx := context.Background()
+
+	s := createSession(t, service, 7)
+
+	_, err := service.Run(ctx, 8, s.ID(), "你好", false)
+	assert.Error(t, err, "其他用户的会话")
+	_, err = service.Run(ctx, 7, s.ID(), " ", false)
+	assert.ErrorIs(t, err, ErrEmptyMessage)
+
+	events, err := service.Run(ctx, 7, s.ID(), "明天下午开会", false)
+	require.NoError(t, err)
+	_, err = service.Run(ctx, 7, s.ID(), "再加一个", false)
+	assert.ErrorIs(t, err, ErrRunInProgress)
+
+	var replies []string
+	var runErr error
+	for event, err := range events {
+		if err != nil {
+			runErr = err
+			break
+		}
+		replies = append(replies, event.Content.Parts[0].Text)
+		require.NoError(t, service.Cancel(7, s.ID()))
+	}
+	assert.Equal(t, []string{"收到：明天下午开会"}, replies)
+	assert.ErrorIs(t, runErr, context.Canceled)
+	assert.ErrorIs(t, service.Cancel(7, s.ID()), ErrRunNotFound)
+
+	// 用户消息和回复都保存在会话中
+	resp, err := sessionsOf(service).Get(ctx, &adksession.GetRequest{AppName: AppName, UserID: session.UserKey(7), SessionID: s.ID()})
+	require.NoError(t, err)
+	assert.Equal(t, 2, resp.Session.Events().Len())
+}
+
+// TestHandler_SendMessage 测试以 SSE 推送 Agent 的回复
+func TestHandler_SendMessage(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	service := newTestService(t, nil)
+	s := createSession(t, service, 7)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) { c.Set("user_id", uint(7)) })
+	r.POST("/sessions/:id/messages", NewHandler(service).SendMessage)
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/sessions/"+s.ID()+"/messages", strings.NewReader(`{"text":"明天下午开会"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "text/event-stream", w.Header().Get("Content-Type"))
+	body := w.Body.String()
+	require.Contains(t, body, "event:event\n")
+	assert.Contains(t, body, "event:done\n")
+
+	data := body[strings.Index(body, "data:")+len("data:") : strings.Index(body, "\n\n")]
+	var event session.EventResponse
+	require.NoError(t, json.Unmarshal([]byte(data), &event))
+	assert.Equal(t, AppName, event.Author)
+	assert.Equal(t, "收到：明天下午开会", event.Content.Parts[0].Text)
+
+	w = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/sessions/missing/messages", strings.NewReader(`{"text":"你好"}`))
+	req.Header.Set("Content-Type", "application/json")
+	r.ServeHTTP(w, req)
+	assert.NotEqual(t, http.StatusOK, w.Code)
+}
@@ -1,26 +1,32 @@
 package calendar
 
 import (
+	"errors"
+	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/galilio/otter/internal/calendar"
 	"github.com/galilio/otter/internal/common/utils"
+	"github.com/galilio/otter/internal/session"
+	"github.com/galilio/otter/internal/user"
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
 )
 
 type calendarTools struct {
 	service calendar.Service
+	users   user.Service
 }
 
-func SetupTools(service calendar.Service) ([]tool.Tool, error) {
-	ct := &calendarTools{service: service}
+// SetupTools 创建日历工具，userService 用于取得用户的时区（为 nil 时使用 UTC）
+func SetupTools(service calendar.Service, userService user.Service) ([]tool.Tool, error) {
+	ct := &calendarTools{service: service, users: userService}
 	tools := []tool.Tool{}
 
 	createItemTool, err := functiontool.New(functiontool.Config{
 		Name:         "create_calendar_item",
-		Description:  "Create a calendar item. Supports VEVENT (requires dtstart, and either dtend or duration), VTODO (requires dtstart or due), VJOURNAL (requires dtstart), VFREEBUSY (requires both dtstart and dtend). If uid is provided and already exists, returns the existing item (idempotent).",
+		Description:  "Create a calendar item. Supports VEVENT (requires dtstart, and either dtend or duration), VTODO (requires dtstart or due), VJOURNAL (requires dtstart), VFREEBUSY (requires both dtstart and dtend). If uid is provided and already exists, returns the existing item (idempotent). A VEVENT that overlaps existing events (recurring events expanded, transparent and cancelled events ignored) is not created by default: the result has success=false and lists the conflicts. Ask the user before double-booking, then retry with conflict_policy \"allow\".",
 		InputSchema:  utils.SchemaFromStruct(CreateRequest{}),
 		OutputSchema: utils.SchemaFromStruct(OperationResult{}),
 	}, ct.CreateCalendarItem)
@@ -44,7 +50,7 @@ func SetupTools(service calendar.Service) ([]tool.Tool, error) {
 
 	updateItemTool, err := functiontool.New(functiontool.Config{
 		Name:         "update_calendar_item",
-		Description:  "Update a calendar item. Requires id and optional fields to update.",
+		Description:  "Update a calendar item. Requires id and optional fields to update. For a recurring item, pass recurrence_id (the original start time of the occurrence) and scope: 'this' changes only that occurrence, 'this_and_following' splits the series at that occurrence, 'all' changes the whole series. Moving an event onto existing events is rejected by default and the conflicts are returned; retry with conflict_policy \"allow\" after the user agrees.",
 		InputSchema:  utils.SchemaFromStruct(UpdateRequest{}),
 		OutputSchema: utils.SchemaFromStruct(OperationResult{}),
 	}, ct.UpdateCalendarItem)
@@ -68,7 +74,7 @@ func SetupTools(service calendar.Service) ([]tool.Tool, error) {
 
 	searchItemsTool, err := functiontool.New(functiontool.Config{
 		Name:         "search_calendar_items",
-		Description:  "Search calendar items by keyword (q) and/or time ranges. The keyword will be searched across all searchable fields (summary, description, location, organizer, comment, contact, categories, resources). At least one search criteria (q or dtstart) is required.",
+		Description:  "Search calendar items by keyword (q) and/or time ranges. The keyword is full-text searched across summary, location, description, organizer, comment, contact, categories and resources (summary matches rank highest); all space-separated terms must match, \"quoted text\" matches an exact phrase and a trailing * matches a word prefix (e.g. rev*). Each result has highlights with the matched snippets marked by <b></b>. At least one search criteria (q or dtstart) is required. Results are sorted by relevance when q is given, otherwise by dtstart; set sort to dtstart, due, priority, last_modified or relevance to override it (only dtstart is allowed when dtstart.start is set, because recurring events are expanded). When has_more is true, pass next_cursor back as cursor with the same criteria to get the next page.",
 		InputSchema:  utils.SchemaFromStruct(SearchRequest{}),
 		OutputSchema: utils.SchemaFromStruct(SearchResponse{}),
 	}, ct.SearchCalendarItems)
@@ -78,11 +84,26 @@ func SetupTools(service calendar.Service) ([]tool.Tool, error) {
 	}
 	tools = append(tools, searchItemsTool)
 
+	findFreeSlotsTool, err := functiontool.New(functiontool.Config{
+		Name:         "find_free_slots",
+		Description:  "Find open time slots of the given duration within a time window, for proposing or booking a meeting. Busy events are taken from the user's calendar (recurring events are expanded; cancelled and transparent events are ignored). Optionally restrict to working hours (workday_start/workday_end in HH:MM, weekdays as MO..SU, interpreted in timezone) and keep buffer_minutes between the slot and other events. Returns non-overlapping candidate slots ranked best first; book one with create_calendar_item.",
+		InputSchema:  utils.SchemaFromStruct(FindFreeSlotsRequest{}),
+		OutputSchema: utils.SchemaFromStruct(FindFreeSlotsResponse{}),
+	}, ct.FindFreeSlots)
+	if err != nil {
+		slog.Error("Failed to create find_free_slots tool", "error", err)
+		return nil, err
+	}
+	tools = append(tools, findFreeSlotsTool)
+
 	return tools, nil
 }
 
 func (ct *calendarTools) CreateCalendarItem(ctx tool.Context, input CreateRequest) (*OperationResult, error) {
-	userID := getUserID(ctx)
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	slog.Info("Creating calendar item", "type", input.Type, "uid", input.UID)
 
 	if input.UID != nil && *input.UID != "" {
@@ -109,7 +130,20 @@ func (ct *calendarTools) CreateCalendarItem(ctx tool.Context, input CreateReques
 		}, calendar.ErrInvalidType
 	}
 
-	dtStart, err := parseOptionalTime(input.DtStart)
+	// 没有时区信息的时间按日历项的时区（默认为用户的时区）解释
+	loc := ct.location(userID)
+	if input.TZID != nil && *input.TZID != "" {
+		if loc, err = time.LoadLocation(*input.TZID); err != nil {
+			slog.Warn("Invalid tzid", "tzid", *input.TZID, "error", err)
+			return &OperationResult{
+				Success: false,
+				Message: "Invalid tzid: " + err.Error(),
+			}, calendar.ErrInvalidInput
+		}
+	}
+	tzid := loc.String()
+
+	dtStart, err := parseOptionalTime(input.DtStart, loc)
 	if err != nil {
 		slog.Warn("Failed to parse dtstart", "error", err)
 		return &OperationResult{
@@ -118,7 +152,7 @@ func (ct *calendarTools) CreateCalendarItem(ctx tool.Context, input CreateReques
 		}, err
 	}
 
-	dtEnd, err := parseOptionalTime(input.DtEnd)
+	dtEnd, err := parseOptionalTime(input.DtEnd, loc)
 	if err != nil {
 		slog.Warn("Failed to parse dtend", "error", err)
 		return &OperationResult{
@@ -127,7 +161,7 @@ func (ct *calendarTools) CreateCalendarItem(ctx tool.Context, input CreateReques
 		}, err
 	}
 
-	due, err := parseOptionalTime(input.Due)
+	due, err := parseOptionalTime(input.Due, loc)
 	if err != nil {
 		slog.Warn("Failed to parse due", "error", err)
 		return &OperationResult{
@@ -136,11 +170,20 @@ func (ct *calendarTools) CreateCalendarItem(ctx tool.Context, input CreateReques
 		}, err
 	}
 
+	// dtstart 只有日期时默认为全天日历项
+	allDay := input.AllDay
+	if allDay == nil && input.DtStart != nil && utils.IsDateOnly(*input.DtStart) {
+		isAllDay := true
+		allDay = &isAllDay
+	}
+
 	req := &calendar.CreateCalendarItemRequest{
 		Type:            itemType,
 		DtStart:         dtStart,
 		DtEnd:           dtEnd,
 		Due:             due,
+		TZID:            &tzid,
+		AllDay:          allDay,
 		Duration:        input.Duration,
 		Summary:         input.Summary,
 		Description:     input.Description,
@@ -159,9 +202,16 @@ func (ct *calendarTools) CreateCalendarItem(ctx tool.Context, input CreateReques
 		Resources:       input.Resources,
 		URL:             input.URL,
 		Class:           input.Class,
+		Transp:          input.Transp,
+		Attendees:       input.Attendees,
+		ConflictPolicy:  conflictPolicy(input.ConflictPolicy),
 	}
 
 	resp, err := ct.service.CreateCalendarItem(&userID, req)
+	if result, ok := conflictResult(err, nil); ok {
+		slog.Info("Calendar item not created due to conflicts", "conflicts", len(result.Conflicts))
+		return result, nil
+	}
 	if err != nil {
 		slog.Error("Failed to create calendar item", "type", input.Type, "error", err)
 		return &OperationResult{
@@ -174,27 +224,32 @@ func (ct *calendarTools) CreateCalendarItem(ctx tool.Context, input CreateReques
 	if err != nil {
 		slog.Warn("Failed to get created item details", "id", resp.ID, "error", err)
 		return &OperationResult{
-			Success: true,
-			Message: "Calendar item created successfully",
-			ID:      &resp.ID,
-			UID:     &resp.UID,
-			Created: true,
+			Success:   true,
+			Message:   "Calendar item created successfully",
+			ID:        &resp.ID,
+			UID:       &resp.UID,
+			Created:   true,
+			Conflicts: resp.Conflicts,
 		}, nil
 	}
 
 	slog.Info("Calendar item created successfully", "id", item.ID, "uid", item.UID, "type", item.Type)
 	return &OperationResult{
-		Success: true,
-		Message: "Calendar item created successfully",
-		ID:      &item.ID,
-		UID:     &item.UID,
-		Created: true,
-		Item:    convertToDetailResponse(item),
+		Success:   true,
+		Message:   "Calendar item created successfully",
+		ID:        &item.ID,
+		UID:       &item.UID,
+		Created:   true,
+		Item:      convertToDetailResponse(item),
+		Conflicts: resp.Conflicts,
 	}, nil
 }
 
 func (ct *calendarTools) GetCalendarItem(ctx tool.Context, input GetRequest) (*ItemDetail, error) {
-	userID := getUserID(ctx)
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	if input.ID == nil && input.UID == nil {
 		slog.Warn("GetCalendarItem: neither id nor uid provided")
@@ -202,8 +257,6 @@ func (ct *calendarTools) GetCalendarItem(ctx tool.Context, input GetRequest) (*I
 	}
 
 	var item *calendar.CalendarItem
-	var err error
-
 	if input.ID != nil {
 		slog.Debug("Getting calendar item by ID", "id", *input.ID)
 		item, err = ct.service.GetCalendarItemByID(&userID, *input.ID)
@@ -222,10 +275,46 @@ func (ct *calendarTools) GetCalendarItem(ctx tool.Context, input GetRequest) (*I
 }
 
 func (ct *calendarTools) UpdateCalendarItem(ctx tool.Context, input UpdateRequest) (*OperationResult, error) {
-	userID := getUserID(ctx)
-	slog.Info("Updating calendar item", "id", input.ID)
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("Updating calendar item", "id", input.ID, "recurrence_id", input.RecurrenceID, "scope", input.Scope)
+
+	recurrenceID, err := parseOptionalTime(input.RecurrenceID, ct.location(userID))
+	if err != nil {
+		slog.Warn("Failed to parse recurrence_id", "error", err)
+		return &OperationResult{
+			Success: false,
+			Message: "Invalid recurrence_id: " + err.Error(),
+			ID:      &input.ID,
+		}, err
+	}
 
-	item, err := ct.service.UpdateCalendarItem(&userID, input.ID, &input.UpdateCalendarItemRequest)
+	req, err := ct.updateItemRequest(userID, input)
+	if err != nil {
+		slog.Warn("Invalid update request", "id", input.ID, "error", err)
+		return &OperationResult{
+			Success: false,
+			Message: err.Error(),
+			ID:      &input.ID,
+		}, err
+	}
+
+	var item *calendar.CalendarItem
+	if recurrenceID != nil {
+		scope := calendar.UpdateScopeThis
+		if input.Scope != nil {
+			scope = calendar.UpdateScope(*input.Scope)
+		}
+		item, err = ct.service.UpdateCalendarItemOccurrence(&userID, input.ID, *recurrenceID, scope, req)
+	} else {
+		item, err = ct.service.UpdateCalendarItem(&userID, input.ID, req)
+	}
+	if result, ok := conflictResult(err, &input.ID); ok {
+		slog.Info("Calendar item not updated due to conflicts", "id", input.ID, "conflicts", len(result.Conflicts))
+		return result, nil
+	}
 	if err != nil {
 		slog.Error("Failed to update calendar item", "id", input.ID, "error", err)
 		return &OperationResult{
@@ -237,20 +326,87 @@ func (ct *calendarTools) UpdateCalendarItem(ctx tool.Context, input UpdateReques
 
 	slog.Info("Calendar item updated successfully", "id", item.ID, "uid", item.UID)
 	return &OperationResult{
-		Success: true,
-		Message: "Calendar item updated successfully",
-		ID:      &item.ID,
-		UID:     &item.UID,
-		Updated: true,
-		Item:    convertToDetailResponse(item),
+		Success:   true,
+		Message:   "Calendar item updated successfully",
+		ID:        &item.ID,
+		UID:       &item.UID,
+		Updated:   true,
+		Item:      convertToDetailResponse(item),
+		Conflicts: item.Conflicts,
 	}, nil
 }
 
+// updateItemRequest 将工具的修改请求转换为日历服务的请求
+// 没有时区信息的时间按 tzid 解释；没有 tzid 时按日历项的时区，日历项没有时区时按用户的时区
+func (ct *calendarTools) updateItemRequest(userID uint, input UpdateRequest) (*calendar.UpdateCalendarItemRequest, error) {
+	loc := ct.location(userID)
+	if input.TZID != nil && *input.TZID != "" {
+		var err error
+		if loc, err = time.LoadLocation(*input.TZID); err != nil {
+			return nil, fmt.Errorf("%w: invalid tzid: %v", calendar.ErrInvalidInput, err)
+		}
+	} else if input.DtStart != nil || input.DtEnd != nil || input.Due != nil || input.Completed != nil {
+		if item, err := ct.service.GetCalendarItemByID(&userID, input.ID); err == nil && item.DtStartTZID != nil {
+			loc = item.StartLocation()
+		}
+	}
+
+	req := &calendar.UpdateCalendarItemRequest{
+		TZID:            input.TZID,
+		AllDay:          input.AllDay,
+		Duration:        input.Duration,
+		Summary:         input.Summary,
+		Description:     input.Description,
+		Location:        input.Location,
+		Organizer:       input.Organizer,
+		Status:          input.Status,
+		Priority:        input.Priority,
+		PercentComplete: input.PercentComplete,
+		RRule:           input.RRule,
+		ExDate:          input.ExDate,
+		RDate:           input.RDate,
+		Categories:      input.Categories,
+		Comment:         input.Comment,
+		Contact:         input.Contact,
+		RelatedTo:       input.RelatedTo,
+		Resources:       input.Resources,
+		URL:             input.URL,
+		Class:           input.Class,
+		Transp:          input.Transp,
+		Attendees:       input.Attendees,
+		ConflictPolicy:  conflictPolicy(input.ConflictPolicy),
+	}
+
+	var err error
+	if req.DtStart, err = parseOptionalTime(input.DtStart, loc); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse dtstart: %v", calendar.ErrInvalidInput, err)
+	}
+	if req.DtEnd, err = parseOptionalTime(input.DtEnd, loc); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse dtend: %v", calendar.ErrInvalidInput, err)
+	}
+	if req.Due, err = parseOptionalTime(input.Due, loc); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse due: %v", calendar.ErrInvalidInput, err)
+	}
+	if req.Completed, err = parseOptionalTime(input.Completed, loc); err != nil {
+		return nil, fmt.Errorf("%w: failed to parse completed: %v", calendar.ErrInvalidInput, err)
+	}
+
+	// dtstart 只有日期时默认为全天日历项
+	if req.AllDay == nil && input.DtStart != nil && utils.IsDateOnly(*input.DtStart) {
+		allDay := true
+		req.AllDay = &allDay
+	}
+	return req, nil
+}
+
 func (ct *calendarTools) DeleteCalendarItem(ctx tool.Context, input DeleteRequest) (*OperationResult, error) {
-	userID := getUserID(ctx)
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
 	slog.Info("Deleting calendar item", "id", input.ID)
 
-	err := ct.service.DeleteCalendarItem(&userID, input.ID)
+	err = ct.service.DeleteCalendarItem(&userID, input.ID)
 	if err != nil && err != calendar.ErrCalendarItemNotFound {
 		slog.Error("Failed to delete calendar item", "id", input.ID, "error", err)
 		return &OperationResult{
@@ -277,7 +433,10 @@ func (ct *calendarTools) DeleteCalendarItem(ctx tool.Context, input DeleteReques
 }
 
 func (ct *calendarTools) SearchCalendarItems(ctx tool.Context, input SearchRequest) (*SearchResponse, error) {
-	userID := getUserID(ctx)
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
 
 	if input.Q == nil && input.DtStart == nil {
 		slog.Warn("SearchCalendarItems: no search criteria provided")
@@ -292,12 +451,17 @@ func (ct *calendarTools) SearchCalendarItems(ctx tool.Context, input SearchReque
 	slog.Debug("Searching calendar items", "q", input.Q, "limit", input.Limit)
 
 	req := calendar.SearchCalendarItemsRequest{
-		Q:     input.Q,
-		Limit: input.Limit,
+		Q:      input.Q,
+		Limit:  input.Limit,
+		Cursor: input.Cursor,
+	}
+	if input.Sort != nil {
+		sortKey := calendar.SortKey(*input.Sort)
+		req.Sort = &sortKey
 	}
 
 	if input.DtStart != nil {
-		dtStart, err := convertTimeRangeInput(input.DtStart)
+		dtStart, err := convertTimeRangeInput(input.DtStart, ct.location(userID))
 		if err != nil {
 			slog.Warn("Failed to parse time range", "error", err)
 			return &SearchResponse{
@@ -311,7 +475,7 @@ func (ct *calendarTools) SearchCalendarItems(ctx tool.Context, input SearchReque
 		}
 	}
 
-	items, err := ct.service.SearchCalendarItems(&userID, &req)
+	result, err := ct.service.SearchCalendarItems(&userID, &req)
 	if err != nil {
 		slog.Error("Failed to search calendar items", "error", err)
 		return &SearchResponse{
@@ -321,29 +485,130 @@ func (ct *calendarTools) SearchCalendarItems(ctx tool.Context, input SearchReque
 		}, err
 	}
 
-	summaries := make([]*Item, 0, len(items))
-	for _, item := range items {
+	summaries := make([]*Item, 0, len(result.Items))
+	for _, item := range result.Items {
 		summaries = append(summaries, convertToResponse(item))
 	}
+	hasMore := result.NextCursor != ""
+
+	slog.Info("Calendar items search completed", "total", len(summaries), "has_more", hasMore)
+	return &SearchResponse{
+		Items:      summaries,
+		Total:      len(summaries),
+		Limit:      input.Limit,
+		HasMore:    hasMore,
+		NextCursor: result.NextCursor,
+	}, nil
+}
+
+func (ct *calendarTools) FindFreeSlots(ctx tool.Context, input FindFreeSlotsRequest) (*FindFreeSlotsResponse, error) {
+	userID, err := getUserID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// 工作时间和没有时区信息的窗口按指定的时区解释，默认为用户的时区
+	loc := ct.location(userID)
+	if input.TimeZone != nil && *input.TimeZone != "" {
+		if loc, err = time.LoadLocation(*input.TimeZone); err != nil {
+			slog.Warn("Invalid timezone", "timezone", *input.TimeZone, "error", err)
+			return nil, calendar.ErrInvalidInput
+		}
+	}
+
+	start, err := utils.ParseDateTimeIn(input.Start, loc)
+	if err != nil {
+		slog.Warn("Failed to parse start", "error", err)
+		return nil, err
+	}
+	end, err := utils.ParseDateTimeIn(input.End, loc)
+	if err != nil {
+		slog.Warn("Failed to parse end", "error", err)
+		return nil, err
+	}
 
-	limit := 20
+	req := &calendar.FindFreeSlotsRequest{
+		Start:           start,
+		End:             end,
+		DurationMinutes: input.DurationMinutes,
+		TimeZone:        loc.String(),
+		Weekdays:        input.Weekdays,
+	}
+	if input.BufferMinutes != nil {
+		req.BufferMinutes = *input.BufferMinutes
+	}
+	if input.WorkdayStart != nil {
+		req.WorkdayStart = *input.WorkdayStart
+	}
+	if input.WorkdayEnd != nil {
+		req.WorkdayEnd = *input.WorkdayEnd
+	}
 	if input.Limit != nil {
-		limit = *input.Limit
+		req.Limit = *input.Limit
 	}
-	hasMore := len(items) >= limit
 
-	slog.Info("Calendar items search completed", "total", len(summaries), "has_more", hasMore)
-	return &SearchResponse{
-		Items:   summaries,
-		Total:   len(summaries),
-		Limit:   input.Limit,
-		HasMore: hasMore,
+	slog.Debug("Finding free slots", "start", start, "end", end, "duration_minutes", input.DurationMinutes)
+	found, err := ct.service.FindFreeSlots(&userID, req)
+	if err != nil {
+		slog.Error("Failed to find free slots", "error", err)
+		return nil, err
+	}
+
+	slots := make([]FreeSlot, 0, len(found))
+	for _, slot := range found {
+		slots = append(slots, FreeSlot{Start: slot.Start, End: slot.End, Score: slot.Score})
+	}
+
+	slog.Info("Free slots found", "total", len(slots))
+	return &FindFreeSlotsResponse{
+		Slots:    slots,
+		Total:    len(slots),
+		TimeZone: req.TimeZone,
 	}, nil
 }
 
-func getUserID(ctx tool.Context) uint {
-	// TODO: extract user ID from context
-	return 2
+// conflictPolicy 工具默认拒绝与已有事件冲突的修改，由助手先征求用户同意
+func conflictPolicy(value *string) *calendar.ConflictPolicy {
+	policy := calendar.ConflictPolicyReject
+	if value != nil && *value != "" {
+		policy = calendar.ConflictPolicy(*value)
+	}
+	return &policy
+}
+
+// conflictResult 将冲突错误转换为未执行的操作结果，便于助手向用户确认后以 conflict_policy=allow 重试
+func conflictResult(err error, id *uint) (*OperationResult, bool) {
+	var conflictErr *calendar.ConflictError
+	if !errors.As(err, &conflictErr) {
+		return nil, false
+	}
+	return &OperationResult{
+		Success:   false,
+		Message:   "The time overlaps existing events, nothing was saved. Ask the user whether to double-book; if they agree, retry with conflict_policy \"allow\".",
+		ID:        id,
+		Conflicts: conflictErr.Conflicts,
+	}, true
+}
+
+// getUserID 当前对话所属的用户：ADK 会话的用户标识，由 Agent 服务的认证绑定到 JWT 中的用户
+func getUserID(ctx tool.Context) (uint, error) {
+	userID, err := session.ParseUserKey(ctx.UserID())
+	if err != nil {
+		slog.Warn("Calendar tool called without an authenticated user", "user_id", ctx.UserID())
+		return 0, err
+	}
+	return userID, nil
+}
+
+// location 用户的时区，没有设置时使用 UTC
+func (ct *calendarTools) location(userID uint) *time.Location {
+	loc := time.UTC
+	if ct.users != nil {
+		if profile, err := ct.users.GetUserProfile(userID); err == nil {
+			loc = profile.Location()
+		}
+	}
+	return loc
 }
 
 func isValidCalendarItemType(t calendar.CalendarItemType) bool {
@@ -351,22 +616,24 @@ func isValidCalendarItemType(t calendar.CalendarItemType) bool {
 		t == calendar.CalendarItemTypeJournal || t == calendar.CalendarItemTypeFreeBusy
 }
 
-func parseOptionalTime(timeStr *string) (*time.Time, error) {
+// parseOptionalTime 解析可选的时间参数，没有时区信息的值按 loc 解释
+func parseOptionalTime(timeStr *string, loc *time.Location) (*time.Time, error) {
 	if timeStr == nil {
 		return nil, nil
 	}
-	parsed, err := utils.ParseDateTime(*timeStr)
+	parsed, err := utils.ParseDateTimeIn(*timeStr, loc)
 	if err != nil {
 		return nil, err
 	}
 	return &parsed, nil
 }
 
-func convertTimeRangeInput(tr *TimeRange) (*calendar.TimeRange, error) {
+// convertTimeRangeInput 解析时间范围，没有时区信息的值按 loc 解释
+func convertTimeRangeInput(tr *TimeRange, loc *time.Location) (*calendar.TimeRange, error) {
 	var start, end *time.Time
 
 	if tr.Start != nil {
-		parsed, err := utils.ParseDateTime(*tr.Start)
+		parsed, err := utils.ParseDateTimeIn(*tr.Start, loc)
 		if err != nil {
 			return nil, err
 		}
@@ -374,7 +641,7 @@ func convertTimeRangeInput(tr *TimeRange) (*calendar.TimeRange, error) {
 	}
 
 	if tr.End != nil {
-		parsed, err := utils.ParseDateTime(*tr.End)
+		parsed, err := utils.ParseDateTimeIn(*tr.End, loc)
 		if err != nil {
 			return nil, err
 		}
@@ -411,6 +678,7 @@ func convertToDetailResponse(item *calendar.CalendarItem) *ItemDetail {
 		CreatedAt:    item.CreatedAt,
 		UpdatedAt:    item.UpdatedAt,
 		Alarms:       item.Alarms,
+		Attendees:    convertAttendees(item.Attendees),
 	}
 }
 
@@ -425,10 +693,32 @@ func convertToResponse(item *calendar.CalendarItem) *Item {
 		DtStart:         item.DtStart,
 		DtEnd:           item.DtEnd,
 		Due:             item.Due,
+		AllDay:          item.AllDay,
 		Completed:       item.Completed,
 		Status:          item.Status,
 		Priority:        item.Priority,
 		PercentComplete: item.PercentComplete,
 		Categories:      []string(item.Categories),
+		RecurrenceID:    item.RecurrenceID,
+		Highlights:      item.Highlights,
 	}
 }
+
+func convertAttendees(attendees []calendar.Attendee) []Attendee {
+	if len(attendees) == 0 {
+		return nil
+	}
+	result := make([]Attendee, 0, len(attendees))
+	for _, a := range attendees {
+		result = append(result, Attendee{
+			ID:       a.ID,
+			Address:  a.Address,
+			CN:       a.CN,
+			Role:     string(a.Role),
+			PartStat: string(a.PartStat),
+			RSVP:     a.RSVP,
+			CUType:   string(a.CUType),
+		})
+	}
+	return result
+}
@@ -16,28 +16,34 @@ type OperationResult struct {
 	Updated bool        `json:"updated,omitempty"`
 	Deleted bool        `json:"deleted,omitempty"`
 	Item    *ItemDetail `json:"item,omitempty"`
+	// Conflicts existing events that overlap the new time; with conflict_policy reject the item is not saved
+	Conflicts []calendar.Conflict `json:"conflicts,omitempty"`
 }
 
 // CreateRequest create calendar item request
 // Required fields (RFC 5545): type (VEVENT/VTODO/VJOURNAL/VFREEBUSY)
-//   - VEVENT: dtstart required, dtend or duration (not both)
+//   - VEVENT: dtstart required, dtend or duration (not both); an all-day event without either lasts one day
 //   - VTODO: dtstart or due
 //   - VJOURNAL: dtstart required
 //   - VFREEBUSY: both dtstart and dtend required
 //
 // Optional: uid (for idempotency), all other fields
-// Time format: RFC3339, e.g. "2024-01-15T14:30:00Z"
+// Time format: RFC3339, e.g. "2024-01-15T14:30:00+08:00"; times without an offset are in tzid (default: the user's time zone)
+// All-day items (birthdays, holidays): pass plain dates, e.g. dtstart "2024-05-06"; dtend is exclusive (the day after the last day)
+// conflict_policy defaults to reject: an overlapping VEVENT is not created and the conflicts are returned
 type CreateRequest struct {
 	UID             *string  `json:"uid,omitempty"`                                                 // 唯一标识符（可选，用于幂等性：如果提供且已存在则返回现有项）
 	Type            string   `json:"type" binding:"required,oneof=VEVENT VTODO VJOURNAL VFREEBUSY"` // 日历项类型（必填）
-	DtStart         *string  `json:"dtstart,omitempty"`                                             // 开始时间，RFC3339 格式，例如: "2024-01-15T14:30:00Z"
+	DtStart         *string  `json:"dtstart,omitempty"`                                             // 开始时间，RFC3339 格式，例如: "2024-01-15T14:30:00+08:00"
 	DtEnd           *string  `json:"dtend,omitempty"`                                               // 结束时间，RFC3339 格式
 	Due             *string  `json:"due,omitempty"`                                                 // 截止时间（VTODO），RFC3339 格式
+	TZID            *string  `json:"tzid,omitempty"`                                                // 时区（IANA 时区名），默认为用户的时区；没有时区信息的时间按该时区解释
+	AllDay          *bool    `json:"all_day,omitempty"`                                             // 全天日历项，dtstart 只有日期（"2024-05-06"）时默认为 true；dtend 为最后一天的次日
 	Duration        *string  `json:"duration,omitempty"`                                            // 持续时间（VEVENT），与 dtend 二选一，格式如 "PT1H30M"
 	Summary         *string  `json:"summary,omitempty"`                                             // 标题
 	Description     *string  `json:"description,omitempty"`                                         // 描述
 	Location        *string  `json:"location,omitempty"`                                            // 地点
-	Organizer       *string  `json:"organizer,omitempty"`                                           // 组织者
+	Organizer       *string  `json:"organizer,omitempty"`                                           // 组织者，例如 "mailto:zhangsan@example.com"
 	Status          *string  `json:"status,omitempty"`                                              // 状态
 	Priority        *int     `json:"priority,omitempty"`                                            // 优先级 (0-9)，仅 VTODO
 	PercentComplete *int     `json:"percent_complete,omitempty"`                                    // 完成百分比 (0-100)，仅 VTODO
@@ -51,6 +57,11 @@ type CreateRequest struct {
 	Resources       []string `json:"resources,omitempty"`                                           // 资源
 	URL             *string  `json:"url,omitempty"`                                                 // URL
 	Class           *string  `json:"class,omitempty"`                                               // 分类（PUBLIC/PRIVATE/CONFIDENTIAL）
+	Transp          *string  `json:"transp,omitempty"`                                              // 是否占用时间（OPAQUE/TRANSPARENT），TRANSPARENT 的事件不算冲突
+	ConflictPolicy  *string  `json:"conflict_policy,omitempty"`                                     // 时间冲突的处理方式：reject（默认）/ warn / allow
+	// 参与者：address 为邮箱，可选 cn（姓名）、role（CHAIR/REQ-PARTICIPANT/OPT-PARTICIPANT/NON-PARTICIPANT）、
+	// partstat（NEEDS-ACTION/ACCEPTED/DECLINED/TENTATIVE）、rsvp、cutype（INDIVIDUAL/GROUP/RESOURCE/ROOM）
+	Attendees []calendar.AttendeeRequest `json:"attendees,omitempty"`
 }
 
 // GetRequest get calendar item request
@@ -61,9 +72,44 @@ type GetRequest struct {
 }
 
 // UpdateRequest update calendar item request
+// Only the fields that are set are changed
+// For a recurring item, recurrence_id selects the occurrence (its original start time)
+// and scope selects which occurrences are changed: this (default), this_and_following or all
+// conflict_policy defaults to reject when the time changes, like CreateRequest
+// Time format: like CreateRequest; times without an offset are in tzid (default: the item's time zone, or the user's)
+// dtstart, dtend and due also accept plain dates ("2024-05-06") for all-day items
 type UpdateRequest struct {
-	ID uint `json:"id" binding:"required"`
-	calendar.UpdateCalendarItemRequest
+	ID              uint     `json:"id" binding:"required"`
+	RecurrenceID    *string  `json:"recurrence_id,omitempty"`    // 重复日历项实例的原始开始时间，RFC3339 格式
+	Scope           *string  `json:"scope,omitempty"`            // 修改范围：this / this_and_following / all
+	DtStart         *string  `json:"dtstart,omitempty"`          // 开始时间，RFC3339 格式，例如: "2024-01-15T14:30:00+08:00"
+	DtEnd           *string  `json:"dtend,omitempty"`            // 结束时间，RFC3339 格式
+	Due             *string  `json:"due,omitempty"`              // 截止时间（VTODO），RFC3339 格式
+	Completed       *string  `json:"completed,omitempty"`        // 完成时间（VTODO），RFC3339 格式
+	TZID            *string  `json:"tzid,omitempty"`             // 时区（IANA 时区名），不指定时保持原有时区
+	AllDay          *bool    `json:"all_day,omitempty"`          // 全天日历项，dtstart 只有日期时默认为 true
+	Duration        *string  `json:"duration,omitempty"`         // 持续时间（VEVENT），格式如 "PT1H30M"
+	Summary         *string  `json:"summary,omitempty"`          // 标题
+	Description     *string  `json:"description,omitempty"`      // 描述
+	Location        *string  `json:"location,omitempty"`         // 地点
+	Organizer       *string  `json:"organizer,omitempty"`        // 组织者
+	Status          *string  `json:"status,omitempty"`           // 状态
+	Priority        *int     `json:"priority,omitempty"`         // 优先级 (0-9)，仅 VTODO
+	PercentComplete *int     `json:"percent_complete,omitempty"` // 完成百分比 (0-100)，仅 VTODO
+	RRule           *string  `json:"rrule,omitempty"`            // 重复规则
+	ExDate          []string `json:"exdate,omitempty"`           // 排除日期
+	RDate           []string `json:"rdate,omitempty"`            // 重复日期
+	Categories      []string `json:"categories,omitempty"`       // 分类
+	Comment         *string  `json:"comment,omitempty"`          // 备注
+	Contact         *string  `json:"contact,omitempty"`          // 联系人
+	RelatedTo       *string  `json:"related_to,omitempty"`       // 关联项
+	Resources       []string `json:"resources,omitempty"`        // 资源
+	URL             *string  `json:"url,omitempty"`              // URL
+	Class           *string  `json:"class,omitempty"`            // 分类（PUBLIC/PRIVATE/CONFIDENTIAL）
+	Transp          *string  `json:"transp,omitempty"`           // 是否占用时间（OPAQUE/TRANSPARENT）
+	ConflictPolicy  *string  `json:"conflict_policy,omitempty"`  // 时间冲突的处理方式：reject（默认）/ warn / allow
+	// 参与者，为 nil 时不修改，空数组表示删除全部参与者；格式与 CreateRequest 相同
+	Attendees []calendar.AttendeeRequest `json:"attendees,omitempty"`
 }
 
 // DeleteRequest delete calendar item request
@@ -79,6 +125,8 @@ type SearchRequest struct {
 	Q       *string    `json:"q,omitempty"`
 	DtStart *TimeRange `json:"dtstart,omitempty"`
 	Limit   *int       `json:"limit,omitempty"`
+	Sort    *string    `json:"sort,omitempty"`   // 排序字段：dtstart、due、priority、last_modified、relevance
+	Cursor  *string    `json:"cursor,omitempty"` // 上一页返回的 next_cursor
 }
 
 // TimeRange time range filter for dtstart field
@@ -98,11 +146,14 @@ type Item struct {
 	DtStart         time.Time                 `json:"dtstart,omitempty"`
 	DtEnd           *time.Time                `json:"dtend,omitempty"`
 	Due             *time.Time                `json:"due,omitempty"`
+	AllDay          bool                      `json:"all_day,omitempty"` // all-day item: only the dates of dtstart/dtend/due matter, dtend is exclusive
 	Completed       *time.Time                `json:"completed,omitempty"`
 	Status          *string                   `json:"status,omitempty"`
 	Priority        *int                      `json:"priority,omitempty"`
 	PercentComplete *int                      `json:"percent_complete,omitempty"`
 	Categories      []string                  `json:"categories,omitempty"`
+	RecurrenceID    *time.Time                `json:"recurrence_id,omitempty"` // set on expanded occurrences of a recurring item
+	Highlights      map[string]string         `json:"highlights,omitempty"`    // search only: matched fields with <b></b> marked snippets
 }
 
 // ItemDetail calendar item detail response
@@ -126,12 +177,54 @@ type ItemDetail struct {
 	CreatedAt    time.Time         `json:"created_at,omitempty"`
 	UpdatedAt    time.Time         `json:"updated_at,omitempty"`
 	Alarms       []calendar.Valarm `json:"alarms,omitempty"`
+	Attendees    []Attendee        `json:"attendees,omitempty"`
+}
+
+// Attendee attendee of a calendar item with its participation status
+type Attendee struct {
+	ID       uint    `json:"id"`
+	Address  string  `json:"address"`
+	CN       *string `json:"cn,omitempty"`
+	Role     string  `json:"role,omitempty"`
+	PartStat string  `json:"partstat,omitempty"`
+	RSVP     bool    `json:"rsvp,omitempty"`
+	CUType   string  `json:"cutype,omitempty"`
 }
 
 // SearchResponse search calendar items response
 type SearchResponse struct {
-	Items   []*Item `json:"items"`
-	Total   int     `json:"total"`
-	Limit   *int    `json:"limit,omitempty"`
-	HasMore bool    `json:"has_more"`
+	Items      []*Item `json:"items"`
+	Total      int     `json:"total"`
+	Limit      *int    `json:"limit,omitempty"`
+	HasMore    bool    `json:"has_more"`
+	NextCursor string  `json:"next_cursor,omitempty"` // 传入下一次请求的 cursor 获取下一页
+}
+
+// FindFreeSlotsRequest find free slots request
+// Searches [start, end) for open slots of duration_minutes, skipping busy events (recurring events are expanded)
+// Working hours (workday_start/workday_end, weekdays) are interpreted in timezone; omit them to allow any time
+type FindFreeSlotsRequest struct {
+	Start           string   `json:"start"`                    // 查找窗口开始，RFC3339 格式
+	End             string   `json:"end"`                      // 查找窗口结束，RFC3339 格式
+	DurationMinutes int      `json:"duration_minutes"`         // 所需时长（分钟）
+	BufferMinutes   *int     `json:"buffer_minutes,omitempty"` // 与已有事件之间至少间隔的分钟数，默认 0
+	TimeZone        *string  `json:"timezone,omitempty"`       // 工作时间使用的时区，默认为用户的时区
+	WorkdayStart    *string  `json:"workday_start,omitempty"`  // 每天可用时间的开始，格式 HH:MM，例如 "09:00"
+	WorkdayEnd      *string  `json:"workday_end,omitempty"`    // 每天可用时间的结束，格式 HH:MM，例如 "18:00"
+	Weekdays        []string `json:"weekdays,omitempty"`       // 可用的星期：MO TU WE TH FR SA SU
+	Limit           *int     `json:"limit,omitempty"`          // 返回数量，默认 5，最多 20
+}
+
+// FreeSlot candidate slot, higher score is better (0-1)
+type FreeSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Score float64   `json:"score"`
+}
+
+// FindFreeSlotsResponse find free slots response, slots are ranked best first
+type FindFreeSlotsResponse struct {
+	Slots    []FreeSlot `json:"slots"`
+	Total    int        `json:"total"`
+	TimeZone string     `json:"timezone"`
 }
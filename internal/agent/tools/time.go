@@ -5,6 +5,9 @@ import (
 	"log/slog"
 	"time"
 
+	"github.com/galilio/otter/internal/common/utils"
+	"github.com/galilio/otter/internal/session"
+	"github.com/galilio/otter/internal/user"
 	"github.com/google/jsonschema-go/jsonschema"
 	"google.golang.org/adk/tool"
 	"google.golang.org/adk/tool/functiontool"
@@ -21,11 +24,30 @@ type GetCurrentTimeResponse struct {
 	Unix     int64  `json:"unix"`
 }
 
-func getCurrentTime(ctx tool.Context, input GetCurrentTimeRequest) (GetCurrentTimeResponse, error) {
-	// 默认时区为 Asia/Shanghai
+type ResolveDateTimeRequest struct {
+	Expression string `json:"expression"`
+	TimeZone   string `json:"timezone,omitempty"`
+}
+
+type ResolveDateTimeResponse struct {
+	Kind     string `json:"kind"`
+	Start    string `json:"start"`
+	End      string `json:"end,omitempty"`
+	AllDay   bool   `json:"all_day"`
+	RRule    string `json:"rrule,omitempty"`
+	TimeZone string `json:"time_zone"`
+}
+
+type timeTools struct {
+	users user.Service
+	now   func() time.Time
+}
+
+func (tt *timeTools) getCurrentTime(ctx tool.Context, input GetCurrentTimeRequest) (GetCurrentTimeResponse, error) {
+	// 默认为用户的时区
 	timezone := input.TimeZone
 	if timezone == "" {
-		timezone = "Asia/Shanghai"
+		timezone = tt.userTimezone(ctx)
 	}
 
 	// 加载时区
@@ -35,7 +57,7 @@ func getCurrentTime(ctx tool.Context, input GetCurrentTimeRequest) (GetCurrentTi
 	}
 
 	// 获取当前时间
-	now := time.Now().In(loc)
+	now := tt.now().In(loc)
 
 	// 格式化时间和日期
 	timeStr := now.Format("15:04:05")
@@ -52,18 +74,76 @@ func getCurrentTime(ctx tool.Context, input GetCurrentTimeRequest) (GetCurrentTi
 	}, nil
 }
 
-func NewTimeTools() ([]tool.Tool, error) {
+// resolveDateTime 按当前时间解析自然语言的时间表达式，全天的结果只返回日期
+func (tt *timeTools) resolveDateTime(ctx tool.Context, input ResolveDateTimeRequest) (ResolveDateTimeResponse, error) {
+	timezone := input.TimeZone
+	if timezone == "" {
+		timezone = tt.userTimezone(ctx)
+	}
+	loc, err := time.LoadLocation(timezone)
+	if err != nil {
+		return ResolveDateTimeResponse{}, fmt.Errorf("无效的时区: %w", err)
+	}
+
+	resolved, err := utils.ParseNaturalDateTime(input.Expression, tt.now(), loc)
+	if err != nil {
+		return ResolveDateTimeResponse{}, err
+	}
+
+	format := time.RFC3339
+	if resolved.AllDay {
+		format = time.DateOnly
+	}
+	resp := ResolveDateTimeResponse{
+		Kind:     string(resolved.Kind),
+		Start:    resolved.Start.Format(format),
+		AllDay:   resolved.AllDay,
+		RRule:    resolved.RRule,
+		TimeZone: timezone,
+	}
+	if resolved.End != nil {
+		resp.End = resolved.End.Format(format)
+	}
+
+	slog.Debug("解析时间表达式", "expression", input.Expression, "timezone", timezone, "kind", resp.Kind, "start", resp.Start)
+	return resp, nil
+}
+
+// userTimezone 当前对话所属用户的时区，没有设置时使用 UTC
+func (tt *timeTools) userTimezone(ctx tool.Context) string {
+	if tt.users == nil {
+		return time.UTC.String()
+	}
+	userID, err := session.ParseUserKey(ctx.UserID())
+	if err != nil {
+		return time.UTC.String()
+	}
+	profile, err := tt.users.GetUserProfile(userID)
+	if err != nil {
+		return time.UTC.String()
+	}
+	return profile.Location().String()
+}
+
+// NewTimeTools 创建时间工具，userService 用于取得用户的时区（为 nil 时使用 UTC）
+func NewTimeTools(userService user.Service) ([]tool.Tool, error) {
+	return newTimeTools(userService, time.Now)
+}
+
+// newTimeTools 创建时间工具，now 返回当前时间
+func newTimeTools(userService user.Service, now func() time.Time) ([]tool.Tool, error) {
+	tt := &timeTools{users: userService, now: now}
 	tools := []tool.Tool{}
 
 	timeTool, err := functiontool.New(functiontool.Config{
 		Name:        "get_current_time",
-		Description: "Get the current time and date in the specified timezone. Default timezone is Asia/Shanghai if not specified.",
+		Description: "Get the current time and date in the specified timezone. Defaults to the user's time zone if not specified.",
 		InputSchema: &jsonschema.Schema{
 			Type: "object",
 			Properties: map[string]*jsonschema.Schema{
 				"timezone": {
 					Type:        "string",
-					Description: "Timezone name (e.g., Asia/Shanghai, America/New_York, UTC). Default is Asia/Shanghai.",
+					Description: "Timezone name (e.g., Asia/Shanghai, America/New_York, UTC). Default is the user's time zone.",
 				},
 			},
 		},
@@ -89,12 +169,69 @@ func NewTimeTools() ([]tool.Tool, error) {
 			},
 			Required: []string{"time", "date", "time_zone", "unix"},
 		},
-	}, getCurrentTime)
+	}, tt.getCurrentTime)
 	if err != nil {
 		slog.Error("Failed to create get_current_time tool", "error", err)
 		return nil, err
 	}
 	tools = append(tools, timeTool)
 
+	resolveTool, err := functiontool.New(functiontool.Config{
+		Name: "resolve_datetime",
+		Description: "Resolve a natural-language date/time expression in Chinese or English (e.g. 下周三下午三点, 月底, 每隔一周的周五, " +
+			"in 2 hours, the first Monday of next month, every weekday at 9am) against the current time. " +
+			"Returns an instant, an all-day date, a range or a recurrence rule. Weeks start on Monday.",
+		InputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"expression": {
+					Type:        "string",
+					Description: "The date/time expression as the user said it, without the surrounding sentence (e.g. 明天下午3点到5点).",
+				},
+				"timezone": {
+					Type:        "string",
+					Description: "Timezone name (e.g., Asia/Shanghai, America/New_York, UTC). Default is the user's time zone.",
+				},
+			},
+			Required: []string{"expression"},
+		},
+		OutputSchema: &jsonschema.Schema{
+			Type: "object",
+			Properties: map[string]*jsonschema.Schema{
+				"kind": {
+					Type:        "string",
+					Enum:        []any{"instant", "date", "range", "recurrence"},
+					Description: "instant: a point in time; date: a whole day; range: a span of time; recurrence: a repeating rule",
+				},
+				"start": {
+					Type:        "string",
+					Description: "Start time in RFC3339 format, or YYYY-MM-DD when all_day is true. For a recurrence, the first occurrence.",
+				},
+				"end": {
+					Type:        "string",
+					Description: "Exclusive end in the same format as start (absent for instants). For a recurrence, the end of the first occurrence.",
+				},
+				"all_day": {
+					Type:        "boolean",
+					Description: "Whether only the dates are meaningful",
+				},
+				"rrule": {
+					Type:        "string",
+					Description: "RFC 5545 RRULE (without the RRULE: prefix) when kind is recurrence",
+				},
+				"time_zone": {
+					Type:        "string",
+					Description: "Timezone used",
+				},
+			},
+			Required: []string{"kind", "start", "all_day", "time_zone"},
+		},
+	}, tt.resolveDateTime)
+	if err != nil {
+		slog.Error("Failed to create resolve_datetime tool", "error", err)
+		return nil, err
+	}
+	tools = append(tools, resolveTool)
+
 	return tools, nil
 }
@@ -0,0 +1,80 @@
+package tools
+
+import (
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/common/utils"
+	"github.com/galilio/otter/internal/session"
+	"github.com/galilio/otter/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/adk/tool"
+)
+
+// stubUserService 返回固定的用户配置
+type stubUserService struct {
+	user.Service
+	profile *user.UserProfile
+}
+
+func (s *stubUserService) GetUserProfile(userID uint) (*user.UserProfile, error) {
+	return s.profile, nil
+}
+
+// stubToolContext 只提供工具用到的用户
+type stubToolContext struct {
+	tool.Context
+	userID string
+}
+
+func (c *stubToolContext) UserID() string { return c.userID }
+
+// runTool 像 Agent 一样以 JSON 参数调用指定名称的工具
+func runTool(t *testing.T, tools []tool.Tool, name string, args map[string]any) (map[string]any, error) {
+	for _, tl := range tools {
+		if tl.Name() == name {
+			runnable, ok := tl.(interface {
+				Run(ctx tool.Context, args any) (map[string]any, error)
+			})
+			require.True(t, ok)
+			return runnable.Run(&stubToolContext{userID: session.UserKey(7)}, args)
+		}
+	}
+	t.Fatalf("tool %s not found", name)
+	return nil, nil
+}
+
+// TestResolveDateTime 测试按当前时间和用户的时区解析时间表达式
+func TestResolveDateTime(t *testing.T) {
+	now := time.Date(2025, 3, 12, 10, 30, 0, 0, time.UTC) // 周三，上海 18:30
+	users := &stubUserService{profile: &user.UserProfile{Timezone: "Asia/Shanghai"}}
+	tools, err := newTimeTools(users, func() time.Time { return now })
+	require.NoError(t, err)
+
+	got, err := runTool(t, tools, "resolve_datetime", map[string]any{"expression": "明天下午3点"})
+	require.NoError(t, err)
+	assert.Equal(t, "instant", got["kind"])
+	assert.Equal(t, "2025-03-13T15:00:00+08:00", got["start"])
+	assert.Equal(t, "Asia/Shanghai", got["time_zone"])
+
+	got, err = runTool(t, tools, "resolve_datetime", map[string]any{"expression": "tonight", "timezone": "UTC"})
+	require.NoError(t, err)
+	assert.Equal(t, "range", got["kind"])
+	assert.Equal(t, "2025-03-12T18:00:00Z", got["start"])
+	assert.Equal(t, "2025-03-13T00:00:00Z", got["end"])
+
+	got, err = runTool(t, tools, "resolve_datetime", map[string]any{"expression": "下周三"})
+	require.NoError(t, err)
+	assert.Equal(t, "date", got["kind"])
+	assert.Equal(t, "2025-03-19", got["start"])
+	assert.Equal(t, true, got["all_day"])
+
+	_, err = runTool(t, tools, "resolve_datetime", map[string]any{"expression": "101年后"})
+	assert.ErrorIs(t, err, utils.ErrUnrecognizedDateTime)
+
+	got, err = runTool(t, tools, "get_current_time", map[string]any{})
+	require.NoError(t, err)
+	assert.Equal(t, "18:30:00", got["time"])
+	assert.Equal(t, "2025-03-12", got["date"])
+}
@@ -66,6 +66,22 @@ func (m *MockUserService) Login(req *user.LoginRequest) (*user.User, error) {
 	return args.Get(0).(*user.User), args.Error(1)
 }
 
+func (m *MockUserService) GetUserProfile(userID uint) (*user.UserProfile, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.UserProfile), args.Error(1)
+}
+
+func (m *MockUserService) UpdateUserProfile(userID uint, req *user.UpdateUserProfileRequest) (*user.UserProfile, error) {
+	args := m.Called(userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.UserProfile), args.Error(1)
+}
+
 // MockRefreshTokenRepository 模拟刷新token仓库
 type MockRefreshTokenRepository struct {
 	mock.Mock
@@ -0,0 +1,830 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/user"
+	"github.com/gin-gonic/gin"
+)
+
+// Prefix CalDAV 资源的 URL 前缀
+const Prefix = "/dav"
+
+// calendarName 每个用户只有一个日历集合
+const calendarName = "default"
+
+// maxBodySize 请求体大小上限
+const maxBodySize = 10 << 20
+
+// Methods CalDAV 需要注册的 HTTP 方法
+var Methods = []string{
+	http.MethodOptions, http.MethodGet, http.MethodHead, http.MethodPut, http.MethodDelete,
+	"PROPFIND", "PROPPATCH", "REPORT",
+}
+
+// allowHeader OPTIONS 响应的 Allow 头
+const allowHeader = "OPTIONS, GET, HEAD, PUT, DELETE, PROPFIND, PROPPATCH, REPORT"
+
+// currentUserKey 上下文中保存已认证用户的键
+const currentUserKey = "caldav_user"
+
+// resourceKind 资源类型
+type resourceKind int
+
+const (
+	resourceRoot       resourceKind = iota // /dav/
+	resourcePrincipal                      // /dav/principals/{username}/
+	resourceHome                           // /dav/calendars/{username}/
+	resourceCollection                     // /dav/calendars/{username}/default/
+	resourceObject                         // /dav/calendars/{username}/default/{name}，name 由客户端决定，默认为 {uid}.ics
+)
+
+// resource 请求路径对应的资源
+type resource struct {
+	kind     resourceKind
+	username string
+	name     string // 日历对象的资源名称
+}
+
+type Handler struct {
+	calendarService calendar.Service
+	userService     user.Service
+}
+
+func NewHandler(calendarService calendar.Service, userService user.Service) *Handler {
+	return &Handler{calendarService: calendarService, userService: userService}
+}
+
+// BasicAuth CalDAV 客户端使用 HTTP Basic 认证（用户名或邮箱 + 密码）
+func (h *Handler) BasicAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		username, password, ok := c.Request.BasicAuth()
+		if !ok {
+			unauthorized(c)
+			return
+		}
+
+		u, err := h.userService.Login(&user.LoginRequest{Username: username, Password: password})
+		if err != nil {
+			unauthorized(c)
+			return
+		}
+
+		c.Set("user_id", u.ID)
+		c.Set("username", u.Username)
+		c.Set(currentUserKey, u)
+		c.Next()
+	}
+}
+
+func unauthorized(c *gin.Context) {
+	c.Header("WWW-Authenticate", `Basic realm="Otter CalDAV", charset="UTF-8"`)
+	c.AbortWithStatus(http.StatusUnauthorized)
+}
+
+// WellKnown 服务发现（RFC 6764）
+// GET/PROPFIND /.well-known/caldav
+func (h *Handler) WellKnown(c *gin.Context) {
+	c.Redirect(http.StatusMovedPermanently, Prefix+"/")
+}
+
+// ServeDAV 处理 /dav/ 下的所有 CalDAV 请求
+func (h *Handler) ServeDAV(c *gin.Context) {
+	u := c.MustGet(currentUserKey).(*user.User)
+
+	res, ok := parsePath(c.Request.URL.EscapedPath())
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	if res.kind != resourceRoot && res.username != u.Username {
+		c.Status(http.StatusForbidden)
+		return
+	}
+
+	switch c.Request.Method {
+	case http.MethodOptions:
+		c.Header("DAV", "1, 3, calendar-access")
+		c.Header("Allow", allowHeader)
+		c.Status(http.StatusOK)
+	case "PROPFIND":
+		h.propfind(c, u, res)
+	case "PROPPATCH":
+		h.proppatch(c, res)
+	case "REPORT":
+		h.report(c, u, res)
+	case http.MethodGet, http.MethodHead:
+		h.get(c, u, res)
+	case http.MethodPut:
+		h.put(c, u, res)
+	case http.MethodDelete:
+		h.delete(c, u, res)
+	default:
+		c.Header("Allow", allowHeader)
+		c.Status(http.StatusMethodNotAllowed)
+	}
+}
+
+// propfind PROPFIND：Depth 为 0 时只返回资源本身，否则同时返回直接子资源
+func (h *Handler) propfind(c *gin.Context, u *user.User, res *resource) {
+	body, err := readBody(c)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	req := propfindRequest{}
+	if len(bytes.TrimSpace(body)) > 0 {
+		if err := xml.Unmarshal(body, &req); err != nil {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+	}
+	if req.Prop == nil && req.PropName == nil {
+		req.AllProp = &struct{}{}
+	}
+
+	self, err := h.properties(u, res, nil, req.Prop.contains(propCalendarData))
+	if err != nil {
+		if errors.Is(err, calendar.ErrCalendarItemNotFound) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	ms := &multistatus{}
+	ms.Responses = append(ms.Responses, propResponse(hrefFor(u.Username, res), self, &req))
+
+	if c.GetHeader("Depth") != "0" {
+		children, err := h.children(u, res, req.Prop.contains(propCalendarData))
+		if err != nil {
+			c.Status(http.StatusInternalServerError)
+			return
+		}
+		for _, child := range children {
+			ms.Responses = append(ms.Responses, propResponse(child.href, child.props, &req))
+		}
+	}
+
+	writeMultistatus(c, ms)
+}
+
+// childResource 子资源及其属性
+type childResource struct {
+	href  string
+	props []property
+}
+
+// children 返回资源的直接子资源
+func (h *Handler) children(u *user.User, res *resource, withData bool) ([]childResource, error) {
+	switch res.kind {
+	case resourceHome:
+		collection := &resource{kind: resourceCollection, username: u.Username}
+		props, err := h.properties(u, collection, nil, withData)
+		if err != nil {
+			return nil, err
+		}
+		return []childResource{{href: hrefFor(u.Username, collection), props: props}}, nil
+	case resourceCollection:
+		objects, err := h.calendarService.ListCalendarObjects(&u.ID, nil)
+		if err != nil {
+			return nil, err
+		}
+		return h.objectResources(u, objects, withData)
+	default:
+		return nil, nil
+	}
+}
+
+// objectResources 返回日历对象资源及其属性
+func (h *Handler) objectResources(u *user.User, objects []*calendar.CalendarObject, withData bool) ([]childResource, error) {
+	result := make([]childResource, 0, len(objects))
+	for _, object := range objects {
+		res := &resource{kind: resourceObject, username: u.Username, name: object.Name}
+		props, err := h.properties(u, res, object, withData)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, childResource{href: hrefFor(u.Username, res), props: props})
+	}
+	return result, nil
+}
+
+// properties 返回资源的所有属性；calendar-data 只在 withData 时生成
+func (h *Handler) properties(u *user.User, res *resource, object *calendar.CalendarObject, withData bool) ([]property, error) {
+	principal := hrefProperty(propCurrentUserPrincipal, principalHref(u.Username))
+
+	switch res.kind {
+	case resourceRoot:
+		return []property{
+			{Name: propResourceType, Value: element(xml.Name{Space: nsDAV, Local: "collection"}, nil, "")},
+			principal,
+		}, nil
+
+	case resourcePrincipal:
+		props := []property{
+			{Name: propResourceType, Value: element(xml.Name{Space: nsDAV, Local: "principal"}, nil, "")},
+			textProperty(propDisplayName, displayName(u)),
+			principal,
+			hrefProperty(propPrincipalURL, principalHref(u.Username)),
+			hrefProperty(propCalendarHomeSet, homeHref(u.Username)),
+		}
+		if u.Email != "" {
+			props = append(props, hrefProperty(propCalendarUserAddressSet, "mailto:"+u.Email))
+		}
+		return props, nil
+
+	case resourceHome:
+		return []property{
+			{Name: propResourceType, Value: element(xml.Name{Space: nsDAV, Local: "collection"}, nil, "")},
+			principal,
+			hrefProperty(propOwner, principalHref(u.Username)),
+		}, nil
+
+	case resourceCollection:
+		token, err := h.calendarService.GetCalendarSyncToken(&u.ID)
+		if err != nil {
+			return nil, err
+		}
+		return []property{
+			{Name: propResourceType, Value: element(xml.Name{Space: nsDAV, Local: "collection"}, nil, "") +
+				element(xml.Name{Space: nsCalDAV, Local: "calendar"}, nil, "")},
+			textProperty(propDisplayName, "Otter"),
+			principal,
+			hrefProperty(propOwner, principalHref(u.Username)),
+			{Name: propSupportedCalendarCompSet, Value: compElements("VEVENT", "VTODO", "VJOURNAL")},
+			{Name: propSupportedReportSet, Value: supportedReports()},
+			{Name: propCurrentUserPrivilegeSet, Value: privileges("read", "write", "write-content", "write-properties", "bind", "unbind", "read-current-user-privilege-set")},
+			textProperty(propSyncToken, token),
+			textProperty(propGetCTag, token),
+		}, nil
+
+	case resourceObject:
+		if object == nil {
+			var err error
+			if object, err = h.calendarService.GetCalendarObject(&u.ID, res.name); err != nil {
+				return nil, err
+			}
+		}
+		props := []property{
+			{Name: propResourceType},
+			textProperty(propGetETag, object.ETag),
+			textProperty(propGetContentType, objectContentType(object)),
+			textProperty(propGetLastModified, object.LastModified.UTC().Format(http.TimeFormat)),
+		}
+		if withData {
+			data, err := object.ICalendar()
+			if err != nil {
+				return nil, err
+			}
+			props = append(props, textProperty(propCalendarData, data))
+		}
+		return props, nil
+	}
+	return nil, nil
+}
+
+// propResponse 按请求筛选属性：找到的属性返回 200，不存在的返回 404
+// allprop 不包括 calendar-data（RFC 4791 9.6）；propname 只返回属性名
+func propResponse(href string, available []property, req *propfindRequest) *response {
+	resp := &response{Href: href}
+
+	if req.AllProp != nil || req.PropName != nil {
+		var found []property
+		for _, p := range available {
+			if p.Name == propCalendarData {
+				continue
+			}
+			if req.PropName != nil {
+				p.Value = ""
+			}
+			found = append(found, p)
+		}
+		resp.Propstats = append(resp.Propstats, propstat{Props: found, Status: http.StatusOK})
+		return resp
+	}
+
+	var found, missing []property
+	for _, name := range req.Prop {
+		p, ok := findProperty(available, name)
+		if ok {
+			found = append(found, p)
+		} else {
+			missing = append(missing, property{Name: name})
+		}
+	}
+	if len(found) > 0 {
+		resp.Propstats = append(resp.Propstats, propstat{Props: found, Status: http.StatusOK})
+	}
+	if len(missing) > 0 {
+		resp.Propstats = append(resp.Propstats, propstat{Props: missing, Status: http.StatusNotFound})
+	}
+	return resp
+}
+
+func findProperty(props []property, name xml.Name) (property, bool) {
+	for _, p := range props {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return property{}, false
+}
+
+// proppatch PROPPATCH：不支持修改属性，所有属性返回 403
+func (h *Handler) proppatch(c *gin.Context, res *resource) {
+	body, err := readBody(c)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	var update propertyUpdate
+	if err := xml.Unmarshal(body, &update); err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	var props []property
+	for _, set := range update.Set {
+		for _, name := range set.Prop {
+			props = append(props, property{Name: name})
+		}
+	}
+	for _, remove := range update.Remove {
+		for _, name := range remove.Prop {
+			props = append(props, property{Name: name})
+		}
+	}
+
+	u := c.MustGet(currentUserKey).(*user.User)
+	resp := &response{Href: hrefFor(u.Username, res)}
+	if len(props) > 0 {
+		resp.Propstats = []propstat{{Props: props, Status: http.StatusForbidden}}
+	}
+	writeMultistatus(c, &multistatus{Responses: []*response{resp}})
+}
+
+// report REPORT：calendar-query、calendar-multiget、sync-collection，只支持日历集合
+func (h *Handler) report(c *gin.Context, u *user.User, res *resource) {
+	if res.kind != resourceCollection {
+		writeError(c, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "supported-report"})
+		return
+	}
+
+	body, err := readBody(c)
+	if err != nil {
+		c.Status(http.StatusBadRequest)
+		return
+	}
+	report, err := decodeReport(body)
+	if err != nil {
+		if errors.Is(err, errInvalidXML) {
+			c.Status(http.StatusBadRequest)
+			return
+		}
+		writeError(c, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "supported-report"})
+		return
+	}
+
+	switch r := report.(type) {
+	case *calendarQuery:
+		h.calendarQuery(c, u, r)
+	case *calendarMultiget:
+		h.calendarMultiget(c, u, r)
+	case *syncCollection:
+		h.syncCollection(c, u, r)
+	}
+}
+
+// calendarQuery calendar-query 报告：按组件类型和时间范围过滤
+func (h *Handler) calendarQuery(c *gin.Context, u *user.User, query *calendarQuery) {
+	filter, err := objectFilter(&query.Filter.CompFilter)
+	if err != nil {
+		writeError(c, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "valid-filter"})
+		return
+	}
+
+	objects, err := h.calendarService.ListCalendarObjects(&u.ID, filter)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	req := &propfindRequest{AllProp: query.AllProp, Prop: query.Prop}
+	h.writeObjects(c, u, objects, req, nil)
+}
+
+// objectFilter 将 comp-filter 转换为日历对象查询条件
+func objectFilter(filter *compFilter) (*calendar.CalendarObjectFilter, error) {
+	result := &calendar.CalendarObjectFilter{}
+	if filter.Name == "" || len(filter.CompFilters) == 0 {
+		return result, nil
+	}
+
+	comp := filter.CompFilters[0]
+	itemType := calendar.CalendarItemType(strings.ToUpper(comp.Name))
+	result.Type = &itemType
+	if comp.TimeRange == nil {
+		return result, nil
+	}
+
+	if comp.TimeRange.Start != "" {
+		start, err := time.Parse("20060102T150405Z", comp.TimeRange.Start)
+		if err != nil {
+			return nil, err
+		}
+		result.Start = &start
+	}
+	if comp.TimeRange.End != "" {
+		end, err := time.Parse("20060102T150405Z", comp.TimeRange.End)
+		if err != nil {
+			return nil, err
+		}
+		result.End = &end
+	}
+	return result, nil
+}
+
+// calendarMultiget calendar-multiget 报告：按 href 获取日历对象，不存在的返回 404
+func (h *Handler) calendarMultiget(c *gin.Context, u *user.User, multiget *calendarMultiget) {
+	names := make([]string, 0, len(multiget.Hrefs))
+	hrefs := map[string]string{}
+	var missing []string
+	for _, href := range multiget.Hrefs {
+		res, ok := parseHref(href)
+		if !ok || res.kind != resourceObject || res.username != u.Username {
+			missing = append(missing, href)
+			continue
+		}
+		names = append(names, res.name)
+		hrefs[res.name] = href
+	}
+
+	objects, err := h.calendarService.GetCalendarObjects(&u.ID, names)
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	found := map[string]bool{}
+	for _, object := range objects {
+		found[object.Name] = true
+	}
+	for _, name := range names {
+		if !found[name] {
+			missing = append(missing, hrefs[name])
+		}
+	}
+
+	req := &propfindRequest{AllProp: multiget.AllProp, Prop: multiget.Prop}
+	h.writeObjects(c, u, objects, req, &multistatus{Responses: notFoundResponses(missing)})
+}
+
+// syncCollection sync-collection 报告：返回同步令牌之后修改和删除的日历对象
+func (h *Handler) syncCollection(c *gin.Context, u *user.User, sync *syncCollection) {
+	result, err := h.calendarService.SyncCalendarObjects(&u.ID, sync.SyncToken)
+	if err != nil {
+		if errors.Is(err, calendar.ErrInvalidSyncToken) {
+			writeError(c, http.StatusForbidden, xml.Name{Space: nsDAV, Local: "valid-sync-token"})
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	var deleted []string
+	for _, name := range result.Deleted {
+		deleted = append(deleted, objectHref(u.Username, name))
+	}
+
+	req := &propfindRequest{Prop: sync.Prop}
+	h.writeObjects(c, u, result.Changed, req, &multistatus{
+		Responses: notFoundResponses(deleted),
+		SyncToken: result.Token,
+	})
+}
+
+// writeObjects 输出日历对象的属性，ms 中已有的响应（不存在或已删除的资源）放在最后
+func (h *Handler) writeObjects(c *gin.Context, u *user.User, objects []*calendar.CalendarObject, req *propfindRequest, ms *multistatus) {
+	if req.AllProp == nil && req.Prop == nil {
+		req.AllProp = &struct{}{}
+	}
+	resources, err := h.objectResources(u, objects, req.Prop.contains(propCalendarData))
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	result := &multistatus{}
+	for _, r := range resources {
+		result.Responses = append(result.Responses, propResponse(r.href, r.props, req))
+	}
+	if ms != nil {
+		result.Responses = append(result.Responses, ms.Responses...)
+		result.SyncToken = ms.SyncToken
+	}
+	writeMultistatus(c, result)
+}
+
+func notFoundResponses(hrefs []string) []*response {
+	responses := make([]*response, 0, len(hrefs))
+	for _, href := range hrefs {
+		responses = append(responses, &response{Href: href, Status: http.StatusNotFound})
+	}
+	return responses
+}
+
+// get GET/HEAD：获取日历对象的 iCalendar 数据
+func (h *Handler) get(c *gin.Context, u *user.User, res *resource) {
+	if res.kind != resourceObject {
+		c.Header("Allow", allowHeader)
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+
+	object, err := h.calendarService.GetCalendarObject(&u.ID, res.name)
+	if err != nil {
+		if errors.Is(err, calendar.ErrCalendarItemNotFound) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	data, err := object.ICalendar()
+	if err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+
+	c.Header("ETag", object.ETag)
+	c.Header("Last-Modified", object.LastModified.UTC().Format(http.TimeFormat))
+	if match := c.GetHeader("If-None-Match"); match != "" && etagMatches(match, object.ETag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, objectContentType(object), []byte(data))
+}
+
+// put PUT：创建或替换日历对象，支持 If-Match 和 If-None-Match: *
+// 服务器会重新序列化数据，因此响应中不返回 ETag（RFC 4791 5.3.4）
+func (h *Handler) put(c *gin.Context, u *user.User, res *resource) {
+	if res.kind != resourceObject {
+		c.Header("Allow", allowHeader)
+		c.Status(http.StatusMethodNotAllowed)
+		return
+	}
+	if !h.checkPreconditions(c, u, res) {
+		return
+	}
+
+	body, err := readBody(c)
+	if err != nil {
+		if errors.Is(err, errBodyTooLarge) {
+			c.Status(http.StatusRequestEntityTooLarge)
+			return
+		}
+		c.Status(http.StatusBadRequest)
+		return
+	}
+
+	created, err := h.calendarService.PutCalendarObject(&u.ID, res.name, bytes.NewReader(body))
+	if err != nil {
+		switch {
+		case errors.Is(err, calendar.ErrUIDConflict):
+			writeError(c, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "no-uid-conflict"})
+		case errors.Is(err, calendar.ErrInvalidInput):
+			writeError(c, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "valid-calendar-object-resource"})
+		case errors.Is(err, calendar.ErrInvalidICalendar):
+			writeError(c, http.StatusForbidden, xml.Name{Space: nsCalDAV, Local: "valid-calendar-data"})
+		default:
+			c.Status(http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if created {
+		c.Status(http.StatusCreated)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// delete DELETE：删除日历对象
+func (h *Handler) delete(c *gin.Context, u *user.User, res *resource) {
+	if res.kind != resourceObject {
+		c.Status(http.StatusForbidden)
+		return
+	}
+	if !h.checkPreconditions(c, u, res) {
+		return
+	}
+
+	if err := h.calendarService.DeleteCalendarObject(&u.ID, res.name); err != nil {
+		if errors.Is(err, calendar.ErrCalendarItemNotFound) {
+			c.Status(http.StatusNotFound)
+			return
+		}
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// checkPreconditions 检查 If-Match / If-None-Match，不满足时返回 412
+func (h *Handler) checkPreconditions(c *gin.Context, u *user.User, res *resource) bool {
+	ifMatch := c.GetHeader("If-Match")
+	ifNoneMatch := c.GetHeader("If-None-Match")
+	if ifMatch == "" && ifNoneMatch == "" {
+		return true
+	}
+
+	object, err := h.calendarService.GetCalendarObject(&u.ID, res.name)
+	if err != nil && !errors.Is(err, calendar.ErrCalendarItemNotFound) {
+		c.Status(http.StatusInternalServerError)
+		return false
+	}
+
+	exists := object != nil
+	ok := true
+	if ifMatch != "" {
+		ok = exists && (strings.TrimSpace(ifMatch) == "*" || etagMatches(ifMatch, object.ETag))
+	}
+	if ifNoneMatch != "" && exists {
+		ok = ok && strings.TrimSpace(ifNoneMatch) != "*" && !etagMatches(ifNoneMatch, object.ETag)
+	}
+	if !ok {
+		c.Status(http.StatusPreconditionFailed)
+	}
+	return ok
+}
+
+// etagMatches 检查 ETag 列表（逗号分隔，可以带 W/ 前缀）中是否包含 etag
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// parsePath 解析请求路径（URL 编码形式，以便资源名称中可以包含 /）
+func parsePath(escapedPath string) (*resource, bool) {
+	rest, ok := strings.CutPrefix(escapedPath, Prefix)
+	if !ok {
+		return nil, false
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(rest, "/"), "/") {
+		if segment == "" {
+			continue
+		}
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			return nil, false
+		}
+		segments = append(segments, unescaped)
+	}
+
+	switch {
+	case len(segments) == 0:
+		return &resource{kind: resourceRoot}, true
+	case len(segments) == 2 && segments[0] == "principals":
+		return &resource{kind: resourcePrincipal, username: segments[1]}, true
+	case len(segments) == 2 && segments[0] == "calendars":
+		return &resource{kind: resourceHome, username: segments[1]}, true
+	case len(segments) == 3 && segments[0] == "calendars" && segments[2] == calendarName:
+		return &resource{kind: resourceCollection, username: segments[1]}, true
+	case len(segments) == 4 && segments[0] == "calendars" && segments[2] == calendarName:
+		name := segments[3]
+		if strings.TrimSuffix(name, ".ics") == "" {
+			return nil, false
+		}
+		return &resource{kind: resourceObject, username: segments[1], name: name}, true
+	}
+	return nil, false
+}
+
+// parseHref 解析报告请求中的 href（可以是绝对 URL 或路径）
+func parseHref(href string) (*resource, bool) {
+	u, err := url.Parse(strings.TrimSpace(href))
+	if err != nil {
+		return nil, false
+	}
+	return parsePath(u.EscapedPath())
+}
+
+func principalHref(username string) string {
+	return Prefix + "/principals/" + url.PathEscape(username) + "/"
+}
+
+func homeHref(username string) string {
+	return Prefix + "/calendars/" + url.PathEscape(username) + "/"
+}
+
+func collectionHref(username string) string {
+	return homeHref(username) + calendarName + "/"
+}
+
+func objectHref(username, name string) string {
+	return collectionHref(username) + url.PathEscape(name)
+}
+
+// hrefFor 返回资源的规范 href
+func hrefFor(username string, res *resource) string {
+	switch res.kind {
+	case resourcePrincipal:
+		return principalHref(username)
+	case resourceHome:
+		return homeHref(username)
+	case resourceCollection:
+		return collectionHref(username)
+	case resourceObject:
+		return objectHref(username, res.name)
+	default:
+		return Prefix + "/"
+	}
+}
+
+// displayName 用户的显示名称
+func displayName(u *user.User) string {
+	if name := strings.TrimSpace(u.FirstName + " " + u.LastName); name != "" {
+		return name
+	}
+	return u.Username
+}
+
+// objectContentType 日历对象的 Content-Type
+func objectContentType(object *calendar.CalendarObject) string {
+	return "text/calendar; charset=utf-8; component=" + string(object.Type)
+}
+
+func compElements(names ...string) string {
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(element(xml.Name{Space: nsCalDAV, Local: "comp"}, map[string]string{"name": name}, ""))
+	}
+	return b.String()
+}
+
+func supportedReports() string {
+	reports := []xml.Name{
+		{Space: nsCalDAV, Local: "calendar-query"},
+		{Space: nsCalDAV, Local: "calendar-multiget"},
+		{Space: nsDAV, Local: "sync-collection"},
+	}
+	var b strings.Builder
+	for _, report := range reports {
+		reportElement := element(xml.Name{Space: nsDAV, Local: "report"}, nil, element(report, nil, ""))
+		b.WriteString(element(xml.Name{Space: nsDAV, Local: "supported-report"}, nil, reportElement))
+	}
+	return b.String()
+}
+
+func privileges(names ...string) string {
+	var b strings.Builder
+	for _, name := range names {
+		b.WriteString(element(xml.Name{Space: nsDAV, Local: "privilege"}, nil, element(xml.Name{Space: nsDAV, Local: name}, nil, "")))
+	}
+	return b.String()
+}
+
+var errBodyTooLarge = errors.New("请求体过大")
+
+// readBody 读取请求体，超过 maxBodySize 时返回 errBodyTooLarge
+func readBody(c *gin.Context) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(body) > maxBodySize {
+		return nil, errBodyTooLarge
+	}
+	return body, nil
+}
+
+func writeMultistatus(c *gin.Context, ms *multistatus) {
+	var buf bytes.Buffer
+	if err := ms.writeTo(&buf); err != nil {
+		c.Status(http.StatusInternalServerError)
+		return
+	}
+	c.Data(http.StatusMultiStatus, "application/xml; charset=utf-8", buf.Bytes())
+}
+
+func writeError(c *gin.Context, status int, condition xml.Name) {
+	c.Data(status, "application/xml; charset=utf-8", []byte(errorBody(condition)))
+}
@@ -0,0 +1,372 @@
+package caldav
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/user"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCalendarService 模拟日历服务，只实现 CalDAV 用到的方法
+type mockCalendarService struct {
+	calendar.Service
+	mock.Mock
+}
+
+func (m *mockCalendarService) ListCalendarObjects(userID *uint, filter *calendar.CalendarObjectFilter) ([]*calendar.CalendarObject, error) {
+	args := m.Called(userID, filter)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*calendar.CalendarObject), args.Error(1)
+}
+
+func (m *mockCalendarService) GetCalendarObjects(userID *uint, names []string) ([]*calendar.CalendarObject, error) {
+	args := m.Called(userID, names)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*calendar.CalendarObject), args.Error(1)
+}
+
+func (m *mockCalendarService) GetCalendarObject(userID *uint, name string) (*calendar.CalendarObject, error) {
+	args := m.Called(userID, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*calendar.CalendarObject), args.Error(1)
+}
+
+func (m *mockCalendarService) PutCalendarObject(userID *uint, name string, r io.Reader) (bool, error) {
+	args := m.Called(userID, name, r)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockCalendarService) DeleteCalendarObject(userID *uint, name string) error {
+	args := m.Called(userID, name)
+	return args.Error(0)
+}
+
+func (m *mockCalendarService) GetCalendarSyncToken(userID *uint) (string, error) {
+	args := m.Called(userID)
+	return args.String(0), args.Error(1)
+}
+
+func (m *mockCalendarService) SyncCalendarObjects(userID *uint, syncToken string) (*calendar.CalendarSyncResult, error) {
+	args := m.Called(userID, syncToken)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*calendar.CalendarSyncResult), args.Error(1)
+}
+
+// mockUserService 模拟用户服务，只实现登录
+type mockUserService struct {
+	user.Service
+}
+
+func (m *mockUserService) Login(req *user.LoginRequest) (*user.User, error) {
+	if req.Username == "alice" && req.Password == "secret" {
+		return &user.User{ID: 1, Username: "alice", Email: "alice@example.com"}, nil
+	}
+	return nil, user.ErrInvalidPassword
+}
+
+var testUserID = uint(1)
+
+func setupTestRouter(calendarService calendar.Service) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	handler := NewHandler(calendarService, &mockUserService{})
+	for _, method := range Methods {
+		router.Handle(method, Prefix+"/*path", handler.BasicAuth(), handler.ServeDAV)
+	}
+	return router
+}
+
+func doRequest(router *gin.Engine, method, path, body string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.SetBasicAuth("alice", "secret")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func testObject(uid string) *calendar.CalendarObject {
+	return &calendar.CalendarObject{
+		UID:   uid,
+		Name:  uid + ".ics",
+		Type:  calendar.CalendarItemTypeEvent,
+		Items: []*calendar.CalendarItem{{ID: 1, UID: uid, Type: calendar.CalendarItemTypeEvent}},
+		ETag:  `"etag-` + uid + `"`,
+	}
+}
+
+// TestBasicAuth 测试未认证和密码错误时返回 401
+func TestBasicAuth(t *testing.T) {
+	router := setupTestRouter(&mockCalendarService{})
+
+	req := httptest.NewRequest("PROPFIND", "/dav/", nil)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Basic")
+
+	req = httptest.NewRequest("PROPFIND", "/dav/", nil)
+	req.SetBasicAuth("alice", "wrong")
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+// TestOptions 测试 OPTIONS 返回 DAV 能力
+func TestOptions(t *testing.T) {
+	router := setupTestRouter(&mockCalendarService{})
+
+	w := doRequest(router, http.MethodOptions, "/dav/calendars/alice/default/", "", nil)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Header().Get("DAV"), "calendar-access")
+	assert.Contains(t, w.Header().Get("Allow"), "REPORT")
+}
+
+// TestPropfind_Principal 测试通过 current-user-principal 和 calendar-home-set 发现日历
+func TestPropfind_Principal(t *testing.T) {
+	router := setupTestRouter(&mockCalendarService{})
+	body := `<?xml version="1.0"?><d:propfind xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+<d:prop><d:current-user-principal/><c:calendar-home-set/><x:color xmlns:x="http://example.com/ns/"/></d:prop></d:propfind>`
+
+	w := doRequest(router, "PROPFIND", "/dav/principals/alice/", body, map[string]string{"Depth": "0"})
+
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "<d:current-user-principal><d:href>/dav/principals/alice/</d:href></d:current-user-principal>")
+	assert.Contains(t, w.Body.String(), "<c:calendar-home-set><d:href>/dav/calendars/alice/</d:href></c:calendar-home-set>")
+	assert.Contains(t, w.Body.String(), `<color xmlns="http://example.com/ns/"/>`)
+	assert.Contains(t, w.Body.String(), "HTTP/1.1 404 Not Found")
+}
+
+// TestPropfind_OtherUser 测试访问其他用户的资源
+func TestPropfind_OtherUser(t *testing.T) {
+	router := setupTestRouter(&mockCalendarService{})
+
+	w := doRequest(router, "PROPFIND", "/dav/calendars/bob/default/", "", nil)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+// TestPropfind_Collection 测试 Depth: 1 列出日历对象
+func TestPropfind_Collection(t *testing.T) {
+	service := &mockCalendarService{}
+	router := setupTestRouter(service)
+	service.On("GetCalendarSyncToken", &testUserID).Return("urn:otter:sync:42", nil)
+	service.On("ListCalendarObjects", &testUserID, (*calendar.CalendarObjectFilter)(nil)).
+		Return([]*calendar.CalendarObject{testObject("event-1")}, nil)
+	body := `<d:propfind xmlns:d="DAV:" xmlns:cs="http://calendarserver.org/ns/"><d:prop><d:resourcetype/><cs:getctag/><d:getetag/></d:prop></d:propfind>`
+
+	w := doRequest(router, "PROPFIND", "/dav/calendars/alice/default/", body, map[string]string{"Depth": "1"})
+
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "<d:resourcetype><d:collection/><c:calendar/></d:resourcetype>")
+	assert.Contains(t, w.Body.String(), "<cs:getctag>urn:otter:sync:42</cs:getctag>")
+	assert.Contains(t, w.Body.String(), "<d:href>/dav/calendars/alice/default/event-1.ics</d:href>")
+	assert.Contains(t, w.Body.String(), `<d:getetag>&#34;etag-event-1&#34;</d:getetag>`)
+	service.AssertExpectations(t)
+}
+
+// TestReport_Multiget 测试 calendar-multiget：返回 calendar-data，不存在的 href 返回 404
+func TestReport_Multiget(t *testing.T) {
+	service := &mockCalendarService{}
+	router := setupTestRouter(service)
+	service.On("GetCalendarObjects", &testUserID, []string{"event-1.ics", "missing.ics"}).
+		Return([]*calendar.CalendarObject{testObject("event-1")}, nil)
+	body := `<c:calendar-multiget xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+<d:prop><d:getetag/><c:calendar-data/></d:prop>
+<d:href>/dav/calendars/alice/default/event-1.ics</d:href>
+<d:href>https://otter.example.com/dav/calendars/alice/default/missing.ics</d:href>
+</c:calendar-multiget>`
+
+	w := doRequest(router, "REPORT", "/dav/calendars/alice/default/", body, nil)
+
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "<c:calendar-data>BEGIN:VCALENDAR")
+	assert.Contains(t, w.Body.String(), "UID:event-1")
+	assert.Contains(t, w.Body.String(), "<d:href>https://otter.example.com/dav/calendars/alice/default/missing.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>")
+	service.AssertExpectations(t)
+}
+
+// TestReport_CalendarQuery 测试 calendar-query 的组件类型和时间范围过滤
+func TestReport_CalendarQuery(t *testing.T) {
+	service := &mockCalendarService{}
+	router := setupTestRouter(service)
+	service.On("ListCalendarObjects", &testUserID, mock.MatchedBy(func(f *calendar.CalendarObjectFilter) bool {
+		return *f.Type == calendar.CalendarItemTypeEvent && f.Start.Equal(utc(2025, 1, 1)) && f.End.Equal(utc(2025, 2, 1))
+	})).Return([]*calendar.CalendarObject{testObject("event-1")}, nil)
+	body := `<c:calendar-query xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">
+<d:prop><d:getetag/></d:prop>
+<c:filter><c:comp-filter name="VCALENDAR"><c:comp-filter name="VEVENT">
+<c:time-range start="20250101T000000Z" end="20250201T000000Z"/>
+</c:comp-filter></c:comp-filter></c:filter>
+</c:calendar-query>`
+
+	w := doRequest(router, "REPORT", "/dav/calendars/alice/default/", body, map[string]string{"Depth": "1"})
+
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "event-1.ics")
+	assert.NotContains(t, w.Body.String(), "calendar-data")
+	service.AssertExpectations(t)
+}
+
+// TestReport_SyncCollection 测试 sync-collection：已删除的对象返回 404，并返回新的同步令牌
+func TestReport_SyncCollection(t *testing.T) {
+	service := &mockCalendarService{}
+	router := setupTestRouter(service)
+	service.On("SyncCalendarObjects", &testUserID, "urn:otter:sync:1").Return(&calendar.CalendarSyncResult{
+		Token:   "urn:otter:sync:2",
+		Changed: []*calendar.CalendarObject{testObject("event-1")},
+		Deleted: []string{"gone.ics"},
+	}, nil)
+	service.On("SyncCalendarObjects", &testUserID, "bogus").Return(nil, calendar.ErrInvalidSyncToken)
+	body := `<d:sync-collection xmlns:d="DAV:"><d:sync-token>%s</d:sync-token><d:sync-level>1</d:sync-level><d:prop><d:getetag/></d:prop></d:sync-collection>`
+
+	w := doRequest(router, "REPORT", "/dav/calendars/alice/default/", strings.Replace(body, "%s", "urn:otter:sync:1", 1), nil)
+
+	assert.Equal(t, http.StatusMultiStatus, w.Code)
+	assert.Contains(t, w.Body.String(), "event-1.ics")
+	assert.Contains(t, w.Body.String(), "<d:href>/dav/calendars/alice/default/gone.ics</d:href><d:status>HTTP/1.1 404 Not Found</d:status>")
+	assert.Contains(t, w.Body.String(), "<d:sync-token>urn:otter:sync:2</d:sync-token></d:multistatus>")
+
+	w = doRequest(router, "REPORT", "/dav/calendars/alice/default/", strings.Replace(body, "%s", "bogus", 1), nil)
+
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "<d:valid-sync-token/>")
+	service.AssertExpectations(t)
+}
+
+// TestPut 测试 PUT：新建返回 201，If-None-Match: * 对已存在的对象返回 412，无效数据返回 403
+func TestPut(t *testing.T) {
+	service := &mockCalendarService{}
+	router := setupTestRouter(service)
+	service.On("PutCalendarObject", &testUserID, "new.ics", mock.Anything).Return(true, nil)
+	service.On("GetCalendarObject", &testUserID, "event-1.ics").Return(testObject("event-1"), nil)
+	service.On("PutCalendarObject", &testUserID, "broken.ics", mock.Anything).Return(false, calendar.ErrInvalidICalendar)
+	service.On("PutCalendarObject", &testUserID, "copy.ics", mock.Anything).Return(false, calendar.ErrUIDConflict)
+
+	w := doRequest(router, http.MethodPut, "/dav/calendars/alice/default/new.ics", "BEGIN:VCALENDAR", nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = doRequest(router, http.MethodPut, "/dav/calendars/alice/default/event-1.ics", "", map[string]string{"If-None-Match": "*"})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = doRequest(router, http.MethodPut, "/dav/calendars/alice/default/event-1.ics", "", map[string]string{"If-Match": `"stale"`})
+	assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+
+	w = doRequest(router, http.MethodPut, "/dav/calendars/alice/default/broken.ics", "x", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "valid-calendar-data")
+
+	w = doRequest(router, http.MethodPut, "/dav/calendars/alice/default/copy.ics", "x", nil)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+	assert.Contains(t, w.Body.String(), "no-uid-conflict")
+	service.AssertExpectations(t)
+}
+
+// TestPut_ClientResourceName 测试客户端自己决定的资源名称（与 UID 不同）：PUT 和 GET 使用同一个 href
+func TestPut_ClientResourceName(t *testing.T) {
+	service := &mockCalendarService{}
+	router := setupTestRouter(service)
+	object := testObject("040000008200E00074C5B7101A82E008")
+	object.Name = "3f9c2b1e-7d4a.ics"
+	service.On("PutCalendarObject", &testUserID, "3f9c2b1e-7d4a.ics", mock.Anything).Return(true, nil)
+	service.On("GetCalendarObject", &testUserID, "3f9c2b1e-7d4a.ics").Return(object, nil)
+	service.On("GetCalendarSyncToken", &testUserID).Return("urn:otter:sync:42", nil)
+	service.On("ListCalendarObjects", &testUserID, (*calendar.CalendarObjectFilter)(nil)).
+		Return([]*calendar.CalendarObject{object}, nil)
+
+	w := doRequest(router, http.MethodPut, "/dav/calendars/alice/default/3f9c2b1e-7d4a.ics", "BEGIN:VCALENDAR", nil)
+	assert.Equal(t, http.StatusCreated, w.Code)
+
+	w = doRequest(router, http.MethodGet, "/dav/calendars/alice/default/3f9c2b1e-7d4a.ics", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), "UID:040000008200E00074C5B7101A82E008")
+
+	w = doRequest(router, "PROPFIND", "/dav/calendars/alice/default/", "", map[string]string{"Depth": "1"})
+	assert.Contains(t, w.Body.String(), "<d:href>/dav/calendars/alice/default/3f9c2b1e-7d4a.ics</d:href>")
+	service.AssertExpectations(t)
+}
+
+// TestMkcalendar_NotAdvertised 测试不支持 MKCALENDAR：OPTIONS 不声明，请求不会被处理
+func TestMkcalendar_NotAdvertised(t *testing.T) {
+	router := setupTestRouter(&mockCalendarService{})
+
+	w := doRequest(router, http.MethodOptions, "/dav/calendars/alice/work/", "", nil)
+	assert.NotContains(t, w.Header().Get("Allow"), "MKCALENDAR")
+	assert.NotContains(t, Methods, "MKCALENDAR")
+
+	w = doRequest(router, "MKCALENDAR", "/dav/calendars/alice/work/", "", nil)
+	assert.NotEqual(t, http.StatusCreated, w.Code)
+}
+
+// TestGetAndDelete 测试 GET 返回 iCalendar 和 ETag，DELETE 不存在的对象返回 404
+func TestGetAndDelete(t *testing.T) {
+	service := &mockCalendarService{}
+	router := setupTestRouter(service)
+	service.On("GetCalendarObject", &testUserID, "event-1.ics").Return(testObject("event-1"), nil)
+	service.On("DeleteCalendarObject", &testUserID, "event-1.ics").Return(nil)
+	service.On("DeleteCalendarObject", &testUserID, "missing.ics").Return(calendar.ErrCalendarItemNotFound)
+	service.On("GetCalendarObject", &testUserID, "error.ics").Return(nil, errors.New("数据库错误"))
+
+	w := doRequest(router, http.MethodGet, "/dav/calendars/alice/default/event-1.ics", "", nil)
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"etag-event-1"`, w.Header().Get("ETag"))
+	assert.True(t, strings.HasPrefix(w.Body.String(), "BEGIN:VCALENDAR\r\n"))
+
+	w = doRequest(router, http.MethodDelete, "/dav/calendars/alice/default/event-1.ics", "", map[string]string{"If-Match": `"etag-event-1"`})
+	assert.Equal(t, http.StatusNoContent, w.Code)
+
+	w = doRequest(router, http.MethodDelete, "/dav/calendars/alice/default/missing.ics", "", nil)
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	w = doRequest(router, http.MethodGet, "/dav/calendars/alice/default/error.ics", "", nil)
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+	service.AssertExpectations(t)
+}
+
+// TestParsePath 测试解析资源路径
+func TestParsePath(t *testing.T) {
+	cases := map[string]resource{
+		"/dav":                          {kind: resourceRoot},
+		"/dav/principals/alice/":        {kind: resourcePrincipal, username: "alice"},
+		"/dav/calendars/alice":          {kind: resourceHome, username: "alice"},
+		"/dav/calendars/alice/default/": {kind: resourceCollection, username: "alice"},
+		"/dav/calendars/alice/default/a%2Fb%40c.ics": {kind: resourceObject, username: "alice", name: "a/b@c.ics"},
+	}
+	for path, want := range cases {
+		res, ok := parsePath(path)
+		require.True(t, ok, path)
+		assert.Equal(t, want, *res, path)
+	}
+
+	for _, path := range []string{"/api/v1", "/dav/other", "/dav/calendars/alice/work/", "/dav/calendars/alice/default/.ics"} {
+		_, ok := parsePath(path)
+		assert.False(t, ok, path)
+	}
+
+	assert.Equal(t, "/dav/calendars/alice/default/a%2Fb@c.ics", objectHref("alice", "a/b@c.ics"))
+}
+
+func utc(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
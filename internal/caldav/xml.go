@@ -0,0 +1,287 @@
+package caldav
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// XML 命名空间
+const (
+	nsDAV            = "DAV:"
+	nsCalDAV         = "urn:ietf:params:xml:ns:caldav"
+	nsCalendarServer = "http://calendarserver.org/ns/"
+)
+
+// nsPrefixes 输出 XML 时使用的命名空间前缀
+var nsPrefixes = map[string]string{
+	nsDAV:            "d",
+	nsCalDAV:         "c",
+	nsCalendarServer: "cs",
+}
+
+var errInvalidXML = errors.New("无效的 XML 请求")
+
+// 常用属性名
+var (
+	propResourceType             = xml.Name{Space: nsDAV, Local: "resourcetype"}
+	propDisplayName              = xml.Name{Space: nsDAV, Local: "displayname"}
+	propCurrentUserPrincipal     = xml.Name{Space: nsDAV, Local: "current-user-principal"}
+	propPrincipalURL             = xml.Name{Space: nsDAV, Local: "principal-URL"}
+	propOwner                    = xml.Name{Space: nsDAV, Local: "owner"}
+	propCurrentUserPrivilegeSet  = xml.Name{Space: nsDAV, Local: "current-user-privilege-set"}
+	propSupportedReportSet       = xml.Name{Space: nsDAV, Local: "supported-report-set"}
+	propSyncToken                = xml.Name{Space: nsDAV, Local: "sync-token"}
+	propGetETag                  = xml.Name{Space: nsDAV, Local: "getetag"}
+	propGetContentType           = xml.Name{Space: nsDAV, Local: "getcontenttype"}
+	propGetLastModified          = xml.Name{Space: nsDAV, Local: "getlastmodified"}
+	propCalendarHomeSet          = xml.Name{Space: nsCalDAV, Local: "calendar-home-set"}
+	propCalendarUserAddressSet   = xml.Name{Space: nsCalDAV, Local: "calendar-user-address-set"}
+	propCalendarData             = xml.Name{Space: nsCalDAV, Local: "calendar-data"}
+	propSupportedCalendarCompSet = xml.Name{Space: nsCalDAV, Local: "supported-calendar-component-set"}
+	propGetCTag                  = xml.Name{Space: nsCalendarServer, Local: "getctag"}
+)
+
+// propNames 请求中的属性名列表（prop 元素的子元素，子元素的内容被忽略）
+type propNames []xml.Name
+
+// UnmarshalXML 实现 xml.Unmarshaler 接口
+func (p *propNames) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			*p = append(*p, t.Name)
+			if err := d.Skip(); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// contains 是否请求了该属性
+func (p propNames) contains(name xml.Name) bool {
+	for _, n := range p {
+		if n == name {
+			return true
+		}
+	}
+	return false
+}
+
+// propfindRequest PROPFIND 请求体（RFC 4918 14.20），请求体为空时等同于 allprop
+type propfindRequest struct {
+	XMLName  xml.Name  `xml:"DAV: propfind"`
+	AllProp  *struct{} `xml:"DAV: allprop"`
+	PropName *struct{} `xml:"DAV: propname"`
+	Prop     propNames `xml:"DAV: prop"`
+}
+
+// propertyUpdate PROPPATCH 请求体（RFC 4918 14.19）
+type propertyUpdate struct {
+	XMLName xml.Name `xml:"DAV: propertyupdate"`
+	Set     []struct {
+		Prop propNames `xml:"DAV: prop"`
+	} `xml:"DAV: set"`
+	Remove []struct {
+		Prop propNames `xml:"DAV: prop"`
+	} `xml:"DAV: remove"`
+}
+
+// calendarQuery calendar-query 报告（RFC 4791 7.8）
+type calendarQuery struct {
+	XMLName xml.Name  `xml:"urn:ietf:params:xml:ns:caldav calendar-query"`
+	AllProp *struct{} `xml:"DAV: allprop"`
+	Prop    propNames `xml:"DAV: prop"`
+	Filter  struct {
+		CompFilter compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+	} `xml:"urn:ietf:params:xml:ns:caldav filter"`
+}
+
+// compFilter 组件过滤条件，只支持组件类型和 time-range，其它条件被忽略
+type compFilter struct {
+	Name        string       `xml:"name,attr"`
+	TimeRange   *timeRange   `xml:"urn:ietf:params:xml:ns:caldav time-range"`
+	CompFilters []compFilter `xml:"urn:ietf:params:xml:ns:caldav comp-filter"`
+}
+
+// timeRange 时间范围过滤（UTC 的 DATE-TIME 值）
+type timeRange struct {
+	Start string `xml:"start,attr"`
+	End   string `xml:"end,attr"`
+}
+
+// calendarMultiget calendar-multiget 报告（RFC 4791 7.9）
+type calendarMultiget struct {
+	XMLName xml.Name  `xml:"urn:ietf:params:xml:ns:caldav calendar-multiget"`
+	AllProp *struct{} `xml:"DAV: allprop"`
+	Prop    propNames `xml:"DAV: prop"`
+	Hrefs   []string  `xml:"DAV: href"`
+}
+
+// syncCollection sync-collection 报告（RFC 6578 3.2）
+type syncCollection struct {
+	XMLName   xml.Name  `xml:"DAV: sync-collection"`
+	SyncToken string    `xml:"DAV: sync-token"`
+	SyncLevel string    `xml:"DAV: sync-level"`
+	Prop      propNames `xml:"DAV: prop"`
+}
+
+// decodeReport 根据根元素解析 REPORT 请求体，返回 *calendarQuery、*calendarMultiget 或 *syncCollection
+func decodeReport(body []byte) (interface{}, error) {
+	root, err := rootElement(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var report interface{}
+	switch root {
+	case xml.Name{Space: nsCalDAV, Local: "calendar-query"}:
+		report = &calendarQuery{}
+	case xml.Name{Space: nsCalDAV, Local: "calendar-multiget"}:
+		report = &calendarMultiget{}
+	case xml.Name{Space: nsDAV, Local: "sync-collection"}:
+		report = &syncCollection{}
+	default:
+		return nil, fmt.Errorf("不支持的报告 %s %s", root.Space, root.Local)
+	}
+	if err := xml.Unmarshal(body, report); err != nil {
+		return nil, fmt.Errorf("%w: %v", errInvalidXML, err)
+	}
+	return report, nil
+}
+
+// rootElement 返回 XML 文档的根元素名
+func rootElement(body []byte) (xml.Name, error) {
+	d := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		tok, err := d.Token()
+		if err != nil {
+			return xml.Name{}, fmt.Errorf("%w: %v", errInvalidXML, err)
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			return start.Name, nil
+		}
+	}
+}
+
+// property 属性及其已编码的 XML 内容
+type property struct {
+	Name  xml.Name
+	Value string
+}
+
+// textProperty 创建文本内容的属性
+func textProperty(name xml.Name, text string) property {
+	return property{Name: name, Value: escapeXML(text)}
+}
+
+// hrefProperty 创建内容为 href 列表的属性
+func hrefProperty(name xml.Name, hrefs ...string) property {
+	var b strings.Builder
+	for _, href := range hrefs {
+		b.WriteString(element(xml.Name{Space: nsDAV, Local: "href"}, nil, escapeXML(href)))
+	}
+	return property{Name: name, Value: b.String()}
+}
+
+// propstat 状态相同的一组属性
+type propstat struct {
+	Props  []property
+	Status int
+}
+
+// response multistatus 中单个资源的结果：属性列表，或只有状态（例如已删除的资源）
+type response struct {
+	Href      string
+	Propstats []propstat
+	Status    int
+}
+
+// multistatus 207 Multi-Status 响应体（RFC 4918 13）
+type multistatus struct {
+	Responses []*response
+	SyncToken string
+}
+
+// writeTo 输出 multistatus XML
+func (ms *multistatus) writeTo(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString(xml.Header)
+	b.WriteString(`<d:multistatus xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav" xmlns:cs="http://calendarserver.org/ns/">`)
+	for _, resp := range ms.Responses {
+		b.WriteString("<d:response>")
+		b.WriteString(element(xml.Name{Space: nsDAV, Local: "href"}, nil, escapeXML(resp.Href)))
+		for _, ps := range resp.Propstats {
+			b.WriteString("<d:propstat><d:prop>")
+			for _, p := range ps.Props {
+				b.WriteString(element(p.Name, nil, p.Value))
+			}
+			b.WriteString("</d:prop>")
+			b.WriteString(statusElement(ps.Status))
+			b.WriteString("</d:propstat>")
+		}
+		if resp.Status != 0 {
+			b.WriteString(statusElement(resp.Status))
+		}
+		b.WriteString("</d:response>")
+	}
+	if ms.SyncToken != "" {
+		b.WriteString(element(propSyncToken, nil, escapeXML(ms.SyncToken)))
+	}
+	b.WriteString("</d:multistatus>")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// statusElement 输出 status 元素
+func statusElement(code int) string {
+	return element(xml.Name{Space: nsDAV, Local: "status"}, nil, fmt.Sprintf("HTTP/1.1 %d %s", code, http.StatusText(code)))
+}
+
+// errorBody DAV:error 响应体，说明未满足的前置条件（RFC 4918 16）
+func errorBody(condition xml.Name) string {
+	return xml.Header +
+		`<d:error xmlns:d="DAV:" xmlns:c="urn:ietf:params:xml:ns:caldav">` +
+		element(condition, nil, "") +
+		`</d:error>`
+}
+
+// element 输出 XML 元素，内容为空时输出自闭合标签；未知命名空间在元素上声明
+func element(name xml.Name, attrs map[string]string, inner string) string {
+	var b strings.Builder
+	tag := name.Local
+	if prefix, ok := nsPrefixes[name.Space]; ok {
+		tag = prefix + ":" + name.Local
+	}
+
+	b.WriteString("<" + tag)
+	if _, ok := nsPrefixes[name.Space]; !ok && name.Space != "" {
+		b.WriteString(` xmlns="` + escapeXML(name.Space) + `"`)
+	}
+	for k, v := range attrs {
+		b.WriteString(" " + k + `="` + escapeXML(v) + `"`)
+	}
+	if inner == "" {
+		b.WriteString("/>")
+		return b.String()
+	}
+	b.WriteString(">" + inner + "</" + tag + ">")
+	return b.String()
+}
+
+// escapeXML 转义 XML 文本
+func escapeXML(s string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
@@ -0,0 +1,123 @@
+package calendar
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// alarmLookahead 查找重复日历项下一次提醒的最大范围
+const alarmLookahead = 2 * 366 * 24 * time.Hour
+
+// AlarmTrigger 提醒的一次触发
+type AlarmTrigger struct {
+	At         time.Time // 触发时刻
+	Occurrence time.Time // 对应实例的开始时间（非重复日历项为 DTSTART）
+	Repeat     int       // 第几次重复，0 表示首次触发
+}
+
+// IsEndRelated 相对触发时间是否相对于结束时间（TRIGGER;RELATED=END）
+func (alarm *Valarm) IsEndRelated() bool {
+	related, _ := alarm.XProperty["TRIGGER-RELATED"].(string)
+	return strings.EqualFold(related, "END")
+}
+
+// repeats 返回重复次数和间隔（RFC 5545 3.8.6.2：REPEAT 和 DURATION 必须同时出现）
+func (alarm *Valarm) repeats() (int, time.Duration) {
+	if alarm.RepeatCount == nil || *alarm.RepeatCount <= 0 || alarm.Duration == nil {
+		return 0, 0
+	}
+	interval, err := ParseDuration(*alarm.Duration)
+	if err != nil || interval <= 0 {
+		return 0, 0
+	}
+	return *alarm.RepeatCount, interval
+}
+
+// NextAlarmTrigger 计算提醒在 after 之后（不含）的下一次触发，没有时返回 nil
+// 相对触发时间基于每个实例的开始时间（RELATED=END 时为结束时间，VTODO 的结束时间为 DUE），
+// 只有 DUE 没有 DTSTART 的 VTODO 总是基于 DUE；
+// overridden 为已有例外实例的 RECURRENCE-ID，这些实例的提醒由例外实例自己的 VALARM 负责
+func NextAlarmTrigger(item *CalendarItem, alarm *Valarm, after time.Time, overridden []time.Time) (*AlarmTrigger, error) {
+	count, interval := alarm.repeats()
+	first := func(base, occurrence time.Time) *AlarmTrigger {
+		for k := 0; k <= count; k++ {
+			if at := base.Add(time.Duration(k) * interval); at.After(after) {
+				return &AlarmTrigger{At: at, Occurrence: occurrence, Repeat: k}
+			}
+		}
+		return nil
+	}
+
+	// 没有 DTSTART 的 VTODO 以 DUE 作为实例的时间
+	dueOnly := item.Type == CalendarItemTypeTodo && item.DtStart.IsZero() && item.Due != nil
+
+	trigger := strings.TrimSpace(alarm.Trigger)
+	offset, err := ParseDuration(trigger)
+	if err != nil {
+		at, isDate, err := parseDateTimeValue(trigger, time.UTC)
+		if err != nil || isDate {
+			return nil, fmt.Errorf("%w: 无效的提醒触发时间 %q", ErrInvalidInput, alarm.Trigger)
+		}
+		if dueOnly {
+			return first(at, *item.Due), nil
+		}
+		return first(at, item.DtStart), nil
+	}
+	if dueOnly {
+		return first(item.Due.Add(offset), *item.Due), nil
+	}
+	if alarm.IsEndRelated() {
+		offset += item.OccurrenceDuration()
+	}
+
+	if !item.IsRecurring() {
+		return first(item.DtStart.Add(offset), item.DtStart), nil
+	}
+
+	// 实例开始时间 s 的最后一次触发为 s+offset+count*interval，只需要查找此后还有触发的实例
+	from := after.Add(-offset - time.Duration(count)*interval)
+	for window := 24 * time.Hour; ; window *= 2 {
+		if window > alarmLookahead {
+			window = alarmLookahead
+		}
+		starts, err := item.OccurrenceStarts(from, from.Add(window))
+		if err != nil {
+			return nil, err
+		}
+
+		var next *AlarmTrigger
+		for _, s := range starts {
+			if next != nil && s.Add(offset).After(next.At) {
+				break
+			}
+			if containsTime(overridden, s) {
+				continue
+			}
+			if t := first(s.Add(offset), s); t != nil && (next == nil || t.At.Before(next.At)) {
+				next = t
+			}
+		}
+		if next != nil || window == alarmLookahead {
+			return next, nil
+		}
+	}
+}
+
+// OccurrenceAt 返回开始时间为 start 的实例，非重复日历项返回自身
+func (item *CalendarItem) OccurrenceAt(start time.Time) *CalendarItem {
+	if !item.IsRecurring() {
+		return item
+	}
+	return newOccurrence(item, start)
+}
+
+// containsTime 时间列表中是否有与 t 相同的时刻
+func containsTime(times []time.Time, t time.Time) bool {
+	for _, v := range times {
+		if v.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
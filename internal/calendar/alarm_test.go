@@ -0,0 +1,109 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestNextAlarmTrigger_Relative 测试相对触发时间和重复
+func TestNextAlarmTrigger_Relative(t *testing.T) {
+	dtEnd := utcTime(2025, 1, 6, 10, 0)
+	item := &CalendarItem{DtStart: utcTime(2025, 1, 6, 9, 0), DtEnd: &dtEnd}
+	repeat, interval := 2, "PT5M"
+	alarm := &Valarm{Trigger: "-PT15M", RepeatCount: &repeat, Duration: &interval}
+
+	next, err := NextAlarmTrigger(item, alarm, utcTime(2025, 1, 1, 0, 0), nil)
+	require.NoError(t, err)
+	assert.Equal(t, &AlarmTrigger{At: utcTime(2025, 1, 6, 8, 45), Occurrence: item.DtStart, Repeat: 0}, next)
+
+	next, err = NextAlarmTrigger(item, alarm, utcTime(2025, 1, 6, 8, 45), nil)
+	require.NoError(t, err)
+	assert.Equal(t, utcTime(2025, 1, 6, 8, 50), next.At)
+	assert.Equal(t, 1, next.Repeat)
+
+	next, err = NextAlarmTrigger(item, alarm, utcTime(2025, 1, 6, 8, 55), nil)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+
+	alarm = &Valarm{Trigger: "PT0S", XProperty: JSONB{"TRIGGER-RELATED": "END"}}
+	next, err = NextAlarmTrigger(item, alarm, utcTime(2025, 1, 1, 0, 0), nil)
+	require.NoError(t, err)
+	assert.Equal(t, dtEnd, next.At)
+}
+
+// TestNextAlarmTrigger_TodoDueOnly 测试只有 DUE 的待办事项：相对触发时间（包括 RELATED=END）基于 DUE
+func TestNextAlarmTrigger_TodoDueOnly(t *testing.T) {
+	due := utcTime(2025, 1, 6, 17, 0)
+	item := &CalendarItem{Type: CalendarItemTypeTodo, Due: &due}
+
+	next, err := NextAlarmTrigger(item, &Valarm{Trigger: "-PT30M"}, utcTime(2025, 1, 1, 0, 0), nil)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, &AlarmTrigger{At: utcTime(2025, 1, 6, 16, 30), Occurrence: due, Repeat: 0}, next)
+
+	alarm := &Valarm{Trigger: "PT0S", XProperty: JSONB{"TRIGGER-RELATED": "END"}}
+	next, err = NextAlarmTrigger(item, alarm, utcTime(2025, 1, 1, 0, 0), nil)
+	require.NoError(t, err)
+	require.NotNil(t, next)
+	assert.Equal(t, due, next.At)
+
+	// 同时有 DTSTART 和 DUE 时 RELATED=END 基于 DUE
+	item.DtStart = utcTime(2025, 1, 6, 9, 0)
+	next, err = NextAlarmTrigger(item, alarm, utcTime(2025, 1, 1, 0, 0), nil)
+	require.NoError(t, err)
+	assert.Equal(t, due, next.At)
+}
+
+// TestNextAlarmTrigger_Absolute 测试绝对触发时间
+func TestNextAlarmTrigger_Absolute(t *testing.T) {
+	item := &CalendarItem{DtStart: utcTime(2025, 1, 6, 9, 0)}
+	alarm := &Valarm{Trigger: "20250105T200000Z"}
+
+	next, err := NextAlarmTrigger(item, alarm, utcTime(2025, 1, 1, 0, 0), nil)
+	require.NoError(t, err)
+	assert.Equal(t, utcTime(2025, 1, 5, 20, 0), next.At)
+
+	next, err = NextAlarmTrigger(item, alarm, utcTime(2025, 1, 5, 20, 0), nil)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+
+	_, err = NextAlarmTrigger(item, &Valarm{Trigger: "soon"}, utcTime(2025, 1, 1, 0, 0), nil)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestNextAlarmTrigger_Recurring 测试重复日历项按实例触发，跳过有例外实例的实例
+func TestNextAlarmTrigger_Recurring(t *testing.T) {
+	rrule := "FREQ=WEEKLY;COUNT=3"
+	item := &CalendarItem{DtStart: utcTime(2025, 1, 6, 9, 0), RRule: &rrule}
+	alarm := &Valarm{Trigger: "-PT10M"}
+
+	next, err := NextAlarmTrigger(item, alarm, utcTime(2025, 1, 6, 8, 50), nil)
+	require.NoError(t, err)
+	assert.Equal(t, utcTime(2025, 1, 13, 8, 50), next.At)
+	assert.Equal(t, utcTime(2025, 1, 13, 9, 0), next.Occurrence)
+
+	overridden := []time.Time{utcTime(2025, 1, 13, 9, 0)}
+	next, err = NextAlarmTrigger(item, alarm, utcTime(2025, 1, 6, 8, 50), overridden)
+	require.NoError(t, err)
+	assert.Equal(t, utcTime(2025, 1, 20, 8, 50), next.At)
+
+	next, err = NextAlarmTrigger(item, alarm, utcTime(2025, 1, 20, 8, 50), nil)
+	require.NoError(t, err)
+	assert.Nil(t, next)
+}
+
+// TestNextAlarmTrigger_RecurringYearly 测试间隔较长的重复日历项
+func TestNextAlarmTrigger_RecurringYearly(t *testing.T) {
+	rrule := "FREQ=YEARLY"
+	item := &CalendarItem{DtStart: utcTime(2020, 3, 1, 0, 0), RRule: &rrule}
+	alarm := &Valarm{Trigger: "-P1D"}
+
+	next, err := NextAlarmTrigger(item, alarm, utcTime(2025, 3, 1, 0, 0), nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, utcTime(2026, 2, 28, 0, 0), next.At)
+	assert.Equal(t, utcTime(2026, 3, 1, 0, 0), next.Occurrence)
+}
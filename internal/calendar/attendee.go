@@ -0,0 +1,311 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+// ErrAttendeeNotFound 参与者不存在
+var ErrAttendeeNotFound = errors.New("参与者不存在")
+
+// maxAttendees 单个日历项最多的参与者数量
+const maxAttendees = 100
+
+// AttendeeRequest 创建或更新日历项时的参与者
+// address 可以是 mailto: 地址或邮箱（自动加上 mailto:）；未指定的参数使用 RFC 5545 的默认值，
+// 更新日历项时已有参与者（按地址匹配）未指定的参数保持不变
+type AttendeeRequest struct {
+	Address  string        `json:"address" binding:"required"`
+	CN       *string       `json:"cn,omitempty"`
+	Role     *AttendeeRole `json:"role,omitempty" binding:"omitempty,oneof=CHAIR REQ-PARTICIPANT OPT-PARTICIPANT NON-PARTICIPANT"`
+	PartStat *PartStat     `json:"partstat,omitempty" binding:"omitempty,oneof=NEEDS-ACTION ACCEPTED DECLINED TENTATIVE DELEGATED COMPLETED IN-PROCESS"`
+	RSVP     *bool         `json:"rsvp,omitempty"`
+	CUType   *CUType       `json:"cutype,omitempty" binding:"omitempty,oneof=INDIVIDUAL GROUP RESOURCE ROOM UNKNOWN"`
+}
+
+// UpdateAttendeeStatusRequest 修改参与者的参与状态
+type UpdateAttendeeStatusRequest struct {
+	PartStat PartStat `json:"partstat" binding:"required,oneof=NEEDS-ACTION ACCEPTED DECLINED TENTATIVE DELEGATED COMPLETED IN-PROCESS"`
+	RSVP     *bool    `json:"rsvp"` // 回复后通常不再需要 RSVP，默认保持不变
+}
+
+// IsValid 验证参与者角色是否有效
+func (r AttendeeRole) IsValid() bool {
+	switch r {
+	case AttendeeRoleChair, AttendeeRoleRequired, AttendeeRoleOptional, AttendeeRoleNonParticipant:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValid 验证参与状态是否有效
+func (p PartStat) IsValid() bool {
+	switch p {
+	case PartStatNeedsAction, PartStatAccepted, PartStatDeclined, PartStatTentative,
+		PartStatDelegated, PartStatCompleted, PartStatInProcess:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsValid 验证日历用户类型是否有效
+func (t CUType) IsValid() bool {
+	switch t {
+	case CUTypeIndividual, CUTypeGroup, CUTypeResource, CUTypeRoom, CUTypeUnknown:
+		return true
+	default:
+		return false
+	}
+}
+
+// normalizeCalendarAddress 规范化日历用户地址：邮箱加上 mailto: 前缀，mailto: 地址校验邮箱格式
+func normalizeCalendarAddress(address string) (string, error) {
+	address = strings.TrimSpace(address)
+	if address == "" {
+		return "", fmt.Errorf("%w: 参与者地址不能为空", ErrInvalidInput)
+	}
+
+	scheme, rest, ok := strings.Cut(address, ":")
+	if !ok {
+		scheme, rest = "mailto", address
+	}
+	if !strings.EqualFold(scheme, "mailto") {
+		// 其它 URI（例如 urn:uuid:、tel:）原样保存
+		return address, nil
+	}
+	parsed, err := mail.ParseAddress(rest)
+	if err != nil || parsed.Address != rest {
+		return "", fmt.Errorf("%w: 无效的参与者邮箱 %q", ErrInvalidInput, rest)
+	}
+	return "mailto:" + parsed.Address, nil
+}
+
+// sameCalendarAddress 比较两个日历用户地址（mailto: 地址不区分大小写）
+func sameCalendarAddress(a, b string) bool {
+	return strings.EqualFold(a, b)
+}
+
+// attendeesFromRequest 将请求中的参与者转换为模型
+// 请求中未指定的参数沿用 existing 中地址相同的参与者（例如已回复的 PARTSTAT），iTIP 回复的内容也一并保留
+func attendeesFromRequest(reqs []AttendeeRequest, existing []Attendee) ([]Attendee, error) {
+	if len(reqs) > maxAttendees {
+		return nil, fmt.Errorf("%w: 参与者不能超过 %d 个", ErrInvalidInput, maxAttendees)
+	}
+
+	attendees := make([]Attendee, 0, len(reqs))
+	for _, req := range reqs {
+		address, err := normalizeCalendarAddress(req.Address)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range attendees {
+			if sameCalendarAddress(a.Address, address) {
+				return nil, fmt.Errorf("%w: 参与者 %s 重复", ErrInvalidInput, address)
+			}
+		}
+
+		attendee := Attendee{
+			Address:  address,
+			Role:     AttendeeRoleRequired,
+			PartStat: PartStatNeedsAction,
+			CUType:   CUTypeIndividual,
+		}
+		for _, e := range existing {
+			if sameCalendarAddress(e.Address, address) {
+				attendee.CN, attendee.Role, attendee.PartStat, attendee.RSVP, attendee.CUType = e.CN, e.Role, e.PartStat, e.RSVP, e.CUType
+				attendee.RespondedAt, attendee.Comment, attendee.ProposedStart, attendee.ProposedEnd = e.RespondedAt, e.Comment, e.ProposedStart, e.ProposedEnd
+				break
+			}
+		}
+
+		if req.CN != nil {
+			attendee.CN = req.CN
+		}
+		if req.Role != nil {
+			if !req.Role.IsValid() {
+				return nil, fmt.Errorf("%w: 无效的参与者角色 %s", ErrInvalidInput, *req.Role)
+			}
+			attendee.Role = *req.Role
+		}
+		if req.PartStat != nil {
+			if !req.PartStat.IsValid() {
+				return nil, fmt.Errorf("%w: 无效的参与状态 %s", ErrInvalidInput, *req.PartStat)
+			}
+			attendee.PartStat = *req.PartStat
+		}
+		if req.RSVP != nil {
+			attendee.RSVP = *req.RSVP
+		}
+		if req.CUType != nil {
+			if !req.CUType.IsValid() {
+				return nil, fmt.Errorf("%w: 无效的日历用户类型 %s", ErrInvalidInput, *req.CUType)
+			}
+			attendee.CUType = *req.CUType
+		}
+		attendees = append(attendees, attendee)
+	}
+	return attendees, nil
+}
+
+// applyAttendeesRequest 将更新请求中的参与者应用到日历项，attendees 为 nil 时不修改
+func applyAttendeesRequest(item *CalendarItem, attendees []AttendeeRequest) error {
+	if attendees == nil {
+		return nil
+	}
+	converted, err := attendeesFromRequest(attendees, item.Attendees)
+	if err != nil {
+		return err
+	}
+	item.Attendees = converted
+	return nil
+}
+
+// replaceAttendees 用新的参与者列表替换日历项已有的参与者，repo 可以是事务中的仓库
+func replaceAttendees(repo Repository, calendarItemID uint, attendees []Attendee) error {
+	if err := repo.DeleteAttendeesByCalendarItemID(calendarItemID); err != nil {
+		return fmt.Errorf("删除原有参与者失败: %w", err)
+	}
+	for i := range attendees {
+		attendee := attendees[i]
+		attendee.ID = 0
+		attendee.CalendarItemID = calendarItemID
+		if err := repo.CreateAttendee(&attendee); err != nil {
+			return fmt.Errorf("创建参与者失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// GetAttendeesByCalendarItemID 根据日历项ID获取所有参与者，日历项必须属于该用户
+func (s *service) GetAttendeesByCalendarItemID(userID *uint, calendarItemID uint) ([]*Attendee, error) {
+	if _, err := s.repo.GetCalendarItemByID(userID, calendarItemID); err != nil {
+		return nil, ErrCalendarItemNotFound
+	}
+
+	attendees, err := s.repo.GetAttendeesByCalendarItemID(calendarItemID)
+	if err != nil {
+		return nil, fmt.Errorf("获取参与者列表失败: %w", err)
+	}
+	return attendees, nil
+}
+
+// UpdateAttendeeStatus 修改参与者的参与状态（PARTSTAT）
+// 参与状态的变化不增加 SEQUENCE（RFC 5546 中回复不改变日程的版本），但会更新日历项的 LAST-MODIFIED，
+// 以便 CalDAV 客户端和订阅者看到新的状态
+func (s *service) UpdateAttendeeStatus(userID *uint, calendarItemID uint, id uint, req *UpdateAttendeeStatusRequest) (*Attendee, error) {
+	if !req.PartStat.IsValid() {
+		return nil, fmt.Errorf("%w: 无效的参与状态 %s", ErrInvalidInput, req.PartStat)
+	}
+
+	item, err := s.repo.GetCalendarItemByID(userID, calendarItemID)
+	if err != nil {
+		return nil, ErrCalendarItemNotFound
+	}
+	attendee, err := s.repo.GetAttendeeByID(id)
+	if err != nil || attendee.CalendarItemID != calendarItemID {
+		return nil, ErrAttendeeNotFound
+	}
+
+	attendee.PartStat = req.PartStat
+	if req.RSVP != nil {
+		attendee.RSVP = *req.RSVP
+	}
+	if err := s.repo.UpdateAttendee(attendee); err != nil {
+		return nil, fmt.Errorf("更新参与者失败: %w", err)
+	}
+
+	if err := s.touchAfterReply(userID, item, attendee); err != nil {
+		return nil, err
+	}
+	return attendee, nil
+}
+
+// touchAfterReply 参与者的回复保存后更新日历项的 LAST-MODIFIED（不增加 SEQUENCE）并通知监听器
+func (s *service) touchAfterReply(userID *uint, item *CalendarItem, attendee *Attendee) error {
+	now := time.Now()
+	item.LastModified = &now
+	if err := s.repo.UpdateCalendarItem(userID, item); err != nil {
+		return fmt.Errorf("更新日历项失败: %w", err)
+	}
+	for i := range item.Attendees {
+		if item.Attendees[i].ID == attendee.ID {
+			item.Attendees[i] = *attendee
+		}
+	}
+	s.emit(EventCalendarItemUpdated, userID, item)
+	return nil
+}
+
+// attendeeFromProperty 将 ATTENDEE 属性转换为参与者
+// 无法识别的参数值按 RFC 5545 的规定处理：ROLE 视为 REQ-PARTICIPANT，PARTSTAT 视为 NEEDS-ACTION，CUTYPE 视为 UNKNOWN
+func attendeeFromProperty(p *icalProperty) *Attendee {
+	attendee := &Attendee{
+		Address:  strings.TrimSpace(p.Value),
+		Role:     AttendeeRoleRequired,
+		PartStat: PartStatNeedsAction,
+		CUType:   CUTypeIndividual,
+		RSVP:     strings.EqualFold(p.Param("RSVP"), "TRUE"),
+	}
+	if cn := p.Param("CN"); cn != "" {
+		attendee.CN = &cn
+	}
+	if v := p.Param("ROLE"); v != "" {
+		if role := AttendeeRole(strings.ToUpper(v)); role.IsValid() {
+			attendee.Role = role
+		}
+	}
+	if v := p.Param("PARTSTAT"); v != "" {
+		if partStat := PartStat(strings.ToUpper(v)); partStat.IsValid() {
+			attendee.PartStat = partStat
+		}
+	}
+	if v := p.Param("CUTYPE"); v != "" {
+		attendee.CUType = CUTypeUnknown
+		if cuType := CUType(strings.ToUpper(v)); cuType.IsValid() {
+			attendee.CUType = cuType
+		}
+	}
+	return attendee
+}
+
+// attendeeParams ATTENDEE 属性的参数，默认值不输出
+func attendeeParams(a *Attendee) []string {
+	var params []string
+	if a.CN != nil && *a.CN != "" {
+		params = append(params, "CN="+quoteICalParam(*a.CN))
+	}
+	if a.CUType != "" && a.CUType != CUTypeIndividual {
+		params = append(params, "CUTYPE="+string(a.CUType))
+	}
+	if a.Role != "" && a.Role != AttendeeRoleRequired {
+		params = append(params, "ROLE="+string(a.Role))
+	}
+	partStat := a.PartStat
+	if partStat == "" {
+		partStat = PartStatNeedsAction
+	}
+	params = append(params, "PARTSTAT="+string(partStat))
+	if a.RSVP {
+		params = append(params, "RSVP=TRUE")
+	}
+	return params
+}
+
+// quoteICalParam 参数值包含 ; : , 时加双引号（RFC 5545 3.2），参数值中不允许双引号和控制字符
+func quoteICalParam(value string) string {
+	value = strings.Map(func(r rune) rune {
+		if r == '"' || r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, value)
+	if strings.ContainsAny(value, ";:,") {
+		return `"` + value + `"`
+	}
+	return value
+}
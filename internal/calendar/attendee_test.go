@@ -0,0 +1,162 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestAttendeesFromRequest 测试参与者地址规范化、默认参数以及保留已有参与者的回复
+func TestAttendeesFromRequest(t *testing.T) {
+	cn := "李四"
+	chair := AttendeeRoleChair
+	existing := []Attendee{
+		{ID: 3, Address: "mailto:lisi@example.com", CN: &cn, Role: AttendeeRoleOptional, PartStat: PartStatAccepted, CUType: CUTypeIndividual},
+	}
+
+	attendees, err := attendeesFromRequest([]AttendeeRequest{
+		{Address: " zhangsan@example.com ", Role: &chair},
+		{Address: "MAILTO:LiSi@example.com"},
+		{Address: "urn:uuid:room-301"},
+	}, existing)
+
+	require.NoError(t, err)
+	require.Len(t, attendees, 3)
+	assert.Equal(t, "mailto:zhangsan@example.com", attendees[0].Address)
+	assert.Equal(t, AttendeeRoleChair, attendees[0].Role)
+	assert.Equal(t, PartStatNeedsAction, attendees[0].PartStat)
+	assert.Equal(t, CUTypeIndividual, attendees[0].CUType)
+	// 地址相同（不区分大小写）的已有参与者保留回复和其它参数
+	assert.Equal(t, PartStatAccepted, attendees[1].PartStat)
+	assert.Equal(t, AttendeeRoleOptional, attendees[1].Role)
+	assert.Equal(t, &cn, attendees[1].CN)
+	assert.Zero(t, attendees[1].ID)
+	assert.Equal(t, "urn:uuid:room-301", attendees[2].Address)
+
+	invalidRole := AttendeeRole("BOSS")
+	tests := []struct {
+		name string
+		reqs []AttendeeRequest
+	}{
+		{"空地址", []AttendeeRequest{{Address: " "}}},
+		{"无效的邮箱", []AttendeeRequest{{Address: "mailto:not-an-email"}}},
+		{"带显示名称的邮箱", []AttendeeRequest{{Address: "张三 <zhangsan@example.com>"}}},
+		{"重复的参与者", []AttendeeRequest{{Address: "a@example.com"}, {Address: "mailto:A@example.com"}}},
+		{"无效的角色", []AttendeeRequest{{Address: "a@example.com", Role: &invalidRole}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := attendeesFromRequest(tt.reqs, nil)
+			assert.ErrorIs(t, err, ErrInvalidInput)
+		})
+	}
+}
+
+// TestService_UpdateCalendarItem_Attendees 测试更新日历项时替换参与者
+func TestService_UpdateCalendarItem_Attendees(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	item := &CalendarItem{
+		ID:        1,
+		UID:       "meeting",
+		Type:      CalendarItemTypeEvent,
+		DtStart:   utcTime(2025, 1, 9, 2, 0),
+		Attendees: []Attendee{{ID: 5, CalendarItemID: 1, Address: "mailto:lisi@example.com", PartStat: PartStatDeclined}},
+	}
+
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(item, nil)
+	mockRepo.On("UpdateCalendarItem", &userID, item).Return(nil)
+	mockRepo.On("DeleteAttendeesByCalendarItemID", uint(1)).Return(nil)
+	mockRepo.On("CreateAttendee", mock.MatchedBy(func(a *Attendee) bool {
+		return a.CalendarItemID == 1 && a.ID == 0
+	})).Return(nil).Twice()
+
+	_, err := service.UpdateCalendarItem(&userID, 1, &UpdateCalendarItemRequest{
+		Attendees: []AttendeeRequest{{Address: "lisi@example.com"}, {Address: "wangwu@example.com"}},
+	})
+
+	require.NoError(t, err)
+	require.Len(t, item.Attendees, 2)
+	assert.Equal(t, PartStatDeclined, item.Attendees[0].PartStat)
+	assert.Equal(t, PartStatNeedsAction, item.Attendees[1].PartStat)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_UpdateAttendeeStatus 测试修改参与状态：不增加 SEQUENCE，只更新 LAST-MODIFIED
+func TestService_UpdateAttendeeStatus(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	sequence := 2
+	item := &CalendarItem{ID: 1, UID: "meeting", Type: CalendarItemTypeEvent, Sequence: &sequence}
+	attendee := &Attendee{ID: 5, CalendarItemID: 1, Address: "mailto:lisi@example.com", PartStat: PartStatNeedsAction, RSVP: true}
+	rsvp := false
+
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(item, nil)
+	mockRepo.On("GetAttendeeByID", uint(5)).Return(attendee, nil)
+	mockRepo.On("UpdateAttendee", attendee).Return(nil)
+	mockRepo.On("UpdateCalendarItem", &userID, mock.MatchedBy(func(i *CalendarItem) bool {
+		return i.LastModified != nil && *i.Sequence == 2
+	})).Return(nil)
+
+	result, err := service.UpdateAttendeeStatus(&userID, 1, 5, &UpdateAttendeeStatusRequest{PartStat: PartStatAccepted, RSVP: &rsvp})
+
+	require.NoError(t, err)
+	assert.Equal(t, PartStatAccepted, result.PartStat)
+	assert.False(t, result.RSVP)
+	mockRepo.AssertExpectations(t)
+
+	// 参与者不属于该日历项
+	mockRepo.On("GetAttendeeByID", uint(6)).Return(&Attendee{ID: 6, CalendarItemID: 2}, nil)
+	_, err = service.UpdateAttendeeStatus(&userID, 1, 6, &UpdateAttendeeStatusRequest{PartStat: PartStatDeclined})
+	assert.ErrorIs(t, err, ErrAttendeeNotFound)
+
+	_, err = service.UpdateAttendeeStatus(&userID, 1, 5, &UpdateAttendeeStatusRequest{PartStat: "MAYBE"})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestAttendeeICalendar 测试 ATTENDEE 属性的导出和解析
+func TestAttendeeICalendar(t *testing.T) {
+	cn := "王, 五"
+	items := []*CalendarItem{{
+		UID:     "meeting",
+		Type:    CalendarItemTypeEvent,
+		DtStart: utcTime(2025, 1, 9, 2, 0),
+		Attendees: []Attendee{
+			{Address: "mailto:wangwu@example.com", CN: &cn, Role: AttendeeRoleOptional, PartStat: PartStatTentative, RSVP: true, CUType: CUTypeIndividual},
+			{Address: "mailto:room@example.com", Role: AttendeeRoleNonParticipant, PartStat: PartStatAccepted, CUType: CUTypeRoom},
+		},
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteICalendar(&buf, "日历", items))
+	data := strings.ReplaceAll(buf.String(), "\r\n ", "")
+	assert.Contains(t, data, "ATTENDEE;CN=\"王, 五\";ROLE=OPT-PARTICIPANT;PARTSTAT=TENTATIVE;RSVP=TRUE:mailto:wangwu@example.com\r\n")
+	assert.Contains(t, data, "ATTENDEE;CUTYPE=ROOM;ROLE=NON-PARTICIPANT;PARTSTAT=ACCEPTED:mailto:room@example.com\r\n")
+
+	calendars, err := parseICalendar(strings.NewReader(buf.String()))
+	require.NoError(t, err)
+	item, _, err := calendarItemFromComponent(calendars[0].Components[0], icalTimezones{})
+	require.NoError(t, err)
+	require.Len(t, item.Attendees, 2)
+	assert.Equal(t, items[0].Attendees[0].Address, item.Attendees[0].Address)
+	assert.Equal(t, cn, *item.Attendees[0].CN)
+	assert.Equal(t, PartStatTentative, item.Attendees[0].PartStat)
+	assert.True(t, item.Attendees[0].RSVP)
+	assert.Equal(t, CUTypeRoom, item.Attendees[1].CUType)
+
+	// 无法识别的参数值按 RFC 5545 处理
+	p, err := parseICalContentLine("ATTENDEE;ROLE=X-OBSERVER;PARTSTAT=X-MAYBE;CUTYPE=X-BOT:mailto:bot@example.com")
+	require.NoError(t, err)
+	attendee := attendeeFromProperty(p)
+	assert.Equal(t, AttendeeRoleRequired, attendee.Role)
+	assert.Equal(t, PartStatNeedsAction, attendee.PartStat)
+	assert.Equal(t, CUTypeUnknown, attendee.CUType)
+}
@@ -0,0 +1,173 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrConflict 与已有事件时间冲突
+var ErrConflict = errors.New("与已有日程时间冲突")
+
+const (
+	// conflictHorizon 检查重复事件冲突时向后展开的时间跨度
+	conflictHorizon = 90 * 24 * time.Hour
+	// maxConflicts 最多返回的冲突数量
+	maxConflicts = 50
+)
+
+// ConflictPolicy 创建或移动事件时对时间冲突的处理方式
+type ConflictPolicy string
+
+const (
+	ConflictPolicyAllow  ConflictPolicy = "allow"  // 不检查冲突
+	ConflictPolicyWarn   ConflictPolicy = "warn"   // 照常保存，并在响应中返回冲突（默认）
+	ConflictPolicyReject ConflictPolicy = "reject" // 有冲突时不保存，返回 ConflictError
+)
+
+// IsValid 验证冲突处理方式是否有效
+func (p ConflictPolicy) IsValid() bool {
+	switch p {
+	case ConflictPolicyAllow, ConflictPolicyWarn, ConflictPolicyReject:
+		return true
+	default:
+		return false
+	}
+}
+
+// Conflict 与新事件时间重叠的已有事件实例
+type Conflict struct {
+	ID           uint       `json:"id"`
+	UID          string     `json:"uid"`
+	Summary      *string    `json:"summary,omitempty"`
+	Start        time.Time  `json:"start"`
+	End          time.Time  `json:"end"`
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty"` // 冲突的是重复事件的实例时为实例的原始开始时间
+}
+
+// ConflictError 冲突处理方式为 reject 且存在冲突，errors.Is(err, ErrConflict) 为 true
+type ConflictError struct {
+	Conflicts []Conflict
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %d 个冲突", ErrConflict.Error(), len(e.Conflicts))
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrConflict
+}
+
+// checkConflicts 按冲突处理方式检查 item 与已有事件的冲突
+// reject 时有冲突返回 ConflictError；excludeUIDs 中的日历项（例如被拆分的原重复事件）不参与检查
+func (s *service) checkConflicts(userID *uint, item *CalendarItem, policy *ConflictPolicy, excludeUIDs ...string) ([]Conflict, error) {
+	p := ConflictPolicyWarn
+	if policy != nil {
+		p = *policy
+	}
+	if p == ConflictPolicyAllow {
+		return nil, nil
+	}
+
+	conflicts, err := s.detectConflicts(userID, item, excludeUIDs)
+	if err != nil {
+		return nil, fmt.Errorf("检查时间冲突失败: %w", err)
+	}
+	if len(conflicts) > 0 && p == ConflictPolicyReject {
+		return nil, &ConflictError{Conflicts: conflicts}
+	}
+	return conflicts, nil
+}
+
+// detectConflicts 查找与 item 时间重叠的已有事件实例
+// 只检查占用时间的 VEVENT；重复事件检查从 DTSTART 起 conflictHorizon 内的实例。
+// 同一 UID 的日历项（自身及其例外实例）不算冲突
+func (s *service) detectConflicts(userID *uint, item *CalendarItem, excludeUIDs []string) ([]Conflict, error) {
+	duration := item.OccurrenceDuration()
+	if item.Type != CalendarItemTypeEvent || !occupiesTime(item) || duration <= 0 {
+		return nil, nil
+	}
+
+	starts := []time.Time{item.DtStart}
+	if item.IsRecurring() {
+		occurrences, err := item.OccurrenceStarts(item.DtStart, item.DtStart.Add(conflictHorizon))
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+		if item.ID != 0 {
+			overridden, err := s.overriddenInstances(userID, []*CalendarItem{item})
+			if err != nil {
+				return nil, err
+			}
+			occurrences = removeOverridden(occurrences, overridden[item.UID])
+		}
+		starts = occurrences
+	}
+	if len(starts) == 0 {
+		return nil, nil
+	}
+
+	from, to := starts[0], starts[len(starts)-1].Add(duration)
+	eventType := CalendarItemTypeEvent
+	existing, err := s.repo.ListCalendarItemsInRange(userID, from, to, &eventType)
+	if err != nil {
+		return nil, err
+	}
+	overridden, err := s.overriddenInstances(userID, existing)
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := map[string]bool{item.UID: true}
+	for _, uid := range excludeUIDs {
+		excluded[uid] = true
+	}
+
+	var conflicts []Conflict
+	for _, occ := range expandCalendarItems(existing, overridden, from, to) {
+		if excluded[occ.UID] || !occupiesTime(occ) {
+			continue
+		}
+		occEnd := occ.DtStart.Add(occ.OccurrenceDuration())
+		if !occEnd.After(occ.DtStart) {
+			continue
+		}
+		for _, start := range starts {
+			if start.Before(occEnd) && occ.DtStart.Before(start.Add(duration)) {
+				conflicts = append(conflicts, Conflict{
+					ID:           occ.ID,
+					UID:          occ.UID,
+					Summary:      occ.Summary,
+					Start:        occ.DtStart,
+					End:          occEnd,
+					RecurrenceID: occ.RecurrenceID,
+				})
+				break
+			}
+		}
+		if len(conflicts) >= maxConflicts {
+			break
+		}
+	}
+	return conflicts, nil
+}
+
+// removeOverridden 去掉已被例外实例代替的实例开始时间
+func removeOverridden(starts []time.Time, recurrenceIDs []time.Time) []time.Time {
+	if len(recurrenceIDs) == 0 {
+		return starts
+	}
+	result := starts[:0:0]
+	for _, start := range starts {
+		if !isOverridden(recurrenceIDs, start) {
+			result = append(result, start)
+		}
+	}
+	return result
+}
+
+// changesTiming 更新请求是否修改了事件占用的时间（需要重新检查冲突）
+func (req *UpdateCalendarItemRequest) changesTiming() bool {
+	return req.DtStart != nil || req.DtEnd != nil || req.Duration != nil || req.RRule != nil ||
+		req.RDate != nil || req.ExDate != nil || req.Status != nil || req.Transp != nil || req.AllDay != nil
+}
@@ -0,0 +1,120 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestService_CreateCalendarItem_Conflicts 测试创建事件时按冲突处理方式检查时间冲突
+func TestService_CreateCalendarItem_Conflicts(t *testing.T) {
+	userID := uint(1)
+	start := time.Date(2025, 1, 9, 10, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	ptr := func(t time.Time) *time.Time { return &t }
+	summary := "周会"
+	cancelled := "CANCELLED"
+	transparent := "TRANSPARENT"
+	rrule := "FREQ=DAILY;COUNT=3"
+
+	existing := []*CalendarItem{
+		{ID: 1, UID: "weekly", Type: CalendarItemTypeEvent, Summary: &summary, DtStart: start.Add(30 * time.Minute), DtEnd: ptr(end.Add(30 * time.Minute))},
+		{ID: 2, UID: "cancelled", Type: CalendarItemTypeEvent, DtStart: start, DtEnd: ptr(end), Status: &cancelled},
+		{ID: 3, UID: "focus", Type: CalendarItemTypeEvent, DtStart: start, DtEnd: ptr(end), Transp: &transparent},
+		{ID: 4, UID: "adjacent", Type: CalendarItemTypeEvent, DtStart: end, DtEnd: ptr(end.Add(time.Hour))},
+	}
+	eventType := CalendarItemTypeEvent
+	newRequest := func(policy ConflictPolicy) *CreateCalendarItemRequest {
+		return &CreateCalendarItemRequest{
+			Type:           CalendarItemTypeEvent,
+			DtStart:        ptr(start),
+			DtEnd:          ptr(end),
+			ConflictPolicy: &policy,
+		}
+	}
+
+	t.Run("warn", func(t *testing.T) {
+		mockRepo := new(mockRepository)
+		service := NewService(mockRepo)
+		mockRepo.On("ListCalendarItemsInRange", &userID, start, end, &eventType).Return(existing, nil)
+		mockRepo.On("CreateCalendarItem", mock.AnythingOfType("*calendar.CalendarItem")).Return(nil)
+
+		resp, err := service.CreateCalendarItem(&userID, newRequest(ConflictPolicyWarn))
+
+		require.NoError(t, err)
+		// 已取消、透明和首尾相接的事件不算冲突
+		require.Len(t, resp.Conflicts, 1)
+		assert.Equal(t, "weekly", resp.Conflicts[0].UID)
+		assert.Equal(t, &summary, resp.Conflicts[0].Summary)
+		mockRepo.AssertExpectations(t)
+	})
+
+	t.Run("reject", func(t *testing.T) {
+		mockRepo := new(mockRepository)
+		service := NewService(mockRepo)
+		mockRepo.On("ListCalendarItemsInRange", &userID, start, end, &eventType).Return(existing, nil)
+
+		resp, err := service.CreateCalendarItem(&userID, newRequest(ConflictPolicyReject))
+
+		assert.Nil(t, resp)
+		assert.ErrorIs(t, err, ErrConflict)
+		var conflictErr *ConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		assert.Len(t, conflictErr.Conflicts, 1)
+		mockRepo.AssertNotCalled(t, "CreateCalendarItem", mock.Anything)
+	})
+
+	t.Run("allow", func(t *testing.T) {
+		mockRepo := new(mockRepository)
+		service := NewService(mockRepo)
+		mockRepo.On("CreateCalendarItem", mock.AnythingOfType("*calendar.CalendarItem")).Return(nil)
+
+		resp, err := service.CreateCalendarItem(&userID, newRequest(ConflictPolicyAllow))
+
+		require.NoError(t, err)
+		assert.Empty(t, resp.Conflicts)
+		mockRepo.AssertNotCalled(t, "ListCalendarItemsInRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("重复事件", func(t *testing.T) {
+		mockRepo := new(mockRepository)
+		service := NewService(mockRepo)
+		// 第三天的实例与已有事件重叠
+		later := &CalendarItem{ID: 5, UID: "later", Type: CalendarItemTypeEvent, DtStart: start.AddDate(0, 0, 2), DtEnd: ptr(end.AddDate(0, 0, 2))}
+		mockRepo.On("ListCalendarItemsInRange", &userID, start, end.AddDate(0, 0, 2), &eventType).Return([]*CalendarItem{later}, nil)
+
+		req := newRequest(ConflictPolicyReject)
+		req.RRule = &rrule
+		_, err := service.CreateCalendarItem(&userID, req)
+
+		var conflictErr *ConflictError
+		require.ErrorAs(t, err, &conflictErr)
+		require.Len(t, conflictErr.Conflicts, 1)
+		assert.Equal(t, "later", conflictErr.Conflicts[0].UID)
+		mockRepo.AssertExpectations(t)
+	})
+}
+
+// TestService_UpdateCalendarItem_SkipsConflictCheck 测试未修改时间的更新不检查冲突
+func TestService_UpdateCalendarItem_SkipsConflictCheck(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	start := time.Date(2025, 1, 9, 10, 0, 0, 0, time.UTC)
+	item := &CalendarItem{ID: 1, UID: "meeting", Type: CalendarItemTypeEvent, DtStart: start}
+	summary := "改名"
+	policy := ConflictPolicyReject
+
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(item, nil)
+	mockRepo.On("UpdateCalendarItem", &userID, item).Return(nil)
+
+	result, err := service.UpdateCalendarItem(&userID, 1, &UpdateCalendarItemRequest{Summary: &summary, ConflictPolicy: &policy})
+
+	require.NoError(t, err)
+	assert.Empty(t, result.Conflicts)
+	mockRepo.AssertNotCalled(t, "ListCalendarItemsInRange", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
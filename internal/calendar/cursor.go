@@ -0,0 +1,251 @@
+package calendar
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ErrInvalidCursor 无效的分页游标
+var ErrInvalidCursor = errors.New("无效的分页游标")
+
+// SortKey 日历项列表和搜索结果的排序字段，排序相同时按 ID 升序
+type SortKey string
+
+const (
+	SortByDtStart      SortKey = "dtstart"       // 开始时间，从早到晚（默认）
+	SortByDue          SortKey = "due"           // 截止时间，从早到晚，没有截止时间的排在最后
+	SortByPriority     SortKey = "priority"      // 优先级，1 最高，未定义（0 或空）的排在最后
+	SortByLastModified SortKey = "last_modified" // 最后修改时间，最近修改的在前，没有 LAST-MODIFIED 时使用更新时间
+	SortByRelevance    SortKey = "relevance"     // 与搜索关键字的匹配程度，只用于搜索
+)
+
+// isValidSortKey 检查排序字段是否有效
+func isValidSortKey(key SortKey) bool {
+	switch key {
+	case SortByDtStart, SortByDue, SortByPriority, SortByLastModified, SortByRelevance:
+		return true
+	}
+	return false
+}
+
+// PageRequest 分页方式：Cursor 不为空时返回排在游标之后的日历项（keyset 分页），否则跳过 Offset 项
+// Limit 为每页数量，为 0 时不限制
+type PageRequest struct {
+	Sort   SortKey
+	Cursor *PageCursor
+	Offset int
+	Limit  int
+}
+
+// PageCursor 分页游标的内容：排序字段和上一页最后一项的排序值、ID
+// 排序值为 NULL 时 Time、Num 和 Rank 都为空；展开的实例使用实例的开始时间和主日历项的 ID
+type PageCursor struct {
+	Sort SortKey    `json:"s"`
+	Time *time.Time `json:"t,omitempty"`
+	Num  *int       `json:"n,omitempty"`
+	Rank *float64   `json:"r,omitempty"` // 全文搜索的相关度
+	ID   uint       `json:"i"`
+}
+
+// formatPageCursor 将游标编码为不透明的字符串，nil 返回空字符串
+func formatPageCursor(cursor *PageCursor) string {
+	if cursor == nil {
+		return ""
+	}
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// parsePageCursor 解析游标，游标的排序字段必须与当前请求一致
+func parsePageCursor(value string, sortKey SortKey) (*PageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, value)
+	}
+	var cursor PageCursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, value)
+	}
+	if cursor.Sort != sortKey {
+		return nil, fmt.Errorf("%w: 游标的排序方式为 %s，与请求的 %s 不一致", ErrInvalidCursor, cursor.Sort, sortKey)
+	}
+	// 开始时间、最后修改时间和相关度不会为 NULL
+	switch sortKey {
+	case SortByDtStart, SortByLastModified:
+		if cursor.Time == nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, value)
+		}
+	case SortByRelevance:
+		if cursor.Rank == nil {
+			return nil, fmt.Errorf("%w: %q", ErrInvalidCursor, value)
+		}
+	}
+	return &cursor, nil
+}
+
+// sortExpression 排序字段对应的 SQL 表达式，NULL 总是排在最后
+type sortExpression struct {
+	sql      string
+	vars     []interface{}
+	desc     bool
+	nullable bool
+}
+
+// newSortExpression 返回排序字段的 SQL 表达式，按相关度排序时 tsquery 为全文搜索的查询表达式
+func newSortExpression(key SortKey, tsquery string) sortExpression {
+	switch key {
+	case SortByDue:
+		return sortExpression{sql: "due", nullable: true}
+	case SortByPriority:
+		return sortExpression{sql: "NULLIF(priority, 0)", nullable: true}
+	case SortByLastModified:
+		return sortExpression{sql: "COALESCE(last_modified, updated_at)", desc: true}
+	case SortByRelevance:
+		return sortExpression{sql: "ts_rank_cd(search_vector, to_tsquery('simple', ?))", vars: []interface{}{tsquery}, desc: true}
+	}
+	return sortExpression{sql: "dt_start"}
+}
+
+// sqlExpr 带参数的 SQL 片段
+func sqlExpr(sql string, vars ...interface{}) clause.Expr {
+	return clause.Expr{SQL: sql, Vars: vars, WithoutParentheses: true}
+}
+
+// orderBy 排序子句：排序字段，然后按 ID 升序
+func (e sortExpression) orderBy() clause.OrderBy {
+	direction := "ASC"
+	if e.desc {
+		direction = "DESC"
+	}
+	return clause.OrderBy{Expression: sqlExpr(e.sql+" "+direction+" NULLS LAST, id ASC", e.vars...)}
+}
+
+// after 排在游标之后的过滤条件
+func (e sortExpression) after(cursor *PageCursor) clause.Expr {
+	var value interface{}
+	switch {
+	case cursor.Time != nil:
+		value = *cursor.Time
+	case cursor.Num != nil:
+		value = *cursor.Num
+	case cursor.Rank != nil:
+		value = *cursor.Rank
+	}
+
+	var vars []interface{}
+	// 上一项的排序值为 NULL：之后只有同样为 NULL 的日历项
+	if value == nil {
+		vars = append(vars, e.vars...)
+		vars = append(vars, cursor.ID)
+		return sqlExpr("("+e.sql+" IS NULL AND id > ?)", vars...)
+	}
+
+	op := ">"
+	if e.desc {
+		op = "<"
+	}
+	sql := e.sql + " " + op + " ? OR (" + e.sql + " = ? AND id > ?)"
+	vars = append(vars, e.vars...)
+	vars = append(vars, value)
+	vars = append(vars, e.vars...)
+	vars = append(vars, value, cursor.ID)
+	if e.nullable {
+		sql += " OR " + e.sql + " IS NULL"
+		vars = append(vars, e.vars...)
+	}
+	return sqlExpr("("+sql+")", vars...)
+}
+
+// applyPage 应用分页：排序、游标或偏移，多取一项用于判断是否还有下一页
+func applyPage(query *gorm.DB, e sortExpression, page PageRequest) *gorm.DB {
+	if page.Cursor != nil {
+		query = query.Where(e.after(page.Cursor))
+	} else if page.Offset > 0 {
+		query = query.Offset(page.Offset)
+	}
+	query = query.Order(e.orderBy())
+	if page.Limit > 0 {
+		query = query.Limit(page.Limit + 1)
+	}
+	return query
+}
+
+// nextPageCursor 查询结果多于每页数量时去掉多取的一项，返回最后一项对应的游标
+// 按相关度排序时排序值由数据库计算，通过 rank 查询
+func nextPageCursor(items []*CalendarItem, page PageRequest, rank func(item *CalendarItem) (float64, error)) ([]*CalendarItem, *PageCursor, error) {
+	if page.Limit <= 0 || len(items) <= page.Limit {
+		return items, nil, nil
+	}
+	items = items[:page.Limit]
+	last := items[len(items)-1]
+
+	cursor := &PageCursor{Sort: page.Sort, ID: last.ID}
+	switch page.Sort {
+	case SortByRelevance:
+		value, err := rank(last)
+		if err != nil {
+			return nil, nil, err
+		}
+		cursor.Rank = &value
+	default:
+		cursor.Time, cursor.Num = sortValue(last, page.Sort)
+	}
+	return items, cursor, nil
+}
+
+// sortValue 日历项的排序值，与 newSortExpression 的 SQL 表达式一致
+func sortValue(item *CalendarItem, key SortKey) (*time.Time, *int) {
+	switch key {
+	case SortByDue:
+		return item.Due, nil
+	case SortByPriority:
+		if item.Priority == nil || *item.Priority == 0 {
+			return nil, nil
+		}
+		return nil, item.Priority
+	case SortByLastModified:
+		if item.LastModified != nil {
+			return item.LastModified, nil
+		}
+		updatedAt := item.UpdatedAt
+		return &updatedAt, nil
+	}
+	dtStart := item.DtStart
+	return &dtStart, nil
+}
+
+// pageOccurrences 在内存中分页展开后的实例：按开始时间和 ID 排序，取游标之后（或跳过 offset 项）的 limit 项
+func pageOccurrences(items []*CalendarItem, cursor *PageCursor, offset, limit int) ([]*CalendarItem, *PageCursor) {
+	sort.SliceStable(items, func(i, j int) bool {
+		if !items[i].DtStart.Equal(items[j].DtStart) {
+			return items[i].DtStart.Before(items[j].DtStart)
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	start := min(offset, len(items))
+	if cursor != nil {
+		start = sort.Search(len(items), func(i int) bool {
+			if !items[i].DtStart.Equal(*cursor.Time) {
+				return items[i].DtStart.After(*cursor.Time)
+			}
+			return items[i].ID > cursor.ID
+		})
+	}
+	end := min(start+limit, len(items))
+	page := items[start:end]
+	if end == len(items) || len(page) == 0 {
+		return page, nil
+	}
+	last := page[len(page)-1]
+	dtStart := last.DtStart
+	return page, &PageCursor{Sort: SortByDtStart, Time: &dtStart, ID: last.ID}
+}
@@ -0,0 +1,106 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestPageCursor_RoundTrip 测试游标编码后可以原样解析
+func TestPageCursor_RoundTrip(t *testing.T) {
+	due := time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+	priority := 3
+	cursors := []*PageCursor{
+		{Sort: SortByDue, Time: &due, ID: 7},
+		{Sort: SortByDue, ID: 8}, // 截止时间为 NULL
+		{Sort: SortByPriority, Num: &priority, ID: 9},
+	}
+	for _, cursor := range cursors {
+		value := formatPageCursor(cursor)
+
+		parsed, err := parsePageCursor(value, cursor.Sort)
+
+		require.NoError(t, err)
+		assert.Equal(t, cursor, parsed)
+	}
+	assert.Empty(t, formatPageCursor(nil))
+}
+
+// TestParsePageCursor_Invalid 测试解析无效的游标：格式错误、缺少排序值、排序方式不一致
+func TestParsePageCursor_Invalid(t *testing.T) {
+	dtStart := time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+	valid := formatPageCursor(&PageCursor{Sort: SortByDtStart, Time: &dtStart, ID: 1})
+
+	cases := []struct {
+		value string
+		sort  SortKey
+	}{
+		{"not a cursor!", SortByDtStart},
+		{"e30", SortByDtStart}, // {}
+		{formatPageCursor(&PageCursor{Sort: SortByDtStart, ID: 1}), SortByDtStart},
+		{formatPageCursor(&PageCursor{Sort: SortByRelevance, ID: 1}), SortByRelevance},
+		{valid, SortByDue},
+	}
+	for _, c := range cases {
+		_, err := parsePageCursor(c.value, c.sort)
+		assert.ErrorIs(t, err, ErrInvalidCursor, c.value)
+	}
+}
+
+// TestSortExpression_After 测试游标之后的过滤条件：升序、降序和可为 NULL 的排序字段
+func TestSortExpression_After(t *testing.T) {
+	at := time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+	priority := 2
+
+	expr := newSortExpression(SortByDtStart, "").after(&PageCursor{Time: &at, ID: 5})
+	assert.Equal(t, "(dt_start > ? OR (dt_start = ? AND id > ?))", expr.SQL)
+	assert.Equal(t, []interface{}{at, at, uint(5)}, expr.Vars)
+
+	expr = newSortExpression(SortByLastModified, "").after(&PageCursor{Time: &at, ID: 5})
+	assert.Contains(t, expr.SQL, "COALESCE(last_modified, updated_at) < ?")
+
+	expr = newSortExpression(SortByPriority, "").after(&PageCursor{Num: &priority, ID: 5})
+	assert.Equal(t, "(NULLIF(priority, 0) > ? OR (NULLIF(priority, 0) = ? AND id > ?) OR NULLIF(priority, 0) IS NULL)", expr.SQL)
+
+	expr = newSortExpression(SortByDue, "").after(&PageCursor{ID: 5})
+	assert.Equal(t, "(due IS NULL AND id > ?)", expr.SQL)
+	assert.Equal(t, []interface{}{uint(5)}, expr.Vars)
+
+	rank := 0.5
+	expr = newSortExpression(SortByRelevance, "'周会'").after(&PageCursor{Rank: &rank, ID: 5})
+	assert.Equal(t, "(ts_rank_cd(search_vector, to_tsquery('simple', ?)) < ? OR (ts_rank_cd(search_vector, to_tsquery('simple', ?)) = ? AND id > ?))", expr.SQL)
+	assert.Equal(t, []interface{}{"'周会'", rank, "'周会'", rank, uint(5)}, expr.Vars)
+}
+
+// TestPageOccurrences 测试在内存中分页展开后的实例：同一时间按 ID 排序，游标从上一页最后一项之后继续
+func TestPageOccurrences(t *testing.T) {
+	base := time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+	items := []*CalendarItem{
+		{ID: 3, DtStart: base.Add(time.Hour)},
+		{ID: 2, DtStart: base},
+		{ID: 1, DtStart: base},
+		{ID: 1, DtStart: base.Add(24 * time.Hour)},
+	}
+
+	page, cursor := pageOccurrences(items, nil, 0, 2)
+
+	require.Len(t, page, 2)
+	assert.Equal(t, uint(1), page[0].ID)
+	assert.Equal(t, uint(2), page[1].ID)
+	require.NotNil(t, cursor)
+	assert.Equal(t, SortByDtStart, cursor.Sort)
+
+	page, cursor = pageOccurrences(items, cursor, 0, 2)
+
+	require.Len(t, page, 2)
+	assert.Equal(t, uint(3), page[0].ID)
+	assert.Equal(t, base.Add(24*time.Hour), page[1].DtStart)
+	assert.Nil(t, cursor)
+
+	page, cursor = pageOccurrences(items, nil, 3, 2)
+
+	assert.Len(t, page, 1)
+	assert.Nil(t, cursor)
+}
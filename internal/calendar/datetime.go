@@ -0,0 +1,308 @@
+package calendar
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// iCalendar 日期时间格式（RFC 5545 3.3.4 / 3.3.5）
+const (
+	icalDateLayout         = "20060102"
+	icalDateTimeLayout     = "20060102T150405"
+	icalDateTimeUTCLayout  = "20060102T150405Z"
+	isoLocalDateTimeLayout = "2006-01-02T15:04:05"
+	isoDateLayout          = "2006-01-02"
+)
+
+// parseDateTimeValue 解析 iCalendar 或 ISO 8601 形式的日期时间值
+// 没有时区信息的值按 loc 解释；返回值 isDate 表示只有日期部分
+func parseDateTimeValue(value string, loc *time.Location) (t time.Time, isDate bool, err error) {
+	value = strings.TrimSpace(value)
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, false, nil
+	}
+	if t, err := time.Parse(icalDateTimeUTCLayout, value); err == nil {
+		return t, false, nil
+	}
+	for _, layout := range []string{icalDateTimeLayout, isoLocalDateTimeLayout} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, false, nil
+		}
+	}
+	for _, layout := range []string{icalDateLayout, isoDateLayout} {
+		if t, err := time.ParseInLocation(layout, value, loc); err == nil {
+			return t, true, nil
+		}
+	}
+	return time.Time{}, false, fmt.Errorf("无法解析日期时间 %q", value)
+}
+
+// dateOnlyFields 请求中可以只写日期的时间字段
+var dateOnlyFields = []string{"dtstart", "dtend", "due"}
+
+// rewriteDateOnlyFields 将 JSON 对象中只有日期的 dtstart、dtend、due 改写为当天的 UTC 零点以便解析为 time.Time，
+// dtstart 只有日期且没有指定 all_day 时补充 "all_day": true；不是 JSON 对象时原样返回
+func rewriteDateOnlyFields(data []byte) []byte {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil || fields == nil {
+		return data
+	}
+
+	changed := false
+	for _, name := range dateOnlyFields {
+		var value string
+		if raw, ok := fields[name]; !ok || json.Unmarshal(raw, &value) != nil {
+			continue
+		}
+		t, isDate, err := parseDateTimeValue(value, time.UTC)
+		if err != nil || !isDate {
+			continue
+		}
+		fields[name], _ = json.Marshal(t.Format(time.RFC3339))
+		changed = true
+		if _, ok := fields["all_day"]; name == "dtstart" && !ok {
+			fields["all_day"] = json.RawMessage("true")
+		}
+	}
+	if !changed {
+		return data
+	}
+	rewritten, err := json.Marshal(fields)
+	if err != nil {
+		return data
+	}
+	return rewritten
+}
+
+// tzidLocations 已加载的时区，避免每次读取日历项都重新加载时区数据
+var tzidLocations sync.Map
+
+// tzidLocation 返回保存的 TZID 对应的时区，为空或无法识别时返回 UTC
+func tzidLocation(tzid *string) *time.Location {
+	if tzid == nil || *tzid == "" {
+		return time.UTC
+	}
+	if loc, ok := tzidLocations.Load(*tzid); ok {
+		return loc.(*time.Location)
+	}
+	loc, err := time.LoadLocation(*tzid)
+	if err != nil {
+		return time.UTC
+	}
+	tzidLocations.Store(*tzid, loc)
+	return loc
+}
+
+// timezoneName 返回日历项中保存的时区名：UTC 和无法按名称重新加载的时区（例如 VTIMEZONE 的固定偏移）返回 nil
+func timezoneName(loc *time.Location) *string {
+	if loc == nil || loc == time.UTC {
+		return nil
+	}
+	name := loc.String()
+	if name == "" || name == "UTC" || name == "Local" {
+		return nil
+	}
+	if _, err := time.LoadLocation(name); err != nil {
+		return nil
+	}
+	return &name
+}
+
+// StartLocation DTSTART 的时区，重复规则按该时区的当地时间展开；没有 TZID 时使用 DtStart 自身的时区
+func (item *CalendarItem) StartLocation() *time.Location {
+	if item.DtStartTZID == nil || *item.DtStartTZID == "" {
+		return item.DtStart.Location()
+	}
+	return tzidLocation(item.DtStartTZID)
+}
+
+// localizeTimes 将设置了时区的 DTSTART、DTEND、DUE 转换到各自的时区（时刻不变）
+func (item *CalendarItem) localizeTimes() {
+	if item.DtStartTZID != nil && !item.DtStart.IsZero() {
+		item.DtStart = item.DtStart.In(tzidLocation(item.DtStartTZID))
+	}
+	if item.DtEndTZID != nil && item.DtEnd != nil {
+		dtEnd := item.DtEnd.In(tzidLocation(item.DtEndTZID))
+		item.DtEnd = &dtEnd
+	}
+	if item.DueTZID != nil && item.Due != nil {
+		due := item.Due.In(tzidLocation(item.DueTZID))
+		item.Due = &due
+	}
+}
+
+// setTimezone 将 DTSTART、DTEND、DUE 的时区设置为 loc
+func (item *CalendarItem) setTimezone(loc *time.Location) {
+	name := timezoneName(loc)
+	item.DtStartTZID, item.DtEndTZID, item.DueTZID = nil, nil, nil
+	if !item.DtStart.IsZero() {
+		item.DtStartTZID = name
+	}
+	if item.DtEnd != nil {
+		item.DtEndTZID = name
+	}
+	if item.Due != nil {
+		item.DueTZID = name
+	}
+	item.localizeTimes()
+}
+
+// startOfDay 返回 t 在其自身时区中的日期在 loc 中的零点
+func startOfDay(t time.Time, loc *time.Location) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, loc)
+}
+
+// daysBetween 返回 a 与 b 各自时区中的日期相差的天数
+func daysBetween(a, b time.Time) int {
+	return int(startOfDay(b, time.UTC).Sub(startOfDay(a, time.UTC)) / (24 * time.Hour))
+}
+
+// normalizeAllDay 将全天日历项的 DTSTART、DTEND、DUE 对齐到 loc 中当天的零点
+// 日期取自各时间自身的时区，例如请求中的 "2024-05-06" 解析为 UTC 零点，仍表示 5 月 6 日。
+// DTEND 不包含在内：不在零点的结束时间顺延到次日零点，不晚于 DTSTART 或缺少 DTEND 和 DURATION 的全天事件持续一天
+func (item *CalendarItem) normalizeAllDay(loc *time.Location) {
+	if !item.AllDay {
+		return
+	}
+	if !item.DtStart.IsZero() {
+		item.DtStart = startOfDay(item.DtStart, loc)
+	}
+	if item.DtEnd != nil {
+		dtEnd := startOfDay(*item.DtEnd, loc)
+		if !startOfDay(*item.DtEnd, item.DtEnd.Location()).Equal(*item.DtEnd) {
+			dtEnd = dtEnd.AddDate(0, 0, 1)
+		}
+		if !item.DtStart.IsZero() && !dtEnd.After(item.DtStart) {
+			dtEnd = item.DtStart.AddDate(0, 0, 1)
+		}
+		item.DtEnd = &dtEnd
+	}
+	if item.Due != nil {
+		due := startOfDay(*item.Due, loc)
+		item.Due = &due
+	}
+	hasDuration := item.Duration != nil && *item.Duration != ""
+	if item.Type == CalendarItemTypeEvent && item.DtEnd == nil && !hasDuration && !item.DtStart.IsZero() {
+		dtEnd := item.DtStart.AddDate(0, 0, 1)
+		item.DtEnd = &dtEnd
+	}
+}
+
+// validateTimezone 检查请求中的时区名，为空表示 UTC
+func validateTimezone(tzid *string) error {
+	if tzid == nil || *tzid == "" {
+		return nil
+	}
+	if _, err := time.LoadLocation(*tzid); err != nil {
+		return fmt.Errorf("%w: 无效的时区 %q", ErrInvalidInput, *tzid)
+	}
+	return nil
+}
+
+// WithUserTimezone 设置查询用户时区的函数：创建日历项时没有指定时区则使用用户的时区，
+// 导入的 iCalendar 数据中的浮动时间和日期也按用户的时区解释。未设置时使用 UTC
+func WithUserTimezone(fn func(userID uint) *time.Location) ServiceOption {
+	return func(s *service) {
+		s.timezone = fn
+	}
+}
+
+// userLocation 返回用户的时区
+func (s *service) userLocation(userID *uint) *time.Location {
+	if s.timezone == nil || userID == nil {
+		return time.UTC
+	}
+	if loc := s.timezone(*userID); loc != nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// requestLocation 返回请求指定的时区，未指定时使用用户的时区
+func (s *service) requestLocation(userID *uint, tzid *string) (*time.Location, error) {
+	if tzid == nil {
+		return s.userLocation(userID), nil
+	}
+	if err := validateTimezone(tzid); err != nil {
+		return nil, err
+	}
+	return tzidLocation(tzid), nil
+}
+
+// ParseDuration 解析 RFC 5545 DURATION 值，例如 "PT1H30M"、"P1D"、"-PT15M"、"P2W"
+// 天和周按 24 小时计算
+func ParseDuration(value string) (time.Duration, error) {
+	s := strings.ToUpper(strings.TrimSpace(value))
+	if s == "" {
+		return 0, errors.New("持续时间为空")
+	}
+
+	sign := time.Duration(1)
+	switch s[0] {
+	case '-':
+		sign = -1
+		s = s[1:]
+	case '+':
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) < 3 {
+		return 0, fmt.Errorf("无效的持续时间 %q", value)
+	}
+	s = s[1:]
+
+	var total time.Duration
+	inTime := false
+	num := ""
+	for _, c := range s {
+		switch {
+		case c >= '0' && c <= '9':
+			num += string(c)
+		case c == 'T':
+			if inTime || num != "" {
+				return 0, fmt.Errorf("无效的持续时间 %q", value)
+			}
+			inTime = true
+		default:
+			if num == "" {
+				return 0, fmt.Errorf("无效的持续时间 %q", value)
+			}
+			n, err := strconv.Atoi(num)
+			if err != nil {
+				return 0, fmt.Errorf("无效的持续时间 %q", value)
+			}
+			num = ""
+
+			var unit time.Duration
+			switch {
+			case c == 'W' && !inTime:
+				unit = 7 * 24 * time.Hour
+			case c == 'D' && !inTime:
+				unit = 24 * time.Hour
+			case c == 'H' && inTime:
+				unit = time.Hour
+			case c == 'M' && inTime:
+				unit = time.Minute
+			case c == 'S' && inTime:
+				unit = time.Second
+			default:
+				return 0, fmt.Errorf("无效的持续时间 %q", value)
+			}
+			total += time.Duration(n) * unit
+		}
+	}
+	if num != "" {
+		return 0, fmt.Errorf("无效的持续时间 %q", value)
+	}
+
+	return sign * total, nil
+}
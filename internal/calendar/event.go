@@ -0,0 +1,64 @@
+package calendar
+
+import "time"
+
+// EventType 日历项生命周期事件类型
+type EventType string
+
+const (
+	EventCalendarItemCreated EventType = "calendar_item.created"
+	EventCalendarItemUpdated EventType = "calendar_item.updated"
+	EventCalendarItemDeleted EventType = "calendar_item.deleted"
+)
+
+// IsValid 检查事件类型是否有效
+func (t EventType) IsValid() bool {
+	switch t {
+	case EventCalendarItemCreated, EventCalendarItemUpdated, EventCalendarItemDeleted:
+		return true
+	}
+	return false
+}
+
+// Event 日历项生命周期事件，Item 为变更后的日历项（删除时为删除前的日历项）
+type Event struct {
+	Type       EventType
+	UserID     *uint
+	Item       *CalendarItem
+	OccurredAt time.Time
+}
+
+// EventListener 日历项生命周期事件的监听器
+// 在修改成功后同步调用，监听器不应执行耗时操作，也不能让修改失败
+type EventListener interface {
+	OnCalendarEvent(event *Event)
+}
+
+// EventListenerFunc 函数形式的 EventListener
+type EventListenerFunc func(event *Event)
+
+// OnCalendarEvent 实现 EventListener 接口
+func (f EventListenerFunc) OnCalendarEvent(event *Event) {
+	f(event)
+}
+
+// ServiceOption 日历服务选项函数
+type ServiceOption func(*service)
+
+// WithEventListener 注册日历项生命周期事件的监听器
+func WithEventListener(listener EventListener) ServiceOption {
+	return func(s *service) {
+		s.listeners = append(s.listeners, listener)
+	}
+}
+
+// emit 通知所有监听器
+func (s *service) emit(eventType EventType, userID *uint, item *CalendarItem) {
+	if len(s.listeners) == 0 {
+		return
+	}
+	event := &Event{Type: eventType, UserID: userID, Item: item, OccurredAt: time.Now()}
+	for _, listener := range s.listeners {
+		listener.OnCalendarEvent(event)
+	}
+}
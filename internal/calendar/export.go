@@ -0,0 +1,569 @@
+package calendar
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+)
+
+var ErrFeedTokenNotFound = errors.New("订阅令牌不存在或已撤销")
+
+// icalProdID 导出日历的 PRODID
+const icalProdID = "-//Otter//Calendar//ZH"
+
+// exportAllEnd 导出全部日历项时使用的窗口结束时间
+var exportAllEnd = time.Date(9999, 12, 31, 23, 59, 59, 0, time.UTC)
+
+// icalMaxLineOctets 内容行折叠前的最大长度（RFC 5545 3.1，不含 CRLF）
+const icalMaxLineOctets = 75
+
+// ExportCalendarRequest 导出日历请求，过滤条件与 ListCalendarItemsRequest 相同（不分页）
+// 指定时间窗口时导出与窗口重叠的日历项，重复日历项整体导出（包括 RRULE），由客户端展开
+type ExportCalendarRequest struct {
+	StartTime *time.Time        `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	EndTime   *time.Time        `form:"end_time" time_format:"2006-01-02T15:04:05Z07:00"`
+	Type      *CalendarItemType `form:"type"`
+}
+
+// CreateFeedTokenRequest 创建订阅令牌请求
+type CreateFeedTokenRequest struct {
+	Name *string `json:"name"`
+}
+
+// ExportICalendar 将用户的日历项导出为 VCALENDAR
+func (s *service) ExportICalendar(userID *uint, req *ExportCalendarRequest, w io.Writer) error {
+	start := time.Time{}
+	if req.StartTime != nil {
+		start = *req.StartTime
+	}
+	// 没有结束时间时导出所有日历项
+	end := exportAllEnd
+	if req.EndTime != nil {
+		end = *req.EndTime
+	}
+
+	items, err := s.repo.ListCalendarItemsInRange(userID, start, end, req.Type)
+	if err != nil {
+		return fmt.Errorf("获取日历项列表失败: %w", err)
+	}
+
+	return WriteICalendar(w, "Otter", items)
+}
+
+// CreateFeedToken 为用户创建新的订阅令牌
+func (s *service) CreateFeedToken(userID uint, req *CreateFeedTokenRequest) (*CalendarFeedToken, error) {
+	token, err := generateFeedToken()
+	if err != nil {
+		return nil, fmt.Errorf("生成订阅令牌失败: %w", err)
+	}
+
+	feedToken := &CalendarFeedToken{
+		Token:  token,
+		UserID: userID,
+		Name:   req.Name,
+	}
+	if err := s.repo.CreateFeedToken(feedToken); err != nil {
+		return nil, fmt.Errorf("创建订阅令牌失败: %w", err)
+	}
+	return feedToken, nil
+}
+
+// ListFeedTokens 列出用户的订阅令牌
+func (s *service) ListFeedTokens(userID uint) ([]*CalendarFeedToken, error) {
+	tokens, err := s.repo.ListFeedTokens(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取订阅令牌列表失败: %w", err)
+	}
+	return tokens, nil
+}
+
+// RevokeFeedToken 撤销用户的订阅令牌，撤销后订阅地址立即失效
+func (s *service) RevokeFeedToken(userID uint, id uint) error {
+	if err := s.repo.RevokeFeedToken(userID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFeedTokenNotFound
+		}
+		return fmt.Errorf("撤销订阅令牌失败: %w", err)
+	}
+	return nil
+}
+
+// ExportFeed 通过订阅令牌导出令牌所属用户的全部日历项
+func (s *service) ExportFeed(token string, w io.Writer) error {
+	feedToken, err := s.repo.GetFeedToken(token)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrFeedTokenNotFound
+		}
+		return fmt.Errorf("获取订阅令牌失败: %w", err)
+	}
+
+	if err := s.repo.TouchFeedToken(feedToken.ID, time.Now()); err != nil {
+		return fmt.Errorf("更新订阅令牌失败: %w", err)
+	}
+
+	name := "Otter"
+	if feedToken.Name != nil && *feedToken.Name != "" {
+		name = *feedToken.Name
+	}
+
+	userID := feedToken.UserID
+	items, err := s.repo.ListCalendarItemsInRange(&userID, time.Time{}, exportAllEnd, nil)
+	if err != nil {
+		return fmt.Errorf("获取日历项列表失败: %w", err)
+	}
+
+	return WriteICalendar(w, name, items)
+}
+
+// generateFeedToken 生成随机的订阅令牌（256 位，URL 安全）
+func generateFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// WriteICalendar 将日历项序列化为 VCALENDAR
+// 同一 UID 的主日历项和例外实例相邻输出，主日历项在前
+func WriteICalendar(w io.Writer, name string, items []*CalendarItem) error {
+	sorted := make([]*CalendarItem, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].UID != sorted[j].UID {
+			return sorted[i].UID < sorted[j].UID
+		}
+		return sorted[i].RecurrenceID == nil && sorted[j].RecurrenceID != nil
+	})
+
+	iw := &icalWriter{w: w}
+	iw.line("BEGIN", nil, "VCALENDAR")
+	iw.line("VERSION", nil, "2.0")
+	iw.line("PRODID", nil, icalProdID)
+	iw.line("CALSCALE", nil, "GREGORIAN")
+	iw.text("X-WR-CALNAME", &name)
+	for _, zone := range itemTimezones(sorted) {
+		iw.timezone(zone.tzid, zone.year)
+	}
+
+	dtstamp := time.Now()
+	for _, item := range sorted {
+		iw.item(item, dtstamp)
+	}
+
+	iw.line("END", nil, "VCALENDAR")
+	return iw.err
+}
+
+// icalWriter 按 RFC 5545 输出内容行：TEXT 转义、CRLF 换行、超过 75 字节时折叠
+// 第一次写入失败后忽略后续写入，错误保存在 err 中
+type icalWriter struct {
+	w   io.Writer
+	err error
+}
+
+// item 输出一个日历项组件（VEVENT、VTODO 等）及其提醒
+func (iw *icalWriter) item(item *CalendarItem, dtstamp time.Time) {
+	name := string(item.Type)
+	iw.line("BEGIN", nil, name)
+	iw.line("UID", nil, item.UID)
+	iw.time("DTSTAMP", &dtstamp)
+	// 全天日历项的时间只输出日期（VALUE=DATE）
+	zonedTime := iw.zonedTime
+	if item.AllDay {
+		zonedTime = iw.date
+	}
+	if item.RecurrenceID != nil && item.AllDay {
+		iw.date("RECURRENCE-ID", item.RecurrenceID, item.DtStartTZID)
+	} else if item.RecurrenceID != nil {
+		iw.time("RECURRENCE-ID", item.RecurrenceID)
+	}
+	// VTODO 可以只有 DUE，此时 DtStart 为零值
+	if !item.DtStart.IsZero() {
+		zonedTime("DTSTART", &item.DtStart, item.DtStartTZID)
+	}
+	zonedTime("DTEND", item.DtEnd, item.DtEndTZID)
+	zonedTime("DUE", item.Due, item.DueTZID)
+	iw.time("COMPLETED", item.Completed)
+	if item.Duration != nil && *item.Duration != "" {
+		iw.line("DURATION", nil, *item.Duration)
+	}
+	iw.text("SUMMARY", item.Summary)
+	iw.text("DESCRIPTION", item.Description)
+	iw.text("LOCATION", item.Location)
+	if item.Organizer != nil && *item.Organizer != "" {
+		iw.line("ORGANIZER", nil, *item.Organizer)
+	}
+	for i := range item.Attendees {
+		iw.line("ATTENDEE", attendeeParams(&item.Attendees[i]), item.Attendees[i].Address)
+	}
+	if item.Status != nil && *item.Status != "" {
+		iw.line("STATUS", nil, strings.ToUpper(*item.Status))
+	}
+	iw.int("PRIORITY", item.Priority)
+	iw.int("PERCENT-COMPLETE", item.PercentComplete)
+	iw.int("SEQUENCE", item.Sequence)
+	if item.RRule != nil && *item.RRule != "" {
+		iw.line("RRULE", nil, strings.TrimPrefix(*item.RRule, "RRULE:"))
+	}
+	iw.instances("EXDATE", item.ExDate)
+	iw.instances("RDATE", item.RDate)
+	iw.list("CATEGORIES", item.Categories)
+	iw.text("COMMENT", item.Comment)
+	iw.text("CONTACT", item.Contact)
+	iw.text("RELATED-TO", item.RelatedTo)
+	iw.list("RESOURCES", item.Resources)
+	if item.URL != nil && *item.URL != "" {
+		iw.line("URL", nil, *item.URL)
+	}
+	if item.Class != nil && *item.Class != "" {
+		iw.line("CLASS", nil, strings.ToUpper(*item.Class))
+	}
+	if item.Transp != nil && *item.Transp != "" {
+		iw.line("TRANSP", nil, strings.ToUpper(*item.Transp))
+	}
+	if !item.CreatedAt.IsZero() {
+		iw.time("CREATED", &item.CreatedAt)
+	}
+	iw.time("LAST-MODIFIED", item.LastModified)
+
+	for i := range item.Alarms {
+		iw.alarm(&item.Alarms[i], item.Summary)
+	}
+
+	iw.line("END", nil, name)
+}
+
+// alarm 输出 VALARM 组件
+// DISPLAY 和 EMAIL 提醒必须包含 DESCRIPTION，没有时使用日历项的摘要
+func (iw *icalWriter) alarm(alarm *Valarm, summary *string) {
+	iw.line("BEGIN", nil, "VALARM")
+	iw.line("ACTION", nil, string(alarm.Action))
+
+	var params []string
+	if alarm.IsEndRelated() {
+		params = append(params, "RELATED=END")
+	}
+	trigger := alarm.Trigger
+	if t, isDate, err := parseDateTimeValue(trigger, time.UTC); err == nil && !isDate {
+		params = append(params, "VALUE=DATE-TIME")
+		trigger = t.UTC().Format(icalDateTimeUTCLayout)
+	}
+	iw.line("TRIGGER", params, trigger)
+
+	description := alarm.Description
+	if (description == nil || *description == "") && alarm.Action != ValarmActionAudio {
+		fallback := "Reminder"
+		if summary != nil && *summary != "" {
+			fallback = *summary
+		}
+		description = &fallback
+	}
+	iw.text("DESCRIPTION", description)
+	iw.text("SUMMARY", alarm.Summary)
+	if alarm.Attendee != nil && *alarm.Attendee != "" {
+		iw.line("ATTENDEE", nil, *alarm.Attendee)
+	}
+	if alarm.Duration != nil && *alarm.Duration != "" {
+		iw.line("DURATION", nil, *alarm.Duration)
+	}
+	iw.int("REPEAT", alarm.RepeatCount)
+
+	names := make([]string, 0, len(alarm.XProperty))
+	for name := range alarm.XProperty {
+		if strings.HasPrefix(strings.ToUpper(name), "X-") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		iw.line(strings.ToUpper(name), nil, fmt.Sprint(alarm.XProperty[name]))
+	}
+
+	iw.line("END", nil, "VALARM")
+}
+
+// time 输出 UTC 的 DATE-TIME 属性
+func (iw *icalWriter) time(name string, t *time.Time) {
+	if t == nil {
+		return
+	}
+	iw.line(name, nil, t.UTC().Format(icalDateTimeUTCLayout))
+}
+
+// zonedTime 输出 DATE-TIME 属性：有时区时输出该时区的本地时间并带 TZID 参数，否则输出 UTC
+func (iw *icalWriter) zonedTime(name string, t *time.Time, tzid *string) {
+	if t == nil {
+		return
+	}
+	loc := tzidLocation(tzid)
+	if loc == time.UTC {
+		iw.time(name, t)
+		return
+	}
+	iw.line(name, []string{"TZID=" + *tzid}, t.In(loc).Format(icalDateTimeLayout))
+}
+
+// date 输出 DATE 属性：日期按时区 tzid 计算
+func (iw *icalWriter) date(name string, t *time.Time, tzid *string) {
+	if t == nil {
+		return
+	}
+	iw.line(name, []string{"VALUE=DATE"}, t.In(tzidLocation(tzid)).Format(icalDateLayout))
+}
+
+// icalZone 导出的日历项引用的时区，year 为引用该时区的最早年份
+type icalZone struct {
+	tzid string
+	year int
+}
+
+// itemTimezones 收集日历项引用的时区（RFC 5545 要求每个 TZID 都有对应的 VTIMEZONE），按 TZID 排序
+func itemTimezones(items []*CalendarItem) []icalZone {
+	years := map[string]int{}
+	add := func(tzid *string, t *time.Time) {
+		if t == nil || tzidLocation(tzid) == time.UTC {
+			return
+		}
+		if year, ok := years[*tzid]; !ok || t.Year() < year {
+			years[*tzid] = t.Year()
+		}
+	}
+	for _, item := range items {
+		// 全天日历项只输出日期，不引用时区
+		if item.AllDay {
+			continue
+		}
+		if !item.DtStart.IsZero() {
+			add(item.DtStartTZID, &item.DtStart)
+		}
+		add(item.DtEndTZID, item.DtEnd)
+		add(item.DueTZID, item.Due)
+	}
+
+	zones := make([]icalZone, 0, len(years))
+	for tzid, year := range years {
+		zones = append(zones, icalZone{tzid: tzid, year: year})
+	}
+	sort.Slice(zones, func(i, j int) bool { return zones[i].tzid < zones[j].tzid })
+	return zones
+}
+
+// timezone 输出 VTIMEZONE 组件（RFC 5545 3.6.5）
+// 按 year 年的偏移变化生成每年重复的 STANDARD/DAYLIGHT 规则；没有夏令时的时区只输出一个 STANDARD
+func (iw *icalWriter) timezone(tzid string, year int) {
+	loc := tzidLocation(&tzid)
+	iw.line("BEGIN", nil, "VTIMEZONE")
+	iw.line("TZID", nil, tzid)
+	iw.line("X-LIC-LOCATION", nil, tzid)
+
+	transitions := zoneTransitions(loc, year)
+	if len(transitions) == 0 {
+		start := time.Date(year, 1, 1, 0, 0, 0, 0, loc)
+		name, offset := start.Zone()
+		iw.line("BEGIN", nil, "STANDARD")
+		iw.line("DTSTART", nil, start.Format(icalDateTimeLayout))
+		iw.line("TZOFFSETFROM", nil, formatUTCOffset(offset))
+		iw.line("TZOFFSETTO", nil, formatUTCOffset(offset))
+		iw.line("TZNAME", nil, name)
+		iw.line("END", nil, "STANDARD")
+	}
+	for _, at := range transitions {
+		_, from := at.Add(-time.Second).Zone()
+		name, to := at.Zone()
+		component := "STANDARD"
+		if at.IsDST() {
+			component = "DAYLIGHT"
+		}
+		// 规则的 DTSTART 是切换前的当地时间
+		local := at.In(time.FixedZone("", from))
+		iw.line("BEGIN", nil, component)
+		iw.line("DTSTART", nil, local.Format(icalDateTimeLayout))
+		iw.line("RRULE", nil, yearlyWeekdayRule(local))
+		iw.line("TZOFFSETFROM", nil, formatUTCOffset(from))
+		iw.line("TZOFFSETTO", nil, formatUTCOffset(to))
+		iw.line("TZNAME", nil, name)
+		iw.line("END", nil, component)
+	}
+	iw.line("END", nil, "VTIMEZONE")
+}
+
+// zoneTransitions 返回时区在 year 年内偏移发生变化的时刻（精确到秒）
+func zoneTransitions(loc *time.Location, year int) []time.Time {
+	var transitions []time.Time
+	end := time.Date(year+1, 1, 1, 0, 0, 0, 0, time.UTC)
+	for t := time.Date(year, 1, 1, 0, 0, 0, 0, time.UTC); t.Before(end); t = t.Add(24 * time.Hour) {
+		_, before := t.In(loc).Zone()
+		next := t.Add(24 * time.Hour)
+		if _, after := next.In(loc).Zone(); after == before {
+			continue
+		}
+		// 二分查找偏移变化的第一秒
+		lo, hi := t, next
+		for hi.Sub(lo) > time.Second {
+			mid := lo.Add(hi.Sub(lo) / 2).Truncate(time.Second)
+			if _, offset := mid.In(loc).Zone(); offset == before {
+				lo = mid
+			} else {
+				hi = mid
+			}
+		}
+		transitions = append(transitions, hi.In(loc))
+	}
+	return transitions
+}
+
+// yearlyWeekdayRule 将切换日期表示为每年第 N 个（或最后一个）星期几的 RRULE，例如 3 月第二个星期日
+func yearlyWeekdayRule(t time.Time) string {
+	n := strconv.Itoa((t.Day()-1)/7 + 1)
+	if t.AddDate(0, 0, 7).Month() != t.Month() {
+		n = "-1"
+	}
+	return fmt.Sprintf("FREQ=YEARLY;BYMONTH=%d;BYDAY=%s%s", int(t.Month()), n, weekdayNames[t.Weekday()])
+}
+
+// formatUTCOffset 格式化 UTC-OFFSET 值，例如 "+0800"、"-0500"、"+053000"
+func formatUTCOffset(seconds int) string {
+	sign := '+'
+	if seconds < 0 {
+		sign = '-'
+		seconds = -seconds
+	}
+	value := fmt.Sprintf("%c%02d%02d", sign, seconds/3600, seconds/60%60)
+	if seconds%60 != 0 {
+		value += fmt.Sprintf("%02d", seconds%60)
+	}
+	return value
+}
+
+// text 输出 TEXT 属性
+func (iw *icalWriter) text(name string, value *string) {
+	if value == nil || *value == "" {
+		return
+	}
+	iw.line(name, nil, escapeICalText(*value))
+}
+
+// int 输出 INTEGER 属性
+func (iw *icalWriter) int(name string, value *int) {
+	if value == nil {
+		return
+	}
+	iw.line(name, nil, strconv.Itoa(*value))
+}
+
+// list 输出以逗号分隔的 TEXT 列表属性
+func (iw *icalWriter) list(name string, values []string) {
+	if len(values) == 0 {
+		return
+	}
+	escaped := make([]string, len(values))
+	for i, v := range values {
+		escaped[i] = escapeICalText(v)
+	}
+	iw.line(name, nil, strings.Join(escaped, ","))
+}
+
+// instances 输出 EXDATE/RDATE，按值类型（DATE、DATE-TIME、PERIOD）分组，每组一行
+func (iw *icalWriter) instances(name string, values []string) {
+	groups := map[string][]string{}
+	var order []string
+	for _, value := range values {
+		valueType, formatted, ok := formatInstanceValue(value)
+		if !ok {
+			continue
+		}
+		if _, exists := groups[valueType]; !exists {
+			order = append(order, valueType)
+		}
+		groups[valueType] = append(groups[valueType], formatted)
+	}
+	for _, valueType := range order {
+		var params []string
+		if valueType != "DATE-TIME" {
+			params = []string{"VALUE=" + valueType}
+		}
+		iw.line(name, params, strings.Join(groups[valueType], ","))
+	}
+}
+
+// formatInstanceValue 将存储的 EXDATE/RDATE 值格式化为 iCalendar 值，返回值类型
+// 浮动时间按 UTC 处理（与展开重复日历项时一致）
+func formatInstanceValue(value string) (valueType, formatted string, ok bool) {
+	start, rest, isPeriod := strings.Cut(strings.TrimSpace(value), "/")
+	t, isDate, err := parseDateTimeValue(start, time.UTC)
+	if err != nil {
+		return "", "", false
+	}
+	if isDate {
+		return "DATE", t.Format(icalDateLayout), true
+	}
+	formatted = t.UTC().Format(icalDateTimeUTCLayout)
+	if isPeriod {
+		return "PERIOD", formatted + "/" + rest, true
+	}
+	return "DATE-TIME", formatted, true
+}
+
+// line 输出一个内容行：name *(";" param) ":" value
+func (iw *icalWriter) line(name string, params []string, value string) {
+	if iw.err != nil {
+		return
+	}
+	var b strings.Builder
+	b.WriteString(name)
+	for _, p := range params {
+		b.WriteByte(';')
+		b.WriteString(p)
+	}
+	b.WriteByte(':')
+	b.WriteString(value)
+	_, iw.err = io.WriteString(iw.w, foldICalLine(b.String()))
+}
+
+// foldICalLine 将内容行按 75 字节折叠（不拆分 UTF-8 字符），续行以空格开头，每行以 CRLF 结尾
+func foldICalLine(line string) string {
+	var b strings.Builder
+	limit := icalMaxLineOctets
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		// 续行开头的空格占用一个字节
+		limit = icalMaxLineOctets - 1
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+	return b.String()
+}
+
+// escapeICalText 转义 TEXT 值（RFC 5545 3.3.11）
+func escapeICalText(s string) string {
+	var b strings.Builder
+	for _, r := range strings.ReplaceAll(s, "\r\n", "\n") {
+		switch r {
+		case '\\', ';', ',':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString("\\n")
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,269 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// TestWriteICalendar 测试导出的 VCALENDAR 可以被重新解析，且内容一致
+func TestWriteICalendar(t *testing.T) {
+	summary := "项目评审, 第一轮; 请准时"
+	description := "第一行\n第二行，这一行很长需要折行显示，这一行很长需要折行显示，这一行很长需要折行显示"
+	rrule := "FREQ=WEEKLY;BYDAY=MO;COUNT=4"
+	duration := "PT1H30M"
+	class := "private"
+	sequence := 2
+	alarmDescription := "评审即将开始"
+	recurrenceID := utcTime(2025, 1, 13, 1, 0)
+	overrideStart := utcTime(2025, 1, 13, 3, 0)
+
+	items := []*CalendarItem{
+		{
+			UID:          "event-1@example.com",
+			Type:         CalendarItemTypeEvent,
+			DtStart:      overrideStart,
+			Summary:      &summary,
+			RecurrenceID: &recurrenceID,
+		},
+		{
+			UID:         "event-1@example.com",
+			Type:        CalendarItemTypeEvent,
+			Summary:     &summary,
+			Description: &description,
+			DtStart:     utcTime(2025, 1, 6, 1, 0),
+			Duration:    &duration,
+			RRule:       &rrule,
+			ExDate:      StringArray{"20250120T010000Z", "2025-01-27"},
+			Categories:  StringArray{"工作", "评审,会议"},
+			Class:       &class,
+			Sequence:    &sequence,
+			Alarms: []Valarm{
+				{Action: ValarmActionDisplay, Trigger: "-PT15M", Description: &alarmDescription},
+				{Action: ValarmActionEmail, Trigger: "PT0S", XProperty: JSONB{"TRIGGER-RELATED": "END", "X-WR-ALARMUID": "alarm-1"}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteICalendar(&buf, "我的日历", items))
+	data := buf.String()
+
+	for _, line := range strings.Split(strings.TrimSuffix(data, "\r\n"), "\r\n") {
+		assert.LessOrEqual(t, len(line), icalMaxLineOctets, line)
+	}
+	assert.Contains(t, data, "EXDATE:20250120T010000Z\r\n")
+	assert.Contains(t, data, "EXDATE;VALUE=DATE:20250127\r\n")
+	assert.Contains(t, data, "TRIGGER;RELATED=END:PT0S\r\n")
+
+	calendars, err := parseICalendar(strings.NewReader(data))
+	require.NoError(t, err)
+	cal := calendars[0]
+	assert.Equal(t, "我的日历", *cal.Text("X-WR-CALNAME"))
+	require.Len(t, cal.Components, 2)
+
+	// 主日历项在例外实例之前
+	master, warnings, err := calendarItemFromComponent(cal.Components[0], icalTimezones{})
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Nil(t, master.RecurrenceID)
+	assert.Equal(t, summary, *master.Summary)
+	assert.Equal(t, description, *master.Description)
+	assert.Equal(t, rrule, *master.RRule)
+	assert.Equal(t, StringArray{"20250120T010000Z", "20250127"}, master.ExDate)
+	assert.Equal(t, StringArray{"工作", "评审,会议"}, master.Categories)
+	assert.Equal(t, "PRIVATE", *master.Class)
+	assert.Equal(t, 2, *master.Sequence)
+	require.Len(t, master.Alarms, 2)
+	assert.Equal(t, alarmDescription, *master.Alarms[0].Description)
+	assert.Equal(t, summary, *master.Alarms[1].Description)
+	assert.Equal(t, "END", master.Alarms[1].XProperty["TRIGGER-RELATED"])
+	assert.Equal(t, "alarm-1", master.Alarms[1].XProperty["X-WR-ALARMUID"])
+
+	override, _, err := calendarItemFromComponent(cal.Components[1], icalTimezones{})
+	require.NoError(t, err)
+	assert.True(t, override.RecurrenceID.Equal(recurrenceID))
+	assert.True(t, override.DtStart.Equal(overrideStart))
+}
+
+// TestWriteICalendar_TZID 测试有时区的时间按当地时间输出，并附带对应的 VTIMEZONE
+func TestWriteICalendar_TZID(t *testing.T) {
+	newYork := "America/New_York"
+	shanghai := "Asia/Shanghai"
+	dtEnd := utcTime(2025, 1, 6, 15, 0)
+	items := []*CalendarItem{
+		{UID: "ny", Type: CalendarItemTypeEvent, DtStart: utcTime(2025, 1, 6, 14, 0), DtEnd: &dtEnd, DtStartTZID: &newYork, DtEndTZID: &newYork},
+		{UID: "sh", Type: CalendarItemTypeEvent, DtStart: utcTime(2025, 1, 6, 1, 0), DtStartTZID: &shanghai},
+		{UID: "utc", Type: CalendarItemTypeEvent, DtStart: utcTime(2025, 1, 6, 1, 0)},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteICalendar(&buf, "Otter", items))
+	data := buf.String()
+
+	assert.Contains(t, data, "DTSTART;TZID=America/New_York:20250106T090000\r\n")
+	assert.Contains(t, data, "DTEND;TZID=America/New_York:20250106T100000\r\n")
+	assert.Contains(t, data, "DTSTART;TZID=Asia/Shanghai:20250106T090000\r\n")
+	assert.Contains(t, data, "DTSTART:20250106T010000Z\r\n")
+	assert.Equal(t, 2, strings.Count(data, "BEGIN:VTIMEZONE\r\n"))
+	assert.Contains(t, data, "BEGIN:DAYLIGHT\r\nDTSTART:20250309T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=3;BYDAY=2SU\r\nTZOFFSETFROM:-0500\r\nTZOFFSETTO:-0400\r\n")
+	assert.Contains(t, data, "BEGIN:STANDARD\r\nDTSTART:20251102T020000\r\nRRULE:FREQ=YEARLY;BYMONTH=11;BYDAY=1SU\r\nTZOFFSETFROM:-0400\r\nTZOFFSETTO:-0500\r\n")
+	assert.Contains(t, data, "TZOFFSETFROM:+0800\r\nTZOFFSETTO:+0800\r\n")
+
+	// 重新导入后时刻和时区不变
+	calendars, err := parseICalendar(strings.NewReader(data))
+	require.NoError(t, err)
+	tz := newICalTimezones(calendars[0], nil)
+	var events []*icalComponent
+	for _, comp := range calendars[0].Components {
+		if comp.Name == "VEVENT" {
+			events = append(events, comp)
+		}
+	}
+	require.Len(t, events, 3)
+	item, _, err := calendarItemFromComponent(events[0], tz)
+	require.NoError(t, err)
+	assert.True(t, item.DtStart.Equal(utcTime(2025, 1, 6, 14, 0)))
+	assert.Equal(t, newYork, *item.DtStartTZID)
+	assert.True(t, item.DtEnd.Equal(dtEnd))
+}
+
+// TestWriteICalendar_AllDay 测试全天日历项输出 VALUE=DATE，重新导入后仍是全天日历项
+func TestWriteICalendar_AllDay(t *testing.T) {
+	tzid := "Asia/Shanghai"
+	shanghai, err := time.LoadLocation(tzid)
+	require.NoError(t, err)
+	dtEnd := time.Date(2024, 5, 9, 0, 0, 0, 0, shanghai)
+	items := []*CalendarItem{{
+		UID:         "trip",
+		Type:        CalendarItemTypeEvent,
+		DtStart:     time.Date(2024, 5, 6, 0, 0, 0, 0, shanghai),
+		DtEnd:       &dtEnd,
+		DtStartTZID: &tzid,
+		DtEndTZID:   &tzid,
+		AllDay:      true,
+	}}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteICalendar(&buf, "Otter", items))
+	data := buf.String()
+
+	assert.Contains(t, data, "DTSTART;VALUE=DATE:20240506\r\n")
+	assert.Contains(t, data, "DTEND;VALUE=DATE:20240509\r\n")
+	assert.NotContains(t, data, "VTIMEZONE")
+
+	calendars, err := parseICalendar(strings.NewReader(data))
+	require.NoError(t, err)
+	item, _, err := calendarItemFromComponent(calendars[0].Components[0], newICalTimezones(calendars[0], shanghai))
+	require.NoError(t, err)
+	assert.True(t, item.AllDay)
+	assert.True(t, item.DtStart.Equal(items[0].DtStart))
+	assert.True(t, item.DtEnd.Equal(dtEnd))
+	assert.Equal(t, tzid, *item.DtEndTZID)
+}
+
+// TestFoldICalLine 测试折叠行不拆分多字节字符
+func TestFoldICalLine(t *testing.T) {
+	line := "SUMMARY:" + strings.Repeat("日", 40)
+
+	folded := foldICalLine(line)
+
+	parts := strings.Split(strings.TrimSuffix(folded, "\r\n"), "\r\n ")
+	require.Greater(t, len(parts), 1)
+	for _, part := range parts {
+		assert.LessOrEqual(t, len(part), icalMaxLineOctets)
+		assert.True(t, strings.ToValidUTF8(part, "") == part)
+	}
+	assert.Equal(t, line, strings.Join(parts, ""))
+	assert.Equal(t, "UID:1\r\n", foldICalLine("UID:1"))
+}
+
+// TestService_ExportICalendar 测试按过滤条件导出
+func TestService_ExportICalendar(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	start := utcTime(2025, 1, 1, 0, 0)
+	itemType := CalendarItemTypeEvent
+	items := []*CalendarItem{{UID: "event-1", Type: CalendarItemTypeEvent, DtStart: utcTime(2025, 1, 6, 9, 0)}}
+	mockRepo.On("ListCalendarItemsInRange", &userID, start, exportAllEnd, &itemType).Return(items, nil)
+
+	var buf bytes.Buffer
+	err := service.ExportICalendar(&userID, &ExportCalendarRequest{StartTime: &start, Type: &itemType}, &buf)
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(buf.String(), "BEGIN:VCALENDAR\r\nVERSION:2.0\r\n"))
+	assert.Contains(t, buf.String(), "UID:event-1\r\nDTSTAMP:")
+	assert.Contains(t, buf.String(), "DTSTART:20250106T090000Z\r\n")
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_ExportFeed 测试通过订阅令牌导出
+func TestService_ExportFeed(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(3)
+	name := "手机"
+	mockRepo.On("GetFeedToken", "secret").Return(&CalendarFeedToken{ID: 5, UserID: userID, Token: "secret", Name: &name}, nil)
+	mockRepo.On("TouchFeedToken", uint(5), mock.AnythingOfType("time.Time")).Return(nil)
+	mockRepo.On("ListCalendarItemsInRange", &userID, time.Time{}, exportAllEnd, (*CalendarItemType)(nil)).Return([]*CalendarItem{}, nil)
+
+	var buf bytes.Buffer
+	err := service.ExportFeed("secret", &buf)
+
+	require.NoError(t, err)
+	assert.Contains(t, buf.String(), "X-WR-CALNAME:手机\r\n")
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_ExportFeed_Revoked 测试已撤销或不存在的订阅令牌
+func TestService_ExportFeed_Revoked(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	mockRepo.On("GetFeedToken", "revoked").Return(nil, gorm.ErrRecordNotFound)
+
+	var buf bytes.Buffer
+	err := service.ExportFeed("revoked", &buf)
+
+	assert.ErrorIs(t, err, ErrFeedTokenNotFound)
+	assert.Zero(t, buf.Len())
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_CreateFeedToken 测试创建订阅令牌
+func TestService_CreateFeedToken(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	mockRepo.On("CreateFeedToken", mock.MatchedBy(func(token *CalendarFeedToken) bool {
+		return token.UserID == 1 && len(token.Token) == 43
+	})).Return(nil)
+
+	token, err := service.CreateFeedToken(1, &CreateFeedTokenRequest{})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, token.Token)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_RevokeFeedToken_NotFound 测试撤销不存在的订阅令牌
+func TestService_RevokeFeedToken_NotFound(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	mockRepo.On("RevokeFeedToken", uint(1), uint(9)).Return(gorm.ErrRecordNotFound)
+
+	err := service.RevokeFeedToken(1, 9)
+
+	assert.ErrorIs(t, err, ErrFeedTokenNotFound)
+	mockRepo.AssertExpectations(t)
+}
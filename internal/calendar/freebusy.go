@@ -0,0 +1,255 @@
+package calendar
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxFreeBusyRange 一次忙闲查询允许的最大时间跨度
+const maxFreeBusyRange = 366 * 24 * time.Hour
+
+// FreeBusyType 忙碌时段类型（FBTYPE）
+type FreeBusyType string
+
+const (
+	FreeBusyTypeBusy          FreeBusyType = "BUSY"           // 已确认的日历项
+	FreeBusyTypeBusyTentative FreeBusyType = "BUSY-TENTATIVE" // 状态为 TENTATIVE 的日历项
+)
+
+// FreeBusyRequest 忙闲查询请求，时间窗口为 [Start, End)
+type FreeBusyRequest struct {
+	Start   time.Time `json:"start" binding:"required"`
+	End     time.Time `json:"end" binding:"required"`
+	UserIDs []uint    `json:"user_ids" binding:"required,min=1,max=20,dive,min=1"`
+}
+
+// BusyPeriod 忙碌时段 [Start, End)
+type BusyPeriod struct {
+	Start time.Time    `json:"start"`
+	End   time.Time    `json:"end"`
+	Type  FreeBusyType `json:"type"`
+}
+
+// UserFreeBusy 一个用户在查询窗口内的忙碌时段
+// Items 只在查询自己时返回，其它用户只能看到忙碌时段
+type UserFreeBusy struct {
+	UserID uint            `json:"user_id"`
+	Busy   []BusyPeriod    `json:"busy"`
+	Items  []*CalendarItem `json:"items,omitempty"`
+}
+
+// FreeBusyResponse 忙闲查询结果，ICalendar 为包含每个用户 VFREEBUSY 组件的 VCALENDAR
+type FreeBusyResponse struct {
+	Start     time.Time       `json:"start"`
+	End       time.Time       `json:"end"`
+	Users     []*UserFreeBusy `json:"users"`
+	ICalendar string          `json:"icalendar"`
+}
+
+// GetFreeBusy 查询用户在时间窗口内的忙碌时段
+// 重复日历项展开为实例；状态为 CANCELLED 或 TRANSP 为 TRANSPARENT 的事件不占用时间；
+// 同类型的重叠时段会被合并，试探性时段中与已确认时段重叠的部分会被去掉
+func (s *service) GetFreeBusy(requesterID uint, req *FreeBusyRequest) (*FreeBusyResponse, error) {
+	if !req.End.After(req.Start) {
+		return nil, fmt.Errorf("%w: 结束时间必须晚于开始时间", ErrInvalidInput)
+	}
+	if req.End.Sub(req.Start) > maxFreeBusyRange {
+		return nil, fmt.Errorf("%w: 查询时间跨度不能超过 366 天", ErrInvalidInput)
+	}
+
+	start, end := req.Start.UTC(), req.End.UTC()
+	result := &FreeBusyResponse{Start: start, End: end}
+	seen := make(map[uint]bool, len(req.UserIDs))
+	for _, userID := range req.UserIDs {
+		if seen[userID] {
+			continue
+		}
+		seen[userID] = true
+
+		busy, items, err := s.busyPeriods(&userID, start, end)
+		if err != nil {
+			return nil, fmt.Errorf("获取忙闲信息失败: %w", err)
+		}
+		userFreeBusy := &UserFreeBusy{UserID: userID, Busy: busy}
+		if userID == requesterID {
+			userFreeBusy.Items = items
+		}
+		result.Users = append(result.Users, userFreeBusy)
+	}
+
+	var buf strings.Builder
+	if err := WriteFreeBusy(&buf, result, time.Now()); err != nil {
+		return nil, fmt.Errorf("生成 VFREEBUSY 失败: %w", err)
+	}
+	result.ICalendar = buf.String()
+	return result, nil
+}
+
+// busyPeriods 计算用户在 [start, end) 内合并后的忙碌时段，同时返回占用时间的事件实例
+func (s *service) busyPeriods(userID *uint, start, end time.Time) ([]BusyPeriod, []*CalendarItem, error) {
+	eventType := CalendarItemTypeEvent
+	items, err := s.repo.ListCalendarItemsInRange(userID, start, end, &eventType)
+	if err != nil {
+		return nil, nil, err
+	}
+	overridden, err := s.overriddenInstances(userID, items)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var periods []BusyPeriod
+	busyItems := make([]*CalendarItem, 0)
+	for _, occ := range expandCalendarItems(items, overridden, start, end) {
+		if !occupiesTime(occ) {
+			continue
+		}
+		occEnd := occ.DtStart.Add(occ.OccurrenceDuration())
+		if !occEnd.After(occ.DtStart) || !occEnd.After(start) || !occ.DtStart.Before(end) {
+			continue
+		}
+
+		fbType := FreeBusyTypeBusy
+		if occ.Status != nil && strings.EqualFold(*occ.Status, "TENTATIVE") {
+			fbType = FreeBusyTypeBusyTentative
+		}
+		periods = append(periods, BusyPeriod{
+			Start: maxTime(occ.DtStart, start).UTC(),
+			End:   minTime(occEnd, end).UTC(),
+			Type:  fbType,
+		})
+		busyItems = append(busyItems, occ)
+	}
+	return mergeBusyPeriods(periods), busyItems, nil
+}
+
+// occupiesTime 事件是否占用时间：已取消或标记为 TRANSPARENT 的事件不占用
+func occupiesTime(item *CalendarItem) bool {
+	if item.Status != nil && strings.EqualFold(*item.Status, "CANCELLED") {
+		return false
+	}
+	if item.Transp != nil && strings.EqualFold(*item.Transp, "TRANSPARENT") {
+		return false
+	}
+	return true
+}
+
+// mergeBusyPeriods 合并重叠或相接的忙碌时段，并按开始时间排序
+// 已确认时段优先：试探性时段只保留不与已确认时段重叠的部分
+func mergeBusyPeriods(periods []BusyPeriod) []BusyPeriod {
+	var busy, tentative []BusyPeriod
+	for _, p := range periods {
+		if p.Type == FreeBusyTypeBusyTentative {
+			tentative = append(tentative, p)
+		} else {
+			busy = append(busy, p)
+		}
+	}
+	busy = mergeOverlapping(busy)
+	tentative = subtractPeriods(mergeOverlapping(tentative), busy)
+
+	result := append(busy, tentative...)
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Start.Before(result[j].Start)
+	})
+	if result == nil {
+		result = []BusyPeriod{}
+	}
+	return result
+}
+
+// mergeOverlapping 合并同一类型中重叠或相接的时段
+func mergeOverlapping(periods []BusyPeriod) []BusyPeriod {
+	if len(periods) == 0 {
+		return nil
+	}
+	sort.SliceStable(periods, func(i, j int) bool {
+		return periods[i].Start.Before(periods[j].Start)
+	})
+
+	merged := []BusyPeriod{periods[0]}
+	for _, p := range periods[1:] {
+		last := &merged[len(merged)-1]
+		if !p.Start.After(last.End) {
+			last.End = maxTime(last.End, p.End)
+			continue
+		}
+		merged = append(merged, p)
+	}
+	return merged
+}
+
+// subtractPeriods 从 periods 中去掉与 other 重叠的部分，两者都必须已排序且不重叠
+func subtractPeriods(periods, other []BusyPeriod) []BusyPeriod {
+	var result []BusyPeriod
+	for _, p := range periods {
+		for _, o := range other {
+			if !o.End.After(p.Start) {
+				continue
+			}
+			if !o.Start.Before(p.End) {
+				break
+			}
+			if o.Start.After(p.Start) {
+				result = append(result, BusyPeriod{Start: p.Start, End: o.Start, Type: p.Type})
+			}
+			p.Start = o.End
+			if !p.End.After(p.Start) {
+				break
+			}
+		}
+		if p.End.After(p.Start) {
+			result = append(result, p)
+		}
+	}
+	return result
+}
+
+func minTime(a, b time.Time) time.Time {
+	if a.Before(b) {
+		return a
+	}
+	return b
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// WriteFreeBusy 将忙闲查询结果序列化为 VCALENDAR，每个用户一个 VFREEBUSY 组件
+// 同类型的忙碌时段输出在同一行 FREEBUSY 属性中（RFC 5545 3.8.2.6）
+func WriteFreeBusy(w io.Writer, fb *FreeBusyResponse, dtstamp time.Time) error {
+	iw := &icalWriter{w: w}
+	iw.line("BEGIN", nil, "VCALENDAR")
+	iw.line("VERSION", nil, "2.0")
+	iw.line("PRODID", nil, icalProdID)
+
+	for _, user := range fb.Users {
+		iw.line("BEGIN", nil, "VFREEBUSY")
+		iw.line("UID", nil, fmt.Sprintf("freebusy-%d-%d-%d@otter", user.UserID, fb.Start.Unix(), fb.End.Unix()))
+		iw.time("DTSTAMP", &dtstamp)
+		iw.time("DTSTART", &fb.Start)
+		iw.time("DTEND", &fb.End)
+
+		for _, fbType := range []FreeBusyType{FreeBusyTypeBusy, FreeBusyTypeBusyTentative} {
+			var values []string
+			for _, p := range user.Busy {
+				if p.Type == fbType {
+					values = append(values, p.Start.UTC().Format(icalDateTimeUTCLayout)+"/"+p.End.UTC().Format(icalDateTimeUTCLayout))
+				}
+			}
+			if len(values) > 0 {
+				iw.line("FREEBUSY", []string{"FBTYPE=" + string(fbType)}, strings.Join(values, ","))
+			}
+		}
+		iw.line("END", nil, "VFREEBUSY")
+	}
+
+	iw.line("END", nil, "VCALENDAR")
+	return iw.err
+}
@@ -0,0 +1,102 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestService_GetFreeBusy 测试展开重复事件、忽略不占用时间的事件并合并重叠时段
+func TestService_GetFreeBusy(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	alice, bob := uint(1), uint(2)
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	at := func(days, hour, minute int) time.Time {
+		return day.AddDate(0, 0, days).Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+	str := func(s string) *string { return &s }
+
+	aliceItems := []*CalendarItem{
+		{ID: 1, UID: "standup", Type: CalendarItemTypeEvent, DtStart: at(0, 9, 0), DtEnd: ptr(at(0, 10, 0)), RRule: str("FREQ=DAILY;COUNT=2"), Summary: str("站会")},
+		{ID: 2, UID: "review", Type: CalendarItemTypeEvent, DtStart: at(0, 9, 30), Duration: str("PT90M"), Summary: str("评审")},
+		{ID: 3, UID: "maybe", Type: CalendarItemTypeEvent, DtStart: at(0, 10, 30), DtEnd: ptr(at(0, 12, 0)), Status: str("TENTATIVE")},
+		{ID: 4, UID: "cancelled", Type: CalendarItemTypeEvent, DtStart: at(0, 14, 0), DtEnd: ptr(at(0, 15, 0)), Status: str("CANCELLED")},
+		{ID: 5, UID: "holiday", Type: CalendarItemTypeEvent, DtStart: at(0, 16, 0), DtEnd: ptr(at(0, 17, 0)), Transp: str("TRANSPARENT")},
+	}
+	bobItems := []*CalendarItem{
+		{ID: 6, UID: "secret", Type: CalendarItemTypeEvent, DtStart: at(1, 23, 0), DtEnd: ptr(at(2, 1, 0)), Summary: str("私人安排")},
+	}
+
+	start, end := day, day.AddDate(0, 0, 2)
+	eventType := CalendarItemTypeEvent
+	mockRepo.On("ListCalendarItemsInRange", &alice, start, end, &eventType).Return(aliceItems, nil)
+	mockRepo.On("ListCalendarItemOverrides", &alice, []string{"standup"}).Return([]*CalendarItem{}, nil)
+	mockRepo.On("ListCalendarItemsInRange", &bob, start, end, &eventType).Return(bobItems, nil)
+
+	result, err := service.GetFreeBusy(alice, &FreeBusyRequest{Start: start, End: end, UserIDs: []uint{alice, bob, alice}})
+
+	require.NoError(t, err)
+	require.Len(t, result.Users, 2)
+
+	assert.Equal(t, []BusyPeriod{
+		{Start: at(0, 9, 0), End: at(0, 11, 0), Type: FreeBusyTypeBusy},
+		{Start: at(0, 11, 0), End: at(0, 12, 0), Type: FreeBusyTypeBusyTentative},
+		{Start: at(1, 9, 0), End: at(1, 10, 0), Type: FreeBusyTypeBusy},
+	}, result.Users[0].Busy)
+	assert.Len(t, result.Users[0].Items, 4)
+
+	// 其它用户只能看到忙碌时段，跨越窗口结束的事件被截断
+	assert.Equal(t, []BusyPeriod{
+		{Start: at(1, 23, 0), End: end, Type: FreeBusyTypeBusy},
+	}, result.Users[1].Busy)
+	assert.Nil(t, result.Users[1].Items)
+
+	// 内容行超过 75 字节时会被折叠
+	ics := strings.ReplaceAll(result.ICalendar, "\r\n ", "")
+	assert.Contains(t, ics, "BEGIN:VFREEBUSY")
+	assert.Contains(t, ics, "FREEBUSY;FBTYPE=BUSY:20250106T090000Z/20250106T110000Z,20250107T090000Z/20250107T100000Z")
+	assert.Contains(t, ics, "FREEBUSY;FBTYPE=BUSY-TENTATIVE:20250106T110000Z/20250106T120000Z")
+	assert.Contains(t, ics, "FREEBUSY;FBTYPE=BUSY:20250107T230000Z/20250108T000000Z")
+	assert.NotContains(t, ics, "私人安排")
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_GetFreeBusy_InvalidRange 测试无效的查询窗口
+func TestService_GetFreeBusy_InvalidRange(t *testing.T) {
+	service := NewService(new(mockRepository))
+	start := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.GetFreeBusy(1, &FreeBusyRequest{Start: start, End: start, UserIDs: []uint{1}})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	_, err = service.GetFreeBusy(1, &FreeBusyRequest{Start: start, End: start.AddDate(2, 0, 0), UserIDs: []uint{1}})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestMergeBusyPeriods 测试合并相接的时段，以及已确认时段覆盖试探性时段
+func TestMergeBusyPeriods(t *testing.T) {
+	base := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	h := func(hour int) time.Time { return base.Add(time.Duration(hour) * time.Hour) }
+
+	merged := mergeBusyPeriods([]BusyPeriod{
+		{Start: h(10), End: h(11), Type: FreeBusyTypeBusy},
+		{Start: h(8), End: h(10), Type: FreeBusyTypeBusy},
+		{Start: h(7), End: h(14), Type: FreeBusyTypeBusyTentative},
+		{Start: h(12), End: h(13), Type: FreeBusyTypeBusy},
+	})
+
+	assert.Equal(t, []BusyPeriod{
+		{Start: h(7), End: h(8), Type: FreeBusyTypeBusyTentative},
+		{Start: h(8), End: h(11), Type: FreeBusyTypeBusy},
+		{Start: h(11), End: h(12), Type: FreeBusyTypeBusyTentative},
+		{Start: h(12), End: h(13), Type: FreeBusyTypeBusy},
+		{Start: h(13), End: h(14), Type: FreeBusyTypeBusyTentative},
+	}, merged)
+	assert.Empty(t, mergeBusyPeriods(nil))
+}
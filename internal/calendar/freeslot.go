@@ -0,0 +1,228 @@
+package calendar
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// slotGranularity 候选时段开始时间的对齐粒度
+	slotGranularity = 15 * time.Minute
+	// defaultFreeSlotLimit 默认返回的候选时段数量
+	defaultFreeSlotLimit = 5
+	// maxFreeSlotLimit 最多返回的候选时段数量
+	maxFreeSlotLimit = 20
+	// slotRoomCap 计算前后余量得分时，余量超过该值不再加分
+	slotRoomCap = 30 * time.Minute
+)
+
+// FindFreeSlotsRequest 查找空闲时段请求
+// 工作时间按 TimeZone 解释，不指定时全天可用；Weekdays 使用 RRULE 中的星期代码（MO、TU…），不指定时每天可用
+type FindFreeSlotsRequest struct {
+	Start           time.Time `json:"start"`
+	End             time.Time `json:"end"`
+	DurationMinutes int       `json:"duration_minutes"`        // 所需时长（必需）
+	BufferMinutes   int       `json:"buffer_minutes"`          // 与已有事件之间至少间隔的分钟数
+	TimeZone        string    `json:"timezone,omitempty"`      // IANA 时区名称，默认 UTC
+	WorkdayStart    string    `json:"workday_start,omitempty"` // 每天可用时间的开始，格式 HH:MM
+	WorkdayEnd      string    `json:"workday_end,omitempty"`   // 每天可用时间的结束，格式 HH:MM，可以为 24:00
+	Weekdays        []string  `json:"weekdays,omitempty"`      // 可用的星期
+	Limit           int       `json:"limit,omitempty"`         // 返回数量，默认 5，最多 20
+}
+
+// FreeSlot 候选空闲时段，Score 越高越推荐（0-1）
+type FreeSlot struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+	Score float64   `json:"score"`
+}
+
+// FindFreeSlots 在时间窗口内查找满足时长的空闲时段，按推荐程度排序
+// 忙碌时段与忙闲查询相同（重复日历项展开，忽略 CANCELLED 和 TRANSPARENT 的事件，试探性事件也视为忙碌），
+// 并在前后各留出 BufferMinutes 的间隔。候选时段按 15 分钟对齐，返回的时段互不重叠
+func (s *service) FindFreeSlots(userID *uint, req *FindFreeSlotsRequest) ([]FreeSlot, error) {
+	if !req.End.After(req.Start) {
+		return nil, fmt.Errorf("%w: 结束时间必须晚于开始时间", ErrInvalidInput)
+	}
+	if req.End.Sub(req.Start) > maxFreeBusyRange {
+		return nil, fmt.Errorf("%w: 查询时间跨度不能超过 366 天", ErrInvalidInput)
+	}
+	if req.DurationMinutes <= 0 {
+		return nil, fmt.Errorf("%w: 时长必须大于 0", ErrInvalidInput)
+	}
+	if req.BufferMinutes < 0 {
+		return nil, fmt.Errorf("%w: 间隔不能为负数", ErrInvalidInput)
+	}
+
+	loc := time.UTC
+	if req.TimeZone != "" {
+		var err error
+		if loc, err = time.LoadLocation(req.TimeZone); err != nil {
+			return nil, fmt.Errorf("%w: 无效的时区 %s", ErrInvalidInput, req.TimeZone)
+		}
+	}
+	dayStart, dayEnd, err := parseWorkday(req.WorkdayStart, req.WorkdayEnd)
+	if err != nil {
+		return nil, err
+	}
+	weekdays := make(map[time.Weekday]bool, len(req.Weekdays))
+	for _, code := range req.Weekdays {
+		weekday, ok := weekdayCodes[strings.ToUpper(strings.TrimSpace(code))]
+		if !ok {
+			return nil, fmt.Errorf("%w: 无效的星期 %s", ErrInvalidInput, code)
+		}
+		weekdays[weekday] = true
+	}
+
+	limit := req.Limit
+	if limit <= 0 {
+		limit = defaultFreeSlotLimit
+	}
+	limit = min(limit, maxFreeSlotLimit)
+
+	duration := time.Duration(req.DurationMinutes) * time.Minute
+	buffer := time.Duration(req.BufferMinutes) * time.Minute
+	start, end := req.Start.UTC(), req.End.UTC()
+
+	busy, _, err := s.busyPeriods(userID, start.Add(-buffer), end.Add(buffer))
+	if err != nil {
+		return nil, fmt.Errorf("获取忙闲信息失败: %w", err)
+	}
+	blocked := make([]BusyPeriod, len(busy))
+	for i, p := range busy {
+		blocked[i] = BusyPeriod{Start: p.Start.Add(-buffer), End: p.End.Add(buffer), Type: FreeBusyTypeBusy}
+	}
+	blocked = mergeOverlapping(blocked)
+
+	var candidates []FreeSlot
+	for _, window := range workingWindows(start, end, loc, dayStart, dayEnd, weekdays) {
+		for _, free := range subtractPeriods([]BusyPeriod{window}, blocked) {
+			candidates = append(candidates, slotCandidates(free, blocked, duration, start, end, loc)...)
+		}
+	}
+
+	// 按得分从高到低挑选互不重叠的时段
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Score > candidates[j].Score
+	})
+	slots := make([]FreeSlot, 0, limit)
+	for _, c := range candidates {
+		if len(slots) >= limit {
+			break
+		}
+		overlaps := false
+		for _, selected := range slots {
+			if c.Start.Before(selected.End) && selected.Start.Before(c.End) {
+				overlaps = true
+				break
+			}
+		}
+		if !overlaps {
+			c.Start, c.End = c.Start.In(loc), c.End.In(loc)
+			slots = append(slots, c)
+		}
+	}
+	return slots, nil
+}
+
+// parseWorkday 解析每天可用时间，返回相对当天零点的偏移；都为空时全天可用
+func parseWorkday(startValue, endValue string) (time.Duration, time.Duration, error) {
+	dayStart, dayEnd := time.Duration(0), 24*time.Hour
+	var err error
+	if startValue != "" {
+		if dayStart, err = parseClock(startValue); err != nil {
+			return 0, 0, err
+		}
+	}
+	if endValue != "" {
+		if dayEnd, err = parseClock(endValue); err != nil {
+			return 0, 0, err
+		}
+	}
+	if dayEnd <= dayStart {
+		return 0, 0, fmt.Errorf("%w: 每天可用时间的结束必须晚于开始", ErrInvalidInput)
+	}
+	return dayStart, dayEnd, nil
+}
+
+// parseClock 解析 HH:MM 格式的时刻，允许 24:00
+func parseClock(value string) (time.Duration, error) {
+	var hour, minute int
+	if _, err := fmt.Sscanf(value, "%d:%d", &hour, &minute); err != nil ||
+		hour < 0 || minute < 0 || minute > 59 || hour > 24 || (hour == 24 && minute != 0) {
+		return 0, fmt.Errorf("%w: 无效的时刻 %s，格式应为 HH:MM", ErrInvalidInput, value)
+	}
+	return time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute, nil
+}
+
+// workingWindows 将 [start, end) 按天切分为可用时间段，时刻按 loc 解释（夏令时切换当天按墙上时间计算）
+func workingWindows(start, end time.Time, loc *time.Location, dayStart, dayEnd time.Duration, weekdays map[time.Weekday]bool) []BusyPeriod {
+	var windows []BusyPeriod
+	local := start.In(loc)
+	for day := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc); day.Before(end); day = day.AddDate(0, 0, 1) {
+		if len(weekdays) > 0 && !weekdays[day.Weekday()] {
+			continue
+		}
+		from := atClock(day, dayStart, loc)
+		to := atClock(day, dayEnd, loc)
+		from, to = maxTime(from, start), minTime(to, end)
+		if to.After(from) {
+			windows = append(windows, BusyPeriod{Start: from.UTC(), End: to.UTC()})
+		}
+	}
+	return windows
+}
+
+// atClock 返回 day 当天 offset 对应的墙上时间
+func atClock(day time.Time, offset time.Duration, loc *time.Location) time.Time {
+	minutes := int(offset / time.Minute)
+	return time.Date(day.Year(), day.Month(), day.Day(), minutes/60, minutes%60, 0, 0, loc)
+}
+
+// slotCandidates 在空闲时段 free 中按对齐粒度生成候选时段并打分，对齐和整点都按 loc 的墙上时间计算
+// 得分综合三个因素：越早越好（0.5）、整点或半点开始（0.2）、与前后的事件留有余量（0.3）
+func slotCandidates(free BusyPeriod, blocked []BusyPeriod, duration time.Duration, windowStart, windowEnd time.Time, loc *time.Location) []FreeSlot {
+	var slots []FreeSlot
+	span := windowEnd.Sub(windowStart)
+	// 按本地时间对齐：UTC 偏移不是整小时的时区（如 +05:30）直接 Truncate 会对齐到 UTC 的刻度
+	_, offset := free.Start.In(loc).Zone()
+	shift := time.Duration(offset) * time.Second
+	first := free.Start.Add(shift).Truncate(slotGranularity).Add(-shift)
+	if first.Before(free.Start) {
+		first = first.Add(slotGranularity)
+	}
+	for t := first; !t.Add(duration).After(free.End); t = t.Add(slotGranularity) {
+		earliness := 1 - float64(t.Sub(windowStart))/float64(span)
+
+		alignment := 0.0
+		switch t.In(loc).Minute() {
+		case 0:
+			alignment = 1
+		case 30:
+			alignment = 0.5
+		}
+
+		room := slotRoom(blocked, t, t.Add(duration))
+
+		score := 0.5*earliness + 0.2*alignment + 0.3*float64(room)/float64(slotRoomCap)
+		slots = append(slots, FreeSlot{Start: t, End: t.Add(duration), Score: math.Round(score*1000) / 1000})
+	}
+	return slots
+}
+
+// slotRoom 时段与前后最近的忙碌时段之间较小的间隔，不超过 slotRoomCap
+func slotRoom(blocked []BusyPeriod, start, end time.Time) time.Duration {
+	room := slotRoomCap
+	for _, p := range blocked {
+		if !p.End.After(start) {
+			room = min(room, start.Sub(p.End))
+		} else if !p.Start.Before(end) {
+			room = min(room, p.Start.Sub(end))
+			break
+		}
+	}
+	return room
+}
@@ -0,0 +1,147 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestService_FindFreeSlots 测试按工作时间和间隔查找空闲时段
+func TestService_FindFreeSlots(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2025, 1, day, hour, minute, 0, 0, shanghai)
+	}
+	ptr := func(t time.Time) *time.Time { return &t }
+	rrule := "FREQ=DAILY;COUNT=5"
+
+	// 1 月 9 日（周四）下午：每天 14:00-15:00 例会，16:00-16:30 评审
+	items := []*CalendarItem{
+		{ID: 1, UID: "daily", Type: CalendarItemTypeEvent, DtStart: at(6, 14, 0), DtEnd: ptr(at(6, 15, 0)), RRule: &rrule},
+		{ID: 2, UID: "review", Type: CalendarItemTypeEvent, DtStart: at(9, 16, 0), DtEnd: ptr(at(9, 16, 30))},
+	}
+
+	start, end := at(9, 13, 0), at(9, 18, 0)
+	eventType := CalendarItemTypeEvent
+	buffer := 15 * time.Minute
+	mockRepo.On("ListCalendarItemsInRange", &userID, start.UTC().Add(-buffer), end.UTC().Add(buffer), &eventType).Return(items, nil)
+	mockRepo.On("ListCalendarItemOverrides", &userID, []string{"daily"}).Return([]*CalendarItem{}, nil)
+
+	slots, err := service.FindFreeSlots(&userID, &FindFreeSlotsRequest{
+		Start:           start,
+		End:             end,
+		DurationMinutes: 60,
+		BufferMinutes:   15,
+		TimeZone:        "Asia/Shanghai",
+		WorkdayStart:    "09:00",
+		WorkdayEnd:      "17:30",
+		Weekdays:        []string{"MO", "TU", "WE", "TH", "FR"},
+		Limit:           3,
+	})
+
+	require.NoError(t, err)
+	// 留出间隔后可用时段为 13:00-13:45、15:15-15:45、16:45-17:30，都不足一小时
+	assert.Empty(t, slots)
+
+	slots, err = service.FindFreeSlots(&userID, &FindFreeSlotsRequest{
+		Start:           start,
+		End:             end,
+		DurationMinutes: 30,
+		BufferMinutes:   15,
+		TimeZone:        "Asia/Shanghai",
+		WorkdayStart:    "09:00",
+		WorkdayEnd:      "17:30",
+		Limit:           3,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, slots, 3)
+	// 13:00 最早且整点开始；13:15 与其重叠被跳过；17:00 整点且离前一个事件有余量，排在 15:15 之前
+	assert.True(t, at(9, 13, 0).Equal(slots[0].Start))
+	assert.True(t, at(9, 13, 30).Equal(slots[0].End))
+	assert.True(t, at(9, 17, 0).Equal(slots[1].Start))
+	assert.True(t, at(9, 15, 15).Equal(slots[2].Start))
+	assert.Equal(t, 0.85, slots[0].Score)
+	assert.Equal(t, shanghai, slots[0].Start.Location())
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_FindFreeSlots_HalfHourOffset 测试 UTC 偏移不是整小时的时区按本地整点打分
+func TestService_FindFreeSlots_HalfHourOffset(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	kolkata, err := time.LoadLocation("Asia/Kolkata")
+	require.NoError(t, err)
+	start := time.Date(2025, 1, 9, 9, 0, 0, 0, kolkata)
+	end := time.Date(2025, 1, 9, 12, 0, 0, 0, kolkata)
+	mockRepo.On("ListCalendarItemsInRange", &userID, mock.Anything, mock.Anything, mock.Anything).Return([]*CalendarItem{}, nil)
+
+	slots, err := service.FindFreeSlots(&userID, &FindFreeSlotsRequest{
+		Start:           start,
+		End:             end,
+		DurationMinutes: 60,
+		TimeZone:        "Asia/Kolkata",
+		Limit:           3,
+	})
+
+	require.NoError(t, err)
+	require.Len(t, slots, 3)
+	// 09:00（UTC 03:30）是本地整点，最早且得满分；其后的候选也都从本地整点开始
+	assert.True(t, start.Equal(slots[0].Start))
+	assert.Equal(t, 1.0, slots[0].Score)
+	for _, slot := range slots {
+		assert.Zero(t, slot.Start.In(kolkata).Minute())
+	}
+}
+
+// TestService_FindFreeSlots_Invalid 测试无效的查找参数
+func TestService_FindFreeSlots_Invalid(t *testing.T) {
+	service := NewService(new(mockRepository))
+	userID := uint(1)
+	start := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		req  *FindFreeSlotsRequest
+	}{
+		{"缺少时长", &FindFreeSlotsRequest{Start: start, End: start.Add(time.Hour)}},
+		{"结束早于开始", &FindFreeSlotsRequest{Start: start, End: start, DurationMinutes: 30}},
+		{"无效的时区", &FindFreeSlotsRequest{Start: start, End: start.Add(time.Hour), DurationMinutes: 30, TimeZone: "Mars/Base"}},
+		{"无效的时刻", &FindFreeSlotsRequest{Start: start, End: start.Add(time.Hour), DurationMinutes: 30, WorkdayStart: "9am"}},
+		{"工作时间颠倒", &FindFreeSlotsRequest{Start: start, End: start.Add(time.Hour), DurationMinutes: 30, WorkdayStart: "18:00", WorkdayEnd: "09:00"}},
+		{"无效的星期", &FindFreeSlotsRequest{Start: start, End: start.Add(time.Hour), DurationMinutes: 30, Weekdays: []string{"XX"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.FindFreeSlots(&userID, tt.req)
+			assert.ErrorIs(t, err, ErrInvalidInput)
+		})
+	}
+}
+
+// TestWorkingWindows 测试按时区和星期切分工作时间
+func TestWorkingWindows(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	// 2025-01-10 是周五
+	start := time.Date(2025, 1, 10, 0, 0, 0, 0, shanghai)
+	end := start.AddDate(0, 0, 4)
+
+	windows := workingWindows(start.UTC(), end.UTC(), shanghai, 9*time.Hour, 18*time.Hour,
+		map[time.Weekday]bool{time.Monday: true, time.Friday: true})
+
+	require.Len(t, windows, 2)
+	assert.True(t, time.Date(2025, 1, 10, 9, 0, 0, 0, shanghai).Equal(windows[0].Start))
+	assert.True(t, time.Date(2025, 1, 10, 18, 0, 0, 0, shanghai).Equal(windows[0].End))
+	assert.True(t, time.Date(2025, 1, 13, 9, 0, 0, 0, shanghai).Equal(windows[1].Start))
+}
@@ -1,6 +1,9 @@
 package calendar
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"net/http"
 	"strconv"
 	"strings"
@@ -22,12 +25,29 @@ type ErrorResponse struct {
 	Error string `json:"error"`
 }
 
+// ConflictResponse 冲突处理方式为 reject 且存在冲突时的响应（409）
+type ConflictResponse struct {
+	Error     string     `json:"error"`
+	Conflicts []Conflict `json:"conflicts"`
+}
+
+// writeConflict 存在冲突时写入 409 响应并返回 true
+func writeConflict(c *gin.Context, err error) bool {
+	var conflictErr *ConflictError
+	if !errors.As(err, &conflictErr) {
+		return false
+	}
+	c.JSON(http.StatusConflict, ConflictResponse{Error: err.Error(), Conflicts: conflictErr.Conflicts})
+	return true
+}
+
 // SearchCalendarItems 搜索日历项
 // GET /api/v1/calendar/items/search?summary=会议&location=北京
 // GET /api/v1/calendar/items/search?summary=会议&dtstart=2024-12-01T00:00:00Z,2024-12-31T23:59:59Z
 // GET /api/v1/calendar/items/search?dtstart=2024-12-01T00:00:00Z,2024-12-31T23:59:59Z (仅时间范围)
 // GET /api/v1/calendar/items/search?summary=会议&dtstart=2024-12-01T00:00:00Z, (只有开始时间)
 // GET /api/v1/calendar/items/search?summary=会议&dtstart=,2024-12-31T23:59:59Z (只有结束时间)
+// GET /api/v1/calendar/items/search?q=会议&sort=relevance&limit=20&cursor=... (按相关度排序，从上一页的 next_cursor 继续)
 func (h *Handler) SearchCalendarItems(c *gin.Context) {
 	userID, err := middleware.GetUserIDFromContext(c)
 	if err != nil {
@@ -85,6 +105,21 @@ func (h *Handler) SearchCalendarItems(c *gin.Context) {
 		Due:       parseTimeRange("due"),
 		Completed: parseTimeRange("completed"),
 	}
+	if limitStr := c.Query("limit"); limitStr != "" {
+		limit, err := strconv.Atoi(limitStr)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的 limit 参数"})
+			return
+		}
+		req.Limit = &limit
+	}
+	if sortStr := c.Query("sort"); sortStr != "" {
+		sortKey := SortKey(sortStr)
+		req.Sort = &sortKey
+	}
+	if cursor := c.Query("cursor"); cursor != "" {
+		req.Cursor = &cursor
+	}
 
 	// 验证：至少需要指定搜索关键字或时间范围
 	if req.Q == nil && req.DtStart == nil && req.DtEnd == nil && req.Due == nil && req.Completed == nil {
@@ -92,9 +127,9 @@ func (h *Handler) SearchCalendarItems(c *gin.Context) {
 		return
 	}
 
-	items, err := h.service.SearchCalendarItems(userID, &req)
+	result, err := h.service.SearchCalendarItems(userID, &req)
 	if err != nil {
-		if err == ErrInvalidSearchField || err == ErrInvalidInput {
+		if errors.Is(err, ErrInvalidSearchField) || errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrInvalidCursor) {
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 			return
 		}
@@ -102,10 +137,7 @@ func (h *Handler) SearchCalendarItems(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"items": items,
-		"count": len(items),
-	})
+	c.JSON(http.StatusOK, result)
 }
 
 // CreateCalendarItem 创建日历项
@@ -125,7 +157,10 @@ func (h *Handler) CreateCalendarItem(c *gin.Context) {
 
 	item, err := h.service.CreateCalendarItem(userID, &req)
 	if err != nil {
-		if err == ErrInvalidType || err == ErrInvalidInput {
+		if writeConflict(c, err) {
+			return
+		}
+		if errors.Is(err, ErrInvalidType) || errors.Is(err, ErrInvalidInput) {
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 			return
 		}
@@ -203,6 +238,9 @@ func (h *Handler) GetCalendarItemByUID(c *gin.Context) {
 
 // UpdateCalendarItem 更新日历项
 // PUT /api/v1/calendar/items/:id
+// 修改重复日历项的实例时指定 recurrence_id（实例的原始开始时间）和 scope：
+// PUT /api/v1/calendar/items/:id?recurrence_id=2024-12-23T09:00:00Z&scope=this
+// scope 可选 this（默认）、this_and_following、all
 func (h *Handler) UpdateCalendarItem(c *gin.Context) {
 	userID, err := middleware.GetUserIDFromContext(c)
 	if err != nil {
@@ -223,13 +261,35 @@ func (h *Handler) UpdateCalendarItem(c *gin.Context) {
 		return
 	}
 
-	item, err := h.service.UpdateCalendarItem(userID, uint(id), &req)
+	var item *CalendarItem
+	scope := UpdateScope(c.Query("scope"))
+	if recurrenceIDStr := c.Query("recurrence_id"); recurrenceIDStr != "" {
+		recurrenceID, parseErr := time.Parse(time.RFC3339, recurrenceIDStr)
+		if parseErr != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的recurrence_id"})
+			return
+		}
+		if scope == "" {
+			scope = UpdateScopeThis
+		}
+		item, err = h.service.UpdateCalendarItemOccurrence(userID, uint(id), recurrenceID, scope, &req)
+	} else {
+		if scope != "" && scope != UpdateScopeAll {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "scope 为 this 或 this_and_following 时需要指定 recurrence_id"})
+			return
+		}
+		item, err = h.service.UpdateCalendarItem(userID, uint(id), &req)
+	}
 	if err != nil {
-		if err == ErrCalendarItemNotFound || err == ErrInvalidInput {
+		if writeConflict(c, err) {
+			return
+		}
+		if errors.Is(err, ErrCalendarItemNotFound) || errors.Is(err, ErrInvalidInput) ||
+			errors.Is(err, ErrNotRecurring) || errors.Is(err, ErrOccurrenceNotFound) {
 			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 			return
 		}
-		if err == ErrForbidden {
+		if errors.Is(err, ErrForbidden) {
 			c.JSON(http.StatusForbidden, ErrorResponse{Error: err.Error()})
 			return
 		}
@@ -273,8 +333,235 @@ func (h *Handler) DeleteCalendarItem(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "日历项删除成功"})
 }
 
+// ListValarms 列出日历项的提醒
+// GET /api/v1/calendar/items/:id/alarms
+func (h *Handler) ListValarms(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的ID"})
+		return
+	}
+
+	alarms, err := h.service.GetValarmsByCalendarItemID(userID, uint(itemID))
+	if err != nil {
+		writeValarmError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alarms)
+}
+
+// CreateValarm 为日历项创建提醒
+// POST /api/v1/calendar/items/:id/alarms
+func (h *Handler) CreateValarm(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的ID"})
+		return
+	}
+
+	var req CreateValarmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	alarm, err := h.service.CreateValarm(userID, uint(itemID), &req)
+	if err != nil {
+		writeValarmError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, alarm)
+}
+
+// GetValarm 获取日历项的提醒
+// GET /api/v1/calendar/items/:id/alarms/:alarmId
+func (h *Handler) GetValarm(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	itemID, alarmID, ok := parseValarmPath(c)
+	if !ok {
+		return
+	}
+
+	alarm, err := h.service.GetValarmByID(userID, itemID, alarmID)
+	if err != nil {
+		writeValarmError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alarm)
+}
+
+// UpdateValarm 更新日历项的提醒
+// PUT /api/v1/calendar/items/:id/alarms/:alarmId
+func (h *Handler) UpdateValarm(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	itemID, alarmID, ok := parseValarmPath(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateValarmRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	alarm, err := h.service.UpdateValarm(userID, itemID, alarmID, &req)
+	if err != nil {
+		writeValarmError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, alarm)
+}
+
+// DeleteValarm 删除日历项的提醒
+// DELETE /api/v1/calendar/items/:id/alarms/:alarmId
+func (h *Handler) DeleteValarm(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	itemID, alarmID, ok := parseValarmPath(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteValarm(userID, itemID, alarmID); err != nil {
+		writeValarmError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "提醒删除成功"})
+}
+
+// parseValarmPath 解析路径中的日历项ID和提醒ID，无效时返回 400
+func parseValarmPath(c *gin.Context) (uint, uint, bool) {
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的ID"})
+		return 0, 0, false
+	}
+	alarmID, err := strconv.ParseUint(c.Param("alarmId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的提醒ID"})
+		return 0, 0, false
+	}
+	return uint(itemID), uint(alarmID), true
+}
+
+// writeValarmError 将提醒相关的错误转换为响应
+func writeValarmError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrCalendarItemNotFound) || errors.Is(err, ErrValarmNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrInvalidAction):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
+// ListAttendees 列出日历项的参与者
+// GET /api/v1/calendar/items/:id/attendees
+func (h *Handler) ListAttendees(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的ID"})
+		return
+	}
+
+	attendees, err := h.service.GetAttendeesByCalendarItemID(userID, uint(itemID))
+	if err != nil {
+		writeAttendeeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attendees)
+}
+
+// UpdateAttendeeStatus 修改参与者的参与状态
+// PUT /api/v1/calendar/items/:id/attendees/:attendeeId/partstat
+func (h *Handler) UpdateAttendeeStatus(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	itemID, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的ID"})
+		return
+	}
+	attendeeID, err := strconv.ParseUint(c.Param("attendeeId"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的参与者ID"})
+		return
+	}
+
+	var req UpdateAttendeeStatusRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	attendee, err := h.service.UpdateAttendeeStatus(userID, uint(itemID), uint(attendeeID), &req)
+	if err != nil {
+		writeAttendeeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, attendee)
+}
+
+// writeAttendeeError 将参与者相关的错误转换为响应
+func writeAttendeeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrCalendarItemNotFound) || errors.Is(err, ErrAttendeeNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
+
 // ListCalendarItems 列出日历项
-// GET /api/v1/calendar/items
+// GET /api/v1/calendar/items?page=1&page_size=20
+// GET /api/v1/calendar/items?sort=due&page_size=20&cursor=... (从上一页的 next_cursor 继续)
 func (h *Handler) ListCalendarItems(c *gin.Context) {
 	userID, err := middleware.GetUserIDFromContext(c)
 	if err != nil {
@@ -290,9 +577,265 @@ func (h *Handler) ListCalendarItems(c *gin.Context) {
 
 	result, err := h.service.ListCalendarItems(userID, &req)
 	if err != nil {
+		if errors.Is(err, ErrInvalidInput) || errors.Is(err, ErrInvalidCursor) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// maxImportSize 导入 iCalendar 文件的大小上限
+const maxImportSize = 10 << 20
+
+// ImportCalendar 导入 iCalendar (.ics) 数据
+// POST /api/v1/calendar/import
+// 请求体为 text/calendar 内容，或 multipart/form-data 上传的 file 字段
+func (h *Handler) ImportCalendar(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	body, err := icalendarBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	defer body.Close()
+
+	report, err := h.service.ImportICalendar(userID, body)
+	if err != nil {
+		if errors.Is(err, ErrInvalidICalendar) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// ProcessITIPMessage 处理参与者回复的 iTIP 消息（METHOD 为 REPLY 或 COUNTER 的 .ics）
+// POST /api/v1/calendar/itip
+// 请求体为 text/calendar 内容，或 multipart/form-data 上传的 file 字段，例如邀请邮件回复中的 .ics 附件
+func (h *Handler) ProcessITIPMessage(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	body, err := icalendarBody(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+	defer body.Close()
+
+	report, err := h.service.ProcessITIPMessage(userID, body)
+	if err != nil {
+		if errors.Is(err, ErrInvalidICalendar) || errors.Is(err, ErrUnsupportedITIPMethod) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// icalendarBody 返回请求中的 iCalendar 数据：请求体本身，或 multipart/form-data 上传的 file 字段
+func icalendarBody(c *gin.Context) (io.ReadCloser, error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportSize)
+	if !strings.HasPrefix(c.ContentType(), "multipart/form-data") {
+		return c.Request.Body, nil
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		return nil, errors.New("缺少上传的文件(file)")
+	}
+	return fileHeader.Open()
+}
+
+// ExportCalendar 导出 iCalendar (.ics) 数据
+// GET /api/v1/calendar/export.ics?start_time=2025-01-01T00:00:00Z&end_time=2025-12-31T23:59:59Z&type=VEVENT
+func (h *Handler) ExportCalendar(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	var req ExportCalendarRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	var buf bytes.Buffer
+	if err := h.service.ExportICalendar(userID, &req, &buf); err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="calendar.ics"`)
+	c.Data(http.StatusOK, icalContentType, buf.Bytes())
+}
+
+// icalContentType iCalendar 数据的 Content-Type
+const icalContentType = "text/calendar; charset=utf-8"
+
+// GetFreeBusy 查询一个或多个用户的忙闲时段
+// POST /api/v1/calendar/freebusy
+// 默认返回 JSON（包含 VFREEBUSY 文本）；Accept 为 text/calendar 时只返回 VCALENDAR
+func (h *Handler) GetFreeBusy(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	var req FreeBusyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := h.service.GetFreeBusy(*userID, &req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidInput) {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
 		return
 	}
 
+	if c.NegotiateFormat(gin.MIMEJSON, "text/calendar") == "text/calendar" {
+		c.Data(http.StatusOK, icalContentType, []byte(result.ICalendar))
+		return
+	}
 	c.JSON(http.StatusOK, result)
 }
+
+// FeedTokenResponse 订阅令牌响应，URL 为可直接在日历客户端中订阅的地址
+type FeedTokenResponse struct {
+	*CalendarFeedToken
+	URL string `json:"url"`
+}
+
+// CreateFeedToken 创建订阅令牌
+// POST /api/v1/calendar/feeds
+func (h *Handler) CreateFeedToken(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	var req CreateFeedTokenRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	token, err := h.service.CreateFeedToken(*userID, &req)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusCreated, FeedTokenResponse{CalendarFeedToken: token, URL: feedURL(c, token.Token)})
+}
+
+// ListFeedTokens 列出订阅令牌
+// GET /api/v1/calendar/feeds
+func (h *Handler) ListFeedTokens(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	tokens, err := h.service.ListFeedTokens(*userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result := make([]FeedTokenResponse, 0, len(tokens))
+	for _, token := range tokens {
+		result = append(result, FeedTokenResponse{CalendarFeedToken: token, URL: feedURL(c, token.Token)})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RevokeFeedToken 撤销订阅令牌
+// DELETE /api/v1/calendar/feeds/:id
+func (h *Handler) RevokeFeedToken(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的ID"})
+		return
+	}
+
+	if err := h.service.RevokeFeedToken(*userID, uint(id)); err != nil {
+		if errors.Is(err, ErrFeedTokenNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "订阅令牌已撤销"})
+}
+
+// CalendarFeed 通过订阅令牌获取 iCalendar 数据（不需要 JWT，供日历客户端订阅）
+// GET /api/v1/calendar/feed/:token
+// GET /api/v1/calendar/feed/:token.ics
+func (h *Handler) CalendarFeed(c *gin.Context) {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+
+	var buf bytes.Buffer
+	if err := h.service.ExportFeed(token, &buf); err != nil {
+		if errors.Is(err, ErrFeedTokenNotFound) {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	c.Header("Cache-Control", "private, max-age=300")
+	c.Data(http.StatusOK, icalContentType, buf.Bytes())
+}
+
+// feedURL 根据当前请求的地址生成订阅地址
+func feedURL(c *gin.Context, token string) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host + "/api/v1/calendar/feed/" + token + ".ics"
+}
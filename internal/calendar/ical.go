@@ -0,0 +1,401 @@
+package calendar
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidICalendar = errors.New("无效的 iCalendar 数据")
+
+// icalProperty iCalendar 内容行（RFC 5545 3.1），例如 DTSTART;TZID=Asia/Shanghai:20250106T090000
+type icalProperty struct {
+	Name   string
+	Params map[string]string
+	Value  string
+}
+
+// Param 返回参数值（参数名不区分大小写）
+func (p *icalProperty) Param(name string) string {
+	return p.Params[strings.ToUpper(name)]
+}
+
+// icalComponent iCalendar 组件（VCALENDAR、VEVENT、VALARM 等）
+type icalComponent struct {
+	Name       string
+	Properties []*icalProperty
+	Components []*icalComponent
+	Raw        string // 组件的原始文本（已展开折叠行）
+}
+
+// Prop 返回第一个同名属性
+func (c *icalComponent) Prop(name string) *icalProperty {
+	for _, p := range c.Properties {
+		if p.Name == name {
+			return p
+		}
+	}
+	return nil
+}
+
+// Props 返回所有同名属性（EXDATE、RDATE、CATEGORIES 等可以出现多次）
+func (c *icalComponent) Props(name string) []*icalProperty {
+	var props []*icalProperty
+	for _, p := range c.Properties {
+		if p.Name == name {
+			props = append(props, p)
+		}
+	}
+	return props
+}
+
+// Text 返回 TEXT 类型属性反转义后的值
+func (c *icalComponent) Text(name string) *string {
+	p := c.Prop(name)
+	if p == nil {
+		return nil
+	}
+	value := unescapeICalText(p.Value)
+	return &value
+}
+
+// parseICalendar 解析 iCalendar 数据，返回顶层的 VCALENDAR 组件
+func parseICalendar(r io.Reader) ([]*icalComponent, error) {
+	lines, err := unfoldICalLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var calendars []*icalComponent
+	var stack []*icalComponent
+	var rawStack [][]string
+
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		prop, err := parseICalContentLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("%w: 第 %d 行: %v", ErrInvalidICalendar, i+1, err)
+		}
+
+		for j := range rawStack {
+			rawStack[j] = append(rawStack[j], line)
+		}
+
+		switch prop.Name {
+		case "BEGIN":
+			comp := &icalComponent{Name: strings.ToUpper(prop.Value)}
+			if len(stack) > 0 {
+				parent := stack[len(stack)-1]
+				parent.Components = append(parent.Components, comp)
+			} else if comp.Name != "VCALENDAR" {
+				return nil, fmt.Errorf("%w: 第 %d 行: 顶层组件必须是 VCALENDAR", ErrInvalidICalendar, i+1)
+			}
+			stack = append(stack, comp)
+			rawStack = append(rawStack, []string{line})
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].Name != strings.ToUpper(prop.Value) {
+				return nil, fmt.Errorf("%w: 第 %d 行: 不匹配的 END:%s", ErrInvalidICalendar, i+1, prop.Value)
+			}
+			comp := stack[len(stack)-1]
+			comp.Raw = strings.Join(rawStack[len(rawStack)-1], "\r\n") + "\r\n"
+			stack = stack[:len(stack)-1]
+			rawStack = rawStack[:len(rawStack)-1]
+			if len(stack) == 0 {
+				calendars = append(calendars, comp)
+			}
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("%w: 第 %d 行: 属性不在组件内", ErrInvalidICalendar, i+1)
+			}
+			comp := stack[len(stack)-1]
+			comp.Properties = append(comp.Properties, prop)
+		}
+	}
+
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("%w: 组件 %s 没有结束", ErrInvalidICalendar, stack[len(stack)-1].Name)
+	}
+	if len(calendars) == 0 {
+		return nil, fmt.Errorf("%w: 没有 VCALENDAR 组件", ErrInvalidICalendar)
+	}
+	return calendars, nil
+}
+
+// unfoldICalLines 读取并展开折叠行（以空格或制表符开头的行是上一行的延续）
+func unfoldICalLines(r io.Reader) ([]string, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 10*1024*1024)
+
+	var lines []string
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if len(lines) == 0 {
+			line = strings.TrimPrefix(line, "\ufeff")
+		}
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidICalendar, err)
+	}
+	return lines, nil
+}
+
+// parseICalContentLine 解析内容行：name *(";" param) ":" value
+// 参数值可以用双引号包含 ; : , 等字符
+func parseICalContentLine(line string) (*icalProperty, error) {
+	prop := &icalProperty{Params: map[string]string{}}
+
+	i := strings.IndexAny(line, ";:")
+	if i <= 0 {
+		return nil, fmt.Errorf("无效的内容行 %q", line)
+	}
+	prop.Name = strings.ToUpper(line[:i])
+
+	for line[i] == ';' {
+		i++
+		eq := strings.IndexByte(line[i:], '=')
+		if eq < 0 {
+			return nil, fmt.Errorf("无效的参数 %q", line)
+		}
+		name := strings.ToUpper(line[i : i+eq])
+		i += eq + 1
+
+		var value strings.Builder
+		inQuotes := false
+		for ; i < len(line); i++ {
+			c := line[i]
+			if c == '"' {
+				inQuotes = !inQuotes
+				continue
+			}
+			if !inQuotes && (c == ';' || c == ':') {
+				break
+			}
+			value.WriteByte(c)
+		}
+		if i >= len(line) {
+			return nil, fmt.Errorf("缺少属性值 %q", line)
+		}
+		prop.Params[name] = value.String()
+	}
+
+	prop.Value = line[i+1:]
+	return prop, nil
+}
+
+// unescapeICalText 反转义 TEXT 值（RFC 5545 3.3.11）
+func unescapeICalText(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+			switch s[i] {
+			case 'n', 'N':
+				b.WriteByte('\n')
+			default:
+				b.WriteByte(s[i])
+			}
+			continue
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// splitICalList 按未转义的逗号拆分列表值并反转义每一项
+func splitICalList(s string) []string {
+	var items []string
+	start := 0
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' {
+			i++
+			continue
+		}
+		if s[i] == ',' {
+			items = append(items, unescapeICalText(s[start:i]))
+			start = i + 1
+		}
+	}
+	items = append(items, unescapeICalText(s[start:]))
+
+	result := items[:0]
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// icalTimezones 按 TZID 解析时区：先使用文件中的 VTIMEZONE 定义，再尝试 IANA 时区名
+// 浮动时间（既没有 TZID 也不是 UTC）和日期按 floating 解释，floating 为 nil 时按 UTC 处理
+type icalTimezones struct {
+	zones    map[string]*time.Location
+	floating *time.Location
+}
+
+// newICalTimezones 读取 VCALENDAR 中的 VTIMEZONE 定义，floating 为浮动时间所在的时区（通常是用户的时区）
+func newICalTimezones(cal *icalComponent, floating *time.Location) icalTimezones {
+	tz := icalTimezones{zones: map[string]*time.Location{}, floating: floating}
+	for _, comp := range cal.Components {
+		if comp.Name != "VTIMEZONE" {
+			continue
+		}
+		p := comp.Prop("TZID")
+		if p == nil {
+			continue
+		}
+		if loc := resolveVTimezone(p.Value, comp); loc != nil {
+			tz.zones[p.Value] = loc
+		}
+	}
+	return tz
+}
+
+// location 返回 TZID 对应的时区，没有 TZID 时返回浮动时间的时区，无法识别时使用 UTC
+func (tz icalTimezones) location(tzid string) *time.Location {
+	if tzid == "" {
+		if tz.floating != nil {
+			return tz.floating
+		}
+		return time.UTC
+	}
+	if loc, ok := tz.zones[tzid]; ok {
+		return loc
+	}
+	if loc := loadIANALocation(tzid); loc != nil {
+		return loc
+	}
+	return time.UTC
+}
+
+// resolveVTimezone 将 VTIMEZONE 映射为 time.Location
+// 优先使用 IANA 时区名（TZID 或 X-LIC-LOCATION），否则使用 STANDARD 的固定偏移
+func resolveVTimezone(tzid string, vtz *icalComponent) *time.Location {
+	if loc := loadIANALocation(tzid); loc != nil {
+		return loc
+	}
+	if p := vtz.Prop("X-LIC-LOCATION"); p != nil {
+		if loc := loadIANALocation(p.Value); loc != nil {
+			return loc
+		}
+	}
+
+	var offsetProp *icalProperty
+	for _, sub := range vtz.Components {
+		if p := sub.Prop("TZOFFSETTO"); p != nil {
+			offsetProp = p
+			if sub.Name == "STANDARD" {
+				break
+			}
+		}
+	}
+	if offsetProp == nil {
+		return nil
+	}
+	offset, err := parseUTCOffset(offsetProp.Value)
+	if err != nil {
+		return nil
+	}
+	return time.FixedZone(tzid, offset)
+}
+
+// loadIANALocation 加载 IANA 时区，兼容 "/mozilla.org/.../Europe/Berlin" 这类带前缀的 TZID
+func loadIANALocation(name string) *time.Location {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil
+	}
+	if loc, err := time.LoadLocation(name); err == nil {
+		return loc
+	}
+	parts := strings.Split(strings.Trim(name, "/"), "/")
+	for i := len(parts) - 2; i >= 0 && i >= len(parts)-3; i-- {
+		if loc, err := time.LoadLocation(strings.Join(parts[i:], "/")); err == nil {
+			return loc
+		}
+	}
+	return nil
+}
+
+// parseUTCOffset 解析 UTC-OFFSET 值，例如 "+0800"、"-0500"、"+053000"
+func parseUTCOffset(value string) (int, error) {
+	value = strings.TrimSpace(value)
+	if len(value) != 5 && len(value) != 7 {
+		return 0, fmt.Errorf("无效的时区偏移 %q", value)
+	}
+	sign := 1
+	switch value[0] {
+	case '-':
+		sign = -1
+	case '+':
+	default:
+		return 0, fmt.Errorf("无效的时区偏移 %q", value)
+	}
+	hh, err1 := strconv.Atoi(value[1:3])
+	mm, err2 := strconv.Atoi(value[3:5])
+	ss := 0
+	var err3 error
+	if len(value) == 7 {
+		ss, err3 = strconv.Atoi(value[5:7])
+	}
+	if err1 != nil || err2 != nil || err3 != nil {
+		return 0, fmt.Errorf("无效的时区偏移 %q", value)
+	}
+	return sign * (hh*3600 + mm*60 + ss), nil
+}
+
+// parseICalTime 解析 DATE 或 DATE-TIME 属性值，TZID 参数决定本地时间的时区，浮动时间和日期按 floating 解释
+func (tz icalTimezones) parseICalTime(p *icalProperty) (time.Time, bool, error) {
+	return parseDateTimeValue(p.Value, tz.location(p.Param("TZID")))
+}
+
+// parseICalTimeTZID 解析 DTSTART、DTEND、DUE，同时返回保存在日历项中的时区名（UTC 时间为 nil）
+// 以及是否只有日期（VALUE=DATE）
+func (tz icalTimezones) parseICalTimeTZID(p *icalProperty) (time.Time, *string, bool, error) {
+	t, isDate, err := tz.parseICalTime(p)
+	if err != nil {
+		return time.Time{}, nil, false, err
+	}
+	return t, timezoneName(t.Location()), isDate, nil
+}
+
+// parseICalTimeList 解析 EXDATE/RDATE 属性中的时间列表，统一格式化为 UTC 的 iCalendar 值
+// 日期值保持为 DATE 格式；RDATE 的 PERIOD 值只转换开始时间
+func (tz icalTimezones) parseICalTimeList(p *icalProperty) ([]string, error) {
+	loc := tz.location(p.Param("TZID"))
+	var values []string
+	for _, value := range strings.Split(p.Value, ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		start, rest, isPeriod := strings.Cut(value, "/")
+		t, isDate, err := parseDateTimeValue(start, loc)
+		if err != nil {
+			return nil, err
+		}
+		formatted := t.UTC().Format(icalDateTimeUTCLayout)
+		if isDate {
+			formatted = t.Format(icalDateLayout)
+		}
+		if isPeriod {
+			formatted += "/" + rest
+		}
+		values = append(values, formatted)
+	}
+	return values, nil
+}
@@ -0,0 +1,190 @@
+package calendar
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testICalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"PRODID:-//Example//Test//EN\r\n" +
+	"BEGIN:VTIMEZONE\r\n" +
+	"TZID:China Standard Time\r\n" +
+	"BEGIN:STANDARD\r\n" +
+	"DTSTART:16010101T000000\r\n" +
+	"TZOFFSETFROM:+0800\r\n" +
+	"TZOFFSETTO:+0800\r\n" +
+	"END:STANDARD\r\n" +
+	"END:VTIMEZONE\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"UID:event-1@example.com\r\n" +
+	"SUMMARY:项目评审\\, 第一轮\r\n" +
+	"DESCRIPTION:第一行\\n第二行，这一行很长需要折\r\n" +
+	" 行显示\r\n" +
+	"ORGANIZER;CN=\"Zhang, San\":mailto:zhangsan@example.com\r\n" +
+	"DTSTART;TZID=China Standard Time:20250106T090000\r\n" +
+	"DURATION:PT1H30M\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO;COUNT=4\r\n" +
+	"EXDATE;TZID=Asia/Shanghai:20250113T090000,20250120T090000\r\n" +
+	"CATEGORIES:工作,评审\r\n" +
+	"SEQUENCE:2\r\n" +
+	"BEGIN:VALARM\r\n" +
+	"ACTION:DISPLAY\r\n" +
+	"DESCRIPTION:评审即将开始\r\n" +
+	"TRIGGER;RELATED=START:-PT15M\r\n" +
+	"X-WR-ALARMUID:alarm-1\r\n" +
+	"END:VALARM\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VTODO\r\n" +
+	"UID:todo-1@example.com\r\n" +
+	"SUMMARY:提交报告\r\n" +
+	"DUE;VALUE=DATE:20250110\r\n" +
+	"PRIORITY:1\r\n" +
+	"END:VTODO\r\n" +
+	"END:VCALENDAR\r\n"
+
+// TestParseICalendar 测试解析 iCalendar：折叠行、参数引号、嵌套组件
+func TestParseICalendar(t *testing.T) {
+	calendars, err := parseICalendar(strings.NewReader(testICalendar))
+
+	require.NoError(t, err)
+	require.Len(t, calendars, 1)
+	cal := calendars[0]
+	require.Len(t, cal.Components, 3)
+
+	event := cal.Components[1]
+	assert.Equal(t, "VEVENT", event.Name)
+	assert.Equal(t, "项目评审, 第一轮", *event.Text("SUMMARY"))
+	assert.Equal(t, "第一行\n第二行，这一行很长需要折行显示", *event.Text("DESCRIPTION"))
+	assert.Equal(t, "Zhang, San", event.Prop("ORGANIZER").Param("cn"))
+	assert.Equal(t, "mailto:zhangsan@example.com", event.Prop("ORGANIZER").Value)
+	require.Len(t, event.Components, 1)
+	assert.Equal(t, "VALARM", event.Components[0].Name)
+	assert.True(t, strings.HasPrefix(event.Raw, "BEGIN:VEVENT\r\n"))
+	assert.True(t, strings.HasSuffix(event.Raw, "END:VEVENT\r\n"))
+}
+
+// TestParseICalendar_Invalid 测试解析无效的 iCalendar
+func TestParseICalendar_Invalid(t *testing.T) {
+	cases := []string{
+		"",
+		"BEGIN:VEVENT\r\nEND:VEVENT\r\n",
+		"BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nEND:VCALENDAR\r\n",
+		"BEGIN:VCALENDAR\r\nno colon here\r\nEND:VCALENDAR\r\n",
+	}
+	for _, c := range cases {
+		_, err := parseICalendar(strings.NewReader(c))
+		assert.ErrorIs(t, err, ErrInvalidICalendar, c)
+	}
+}
+
+// TestCalendarItemFromComponent 测试将组件转换为日历项
+func TestCalendarItemFromComponent(t *testing.T) {
+	calendars, err := parseICalendar(strings.NewReader(testICalendar))
+	require.NoError(t, err)
+	tz := newICalTimezones(calendars[0], nil)
+
+	item, warnings, err := calendarItemFromComponent(calendars[0].Components[1], tz)
+
+	require.NoError(t, err)
+	assert.Empty(t, warnings)
+	assert.Equal(t, "event-1@example.com", item.UID)
+	assert.Equal(t, CalendarItemTypeEvent, item.Type)
+	assert.True(t, item.DtStart.Equal(time.Date(2025, 1, 6, 1, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "PT1H30M", *item.Duration)
+	assert.Equal(t, "FREQ=WEEKLY;BYDAY=MO;COUNT=4", *item.RRule)
+	assert.Equal(t, StringArray{"20250113T010000Z", "20250120T010000Z"}, item.ExDate)
+	assert.Equal(t, StringArray{"工作", "评审"}, item.Categories)
+	assert.Equal(t, 2, *item.Sequence)
+	require.Len(t, item.Alarms, 1)
+	assert.Equal(t, ValarmActionDisplay, item.Alarms[0].Action)
+	assert.Equal(t, "-PT15M", item.Alarms[0].Trigger)
+	assert.Equal(t, "alarm-1", item.Alarms[0].XProperty["X-WR-ALARMUID"])
+
+	todo, _, err := calendarItemFromComponent(calendars[0].Components[2], tz)
+
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC), *todo.Due)
+	assert.True(t, todo.AllDay)
+	assert.Equal(t, 1, *todo.Priority)
+}
+
+// TestCalendarItemFromComponent_AllDay 测试导入只有日期的 DTSTART：按用户的时区保存为当天零点，没有 DTEND 时持续一天
+func TestCalendarItemFromComponent_AllDay(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:birthday\r\nDTSTART;VALUE=DATE:20240506\r\nRRULE:FREQ=YEARLY\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	calendars, err := parseICalendar(strings.NewReader(data))
+	require.NoError(t, err)
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+
+	item, _, err := calendarItemFromComponent(calendars[0].Components[0], newICalTimezones(calendars[0], newYork))
+
+	require.NoError(t, err)
+	assert.True(t, item.AllDay)
+	assert.True(t, item.DtStart.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, newYork)))
+	assert.True(t, item.DtEnd.Equal(time.Date(2024, 5, 7, 0, 0, 0, 0, newYork)))
+	assert.Equal(t, "America/New_York", *item.DtEndTZID)
+}
+
+// TestCalendarItemFromComponent_TZID 测试保存时间的时区：浮动时间和日期按用户的时区解释，UTC 时间不保存时区
+func TestCalendarItemFromComponent_TZID(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:floating\r\nDTSTART:20250106T090000\r\nDTEND;TZID=America/New_York:20250105T210000\r\nEND:VEVENT\r\n" +
+		"BEGIN:VTODO\r\nUID:todo\r\nDTSTART:20250106T010000Z\r\nDUE;VALUE=DATE:20250110\r\nEND:VTODO\r\n" +
+		"END:VCALENDAR\r\n"
+	calendars, err := parseICalendar(strings.NewReader(data))
+	require.NoError(t, err)
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	tz := newICalTimezones(calendars[0], shanghai)
+
+	event, _, err := calendarItemFromComponent(calendars[0].Components[0], tz)
+
+	require.NoError(t, err)
+	assert.True(t, event.DtStart.Equal(time.Date(2025, 1, 6, 1, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "Asia/Shanghai", *event.DtStartTZID)
+	assert.True(t, event.DtEnd.Equal(time.Date(2025, 1, 6, 2, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "America/New_York", *event.DtEndTZID)
+
+	todo, _, err := calendarItemFromComponent(calendars[0].Components[1], tz)
+
+	require.NoError(t, err)
+	assert.Nil(t, todo.DtStartTZID)
+	assert.True(t, todo.Due.Equal(time.Date(2025, 1, 9, 16, 0, 0, 0, time.UTC)))
+	assert.Equal(t, "Asia/Shanghai", *todo.DueTZID)
+}
+
+// TestCalendarItemFromComponent_Invalid 测试缺少必需属性的组件
+func TestCalendarItemFromComponent_Invalid(t *testing.T) {
+	data := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:no-start\r\nSUMMARY:x\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+	calendars, err := parseICalendar(strings.NewReader(data))
+	require.NoError(t, err)
+
+	_, _, err = calendarItemFromComponent(calendars[0].Components[0], icalTimezones{})
+
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestResolveVTimezone 测试时区解析：IANA 名称、X-LIC-LOCATION、固定偏移
+func TestResolveVTimezone(t *testing.T) {
+	tz := icalTimezones{}
+	assert.Equal(t, "Asia/Shanghai", tz.location("Asia/Shanghai").String())
+	assert.Equal(t, "Europe/Berlin", tz.location("/mozilla.org/20050126_1/Europe/Berlin").String())
+	assert.Equal(t, time.UTC, tz.location("Unknown Zone"))
+
+	vtz := &icalComponent{
+		Name:       "VTIMEZONE",
+		Properties: []*icalProperty{{Name: "X-LIC-LOCATION", Value: "America/New_York"}},
+	}
+	assert.Equal(t, "America/New_York", resolveVTimezone("Eastern Standard Time", vtz).String())
+
+	offset, err := parseUTCOffset("-0530")
+	require.NoError(t, err)
+	assert.Equal(t, -(5*3600 + 30*60), offset)
+}
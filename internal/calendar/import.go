@@ -0,0 +1,420 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ImportStatus 单个组件的导入结果
+type ImportStatus string
+
+const (
+	ImportStatusCreated ImportStatus = "created" // 新建
+	ImportStatusUpdated ImportStatus = "updated" // SEQUENCE 更新，覆盖已有日历项
+	ImportStatusSkipped ImportStatus = "skipped" // 已有日历项不比导入的旧
+	ImportStatusFailed  ImportStatus = "failed"  // 解析或保存失败
+)
+
+// ImportItemResult 单个组件的导入结果
+type ImportItemResult struct {
+	Type         CalendarItemType `json:"type"`
+	UID          string           `json:"uid"`
+	RecurrenceID *time.Time       `json:"recurrence_id,omitempty"`
+	Summary      *string          `json:"summary,omitempty"`
+	Status       ImportStatus     `json:"status"`
+	ID           uint             `json:"id,omitempty"`
+	Error        string           `json:"error,omitempty"`
+	Warnings     []string         `json:"warnings,omitempty"` // 被忽略的内容，例如不支持的 VALARM
+}
+
+// ImportReport iCalendar 导入报告
+type ImportReport struct {
+	Created int                 `json:"created"`
+	Updated int                 `json:"updated"`
+	Skipped int                 `json:"skipped"`
+	Failed  int                 `json:"failed"`
+	Items   []*ImportItemResult `json:"items"`
+}
+
+func (r *ImportReport) add(result *ImportItemResult) {
+	switch result.Status {
+	case ImportStatusCreated:
+		r.Created++
+	case ImportStatusUpdated:
+		r.Updated++
+	case ImportStatusSkipped:
+		r.Skipped++
+	case ImportStatusFailed:
+		r.Failed++
+	}
+	r.Items = append(r.Items, result)
+}
+
+// ImportICalendar 导入 iCalendar 数据
+// 按 UID（以及 RECURRENCE-ID）匹配已有日历项：不存在则新建；导入的 SEQUENCE 更大
+// （或相同但 LAST-MODIFIED 更新）时覆盖，否则跳过。单个组件失败不影响其它组件
+func (s *service) ImportICalendar(userID *uint, r io.Reader) (*ImportReport, error) {
+	calendars, err := parseICalendar(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{Items: []*ImportItemResult{}}
+	for _, cal := range calendars {
+		tz := newICalTimezones(cal, s.userLocation(userID))
+		for _, comp := range cal.Components {
+			itemType := CalendarItemType(comp.Name)
+			if !isValidCalendarItemType(itemType) {
+				continue
+			}
+			report.add(s.importComponent(userID, comp, tz))
+		}
+	}
+	return report, nil
+}
+
+// importComponent 导入单个 VEVENT/VTODO/VJOURNAL/VFREEBUSY 组件
+func (s *service) importComponent(userID *uint, comp *icalComponent, tz icalTimezones) *ImportItemResult {
+	result := &ImportItemResult{Type: CalendarItemType(comp.Name), Summary: comp.Text("SUMMARY")}
+	if p := comp.Prop("UID"); p != nil {
+		result.UID = p.Value
+	}
+
+	item, warnings, err := calendarItemFromComponent(comp, tz)
+	result.Warnings = warnings
+	if err != nil {
+		result.Status = ImportStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	item.UserID = userID
+	result.UID = item.UID
+	result.RecurrenceID = item.RecurrenceID
+
+	var existing *CalendarItem
+	if item.RecurrenceID != nil {
+		existing, err = s.repo.GetCalendarItemOverride(userID, item.UID, *item.RecurrenceID)
+	} else {
+		existing, err = s.repo.GetCalendarItemByUID(userID, item.UID)
+	}
+
+	if err != nil || existing == nil {
+		if err := s.repo.CreateCalendarItem(item); err != nil {
+			result.Status = ImportStatusFailed
+			result.Error = fmt.Sprintf("创建日历项失败: %v", err)
+			return result
+		}
+		result.Status = ImportStatusCreated
+		result.ID = item.ID
+		s.emit(EventCalendarItemCreated, userID, item)
+		return result
+	}
+
+	result.ID = existing.ID
+	if !isNewerRevision(item, existing) {
+		result.Status = ImportStatusSkipped
+		return result
+	}
+
+	item.ID = existing.ID
+	if err := s.repo.UpdateCalendarItem(userID, item); err != nil {
+		result.Status = ImportStatusFailed
+		result.Error = fmt.Sprintf("更新日历项失败: %v", err)
+		return result
+	}
+	if err := replaceValarms(s.repo, existing.ID, item.Alarms); err != nil {
+		result.Status = ImportStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+	if err := replaceAttendees(s.repo, existing.ID, item.Attendees); err != nil {
+		result.Status = ImportStatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	result.Status = ImportStatusUpdated
+	s.emit(EventCalendarItemUpdated, userID, item)
+	return result
+}
+
+// replaceValarms 用导入的提醒替换日历项已有的提醒，repo 可以是事务中的仓库
+func replaceValarms(repo Repository, calendarItemID uint, alarms []Valarm) error {
+	if err := repo.DeleteValarmsByCalendarItemID(calendarItemID); err != nil {
+		return fmt.Errorf("删除原有提醒失败: %w", err)
+	}
+	for i := range alarms {
+		alarm := alarms[i]
+		alarm.CalendarItemID = calendarItemID
+		if err := repo.CreateValarm(&alarm); err != nil {
+			return fmt.Errorf("创建提醒失败: %w", err)
+		}
+	}
+	return nil
+}
+
+// isNewerRevision 导入的日历项是否比已有的更新：SEQUENCE 更大，或相同但 LAST-MODIFIED 更晚
+func isNewerRevision(incoming, existing *CalendarItem) bool {
+	inSeq, exSeq := 0, 0
+	if incoming.Sequence != nil {
+		inSeq = *incoming.Sequence
+	}
+	if existing.Sequence != nil {
+		exSeq = *existing.Sequence
+	}
+	if inSeq != exSeq {
+		return inSeq > exSeq
+	}
+	if incoming.LastModified == nil || existing.LastModified == nil {
+		return false
+	}
+	return incoming.LastModified.After(*existing.LastModified)
+}
+
+// calendarItemFromComponent 将 iCalendar 组件转换为日历项（包括其中的 ATTENDEE 和 VALARM）
+// 无法转换的 VALARM 会被忽略并在 warnings 中说明
+func calendarItemFromComponent(comp *icalComponent, tz icalTimezones) (item *CalendarItem, warnings []string, err error) {
+	item = &CalendarItem{
+		Type:        CalendarItemType(comp.Name),
+		Summary:     comp.Text("SUMMARY"),
+		Description: comp.Text("DESCRIPTION"),
+		Location:    comp.Text("LOCATION"),
+		Comment:     comp.Text("COMMENT"),
+		Contact:     comp.Text("CONTACT"),
+		Status:      comp.Text("STATUS"),
+		Class:       comp.Text("CLASS"),
+		Transp:      comp.Text("TRANSP"),
+		RelatedTo:   comp.Text("RELATED-TO"),
+		Categories:  StringArray{},
+		Resources:   StringArray{},
+		ExDate:      StringArray{},
+		RDate:       StringArray{},
+	}
+	raw := comp.Raw
+	item.RawIcal = &raw
+
+	if p := comp.Prop("UID"); p != nil && strings.TrimSpace(p.Value) != "" {
+		item.UID = strings.TrimSpace(p.Value)
+	} else {
+		item.UID = uuid.New().String()
+	}
+	if p := comp.Prop("ORGANIZER"); p != nil {
+		organizer := p.Value
+		item.Organizer = &organizer
+	}
+	for _, p := range comp.Props("ATTENDEE") {
+		if strings.TrimSpace(p.Value) != "" {
+			item.Attendees = append(item.Attendees, *attendeeFromProperty(p))
+		}
+	}
+	if p := comp.Prop("URL"); p != nil {
+		url := p.Value
+		item.URL = &url
+	}
+	if p := comp.Prop("DURATION"); p != nil {
+		if _, err := ParseDuration(p.Value); err != nil {
+			return nil, nil, fmt.Errorf("DURATION: %w", err)
+		}
+		duration := p.Value
+		item.Duration = &duration
+	}
+	if p := comp.Prop("RRULE"); p != nil {
+		if _, err := ParseRRule(p.Value); err != nil {
+			return nil, nil, fmt.Errorf("RRULE: %w", err)
+		}
+		rrule := p.Value
+		item.RRule = &rrule
+	}
+
+	if item.Priority, err = intProp(comp, "PRIORITY", 0, 9); err != nil {
+		return nil, nil, err
+	}
+	if item.PercentComplete, err = intProp(comp, "PERCENT-COMPLETE", 0, 100); err != nil {
+		return nil, nil, err
+	}
+	if item.Sequence, err = intProp(comp, "SEQUENCE", 0, -1); err != nil {
+		return nil, nil, err
+	}
+
+	timeProps := []struct {
+		name   string
+		target **time.Time
+	}{
+		{"COMPLETED", &item.Completed},
+		{"LAST-MODIFIED", &item.LastModified},
+		{"RECURRENCE-ID", &item.RecurrenceID},
+	}
+	for _, tp := range timeProps {
+		p := comp.Prop(tp.name)
+		if p == nil {
+			continue
+		}
+		t, _, err := tz.parseICalTime(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%s: %w", tp.name, err)
+		}
+		*tp.target = &t
+	}
+	// DTSTART（VTODO 没有 DTSTART 时为 DUE）只有日期时为全天日历项
+	var startIsDate, dueIsDate bool
+	if p := comp.Prop("DTSTART"); p != nil {
+		if item.DtStart, item.DtStartTZID, startIsDate, err = tz.parseICalTimeTZID(p); err != nil {
+			return nil, nil, fmt.Errorf("DTSTART: %w", err)
+		}
+	}
+	if p := comp.Prop("DTEND"); p != nil {
+		dtEnd, tzid, _, err := tz.parseICalTimeTZID(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("DTEND: %w", err)
+		}
+		item.DtEnd, item.DtEndTZID = &dtEnd, tzid
+	}
+	if p := comp.Prop("DUE"); p != nil {
+		due, tzid, isDate, err := tz.parseICalTimeTZID(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("DUE: %w", err)
+		}
+		item.Due, item.DueTZID, dueIsDate = &due, tzid, isDate
+	}
+	if startIsDate || (item.DtStart.IsZero() && dueIsDate) {
+		loc := item.StartLocation()
+		if item.DtStart.IsZero() {
+			loc = item.Due.Location()
+		}
+		item.AllDay = true
+		item.normalizeAllDay(loc)
+		item.setTimezone(loc)
+	}
+
+	for _, p := range comp.Props("CATEGORIES") {
+		item.Categories = append(item.Categories, splitICalList(p.Value)...)
+	}
+	for _, p := range comp.Props("RESOURCES") {
+		item.Resources = append(item.Resources, splitICalList(p.Value)...)
+	}
+	for _, p := range comp.Props("EXDATE") {
+		values, err := tz.parseICalTimeList(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("EXDATE: %w", err)
+		}
+		item.ExDate = append(item.ExDate, values...)
+	}
+	for _, p := range comp.Props("RDATE") {
+		values, err := tz.parseICalTimeList(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("RDATE: %w", err)
+		}
+		item.RDate = append(item.RDate, values...)
+	}
+
+	if err := validateImportedItem(comp, item); err != nil {
+		return nil, nil, err
+	}
+
+	for _, sub := range comp.Components {
+		if sub.Name != "VALARM" {
+			continue
+		}
+		alarm, err := valarmFromComponent(sub)
+		if err != nil {
+			warnings = append(warnings, fmt.Sprintf("忽略 VALARM: %v", err))
+			continue
+		}
+		item.Alarms = append(item.Alarms, *alarm)
+	}
+
+	return item, warnings, nil
+}
+
+// validateImportedItem 按组件类型检查必需属性（与创建日历项的规则一致，
+// 但允许 VEVENT 既没有 DTEND 也没有 DURATION，RFC 5545 中这是合法的）
+func validateImportedItem(comp *icalComponent, item *CalendarItem) error {
+	hasStart := comp.Prop("DTSTART") != nil
+	switch item.Type {
+	case CalendarItemTypeEvent, CalendarItemTypeJournal:
+		if !hasStart {
+			return fmt.Errorf("%w: %s 缺少 DTSTART", ErrInvalidInput, item.Type)
+		}
+		if item.DtEnd != nil && item.Duration != nil {
+			return fmt.Errorf("%w: %s 不能同时包含 DTEND 和 DURATION", ErrInvalidInput, item.Type)
+		}
+	case CalendarItemTypeTodo:
+		if !hasStart && item.Due == nil {
+			return fmt.Errorf("%w: VTODO 需要 DTSTART 或 DUE", ErrInvalidInput)
+		}
+	case CalendarItemTypeFreeBusy:
+		if !hasStart || item.DtEnd == nil {
+			return fmt.Errorf("%w: VFREEBUSY 需要 DTSTART 和 DTEND", ErrInvalidInput)
+		}
+	}
+	return nil
+}
+
+// valarmFromComponent 将 VALARM 组件转换为提醒，X- 属性保存在 XProperty 中
+func valarmFromComponent(comp *icalComponent) (*Valarm, error) {
+	alarm := &Valarm{
+		Description: comp.Text("DESCRIPTION"),
+		Summary:     comp.Text("SUMMARY"),
+	}
+
+	if p := comp.Prop("ACTION"); p != nil {
+		alarm.Action = ValarmAction(strings.ToUpper(p.Value))
+	}
+	if !isValidValarmAction(alarm.Action) {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAction, alarm.Action)
+	}
+
+	p := comp.Prop("TRIGGER")
+	if p == nil {
+		return nil, errors.New("缺少 TRIGGER")
+	}
+	alarm.Trigger = p.Value
+	if related := p.Param("RELATED"); strings.EqualFold(related, "END") {
+		alarm.XProperty = JSONB{"TRIGGER-RELATED": "END"}
+	}
+
+	if p := comp.Prop("ATTENDEE"); p != nil {
+		attendee := p.Value
+		alarm.Attendee = &attendee
+	}
+	if p := comp.Prop("DURATION"); p != nil {
+		duration := p.Value
+		alarm.Duration = &duration
+	}
+	repeat, err := intProp(comp, "REPEAT", 0, -1)
+	if err != nil {
+		return nil, err
+	}
+	alarm.RepeatCount = repeat
+
+	for _, p := range comp.Properties {
+		if !strings.HasPrefix(p.Name, "X-") {
+			continue
+		}
+		if alarm.XProperty == nil {
+			alarm.XProperty = JSONB{}
+		}
+		alarm.XProperty[p.Name] = p.Value
+	}
+
+	return alarm, nil
+}
+
+// intProp 解析整数属性，max < 0 表示没有上限
+func intProp(comp *icalComponent, name string, min, max int) (*int, error) {
+	p := comp.Prop(name)
+	if p == nil {
+		return nil, nil
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(p.Value))
+	if err != nil || n < min || (max >= 0 && n > max) {
+		return nil, fmt.Errorf("%w: 无效的 %s %q", ErrInvalidInput, name, p.Value)
+	}
+	return &n, nil
+}
@@ -0,0 +1,109 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestService_ImportICalendar 测试导入：新建、按 SEQUENCE 更新
+func TestService_ImportICalendar(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	existingSeq := 1
+	existing := &CalendarItem{ID: 7, UID: "event-1@example.com", Sequence: &existingSeq}
+
+	mockRepo.On("GetCalendarItemByUID", &userID, "event-1@example.com").Return(existing, nil)
+	mockRepo.On("UpdateCalendarItem", &userID, mock.MatchedBy(func(item *CalendarItem) bool {
+		return item.ID == 7 && *item.Sequence == 2 && *item.UserID == userID
+	})).Return(nil)
+	mockRepo.On("DeleteValarmsByCalendarItemID", uint(7)).Return(nil)
+	mockRepo.On("DeleteAttendeesByCalendarItemID", uint(7)).Return(nil)
+	mockRepo.On("CreateValarm", mock.MatchedBy(func(alarm *Valarm) bool {
+		return alarm.CalendarItemID == 7 && alarm.Trigger == "-PT15M"
+	})).Return(nil)
+	mockRepo.On("GetCalendarItemByUID", &userID, "todo-1@example.com").Return(nil, errors.New("not found"))
+	mockRepo.On("CreateCalendarItem", mock.MatchedBy(func(item *CalendarItem) bool {
+		return item.UID == "todo-1@example.com" && item.Type == CalendarItemTypeTodo && item.RawIcal != nil
+	})).Return(nil)
+
+	report, err := service.ImportICalendar(&userID, strings.NewReader(testICalendar))
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Updated)
+	assert.Equal(t, 0, report.Skipped)
+	assert.Equal(t, 0, report.Failed)
+	require.Len(t, report.Items, 2)
+	assert.Equal(t, ImportStatusUpdated, report.Items[0].Status)
+	assert.Equal(t, uint(7), report.Items[0].ID)
+	assert.Equal(t, ImportStatusCreated, report.Items[1].Status)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_ImportICalendar_SkipAndFail 测试导入：SEQUENCE 不更新时跳过，无效组件失败
+func TestService_ImportICalendar_SkipAndFail(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:same\r\nDTSTART:20250106T090000Z\r\nSEQUENCE:3\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:broken\r\nDTSTART:20250106T090000Z\r\nRRULE:FREQ=SOMETIMES\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+	existingSeq := 3
+	existing := &CalendarItem{ID: 3, UID: "same", Sequence: &existingSeq}
+
+	mockRepo.On("GetCalendarItemByUID", &userID, "same").Return(existing, nil)
+
+	report, err := service.ImportICalendar(&userID, strings.NewReader(data))
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Skipped)
+	assert.Equal(t, 1, report.Failed)
+	assert.Equal(t, "broken", report.Items[1].UID)
+	assert.Contains(t, report.Items[1].Error, "RRULE")
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_ImportICalendar_Invalid 测试导入无效数据
+func TestService_ImportICalendar_Invalid(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	report, err := service.ImportICalendar(&userID, strings.NewReader("not a calendar"))
+
+	assert.Nil(t, report)
+	assert.ErrorIs(t, err, ErrInvalidICalendar)
+}
+
+// TestService_ImportICalendar_SameUIDAsOtherUser 测试两个用户导入同一个 UID：只查找当前用户的日历项，
+// 其他用户持有相同 UID 时仍然为当前用户新建
+func TestService_ImportICalendar_SameUIDAsOtherUser(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	otherUserID, userID := uint(1), uint(2)
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:shared@example.com\r\nDTSTART:20250106T090000Z\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	mockRepo.On("GetCalendarItemByUID", &userID, "shared@example.com").Return(nil, errors.New("not found"))
+	mockRepo.On("CreateCalendarItem", mock.MatchedBy(func(item *CalendarItem) bool {
+		return item.UID == "shared@example.com" && *item.UserID == userID
+	})).Return(nil)
+
+	report, err := service.ImportICalendar(&userID, strings.NewReader(data))
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, report.Created)
+	mockRepo.AssertNotCalled(t, "GetCalendarItemByUID", &otherUserID, "shared@example.com")
+	mockRepo.AssertExpectations(t)
+}
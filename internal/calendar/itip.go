@@ -0,0 +1,252 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// ErrUnsupportedITIPMethod 不支持的 iTIP 方法
+var ErrUnsupportedITIPMethod = errors.New("不支持的 iTIP 方法")
+
+// ITIPMethod iTIP 消息的 METHOD（RFC 5546 1.4）
+type ITIPMethod string
+
+const (
+	ITIPMethodRequest ITIPMethod = "REQUEST" // 组织者发出的邀请或更新
+	ITIPMethodReply   ITIPMethod = "REPLY"   // 参与者的答复
+	ITIPMethodCancel  ITIPMethod = "CANCEL"  // 组织者取消日程或取消某些参与者
+	ITIPMethodCounter ITIPMethod = "COUNTER" // 参与者提议修改（例如新的时间）
+)
+
+// ITIPResult 收到的 iTIP 消息中单个组件的处理结果
+type ITIPResult struct {
+	UID            string     `json:"uid"`
+	RecurrenceID   *time.Time `json:"recurrence_id,omitempty"`
+	Attendee       string     `json:"attendee,omitempty"`
+	PartStat       PartStat   `json:"partstat,omitempty"`
+	CalendarItemID uint       `json:"calendar_item_id,omitempty"`
+	Applied        bool       `json:"applied"`
+	Reason         string     `json:"reason,omitempty"` // 未应用的原因
+}
+
+// ITIPReport 收到的 iTIP 消息的处理报告
+type ITIPReport struct {
+	Method  ITIPMethod    `json:"method"`
+	Results []*ITIPResult `json:"results"`
+}
+
+// WriteITIP 将日历项序列化为 iTIP 消息（RFC 5546）
+// 消息只包含该日历项一个组件，不包含组织者自己的 VALARM；CANCEL 消息的 STATUS 为 CANCELLED。
+// ORGANIZER、ATTENDEE 和 SEQUENCE 由调用方在 item 中准备好
+func WriteITIP(w io.Writer, method ITIPMethod, item *CalendarItem) error {
+	msg := *item
+	msg.Alarms = nil
+	if method == ITIPMethodCancel {
+		cancelled := "CANCELLED"
+		msg.Status = &cancelled
+	}
+
+	iw := &icalWriter{w: w}
+	iw.line("BEGIN", nil, "VCALENDAR")
+	iw.line("VERSION", nil, "2.0")
+	iw.line("PRODID", nil, icalProdID)
+	iw.line("CALSCALE", nil, "GREGORIAN")
+	iw.line("METHOD", nil, string(method))
+	for _, zone := range itemTimezones([]*CalendarItem{&msg}) {
+		iw.timezone(zone.tzid, zone.year)
+	}
+	iw.item(&msg, time.Now())
+	iw.line("END", nil, "VCALENDAR")
+	return iw.err
+}
+
+// ProcessITIPMessage 处理参与者发回给组织者的 iTIP 消息（REPLY 或 COUNTER）
+// 按 UID（以及 RECURRENCE-ID）找到用户的日历项，按地址找到回复的参与者：
+// REPLY 记录参与状态，COUNTER 记录提议的新时间，两者都记录 COMMENT 和回复时间。
+// 针对旧版本（SEQUENCE 更小）的回复以及比已记录的回复更早的回复会被忽略。单个组件失败不影响其它组件
+func (s *service) ProcessITIPMessage(userID *uint, r io.Reader) (*ITIPReport, error) {
+	calendars, err := parseICalendar(r)
+	if err != nil {
+		return nil, err
+	}
+	if len(calendars) != 1 {
+		return nil, fmt.Errorf("%w: iTIP 消息只能包含一个 VCALENDAR", ErrInvalidICalendar)
+	}
+	cal := calendars[0]
+
+	p := cal.Prop("METHOD")
+	if p == nil {
+		return nil, fmt.Errorf("%w: 缺少 METHOD", ErrInvalidICalendar)
+	}
+	method := ITIPMethod(strings.ToUpper(strings.TrimSpace(p.Value)))
+	if method != ITIPMethodReply && method != ITIPMethodCounter {
+		return nil, fmt.Errorf("%w: %s，只接受 REPLY 和 COUNTER", ErrUnsupportedITIPMethod, method)
+	}
+
+	report := &ITIPReport{Method: method, Results: []*ITIPResult{}}
+	tz := newICalTimezones(cal, s.userLocation(userID))
+	for _, comp := range cal.Components {
+		if comp.Name != string(CalendarItemTypeEvent) && comp.Name != string(CalendarItemTypeTodo) {
+			continue
+		}
+		report.Results = append(report.Results, s.processITIPComponent(userID, method, comp, tz))
+	}
+	return report, nil
+}
+
+// processITIPComponent 处理 REPLY/COUNTER 中的单个 VEVENT/VTODO 组件
+func (s *service) processITIPComponent(userID *uint, method ITIPMethod, comp *icalComponent, tz icalTimezones) *ITIPResult {
+	result := &ITIPResult{}
+	skip := func(format string, args ...any) *ITIPResult {
+		result.Reason = fmt.Sprintf(format, args...)
+		return result
+	}
+
+	if p := comp.Prop("UID"); p != nil {
+		result.UID = strings.TrimSpace(p.Value)
+	}
+	if result.UID == "" {
+		return skip("缺少 UID")
+	}
+	if p := comp.Prop("RECURRENCE-ID"); p != nil {
+		recurrenceID, _, err := tz.parseICalTime(p)
+		if err != nil {
+			return skip("无效的 RECURRENCE-ID: %v", err)
+		}
+		result.RecurrenceID = &recurrenceID
+	}
+	attendees := comp.Props("ATTENDEE")
+	if len(attendees) != 1 {
+		return skip("%s 必须包含且只包含一个 ATTENDEE", method)
+	}
+	reply := attendeeFromProperty(attendees[0])
+	result.Attendee = reply.Address
+
+	var item *CalendarItem
+	var err error
+	if result.RecurrenceID != nil {
+		item, err = s.repo.GetCalendarItemOverride(userID, result.UID, *result.RecurrenceID)
+		if err != nil {
+			return skip("该实例没有单独修改过，暂不支持对重复日程的单个实例回复")
+		}
+	} else if item, err = s.repo.GetCalendarItemByUID(userID, result.UID); err != nil {
+		return skip("日程不存在")
+	}
+	result.CalendarItemID = item.ID
+
+	if p := comp.Prop("ORGANIZER"); p != nil && item.Organizer != nil && *item.Organizer != "" &&
+		!sameCalendarAddress(strings.TrimSpace(p.Value), *item.Organizer) {
+		return skip("ORGANIZER 与日程的组织者不一致")
+	}
+
+	var attendee *Attendee
+	for i := range item.Attendees {
+		if sameCalendarAddress(item.Attendees[i].Address, reply.Address) {
+			attendee = &item.Attendees[i]
+			break
+		}
+	}
+	if attendee == nil {
+		return skip("%s 不是该日程的参与者", reply.Address)
+	}
+
+	sequence, err := intProp(comp, "SEQUENCE", 0, -1)
+	if err != nil {
+		return skip("%v", err)
+	}
+	// 没有 SEQUENCE 时视为 0（RFC 5545 3.8.7.4）
+	replied := 0
+	if sequence != nil {
+		replied = *sequence
+	}
+	if current := sequenceOf(item); replied < current {
+		return skip("回复针对的是日程的旧版本（SEQUENCE %d，当前为 %d）", replied, current)
+	}
+
+	respondedAt := time.Now()
+	if p := comp.Prop("DTSTAMP"); p != nil {
+		if t, _, err := tz.parseICalTime(p); err == nil {
+			respondedAt = t
+		}
+	}
+	if attendee.RespondedAt != nil && respondedAt.Before(*attendee.RespondedAt) {
+		return skip("已经记录了该参与者更新的回复")
+	}
+
+	switch method {
+	case ITIPMethodReply:
+		if attendees[0].Param("PARTSTAT") == "" {
+			return skip("REPLY 的 ATTENDEE 缺少 PARTSTAT")
+		}
+		attendee.PartStat = reply.PartStat
+		attendee.RSVP = false
+		attendee.ProposedStart, attendee.ProposedEnd = nil, nil
+	case ITIPMethodCounter:
+		start, end, err := proposedTimes(comp, tz)
+		if err != nil {
+			return skip("%v", err)
+		}
+		attendee.ProposedStart, attendee.ProposedEnd = start, end
+	}
+	attendee.RespondedAt = &respondedAt
+	attendee.Comment = comp.Text("COMMENT")
+	result.PartStat = attendee.PartStat
+
+	if err := s.repo.UpdateAttendee(attendee); err != nil {
+		return skip("更新参与者失败: %v", err)
+	}
+	if err := s.touchAfterReply(userID, item, attendee); err != nil {
+		return skip("%v", err)
+	}
+	result.Applied = true
+	return result
+}
+
+// proposedTimes 解析 COUNTER 中提议的开始和结束时间（DTEND 或 DTSTART + DURATION，VTODO 为 DUE）
+func proposedTimes(comp *icalComponent, tz icalTimezones) (start, end *time.Time, err error) {
+	p := comp.Prop("DTSTART")
+	if p == nil {
+		return nil, nil, fmt.Errorf("COUNTER 缺少 DTSTART")
+	}
+	t, _, err := tz.parseICalTime(p)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无效的 DTSTART: %w", err)
+	}
+	start = &t
+
+	if p := comp.Prop("DTEND"); p != nil {
+		t, _, err := tz.parseICalTime(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("无效的 DTEND: %w", err)
+		}
+		end = &t
+	} else if p := comp.Prop("DUE"); p != nil {
+		t, _, err := tz.parseICalTime(p)
+		if err != nil {
+			return nil, nil, fmt.Errorf("无效的 DUE: %w", err)
+		}
+		end = &t
+	} else if p := comp.Prop("DURATION"); p != nil {
+		d, err := ParseDuration(p.Value)
+		if err != nil {
+			return nil, nil, fmt.Errorf("无效的 DURATION: %w", err)
+		}
+		t := start.Add(d)
+		end = &t
+	}
+	if end != nil && end.Before(*start) {
+		return nil, nil, fmt.Errorf("提议的结束时间早于开始时间")
+	}
+	return start, end, nil
+}
+
+// sequenceOf 日历项的 SEQUENCE，未设置时为 0
+func sequenceOf(item *CalendarItem) int {
+	if item.Sequence == nil {
+		return 0
+	}
+	return *item.Sequence
+}
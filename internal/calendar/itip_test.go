@@ -0,0 +1,159 @@
+package calendar
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// itipMessage 构造参与者发回的 iTIP 消息
+func itipMessage(method string, props ...string) string {
+	lines := []string{"BEGIN:VCALENDAR", "VERSION:2.0", "PRODID:-//Test//EN", "METHOD:" + method, "BEGIN:VEVENT"}
+	lines = append(lines, props...)
+	lines = append(lines, "END:VEVENT", "END:VCALENDAR")
+	return strings.Join(lines, "\r\n") + "\r\n"
+}
+
+// TestWriteITIP 测试 iTIP 消息包含 METHOD、不包含 VALARM，CANCEL 的 STATUS 为 CANCELLED
+func TestWriteITIP(t *testing.T) {
+	organizer := "mailto:alice@example.com"
+	sequence := 2
+	item := &CalendarItem{
+		UID:       "meeting",
+		Type:      CalendarItemTypeEvent,
+		DtStart:   utcTime(2025, 1, 9, 2, 0),
+		Organizer: &organizer,
+		Sequence:  &sequence,
+		Attendees: []Attendee{{Address: "mailto:bob@example.com", PartStat: PartStatNeedsAction, RSVP: true}},
+		Alarms:    []Valarm{{Action: ValarmActionDisplay, Trigger: "-PT15M"}},
+	}
+
+	var buf bytes.Buffer
+	require.NoError(t, WriteITIP(&buf, ITIPMethodCancel, item))
+	data := buf.String()
+	assert.Contains(t, data, "METHOD:CANCEL\r\n")
+	assert.Contains(t, data, "ORGANIZER:mailto:alice@example.com\r\n")
+	assert.Contains(t, data, "ATTENDEE;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:bob@example.com\r\n")
+	assert.Contains(t, data, "STATUS:CANCELLED\r\n")
+	assert.Contains(t, data, "SEQUENCE:2\r\n")
+	assert.NotContains(t, data, "VALARM")
+	assert.Nil(t, item.Status)
+	assert.Len(t, item.Alarms, 1)
+}
+
+// TestService_ProcessITIPMessage_Reply 测试 REPLY 记录参与状态、COMMENT 和回复时间，不增加 SEQUENCE
+func TestService_ProcessITIPMessage_Reply(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	organizer := "mailto:alice@example.com"
+	sequence := 1
+	item := &CalendarItem{
+		ID: 1, UID: "meeting", Type: CalendarItemTypeEvent, DtStart: utcTime(2025, 1, 9, 2, 0),
+		Organizer: &organizer, Sequence: &sequence,
+		Attendees: []Attendee{{ID: 5, CalendarItemID: 1, Address: "mailto:bob@example.com", PartStat: PartStatNeedsAction, RSVP: true}},
+	}
+
+	mockRepo.On("GetCalendarItemByUID", &userID, "meeting").Return(item, nil)
+	mockRepo.On("UpdateAttendee", mock.MatchedBy(func(a *Attendee) bool { return a.ID == 5 })).Return(nil)
+	mockRepo.On("UpdateCalendarItem", &userID, mock.MatchedBy(func(i *CalendarItem) bool {
+		return i.LastModified != nil && *i.Sequence == 1
+	})).Return(nil)
+
+	report, err := service.ProcessITIPMessage(&userID, strings.NewReader(itipMessage("REPLY",
+		"UID:meeting",
+		"DTSTAMP:20250106T080000Z",
+		"SEQUENCE:1",
+		"ORGANIZER:mailto:alice@example.com",
+		"ATTENDEE;PARTSTAT=ACCEPTED:MAILTO:Bob@example.com",
+		"COMMENT:会准时到",
+	)))
+
+	require.NoError(t, err)
+	assert.Equal(t, ITIPMethodReply, report.Method)
+	require.Len(t, report.Results, 1)
+	assert.True(t, report.Results[0].Applied, report.Results[0].Reason)
+	assert.Equal(t, PartStatAccepted, report.Results[0].PartStat)
+	assert.Equal(t, uint(1), report.Results[0].CalendarItemID)
+
+	attendee := item.Attendees[0]
+	assert.Equal(t, PartStatAccepted, attendee.PartStat)
+	assert.False(t, attendee.RSVP)
+	assert.Equal(t, "会准时到", *attendee.Comment)
+	assert.True(t, utcTime(2025, 1, 6, 8, 0).Equal(*attendee.RespondedAt))
+	mockRepo.AssertExpectations(t)
+
+	// 比已记录的回复更早的回复、针对旧版本的回复以及非参与者的回复被忽略
+	tests := []struct {
+		name  string
+		props []string
+	}{
+		{"更早的回复", []string{"UID:meeting", "DTSTAMP:20250105T080000Z", "SEQUENCE:1", "ATTENDEE;PARTSTAT=DECLINED:mailto:bob@example.com"}},
+		{"旧版本", []string{"UID:meeting", "DTSTAMP:20250107T080000Z", "SEQUENCE:0", "ATTENDEE;PARTSTAT=DECLINED:mailto:bob@example.com"}},
+		{"非参与者", []string{"UID:meeting", "DTSTAMP:20250107T080000Z", "SEQUENCE:1", "ATTENDEE;PARTSTAT=DECLINED:mailto:eve@example.com"}},
+		{"缺少 PARTSTAT", []string{"UID:meeting", "DTSTAMP:20250107T080000Z", "SEQUENCE:1", "ATTENDEE:mailto:bob@example.com"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := service.ProcessITIPMessage(&userID, strings.NewReader(itipMessage("REPLY", tt.props...)))
+			require.NoError(t, err)
+			require.Len(t, report.Results, 1)
+			assert.False(t, report.Results[0].Applied)
+			assert.NotEmpty(t, report.Results[0].Reason)
+		})
+	}
+	assert.Equal(t, PartStatAccepted, item.Attendees[0].PartStat)
+}
+
+// TestService_ProcessITIPMessage_Counter 测试 COUNTER 记录提议的时间，不修改参与状态
+func TestService_ProcessITIPMessage_Counter(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	item := &CalendarItem{
+		ID: 1, UID: "meeting", Type: CalendarItemTypeEvent, DtStart: utcTime(2025, 1, 9, 2, 0),
+		Attendees: []Attendee{{ID: 5, CalendarItemID: 1, Address: "mailto:bob@example.com", PartStat: PartStatTentative}},
+	}
+
+	mockRepo.On("GetCalendarItemByUID", &userID, "meeting").Return(item, nil)
+	mockRepo.On("UpdateAttendee", mock.Anything).Return(nil)
+	mockRepo.On("UpdateCalendarItem", &userID, item).Return(nil)
+
+	report, err := service.ProcessITIPMessage(&userID, strings.NewReader(itipMessage("COUNTER",
+		"UID:meeting",
+		"DTSTAMP:20250106T080000Z",
+		"DTSTART;TZID=Asia/Shanghai:20250109T140000",
+		"DURATION:PT1H",
+		"ATTENDEE;PARTSTAT=TENTATIVE:mailto:bob@example.com",
+		"COMMENT:上午有课，改到下午可以吗？",
+	)))
+
+	require.NoError(t, err)
+	require.Len(t, report.Results, 1)
+	assert.True(t, report.Results[0].Applied, report.Results[0].Reason)
+
+	attendee := item.Attendees[0]
+	assert.Equal(t, PartStatTentative, attendee.PartStat)
+	assert.True(t, utcTime(2025, 1, 9, 6, 0).Equal(*attendee.ProposedStart))
+	assert.True(t, utcTime(2025, 1, 9, 7, 0).Equal(*attendee.ProposedEnd))
+	assert.Equal(t, "上午有课，改到下午可以吗？", *attendee.Comment)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_ProcessITIPMessage_Invalid 测试只接受 REPLY 和 COUNTER
+func TestService_ProcessITIPMessage_Invalid(t *testing.T) {
+	service := NewService(new(mockRepository))
+	userID := uint(1)
+
+	_, err := service.ProcessITIPMessage(&userID, strings.NewReader(itipMessage("REQUEST", "UID:meeting")))
+	assert.ErrorIs(t, err, ErrUnsupportedITIPMethod)
+
+	_, err = service.ProcessITIPMessage(&userID, strings.NewReader("BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:meeting\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"))
+	assert.ErrorIs(t, err, ErrInvalidICalendar)
+}
@@ -25,7 +25,8 @@ type CalendarItem struct {
 	UpdatedAt time.Time      `json:"updated_at"`
 	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
 
-	UID             string           `json:"uid" gorm:"not null;size:255;index:idx_uid,unique"`
+	UID             string           `json:"uid" gorm:"not null;size:255;index"` // 同一用户的主日历项唯一；例外实例与主日历项共用 UID（唯一索引见数据库迁移）
+	ResourceName    *string          `json:"-" gorm:"size:255;index"`            // CalDAV 资源名称（客户端 PUT 时 href 的最后一段），为空时为 {UID}.ics
 	Type            CalendarItemType `json:"type" gorm:"not null;type:varchar(20);check:type IN ('VEVENT','VTODO','VJOURNAL','VFREEBUSY')"`
 	Summary         *string          `json:"summary" gorm:"size:500"`
 	Description     *string          `json:"description" gorm:"type:text"`
@@ -34,6 +35,10 @@ type CalendarItem struct {
 	DtStart         time.Time        `json:"dtstart" gorm:"not null;index"`
 	DtEnd           *time.Time       `json:"dtend" gorm:"index"`
 	Due             *time.Time       `json:"due" gorm:"index"`
+	DtStartTZID     *string          `json:"dtstart_tzid" gorm:"column:dt_start_tzid;size:64"` // DTSTART 的时区（IANA 时区名），为空表示 UTC；浮动时间按用户的时区保存
+	DtEndTZID       *string          `json:"dtend_tzid" gorm:"column:dt_end_tzid;size:64"`     // DTEND 的时区
+	DueTZID         *string          `json:"due_tzid" gorm:"column:due_tzid;size:64"`          // DUE 的时区
+	AllDay          bool             `json:"all_day" gorm:"not null;default:false"`            // DTSTART、DTEND、DUE 只有日期（VALUE=DATE），保存为所在时区当天的零点，DTEND 不包含在内
 	Completed       *time.Time       `json:"completed"`
 	Duration        *string          `json:"duration" gorm:"size:100"`
 	Status          *string          `json:"status" gorm:"size:50"`
@@ -50,6 +55,7 @@ type CalendarItem struct {
 	Resources       StringArray      `json:"resources" gorm:"type:jsonb"`
 	URL             *string          `json:"url" gorm:"size:1000"`
 	Class           *string          `json:"class" gorm:"size:50"`
+	Transp          *string          `json:"transp" gorm:"size:20"` // OPAQUE（默认，占用时间）或 TRANSPARENT（不占用时间）
 	LastModified    *time.Time       `json:"last_modified"`
 	RawIcal         *string          `json:"raw_ical" gorm:"type:text"`
 
@@ -58,12 +64,47 @@ type CalendarItem struct {
 
 	// 关联的提醒
 	Alarms []Valarm `json:"alarms" gorm:"foreignKey:CalendarItemID;constraint:OnDelete:CASCADE"`
+
+	// 参与者（ATTENDEE）
+	Attendees []Attendee `json:"attendees" gorm:"foreignKey:CalendarItemID;constraint:OnDelete:CASCADE"`
+
+	// RECURRENCE-ID：例外实例（覆盖重复日历项的某一次实例）为被覆盖实例的原始开始时间，主日历项为空
+	// 展开后的实例也会设置该字段
+	RecurrenceID *time.Time `json:"recurrence_id,omitempty" gorm:"index"`
+
+	// 指向主日历项（仅展开后的实例，不入库）
+	Master *CalendarItem `json:"-" gorm:"-"`
+
+	// 修改时间后与之重叠的已有事件（仅更新接口返回，不入库）
+	Conflicts []Conflict `json:"conflicts,omitempty" gorm:"-"`
+
+	// 匹配搜索关键字的字段及其高亮片段，匹配部分用 <b></b> 标记（仅搜索接口返回，不入库）
+	Highlights map[string]string `json:"highlights,omitempty" gorm:"-"`
+}
+
+// IsOverride 是否为重复日历项的例外实例（已入库且带 RECURRENCE-ID）
+func (item *CalendarItem) IsOverride() bool {
+	return item.RecurrenceID != nil && item.Master == nil
+}
+
+// AfterFind 从数据库读取后将 DTSTART、DTEND、DUE 转换到各自的时区
+func (item *CalendarItem) AfterFind(tx *gorm.DB) error {
+	item.localizeTimes()
+	return nil
 }
 
 func (CalendarItem) TableName() string {
 	return "calendar_items"
 }
 
+// StringValue 返回可选文本属性的值，未设置时返回空字符串
+func StringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
 // ValarmAction 提醒动作类型
 type ValarmAction string
 
@@ -95,6 +136,85 @@ func (Valarm) TableName() string {
 	return "valarms"
 }
 
+// AttendeeRole 参与者角色（ROLE 参数）
+type AttendeeRole string
+
+const (
+	AttendeeRoleChair          AttendeeRole = "CHAIR"
+	AttendeeRoleRequired       AttendeeRole = "REQ-PARTICIPANT"
+	AttendeeRoleOptional       AttendeeRole = "OPT-PARTICIPANT"
+	AttendeeRoleNonParticipant AttendeeRole = "NON-PARTICIPANT"
+)
+
+// PartStat 参与状态（PARTSTAT 参数），COMPLETED 和 IN-PROCESS 仅用于 VTODO
+type PartStat string
+
+const (
+	PartStatNeedsAction PartStat = "NEEDS-ACTION"
+	PartStatAccepted    PartStat = "ACCEPTED"
+	PartStatDeclined    PartStat = "DECLINED"
+	PartStatTentative   PartStat = "TENTATIVE"
+	PartStatDelegated   PartStat = "DELEGATED"
+	PartStatCompleted   PartStat = "COMPLETED"
+	PartStatInProcess   PartStat = "IN-PROCESS"
+)
+
+// CUType 日历用户类型（CUTYPE 参数）
+type CUType string
+
+const (
+	CUTypeIndividual CUType = "INDIVIDUAL"
+	CUTypeGroup      CUType = "GROUP"
+	CUTypeResource   CUType = "RESOURCE"
+	CUTypeRoom       CUType = "ROOM"
+	CUTypeUnknown    CUType = "UNKNOWN"
+)
+
+// Attendee 参与者模型
+type Attendee struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	CalendarItemID uint         `json:"calendar_item_id" gorm:"not null;index"`
+	Address        string       `json:"address" gorm:"not null;size:500;index"`          // 日历用户地址，例如 mailto:alice@example.com
+	CN             *string      `json:"cn" gorm:"size:255"`                              // 显示名称
+	Role           AttendeeRole `json:"role" gorm:"not null;type:varchar(20)"`           // 默认 REQ-PARTICIPANT
+	PartStat       PartStat     `json:"partstat" gorm:"not null;type:varchar(20);index"` // 默认 NEEDS-ACTION
+	RSVP           bool         `json:"rsvp"`                                            // 是否期望回复
+	CUType         CUType       `json:"cutype" gorm:"not null;type:varchar(20)"`         // 默认 INDIVIDUAL
+
+	// 通过 iTIP（REPLY/COUNTER）收到的回复
+	RespondedAt   *time.Time `json:"responded_at"`             // 最近一次回复的 DTSTAMP
+	Comment       *string    `json:"comment" gorm:"type:text"` // 回复附带的 COMMENT
+	ProposedStart *time.Time `json:"proposed_start"`           // COUNTER 提议的开始时间
+	ProposedEnd   *time.Time `json:"proposed_end"`             // COUNTER 提议的结束时间
+}
+
+func (Attendee) TableName() string {
+	return "attendees"
+}
+
+// CalendarFeedToken 日历订阅令牌
+// 持有令牌即可只读订阅用户的日历（不需要 JWT），撤销后订阅地址失效
+type CalendarFeedToken struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	Token          string     `json:"token" gorm:"uniqueIndex;not null;size:255"` // 订阅令牌
+	UserID         uint       `json:"user_id" gorm:"not null;index"`              // 用户ID
+	Name           *string    `json:"name" gorm:"size:255"`                       // 名称，例如订阅的设备
+	IsRevoked      bool       `json:"is_revoked" gorm:"default:false"`            // 是否已撤销
+	LastAccessedAt *time.Time `json:"last_accessed_at"`                           // 最后一次被订阅客户端访问的时间
+}
+
+func (CalendarFeedToken) TableName() string {
+	return "calendar_feed_tokens"
+}
+
 // StringArray 字符串数组类型，用于 JSONB 存储
 type StringArray []string
 
@@ -0,0 +1,434 @@
+package calendar
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidSyncToken = errors.New("无效的同步令牌")
+	ErrUIDConflict      = errors.New("UID 已被其他日历对象资源使用")
+)
+
+// syncTokenPrefix 同步令牌（RFC 6578 要求为 URI）的前缀，后面是最后一次修改的时间（微秒）
+const syncTokenPrefix = "urn:otter:sync:"
+
+// calendarObjectSuffix 默认资源名称的后缀：没有通过 CalDAV PUT 指定资源名称的日历对象使用 {UID}.ics
+const calendarObjectSuffix = ".ics"
+
+// CalendarObject 日历对象资源（RFC 4791 4.1）：同一 UID 的主日历项及其例外实例
+// 没有主日历项时（例如只收到某一次实例的邀请）Items 只包含例外实例
+// 资源名称由客户端 PUT 时决定，不要求与 UID 相同（RFC 4791 4.1）
+type CalendarObject struct {
+	UID          string
+	Name         string // 资源名称（href 的最后一段）
+	Type         CalendarItemType
+	Items        []*CalendarItem // 主日历项在前，例外实例按 RECURRENCE-ID 排序
+	ETag         string
+	LastModified time.Time
+}
+
+// Master 返回主日历项，没有时返回 nil
+func (o *CalendarObject) Master() *CalendarItem {
+	if len(o.Items) > 0 && o.Items[0].RecurrenceID == nil {
+		return o.Items[0]
+	}
+	return nil
+}
+
+// ICalendar 将日历对象序列化为 VCALENDAR
+func (o *CalendarObject) ICalendar() (string, error) {
+	var buf bytes.Buffer
+	if err := WriteICalendar(&buf, "Otter", o.Items); err != nil {
+		return "", err
+	}
+	return buf.String(), nil
+}
+
+// CalendarObjectFilter 日历对象查询条件（CalDAV calendar-query 的 comp-filter 和 time-range）
+type CalendarObjectFilter struct {
+	Type  *CalendarItemType
+	Start *time.Time
+	End   *time.Time
+}
+
+// CalendarSyncResult 增量同步结果（RFC 6578 sync-collection）
+type CalendarSyncResult struct {
+	Token   string            // 新的同步令牌
+	Changed []*CalendarObject // 新建或修改的日历对象
+	Deleted []string          // 已删除的日历对象的资源名称
+}
+
+// calendarObjectName 日历项所属日历对象的资源名称
+func calendarObjectName(item *CalendarItem) string {
+	if item.ResourceName != nil && *item.ResourceName != "" {
+		return *item.ResourceName
+	}
+	return item.UID + calendarObjectSuffix
+}
+
+// ListCalendarObjects 列出符合条件的日历对象
+// 指定时间范围时，重复日历项只有在范围内有实例时才返回
+func (s *service) ListCalendarObjects(userID *uint, filter *CalendarObjectFilter) ([]*CalendarObject, error) {
+	if filter == nil {
+		filter = &CalendarObjectFilter{}
+	}
+	start := time.Time{}
+	if filter.Start != nil {
+		start = *filter.Start
+	}
+	end := exportAllEnd
+	if filter.End != nil {
+		end = *filter.End
+	}
+
+	items, err := s.repo.ListCalendarItemsInRange(userID, start, end, filter.Type)
+	if err != nil {
+		return nil, fmt.Errorf("获取日历项列表失败: %w", err)
+	}
+	if filter.Start == nil && filter.End == nil {
+		return groupCalendarObjects(items), nil
+	}
+
+	var uids []string
+	seen := map[string]bool{}
+	for _, item := range items {
+		if seen[item.UID] || !occursBetween(item, start, end) {
+			continue
+		}
+		seen[item.UID] = true
+		uids = append(uids, item.UID)
+	}
+	return s.calendarObjectsByUIDs(userID, uids)
+}
+
+// occursBetween 日历项在 [start, end] 内是否有实例
+func occursBetween(item *CalendarItem, start, end time.Time) bool {
+	if !item.IsRecurring() {
+		return true
+	}
+	occurrences, err := ExpandCalendarItem(item, start, end)
+	return err != nil || len(occurrences) > 0
+}
+
+// GetCalendarObjects 根据资源名称获取日历对象（CalDAV calendar-multiget），不存在的资源名称被忽略
+func (s *service) GetCalendarObjects(userID *uint, names []string) ([]*CalendarObject, error) {
+	uids, err := s.repo.GetCalendarObjectUIDs(userID, names)
+	if err != nil {
+		return nil, fmt.Errorf("获取日历对象失败: %w", err)
+	}
+	return s.calendarObjectsByUIDs(userID, uids)
+}
+
+// calendarObjectsByUIDs 根据 UID 获取日历对象，不存在的 UID 被忽略
+func (s *service) calendarObjectsByUIDs(userID *uint, uids []string) ([]*CalendarObject, error) {
+	items, err := s.repo.ListCalendarItemsByUIDs(userID, uids)
+	if err != nil {
+		return nil, fmt.Errorf("获取日历项失败: %w", err)
+	}
+	return groupCalendarObjects(items), nil
+}
+
+// GetCalendarObject 根据资源名称获取日历对象
+func (s *service) GetCalendarObject(userID *uint, name string) (*CalendarObject, error) {
+	objects, err := s.GetCalendarObjects(userID, []string{name})
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, ErrCalendarItemNotFound
+	}
+	return objects[0], nil
+}
+
+// PutCalendarObject 用 iCalendar 数据创建或替换资源名称为 name 的日历对象（CalDAV PUT）
+// 数据中所有组件的 UID 必须相同；数据中没有的例外实例会被删除。返回是否为新建
+// 已有的资源不能修改 UID，UID 已保存在其他资源中时返回 ErrUIDConflict（RFC 4791 5.3.2.1）
+func (s *service) PutCalendarObject(userID *uint, name string, r io.Reader) (bool, error) {
+	calendars, err := parseICalendar(r)
+	if err != nil {
+		return false, err
+	}
+	if len(calendars) != 1 {
+		return false, fmt.Errorf("%w: 日历对象只能包含一个 VCALENDAR", ErrInvalidICalendar)
+	}
+	cal := calendars[0]
+	tz := newICalTimezones(cal, s.userLocation(userID))
+
+	var items []*CalendarItem
+	for _, comp := range cal.Components {
+		if !isValidCalendarItemType(CalendarItemType(comp.Name)) {
+			continue
+		}
+		item, _, err := calendarItemFromComponent(comp, tz)
+		if err != nil {
+			return false, fmt.Errorf("%w: %v", ErrInvalidICalendar, err)
+		}
+		if len(items) > 0 && item.UID != items[0].UID {
+			return false, fmt.Errorf("%w: 日历对象中所有组件的 UID 必须相同", ErrInvalidICalendar)
+		}
+		if len(items) > 0 && item.Type != items[0].Type {
+			return false, fmt.Errorf("%w: 日历对象只能包含一种组件", ErrInvalidICalendar)
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return false, fmt.Errorf("%w: 没有日历组件", ErrInvalidICalendar)
+	}
+
+	uid := items[0].UID
+
+	current, err := s.repo.GetCalendarObjectUIDs(userID, []string{name})
+	if err != nil {
+		return false, fmt.Errorf("获取日历对象失败: %w", err)
+	}
+	if len(current) > 0 && current[0] != uid {
+		return false, fmt.Errorf("%w: 资源 %s 中日历对象的 UID 为 %q，不能修改", ErrUIDConflict, name, current[0])
+	}
+	existing, err := s.repo.ListCalendarItemsByUIDs(userID, []string{uid})
+	if err != nil {
+		return false, fmt.Errorf("获取日历项失败: %w", err)
+	}
+	if len(existing) > 0 && calendarObjectName(existing[0]) != name {
+		return false, fmt.Errorf("%w: UID %q 已保存在资源 %s 中", ErrUIDConflict, uid, calendarObjectName(existing[0]))
+	}
+
+	// 资源名称与默认名称不同时才需要保存
+	var resourceName *string
+	if name != uid+calendarObjectSuffix {
+		resourceName = &name
+	}
+
+	// LAST-MODIFIED 记录服务器保存的时间，截断到数据库精度以保证 ETag 稳定
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	var created, updated, deleted []*CalendarItem
+	// 整个日历对象在同一个事务中保存，任何一步失败都不会留下只更新了一部分的对象；事件在提交后发出
+	err = s.repo.Transaction(func(repo Repository) error {
+		kept := map[uint]bool{}
+		for _, item := range items {
+			item.UserID = userID
+			item.ResourceName = resourceName
+			item.LastModified = &now
+
+			current := findCalendarItem(existing, item.RecurrenceID)
+			if current == nil {
+				if err := repo.CreateCalendarItem(item); err != nil {
+					return fmt.Errorf("创建日历项失败: %w", err)
+				}
+				created = append(created, item)
+				continue
+			}
+
+			item.ID = current.ID
+			kept[current.ID] = true
+			if err := repo.UpdateCalendarItem(userID, item); err != nil {
+				return fmt.Errorf("更新日历项失败: %w", err)
+			}
+			if err := replaceValarms(repo, current.ID, item.Alarms); err != nil {
+				return err
+			}
+			if err := replaceAttendees(repo, current.ID, item.Attendees); err != nil {
+				return err
+			}
+			updated = append(updated, item)
+		}
+
+		for _, item := range existing {
+			if kept[item.ID] {
+				continue
+			}
+			if err := repo.DeleteCalendarItem(userID, item.ID); err != nil {
+				return fmt.Errorf("删除日历项失败: %w", err)
+			}
+			deleted = append(deleted, item)
+		}
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, item := range created {
+		s.emit(EventCalendarItemCreated, userID, item)
+	}
+	for _, item := range updated {
+		s.emit(EventCalendarItemUpdated, userID, item)
+	}
+	for _, item := range deleted {
+		s.emit(EventCalendarItemDeleted, userID, item)
+	}
+	return len(existing) == 0, nil
+}
+
+// findCalendarItem 在同一 UID 的日历项中查找 RECURRENCE-ID 相同的日历项（nil 表示主日历项）
+func findCalendarItem(items []*CalendarItem, recurrenceID *time.Time) *CalendarItem {
+	for _, item := range items {
+		if recurrenceID == nil && item.RecurrenceID == nil {
+			return item
+		}
+		if recurrenceID != nil && item.RecurrenceID != nil && item.RecurrenceID.Equal(*recurrenceID) {
+			return item
+		}
+	}
+	return nil
+}
+
+// DeleteCalendarObject 删除资源名称为 name 的日历对象（主日历项及所有例外实例）
+func (s *service) DeleteCalendarObject(userID *uint, name string) error {
+	object, err := s.GetCalendarObject(userID, name)
+	if err != nil {
+		return err
+	}
+	// 主日历项和例外实例在同一个事务中删除，事件在提交后发出
+	err = s.repo.Transaction(func(repo Repository) error {
+		for _, item := range object.Items {
+			if err := repo.DeleteCalendarItem(userID, item.ID); err != nil {
+				return fmt.Errorf("删除日历项失败: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	for _, item := range object.Items {
+		s.emit(EventCalendarItemDeleted, userID, item)
+	}
+	return nil
+}
+
+// GetCalendarSyncToken 获取用户日历当前的同步令牌，日历有任何修改后令牌都会改变
+func (s *service) GetCalendarSyncToken(userID *uint) (string, error) {
+	latest, err := s.repo.GetLatestCalendarItemChange(userID)
+	if err != nil {
+		return "", fmt.Errorf("获取同步令牌失败: %w", err)
+	}
+	return formatSyncToken(latest), nil
+}
+
+// SyncCalendarObjects 返回同步令牌之后修改和删除的日历对象，令牌为空时返回所有日历对象
+func (s *service) SyncCalendarObjects(userID *uint, syncToken string) (*CalendarSyncResult, error) {
+	var since *time.Time
+	if syncToken != "" {
+		t, err := parseSyncToken(syncToken)
+		if err != nil {
+			return nil, err
+		}
+		since = &t
+	}
+
+	// 先取令牌再查询修改：查询期间发生的修改在下次同步时会再次返回，但不会丢失
+	token, err := s.GetCalendarSyncToken(userID)
+	if err != nil {
+		return nil, err
+	}
+	result := &CalendarSyncResult{Token: token}
+
+	if since == nil {
+		result.Changed, err = s.ListCalendarObjects(userID, nil)
+		if err != nil {
+			return nil, err
+		}
+		return result, nil
+	}
+
+	names, err := s.repo.ListChangedCalendarObjectNames(userID, *since)
+	if err != nil {
+		return nil, fmt.Errorf("获取修改的日历项失败: %w", err)
+	}
+	result.Changed, err = s.GetCalendarObjects(userID, names)
+	if err != nil {
+		return nil, err
+	}
+
+	alive := make(map[string]bool, len(result.Changed))
+	for _, object := range result.Changed {
+		alive[object.Name] = true
+	}
+	for _, name := range names {
+		if !alive[name] {
+			result.Deleted = append(result.Deleted, name)
+		}
+	}
+	sort.Strings(result.Deleted)
+	return result, nil
+}
+
+// formatSyncToken 将最后修改时间格式化为同步令牌
+func formatSyncToken(latest *time.Time) string {
+	var micros int64
+	if latest != nil {
+		micros = latest.UnixMicro()
+	}
+	return syncTokenPrefix + strconv.FormatInt(micros, 10)
+}
+
+// parseSyncToken 解析同步令牌中的最后修改时间
+func parseSyncToken(token string) (time.Time, error) {
+	value, ok := strings.CutPrefix(strings.TrimSpace(token), syncTokenPrefix)
+	if !ok {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidSyncToken, token)
+	}
+	micros, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || micros < 0 {
+		return time.Time{}, fmt.Errorf("%w: %q", ErrInvalidSyncToken, token)
+	}
+	return time.UnixMicro(micros).UTC(), nil
+}
+
+// groupCalendarObjects 将日历项按 UID 分组为日历对象，按 UID 排序
+func groupCalendarObjects(items []*CalendarItem) []*CalendarObject {
+	byUID := map[string]*CalendarObject{}
+	var objects []*CalendarObject
+	for _, item := range items {
+		object, ok := byUID[item.UID]
+		if !ok {
+			object = &CalendarObject{UID: item.UID, Name: calendarObjectName(item), Type: item.Type}
+			byUID[item.UID] = object
+			objects = append(objects, object)
+		}
+		object.Items = append(object.Items, item)
+	}
+
+	for _, object := range objects {
+		sort.SliceStable(object.Items, func(i, j int) bool {
+			a, b := object.Items[i].RecurrenceID, object.Items[j].RecurrenceID
+			if a == nil || b == nil {
+				return a == nil && b != nil
+			}
+			return a.Before(*b)
+		})
+		object.ETag, object.LastModified = calendarObjectETag(object.Items)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].UID < objects[j].UID })
+	return objects
+}
+
+// calendarObjectETag 根据各日历项的 SEQUENCE 和 LAST-MODIFIED 计算 ETag（没有 LAST-MODIFIED 时使用更新时间）
+// 同时返回日历对象的最后修改时间
+func calendarObjectETag(items []*CalendarItem) (string, time.Time) {
+	h := fnv.New64a()
+	var lastModified time.Time
+	for _, item := range items {
+		modified := item.UpdatedAt
+		if item.LastModified != nil {
+			modified = *item.LastModified
+		}
+		if modified.After(lastModified) {
+			lastModified = modified
+		}
+		sequence := 0
+		if item.Sequence != nil {
+			sequence = *item.Sequence
+		}
+		fmt.Fprintf(h, "%d:%d:%d;", item.ID, sequence, modified.UnixMicro())
+	}
+	return fmt.Sprintf(`"%x"`, h.Sum64()), lastModified
+}
@@ -0,0 +1,248 @@
+package calendar
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGroupCalendarObjects 测试按 UID 分组：主日历项在前，ETag 随 SEQUENCE 变化
+func TestGroupCalendarObjects(t *testing.T) {
+	rid := utcTime(2025, 1, 13, 9, 0)
+	seq := 1
+	items := []*CalendarItem{
+		{ID: 2, UID: "b", Type: CalendarItemTypeEvent, RecurrenceID: &rid},
+		{ID: 3, UID: "a", Type: CalendarItemTypeTodo},
+		{ID: 1, UID: "b", Type: CalendarItemTypeEvent, Sequence: &seq},
+	}
+
+	objects := groupCalendarObjects(items)
+
+	require.Len(t, objects, 2)
+	assert.Equal(t, "a", objects[0].UID)
+	assert.Equal(t, "b", objects[1].UID)
+	assert.Equal(t, uint(1), objects[1].Master().ID)
+	assert.Equal(t, uint(2), objects[1].Items[1].ID)
+
+	etag := objects[1].ETag
+	seq = 2
+	assert.NotEqual(t, etag, groupCalendarObjects(items)[1].ETag)
+}
+
+// TestService_PutCalendarObject_Create 测试 PUT 新建日历对象（主日历项和例外实例）
+func TestService_PutCalendarObject_Create(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:weekly\r\nDTSTART:20250106T090000Z\r\nDURATION:PT1H\r\nRRULE:FREQ=WEEKLY\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:weekly\r\nRECURRENCE-ID:20250113T090000Z\r\nDTSTART:20250113T100000Z\r\nDURATION:PT1H\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	mockRepo.On("GetCalendarObjectUIDs", &userID, []string{"weekly.ics"}).Return([]string{}, nil)
+	mockRepo.On("ListCalendarItemsByUIDs", &userID, []string{"weekly"}).Return([]*CalendarItem{}, nil)
+	mockRepo.On("CreateCalendarItem", mock.MatchedBy(func(item *CalendarItem) bool {
+		return item.UID == "weekly" && *item.UserID == userID && item.LastModified != nil && item.ResourceName == nil
+	})).Return(nil).Twice()
+
+	created, err := service.PutCalendarObject(&userID, "weekly.ics", strings.NewReader(data))
+
+	require.NoError(t, err)
+	assert.True(t, created)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_PutCalendarObject_Replace 测试 PUT 替换日历对象：更新主日历项，删除数据中没有的例外实例
+func TestService_PutCalendarObject_Replace(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	rid := utcTime(2025, 1, 13, 9, 0)
+	existing := []*CalendarItem{
+		{ID: 1, UID: "weekly", Type: CalendarItemTypeEvent},
+		{ID: 2, UID: "weekly", Type: CalendarItemTypeEvent, RecurrenceID: &rid},
+	}
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:weekly\r\nDTSTART:20250106T090000Z\r\nDURATION:PT1H\r\nRRULE:FREQ=WEEKLY\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	mockRepo.On("GetCalendarObjectUIDs", &userID, []string{"weekly.ics"}).Return([]string{"weekly"}, nil)
+	mockRepo.On("ListCalendarItemsByUIDs", &userID, []string{"weekly"}).Return(existing, nil)
+	mockRepo.On("UpdateCalendarItem", &userID, mock.MatchedBy(func(item *CalendarItem) bool {
+		return item.ID == 1 && *item.RRule == "FREQ=WEEKLY"
+	})).Return(nil)
+	mockRepo.On("DeleteValarmsByCalendarItemID", uint(1)).Return(nil)
+	mockRepo.On("DeleteAttendeesByCalendarItemID", uint(1)).Return(nil)
+	mockRepo.On("DeleteCalendarItem", &userID, uint(2)).Return(nil)
+
+	created, err := service.PutCalendarObject(&userID, "weekly.ics", strings.NewReader(data))
+
+	require.NoError(t, err)
+	assert.False(t, created)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_PutCalendarObject_FailureEmitsNoEvents 测试保存失败时事务回滚，不发出任何事件
+func TestService_PutCalendarObject_FailureEmitsNoEvents(t *testing.T) {
+	mockRepo := new(mockRepository)
+	var events []EventType
+	service := NewService(mockRepo, WithEventListener(EventListenerFunc(func(event *Event) {
+		events = append(events, event.Type)
+	})))
+
+	userID := uint(1)
+	existing := []*CalendarItem{{ID: 1, UID: "weekly", Type: CalendarItemTypeEvent}}
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:weekly\r\nDTSTART:20250106T090000Z\r\nDURATION:PT1H\r\nRRULE:FREQ=WEEKLY\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:weekly\r\nRECURRENCE-ID:20250113T090000Z\r\nDTSTART:20250113T100000Z\r\nDURATION:PT1H\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	mockRepo.On("GetCalendarObjectUIDs", &userID, []string{"weekly.ics"}).Return([]string{"weekly"}, nil)
+	mockRepo.On("ListCalendarItemsByUIDs", &userID, []string{"weekly"}).Return(existing, nil)
+	mockRepo.On("UpdateCalendarItem", &userID, mock.Anything).Return(nil)
+	mockRepo.On("DeleteValarmsByCalendarItemID", uint(1)).Return(nil)
+	mockRepo.On("DeleteAttendeesByCalendarItemID", uint(1)).Return(nil)
+	mockRepo.On("CreateCalendarItem", mock.Anything).Return(errors.New("db error"))
+
+	_, err := service.PutCalendarObject(&userID, "weekly.ics", strings.NewReader(data))
+
+	require.Error(t, err)
+	assert.Empty(t, events)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_PutCalendarObject_ClientResourceName 测试客户端指定的资源名称与 UID 不同时保存资源名称
+func TestService_PutCalendarObject_ClientResourceName(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	data := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:040000008200E001\r\nDTSTART:20250106T090000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	mockRepo.On("GetCalendarObjectUIDs", &userID, []string{"3f9c2b1e.ics"}).Return([]string{}, nil)
+	mockRepo.On("ListCalendarItemsByUIDs", &userID, []string{"040000008200E001"}).Return([]*CalendarItem{}, nil)
+	mockRepo.On("CreateCalendarItem", mock.MatchedBy(func(item *CalendarItem) bool {
+		return item.UID == "040000008200E001" && item.ResourceName != nil && *item.ResourceName == "3f9c2b1e.ics"
+	})).Return(nil)
+
+	created, err := service.PutCalendarObject(&userID, "3f9c2b1e.ics", strings.NewReader(data))
+
+	require.NoError(t, err)
+	assert.True(t, created)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_PutCalendarObject_UIDConflict 测试 UID 冲突：UID 已保存在其他资源中，或修改已有资源的 UID
+func TestService_PutCalendarObject_UIDConflict(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	data := "BEGIN:VCALENDAR\r\nBEGIN:VEVENT\r\nUID:weekly\r\nDTSTART:20250106T090000Z\r\nEND:VEVENT\r\nEND:VCALENDAR\r\n"
+
+	mockRepo.On("GetCalendarObjectUIDs", &userID, []string{"copy.ics"}).Return([]string{}, nil)
+	mockRepo.On("ListCalendarItemsByUIDs", &userID, []string{"weekly"}).
+		Return([]*CalendarItem{{ID: 1, UID: "weekly", Type: CalendarItemTypeEvent}}, nil)
+	mockRepo.On("GetCalendarObjectUIDs", &userID, []string{"daily.ics"}).Return([]string{"daily"}, nil)
+
+	_, err := service.PutCalendarObject(&userID, "copy.ics", strings.NewReader(data))
+	assert.ErrorIs(t, err, ErrUIDConflict)
+
+	_, err = service.PutCalendarObject(&userID, "daily.ics", strings.NewReader(data))
+	assert.ErrorIs(t, err, ErrUIDConflict)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_PutCalendarObject_MixedUIDs 测试日历对象中的组件 UID 不一致
+func TestService_PutCalendarObject_MixedUIDs(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	data := "BEGIN:VCALENDAR\r\n" +
+		"BEGIN:VEVENT\r\nUID:a\r\nDTSTART:20250106T090000Z\r\nEND:VEVENT\r\n" +
+		"BEGIN:VEVENT\r\nUID:b\r\nDTSTART:20250106T090000Z\r\nEND:VEVENT\r\n" +
+		"END:VCALENDAR\r\n"
+
+	_, err := service.PutCalendarObject(&userID, "a.ics", strings.NewReader(data))
+
+	assert.ErrorIs(t, err, ErrInvalidICalendar)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_SyncCalendarObjects 测试增量同步：修改的对象和已删除的 UID
+func TestService_SyncCalendarObjects(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	since := time.UnixMicro(1736154000000000).UTC()
+	latest := since.Add(time.Hour)
+
+	mockRepo.On("GetLatestCalendarItemChange", &userID).Return(&latest, nil)
+	name := "3f9c2b1e.ics"
+	mockRepo.On("ListChangedCalendarObjectNames", &userID, since).Return([]string{"changed.ics", name, "gone.ics"}, nil)
+	mockRepo.On("GetCalendarObjectUIDs", &userID, []string{"changed.ics", name, "gone.ics"}).Return([]string{"changed", "client"}, nil)
+	mockRepo.On("ListCalendarItemsByUIDs", &userID, []string{"changed", "client"}).Return([]*CalendarItem{
+		{ID: 1, UID: "changed", Type: CalendarItemTypeEvent},
+		{ID: 2, UID: "client", Type: CalendarItemTypeEvent, ResourceName: &name},
+	}, nil)
+
+	result, err := service.SyncCalendarObjects(&userID, formatSyncToken(&since))
+
+	require.NoError(t, err)
+	assert.Equal(t, formatSyncToken(&latest), result.Token)
+	require.Len(t, result.Changed, 2)
+	assert.Equal(t, "changed.ics", result.Changed[0].Name)
+	assert.Equal(t, name, result.Changed[1].Name)
+	assert.Equal(t, []string{"gone.ics"}, result.Deleted)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_SyncCalendarObjects_InvalidToken 测试无效的同步令牌
+func TestService_SyncCalendarObjects_InvalidToken(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	for _, token := range []string{"garbage", syncTokenPrefix + "abc", syncTokenPrefix + "-1"} {
+		_, err := service.SyncCalendarObjects(&userID, token)
+		assert.ErrorIs(t, err, ErrInvalidSyncToken, token)
+	}
+
+	mockRepo.On("GetLatestCalendarItemChange", &userID).Return(nil, errors.New("数据库错误"))
+	_, err := service.SyncCalendarObjects(&userID, "")
+	assert.Error(t, err)
+}
+
+// TestService_ListCalendarObjects_TimeRange 测试按时间范围过滤：只有范围外实例的重复日历项被排除
+func TestService_ListCalendarObjects_TimeRange(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	start := utcTime(2025, 3, 1, 0, 0)
+	end := utcTime(2025, 3, 31, 0, 0)
+	ended := "FREQ=DAILY;COUNT=2"
+	daily := "FREQ=DAILY"
+	items := []*CalendarItem{
+		{ID: 1, UID: "ended", Type: CalendarItemTypeEvent, DtStart: utcTime(2025, 1, 6, 9, 0), RRule: &ended},
+		{ID: 2, UID: "daily", Type: CalendarItemTypeEvent, DtStart: utcTime(2025, 1, 6, 9, 0), RRule: &daily},
+		{ID: 3, UID: "single", Type: CalendarItemTypeEvent, DtStart: utcTime(2025, 3, 5, 9, 0)},
+	}
+	mockRepo.On("ListCalendarItemsInRange", &userID, start, end, (*CalendarItemType)(nil)).Return(items, nil)
+	mockRepo.On("ListCalendarItemsByUIDs", &userID, []string{"daily", "single"}).Return(items[1:], nil)
+
+	objects, err := service.ListCalendarObjects(&userID, &CalendarObjectFilter{Start: &start, End: &end})
+
+	require.NoError(t, err)
+	require.Len(t, objects, 2)
+	mockRepo.AssertExpectations(t)
+}
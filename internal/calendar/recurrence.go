@@ -0,0 +1,673 @@
+package calendar
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var ErrInvalidRRule = errors.New("无效的重复规则")
+
+// maxRecurrencePeriods 展开重复规则时最多迭代的周期数，防止无法匹配的规则造成死循环
+const maxRecurrencePeriods = 100000
+
+// Frequency 重复频率（RRULE 中的 FREQ）
+type Frequency string
+
+const (
+	FrequencySecondly Frequency = "SECONDLY"
+	FrequencyMinutely Frequency = "MINUTELY"
+	FrequencyHourly   Frequency = "HOURLY"
+	FrequencyDaily    Frequency = "DAILY"
+	FrequencyWeekly   Frequency = "WEEKLY"
+	FrequencyMonthly  Frequency = "MONTHLY"
+	FrequencyYearly   Frequency = "YEARLY"
+)
+
+// WeekdayNum BYDAY 中的一项，例如 MO、2TU、-1FR
+type WeekdayNum struct {
+	N       int // 第 N 个，0 表示周期内所有该星期几，负数表示倒数第 N 个
+	Weekday time.Weekday
+}
+
+// RRule 重复规则（RFC 5545 RRULE）
+// 支持 FREQ、INTERVAL、COUNT、UNTIL、BYDAY、BYMONTHDAY、BYMONTH、BYSETPOS、WKST
+type RRule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      *time.Time
+	UntilDate  bool // UNTIL 只有日期：包含该日（按 DTSTART 的时区）的全部实例
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+	ByMonth    []int
+	BySetPos   []int
+	WKST       time.Weekday
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+var weekdayNames = [...]string{"SU", "MO", "TU", "WE", "TH", "FR", "SA"}
+
+// ParseRRule 解析 RRULE 字符串，例如 "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"
+// 允许带 "RRULE:" 前缀
+func ParseRRule(s string) (*RRule, error) {
+	s = strings.TrimSpace(s)
+	if len(s) >= 6 && strings.EqualFold(s[:6], "RRULE:") {
+		s = s[6:]
+	}
+	if s == "" {
+		return nil, fmt.Errorf("%w: 规则为空", ErrInvalidRRule)
+	}
+
+	rule := &RRule{Interval: 1, WKST: time.Monday}
+	for _, part := range strings.Split(s, ";") {
+		if part == "" {
+			continue
+		}
+		key, value, ok := strings.Cut(part, "=")
+		if !ok {
+			return nil, fmt.Errorf("%w: %q 不是 KEY=VALUE 格式", ErrInvalidRRule, part)
+		}
+		key = strings.ToUpper(strings.TrimSpace(key))
+		value = strings.ToUpper(strings.TrimSpace(value))
+
+		var err error
+		switch key {
+		case "FREQ":
+			rule.Freq = Frequency(value)
+		case "INTERVAL":
+			rule.Interval, err = strconv.Atoi(value)
+			if err == nil && rule.Interval < 1 {
+				err = errors.New("必须大于0")
+			}
+		case "COUNT":
+			rule.Count, err = strconv.Atoi(value)
+			if err == nil && rule.Count < 1 {
+				err = errors.New("必须大于0")
+			}
+		case "UNTIL":
+			var until time.Time
+			until, rule.UntilDate, err = parseDateTimeValue(value, time.UTC)
+			rule.Until = &until
+		case "BYDAY":
+			rule.ByDay, err = parseWeekdayList(value)
+		case "BYMONTHDAY":
+			rule.ByMonthDay, err = parseIntList(value, -31, 31)
+		case "BYMONTH":
+			rule.ByMonth, err = parseIntList(value, 1, 12)
+		case "BYSETPOS":
+			rule.BySetPos, err = parseIntList(value, -366, 366)
+		case "WKST":
+			wd, ok := weekdayCodes[value]
+			if !ok {
+				err = errors.New("未知的星期")
+			}
+			rule.WKST = wd
+		default:
+			return nil, fmt.Errorf("%w: 不支持的规则部分 %s", ErrInvalidRRule, key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s=%s: %v", ErrInvalidRRule, key, value, err)
+		}
+	}
+
+	switch rule.Freq {
+	case FrequencySecondly, FrequencyMinutely, FrequencyHourly,
+		FrequencyDaily, FrequencyWeekly, FrequencyMonthly, FrequencyYearly:
+	case "":
+		return nil, fmt.Errorf("%w: 缺少 FREQ", ErrInvalidRRule)
+	default:
+		return nil, fmt.Errorf("%w: 不支持的 FREQ %s", ErrInvalidRRule, rule.Freq)
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("%w: COUNT 和 UNTIL 不能同时出现", ErrInvalidRRule)
+	}
+
+	return rule, nil
+}
+
+// parseWeekdayList 解析 BYDAY 列表
+func parseWeekdayList(value string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if len(item) < 2 {
+			return nil, fmt.Errorf("无效的星期 %q", item)
+		}
+		wd, ok := weekdayCodes[item[len(item)-2:]]
+		if !ok {
+			return nil, fmt.Errorf("无效的星期 %q", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -53 || n > 53 {
+				return nil, fmt.Errorf("无效的序号 %q", item)
+			}
+		}
+		days = append(days, WeekdayNum{N: n, Weekday: wd})
+	}
+	return days, nil
+}
+
+// parseIntList 解析逗号分隔的整数列表，0 和超出范围的值视为无效
+func parseIntList(value string, min, max int) ([]int, error) {
+	var nums []int
+	for _, item := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(item))
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("无效的数值 %q", item)
+		}
+		nums = append(nums, n)
+	}
+	return nums, nil
+}
+
+// String 将规则格式化为 RRULE 字符串（不含 "RRULE:" 前缀）
+func (r *RRule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if r.Until != nil && r.UntilDate {
+		parts = append(parts, "UNTIL="+r.Until.Format(icalDateLayout))
+	} else if r.Until != nil {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format(icalDateTimeUTCLayout))
+	}
+	if len(r.ByMonth) > 0 {
+		parts = append(parts, "BYMONTH="+joinInts(r.ByMonth))
+	}
+	if len(r.ByMonthDay) > 0 {
+		parts = append(parts, "BYMONTHDAY="+joinInts(r.ByMonthDay))
+	}
+	if len(r.ByDay) > 0 {
+		days := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			days[i] = weekdayNames[d.Weekday]
+			if d.N != 0 {
+				days[i] = strconv.Itoa(d.N) + days[i]
+			}
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if len(r.BySetPos) > 0 {
+		parts = append(parts, "BYSETPOS="+joinInts(r.BySetPos))
+	}
+	if r.WKST != time.Monday {
+		parts = append(parts, "WKST="+weekdayNames[r.WKST])
+	}
+	return strings.Join(parts, ";")
+}
+
+func joinInts(nums []int) string {
+	strs := make([]string, len(nums))
+	for i, n := range nums {
+		strs[i] = strconv.Itoa(n)
+	}
+	return strings.Join(strs, ",")
+}
+
+// Between 返回开始时间落在 [from, to] 内的所有实例
+// dtstart 总是作为第一个实例（计入 COUNT），即使它不满足规则
+func (r *RRule) Between(dtstart, from, to time.Time) []time.Time {
+	var result []time.Time
+	r.iterate(dtstart, func(t time.Time) bool {
+		if t.After(to) {
+			return false
+		}
+		if !t.Before(from) {
+			result = append(result, t)
+		}
+		return true
+	})
+	return result
+}
+
+// iterate 按时间顺序依次产生实例，直到 COUNT/UNTIL 耗尽或 fn 返回 false
+func (r *RRule) iterate(dtstart time.Time, fn func(time.Time) bool) {
+	interval := r.Interval
+	if interval < 1 {
+		interval = 1
+	}
+
+	until := r.Until
+	if until != nil && r.UntilDate {
+		end := startOfDay(*until, dtstart.Location()).AddDate(0, 0, 1).Add(-time.Nanosecond)
+		until = &end
+	}
+
+	emitted := 0
+	emit := func(t time.Time) bool {
+		if until != nil && t.After(*until) {
+			return false
+		}
+		if r.Count > 0 && emitted >= r.Count {
+			return false
+		}
+		emitted++
+		return fn(t)
+	}
+
+	if !emit(dtstart) {
+		return
+	}
+	for i := 0; i < maxRecurrencePeriods; i++ {
+		for _, t := range r.periodCandidates(dtstart, i*interval) {
+			if !t.After(dtstart) {
+				continue
+			}
+			if !emit(t) {
+				return
+			}
+		}
+	}
+}
+
+// periodCandidates 计算从 dtstart 所在周期起第 n 个周期内满足规则的时间（已排序）
+func (r *RRule) periodCandidates(dtstart time.Time, n int) []time.Time {
+	loc := dtstart.Location()
+	y, mo, d := dtstart.Date()
+	hh, mm, ss := dtstart.Clock()
+
+	var days []time.Time
+	switch r.Freq {
+	case FrequencyYearly:
+		days = r.yearlyDays(y+n, mo, d, loc)
+	case FrequencyMonthly:
+		first := time.Date(y, mo+time.Month(n), 1, 0, 0, 0, 0, loc)
+		days = r.monthlyDays(first.Year(), first.Month(), d, loc)
+	case FrequencyWeekly:
+		offset := (int(dtstart.Weekday()) - int(r.WKST) + 7) % 7
+		weekStart := time.Date(y, mo, d-offset+7*n, 0, 0, 0, 0, loc)
+		days = r.weeklyDays(weekStart, dtstart.Weekday())
+	case FrequencyDaily:
+		day := time.Date(y, mo, d+n, 0, 0, 0, 0, loc)
+		if r.matchesFilters(day) {
+			days = []time.Time{day}
+		}
+	case FrequencyHourly, FrequencyMinutely, FrequencySecondly:
+		unit := map[Frequency]time.Duration{
+			FrequencyHourly:   time.Hour,
+			FrequencyMinutely: time.Minute,
+			FrequencySecondly: time.Second,
+		}[r.Freq]
+		t := dtstart.Add(time.Duration(n) * unit)
+		if r.matchesFilters(t) {
+			return []time.Time{t}
+		}
+		return nil
+	}
+
+	times := make([]time.Time, 0, len(days))
+	for _, day := range days {
+		times = append(times, time.Date(day.Year(), day.Month(), day.Day(), hh, mm, ss, dtstart.Nanosecond(), loc))
+	}
+	sortTimes(times)
+	return r.applySetPos(times)
+}
+
+// yearlyDays FREQ=YEARLY 时一年内的候选日期
+func (r *RRule) yearlyDays(year int, dtMonth time.Month, dtDay int, loc *time.Location) []time.Time {
+	months := r.ByMonth
+	if len(months) == 0 {
+		if len(r.ByDay) > 0 && len(r.ByMonthDay) == 0 {
+			// 只有 BYDAY 时在全年范围内展开，序号相对于整年
+			first := time.Date(year, time.January, 1, 0, 0, 0, 0, loc)
+			last := time.Date(year, time.December, 31, 0, 0, 0, 0, loc)
+			return r.expandByDay(first, last)
+		}
+		months = []int{int(dtMonth)}
+	}
+
+	var days []time.Time
+	for _, m := range months {
+		month := time.Month(m)
+		switch {
+		case len(r.ByMonthDay) > 0:
+			days = append(days, r.monthDays(year, month, loc)...)
+		case len(r.ByDay) > 0:
+			first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+			days = append(days, r.expandByDay(first, first.AddDate(0, 1, -1))...)
+		default:
+			if day, ok := validDate(year, month, dtDay, loc); ok {
+				days = append(days, day)
+			}
+		}
+	}
+	return days
+}
+
+// monthlyDays FREQ=MONTHLY 时一个月内的候选日期
+func (r *RRule) monthlyDays(year int, month time.Month, dtDay int, loc *time.Location) []time.Time {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(month)) {
+		return nil
+	}
+	switch {
+	case len(r.ByMonthDay) > 0:
+		return r.monthDays(year, month, loc)
+	case len(r.ByDay) > 0:
+		first := time.Date(year, month, 1, 0, 0, 0, 0, loc)
+		return r.expandByDay(first, first.AddDate(0, 1, -1))
+	default:
+		if day, ok := validDate(year, month, dtDay, loc); ok {
+			return []time.Time{day}
+		}
+		return nil
+	}
+}
+
+// weeklyDays FREQ=WEEKLY 时一周内的候选日期
+func (r *RRule) weeklyDays(weekStart time.Time, dtWeekday time.Weekday) []time.Time {
+	var days []time.Time
+	for i := 0; i < 7; i++ {
+		day := weekStart.AddDate(0, 0, i)
+		if len(r.ByDay) > 0 {
+			if !r.matchesWeekday(day.Weekday()) {
+				continue
+			}
+		} else if day.Weekday() != dtWeekday {
+			continue
+		}
+		if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(day.Month())) {
+			continue
+		}
+		days = append(days, day)
+	}
+	return days
+}
+
+// monthDays 按 BYMONTHDAY 计算某月的日期（负数表示从月末倒数），并用 BYDAY 过滤
+func (r *RRule) monthDays(year int, month time.Month, loc *time.Location) []time.Time {
+	daysInMonth := time.Date(year, month+1, 0, 0, 0, 0, 0, loc).Day()
+	var days []time.Time
+	for _, md := range r.ByMonthDay {
+		day := md
+		if md < 0 {
+			day = daysInMonth + md + 1
+		}
+		if day < 1 || day > daysInMonth {
+			continue
+		}
+		t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+		if len(r.ByDay) > 0 && !r.matchesWeekday(t.Weekday()) {
+			continue
+		}
+		days = append(days, t)
+	}
+	return days
+}
+
+// expandByDay 在 [first, last] 范围内展开 BYDAY（带序号时取第 N 个/倒数第 N 个）
+func (r *RRule) expandByDay(first, last time.Time) []time.Time {
+	var days []time.Time
+	for _, wd := range r.ByDay {
+		var matches []time.Time
+		offset := (int(wd.Weekday) - int(first.Weekday()) + 7) % 7
+		for day := first.AddDate(0, 0, offset); !day.After(last); day = day.AddDate(0, 0, 7) {
+			matches = append(matches, day)
+		}
+		switch {
+		case wd.N == 0:
+			days = append(days, matches...)
+		case wd.N > 0 && wd.N <= len(matches):
+			days = append(days, matches[wd.N-1])
+		case wd.N < 0 && -wd.N <= len(matches):
+			days = append(days, matches[len(matches)+wd.N])
+		}
+	}
+	return days
+}
+
+// matchesFilters 检查 BYMONTH/BYMONTHDAY/BYDAY 限制条件（用于 DAILY 及更细粒度的频率）
+func (r *RRule) matchesFilters(t time.Time) bool {
+	if len(r.ByMonth) > 0 && !containsInt(r.ByMonth, int(t.Month())) {
+		return false
+	}
+	if len(r.ByMonthDay) > 0 {
+		daysInMonth := time.Date(t.Year(), t.Month()+1, 0, 0, 0, 0, 0, t.Location()).Day()
+		matched := false
+		for _, md := range r.ByMonthDay {
+			if md == t.Day() || (md < 0 && daysInMonth+md+1 == t.Day()) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	if len(r.ByDay) > 0 && !r.matchesWeekday(t.Weekday()) {
+		return false
+	}
+	return true
+}
+
+func (r *RRule) matchesWeekday(wd time.Weekday) bool {
+	for _, d := range r.ByDay {
+		if d.Weekday == wd {
+			return true
+		}
+	}
+	return false
+}
+
+// applySetPos 按 BYSETPOS 从周期候选集中挑选
+func (r *RRule) applySetPos(times []time.Time) []time.Time {
+	if len(r.BySetPos) == 0 || len(times) == 0 {
+		return times
+	}
+	var picked []time.Time
+	for _, pos := range r.BySetPos {
+		idx := pos - 1
+		if pos < 0 {
+			idx = len(times) + pos
+		}
+		if idx >= 0 && idx < len(times) {
+			picked = append(picked, times[idx])
+		}
+	}
+	sortTimes(picked)
+	return dedupeTimes(picked)
+}
+
+// validDate 构造日期，若日期不存在（如 2 月 30 日）则返回 false
+func validDate(year int, month time.Month, day int, loc *time.Location) (time.Time, bool) {
+	t := time.Date(year, month, day, 0, 0, 0, 0, loc)
+	return t, t.Day() == day
+}
+
+func containsInt(nums []int, n int) bool {
+	for _, v := range nums {
+		if v == n {
+			return true
+		}
+	}
+	return false
+}
+
+func sortTimes(times []time.Time) {
+	sort.Slice(times, func(i, j int) bool { return times[i].Before(times[j]) })
+}
+
+// dedupeTimes 去除已排序切片中的重复时间
+func dedupeTimes(times []time.Time) []time.Time {
+	if len(times) < 2 {
+		return times
+	}
+	out := times[:1]
+	for _, t := range times[1:] {
+		if !t.Equal(out[len(out)-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// IsRecurring 是否为重复日历项（带 RRULE 或 RDATE）
+func (item *CalendarItem) IsRecurring() bool {
+	return (item.RRule != nil && strings.TrimSpace(*item.RRule) != "") || len(item.RDate) > 0
+}
+
+// OccurrenceDuration 单个实例的持续时间：优先 DTEND，其次 DURATION，VTODO 使用 DUE
+func (item *CalendarItem) OccurrenceDuration() time.Duration {
+	if item.DtEnd != nil {
+		return item.DtEnd.Sub(item.DtStart)
+	}
+	if item.Duration != nil && *item.Duration != "" {
+		if d, err := ParseDuration(*item.Duration); err == nil && d > 0 {
+			return d
+		}
+	}
+	if item.Due != nil && !item.DtStart.IsZero() {
+		return item.Due.Sub(item.DtStart)
+	}
+	return 0
+}
+
+// OccurrenceStarts 计算开始时间落在 [from, to] 内的所有实例
+// 综合 RRULE、RDATE 并去除 EXDATE；非重复日历项只返回 DTSTART（如果在范围内）
+// 只有日期的 EXDATE、RDATE 按 DTSTART 的时区解释
+func (item *CalendarItem) OccurrenceStarts(from, to time.Time) ([]time.Time, error) {
+	// 按 DTSTART 时区的当地时间展开，夏令时切换前后实例的当地时间保持不变
+	loc := item.StartLocation()
+	dtstart := item.DtStart.In(loc)
+
+	var starts []time.Time
+	if item.RRule != nil && strings.TrimSpace(*item.RRule) != "" {
+		rule, err := ParseRRule(*item.RRule)
+		if err != nil {
+			return nil, err
+		}
+		starts = rule.Between(dtstart, from, to)
+	} else if !dtstart.Before(from) && !dtstart.After(to) {
+		starts = append(starts, dtstart)
+	}
+
+	for _, value := range item.RDate {
+		t, err := parseInstanceValue(value, dtstart)
+		if err != nil {
+			return nil, fmt.Errorf("无效的 RDATE %q: %w", value, err)
+		}
+		if !t.Before(from) && !t.After(to) {
+			starts = append(starts, t)
+		}
+	}
+
+	if len(item.ExDate) > 0 {
+		filtered := starts[:0]
+		for _, t := range starts {
+			excluded := false
+			for _, value := range item.ExDate {
+				ex, isDate, err := parseDateTimeValue(value, loc)
+				if err != nil {
+					return nil, fmt.Errorf("无效的 EXDATE %q: %w", value, err)
+				}
+				if (isDate && sameDate(t.In(loc), ex)) || (!isDate && t.Equal(ex)) {
+					excluded = true
+					break
+				}
+			}
+			if !excluded {
+				filtered = append(filtered, t)
+			}
+		}
+		starts = filtered
+	}
+
+	sortTimes(starts)
+	return dedupeTimes(starts), nil
+}
+
+// parseInstanceValue 解析 RDATE 的值，只有日期时使用 DTSTART 的时刻；PERIOD 值取开始时间
+func parseInstanceValue(value string, dtstart time.Time) (time.Time, error) {
+	if start, _, ok := strings.Cut(value, "/"); ok {
+		value = start
+	}
+	t, isDate, err := parseDateTimeValue(value, dtstart.Location())
+	if err != nil {
+		return time.Time{}, err
+	}
+	if isDate {
+		hh, mm, ss := dtstart.Clock()
+		t = time.Date(t.Year(), t.Month(), t.Day(), hh, mm, ss, 0, dtstart.Location())
+	}
+	return t, nil
+}
+
+func sameDate(a, b time.Time) bool {
+	ay, am, ad := a.Date()
+	by, bm, bd := b.Date()
+	return ay == by && am == bm && ad == bd
+}
+
+// ExpandCalendarItem 将日历项展开为与 [start, end] 有重叠的实例
+// 非重复日历项原样返回；重复日历项的每个实例都是主日历项的浅拷贝，
+// DtStart/DtEnd/Due 平移到实例时间，RecurrenceID 为实例的原始开始时间，Master 指向主日历项
+func ExpandCalendarItem(item *CalendarItem, start, end time.Time) ([]*CalendarItem, error) {
+	if !item.IsRecurring() {
+		return []*CalendarItem{item}, nil
+	}
+
+	duration := item.OccurrenceDuration()
+	starts, err := item.OccurrenceStarts(start.Add(-duration), end)
+	if err != nil {
+		return nil, err
+	}
+
+	occurrences := make([]*CalendarItem, 0, len(starts))
+	for _, s := range starts {
+		// 有持续时间的实例必须在窗口开始之后结束
+		if duration > 0 && !s.Add(duration).After(start) {
+			continue
+		}
+		// 全天实例恰好在窗口结束时开始，不算重叠
+		if item.AllDay && s.Equal(end) && end.After(start) {
+			continue
+		}
+		occurrences = append(occurrences, newOccurrence(item, s))
+	}
+	return occurrences, nil
+}
+
+// shiftTime 将主日历项的时间 t 平移到开始于 start 的实例
+// 全天日历项按天数平移，夏令时切换前后实例仍在当地零点结束
+func (item *CalendarItem) shiftTime(t, start time.Time) time.Time {
+	if item.AllDay {
+		return start.AddDate(0, 0, daysBetween(item.DtStart, t)).In(t.Location())
+	}
+	return start.Add(t.Sub(item.DtStart)).In(t.Location())
+}
+
+// newOccurrence 基于主日历项创建一个实例
+func newOccurrence(master *CalendarItem, start time.Time) *CalendarItem {
+	occ := *master
+	occ.DtStart = start
+	if master.DtEnd != nil {
+		dtEnd := master.shiftTime(*master.DtEnd, start)
+		occ.DtEnd = &dtEnd
+	}
+	if master.Due != nil {
+		due := master.shiftTime(*master.Due, start)
+		occ.Due = &due
+	}
+	recurrenceID := start
+	occ.RecurrenceID = &recurrenceID
+	occ.Master = master
+	return &occ
+}
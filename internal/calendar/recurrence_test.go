@@ -0,0 +1,277 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func utcTime(year int, month time.Month, day, hour, min int) time.Time {
+	return time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+}
+
+// TestParseRRule 测试解析重复规则
+func TestParseRRule(t *testing.T) {
+	rule, err := ParseRRule("RRULE:FREQ=MONTHLY;INTERVAL=2;BYDAY=-1FR,2MO;BYMONTH=1,6;COUNT=5")
+
+	require.NoError(t, err)
+	assert.Equal(t, FrequencyMonthly, rule.Freq)
+	assert.Equal(t, 2, rule.Interval)
+	assert.Equal(t, 5, rule.Count)
+	assert.Equal(t, []WeekdayNum{{N: -1, Weekday: time.Friday}, {N: 2, Weekday: time.Monday}}, rule.ByDay)
+	assert.Equal(t, []int{1, 6}, rule.ByMonth)
+	assert.Equal(t, time.Monday, rule.WKST)
+}
+
+// TestParseRRule_Invalid 测试解析无效的重复规则
+func TestParseRRule_Invalid(t *testing.T) {
+	cases := []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=SOMETIMES",
+		"FREQ=DAILY;COUNT=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20250101T000000Z",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;BYHOUR=9",
+		"FREQ",
+	}
+	for _, c := range cases {
+		_, err := ParseRRule(c)
+		assert.ErrorIs(t, err, ErrInvalidRRule, c)
+	}
+}
+
+// TestRRule_String 测试重复规则序列化后可以再次解析
+func TestRRule_String(t *testing.T) {
+	rule, err := ParseRRule("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE;UNTIL=20250301T000000Z")
+	require.NoError(t, err)
+
+	again, err := ParseRRule(rule.String())
+
+	require.NoError(t, err)
+	assert.Equal(t, rule, again)
+
+	dateRule, err := ParseRRule("FREQ=DAILY;UNTIL=20250301")
+	require.NoError(t, err)
+	assert.True(t, dateRule.UntilDate)
+	assert.Equal(t, "FREQ=DAILY;UNTIL=20250301", dateRule.String())
+}
+
+// TestRRule_Between 测试按规则展开实例
+func TestRRule_Between(t *testing.T) {
+	dtstart := utcTime(2025, 1, 6, 9, 0) // 周一
+	far := utcTime(2030, 1, 1, 0, 0)
+
+	cases := []struct {
+		name  string
+		rule  string
+		from  time.Time
+		to    time.Time
+		want  []time.Time
+		count int
+	}{
+		{
+			name: "daily count",
+			rule: "FREQ=DAILY;COUNT=3",
+			from: dtstart, to: far,
+			want: []time.Time{utcTime(2025, 1, 6, 9, 0), utcTime(2025, 1, 7, 9, 0), utcTime(2025, 1, 8, 9, 0)},
+		},
+		{
+			name: "weekly byday",
+			rule: "FREQ=WEEKLY;BYDAY=MO,WE;COUNT=4",
+			from: dtstart, to: far,
+			want: []time.Time{utcTime(2025, 1, 6, 9, 0), utcTime(2025, 1, 8, 9, 0), utcTime(2025, 1, 13, 9, 0), utcTime(2025, 1, 15, 9, 0)},
+		},
+		{
+			name: "biweekly until",
+			rule: "FREQ=WEEKLY;INTERVAL=2;UNTIL=20250203T090000Z",
+			from: dtstart, to: far,
+			want: []time.Time{utcTime(2025, 1, 6, 9, 0), utcTime(2025, 1, 20, 9, 0), utcTime(2025, 2, 3, 9, 0)},
+		},
+		{
+			name: "monthly last friday",
+			rule: "FREQ=MONTHLY;BYDAY=-1FR;COUNT=3",
+			from: dtstart, to: far,
+			want: []time.Time{utcTime(2025, 1, 6, 9, 0), utcTime(2025, 1, 31, 9, 0), utcTime(2025, 2, 28, 9, 0)},
+		},
+		{
+			name: "monthly 31st skips short months",
+			rule: "FREQ=MONTHLY;BYMONTHDAY=31;COUNT=3",
+			from: utcTime(2025, 1, 7, 0, 0), to: far,
+			want: []time.Time{utcTime(2025, 1, 31, 9, 0), utcTime(2025, 3, 31, 9, 0)},
+		},
+		{
+			name: "last weekday of month",
+			rule: "FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-1",
+			from: utcTime(2025, 2, 1, 0, 0), to: utcTime(2025, 4, 1, 0, 0),
+			want: []time.Time{utcTime(2025, 2, 28, 9, 0), utcTime(2025, 3, 31, 9, 0)},
+		},
+		{
+			name: "yearly",
+			rule: "FREQ=YEARLY",
+			from: utcTime(2026, 1, 1, 0, 0), to: utcTime(2027, 12, 31, 0, 0),
+			want: []time.Time{utcTime(2026, 1, 6, 9, 0), utcTime(2027, 1, 6, 9, 0)},
+		},
+		{
+			name: "window in the middle",
+			rule: "FREQ=DAILY",
+			from: utcTime(2025, 3, 1, 0, 0), to: utcTime(2025, 3, 2, 23, 0),
+			want: []time.Time{utcTime(2025, 3, 1, 9, 0), utcTime(2025, 3, 2, 9, 0)},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rule, err := ParseRRule(tc.rule)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.want, rule.Between(dtstart, tc.from, tc.to))
+		})
+	}
+}
+
+// TestCalendarItem_OccurrenceStarts 测试 RDATE 与 EXDATE
+func TestCalendarItem_OccurrenceStarts(t *testing.T) {
+	rrule := "FREQ=DAILY;COUNT=5"
+	item := &CalendarItem{
+		DtStart: utcTime(2025, 1, 6, 9, 0),
+		RRule:   &rrule,
+		ExDate:  StringArray{"20250107T090000Z", "2025-01-09"},
+		RDate:   StringArray{"20250120", "2025-01-21T15:00:00Z"},
+	}
+
+	starts, err := item.OccurrenceStarts(utcTime(2025, 1, 1, 0, 0), utcTime(2025, 2, 1, 0, 0))
+
+	require.NoError(t, err)
+	assert.Equal(t, []time.Time{
+		utcTime(2025, 1, 6, 9, 0),
+		utcTime(2025, 1, 8, 9, 0),
+		utcTime(2025, 1, 10, 9, 0),
+		utcTime(2025, 1, 20, 9, 0),
+		utcTime(2025, 1, 21, 15, 0),
+	}, starts)
+}
+
+// TestExpandCalendarItem 测试展开日历项
+func TestExpandCalendarItem(t *testing.T) {
+	rrule := "FREQ=DAILY"
+	dtEnd := utcTime(2025, 1, 6, 10, 0)
+	master := &CalendarItem{
+		ID:      7,
+		UID:     "daily",
+		DtStart: utcTime(2025, 1, 6, 9, 0),
+		DtEnd:   &dtEnd,
+		RRule:   &rrule,
+	}
+
+	// 窗口从 1 月 8 日 9:30 开始，8 日的实例仍在进行中
+	occurrences, err := ExpandCalendarItem(master, utcTime(2025, 1, 8, 9, 30), utcTime(2025, 1, 9, 12, 0))
+
+	require.NoError(t, err)
+	require.Len(t, occurrences, 2)
+	assert.Equal(t, utcTime(2025, 1, 8, 9, 0), occurrences[0].DtStart)
+	assert.Equal(t, utcTime(2025, 1, 8, 10, 0), *occurrences[0].DtEnd)
+	assert.Equal(t, utcTime(2025, 1, 8, 9, 0), *occurrences[0].RecurrenceID)
+	assert.Equal(t, uint(7), occurrences[0].ID)
+	assert.Same(t, master, occurrences[0].Master)
+	assert.Equal(t, utcTime(2025, 1, 9, 9, 0), occurrences[1].DtStart)
+	assert.Equal(t, utcTime(2025, 1, 6, 10, 0), *master.DtEnd)
+}
+
+// TestExpandCalendarItem_TZID 测试按 DTSTART 的时区展开：夏令时切换后实例仍在当地时间 9:00，只有日期的 EXDATE 按当地日期匹配
+func TestExpandCalendarItem_TZID(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	tzid := "America/New_York"
+	rrule := "FREQ=WEEKLY;COUNT=4"
+	// 读取自数据库的时间为 UTC：2025-03-01 9:00 EST
+	dtEnd := utcTime(2025, 3, 1, 15, 0)
+	master := &CalendarItem{
+		DtStart:     utcTime(2025, 3, 1, 14, 0),
+		DtEnd:       &dtEnd,
+		DtStartTZID: &tzid,
+		DtEndTZID:   &tzid,
+		RRule:       &rrule,
+		ExDate:      StringArray{"20250315"},
+	}
+	master.localizeTimes()
+
+	occurrences, err := ExpandCalendarItem(master, utcTime(2025, 3, 1, 0, 0), utcTime(2025, 4, 1, 0, 0))
+
+	require.NoError(t, err)
+	require.Len(t, occurrences, 3)
+	assert.Equal(t, utcTime(2025, 3, 1, 14, 0), occurrences[0].DtStart.UTC())
+	assert.Equal(t, utcTime(2025, 3, 8, 14, 0), occurrences[1].DtStart.UTC())
+	// 3 月 9 日切换到 EDT（UTC-4）
+	assert.Equal(t, utcTime(2025, 3, 22, 13, 0), occurrences[2].DtStart.UTC())
+	assert.Equal(t, utcTime(2025, 3, 22, 14, 0), occurrences[2].DtEnd.UTC())
+	assert.Equal(t, newYork, occurrences[2].DtStart.Location())
+	assert.Equal(t, 9, occurrences[2].DtStart.Hour())
+}
+
+// TestExpandCalendarItem_AllDay 测试展开全天事件：夏令时切换后仍在当地零点开始和结束，
+// 只有日期的 UNTIL 包含当天，恰好在窗口结束时开始的实例不算重叠
+func TestExpandCalendarItem_AllDay(t *testing.T) {
+	tzid := "America/New_York"
+	rrule := "FREQ=WEEKLY;UNTIL=20250316"
+	// 读取自数据库的时间为 UTC：2025-03-02（周日）纽约当地零点
+	dtEnd := utcTime(2025, 3, 3, 5, 0)
+	master := &CalendarItem{
+		DtStart:     utcTime(2025, 3, 2, 5, 0),
+		DtEnd:       &dtEnd,
+		DtStartTZID: &tzid,
+		DtEndTZID:   &tzid,
+		RRule:       &rrule,
+		AllDay:      true,
+	}
+	master.localizeTimes()
+
+	occurrences, err := ExpandCalendarItem(master, utcTime(2025, 3, 1, 0, 0), utcTime(2025, 4, 1, 0, 0))
+
+	require.NoError(t, err)
+	require.Len(t, occurrences, 3)
+	// 3 月 9 日凌晨切换到 EDT（UTC-4），这一天只有 23 小时
+	assert.Equal(t, utcTime(2025, 3, 9, 5, 0), occurrences[1].DtStart.UTC())
+	assert.Equal(t, utcTime(2025, 3, 10, 4, 0), occurrences[1].DtEnd.UTC())
+	assert.Equal(t, utcTime(2025, 3, 16, 4, 0), occurrences[2].DtStart.UTC())
+	assert.Equal(t, 0, occurrences[2].DtEnd.Hour())
+
+	occurrences, err = ExpandCalendarItem(master, utcTime(2025, 3, 3, 5, 0), utcTime(2025, 3, 9, 5, 0))
+
+	require.NoError(t, err)
+	assert.Empty(t, occurrences)
+}
+
+// TestExpandCalendarItem_NotRecurring 测试展开非重复日历项
+func TestExpandCalendarItem_NotRecurring(t *testing.T) {
+	item := &CalendarItem{ID: 1, DtStart: utcTime(2025, 1, 6, 9, 0)}
+
+	occurrences, err := ExpandCalendarItem(item, utcTime(2025, 1, 1, 0, 0), utcTime(2025, 2, 1, 0, 0))
+
+	require.NoError(t, err)
+	assert.Equal(t, []*CalendarItem{item}, occurrences)
+	assert.Nil(t, occurrences[0].RecurrenceID)
+}
+
+// TestParseDuration 测试解析 DURATION
+func TestParseDuration(t *testing.T) {
+	cases := map[string]time.Duration{
+		"PT1H30M": 90 * time.Minute,
+		"P1D":     24 * time.Hour,
+		"P2W":     14 * 24 * time.Hour,
+		"-PT15M":  -15 * time.Minute,
+		"P1DT2S":  24*time.Hour + 2*time.Second,
+	}
+	for value, want := range cases {
+		got, err := ParseDuration(value)
+		assert.NoError(t, err, value)
+		assert.Equal(t, want, got, value)
+	}
+
+	for _, value := range []string{"", "P", "PT", "1H", "PT1D", "P1H", "PTH"} {
+		_, err := ParseDuration(value)
+		assert.Error(t, err, value)
+	}
+}
@@ -1,6 +1,7 @@
 package calendar
 
 import (
+	"database/sql"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -11,14 +12,36 @@ import (
 
 // Repository 日历项仓库接口
 type Repository interface {
+	// Transaction 在事务中执行 fn，fn 的参数是绑定到该事务的仓库
+	Transaction(fn func(repo Repository) error) error
+
 	// CalendarItem 相关方法
 	CreateCalendarItem(item *CalendarItem) error
 	GetCalendarItemByID(userID *uint, id uint) (*CalendarItem, error)
 	GetCalendarItemByUID(userID *uint, uid string) (*CalendarItem, error)
 	UpdateCalendarItem(userID *uint, item *CalendarItem) error
 	DeleteCalendarItem(userID *uint, id uint) error
-	ListCalendarItems(userID *uint, startTime, endTime *time.Time, itemType *CalendarItemType, offset, limit int) ([]*CalendarItem, int64, error)
-	SearchCalendarItems(userID *uint, q string, timeRanges map[string]TimeRange, limit int) ([]*CalendarItem, error)
+	ListCalendarItems(userID *uint, startTime, endTime *time.Time, itemType *CalendarItemType, page PageRequest) ([]*CalendarItem, int64, *PageCursor, error)
+	ListCalendarItemsInRange(userID *uint, startTime, endTime time.Time, itemType *CalendarItemType) ([]*CalendarItem, error)
+	ListDueTodos(userID *uint, startTime, endTime time.Time) ([]*CalendarItem, error)
+
+	// 重复日历项例外实例（RECURRENCE-ID）相关方法
+	GetCalendarItemOverride(userID *uint, uid string, recurrenceID time.Time) (*CalendarItem, error)
+	ListCalendarItemOverrides(userID *uint, uids []string) ([]*CalendarItem, error)
+	ReassignCalendarItemOverrides(userID *uint, uid, newUID string, from time.Time) error
+	DeleteCalendarItemOverrides(userID *uint, uid string) error
+	ListCalendarItemsByUIDs(userID *uint, uids []string) ([]*CalendarItem, error)
+	GetCalendarObjectUIDs(userID *uint, names []string) ([]string, error)
+	ListChangedCalendarObjectNames(userID *uint, since time.Time) ([]string, error)
+	GetLatestCalendarItemChange(userID *uint) (*time.Time, error)
+
+	// 订阅令牌相关方法
+	CreateFeedToken(token *CalendarFeedToken) error
+	GetFeedToken(token string) (*CalendarFeedToken, error)
+	ListFeedTokens(userID uint) ([]*CalendarFeedToken, error)
+	RevokeFeedToken(userID uint, id uint) error
+	TouchFeedToken(id uint, accessedAt time.Time) error
+	SearchCalendarItems(userID *uint, q string, timeRanges map[string]TimeRange, page PageRequest) ([]*CalendarItem, *PageCursor, error)
 
 	// Valarm 相关方法
 	CreateValarm(alarm *Valarm) error
@@ -27,6 +50,13 @@ type Repository interface {
 	UpdateValarm(alarm *Valarm) error
 	DeleteValarm(id uint) error
 	DeleteValarmsByCalendarItemID(calendarItemID uint) error
+
+	// Attendee 相关方法
+	CreateAttendee(attendee *Attendee) error
+	GetAttendeeByID(id uint) (*Attendee, error)
+	GetAttendeesByCalendarItemID(calendarItemID uint) ([]*Attendee, error)
+	UpdateAttendee(attendee *Attendee) error
+	DeleteAttendeesByCalendarItemID(calendarItemID uint) error
 }
 
 type repository struct {
@@ -38,6 +68,13 @@ func NewRepository(db *gorm.DB) Repository {
 	return &repository{db: db}
 }
 
+// Transaction 在事务中执行 fn，fn 的参数是绑定到该事务的仓库
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
 // CreateCalendarItem 创建日历项
 func (r *repository) CreateCalendarItem(item *CalendarItem) error {
 	return r.db.Create(item).Error
@@ -46,7 +83,7 @@ func (r *repository) CreateCalendarItem(item *CalendarItem) error {
 // GetCalendarItemByID 根据ID获取日历项（带用户ID过滤）
 func (r *repository) GetCalendarItemByID(userID *uint, id uint) (*CalendarItem, error) {
 	var item CalendarItem
-	query := r.db.Preload("Alarms").Where("id = ?", id)
+	query := r.db.Preload("Alarms").Preload("Attendees").Where("id = ?", id)
 
 	// 过滤用户ID
 	if userID != nil {
@@ -60,9 +97,10 @@ func (r *repository) GetCalendarItemByID(userID *uint, id uint) (*CalendarItem,
 }
 
 // GetCalendarItemByUID 根据UID获取日历项（带用户ID过滤）
+// 只返回主日历项，不包括共用 UID 的例外实例
 func (r *repository) GetCalendarItemByUID(userID *uint, uid string) (*CalendarItem, error) {
 	var item CalendarItem
-	query := r.db.Preload("Alarms").Where("uid = ?", uid)
+	query := r.db.Preload("Alarms").Preload("Attendees").Where("uid = ? AND recurrence_id IS NULL", uid)
 
 	// 过滤用户ID
 	if userID != nil {
@@ -93,6 +131,10 @@ func (r *repository) UpdateCalendarItem(userID *uint, item *CalendarItem) error
 		"dt_start":         item.DtStart,
 		"dt_end":           item.DtEnd,
 		"due":              item.Due,
+		"dt_start_tzid":    item.DtStartTZID,
+		"dt_end_tzid":      item.DtEndTZID,
+		"due_tzid":         item.DueTZID,
+		"all_day":          item.AllDay,
 		"completed":        item.Completed,
 		"duration":         item.Duration,
 		"status":           item.Status,
@@ -108,6 +150,7 @@ func (r *repository) UpdateCalendarItem(userID *uint, item *CalendarItem) error
 		"resources":        item.Resources,
 		"url":              item.URL,
 		"class":            item.Class,
+		"transp":           item.Transp,
 		"raw_ical":         item.RawIcal,
 		"sequence":         item.Sequence,
 		"last_modified":    item.LastModified,
@@ -147,8 +190,8 @@ func (r *repository) DeleteCalendarItem(userID *uint, id uint) error {
 	return nil
 }
 
-// ListCalendarItems 列出日历项
-func (r *repository) ListCalendarItems(userID *uint, startTime, endTime *time.Time, itemType *CalendarItemType, offset, limit int) ([]*CalendarItem, int64, error) {
+// ListCalendarItems 列出日历项，total 为不分页时的总数，还有下一页时返回下一页的游标
+func (r *repository) ListCalendarItems(userID *uint, startTime, endTime *time.Time, itemType *CalendarItemType, page PageRequest) ([]*CalendarItem, int64, *PageCursor, error) {
 	var items []*CalendarItem
 	var total int64
 
@@ -167,8 +210,8 @@ func (r *repository) ListCalendarItems(userID *uint, startTime, endTime *time.Ti
 	// 时间范围过滤
 	if startTime != nil && endTime != nil {
 		// 查找在时间范围内有重叠的日历项
-		// dt_start <= endTime AND (dt_end >= startTime OR dt_end IS NULL)
-		query = query.Where("dt_start <= ? AND (dt_end >= ? OR dt_end IS NULL)", *endTime, *startTime)
+		// dt_start <= endTime AND (dt_end >= startTime OR dt_end IS NULL)，全天日历项按整天计算
+		query = query.Where(startsBeforeCondition+" AND ("+endsAfterCondition+")", *endTime, *endTime, *startTime, *startTime)
 	} else if startTime != nil {
 		query = query.Where("dt_start >= ?", *startTime)
 	} else if endTime != nil {
@@ -177,53 +220,264 @@ func (r *repository) ListCalendarItems(userID *uint, startTime, endTime *time.Ti
 
 	// 统计总数
 	if err := query.Count(&total).Error; err != nil {
-		return nil, 0, err
+		return nil, 0, nil, err
 	}
 
 	// 查询列表
-	if err := query.Preload("Alarms").Offset(offset).Limit(limit).Order("dt_start ASC").Find(&items).Error; err != nil {
-		return nil, 0, err
+	query = applyPage(query, newSortExpression(page.Sort, ""), page)
+	if err := query.Preload("Alarms").Preload("Attendees").Find(&items).Error; err != nil {
+		return nil, 0, nil, err
 	}
 
-	return items, total, nil
+	items, cursor, err := nextPageCursor(items, page, nil)
+	if err != nil {
+		return nil, 0, nil, err
+	}
+	return items, total, cursor, nil
 }
 
-// escapeSQLString 转义 SQL 字符串中的单引号，防止 SQL 注入
-func escapeSQLString(s string) string {
-	return strings.ReplaceAll(s, "'", "''")
+// 与时间窗口重叠的过滤条件，参数依次为窗口结束、窗口结束、窗口开始、窗口开始
+// 全天日历项的 DTEND 不包含在内：恰好在窗口结束时开始或在窗口开始时结束的全天日历项不算重叠
+const (
+	startsBeforeCondition = "(dt_start < ? OR (dt_start = ? AND NOT all_day))"
+	endsAfterCondition    = "dt_end > ? OR (dt_end = ? AND NOT all_day) OR dt_end IS NULL"
+)
+
+// recurringCondition 重复日历项（带 RRULE 或 RDATE）的过滤条件
+const recurringCondition = "((r_rule IS NOT NULL AND r_rule <> '') OR r_date <> '[]'::jsonb)"
+
+// ListCalendarItemsInRange 列出时间窗口内可能出现的所有日历项（不分页）
+// 包括与窗口重叠的普通日历项，以及开始于窗口结束之前的重复日历项（由 Service 层展开）
+func (r *repository) ListCalendarItemsInRange(userID *uint, startTime, endTime time.Time, itemType *CalendarItemType) ([]*CalendarItem, error) {
+	var items []*CalendarItem
+
+	query := r.db.Model(&CalendarItem{})
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	// 过滤类型
+	if itemType != nil {
+		query = query.Where("type = ?", *itemType)
+	}
+
+	query = query.Where(startsBeforeCondition+" AND ("+endsAfterCondition+" OR "+recurringCondition+")", endTime, endTime, startTime, startTime)
+
+	if err := query.Preload("Alarms").Preload("Attendees").Order("dt_start ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
 }
 
-// fieldColumnMap 字段名到 SQL 列名的映射
-// 对于 JSONB 字段，列名包含 ::text 转换
-var fieldColumnMap = map[string]string{
-	"summary":     "summary",
-	"description": "description",
-	"location":    "location",
-	"organizer":   "organizer",
-	"comment":     "comment",
-	"contact":     "contact",
-	"categories":  "categories::text",
-	"resources":   "resources::text",
+// ListDueTodos 列出在 [startTime, endTime) 内到期且未完成的待办，按截止时间排序
+func (r *repository) ListDueTodos(userID *uint, startTime, endTime time.Time) ([]*CalendarItem, error) {
+	var items []*CalendarItem
+
+	query := r.db.Model(&CalendarItem{}).Where("type = ?", CalendarItemTypeTodo)
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	query = query.Where("due >= ? AND due < ?", startTime, endTime).
+		Where("completed IS NULL AND (status IS NULL OR status NOT IN ?)", []string{"COMPLETED", "CANCELLED"})
+
+	if err := query.Order("due ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+
+	return items, nil
 }
 
-// getFieldColumn 获取字段对应的 SQL 列名
-func getFieldColumn(field string) (string, bool) {
-	column, ok := fieldColumnMap[field]
-	return column, ok
+// GetCalendarItemOverride 获取重复日历项某次实例的例外（带用户ID过滤）
+func (r *repository) GetCalendarItemOverride(userID *uint, uid string, recurrenceID time.Time) (*CalendarItem, error) {
+	var item CalendarItem
+	query := r.db.Preload("Alarms").Preload("Attendees").Where("uid = ? AND recurrence_id = ?", uid, recurrenceID)
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	if err := query.First(&item).Error; err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// ListCalendarItemOverrides 列出多个重复日历项的所有例外实例（带用户ID过滤）
+func (r *repository) ListCalendarItemOverrides(userID *uint, uids []string) ([]*CalendarItem, error) {
+	var items []*CalendarItem
+	if len(uids) == 0 {
+		return items, nil
+	}
+
+	query := r.db.Where("uid IN ? AND recurrence_id IS NOT NULL", uids)
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	if err := query.Order("recurrence_id ASC").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// ReassignCalendarItemOverrides 将 RECURRENCE-ID 不早于 from 的例外实例转移到新的 UID，并使用新日历对象默认的资源名称
+// 用于拆分重复日历项（修改“此实例及之后”）
+func (r *repository) ReassignCalendarItemOverrides(userID *uint, uid, newUID string, from time.Time) error {
+	query := r.db.Model(&CalendarItem{}).Where("uid = ? AND recurrence_id >= ?", uid, from)
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	return query.Updates(map[string]interface{}{"uid": newUID, "resource_name": nil}).Error
 }
 
-// getFieldMatchScoreSQL 获取字段匹配分数的 SQL 表达式
-func getFieldMatchScoreSQL(field string, keywordExact, keywordPrefix string) string {
-	column, ok := getFieldColumn(field)
-	if !ok {
-		return "1"
+// DeleteCalendarItemOverrides 删除重复日历项的所有例外实例（软删除，带用户ID过滤）
+func (r *repository) DeleteCalendarItemOverrides(userID *uint, uid string) error {
+	query := r.db.Where("uid = ? AND recurrence_id IS NOT NULL", uid)
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
 	}
 
-	return fmt.Sprintf(`CASE 
-		WHEN %s ILIKE '%s' THEN 3
-		WHEN %s ILIKE '%s' THEN 2
-		ELSE 1
-	END`, column, keywordExact, column, keywordPrefix)
+	return query.Delete(&CalendarItem{}).Error
+}
+
+// ListCalendarItemsByUIDs 列出多个 UID 的主日历项和例外实例（带用户ID过滤）
+func (r *repository) ListCalendarItemsByUIDs(userID *uint, uids []string) ([]*CalendarItem, error) {
+	var items []*CalendarItem
+	if len(uids) == 0 {
+		return items, nil
+	}
+
+	query := r.db.Preload("Alarms").Preload("Attendees").Where("uid IN ?", uids)
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	if err := query.Order("uid ASC, recurrence_id ASC NULLS FIRST").Find(&items).Error; err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+// GetCalendarObjectUIDs 根据 CalDAV 资源名称查找日历对象的 UID（带用户ID过滤），不存在的资源名称被忽略
+// 没有保存资源名称的日历项使用默认名称 {UID}.ics
+func (r *repository) GetCalendarObjectUIDs(userID *uint, names []string) ([]string, error) {
+	var uids []string
+	if len(names) == 0 {
+		return uids, nil
+	}
+
+	var defaultUIDs []string
+	for _, name := range names {
+		if uid, ok := strings.CutSuffix(name, calendarObjectSuffix); ok {
+			defaultUIDs = append(defaultUIDs, uid)
+		}
+	}
+	query := r.db.Model(&CalendarItem{}).Where("resource_name IN ? OR (resource_name IS NULL AND uid IN ?)", names, defaultUIDs)
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	if err := query.Distinct().Pluck("uid", &uids).Error; err != nil {
+		return nil, err
+	}
+	return uids, nil
+}
+
+// ListChangedCalendarObjectNames 列出 since 之后修改或删除过的日历对象的 CalDAV 资源名称（包括已软删除的日历项）
+func (r *repository) ListChangedCalendarObjectNames(userID *uint, since time.Time) ([]string, error) {
+	var names []string
+
+	query := r.db.Unscoped().Model(&CalendarItem{}).Where("updated_at > ? OR deleted_at > ?", since, since)
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	if err := query.Distinct().Pluck("COALESCE(resource_name, uid || '"+calendarObjectSuffix+"')", &names).Error; err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// GetLatestCalendarItemChange 获取日历项最后一次修改或删除的时间，没有日历项时返回 nil
+func (r *repository) GetLatestCalendarItemChange(userID *uint) (*time.Time, error) {
+	var latest sql.NullTime
+
+	query := r.db.Unscoped().Model(&CalendarItem{}).Select("GREATEST(MAX(updated_at), MAX(deleted_at))")
+
+	// 过滤用户ID
+	if userID != nil {
+		query = query.Where("user_id = ?", *userID)
+	}
+
+	if err := query.Scan(&latest).Error; err != nil {
+		return nil, err
+	}
+	if !latest.Valid {
+		return nil, nil
+	}
+	return &latest.Time, nil
+}
+
+// CreateFeedToken 创建订阅令牌
+func (r *repository) CreateFeedToken(token *CalendarFeedToken) error {
+	return r.db.Create(token).Error
+}
+
+// GetFeedToken 根据令牌获取未撤销的订阅令牌
+func (r *repository) GetFeedToken(token string) (*CalendarFeedToken, error) {
+	var feedToken CalendarFeedToken
+	if err := r.db.Where("token = ? AND is_revoked = ?", token, false).First(&feedToken).Error; err != nil {
+		return nil, err
+	}
+	return &feedToken, nil
+}
+
+// ListFeedTokens 列出用户未撤销的订阅令牌
+func (r *repository) ListFeedTokens(userID uint) ([]*CalendarFeedToken, error) {
+	var tokens []*CalendarFeedToken
+	if err := r.db.Where("user_id = ? AND is_revoked = ?", userID, false).Order("id ASC").Find(&tokens).Error; err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeFeedToken 撤销用户的订阅令牌
+func (r *repository) RevokeFeedToken(userID uint, id uint) error {
+	result := r.db.Model(&CalendarFeedToken{}).
+		Where("id = ? AND user_id = ? AND is_revoked = ?", id, userID, false).
+		Update("is_revoked", true)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// TouchFeedToken 记录订阅令牌的最后访问时间
+func (r *repository) TouchFeedToken(id uint, accessedAt time.Time) error {
+	return r.db.Model(&CalendarFeedToken{}).Where("id = ?", id).Update("last_accessed_at", accessedAt).Error
 }
 
 // applyTimeRangeFilters 应用时间范围过滤
@@ -244,7 +498,8 @@ func applyTimeRangeFilters(query *gorm.DB, timeRanges map[string]TimeRange) *gor
 		isDtStart := field == "dtstart"
 		if timeRange.Start != nil {
 			if isDtStart {
-				query = query.Where(column+" >= ?", *timeRange.Start)
+				// 重复日历项的主项可能早于开始时间，其实例由 Service 层展开后再过滤
+				query = query.Where("("+column+" >= ? OR "+recurringCondition+")", *timeRange.Start)
 			} else {
 				query = query.Where("("+column+" >= ? OR "+column+" IS NULL)", *timeRange.Start)
 			}
@@ -261,46 +516,26 @@ func applyTimeRangeFilters(query *gorm.DB, timeRanges map[string]TimeRange) *gor
 	return query
 }
 
-// applyFullTextSearch 应用全文搜索（在所有可搜索字段中搜索）
-// 在所有可搜索字段中使用 OR 连接，任一字段匹配即可
-func applyFullTextSearch(query *gorm.DB, q string) (*gorm.DB, error) {
+// applyFullTextSearch 应用全文搜索：search_vector 匹配关键字，返回用于计算相关度的查询表达式
+// 关键字的语法和中文分词方式见 parseSearchQuery
+func applyFullTextSearch(query *gorm.DB, q string) (*gorm.DB, string, error) {
 	if q == "" {
-		return query, nil
+		return query, "", nil
 	}
 
-	// 获取所有可搜索字段
-	var searchQueries []string
-	var args []interface{}
-
-	keywordEscaped := escapeSQLString(q)
-	keywordPattern := "%" + keywordEscaped + "%"
-
-	// 在所有可搜索字段中搜索
-	for field, column := range fieldColumnMap {
-		_ = field // 字段名用于文档，实际使用列名
-		searchQueries = append(searchQueries, column+" ILIKE ?")
-		args = append(args, keywordPattern)
+	parsed, err := parseSearchQuery(q)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: 搜索关键字中没有可搜索的文字", err)
 	}
-
-	if len(searchQueries) > 0 {
-		// 使用 OR 连接所有字段，任一字段匹配即可
-		query = query.Where("("+strings.Join(searchQueries, " OR ")+")", args...)
-	}
-
-	return query, nil
+	tsquery := parsed.tsquery()
+	return query.Where("search_vector @@ to_tsquery('simple', ?)", tsquery), tsquery, nil
 }
 
-// buildOrderBy 构建排序表达式
-// 按开始时间（dtstart）升序排序
-func buildOrderBy(q string) string {
-	return "dt_start ASC"
-}
-
-// SearchCalendarItems 搜索日历项（按匹配程度排序）
+// SearchCalendarItems 搜索日历项
 // q: 搜索关键字，在所有可搜索字段中搜索
-// 支持多个时间字段的范围过滤
+// 支持多个时间字段的范围过滤，按 page.Sort 排序，按相关度（ts_rank_cd）排序时 q 不能为空
 // 注意：此方法假设参数已经由Service层验证，不再进行重复验证
-func (r *repository) SearchCalendarItems(userID *uint, q string, timeRanges map[string]TimeRange, limit int) ([]*CalendarItem, error) {
+func (r *repository) SearchCalendarItems(userID *uint, q string, timeRanges map[string]TimeRange, page PageRequest) ([]*CalendarItem, *PageCursor, error) {
 	// 构建基础查询
 	query := r.db.Model(&CalendarItem{})
 	if userID != nil {
@@ -311,25 +546,33 @@ func (r *repository) SearchCalendarItems(userID *uint, q string, timeRanges map[
 	query = applyTimeRangeFilters(query, timeRanges)
 
 	// 应用全文搜索（如果有关键字）
-	if q != "" {
-		var err error
-		query, err = applyFullTextSearch(query, q)
-		if err != nil {
-			return nil, err
-		}
+	query, tsquery, err := applyFullTextSearch(query, q)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	// 应用排序
-	query = query.Order(buildOrderBy(q))
+	// 应用排序和分页
+	order := newSortExpression(page.Sort, tsquery)
+	query = applyPage(query, order, page)
 
 	// 执行查询
 	var items []*CalendarItem
-	if err := query.Limit(limit).Find(&items).Error; err != nil {
-		return nil, err
+	if err := query.Find(&items).Error; err != nil {
+		return nil, nil, err
+	}
+
+	// 按相关度排序时，下一页的游标需要最后一项的相关度
+	items, cursor, err := nextPageCursor(items, page, func(item *CalendarItem) (float64, error) {
+		var rank float64
+		err := r.db.Model(&CalendarItem{}).Select(order.sql, order.vars...).Where("id = ?", item.ID).Scan(&rank).Error
+		return rank, err
+	})
+	if err != nil {
+		return nil, nil, err
 	}
 
 	slog.Debug("SearchCalendarItems", "items_count", len(items))
-	return items, nil
+	return items, cursor, nil
 }
 
 // CreateValarm 创建提醒
@@ -369,3 +612,36 @@ func (r *repository) DeleteValarm(id uint) error {
 func (r *repository) DeleteValarmsByCalendarItemID(calendarItemID uint) error {
 	return r.db.Where("calendar_item_id = ?", calendarItemID).Delete(&Valarm{}).Error
 }
+
+// CreateAttendee 创建参与者
+func (r *repository) CreateAttendee(attendee *Attendee) error {
+	return r.db.Create(attendee).Error
+}
+
+// GetAttendeeByID 根据ID获取参与者
+func (r *repository) GetAttendeeByID(id uint) (*Attendee, error) {
+	var attendee Attendee
+	if err := r.db.First(&attendee, id).Error; err != nil {
+		return nil, err
+	}
+	return &attendee, nil
+}
+
+// GetAttendeesByCalendarItemID 根据日历项ID获取所有参与者
+func (r *repository) GetAttendeesByCalendarItemID(calendarItemID uint) ([]*Attendee, error) {
+	var attendees []*Attendee
+	if err := r.db.Where("calendar_item_id = ?", calendarItemID).Order("id ASC").Find(&attendees).Error; err != nil {
+		return nil, err
+	}
+	return attendees, nil
+}
+
+// UpdateAttendee 更新参与者
+func (r *repository) UpdateAttendee(attendee *Attendee) error {
+	return r.db.Save(attendee).Error
+}
+
+// DeleteAttendeesByCalendarItemID 删除日历项的所有参与者
+func (r *repository) DeleteAttendeesByCalendarItemID(calendarItemID uint) error {
+	return r.db.Where("calendar_item_id = ?", calendarItemID).Delete(&Attendee{}).Error
+}
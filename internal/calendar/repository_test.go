@@ -8,6 +8,7 @@ import (
 
 	"github.com/DATA-DOG/go-sqlmock"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
@@ -51,6 +52,7 @@ func TestRepository_CreateCalendarItem(t *testing.T) {
 			sqlmock.AnyArg(), // UpdatedAt
 			sqlmock.AnyArg(), // DeletedAt
 			item.UID,
+			sqlmock.AnyArg(), // ResourceName
 			item.Type,
 			item.Summary,
 			sqlmock.AnyArg(), // Description
@@ -59,6 +61,10 @@ func TestRepository_CreateCalendarItem(t *testing.T) {
 			item.DtStart,
 			sqlmock.AnyArg(), // DtEnd
 			sqlmock.AnyArg(), // Due
+			sqlmock.AnyArg(), // DtStartTZID
+			sqlmock.AnyArg(), // DtEndTZID
+			sqlmock.AnyArg(), // DueTZID
+			sqlmock.AnyArg(), // AllDay
 			sqlmock.AnyArg(), // Completed
 			sqlmock.AnyArg(), // Duration
 			sqlmock.AnyArg(), // Status
@@ -75,9 +81,11 @@ func TestRepository_CreateCalendarItem(t *testing.T) {
 			sqlmock.AnyArg(), // Resources
 			sqlmock.AnyArg(), // URL
 			sqlmock.AnyArg(), // Class
+			sqlmock.AnyArg(), // Transp
 			sqlmock.AnyArg(), // LastModified
 			sqlmock.AnyArg(), // RawIcal
 			sqlmock.AnyArg(), // UserID
+			sqlmock.AnyArg(), // RecurrenceID
 		).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
 	mock.ExpectCommit()
@@ -117,14 +125,17 @@ func TestRepository_GetCalendarItemByID_Success(t *testing.T) {
 	)
 
 	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(itemID, userID).
+		WithArgs(itemID, userID, 1).
 		WillReturnRows(rows)
 
 	// Preload Alarms 查询（即使没有alarms也会查询）
 	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
-	userID := uint(1)
+	// Preload Attendees 查询
+	mock.ExpectQuery(`SELECT \* FROM "attendees"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
 	item, err := repo.GetCalendarItemByID(&userID, itemID)
 
 	assert.NoError(t, err)
@@ -144,7 +155,7 @@ func TestRepository_GetCalendarItemByID_NotFound(t *testing.T) {
 	userID := uint(1)
 
 	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(itemID, userID).
+		WithArgs(itemID, userID, 1).
 		WillReturnError(sql.ErrNoRows)
 	item, err := repo.GetCalendarItemByID(&userID, itemID)
 
@@ -181,12 +192,16 @@ func TestRepository_GetCalendarItemByUID_Success(t *testing.T) {
 
 	userID := uint(1)
 	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(uid, userID).
+		WithArgs(uid, userID, 1).
 		WillReturnRows(rows)
 
 	// Preload Alarms 查询
 	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	// Preload Attendees 查询
+	mock.ExpectQuery(`SELECT \* FROM "attendees"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 	item, err := repo.GetCalendarItemByUID(&userID, uid)
 
 	assert.NoError(t, err)
@@ -204,7 +219,7 @@ func TestRepository_GetCalendarItemByUID_NotFound(t *testing.T) {
 	userID := uint(1)
 
 	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(uid, userID).
+		WithArgs(uid, userID, 1).
 		WillReturnError(sql.ErrNoRows)
 	item, err := repo.GetCalendarItemByUID(&userID, uid)
 
@@ -228,46 +243,49 @@ func TestRepository_UpdateCalendarItem(t *testing.T) {
 		DtStart: now,
 	}
 
+	userID := uint(1)
+
+	// Updates(map) 按列名字母序生成 SET 子句，并自动追加 updated_at
 	mock.ExpectBegin()
 	mock.ExpectExec(`UPDATE "calendar_items" SET`).
 		WithArgs(
-			sqlmock.AnyArg(), // CreatedAt
-			sqlmock.AnyArg(), // UpdatedAt
-			sqlmock.AnyArg(), // DeletedAt
-			item.UID,
-			item.Type,
-			item.Summary,
-			sqlmock.AnyArg(), // Description
-			sqlmock.AnyArg(), // Location
-			sqlmock.AnyArg(), // Organizer
+			sqlmock.AnyArg(), // all_day
+			sqlmock.AnyArg(), // categories
+			sqlmock.AnyArg(), // class
+			sqlmock.AnyArg(), // comment
+			sqlmock.AnyArg(), // completed
+			sqlmock.AnyArg(), // contact
+			sqlmock.AnyArg(), // description
+			sqlmock.AnyArg(), // dt_end
+			sqlmock.AnyArg(), // dt_end_tzid
 			item.DtStart,
-			sqlmock.AnyArg(), // DtEnd
-			sqlmock.AnyArg(), // Due
-			sqlmock.AnyArg(), // Completed
-			sqlmock.AnyArg(), // Duration
-			sqlmock.AnyArg(), // Status
-			sqlmock.AnyArg(), // Priority
-			sqlmock.AnyArg(), // PercentComplete
-			sqlmock.AnyArg(), // Sequence
-			sqlmock.AnyArg(), // RRule
-			sqlmock.AnyArg(), // ExDate
-			sqlmock.AnyArg(), // RDate
-			sqlmock.AnyArg(), // Categories
-			sqlmock.AnyArg(), // Comment
-			sqlmock.AnyArg(), // Contact
-			sqlmock.AnyArg(), // RelatedTo
-			sqlmock.AnyArg(), // Resources
-			sqlmock.AnyArg(), // URL
-			sqlmock.AnyArg(), // Class
-			sqlmock.AnyArg(), // LastModified
-			sqlmock.AnyArg(), // RawIcal
-			sqlmock.AnyArg(), // UserID
+			sqlmock.AnyArg(), // dt_start_tzid
+			sqlmock.AnyArg(), // due
+			sqlmock.AnyArg(), // due_tzid
+			sqlmock.AnyArg(), // duration
+			sqlmock.AnyArg(), // ex_date
+			sqlmock.AnyArg(), // last_modified
+			sqlmock.AnyArg(), // location
+			sqlmock.AnyArg(), // organizer
+			sqlmock.AnyArg(), // percent_complete
+			sqlmock.AnyArg(), // priority
+			sqlmock.AnyArg(), // r_date
+			sqlmock.AnyArg(), // r_rule
+			sqlmock.AnyArg(), // raw_ical
+			sqlmock.AnyArg(), // related_to
+			sqlmock.AnyArg(), // resources
+			sqlmock.AnyArg(), // sequence
+			sqlmock.AnyArg(), // status
+			item.Summary,
+			sqlmock.AnyArg(), // transp
+			sqlmock.AnyArg(), // url
+			sqlmock.AnyArg(), // updated_at
 			item.ID,          // WHERE条件中的ID
+			userID,           // WHERE条件中的用户ID
 		).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	userID := uint(1)
 	err := repo.UpdateCalendarItem(&userID, item)
 
 	assert.NoError(t, err)
@@ -280,14 +298,14 @@ func TestRepository_DeleteCalendarItem(t *testing.T) {
 	repo := NewRepository(db)
 
 	itemID := uint(1)
+	userID := uint(1)
 
 	mock.ExpectBegin()
 	mock.ExpectExec(`UPDATE "calendar_items" SET`).
-		WithArgs(sqlmock.AnyArg(), itemID).
+		WithArgs(sqlmock.AnyArg(), itemID, userID).
 		WillReturnResult(sqlmock.NewResult(0, 1))
 	mock.ExpectCommit()
 
-	userID := uint(1)
 	err := repo.DeleteCalendarItem(&userID, itemID)
 
 	assert.NoError(t, err)
@@ -334,18 +352,264 @@ func TestRepository_ListCalendarItems_Success(t *testing.T) {
 	}
 
 	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(userID, endTime, startTime, limit).
+		WithArgs(userID, endTime, endTime, startTime, startTime, limit+1).
 		WillReturnRows(rows)
 
 	// Preload Alarms 查询
 	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
 		WillReturnRows(sqlmock.NewRows([]string{"id"}))
 
-	items, totalCount, err := repo.ListCalendarItems(&userID, &startTime, &endTime, nil, offset, limit)
+	// Preload Attendees 查询
+	mock.ExpectQuery(`SELECT \* FROM "attendees"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	items, totalCount, cursor, err := repo.ListCalendarItems(&userID, &startTime, &endTime, nil, PageRequest{Sort: SortByDtStart, Offset: offset, Limit: limit})
 
 	assert.NoError(t, err)
 	assert.Equal(t, total, totalCount)
 	assert.Len(t, items, 2)
+	assert.Nil(t, cursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_ListCalendarItems_Cursor 测试按截止时间排序的游标分页：从游标之后查询，多取的一项用于生成下一页的游标
+func TestRepository_ListCalendarItems_Cursor(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+	due := time.Date(2025, 3, 12, 9, 0, 0, 0, time.UTC)
+	next := due.Add(time.Hour)
+
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "calendar_items"`).
+		WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(5))
+	mock.ExpectQuery(`SELECT \* FROM "calendar_items" WHERE user_id = \$1 AND "calendar_items"."deleted_at" IS NULL AND \(\(due > \$2 OR \(due = \$3 AND id > \$4\) OR due IS NULL\)\) ORDER BY due ASC NULLS LAST, id ASC LIMIT \$5`).
+		WithArgs(userID, due, due, uint(3), 3).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "due"}).AddRow(4, next).AddRow(6, nil).AddRow(7, nil))
+	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "attendees"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	page := PageRequest{Sort: SortByDue, Cursor: &PageCursor{Sort: SortByDue, Time: &due, ID: 3}, Limit: 2}
+	items, total, cursor, err := repo.ListCalendarItems(&userID, nil, nil, nil, page)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), total)
+	assert.Len(t, items, 2)
+	assert.Equal(t, &PageCursor{Sort: SortByDue, ID: 6}, cursor)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_ListCalendarItemsInRange 测试列出时间窗口内的日历项（包括重复日历项）
+func TestRepository_ListCalendarItemsInRange(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+	startTime := time.Now()
+	endTime := startTime.Add(7 * 24 * time.Hour)
+	itemType := CalendarItemTypeEvent
+
+	rows := sqlmock.NewRows([]string{"id", "uid", "type", "dt_start", "r_rule"}).
+		AddRow(uint(1), "uid-1", CalendarItemTypeEvent, startTime.Add(-30*24*time.Hour), "FREQ=WEEKLY").
+		AddRow(uint(2), "uid-2", CalendarItemTypeEvent, startTime, nil)
+
+	mock.ExpectQuery(`SELECT \* FROM "calendar_items" WHERE user_id = \$1 AND type = \$2 AND \(\(dt_start < \$3 OR \(dt_start = \$4 AND NOT all_day\)\) AND \(dt_end > \$5 OR \(dt_end = \$6 AND NOT all_day\) OR dt_end IS NULL OR \(\(r_rule IS NOT NULL AND r_rule <> ''\) OR r_date <> '\[\]'::jsonb\)\)\) AND "calendar_items"."deleted_at" IS NULL ORDER BY dt_start ASC`).
+		WithArgs(userID, itemType, endTime, endTime, startTime, startTime).
+		WillReturnRows(rows)
+
+	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "attendees"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	items, err := repo.ListCalendarItemsInRange(&userID, startTime, endTime, &itemType)
+
+	assert.NoError(t, err)
+	assert.Len(t, items, 2)
+	assert.True(t, items[0].IsRecurring())
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_ListDueTodos 测试列出时间窗口内到期且未完成的待办
+func TestRepository_ListDueTodos(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+	startTime := time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(24 * time.Hour)
+
+	rows := sqlmock.NewRows([]string{"id", "uid", "type", "due"}).
+		AddRow(uint(3), "todo-1", CalendarItemTypeTodo, startTime.Add(10*time.Hour))
+	mock.ExpectQuery(`SELECT \* FROM "calendar_items" WHERE type = \$1 AND user_id = \$2 AND \(due >= \$3 AND due < \$4\) AND \(completed IS NULL AND \(status IS NULL OR status NOT IN \(\$5,\$6\)\)\) AND "calendar_items"."deleted_at" IS NULL ORDER BY due ASC`).
+		WithArgs(CalendarItemTypeTodo, userID, startTime, endTime, "COMPLETED", "CANCELLED").
+		WillReturnRows(rows)
+
+	items, err := repo.ListDueTodos(&userID, startTime, endTime)
+
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.Equal(t, "todo-1", items[0].UID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_GetCalendarItemOverride 测试获取重复日历项的例外实例
+func TestRepository_GetCalendarItemOverride(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+	uid := "daily"
+	recurrenceID := time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC)
+
+	rows := sqlmock.NewRows([]string{"id", "uid", "dt_start", "recurrence_id"}).
+		AddRow(uint(5), uid, recurrenceID.Add(time.Hour), recurrenceID)
+	mock.ExpectQuery(`SELECT \* FROM "calendar_items" WHERE \(uid = \$1 AND recurrence_id = \$2\) AND user_id = \$3`).
+		WithArgs(uid, recurrenceID, userID, 1).
+		WillReturnRows(rows)
+	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectQuery(`SELECT \* FROM "attendees"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	item, err := repo.GetCalendarItemOverride(&userID, uid, recurrenceID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), item.ID)
+	assert.True(t, item.RecurrenceID.Equal(recurrenceID))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_ReassignCalendarItemOverrides 测试拆分重复日历项时转移例外实例
+func TestRepository_ReassignCalendarItemOverrides(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+	from := time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "calendar_items" SET "resource_name"=\$1,"uid"=\$2,"updated_at"=\$3 WHERE \(uid = \$4 AND recurrence_id >= \$5\) AND user_id = \$6`).
+		WithArgs(nil, "new-uid", sqlmock.AnyArg(), "daily", from, userID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	err := repo.ReassignCalendarItemOverrides(&userID, "daily", "new-uid", from)
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_Transaction_Rollback 测试事务中任何一步失败时回滚之前的修改（拆分重复日历项时原日历项已被删除）
+func TestRepository_Transaction_Rollback(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+	from := time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC)
+	master := &CalendarItem{ID: 3, UID: "daily"}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "calendar_items" SET "resource_name"=`).
+		WithArgs(nil, "new-uid", sqlmock.AnyArg(), "daily", from, userID).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE "calendar_items" SET`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	err := repo.Transaction(func(repo Repository) error {
+		if err := repo.ReassignCalendarItemOverrides(&userID, "daily", "new-uid", from); err != nil {
+			return err
+		}
+		return repo.UpdateCalendarItem(&userID, master)
+	})
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_GetCalendarObjectUIDs 测试根据资源名称查找 UID：保存的资源名称和默认名称 {UID}.ics
+func TestRepository_GetCalendarObjectUIDs(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+	names := []string{"3f9c2b1e.ics", "event-1.ics", "no-suffix"}
+
+	mock.ExpectQuery(`SELECT DISTINCT "uid" FROM "calendar_items" WHERE \(resource_name IN \(\$1,\$2,\$3\) OR \(resource_name IS NULL AND uid IN \(\$4,\$5\)\)\) AND user_id = \$6 AND "calendar_items"."deleted_at" IS NULL`).
+		WithArgs("3f9c2b1e.ics", "event-1.ics", "no-suffix", "3f9c2b1e", "event-1", userID).
+		WillReturnRows(sqlmock.NewRows([]string{"uid"}).AddRow("040000008200E001").AddRow("event-1"))
+
+	uids, err := repo.GetCalendarObjectUIDs(&userID, names)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"040000008200E001", "event-1"}, uids)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_ListChangedCalendarObjectNames 测试查询修改过的日历对象的资源名称（包括已软删除的日历项）
+func TestRepository_ListChangedCalendarObjectNames(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+	since := time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT DISTINCT COALESCE\(resource_name, uid \|\| '.ics'\) FROM "calendar_items" WHERE \(updated_at > \$1 OR deleted_at > \$2\) AND user_id = \$3`).
+		WithArgs(since, since, userID).
+		WillReturnRows(sqlmock.NewRows([]string{"name"}).AddRow("a.ics").AddRow("3f9c2b1e.ics"))
+
+	names, err := repo.ListChangedCalendarObjectNames(&userID, since)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"a.ics", "3f9c2b1e.ics"}, names)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_GetLatestCalendarItemChange 测试获取最后修改时间，没有日历项时返回 nil
+func TestRepository_GetLatestCalendarItemChange(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+	latest := time.Date(2025, 1, 8, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT GREATEST\(MAX\(updated_at\), MAX\(deleted_at\)\) FROM "calendar_items" WHERE user_id = \$1`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"greatest"}).AddRow(latest))
+	mock.ExpectQuery(`SELECT GREATEST`).
+		WithArgs(userID).
+		WillReturnRows(sqlmock.NewRows([]string{"greatest"}).AddRow(nil))
+
+	got, err := repo.GetLatestCalendarItemChange(&userID)
+	assert.NoError(t, err)
+	assert.Equal(t, latest, *got)
+
+	got, err = repo.GetLatestCalendarItemChange(&userID)
+	assert.NoError(t, err)
+	assert.Nil(t, got)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_RevokeFeedToken 测试撤销订阅令牌，令牌不存在时返回 ErrRecordNotFound
+func TestRepository_RevokeFeedToken(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "calendar_feed_tokens" SET "is_revoked"=\$1,"updated_at"=\$2 WHERE \(id = \$3 AND user_id = \$4 AND is_revoked = \$5\)`).
+		WithArgs(true, sqlmock.AnyArg(), 2, 1, false).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "calendar_feed_tokens" SET`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.RevokeFeedToken(1, 2))
+	assert.ErrorIs(t, repo.RevokeFeedToken(1, 3), gorm.ErrRecordNotFound)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
@@ -562,7 +826,6 @@ func TestRepository_SearchCalendarItemsByKeyword_SingleField(t *testing.T) {
 
 	userID := uint(1)
 	q := "测试"
-	keywordPattern := "%测试%"
 
 	summary := "测试事件"
 	exDateJSON, _ := json.Marshal([]string{})
@@ -584,16 +847,12 @@ func TestRepository_SearchCalendarItemsByKeyword_SingleField(t *testing.T) {
 	)
 
 	// GORM 会自动添加 deleted_at IS NULL 和 LIMIT 条件
-	// 现在在所有可搜索字段中搜索，会有多个 OR 条件
-	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(userID, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+	// 关键字转换为 tsquery，与 search_vector 匹配
+	mock.ExpectQuery(`SELECT \* FROM "calendar_items" WHERE user_id = \$1 AND search_vector @@ to_tsquery\('simple', \$2\)`).
+		WithArgs(userID, "'测试'", 21).
 		WillReturnRows(rows)
 
-	// Preload Alarms 查询
-	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
-
-	items, err := repo.SearchCalendarItems(&userID, q, nil, 20)
+	items, _, err := repo.SearchCalendarItems(&userID, q, nil, PageRequest{Sort: SortByDtStart, Limit: 20})
 
 	assert.NoError(t, err)
 	assert.Len(t, items, 1)
@@ -638,14 +897,10 @@ func TestRepository_SearchCalendarItems_WithTimeRange(t *testing.T) {
 
 	// GORM 会自动添加 deleted_at IS NULL 和 LIMIT 条件
 	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(userID, startTime, endTime, sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg(), sqlmock.AnyArg()).
+		WithArgs(userID, startTime, endTime, "'测试'", sqlmock.AnyArg()).
 		WillReturnRows(rows)
 
-	// Preload Alarms 查询
-	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
-
-	items, err := repo.SearchCalendarItems(&userID, q, timeRanges, 20)
+	items, _, err := repo.SearchCalendarItems(&userID, q, timeRanges, PageRequest{Sort: SortByDtStart, Limit: 20})
 
 	assert.NoError(t, err)
 	assert.Len(t, items, 1)
@@ -693,11 +948,7 @@ func TestRepository_SearchCalendarItems_OnlyTimeRange(t *testing.T) {
 		WithArgs(userID, startTime, endTime, sqlmock.AnyArg()).
 		WillReturnRows(rows)
 
-	// Preload Alarms 查询
-	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
-
-	items, err := repo.SearchCalendarItems(&userID, q, timeRanges, 20)
+	items, _, err := repo.SearchCalendarItems(&userID, q, timeRanges, PageRequest{Sort: SortByDtStart, Limit: 20})
 
 	assert.NoError(t, err)
 	assert.Len(t, items, 1)
@@ -705,17 +956,12 @@ func TestRepository_SearchCalendarItems_OnlyTimeRange(t *testing.T) {
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// TestRepository_SearchCalendarItemsByKeyword_DuplicateFields 测试重复字段去重
-func TestRepository_SearchCalendarItemsByKeyword_DuplicateFields(t *testing.T) {
+// TestRepository_SearchCalendarItemsByKeyword_Syntax 测试关键字转换为 tsquery：短语、中文二元组和前缀匹配
+func TestRepository_SearchCalendarItemsByKeyword_Syntax(t *testing.T) {
 	db, mock := setupTestDB(t)
 	repo := NewRepository(db)
 
 	userID := uint(1)
-	fieldKeywords := map[string]string{
-		"summary":  "测试",
-		"location": "测试",
-	}
-	keywordPattern := "%测试%"
 
 	summary := "测试事件"
 	location := "测试地点"
@@ -737,16 +983,12 @@ func TestRepository_SearchCalendarItemsByKeyword_DuplicateFields(t *testing.T) {
 		nil, resourcesJSON, nil, nil, nil, nil, userID,
 	)
 
-	// map 自动去重，GORM 会自动添加 deleted_at IS NULL 和 LIMIT 条件
-	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(userID, keywordPattern, keywordPattern, sqlmock.AnyArg()).
+	// 所有词都必须匹配，GORM 会自动添加 deleted_at IS NULL 和 LIMIT 条件
+	mock.ExpectQuery(`SELECT \* FROM "calendar_items" WHERE user_id = \$1 AND search_vector @@ to_tsquery\('simple', \$2\)`).
+		WithArgs(userID, "('weekly' <-> 'sync') & ('测试' <-> '试地' <-> '地点') & 'rev':*", sqlmock.AnyArg()).
 		WillReturnRows(rows)
 
-	// Preload Alarms 查询
-	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
-
-	items, err := repo.SearchCalendarItemsByFieldKeywords(&userID, fieldKeywords, nil)
+	items, _, err := repo.SearchCalendarItems(&userID, `"Weekly sync" 测试地点 rev*`, nil, PageRequest{Sort: SortByDtStart, Limit: 20})
 
 	assert.NoError(t, err)
 	assert.Len(t, items, 1)
@@ -758,11 +1000,6 @@ func TestRepository_SearchCalendarItemsByKeyword_NoUserID(t *testing.T) {
 	db, mock := setupTestDB(t)
 	repo := NewRepository(db)
 
-	fieldKeywords := map[string]string{
-		"summary": "测试",
-	}
-	keywordPattern := "%测试%"
-
 	summary := "测试事件"
 	exDateJSON, _ := json.Marshal([]string{})
 	categoriesJSON, _ := json.Marshal([]string{})
@@ -782,32 +1019,48 @@ func TestRepository_SearchCalendarItemsByKeyword_NoUserID(t *testing.T) {
 		nil, resourcesJSON, nil, nil, nil, nil, nil,
 	)
 
-	// GORM 会自动添加 deleted_at IS NULL 和 LIMIT 条件
+	// 没有用户ID过滤，只有关键字参数和 LIMIT
 	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(keywordPattern, sqlmock.AnyArg()).
+		WithArgs(sqlmock.AnyArg(), sqlmock.AnyArg()).
 		WillReturnRows(rows)
 
-	// Preload Alarms 查询
-	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	items, _, err := repo.SearchCalendarItems(nil, "测试", nil, PageRequest{Sort: SortByDtStart, Limit: 20})
 
-	items, err := repo.SearchCalendarItemsByFieldKeywords(nil, fieldKeywords, nil)
+	assert.NoError(t, err)
+	assert.Len(t, items, 1)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_SearchCalendarItems_Relevance 测试按相关度排序：按 ts_rank_cd 降序，下一页的游标包含最后一项的相关度
+func TestRepository_SearchCalendarItems_Relevance(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	userID := uint(1)
+
+	mock.ExpectQuery(`SELECT \* FROM "calendar_items" WHERE .* ORDER BY ts_rank_cd\(search_vector, to_tsquery\('simple', \$3\)\) DESC NULLS LAST, id ASC LIMIT \$4`).
+		WithArgs(userID, "'周会'", "'周会'", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(2).AddRow(1))
+	mock.ExpectQuery(`SELECT ts_rank_cd\(search_vector, to_tsquery\('simple', \$1\)\) FROM "calendar_items" WHERE id = \$2`).
+		WithArgs("'周会'", 2).
+		WillReturnRows(sqlmock.NewRows([]string{"rank"}).AddRow(0.6))
+
+	items, cursor, err := repo.SearchCalendarItems(&userID, "周会", nil, PageRequest{Sort: SortByRelevance, Limit: 1})
 
 	assert.NoError(t, err)
 	assert.Len(t, items, 1)
+	require.NotNil(t, cursor)
+	assert.Equal(t, uint(2), cursor.ID)
+	assert.Equal(t, 0.6, *cursor.Rank)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
 
-// TestRepository_SearchCalendarItemsByKeyword_JSONBFields 测试 JSONB 字段搜索
+// TestRepository_SearchCalendarItemsByKeyword_JSONBFields 测试 JSONB 字段（categories）包含在 search_vector 中，结果正常解析
 func TestRepository_SearchCalendarItemsByKeyword_JSONBFields(t *testing.T) {
 	db, mock := setupTestDB(t)
 	repo := NewRepository(db)
 
 	userID := uint(1)
-	fieldKeywords := map[string]string{
-		"categories": "工作",
-	}
-	keywordPattern := "%工作%"
 
 	summary := "测试事件"
 	categoriesJSON, _ := json.Marshal([]string{"工作", "重要"})
@@ -828,18 +1081,15 @@ func TestRepository_SearchCalendarItemsByKeyword_JSONBFields(t *testing.T) {
 		nil, resourcesJSON, nil, nil, nil, nil, userID,
 	)
 
-	// GORM 会自动添加 deleted_at IS NULL 和 LIMIT 条件
-	mock.ExpectQuery(`SELECT \* FROM "calendar_items"`).
-		WithArgs(userID, keywordPattern, sqlmock.AnyArg()).
+	// categories 由 search_vector 生成列索引，查询只需匹配 search_vector
+	mock.ExpectQuery(`SELECT \* FROM "calendar_items" WHERE .*search_vector @@`).
+		WithArgs(userID, "'工作'", sqlmock.AnyArg()).
 		WillReturnRows(rows)
 
-	// Preload Alarms 查询
-	mock.ExpectQuery(`SELECT \* FROM "valarms"`).
-		WillReturnRows(sqlmock.NewRows([]string{"id"}))
-
-	items, err := repo.SearchCalendarItemsByFieldKeywords(&userID, fieldKeywords, nil)
+	items, _, err := repo.SearchCalendarItems(&userID, "工作", nil, PageRequest{Sort: SortByDtStart, Limit: 20})
 
 	assert.NoError(t, err)
 	assert.Len(t, items, 1)
+	assert.Equal(t, []string{"工作", "重要"}, []string(items[0].Categories))
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
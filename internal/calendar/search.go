@@ -0,0 +1,313 @@
+package calendar
+
+import (
+	"html"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// 全文搜索
+//
+// calendar_items.search_vector 是由数据库维护的 tsvector 生成列（见数据库迁移），各字段的权重为
+// summary (A) > location (B) > description (C) > organizer、comment、contact、categories、resources (D)
+// PostgreSQL 自带的分词器不能切分中文，因此文本先经过 calendar_search_tokens 函数处理：
+// 汉字拆成相邻两字的二元组和单字，其余文本去掉标点后按 simple 配置分词（只转小写，不做词干提取）
+// 查询时按同样的规则切分关键字，连续的汉字用 <-> 连接成短语，因此“项目评审”不会匹配“评审项目”
+
+// 搜索结果高亮片段的格式
+const (
+	highlightStart   = "<b>"
+	highlightEnd     = "</b>"
+	highlightContext = 20 // 第一个匹配之前保留的字符数
+	highlightLength  = 80 // 片段的最大字符数（不含标记）
+)
+
+// isSearchHan 是否为按二元组切分的汉字，与数据库函数 calendar_search_tokens 的范围一致
+func isSearchHan(r rune) bool {
+	return (r >= 0x3400 && r <= 0x4dbf) || (r >= 0x4e00 && r <= 0x9fff) || (r >= 0xf900 && r <= 0xfaff)
+}
+
+// searchTerm 搜索关键字中的一个词或引号括起来的短语
+type searchTerm struct {
+	words  []string // 非汉字部分，小写
+	han    []string // 连续的汉字
+	phrase bool     // 短语：各个词必须相邻且按顺序出现
+	prefix bool     // 前缀匹配：以 * 结尾，只作用于最后一个词
+}
+
+// searchQuery 解析后的搜索关键字，所有词都必须匹配
+type searchQuery struct {
+	terms []searchTerm
+}
+
+// parseSearchQuery 解析搜索关键字：空白分隔的词都必须匹配，双引号（包括中文引号）括起来的是短语，
+// 以 * 结尾的词按前缀匹配，例如 `"weekly sync" 项目 rev*`。没有可搜索的内容时返回 ErrInvalidInput
+func parseSearchQuery(q string) (*searchQuery, error) {
+	var query searchQuery
+	var current strings.Builder
+	inQuote := false
+	flush := func(phrase bool) {
+		text := current.String()
+		current.Reset()
+		prefix := !phrase && strings.HasSuffix(text, "*")
+		if term, ok := newSearchTerm(strings.TrimRight(text, "*"), phrase, prefix); ok {
+			query.terms = append(query.terms, term)
+		}
+	}
+
+	for _, r := range q {
+		switch {
+		case r == '"' || r == '“' || r == '”':
+			flush(inQuote)
+			inQuote = !inQuote
+		case unicode.IsSpace(r) && !inQuote:
+			flush(false)
+		default:
+			current.WriteRune(r)
+		}
+	}
+	flush(inQuote)
+
+	if len(query.terms) == 0 {
+		return nil, ErrInvalidInput
+	}
+	return &query, nil
+}
+
+// newSearchTerm 将一个词或短语切分为非汉字的词和连续的汉字，没有可搜索的内容时返回 false
+func newSearchTerm(text string, phrase, prefix bool) (searchTerm, bool) {
+	term := searchTerm{phrase: phrase, prefix: prefix}
+	var word, han []rune
+	flush := func() {
+		if len(word) > 0 {
+			term.words = append(term.words, strings.Map(unicode.ToLower, string(word)))
+			word = word[:0]
+		}
+		if len(han) > 0 {
+			term.han = append(term.han, string(han))
+			han = han[:0]
+		}
+	}
+	for _, r := range text {
+		switch {
+		case isSearchHan(r):
+			if len(word) > 0 {
+				flush()
+			}
+			han = append(han, r)
+		case unicode.IsLetter(r) || unicode.IsNumber(r):
+			if len(han) > 0 {
+				flush()
+			}
+			word = append(word, r)
+		default:
+			flush()
+		}
+	}
+	flush()
+	// 只有以非汉字的词结尾时才按前缀匹配（汉字的二元组已经可以匹配任意位置的子串）
+	if last, _ := utf8.DecodeLastRuneInString(text); !isSearchWordRune(last) {
+		term.prefix = false
+	}
+	return term, len(term.words) > 0 || len(term.han) > 0
+}
+
+// tsquery 转换为 to_tsquery('simple', ...) 的参数
+func (q *searchQuery) tsquery() string {
+	parts := make([]string, 0, len(q.terms))
+	for _, term := range q.terms {
+		parts = append(parts, term.tsquery())
+	}
+	return strings.Join(parts, " & ")
+}
+
+// tsquery 单个词或短语的查询表达式
+// 非汉字部分和汉字部分在 tsvector 中的位置不相邻，两部分之间用 & 连接
+func (t searchTerm) tsquery() string {
+	var groups []string
+	if len(t.words) > 0 {
+		lexemes := make([]string, len(t.words))
+		for i, word := range t.words {
+			lexemes[i] = quoteLexeme(word)
+		}
+		if t.prefix {
+			lexemes[len(lexemes)-1] += ":*"
+		}
+		separator := " & "
+		if t.phrase {
+			separator = " <-> "
+		}
+		groups = append(groups, joinQuery(lexemes, separator))
+	}
+
+	var runs []string
+	for _, run := range t.han {
+		runs = append(runs, hanQuery(run))
+	}
+	if len(runs) > 0 {
+		separator := " & "
+		if t.phrase {
+			separator = " <-> "
+		}
+		groups = append(groups, joinQuery(runs, separator))
+	}
+	return joinQuery(groups, " & ")
+}
+
+// hanQuery 连续汉字的查询表达式：单字直接匹配，多个字匹配相邻的二元组
+func hanQuery(run string) string {
+	chars := []rune(run)
+	if len(chars) == 1 {
+		return quoteLexeme(run)
+	}
+	bigrams := make([]string, 0, len(chars)-1)
+	for i := 0; i+1 < len(chars); i++ {
+		bigrams = append(bigrams, quoteLexeme(string(chars[i:i+2])))
+	}
+	return joinQuery(bigrams, " <-> ")
+}
+
+// joinQuery 连接多个查询表达式，多于一个时加括号
+func joinQuery(parts []string, separator string) string {
+	if len(parts) == 1 {
+		return parts[0]
+	}
+	return "(" + strings.Join(parts, separator) + ")"
+}
+
+// quoteLexeme 将词用单引号括起来，避免被解析为 tsquery 运算符
+func quoteLexeme(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// highlightFields 生成高亮片段的字段，与 search_vector 包含的字段一致
+var highlightFields = []struct {
+	name  string
+	value func(item *CalendarItem) string
+}{
+	{"summary", func(item *CalendarItem) string { return StringValue(item.Summary) }},
+	{"location", func(item *CalendarItem) string { return StringValue(item.Location) }},
+	{"description", func(item *CalendarItem) string { return StringValue(item.Description) }},
+	{"organizer", func(item *CalendarItem) string { return StringValue(item.Organizer) }},
+	{"comment", func(item *CalendarItem) string { return StringValue(item.Comment) }},
+	{"contact", func(item *CalendarItem) string { return StringValue(item.Contact) }},
+	{"categories", func(item *CalendarItem) string { return strings.Join(item.Categories, ", ") }},
+	{"resources", func(item *CalendarItem) string { return strings.Join(item.Resources, ", ") }},
+}
+
+// highlight 为日历项中匹配关键字的字段生成高亮片段，结果保存在 item.Highlights
+func (q *searchQuery) highlight(item *CalendarItem) {
+	item.Highlights = nil
+	for _, field := range highlightFields {
+		if snippet, ok := q.snippet(field.value(item)); ok {
+			if item.Highlights == nil {
+				item.Highlights = make(map[string]string)
+			}
+			item.Highlights[field.name] = snippet
+		}
+	}
+}
+
+// snippet 截取文本中第一个匹配附近的片段，匹配的部分用 <b></b> 标记；没有匹配时返回 false
+// 片段中的文本都经过 HTML 转义，只有高亮标记是 HTML
+func (q *searchQuery) snippet(text string) (string, bool) {
+	if text == "" {
+		return "", false
+	}
+	runes := []rune(text)
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+
+	// 标记匹配的字符
+	marked := make([]bool, len(runes))
+	found := false
+	for _, term := range q.terms {
+		for i, word := range term.words {
+			prefix := term.prefix && i == len(term.words)-1
+			found = markMatches(lower, marked, []rune(word), true, prefix) || found
+		}
+		for _, run := range term.han {
+			found = markMatches(lower, marked, []rune(run), false, false) || found
+		}
+	}
+	if !found {
+		return "", false
+	}
+
+	first := 0
+	for !marked[first] {
+		first++
+	}
+	start := max(first-highlightContext, 0)
+	end := min(start+highlightLength, len(runes))
+
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	// 按是否匹配分段，每段转义后再加标记
+	for i := start; i < end; {
+		j := i + 1
+		for j < end && marked[j] == marked[i] {
+			j++
+		}
+		segment := html.EscapeString(string(runes[i:j]))
+		if marked[i] {
+			segment = highlightStart + segment + highlightEnd
+		}
+		b.WriteString(segment)
+		i = j
+	}
+	if end < len(runes) {
+		b.WriteString("…")
+	}
+	return b.String(), true
+}
+
+// markMatches 标记 text 中所有与 pattern 相同的位置
+// word 为 true 时只匹配完整的词（前后不能是字母或数字），prefix 为 true 时允许匹配词的开头
+func markMatches(text []rune, marked []bool, pattern []rune, word, prefix bool) bool {
+	found := false
+	for i := 0; i+len(pattern) <= len(text); i++ {
+		if !runesEqual(text[i:i+len(pattern)], pattern) {
+			continue
+		}
+		end := i + len(pattern)
+		if word {
+			if i > 0 && isSearchWordRune(text[i-1]) {
+				continue
+			}
+			if end < len(text) && isSearchWordRune(text[end]) {
+				if !prefix {
+					continue
+				}
+				for end < len(text) && isSearchWordRune(text[end]) {
+					end++
+				}
+			}
+		}
+		for j := i; j < end; j++ {
+			marked[j] = true
+		}
+		found = true
+	}
+	return found
+}
+
+// isSearchWordRune 是否为非汉字词的组成字符
+func isSearchWordRune(r rune) bool {
+	return !isSearchHan(r) && (unicode.IsLetter(r) || unicode.IsNumber(r))
+}
+
+// runesEqual 比较两个字符序列是否相同
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,88 @@
+package calendar
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseSearchQuery 测试搜索关键字转换为 tsquery：中文二元组、短语、前缀匹配和中英文混合
+func TestParseSearchQuery(t *testing.T) {
+	cases := []struct {
+		q    string
+		want string
+	}{
+		{"会", "'会'"},
+		{"周会", "'周会'"},
+		{"项目评审", "('项目' <-> '目评' <-> '评审')"},
+		{"Weekly", "'weekly'"},
+		{"weekly sync", "'weekly' & 'sync'"},
+		{`"weekly sync"`, "('weekly' <-> 'sync')"},
+		{"“项目 评审”", "('项目' <-> '评审')"},
+		{"rev*", "'rev':*"},
+		{"项目*", "'项目'"},
+		{"Q3项目", "('q3' & '项目')"},
+		{"o'clock", "('o' & 'clock')"},
+		{"  周会   room-101 ", "'周会' & ('room' & '101')"},
+	}
+	for _, c := range cases {
+		query, err := parseSearchQuery(c.q)
+
+		require.NoError(t, err, c.q)
+		assert.Equal(t, c.want, query.tsquery(), c.q)
+	}
+}
+
+// TestParseSearchQuery_Invalid 测试没有可搜索文字的关键字
+func TestParseSearchQuery_Invalid(t *testing.T) {
+	for _, q := range []string{"", "   ", "!!!", `""`, "*"} {
+		_, err := parseSearchQuery(q)
+		assert.ErrorIs(t, err, ErrInvalidInput, q)
+	}
+}
+
+// TestSearchQuery_Highlight 测试高亮片段：匹配部分加标记，长文本截取第一个匹配附近的内容，不匹配的字段不返回
+func TestSearchQuery_Highlight(t *testing.T) {
+	summary := "Weekly Sync：项目评审"
+	description := "这是一段很长的描述文字，用来测试高亮片段的截取。前面有很多无关的内容，直到最后才提到项目评审和 review 的安排，之后还有更多的文字内容需要被截断掉才行，例如会议纪要、参会人员名单以及下一次会议的时间和地点等等。"
+	location := "3 号会议室"
+	item := &CalendarItem{Summary: &summary, Description: &description, Location: &location, Categories: StringArray{"工作", "评审"}}
+	query, err := parseSearchQuery("评审 rev*")
+	require.NoError(t, err)
+
+	query.highlight(item)
+
+	assert.Equal(t, "Weekly Sync：项目<b>评审</b>", item.Highlights["summary"])
+	assert.Equal(t, "工作, <b>评审</b>", item.Highlights["categories"])
+	assert.Contains(t, item.Highlights["description"], "项目<b>评审</b>和 <b>review</b> 的安排")
+	assert.True(t, len([]rune(item.Highlights["description"])) < len([]rune(description)))
+	assert.Regexp(t, "^….*…$", item.Highlights["description"])
+	assert.NotContains(t, item.Highlights, "location")
+}
+
+// TestSearchQuery_HighlightWholeWord 测试非前缀匹配的词只高亮完整的词
+func TestSearchQuery_HighlightWholeWord(t *testing.T) {
+	query, err := parseSearchQuery("sync")
+	require.NoError(t, err)
+
+	snippet, ok := query.snippet("Sync up, then async sync")
+
+	assert.True(t, ok)
+	assert.Equal(t, "<b>Sync</b> up, then async <b>sync</b>", snippet)
+
+	_, ok = query.snippet("asynchronous")
+	assert.False(t, ok)
+}
+
+// TestSearchQuery_HighlightEscapesHTML 测试高亮片段中的文本经过 HTML 转义，只有高亮标记是 HTML
+func TestSearchQuery_HighlightEscapesHTML(t *testing.T) {
+	summary := `<script>alert("x")</script> & 评审`
+	item := &CalendarItem{Summary: &summary}
+	query, err := parseSearchQuery("script")
+	require.NoError(t, err)
+
+	query.highlight(item)
+
+	assert.Equal(t, "&lt;<b>script</b>&gt;alert(&#34;x&#34;)&lt;/<b>script</b>&gt; &amp; 评审", item.Highlights["summary"])
+}
@@ -1,9 +1,12 @@
 package calendar
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log/slog"
+	"sort"
 	"strings"
 	"time"
 
@@ -18,8 +21,29 @@ var (
 	ErrInvalidAction        = errors.New("无效的提醒动作类型")
 	ErrInvalidSearchField   = errors.New("无效的搜索字段")
 	ErrForbidden            = errors.New("无权访问该日历项")
+	ErrNotRecurring         = errors.New("日历项不是重复日历项")
+	ErrOccurrenceNotFound   = errors.New("重复日历项中不存在该实例")
 )
 
+// UpdateScope 修改重复日历项时的作用范围
+type UpdateScope string
+
+const (
+	UpdateScopeThis             UpdateScope = "this"               // 仅修改此实例（创建或修改例外实例）
+	UpdateScopeThisAndFollowing UpdateScope = "this_and_following" // 修改此实例及之后的所有实例（拆分重复规则）
+	UpdateScopeAll              UpdateScope = "all"                // 修改所有实例（修改主日历项）
+)
+
+// IsValid 验证修改范围是否有效
+func (s UpdateScope) IsValid() bool {
+	switch s {
+	case UpdateScopeThis, UpdateScopeThisAndFollowing, UpdateScopeAll:
+		return true
+	default:
+		return false
+	}
+}
+
 // SearchableField 可搜索的字段
 type SearchableField string
 
@@ -53,16 +77,42 @@ type Service interface {
 	GetCalendarItemByID(userID *uint, id uint) (*CalendarItem, error)
 	GetCalendarItemByUID(userID *uint, uid string) (*CalendarItem, error)
 	UpdateCalendarItem(userID *uint, id uint, req *UpdateCalendarItemRequest) (*CalendarItem, error)
+	UpdateCalendarItemOccurrence(userID *uint, id uint, recurrenceID time.Time, scope UpdateScope, req *UpdateCalendarItemRequest) (*CalendarItem, error)
 	DeleteCalendarItem(userID *uint, id uint) error
 	ListCalendarItems(userID *uint, req *ListCalendarItemsRequest) (*CalendarItemListResponse, error)
-	SearchCalendarItems(userID *uint, req *SearchCalendarItemsRequest) ([]*CalendarItem, error)
+	ListDueTodos(userID *uint, startTime, endTime time.Time) ([]*CalendarItem, error)
+	SearchCalendarItems(userID *uint, req *SearchCalendarItemsRequest) (*SearchCalendarItemsResponse, error)
+	ImportICalendar(userID *uint, r io.Reader) (*ImportReport, error)
+	ExportICalendar(userID *uint, req *ExportCalendarRequest, w io.Writer) error
+	GetFreeBusy(requesterID uint, req *FreeBusyRequest) (*FreeBusyResponse, error)
+	FindFreeSlots(userID *uint, req *FindFreeSlotsRequest) ([]FreeSlot, error)
+	ProcessITIPMessage(userID *uint, r io.Reader) (*ITIPReport, error)
+
+	// CalDAV 日历对象相关方法（同一 UID 的主日历项和例外实例作为一个资源）
+	ListCalendarObjects(userID *uint, filter *CalendarObjectFilter) ([]*CalendarObject, error)
+	GetCalendarObjects(userID *uint, names []string) ([]*CalendarObject, error)
+	GetCalendarObject(userID *uint, name string) (*CalendarObject, error)
+	PutCalendarObject(userID *uint, name string, r io.Reader) (bool, error)
+	DeleteCalendarObject(userID *uint, name string) error
+	GetCalendarSyncToken(userID *uint) (string, error)
+	SyncCalendarObjects(userID *uint, syncToken string) (*CalendarSyncResult, error)
+
+	// 订阅令牌相关方法
+	CreateFeedToken(userID uint, req *CreateFeedTokenRequest) (*CalendarFeedToken, error)
+	ListFeedTokens(userID uint) ([]*CalendarFeedToken, error)
+	RevokeFeedToken(userID uint, id uint) error
+	ExportFeed(token string, w io.Writer) error
 
 	// Valarm 相关方法
-	CreateValarm(calendarItemID uint, req *CreateValarmRequest) (*Valarm, error)
-	GetValarmByID(id uint) (*Valarm, error)
-	GetValarmsByCalendarItemID(calendarItemID uint) ([]*Valarm, error)
-	UpdateValarm(id uint, req *UpdateValarmRequest) (*Valarm, error)
-	DeleteValarm(id uint) error
+	CreateValarm(userID *uint, calendarItemID uint, req *CreateValarmRequest) (*Valarm, error)
+	GetValarmByID(userID *uint, calendarItemID uint, id uint) (*Valarm, error)
+	GetValarmsByCalendarItemID(userID *uint, calendarItemID uint) ([]*Valarm, error)
+	UpdateValarm(userID *uint, calendarItemID uint, id uint, req *UpdateValarmRequest) (*Valarm, error)
+	DeleteValarm(userID *uint, calendarItemID uint, id uint) error
+
+	// Attendee 相关方法
+	GetAttendeesByCalendarItemID(userID *uint, calendarItemID uint) ([]*Attendee, error)
+	UpdateAttendeeStatus(userID *uint, calendarItemID uint, id uint, req *UpdateAttendeeStatusRequest) (*Attendee, error)
 }
 
 // CreateCalendarItemRequest 创建日历项请求
@@ -72,36 +122,48 @@ type Service interface {
 // - VJOURNAL: DTSTART 必需
 // - VFREEBUSY: DTSTART 和 DTEND 都必需
 type CreateCalendarItemRequest struct {
-	Type            CalendarItemType `json:"type" binding:"required,oneof=VEVENT VTODO VJOURNAL VFREEBUSY"`
-	Summary         *string          `json:"summary"`
-	Description     *string          `json:"description"`
-	Location        *string          `json:"location"`
-	Organizer       *string          `json:"organizer"`
-	DtStart         *time.Time       `json:"dtstart"`  // 根据类型可能必需
-	DtEnd           *time.Time       `json:"dtend"`    // VFREEBUSY 必需，VEVENT 与 DURATION 二选一
-	Due             *time.Time       `json:"due"`      // VTODO 可选（与 DTSTART 二选一）
-	Duration        *string          `json:"duration"` // VEVENT 可选（与 DTEND 二选一）
-	Status          *string          `json:"status"`
-	Priority        *int             `json:"priority" binding:"omitempty,gte=0,lte=9"`
-	PercentComplete *int             `json:"percent_complete" binding:"omitempty,gte=0,lte=100"`
-	RRule           *string          `json:"rrule"`
-	ExDate          []string         `json:"exdate"`
-	RDate           []string         `json:"rdate"`
-	Categories      []string         `json:"categories"`
-	Comment         *string          `json:"comment"`
-	Contact         *string          `json:"contact"`
-	RelatedTo       *string          `json:"related_to"`
-	Resources       []string         `json:"resources"`
-	URL             *string          `json:"url"`
-	Class           *string          `json:"class"`
-	RawIcal         *string          `json:"raw_ical"`
-	Sequence        *int             `json:"sequence"`
+	Type            CalendarItemType  `json:"type" binding:"required,oneof=VEVENT VTODO VJOURNAL VFREEBUSY"`
+	Summary         *string           `json:"summary"`
+	Description     *string           `json:"description"`
+	Location        *string           `json:"location"`
+	Organizer       *string           `json:"organizer"`
+	DtStart         *time.Time        `json:"dtstart"`  // 根据类型可能必需
+	DtEnd           *time.Time        `json:"dtend"`    // VFREEBUSY 必需，VEVENT 与 DURATION 二选一
+	Due             *time.Time        `json:"due"`      // VTODO 可选（与 DTSTART 二选一）
+	TZID            *string           `json:"tzid"`     // DTSTART、DTEND、DUE 的时区（IANA 时区名），默认为用户的时区
+	AllDay          *bool             `json:"all_day"`  // 全天日历项，时间只取日期；dtstart 只有日期（"2024-05-06"）时默认为 true
+	Duration        *string           `json:"duration"` // VEVENT 可选（与 DTEND 二选一）
+	Status          *string           `json:"status"`
+	Priority        *int              `json:"priority" binding:"omitempty,gte=0,lte=9"`
+	PercentComplete *int              `json:"percent_complete" binding:"omitempty,gte=0,lte=100"`
+	RRule           *string           `json:"rrule"`
+	ExDate          []string          `json:"exdate"`
+	RDate           []string          `json:"rdate"`
+	Categories      []string          `json:"categories"`
+	Comment         *string           `json:"comment"`
+	Contact         *string           `json:"contact"`
+	RelatedTo       *string           `json:"related_to"`
+	Resources       []string          `json:"resources"`
+	URL             *string           `json:"url"`
+	Class           *string           `json:"class"`
+	Transp          *string           `json:"transp" binding:"omitempty,oneof=OPAQUE TRANSPARENT"`
+	RawIcal         *string           `json:"raw_ical"`
+	Sequence        *int              `json:"sequence"`
+	Attendees       []AttendeeRequest `json:"attendees" binding:"omitempty,max=100,dive"`
+	ConflictPolicy  *ConflictPolicy   `json:"conflict_policy" binding:"omitempty,oneof=allow warn reject"` // 时间冲突的处理方式，默认 warn
+}
+
+// UnmarshalJSON 允许 dtstart、dtend、due 只写日期，例如 "2024-05-06"，此时默认为全天日历项
+func (req *CreateCalendarItemRequest) UnmarshalJSON(data []byte) error {
+	type plain CreateCalendarItemRequest
+	return json.Unmarshal(rewriteDateOnlyFields(data), (*plain)(req))
 }
 
 type CreateCalendarItemResponse struct {
-	ID   uint             `json:"id"`
-	UID  string           `json:"uid"`
-	Type CalendarItemType `json:"type"`
+	ID        uint             `json:"id"`
+	UID       string           `json:"uid"`
+	Type      CalendarItemType `json:"type"`
+	Conflicts []Conflict       `json:"conflicts,omitempty"` // 冲突处理方式为 warn 时与新事件时间重叠的已有事件
 }
 
 // UpdateCalendarItemRequest 更新日历项请求
@@ -113,13 +175,15 @@ type UpdateCalendarItemRequest struct {
 	DtStart         *time.Time `json:"dtstart,omitempty"`
 	DtEnd           *time.Time `json:"dtend,omitempty"`
 	Due             *time.Time `json:"due,omitempty"`
+	TZID            *string    `json:"tzid,omitempty"`    // DTSTART、DTEND、DUE 的时区（IANA 时区名），为 nil 时保持原有时区
+	AllDay          *bool      `json:"all_day,omitempty"` // 是否为全天日历项；dtstart 只有日期时默认为 true
 	Completed       *time.Time `json:"completed,omitempty"`
 	Duration        *string    `json:"duration,omitempty"`
 	Status          *string    `json:"status,omitempty"`
-	Priority        *int       `json:"priority" binding:"omitempty,gte=0,lte=9"`
-	PercentComplete *int       `json:"percent_complete" binding:"omitempty,gte=0,lte=100"`
+	Priority        *int       `json:"priority,omitempty" binding:"omitempty,gte=0,lte=9"`
+	PercentComplete *int       `json:"percent_complete,omitempty" binding:"omitempty,gte=0,lte=100"`
 	RRule           *string    `json:"rrule,omitempty"`
-	ExDate          []string   `json:"exdate"`
+	ExDate          []string   `json:"exdate,omitempty"`
 	RDate           []string   `json:"rdate,omitempty"`
 	Categories      []string   `json:"categories,omitempty"`
 	Comment         *string    `json:"comment,omitempty"`
@@ -128,26 +192,42 @@ type UpdateCalendarItemRequest struct {
 	Resources       []string   `json:"resources,omitempty"`
 	URL             *string    `json:"url,omitempty"`
 	Class           *string    `json:"class,omitempty"`
+	Transp          *string    `json:"transp,omitempty" binding:"omitempty,oneof=OPAQUE TRANSPARENT"`
 	RawIcal         *string    `json:"raw_ical,omitempty"`
 	Sequence        *int       `json:"sequence,omitempty"`
+	// 参与者，为 nil 时不修改，空数组表示删除全部参与者；按地址匹配已有参与者，未指定的参数保持不变
+	Attendees []AttendeeRequest `json:"attendees,omitempty" binding:"omitempty,max=100,dive"`
+	// 时间冲突的处理方式，默认 warn；只在修改了开始时间、结束时间、重复规则、状态或 TRANSP 时检查
+	ConflictPolicy *ConflictPolicy `json:"conflict_policy,omitempty" binding:"omitempty,oneof=allow warn reject"`
+}
+
+// UnmarshalJSON 允许 dtstart、dtend、due 只写日期，例如 "2024-05-06"，此时默认为全天日历项
+func (req *UpdateCalendarItemRequest) UnmarshalJSON(data []byte) error {
+	type plain UpdateCalendarItemRequest
+	return json.Unmarshal(rewriteDateOnlyFields(data), (*plain)(req))
 }
 
 // ListCalendarItemsRequest 列出日历项请求
+// 同时指定 StartTime 和 EndTime 时，重复日历项会被展开为窗口内的各个实例，此时只能按开始时间排序
+// 指定 Cursor 时从上一页的 next_cursor 之后继续，忽略 Page
 type ListCalendarItemsRequest struct {
 	Page      int               `form:"page" binding:"omitempty,min=1"`
 	PageSize  int               `form:"page_size" binding:"omitempty,min=1,max=100"`
 	StartTime *time.Time        `form:"start_time" time_format:"2006-01-02T15:04:05Z07:00"`
 	EndTime   *time.Time        `form:"end_time" time_format:"2006-01-02T15:04:05Z07:00"`
 	Type      *CalendarItemType `form:"type"`
+	Sort      SortKey           `form:"sort" binding:"omitempty,oneof=dtstart due priority last_modified"` // 排序字段，默认 dtstart
+	Cursor    string            `form:"cursor"`
 }
 
 // CalendarItemListResponse 日历项列表响应
 type CalendarItemListResponse struct {
 	Items      []*CalendarItem `json:"items"`
 	Total      int64           `json:"total"`
-	Page       int             `json:"page"`
+	Page       int             `json:"page,omitempty"` // 使用游标分页时为 0
 	PageSize   int             `json:"page_size"`
 	TotalPages int             `json:"total_pages"`
+	NextCursor string          `json:"next_cursor,omitempty"` // 下一页的游标，没有下一页时为空
 }
 
 // TimeRange 时间范围
@@ -158,7 +238,8 @@ type TimeRange struct {
 
 // SearchCalendarItemsRequest 搜索日历项请求
 type SearchCalendarItemsRequest struct {
-	// 搜索关键字：在所有可搜索字段中搜索（summary, description, location, organizer, comment, contact, categories, resources）
+	// 搜索关键字：在所有可搜索字段中全文搜索（summary, description, location, organizer, comment, contact, categories, resources）
+	// 空白分隔的词都必须匹配，双引号括起来的是短语，以 * 结尾的词按前缀匹配
 	Q *string `json:"q,omitempty"`
 
 	// 开始时间范围过滤
@@ -172,6 +253,18 @@ type SearchCalendarItemsRequest struct {
 
 	// 返回结果数量限制，默认20，最大100
 	Limit *int `json:"limit,omitempty"`
+	// 排序字段：有关键字时默认按相关度（relevance），否则按开始时间（dtstart）
+	// 指定了开始时间下限时重复日历项会被展开，只能按开始时间排序
+	Sort *SortKey `json:"sort,omitempty"`
+	// 上一页返回的 next_cursor
+	Cursor *string `json:"cursor,omitempty"`
+}
+
+// SearchCalendarItemsResponse 搜索日历项响应
+type SearchCalendarItemsResponse struct {
+	Items      []*CalendarItem `json:"items"`
+	Count      int             `json:"count"`
+	NextCursor string          `json:"next_cursor,omitempty"` // 下一页的游标，没有下一页时为空
 }
 
 // CreateValarmRequest 创建提醒请求
@@ -199,12 +292,18 @@ type UpdateValarmRequest struct {
 }
 
 type service struct {
-	repo Repository
+	repo      Repository
+	listeners []EventListener
+	timezone  func(userID uint) *time.Location
 }
 
 // NewService 创建新的服务实例
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, opts ...ServiceOption) Service {
+	s := &service{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 // CreateCalendarItem 创建日历项
@@ -221,6 +320,11 @@ func (s *service) CreateCalendarItem(userID *uint, req *CreateCalendarItemReques
 		return nil, err
 	}
 
+	loc, err := s.requestLocation(userID, req.TZID)
+	if err != nil {
+		return nil, err
+	}
+
 	// 生成 UID
 	uid := uuid.New().String()
 
@@ -248,6 +352,7 @@ func (s *service) CreateCalendarItem(userID *uint, req *CreateCalendarItemReques
 		Resources:       StringArray(req.Resources),
 		URL:             req.URL,
 		Class:           req.Class,
+		Transp:          req.Transp,
 		RawIcal:         req.RawIcal,
 		UserID:          userID,
 	}
@@ -256,6 +361,24 @@ func (s *service) CreateCalendarItem(userID *uint, req *CreateCalendarItemReques
 	if req.DtStart != nil {
 		item.DtStart = *req.DtStart
 	}
+	if req.AllDay != nil {
+		item.AllDay = *req.AllDay
+	}
+	item.normalizeAllDay(loc)
+	item.setTimezone(loc)
+
+	if len(req.Attendees) > 0 {
+		attendees, err := attendeesFromRequest(req.Attendees, nil)
+		if err != nil {
+			return nil, err
+		}
+		item.Attendees = attendees
+	}
+
+	conflicts, err := s.checkConflicts(userID, item, req.ConflictPolicy)
+	if err != nil {
+		return nil, err
+	}
 
 	now := time.Now()
 	item.LastModified = &now
@@ -263,11 +386,13 @@ func (s *service) CreateCalendarItem(userID *uint, req *CreateCalendarItemReques
 	if err := s.repo.CreateCalendarItem(item); err != nil {
 		return nil, fmt.Errorf("创建日历项失败: %w", err)
 	}
+	s.emit(EventCalendarItemCreated, userID, item)
 
 	return &CreateCalendarItemResponse{
-		ID:   item.ID,
-		UID:  item.UID,
-		Type: item.Type,
+		ID:        item.ID,
+		UID:       item.UID,
+		Type:      item.Type,
+		Conflicts: conflicts,
 	}, nil
 }
 
@@ -291,13 +416,54 @@ func (s *service) GetCalendarItemByUID(userID *uint, uid string) (*CalendarItem,
 
 // UpdateCalendarItem 更新日历项
 func (s *service) UpdateCalendarItem(userID *uint, id uint, req *UpdateCalendarItemRequest) (*CalendarItem, error) {
+	if err := validateRecurrence(req.RRule, req.ExDate, req.RDate); err != nil {
+		return nil, err
+	}
+	if err := validateTimezone(req.TZID); err != nil {
+		return nil, err
+	}
+
 	// 先获取现有项（带用户ID过滤）
 	item, err := s.repo.GetCalendarItemByID(userID, id)
 	if err != nil {
 		return nil, ErrCalendarItemNotFound
 	}
 
-	// 更新字段
+	applyUpdateRequest(item, req)
+	if err := applyAttendeesRequest(item, req.Attendees); err != nil {
+		return nil, err
+	}
+	touchCalendarItem(item, req.Sequence)
+
+	var conflicts []Conflict
+	if req.changesTiming() {
+		if conflicts, err = s.checkConflicts(userID, item, req.ConflictPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.UpdateCalendarItem(userID, item); err != nil {
+		return nil, fmt.Errorf("更新日历项失败: %w", err)
+	}
+	if req.Attendees != nil {
+		if err := replaceAttendees(s.repo, item.ID, item.Attendees); err != nil {
+			return nil, err
+		}
+	}
+
+	// 重新获取更新后的项
+	updatedItem, err := s.repo.GetCalendarItemByID(userID, id)
+	if err != nil {
+		return nil, fmt.Errorf("获取更新后的日历项失败: %w", err)
+	}
+	s.emit(EventCalendarItemUpdated, userID, updatedItem)
+
+	updatedItem.Conflicts = conflicts
+	return updatedItem, nil
+}
+
+// applyUpdateRequest 将更新请求中的非空字段应用到日历项
+func applyUpdateRequest(item *CalendarItem, req *UpdateCalendarItemRequest) {
 	if req.Summary != nil {
 		item.Summary = req.Summary
 	}
@@ -319,6 +485,18 @@ func (s *service) UpdateCalendarItem(userID *uint, id uint, req *UpdateCalendarI
 	if req.Due != nil {
 		item.Due = req.Due
 	}
+	if req.AllDay != nil {
+		item.AllDay = *req.AllDay
+	}
+	// 修改时间时沿用原有时区，除非同时指定了新的时区
+	if req.TZID != nil || req.DtStart != nil || req.DtEnd != nil || req.Due != nil || req.AllDay != nil {
+		loc := tzidLocation(item.DtStartTZID)
+		if req.TZID != nil {
+			loc = tzidLocation(req.TZID)
+		}
+		item.normalizeAllDay(loc)
+		item.setTimezone(loc)
+	}
 	if req.Completed != nil {
 		item.Completed = req.Completed
 	}
@@ -364,48 +542,295 @@ func (s *service) UpdateCalendarItem(userID *uint, id uint, req *UpdateCalendarI
 	if req.Class != nil {
 		item.Class = req.Class
 	}
+	if req.Transp != nil {
+		item.Transp = req.Transp
+	}
 	if req.RawIcal != nil {
 		item.RawIcal = req.RawIcal
 	}
+}
 
+// touchCalendarItem 更新最后修改时间，并在未指定序号时自动增加序号
+func touchCalendarItem(item *CalendarItem, sequence *int) {
 	now := time.Now()
 	item.LastModified = &now
 
-	if req.Sequence != nil {
-		item.Sequence = req.Sequence
+	if sequence != nil {
+		item.Sequence = sequence
 	} else {
-		// 自动增加序号
+		// 自动增加序号（未设置时视为 0）
 		if item.Sequence == nil {
-			seq := 0
+			seq := 1
 			item.Sequence = &seq
 		} else {
-			*item.Sequence++
+			seq := *item.Sequence + 1
+			item.Sequence = &seq
 		}
 	}
+}
 
-	if err := s.repo.UpdateCalendarItem(userID, item); err != nil {
-		return nil, fmt.Errorf("更新日历项失败: %w", err)
+// UpdateCalendarItemOccurrence 按作用范围修改重复日历项的实例
+// id 可以是主日历项或已有的例外实例；recurrenceID 为被修改实例的原始开始时间
+func (s *service) UpdateCalendarItemOccurrence(userID *uint, id uint, recurrenceID time.Time, scope UpdateScope, req *UpdateCalendarItemRequest) (*CalendarItem, error) {
+	if !scope.IsValid() {
+		return nil, fmt.Errorf("%w: 无效的修改范围 %q", ErrInvalidInput, scope)
+	}
+	if scope == UpdateScopeThis && (req.RRule != nil || req.RDate != nil || req.ExDate != nil) {
+		return nil, fmt.Errorf("%w: 单个实例不能修改重复规则", ErrInvalidInput)
+	}
+	if err := validateRecurrence(req.RRule, req.ExDate, req.RDate); err != nil {
+		return nil, err
+	}
+	if err := validateTimezone(req.TZID); err != nil {
+		return nil, err
 	}
 
-	// 重新获取更新后的项
-	updatedItem, err := s.repo.GetCalendarItemByID(userID, id)
+	item, err := s.repo.GetCalendarItemByID(userID, id)
 	if err != nil {
-		return nil, fmt.Errorf("获取更新后的日历项失败: %w", err)
+		return nil, ErrCalendarItemNotFound
 	}
 
-	return updatedItem, nil
+	// 传入的是例外实例：仅修改此实例时直接更新，否则找到主日历项
+	master := item
+	if item.RecurrenceID != nil {
+		if scope == UpdateScopeThis {
+			return s.UpdateCalendarItem(userID, item.ID, req)
+		}
+		master, err = s.repo.GetCalendarItemByUID(userID, item.UID)
+		if err != nil {
+			return nil, ErrCalendarItemNotFound
+		}
+	}
+	if !master.IsRecurring() {
+		return nil, ErrNotRecurring
+	}
+
+	switch scope {
+	case UpdateScopeAll:
+		return s.UpdateCalendarItem(userID, master.ID, req)
+	case UpdateScopeThisAndFollowing:
+		// 从第一个实例开始即修改全部
+		if !recurrenceID.After(master.DtStart) {
+			return s.UpdateCalendarItem(userID, master.ID, req)
+		}
+		return s.splitCalendarItem(userID, master, recurrenceID, req)
+	default:
+		return s.updateOccurrence(userID, master, recurrenceID, req)
+	}
+}
+
+// updateOccurrence 修改重复日历项的单个实例：已有例外实例时更新它，否则创建例外实例
+func (s *service) updateOccurrence(userID *uint, master *CalendarItem, recurrenceID time.Time, req *UpdateCalendarItemRequest) (*CalendarItem, error) {
+	if override, err := s.repo.GetCalendarItemOverride(userID, master.UID, recurrenceID); err == nil {
+		return s.UpdateCalendarItem(userID, override.ID, req)
+	}
+
+	start, ok := occurrenceAt(master, recurrenceID)
+	if !ok {
+		return nil, ErrOccurrenceNotFound
+	}
+
+	override := copyCalendarItem(master, start)
+	override.RecurrenceID = &start
+	override.RRule = nil
+	override.RDate = nil
+	override.ExDate = nil
+	applyUpdateRequest(override, req)
+	if err := applyAttendeesRequest(override, req.Attendees); err != nil {
+		return nil, err
+	}
+	touchCalendarItem(override, req.Sequence)
+
+	var conflicts []Conflict
+	if req.changesTiming() {
+		var err error
+		if conflicts, err = s.checkConflicts(userID, override, req.ConflictPolicy); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.repo.CreateCalendarItem(override); err != nil {
+		return nil, fmt.Errorf("创建例外实例失败: %w", err)
+	}
+	s.emit(EventCalendarItemCreated, userID, override)
+
+	override.Conflicts = conflicts
+	return override, nil
+}
+
+// splitCalendarItem 在 recurrenceID 处拆分重复日历项
+// 原日历项的重复规则截止到该实例之前（UNTIL），从该实例开始的部分成为新的日历项（新 UID，
+// RELATED-TO 指向原日历项）并应用修改；之后的例外实例随之转移到新日历项
+func (s *service) splitCalendarItem(userID *uint, master *CalendarItem, recurrenceID time.Time, req *UpdateCalendarItemRequest) (*CalendarItem, error) {
+	start, ok := occurrenceAt(master, recurrenceID)
+	if !ok {
+		return nil, ErrOccurrenceNotFound
+	}
+
+	following := copyCalendarItem(master, start)
+	following.UID = uuid.New().String()
+	following.ResourceName = nil // 新的日历对象使用默认的 CalDAV 资源名称
+	following.RelatedTo = &master.UID
+	seq := 0
+	following.Sequence = &seq
+
+	// 原日历项的 RRULE 截止到拆分点之前，COUNT 按已发生的实例数扣减
+	until := start.Add(-time.Second)
+	if master.RRule != nil && strings.TrimSpace(*master.RRule) != "" {
+		rule, err := ParseRRule(*master.RRule)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+
+		followingRule := *rule
+		if rule.Count > 0 {
+			followingRule.Count = rule.Count - len(rule.Between(master.DtStart, master.DtStart, until))
+		}
+		followingRRule := followingRule.String()
+		following.RRule = &followingRRule
+
+		masterRule := *rule
+		masterRule.Count = 0
+		untilUTC := until.UTC()
+		masterRule.Until = &untilUTC
+		if master.AllDay {
+			// 全天日历项的 UNTIL 只有日期，与 DTSTART 的类型一致（RFC 5545 3.3.10）
+			untilDate := startOfDay(until, time.UTC)
+			masterRule.Until, masterRule.UntilDate = &untilDate, true
+		}
+		masterRRule := masterRule.String()
+		master.RRule = &masterRRule
+	}
+	master.RDate, following.RDate = splitInstanceValues(master.RDate, master.DtStart, start)
+	master.ExDate, following.ExDate = splitInstanceValues(master.ExDate, master.DtStart, start)
+
+	applyUpdateRequest(following, req)
+	if err := applyAttendeesRequest(following, req.Attendees); err != nil {
+		return nil, err
+	}
+	touchCalendarItem(following, req.Sequence)
+
+	var conflicts []Conflict
+	if req.changesTiming() {
+		var err error
+		if conflicts, err = s.checkConflicts(userID, following, req.ConflictPolicy, master.UID); err != nil {
+			return nil, err
+		}
+	}
+
+	// 新建、转移例外实例和截断原日历项在同一个事务中完成，任何一步失败都不会留下重复的实例
+	touchCalendarItem(master, nil)
+	err := s.repo.Transaction(func(repo Repository) error {
+		if err := repo.CreateCalendarItem(following); err != nil {
+			return fmt.Errorf("创建拆分后的日历项失败: %w", err)
+		}
+		if err := repo.ReassignCalendarItemOverrides(userID, master.UID, following.UID, start); err != nil {
+			return fmt.Errorf("转移例外实例失败: %w", err)
+		}
+		if err := repo.UpdateCalendarItem(userID, master); err != nil {
+			return fmt.Errorf("更新日历项失败: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	s.emit(EventCalendarItemCreated, userID, following)
+	s.emit(EventCalendarItemUpdated, userID, master)
+
+	following.Conflicts = conflicts
+	return following, nil
+}
+
+// occurrenceAt 检查 recurrenceID 是否为重复日历项的一个实例，返回实例的开始时间
+func occurrenceAt(master *CalendarItem, recurrenceID time.Time) (time.Time, bool) {
+	starts, err := master.OccurrenceStarts(recurrenceID, recurrenceID)
+	if err != nil || len(starts) == 0 {
+		return time.Time{}, false
+	}
+	return starts[0], true
+}
+
+// copyCalendarItem 基于主日历项创建一个待入库的副本，开始时间平移到 start，提醒和参与者一并复制
+func copyCalendarItem(master *CalendarItem, start time.Time) *CalendarItem {
+	item := newOccurrence(master, start)
+	item.ID = 0
+	item.CreatedAt = time.Time{}
+	item.UpdatedAt = time.Time{}
+	item.RecurrenceID = nil
+	item.Master = nil
+
+	item.Alarms = make([]Valarm, 0, len(master.Alarms))
+	for _, alarm := range master.Alarms {
+		alarm.ID = 0
+		alarm.CalendarItemID = 0
+		alarm.CreatedAt = time.Time{}
+		alarm.UpdatedAt = time.Time{}
+		item.Alarms = append(item.Alarms, alarm)
+	}
+
+	item.Attendees = make([]Attendee, 0, len(master.Attendees))
+	for _, attendee := range master.Attendees {
+		attendee.ID = 0
+		attendee.CalendarItemID = 0
+		attendee.CreatedAt = time.Time{}
+		attendee.UpdatedAt = time.Time{}
+		item.Attendees = append(item.Attendees, attendee)
+	}
+	return item
+}
+
+// splitInstanceValues 按时间点拆分 RDATE/EXDATE 列表，无法解析的值保留在前半部分
+func splitInstanceValues(values StringArray, dtstart, at time.Time) (before, after StringArray) {
+	for _, value := range values {
+		t, err := parseInstanceValue(value, dtstart)
+		if err == nil && !t.Before(at) {
+			after = append(after, value)
+		} else {
+			before = append(before, value)
+		}
+	}
+	return before, after
 }
 
 // DeleteCalendarItem 删除日历项
+// 删除重复日历项的主日历项时，一并删除其例外实例
 func (s *service) DeleteCalendarItem(userID *uint, id uint) error {
+	item, err := s.repo.GetCalendarItemByID(userID, id)
+	if err != nil {
+		return ErrCalendarItemNotFound
+	}
+
 	if err := s.repo.DeleteCalendarItem(userID, id); err != nil {
 		return ErrCalendarItemNotFound
 	}
+
+	if item.RecurrenceID == nil && item.IsRecurring() {
+		if err := s.repo.DeleteCalendarItemOverrides(userID, item.UID); err != nil {
+			return fmt.Errorf("删除例外实例失败: %w", err)
+		}
+	}
+	s.emit(EventCalendarItemDeleted, userID, item)
 	return nil
 }
 
 // ListCalendarItems 列出日历项
 func (s *service) ListCalendarItems(userID *uint, req *ListCalendarItemsRequest) (*CalendarItemListResponse, error) {
+	sortKey := req.Sort
+	if sortKey == "" {
+		sortKey = SortByDtStart
+	}
+	if !isValidSortKey(sortKey) || sortKey == SortByRelevance {
+		return nil, fmt.Errorf("%w: 不支持的排序字段 %s", ErrInvalidInput, sortKey)
+	}
+	var cursor *PageCursor
+	if req.Cursor != "" {
+		var err error
+		if cursor, err = parsePageCursor(req.Cursor, sortKey); err != nil {
+			return nil, err
+		}
+	}
+
 	// 设置默认分页参数
 	page := req.Page
 	if page < 1 {
@@ -417,8 +842,46 @@ func (s *service) ListCalendarItems(userID *uint, req *ListCalendarItemsRequest)
 	}
 
 	offset := (page - 1) * pageSize
+	if cursor != nil {
+		page = 0
+	}
+
+	// 指定完整时间窗口时展开重复日历项，在内存中分页
+	if req.StartTime != nil && req.EndTime != nil {
+		if sortKey != SortByDtStart {
+			return nil, fmt.Errorf("%w: 展开重复日历项时只能按开始时间排序", ErrInvalidInput)
+		}
+
+		items, err := s.repo.ListCalendarItemsInRange(userID, *req.StartTime, *req.EndTime, req.Type)
+		if err != nil {
+			return nil, fmt.Errorf("获取日历项列表失败: %w", err)
+		}
+
+		overridden, err := s.overriddenInstances(userID, items)
+		if err != nil {
+			return nil, fmt.Errorf("获取例外实例失败: %w", err)
+		}
 
-	items, total, err := s.repo.ListCalendarItems(userID, req.StartTime, req.EndTime, req.Type, offset, pageSize)
+		occurrences := expandCalendarItems(items, overridden, *req.StartTime, *req.EndTime)
+		total := len(occurrences)
+		pageItems, next := pageOccurrences(occurrences, cursor, offset, pageSize)
+
+		return &CalendarItemListResponse{
+			Items:      pageItems,
+			Total:      int64(total),
+			Page:       page,
+			PageSize:   pageSize,
+			TotalPages: (total + pageSize - 1) / pageSize,
+			NextCursor: formatPageCursor(next),
+		}, nil
+	}
+
+	items, total, next, err := s.repo.ListCalendarItems(userID, req.StartTime, req.EndTime, req.Type, PageRequest{
+		Sort:   sortKey,
+		Cursor: cursor,
+		Offset: offset,
+		Limit:  pageSize,
+	})
 	if err != nil {
 		return nil, fmt.Errorf("获取日历项列表失败: %w", err)
 	}
@@ -431,11 +894,25 @@ func (s *service) ListCalendarItems(userID *uint, req *ListCalendarItemsRequest)
 		Page:       page,
 		PageSize:   pageSize,
 		TotalPages: totalPages,
+		NextCursor: formatPageCursor(next),
 	}, nil
 }
 
-// SearchCalendarItems 搜索日历项（按匹配程度排序）
-func (s *service) SearchCalendarItems(userID *uint, req *SearchCalendarItemsRequest) ([]*CalendarItem, error) {
+// ListDueTodos 列出在 [startTime, endTime) 内到期且未完成的待办
+func (s *service) ListDueTodos(userID *uint, startTime, endTime time.Time) ([]*CalendarItem, error) {
+	if !endTime.After(startTime) {
+		return nil, fmt.Errorf("%w: 结束时间必须晚于开始时间", ErrInvalidInput)
+	}
+
+	items, err := s.repo.ListDueTodos(userID, startTime, endTime)
+	if err != nil {
+		return nil, fmt.Errorf("获取到期待办失败: %w", err)
+	}
+	return items, nil
+}
+
+// SearchCalendarItems 搜索日历项，有关键字时默认按匹配程度排序
+func (s *service) SearchCalendarItems(userID *uint, req *SearchCalendarItemsRequest) (*SearchCalendarItemsResponse, error) {
 	// 准备搜索关键字
 	var q string
 	if req.Q != nil {
@@ -461,6 +938,13 @@ func (s *service) SearchCalendarItems(userID *uint, req *SearchCalendarItemsRequ
 	if q == "" && len(timeRanges) == 0 {
 		return nil, fmt.Errorf("%w: 至少需要指定搜索关键字(q)或时间范围", ErrInvalidInput)
 	}
+	var query *searchQuery
+	if q != "" {
+		var err error
+		if query, err = parseSearchQuery(q); err != nil {
+			return nil, fmt.Errorf("%w: 搜索关键字中没有可搜索的文字", err)
+		}
+	}
 
 	// 验证并设置返回数量限制
 	limit := 20 // 默认值
@@ -475,19 +959,179 @@ func (s *service) SearchCalendarItems(userID *uint, req *SearchCalendarItemsRequ
 		}
 	}
 
+	// 验证排序字段和游标
+	expand := req.DtStart != nil && req.DtStart.Start != nil
+	sortKey := SortByDtStart
+	if q != "" && !expand {
+		sortKey = SortByRelevance
+	}
+	if req.Sort != nil && *req.Sort != "" {
+		sortKey = *req.Sort
+	}
+	if !isValidSortKey(sortKey) {
+		return nil, fmt.Errorf("%w: 不支持的排序字段 %s", ErrInvalidInput, sortKey)
+	}
+	if sortKey == SortByRelevance && q == "" {
+		return nil, fmt.Errorf("%w: 按相关度排序时需要指定搜索关键字(q)", ErrInvalidInput)
+	}
+	if expand && sortKey != SortByDtStart {
+		return nil, fmt.Errorf("%w: 指定开始时间下限时重复日历项会被展开，只能按开始时间排序", ErrInvalidInput)
+	}
+	var cursor *PageCursor
+	if req.Cursor != nil && *req.Cursor != "" {
+		var err error
+		if cursor, err = parsePageCursor(*req.Cursor, sortKey); err != nil {
+			return nil, err
+		}
+	}
+
+	// 指定了开始时间下限时，查询范围内的全部日历项，将重复日历项展开为范围内的实例后在内存中分页
+	if expand {
+		dtStart := *req.DtStart
+		if dtStart.End == nil {
+			end := dtStart.Start.Add(searchExpandHorizon)
+			dtStart.End = &end
+		}
+		timeRanges["dtstart"] = dtStart
+
+		items, _, err := s.repo.SearchCalendarItems(userID, q, timeRanges, PageRequest{Sort: SortByDtStart})
+		if err != nil {
+			return nil, fmt.Errorf("搜索日历项失败: %w", err)
+		}
+
+		overridden, err := s.overriddenInstances(userID, items)
+		if err != nil {
+			return nil, fmt.Errorf("获取例外实例失败: %w", err)
+		}
+		items, next := pageOccurrences(expandSearchResults(items, overridden, dtStart), cursor, 0, limit)
+		return newSearchResponse(items, next, query), nil
+	}
+
 	// 调用Repository层进行搜索
-	items, err := s.repo.SearchCalendarItems(userID, q, timeRanges, limit)
+	items, next, err := s.repo.SearchCalendarItems(userID, q, timeRanges, PageRequest{Sort: sortKey, Cursor: cursor, Limit: limit})
 	if err != nil {
 		return nil, fmt.Errorf("搜索日历项失败: %w", err)
 	}
 
-	return items, nil
+	return newSearchResponse(items, next, query), nil
+}
+
+// newSearchResponse 构建搜索响应，有关键字时为每个日历项生成高亮片段
+func newSearchResponse(items []*CalendarItem, next *PageCursor, query *searchQuery) *SearchCalendarItemsResponse {
+	if query != nil {
+		for _, item := range items {
+			query.highlight(item)
+		}
+	}
+	return &SearchCalendarItemsResponse{Items: items, Count: len(items), NextCursor: formatPageCursor(next)}
 }
 
-// CreateValarm 创建提醒
-func (s *service) CreateValarm(calendarItemID uint, req *CreateValarmRequest) (*Valarm, error) {
-	// 验证日历项是否存在（不验证用户ID，因为创建提醒时可能不需要用户验证）
-	_, err := s.repo.GetCalendarItemByID(nil, calendarItemID)
+// searchExpandHorizon 搜索只指定开始时间下限时，重复日历项向后展开的时间跨度
+const searchExpandHorizon = 366 * 24 * time.Hour
+
+// overriddenInstances 查询重复日历项已有例外实例的 RECURRENCE-ID，按 UID 分组
+// 展开时这些实例由例外实例代替
+func (s *service) overriddenInstances(userID *uint, items []*CalendarItem) (map[string][]time.Time, error) {
+	var uids []string
+	for _, item := range items {
+		if item.IsRecurring() {
+			uids = append(uids, item.UID)
+		}
+	}
+	if len(uids) == 0 {
+		return nil, nil
+	}
+
+	overrides, err := s.repo.ListCalendarItemOverrides(userID, uids)
+	if err != nil {
+		return nil, err
+	}
+
+	overridden := make(map[string][]time.Time, len(overrides))
+	for _, override := range overrides {
+		overridden[override.UID] = append(overridden[override.UID], *override.RecurrenceID)
+	}
+	return overridden, nil
+}
+
+// isOverridden 实例开始时间是否已被例外实例代替
+func isOverridden(recurrenceIDs []time.Time, start time.Time) bool {
+	for _, id := range recurrenceIDs {
+		if id.Equal(start) {
+			return true
+		}
+	}
+	return false
+}
+
+// expandCalendarItems 将日历项展开为与 [start, end] 重叠的实例，并按开始时间排序
+// 已被例外实例代替的实例会被跳过；重复规则无法解析的日历项按普通日历项处理
+func expandCalendarItems(items []*CalendarItem, overridden map[string][]time.Time, start, end time.Time) []*CalendarItem {
+	result := make([]*CalendarItem, 0, len(items))
+	for _, item := range items {
+		expanded, err := ExpandCalendarItem(item, start, end)
+		if err != nil {
+			slog.Warn("展开重复日历项失败，按普通日历项处理", "id", item.ID, "error", err)
+			if !item.DtStart.After(end) && (item.DtEnd == nil || !item.DtEnd.Before(start)) {
+				result = append(result, item)
+			}
+			continue
+		}
+		for _, occ := range expanded {
+			if occ.Master != nil && isOverridden(overridden[item.UID], occ.DtStart) {
+				continue
+			}
+			result = append(result, occ)
+		}
+	}
+	sortByDtStart(result)
+	return result
+}
+
+// expandSearchResults 将搜索结果中的重复日历项展开为开始时间落在 timeRange 内的实例
+func expandSearchResults(items []*CalendarItem, overridden map[string][]time.Time, timeRange TimeRange) []*CalendarItem {
+	from := *timeRange.Start
+	to := from.Add(searchExpandHorizon)
+	if timeRange.End != nil {
+		to = *timeRange.End
+	}
+
+	result := make([]*CalendarItem, 0, len(items))
+	for _, item := range items {
+		if !item.IsRecurring() {
+			result = append(result, item)
+			continue
+		}
+		starts, err := item.OccurrenceStarts(from, to)
+		if err != nil {
+			slog.Warn("展开重复日历项失败，按普通日历项处理", "id", item.ID, "error", err)
+			if !item.DtStart.Before(from) {
+				result = append(result, item)
+			}
+			continue
+		}
+		for _, start := range starts {
+			if isOverridden(overridden[item.UID], start) {
+				continue
+			}
+			result = append(result, newOccurrence(item, start))
+		}
+	}
+
+	sortByDtStart(result)
+	return result
+}
+
+// sortByDtStart 按开始时间排序（稳定排序，保持同一时间日历项的原有顺序）
+func sortByDtStart(items []*CalendarItem) {
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DtStart.Before(items[j].DtStart)
+	})
+}
+
+// CreateValarm 创建提醒，日历项必须属于该用户
+func (s *service) CreateValarm(userID *uint, calendarItemID uint, req *CreateValarmRequest) (*Valarm, error) {
+	_, err := s.repo.GetCalendarItemByID(userID, calendarItemID)
 	if err != nil {
 		return nil, ErrCalendarItemNotFound
 	}
@@ -502,6 +1146,13 @@ func (s *service) CreateValarm(calendarItemID uint, req *CreateValarmRequest) (*
 		return nil, fmt.Errorf("%w: DISPLAY 类型需要 description", ErrInvalidInput)
 	}
 
+	if err := validateTrigger(req.Trigger); err != nil {
+		return nil, err
+	}
+	if err := validateAlarmDuration(req.Duration); err != nil {
+		return nil, err
+	}
+
 	alarm := &Valarm{
 		CalendarItemID: calendarItemID,
 		Action:         req.Action,
@@ -524,19 +1175,28 @@ func (s *service) CreateValarm(calendarItemID uint, req *CreateValarmRequest) (*
 	return alarm, nil
 }
 
-// GetValarmByID 根据ID获取提醒
-func (s *service) GetValarmByID(id uint) (*Valarm, error) {
+// GetValarmByID 根据ID获取日历项的提醒
+func (s *service) GetValarmByID(userID *uint, calendarItemID uint, id uint) (*Valarm, error) {
+	return s.getOwnedValarm(userID, calendarItemID, id)
+}
+
+// getOwnedValarm 获取提醒并验证其属于该用户的日历项
+// 日历项不属于该用户时返回 ErrCalendarItemNotFound，提醒不属于该日历项时返回 ErrValarmNotFound
+func (s *service) getOwnedValarm(userID *uint, calendarItemID uint, id uint) (*Valarm, error) {
+	if _, err := s.repo.GetCalendarItemByID(userID, calendarItemID); err != nil {
+		return nil, ErrCalendarItemNotFound
+	}
+
 	alarm, err := s.repo.GetValarmByID(id)
-	if err != nil {
+	if err != nil || alarm.CalendarItemID != calendarItemID {
 		return nil, ErrValarmNotFound
 	}
 	return alarm, nil
 }
 
-// GetValarmsByCalendarItemID 根据日历项ID获取所有提醒
-func (s *service) GetValarmsByCalendarItemID(calendarItemID uint) ([]*Valarm, error) {
-	// 验证日历项是否存在（不验证用户ID，因为获取提醒时可能不需要用户验证）
-	_, err := s.repo.GetCalendarItemByID(nil, calendarItemID)
+// GetValarmsByCalendarItemID 根据日历项ID获取所有提醒，日历项必须属于该用户
+func (s *service) GetValarmsByCalendarItemID(userID *uint, calendarItemID uint) ([]*Valarm, error) {
+	_, err := s.repo.GetCalendarItemByID(userID, calendarItemID)
 	if err != nil {
 		return nil, ErrCalendarItemNotFound
 	}
@@ -550,10 +1210,10 @@ func (s *service) GetValarmsByCalendarItemID(calendarItemID uint) ([]*Valarm, er
 }
 
 // UpdateValarm 更新提醒
-func (s *service) UpdateValarm(id uint, req *UpdateValarmRequest) (*Valarm, error) {
-	alarm, err := s.repo.GetValarmByID(id)
+func (s *service) UpdateValarm(userID *uint, calendarItemID uint, id uint, req *UpdateValarmRequest) (*Valarm, error) {
+	alarm, err := s.getOwnedValarm(userID, calendarItemID, id)
 	if err != nil {
-		return nil, ErrValarmNotFound
+		return nil, err
 	}
 
 	if req.Action != nil {
@@ -563,6 +1223,9 @@ func (s *service) UpdateValarm(id uint, req *UpdateValarmRequest) (*Valarm, erro
 		alarm.Action = *req.Action
 	}
 	if req.Trigger != nil {
+		if err := validateTrigger(*req.Trigger); err != nil {
+			return nil, err
+		}
 		alarm.Trigger = *req.Trigger
 	}
 	if req.Description != nil {
@@ -575,6 +1238,9 @@ func (s *service) UpdateValarm(id uint, req *UpdateValarmRequest) (*Valarm, erro
 		alarm.Attendee = req.Attendee
 	}
 	if req.Duration != nil {
+		if err := validateAlarmDuration(req.Duration); err != nil {
+			return nil, err
+		}
 		alarm.Duration = req.Duration
 	}
 	if req.RepeatCount != nil {
@@ -584,6 +1250,10 @@ func (s *service) UpdateValarm(id uint, req *UpdateValarmRequest) (*Valarm, erro
 		alarm.XProperty = JSONB(req.XProperty)
 	}
 
+	if alarm.Action == ValarmActionDisplay && (alarm.Description == nil || *alarm.Description == "") {
+		return nil, fmt.Errorf("%w: DISPLAY 类型需要 description", ErrInvalidInput)
+	}
+
 	if err := s.repo.UpdateValarm(alarm); err != nil {
 		return nil, fmt.Errorf("更新提醒失败: %w", err)
 	}
@@ -592,10 +1262,9 @@ func (s *service) UpdateValarm(id uint, req *UpdateValarmRequest) (*Valarm, erro
 }
 
 // DeleteValarm 删除提醒
-func (s *service) DeleteValarm(id uint) error {
-	_, err := s.repo.GetValarmByID(id)
-	if err != nil {
-		return ErrValarmNotFound
+func (s *service) DeleteValarm(userID *uint, calendarItemID uint, id uint) error {
+	if _, err := s.getOwnedValarm(userID, calendarItemID, id); err != nil {
+		return err
 	}
 
 	if err := s.repo.DeleteValarm(id); err != nil {
@@ -605,6 +1274,32 @@ func (s *service) DeleteValarm(id uint) error {
 	return nil
 }
 
+// validateTrigger 验证提醒触发时间（RFC 5545 3.8.6.3）：相对时长（例如 "-PT15M"）或 UTC 的绝对日期时间
+func validateTrigger(trigger string) error {
+	trigger = strings.TrimSpace(trigger)
+	if _, err := ParseDuration(trigger); err == nil {
+		return nil
+	}
+	if _, err := time.Parse(icalDateTimeUTCLayout, trigger); err == nil {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, trigger); err == nil {
+		return nil
+	}
+	return fmt.Errorf("%w: trigger %q 必须是持续时间（例如 -PT15M）或绝对日期时间（例如 20250106T090000Z）", ErrInvalidInput, trigger)
+}
+
+// validateAlarmDuration 验证提醒重复间隔（RFC 5545 3.8.2.5）
+func validateAlarmDuration(duration *string) error {
+	if duration == nil || *duration == "" {
+		return nil
+	}
+	if _, err := ParseDuration(*duration); err != nil {
+		return fmt.Errorf("%w: duration %q 不是有效的持续时间", ErrInvalidInput, *duration)
+	}
+	return nil
+}
+
 // isValidCalendarItemType 验证日历项类型
 func isValidCalendarItemType(t CalendarItemType) bool {
 	return t == CalendarItemTypeEvent || t == CalendarItemTypeTodo ||
@@ -631,8 +1326,10 @@ func validateCalendarItemRequest(req *CreateCalendarItemRequest) error {
 		}
 		// DTEND 或 DURATION 至少一个，但不能同时存在
 		hasDtEnd := req.DtEnd != nil
+		// 全天事件可以都不指定，默认持续一天
 		hasDuration := req.Duration != nil && *req.Duration != ""
-		if !hasDtEnd && !hasDuration {
+		allDay := req.AllDay != nil && *req.AllDay
+		if !hasDtEnd && !hasDuration && !allDay {
 			return fmt.Errorf("%w: VEVENT 类型需要 dtend 或 duration 至少一个", ErrInvalidInput)
 		}
 		if hasDtEnd && hasDuration {
@@ -661,5 +1358,25 @@ func validateCalendarItemRequest(req *CreateCalendarItemRequest) error {
 		}
 	}
 
+	return validateRecurrence(req.RRule, req.ExDate, req.RDate)
+}
+
+// validateRecurrence 验证 RRULE、EXDATE、RDATE 的格式
+func validateRecurrence(rrule *string, exDate, rDate []string) error {
+	if rrule != nil && strings.TrimSpace(*rrule) != "" {
+		if _, err := ParseRRule(*rrule); err != nil {
+			return fmt.Errorf("%w: %v", ErrInvalidInput, err)
+		}
+	}
+	for _, value := range exDate {
+		if _, _, err := parseDateTimeValue(value, time.UTC); err != nil {
+			return fmt.Errorf("%w: exdate %v", ErrInvalidInput, err)
+		}
+	}
+	for _, value := range rDate {
+		if _, err := parseInstanceValue(value, time.Time{}); err != nil {
+			return fmt.Errorf("%w: rdate %v", ErrInvalidInput, err)
+		}
+	}
 	return nil
 }
@@ -1,19 +1,25 @@
 package calendar
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 )
 
-// mockRepository 是 Repository 接口的 mock 实现
+// mockRepository 是 Repository 接口的 mock 实现，Transaction 直接使用自身
 type mockRepository struct {
 	mock.Mock
 }
 
+func (m *mockRepository) Transaction(fn func(repo Repository) error) error {
+	return fn(m)
+}
+
 func (m *mockRepository) CreateCalendarItem(item *CalendarItem) error {
 	args := m.Called(item)
 	return args.Error(0)
@@ -45,12 +51,118 @@ func (m *mockRepository) DeleteCalendarItem(userID *uint, id uint) error {
 	return args.Error(0)
 }
 
-func (m *mockRepository) ListCalendarItems(userID *uint, startTime, endTime *time.Time, itemType *CalendarItemType, offset, limit int) ([]*CalendarItem, int64, error) {
-	args := m.Called(userID, startTime, endTime, itemType, offset, limit)
+func (m *mockRepository) ListCalendarItems(userID *uint, startTime, endTime *time.Time, itemType *CalendarItemType, page PageRequest) ([]*CalendarItem, int64, *PageCursor, error) {
+	args := m.Called(userID, startTime, endTime, itemType, page)
+	cursor, _ := args.Get(2).(*PageCursor)
+	if args.Get(0) == nil {
+		return nil, args.Get(1).(int64), cursor, args.Error(3)
+	}
+	return args.Get(0).([]*CalendarItem), args.Get(1).(int64), cursor, args.Error(3)
+}
+
+func (m *mockRepository) ListCalendarItemsInRange(userID *uint, startTime, endTime time.Time, itemType *CalendarItemType) ([]*CalendarItem, error) {
+	args := m.Called(userID, startTime, endTime, itemType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*CalendarItem), args.Error(1)
+}
+
+func (m *mockRepository) ListDueTodos(userID *uint, startTime, endTime time.Time) ([]*CalendarItem, error) {
+	args := m.Called(userID, startTime, endTime)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*CalendarItem), args.Error(1)
+}
+
+func (m *mockRepository) GetCalendarItemOverride(userID *uint, uid string, recurrenceID time.Time) (*CalendarItem, error) {
+	args := m.Called(userID, uid, recurrenceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CalendarItem), args.Error(1)
+}
+
+func (m *mockRepository) ListCalendarItemOverrides(userID *uint, uids []string) ([]*CalendarItem, error) {
+	args := m.Called(userID, uids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*CalendarItem), args.Error(1)
+}
+
+func (m *mockRepository) ReassignCalendarItemOverrides(userID *uint, uid, newUID string, from time.Time) error {
+	args := m.Called(userID, uid, newUID, from)
+	return args.Error(0)
+}
+
+func (m *mockRepository) DeleteCalendarItemOverrides(userID *uint, uid string) error {
+	args := m.Called(userID, uid)
+	return args.Error(0)
+}
+
+func (m *mockRepository) ListCalendarItemsByUIDs(userID *uint, uids []string) ([]*CalendarItem, error) {
+	args := m.Called(userID, uids)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*CalendarItem), args.Error(1)
+}
+
+func (m *mockRepository) GetCalendarObjectUIDs(userID *uint, names []string) ([]string, error) {
+	args := m.Called(userID, names)
 	if args.Get(0) == nil {
-		return nil, args.Get(1).(int64), args.Error(2)
+		return nil, args.Error(1)
 	}
-	return args.Get(0).([]*CalendarItem), args.Get(1).(int64), args.Error(2)
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *mockRepository) ListChangedCalendarObjectNames(userID *uint, since time.Time) ([]string, error) {
+	args := m.Called(userID, since)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+func (m *mockRepository) GetLatestCalendarItemChange(userID *uint) (*time.Time, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*time.Time), args.Error(1)
+}
+
+func (m *mockRepository) CreateFeedToken(token *CalendarFeedToken) error {
+	args := m.Called(token)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetFeedToken(token string) (*CalendarFeedToken, error) {
+	args := m.Called(token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*CalendarFeedToken), args.Error(1)
+}
+
+func (m *mockRepository) ListFeedTokens(userID uint) ([]*CalendarFeedToken, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*CalendarFeedToken), args.Error(1)
+}
+
+func (m *mockRepository) RevokeFeedToken(userID uint, id uint) error {
+	args := m.Called(userID, id)
+	return args.Error(0)
+}
+
+func (m *mockRepository) TouchFeedToken(id uint, accessedAt time.Time) error {
+	args := m.Called(id, accessedAt)
+	return args.Error(0)
 }
 
 func (m *mockRepository) CreateValarm(alarm *Valarm) error {
@@ -89,6 +201,37 @@ func (m *mockRepository) DeleteValarmsByCalendarItemID(calendarItemID uint) erro
 	return args.Error(0)
 }
 
+func (m *mockRepository) CreateAttendee(attendee *Attendee) error {
+	args := m.Called(attendee)
+	return args.Error(0)
+}
+
+func (m *mockRepository) GetAttendeeByID(id uint) (*Attendee, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Attendee), args.Error(1)
+}
+
+func (m *mockRepository) GetAttendeesByCalendarItemID(calendarItemID uint) ([]*Attendee, error) {
+	args := m.Called(calendarItemID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]*Attendee), args.Error(1)
+}
+
+func (m *mockRepository) UpdateAttendee(attendee *Attendee) error {
+	args := m.Called(attendee)
+	return args.Error(0)
+}
+
+func (m *mockRepository) DeleteAttendeesByCalendarItemID(calendarItemID uint) error {
+	args := m.Called(calendarItemID)
+	return args.Error(0)
+}
+
 func (m *mockRepository) SearchCalendarItemsByKeyword(userID *uint, fields []string, keyword string) ([]*CalendarItem, error) {
 	args := m.Called(userID, fields, keyword)
 	if args.Get(0) == nil {
@@ -97,12 +240,13 @@ func (m *mockRepository) SearchCalendarItemsByKeyword(userID *uint, fields []str
 	return args.Get(0).([]*CalendarItem), args.Error(1)
 }
 
-func (m *mockRepository) SearchCalendarItems(userID *uint, q string, timeRanges map[string]TimeRange, limit int) ([]*CalendarItem, error) {
-	args := m.Called(userID, q, timeRanges, limit)
+func (m *mockRepository) SearchCalendarItems(userID *uint, q string, timeRanges map[string]TimeRange, page PageRequest) ([]*CalendarItem, *PageCursor, error) {
+	args := m.Called(userID, q, timeRanges, page)
+	cursor, _ := args.Get(1).(*PageCursor)
 	if args.Get(0) == nil {
-		return nil, args.Error(1)
+		return nil, cursor, args.Error(2)
 	}
-	return args.Get(0).([]*CalendarItem), args.Error(1)
+	return args.Get(0).([]*CalendarItem), cursor, args.Error(2)
 }
 
 // TestService_CreateCalendarItem_Success 测试创建日历项成功
@@ -137,10 +281,155 @@ func TestService_CreateCalendarItem_Success(t *testing.T) {
 	assert.NoError(t, err)
 	assert.NotNil(t, item)
 	assert.Equal(t, CalendarItemTypeEvent, item.Type)
-	assert.Equal(t, summary, *item.Summary)
+	assert.NotEmpty(t, item.UID)
 	mockRepo.AssertExpectations(t)
 }
 
+// TestService_CreateCalendarItem_TZID 测试没有指定时区时使用用户的时区，指定的时区必须有效
+func TestService_CreateCalendarItem_TZID(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo, WithUserTimezone(func(userID uint) *time.Location {
+		return shanghai
+	}))
+
+	userID := uint(1)
+	start := utcTime(2025, 1, 6, 7, 0)
+	due := utcTime(2025, 1, 6, 9, 0)
+	var created []*CalendarItem
+	mockRepo.On("CreateCalendarItem", mock.AnythingOfType("*calendar.CalendarItem")).
+		Return(nil).
+		Run(func(args mock.Arguments) {
+			created = append(created, args.Get(0).(*CalendarItem))
+		})
+
+	_, err = service.CreateCalendarItem(&userID, &CreateCalendarItemRequest{Type: CalendarItemTypeTodo, DtStart: &start, Due: &due})
+	require.NoError(t, err)
+	newYork := "America/New_York"
+	_, err = service.CreateCalendarItem(&userID, &CreateCalendarItemRequest{Type: CalendarItemTypeTodo, DtStart: &start, TZID: &newYork})
+	require.NoError(t, err)
+
+	require.Len(t, created, 2)
+	assert.Equal(t, "Asia/Shanghai", *created[0].DtStartTZID)
+	assert.Equal(t, "Asia/Shanghai", *created[0].DueTZID)
+	assert.Nil(t, created[0].DtEndTZID)
+	assert.Equal(t, 15, created[0].DtStart.Hour())
+	assert.True(t, created[0].DtStart.Equal(start))
+	assert.Equal(t, newYork, *created[1].DtStartTZID)
+	assert.Equal(t, 2, created[1].DtStart.Hour())
+
+	invalid := "Mars/Olympus"
+	_, err = service.CreateCalendarItem(&userID, &CreateCalendarItemRequest{Type: CalendarItemTypeTodo, DtStart: &start, TZID: &invalid})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
+
+// TestService_CreateCalendarItem_AllDay 测试只有日期的请求创建全天事件：按用户时区的当天零点保存，默认持续一天
+func TestService_CreateCalendarItem_AllDay(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo, WithUserTimezone(func(userID uint) *time.Location {
+		return shanghai
+	}))
+
+	userID := uint(1)
+	var created *CalendarItem
+	mockRepo.On("CreateCalendarItem", mock.AnythingOfType("*calendar.CalendarItem")).
+		Return(nil).
+		Run(func(args mock.Arguments) {
+			created = args.Get(0).(*CalendarItem)
+		})
+
+	var req CreateCalendarItemRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"type":"VEVENT","summary":"生日","dtstart":"2024-05-06","conflict_policy":"allow"}`), &req))
+	require.NotNil(t, req.AllDay)
+	assert.True(t, *req.AllDay)
+
+	_, err = service.CreateCalendarItem(&userID, &req)
+
+	require.NoError(t, err)
+	assert.True(t, created.AllDay)
+	assert.True(t, created.DtStart.Equal(time.Date(2024, 5, 6, 0, 0, 0, 0, shanghai)))
+	assert.True(t, created.DtEnd.Equal(time.Date(2024, 5, 7, 0, 0, 0, 0, shanghai)))
+	assert.Equal(t, "Asia/Shanghai", *created.DtStartTZID)
+	assert.Equal(t, "Asia/Shanghai", *created.DtEndTZID)
+}
+
+// TestUpdateCalendarItemRequest_UnmarshalJSON 测试只有日期的时间字段，以及显式指定的 all_day 不被覆盖
+func TestUpdateCalendarItemRequest_UnmarshalJSON(t *testing.T) {
+	var req UpdateCalendarItemRequest
+	require.NoError(t, json.Unmarshal([]byte(`{"summary":"假期","dtstart":"2024-05-06","dtend":"20240509","all_day":false}`), &req))
+
+	assert.Equal(t, "假期", *req.Summary)
+	assert.Equal(t, utcTime(2024, 5, 6, 0, 0), *req.DtStart)
+	assert.Equal(t, utcTime(2024, 5, 9, 0, 0), *req.DtEnd)
+	assert.False(t, *req.AllDay)
+
+	req = UpdateCalendarItemRequest{}
+	require.NoError(t, json.Unmarshal([]byte(`{"dtstart":"2024-05-06T09:00:00+08:00"}`), &req))
+	assert.Nil(t, req.AllDay)
+	assert.Error(t, json.Unmarshal([]byte(`{"dtstart":"明天"}`), &req))
+}
+
+// TestApplyUpdateRequest_AllDay 测试修改全天事件的日期：沿用原有时区，不在零点的结束时间顺延到次日
+func TestApplyUpdateRequest_AllDay(t *testing.T) {
+	tzid := "America/New_York"
+	dtEnd := utcTime(2024, 5, 7, 4, 0)
+	item := &CalendarItem{
+		Type:        CalendarItemTypeEvent,
+		DtStart:     utcTime(2024, 5, 6, 4, 0),
+		DtEnd:       &dtEnd,
+		DtStartTZID: &tzid,
+		DtEndTZID:   &tzid,
+		AllDay:      true,
+	}
+	item.localizeTimes()
+
+	start := utcTime(2024, 5, 10, 0, 0)
+	end := time.Date(2024, 5, 11, 18, 0, 0, 0, item.DtStart.Location())
+	applyUpdateRequest(item, &UpdateCalendarItemRequest{DtStart: &start, DtEnd: &end})
+
+	assert.Equal(t, utcTime(2024, 5, 10, 4, 0), item.DtStart.UTC())
+	assert.Equal(t, utcTime(2024, 5, 12, 4, 0), item.DtEnd.UTC())
+	assert.Equal(t, tzid, *item.DtEndTZID)
+}
+
+// TestService_EventListener 测试创建、更新和删除日历项后通知监听器
+func TestService_EventListener(t *testing.T) {
+	mockRepo := new(mockRepository)
+	var events []*Event
+	service := NewService(mockRepo, WithEventListener(EventListenerFunc(func(event *Event) {
+		events = append(events, event)
+	})))
+
+	userID := uint(1)
+	now := time.Now()
+	summary := "测试事件"
+	existing := &CalendarItem{ID: 1, UID: "uid-1", Type: CalendarItemTypeEvent, Summary: &summary, DtStart: now, UserID: &userID}
+
+	mockRepo.On("CreateCalendarItem", mock.AnythingOfType("*calendar.CalendarItem")).Return(nil)
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(existing, nil)
+	mockRepo.On("UpdateCalendarItem", &userID, existing).Return(nil)
+	mockRepo.On("DeleteCalendarItem", &userID, uint(1)).Return(nil)
+
+	_, err := service.CreateCalendarItem(&userID, &CreateCalendarItemRequest{Type: CalendarItemTypeEvent, Summary: &summary, DtStart: &now, DtEnd: &now})
+	require.NoError(t, err)
+	_, err = service.UpdateCalendarItem(&userID, 1, &UpdateCalendarItemRequest{Summary: &summary})
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteCalendarItem(&userID, 1))
+
+	require.Len(t, events, 3)
+	assert.Equal(t, EventCalendarItemCreated, events[0].Type)
+	assert.Equal(t, summary, *events[0].Item.Summary)
+	assert.Equal(t, EventCalendarItemUpdated, events[1].Type)
+	assert.Equal(t, EventCalendarItemDeleted, events[2].Type)
+	assert.Equal(t, "uid-1", events[2].Item.UID)
+	for _, event := range events {
+		assert.Equal(t, &userID, event.UserID)
+	}
+}
+
 // TestService_CreateCalendarItem_InvalidType 测试无效的日历项类型
 func TestService_CreateCalendarItem_InvalidType(t *testing.T) {
 	mockRepo := new(mockRepository)
@@ -309,14 +598,30 @@ func TestService_DeleteCalendarItem_Success(t *testing.T) {
 
 	userID := uint(1)
 	itemID := uint(1)
-	existingItem := &CalendarItem{
-		ID:     itemID,
-		UID:    "test-uid-123",
-		Type:   CalendarItemTypeEvent,
-		UserID: &userID,
-	}
+	existingItem := &CalendarItem{ID: itemID, UID: "test-uid", Type: CalendarItemTypeEvent}
+
+	mockRepo.On("GetCalendarItemByID", &userID, itemID).Return(existingItem, nil)
+	mockRepo.On("DeleteCalendarItem", &userID, itemID).Return(nil)
 
+	err := service.DeleteCalendarItem(&userID, itemID)
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_DeleteCalendarItem_RecurringDeletesOverrides 测试删除重复日历项时一并删除例外实例
+func TestService_DeleteCalendarItem_RecurringDeletesOverrides(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	itemID := uint(1)
+	rrule := "FREQ=DAILY"
+	existingItem := &CalendarItem{ID: itemID, UID: "daily", Type: CalendarItemTypeEvent, RRule: &rrule}
+
+	mockRepo.On("GetCalendarItemByID", &userID, itemID).Return(existingItem, nil)
 	mockRepo.On("DeleteCalendarItem", &userID, itemID).Return(nil)
+	mockRepo.On("DeleteCalendarItemOverrides", &userID, "daily").Return(nil)
 
 	err := service.DeleteCalendarItem(&userID, itemID)
 
@@ -332,7 +637,7 @@ func TestService_DeleteCalendarItem_NotFound(t *testing.T) {
 	userID := uint(1)
 	itemID := uint(999)
 
-	mockRepo.On("DeleteCalendarItem", &userID, itemID).Return(errors.New("not found"))
+	mockRepo.On("GetCalendarItemByID", &userID, itemID).Return(nil, errors.New("not found"))
 
 	err := service.DeleteCalendarItem(&userID, itemID)
 
@@ -378,8 +683,8 @@ func TestService_ListCalendarItems_Success(t *testing.T) {
 		EndTime:   &endTime,
 	}
 
-	mockRepo.On("ListCalendarItems", &userID, &startTime, &endTime, (*CalendarItemType)(nil), 0, 10).
-		Return(items, int64(2), nil)
+	mockRepo.On("ListCalendarItemsInRange", &userID, startTime, endTime, (*CalendarItemType)(nil)).
+		Return(items, nil)
 
 	result, err := service.ListCalendarItems(&userID, req)
 
@@ -403,8 +708,8 @@ func TestService_ListCalendarItems_DefaultPagination(t *testing.T) {
 		PageSize: 0, // 无效值，应该使用默认值10
 	}
 
-	mockRepo.On("ListCalendarItems", &userID, (*time.Time)(nil), (*time.Time)(nil), (*CalendarItemType)(nil), 0, 10).
-		Return([]*CalendarItem{}, int64(0), nil)
+	mockRepo.On("ListCalendarItems", &userID, (*time.Time)(nil), (*time.Time)(nil), (*CalendarItemType)(nil), PageRequest{Sort: SortByDtStart, Limit: 10}).
+		Return([]*CalendarItem{}, int64(0), nil, nil)
 
 	result, err := service.ListCalendarItems(&userID, req)
 
@@ -415,11 +720,301 @@ func TestService_ListCalendarItems_DefaultPagination(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+// TestService_ListCalendarItems_ExpandRecurring 测试列出日历项时展开重复日历项
+func TestService_ListCalendarItems_ExpandRecurring(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	dtStart := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	dtEnd := dtStart.Add(time.Hour)
+	rrule := "FREQ=DAILY;COUNT=10"
+	single := time.Date(2025, 1, 7, 12, 0, 0, 0, time.UTC)
+
+	items := []*CalendarItem{
+		{ID: 1, UID: "daily", Type: CalendarItemTypeEvent, DtStart: dtStart, DtEnd: &dtEnd, RRule: &rrule, UserID: &userID},
+		{ID: 2, UID: "single", Type: CalendarItemTypeEvent, DtStart: single, UserID: &userID},
+	}
+
+	startTime := time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2025, 1, 9, 23, 59, 59, 0, time.UTC)
+	req := &ListCalendarItemsRequest{
+		Page:      1,
+		PageSize:  2,
+		StartTime: &startTime,
+		EndTime:   &endTime,
+	}
+
+	mockRepo.On("ListCalendarItemsInRange", &userID, startTime, endTime, (*CalendarItemType)(nil)).
+		Return(items, nil)
+	mockRepo.On("ListCalendarItemOverrides", &userID, []string{"daily"}).Return([]*CalendarItem{}, nil)
+
+	result, err := service.ListCalendarItems(&userID, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(4), result.Total)
+	assert.Equal(t, 2, result.TotalPages)
+	assert.Len(t, result.Items, 2)
+	assert.Equal(t, time.Date(2025, 1, 7, 9, 0, 0, 0, time.UTC), result.Items[0].DtStart)
+	assert.NotNil(t, result.Items[0].RecurrenceID)
+	assert.Equal(t, "single", result.Items[1].UID)
+	assert.Nil(t, result.Items[1].RecurrenceID)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_ListCalendarItems_Cursor 测试展开后的实例使用游标分页：第二页从上一页最后一个实例之后继续
+func TestService_ListCalendarItems_Cursor(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	dtStart := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	rrule := "FREQ=DAILY;COUNT=3"
+	items := []*CalendarItem{{ID: 1, UID: "daily", Type: CalendarItemTypeEvent, DtStart: dtStart, RRule: &rrule, UserID: &userID}}
+
+	startTime := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	mockRepo.On("ListCalendarItemsInRange", &userID, startTime, endTime, (*CalendarItemType)(nil)).Return(items, nil)
+	mockRepo.On("ListCalendarItemOverrides", &userID, []string{"daily"}).Return([]*CalendarItem{}, nil)
+
+	first, err := service.ListCalendarItems(&userID, &ListCalendarItemsRequest{PageSize: 2, StartTime: &startTime, EndTime: &endTime})
+
+	require.NoError(t, err)
+	require.Len(t, first.Items, 2)
+	require.NotEmpty(t, first.NextCursor)
+
+	second, err := service.ListCalendarItems(&userID, &ListCalendarItemsRequest{PageSize: 2, StartTime: &startTime, EndTime: &endTime, Cursor: first.NextCursor})
+
+	require.NoError(t, err)
+	require.Len(t, second.Items, 1)
+	assert.Equal(t, dtStart.AddDate(0, 0, 2), second.Items[0].DtStart)
+	assert.Empty(t, second.NextCursor)
+	assert.Equal(t, 0, second.Page)
+}
+
+// TestService_ListCalendarItems_Sort 测试按优先级排序：游标和排序字段传给 Repository，返回下一页的游标
+func TestService_ListCalendarItems_Sort(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	priority := 1
+	cursor := &PageCursor{Sort: SortByPriority, Num: &priority, ID: 4}
+	next := &PageCursor{Sort: SortByPriority, ID: 9}
+
+	mockRepo.On("ListCalendarItems", &userID, (*time.Time)(nil), (*time.Time)(nil), (*CalendarItemType)(nil),
+		PageRequest{Sort: SortByPriority, Cursor: cursor, Limit: 10}).
+		Return([]*CalendarItem{{ID: 9}}, int64(20), next, nil)
+
+	result, err := service.ListCalendarItems(&userID, &ListCalendarItemsRequest{Sort: SortByPriority, Cursor: formatPageCursor(cursor)})
+
+	require.NoError(t, err)
+	assert.Equal(t, formatPageCursor(next), result.NextCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_ListCalendarItems_InvalidSortOrCursor 测试无效的游标，以及展开重复日历项时按其他字段排序
+func TestService_ListCalendarItems_InvalidSortOrCursor(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	startTime := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	endTime := startTime.AddDate(0, 0, 7)
+
+	_, err := service.ListCalendarItems(&userID, &ListCalendarItemsRequest{Cursor: "bad"})
+	assert.ErrorIs(t, err, ErrInvalidCursor)
+
+	_, err = service.ListCalendarItems(&userID, &ListCalendarItemsRequest{Sort: SortByDue, StartTime: &startTime, EndTime: &endTime})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	_, err = service.ListCalendarItems(&userID, &ListCalendarItemsRequest{Sort: SortByRelevance})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	mockRepo.AssertNotCalled(t, "ListCalendarItems", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestService_CreateCalendarItem_InvalidRRule 测试创建日历项时重复规则无效
+func TestService_CreateCalendarItem_InvalidRRule(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	dtStart := time.Now()
+	duration := "PT1H"
+	rrule := "FREQ=SOMETIMES"
+	req := &CreateCalendarItemRequest{
+		Type:     CalendarItemTypeEvent,
+		DtStart:  &dtStart,
+		Duration: &duration,
+		RRule:    &rrule,
+	}
+
+	result, err := service.CreateCalendarItem(&userID, req)
+
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	mockRepo.AssertNotCalled(t, "CreateCalendarItem", mock.Anything)
+}
+
+// TestService_ListCalendarItems_SkipOverriddenOccurrence 测试展开时跳过已被例外实例代替的实例
+func TestService_ListCalendarItems_SkipOverriddenOccurrence(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	rrule := "FREQ=DAILY;COUNT=3"
+	dtStart := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	recurrenceID := dtStart.Add(24 * time.Hour)
+	moved := recurrenceID.Add(5 * time.Hour)
+	master := &CalendarItem{ID: 1, UID: "daily", Type: CalendarItemTypeEvent, DtStart: dtStart, RRule: &rrule, UserID: &userID}
+	override := &CalendarItem{ID: 2, UID: "daily", Type: CalendarItemTypeEvent, DtStart: moved, RecurrenceID: &recurrenceID, UserID: &userID}
+
+	startTime := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2025, 1, 9, 0, 0, 0, 0, time.UTC)
+	req := &ListCalendarItemsRequest{StartTime: &startTime, EndTime: &endTime}
+
+	mockRepo.On("ListCalendarItemsInRange", &userID, startTime, endTime, (*CalendarItemType)(nil)).
+		Return([]*CalendarItem{master, override}, nil)
+	mockRepo.On("ListCalendarItemOverrides", &userID, []string{"daily"}).Return([]*CalendarItem{override}, nil)
+
+	result, err := service.ListCalendarItems(&userID, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), result.Total)
+	assert.Equal(t, dtStart, result.Items[0].DtStart)
+	assert.Equal(t, uint(2), result.Items[1].ID)
+	assert.Equal(t, moved, result.Items[1].DtStart)
+	assert.Equal(t, dtStart.Add(48*time.Hour), result.Items[2].DtStart)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_UpdateCalendarItemOccurrence_This 测试修改单个实例时创建例外实例
+func TestService_UpdateCalendarItemOccurrence_This(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	summary := "每日站会"
+	newSummary := "改到下午的站会"
+	rrule := "FREQ=DAILY;COUNT=5"
+	dtStart := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	dtEnd := dtStart.Add(15 * time.Minute)
+	recurrenceID := dtStart.Add(48 * time.Hour)
+	newStart := recurrenceID.Add(6 * time.Hour)
+	newEnd := newStart.Add(15 * time.Minute)
+	seq := 2
+	master := &CalendarItem{
+		ID: 1, UID: "daily", Type: CalendarItemTypeEvent, Summary: &summary,
+		DtStart: dtStart, DtEnd: &dtEnd, RRule: &rrule, Sequence: &seq, UserID: &userID,
+		Alarms: []Valarm{{ID: 9, CalendarItemID: 1, Action: ValarmActionDisplay, Trigger: "-PT5M"}},
+	}
+	req := &UpdateCalendarItemRequest{Summary: &newSummary, DtStart: &newStart, DtEnd: &newEnd}
+
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(master, nil)
+	mockRepo.On("GetCalendarItemOverride", &userID, "daily", recurrenceID).Return(nil, errors.New("not found"))
+	// 移动实例时检查冲突，同一重复日历项的其它实例不算冲突
+	eventType := CalendarItemTypeEvent
+	mockRepo.On("ListCalendarItemsInRange", &userID, newStart, newEnd, &eventType).Return([]*CalendarItem{master}, nil)
+	mockRepo.On("ListCalendarItemOverrides", &userID, []string{"daily"}).Return([]*CalendarItem{}, nil)
+	mockRepo.On("CreateCalendarItem", mock.MatchedBy(func(item *CalendarItem) bool {
+		return item.ID == 0 && item.UID == "daily" && item.RecurrenceID != nil && item.RecurrenceID.Equal(recurrenceID) &&
+			item.RRule == nil && *item.Summary == newSummary && item.DtStart.Equal(newStart) &&
+			len(item.Alarms) == 1 && item.Alarms[0].ID == 0
+	})).Return(nil)
+
+	result, err := service.UpdateCalendarItemOccurrence(&userID, 1, recurrenceID, UpdateScopeThis, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "daily", result.UID)
+	assert.Empty(t, result.Conflicts)
+	assert.Equal(t, 2, *master.Sequence)
+	assert.Equal(t, summary, *master.Summary)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_UpdateCalendarItemOccurrence_ThisAndFollowing 测试修改此实例及之后时拆分重复规则
+func TestService_UpdateCalendarItemOccurrence_ThisAndFollowing(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	location := "会议室C"
+	rrule := "FREQ=DAILY;COUNT=5"
+	resourceName := "3f9c2b1e.ics"
+	dtStart := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	recurrenceID := dtStart.Add(48 * time.Hour)
+	master := &CalendarItem{
+		ID: 1, UID: "daily", Type: CalendarItemTypeEvent, DtStart: dtStart, RRule: &rrule, UserID: &userID,
+		ExDate: StringArray{"20250107T090000Z", "20250109T090000Z"}, ResourceName: &resourceName,
+	}
+	req := &UpdateCalendarItemRequest{Location: &location}
+
+	var newUID string
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(master, nil)
+	mockRepo.On("CreateCalendarItem", mock.MatchedBy(func(item *CalendarItem) bool {
+		newUID = item.UID
+		return item.UID != "daily" && *item.RelatedTo == "daily" && item.DtStart.Equal(recurrenceID) && item.ResourceName == nil &&
+			*item.RRule == "FREQ=DAILY;COUNT=3" && *item.Location == location &&
+			len(item.ExDate) == 1 && item.ExDate[0] == "20250109T090000Z"
+	})).Return(nil)
+	mockRepo.On("ReassignCalendarItemOverrides", &userID, "daily", mock.AnythingOfType("string"), recurrenceID).Return(nil)
+	mockRepo.On("UpdateCalendarItem", &userID, mock.MatchedBy(func(item *CalendarItem) bool {
+		return item.ID == 1 && *item.RRule == "FREQ=DAILY;UNTIL=20250108T085959Z" &&
+			len(item.ExDate) == 1 && item.ExDate[0] == "20250107T090000Z"
+	})).Return(nil)
+
+	result, err := service.UpdateCalendarItemOccurrence(&userID, 1, recurrenceID, UpdateScopeThisAndFollowing, req)
+
+	assert.NoError(t, err)
+	assert.Equal(t, newUID, result.UID)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_UpdateCalendarItemOccurrence_NotOccurrence 测试修改不存在的实例
+func TestService_UpdateCalendarItemOccurrence_NotOccurrence(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	rrule := "FREQ=WEEKLY"
+	dtStart := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	recurrenceID := dtStart.Add(24 * time.Hour)
+	master := &CalendarItem{ID: 1, UID: "weekly", DtStart: dtStart, RRule: &rrule, UserID: &userID}
+
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(master, nil)
+	mockRepo.On("GetCalendarItemOverride", &userID, "weekly", recurrenceID).Return(nil, errors.New("not found"))
+
+	result, err := service.UpdateCalendarItemOccurrence(&userID, 1, recurrenceID, UpdateScopeThis, &UpdateCalendarItemRequest{})
+
+	assert.Nil(t, result)
+	assert.Equal(t, ErrOccurrenceNotFound, err)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_UpdateCalendarItemOccurrence_NotRecurring 测试修改非重复日历项的实例
+func TestService_UpdateCalendarItemOccurrence_NotRecurring(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	dtStart := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	item := &CalendarItem{ID: 1, UID: "single", DtStart: dtStart, UserID: &userID}
+
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(item, nil)
+
+	result, err := service.UpdateCalendarItemOccurrence(&userID, 1, dtStart, UpdateScopeAll, &UpdateCalendarItemRequest{})
+
+	assert.Nil(t, result)
+	assert.Equal(t, ErrNotRecurring, err)
+	mockRepo.AssertExpectations(t)
+}
+
 // TestService_CreateValarm_Success 测试创建提醒成功
 func TestService_CreateValarm_Success(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	calendarItemID := uint(1)
 	description := "提醒内容"
 	req := &CreateValarmRequest{
@@ -434,7 +1029,7 @@ func TestService_CreateValarm_Success(t *testing.T) {
 		Type: CalendarItemTypeEvent,
 	}
 
-	mockRepo.On("GetCalendarItemByID", mock.AnythingOfType("*uint"), calendarItemID).Return(existingItem, nil)
+	mockRepo.On("GetCalendarItemByID", &userID, calendarItemID).Return(existingItem, nil)
 	mockRepo.On("CreateValarm", mock.AnythingOfType("*calendar.Valarm")).
 		Return(nil).
 		Run(func(args mock.Arguments) {
@@ -445,7 +1040,7 @@ func TestService_CreateValarm_Success(t *testing.T) {
 			assert.Equal(t, description, *alarm.Description)
 		})
 
-	alarm, err := service.CreateValarm(calendarItemID, req)
+	alarm, err := service.CreateValarm(&userID, calendarItemID, req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, alarm)
@@ -453,11 +1048,12 @@ func TestService_CreateValarm_Success(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
-// TestService_CreateValarm_CalendarItemNotFound 测试日历项不存在
+// TestService_CreateValarm_CalendarItemNotFound 测试日历项不存在或不属于该用户
 func TestService_CreateValarm_CalendarItemNotFound(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(2)
 	calendarItemID := uint(999)
 	description := "提醒内容"
 	req := &CreateValarmRequest{
@@ -466,9 +1062,9 @@ func TestService_CreateValarm_CalendarItemNotFound(t *testing.T) {
 		Description: &description,
 	}
 
-	mockRepo.On("GetCalendarItemByID", calendarItemID).Return(nil, errors.New("not found"))
+	mockRepo.On("GetCalendarItemByID", &userID, calendarItemID).Return(nil, errors.New("not found"))
 
-	alarm, err := service.CreateValarm(calendarItemID, req)
+	alarm, err := service.CreateValarm(&userID, calendarItemID, req)
 
 	assert.Error(t, err)
 	assert.Equal(t, ErrCalendarItemNotFound, err)
@@ -481,6 +1077,7 @@ func TestService_CreateValarm_DisplayWithoutDescription(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	calendarItemID := uint(1)
 	existingItem := &CalendarItem{
 		ID:   calendarItemID,
@@ -494,9 +1091,9 @@ func TestService_CreateValarm_DisplayWithoutDescription(t *testing.T) {
 		Description: nil, // DISPLAY类型必须提供description
 	}
 
-	mockRepo.On("GetCalendarItemByID", mock.AnythingOfType("*uint"), calendarItemID).Return(existingItem, nil)
+	mockRepo.On("GetCalendarItemByID", &userID, calendarItemID).Return(existingItem, nil)
 
-	alarm, err := service.CreateValarm(calendarItemID, req)
+	alarm, err := service.CreateValarm(&userID, calendarItemID, req)
 
 	assert.Error(t, err)
 	assert.Contains(t, err.Error(), ErrInvalidInput.Error())
@@ -509,6 +1106,7 @@ func TestService_CreateValarm_InvalidAction(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	calendarItemID := uint(1)
 	existingItem := &CalendarItem{
 		ID:   calendarItemID,
@@ -521,9 +1119,9 @@ func TestService_CreateValarm_InvalidAction(t *testing.T) {
 		Trigger: "-PT15M",
 	}
 
-	mockRepo.On("GetCalendarItemByID", mock.AnythingOfType("*uint"), calendarItemID).Return(existingItem, nil)
+	mockRepo.On("GetCalendarItemByID", &userID, calendarItemID).Return(existingItem, nil)
 
-	alarm, err := service.CreateValarm(calendarItemID, req)
+	alarm, err := service.CreateValarm(&userID, calendarItemID, req)
 
 	assert.Error(t, err)
 	assert.Equal(t, ErrInvalidAction, err)
@@ -531,11 +1129,43 @@ func TestService_CreateValarm_InvalidAction(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+// TestService_CreateValarm_InvalidTrigger 测试无效的触发时间
+func TestService_CreateValarm_InvalidTrigger(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	calendarItemID := uint(1)
+	existingItem := &CalendarItem{ID: calendarItemID, UID: "test-uid-123", Type: CalendarItemTypeEvent}
+	mockRepo.On("GetCalendarItemByID", &userID, calendarItemID).Return(existingItem, nil)
+
+	for _, trigger := range []string{"15 minutes before", "20250106", "20250106T090000"} {
+		req := &CreateValarmRequest{Action: ValarmActionAudio, Trigger: trigger}
+
+		alarm, err := service.CreateValarm(&userID, calendarItemID, req)
+
+		assert.ErrorIs(t, err, ErrInvalidInput, trigger)
+		assert.Nil(t, alarm)
+	}
+	mockRepo.AssertNotCalled(t, "CreateValarm", mock.Anything)
+}
+
+// TestValidateTrigger 测试触发时间格式验证
+func TestValidateTrigger(t *testing.T) {
+	for _, trigger := range []string{"-PT15M", "PT0S", "-P1D", "+PT1H", "P1W", "20250106T090000Z", "2025-01-06T09:00:00+08:00"} {
+		assert.NoError(t, validateTrigger(trigger), trigger)
+	}
+	for _, trigger := range []string{"", "-15m", "PT", "2025-01-06", "2025-01-06T09:00:00"} {
+		assert.ErrorIs(t, validateTrigger(trigger), ErrInvalidInput, trigger)
+	}
+}
+
 // TestService_GetValarmByID_Success 测试根据ID获取提醒成功
 func TestService_GetValarmByID_Success(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	alarmID := uint(1)
 	description := "提醒内容"
 	expectedAlarm := &Valarm{
@@ -546,9 +1176,10 @@ func TestService_GetValarmByID_Success(t *testing.T) {
 		Description:    &description,
 	}
 
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(&CalendarItem{ID: 1}, nil)
 	mockRepo.On("GetValarmByID", alarmID).Return(expectedAlarm, nil)
 
-	alarm, err := service.GetValarmByID(alarmID)
+	alarm, err := service.GetValarmByID(&userID, 1, alarmID)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, alarm)
@@ -561,11 +1192,13 @@ func TestService_GetValarmByID_NotFound(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	alarmID := uint(999)
 
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(&CalendarItem{ID: 1}, nil)
 	mockRepo.On("GetValarmByID", alarmID).Return(nil, errors.New("not found"))
 
-	alarm, err := service.GetValarmByID(alarmID)
+	alarm, err := service.GetValarmByID(&userID, 1, alarmID)
 
 	assert.Error(t, err)
 	assert.Equal(t, ErrValarmNotFound, err)
@@ -573,11 +1206,44 @@ func TestService_GetValarmByID_NotFound(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+// TestService_GetValarmByID_OtherItem 测试提醒不属于路径中的日历项
+func TestService_GetValarmByID_OtherItem(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(&CalendarItem{ID: 1}, nil)
+	mockRepo.On("GetValarmByID", uint(5)).Return(&Valarm{ID: 5, CalendarItemID: 2}, nil)
+
+	alarm, err := service.GetValarmByID(&userID, 1, 5)
+
+	assert.Equal(t, ErrValarmNotFound, err)
+	assert.Nil(t, alarm)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_GetValarmByID_OtherUser 测试日历项不属于该用户时不查询提醒
+func TestService_GetValarmByID_OtherUser(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(2)
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(nil, errors.New("not found"))
+
+	alarm, err := service.GetValarmByID(&userID, 1, 5)
+
+	assert.Equal(t, ErrCalendarItemNotFound, err)
+	assert.Nil(t, alarm)
+	mockRepo.AssertExpectations(t)
+	mockRepo.AssertNotCalled(t, "GetValarmByID", mock.Anything)
+}
+
 // TestService_GetValarmsByCalendarItemID_Success 测试获取日历项的所有提醒成功
 func TestService_GetValarmsByCalendarItemID_Success(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	calendarItemID := uint(1)
 	description := "提醒内容"
 	alarms := []*Valarm{
@@ -602,10 +1268,10 @@ func TestService_GetValarmsByCalendarItemID_Success(t *testing.T) {
 		Type: CalendarItemTypeEvent,
 	}
 
-	mockRepo.On("GetCalendarItemByID", mock.AnythingOfType("*uint"), calendarItemID).Return(existingItem, nil)
+	mockRepo.On("GetCalendarItemByID", &userID, calendarItemID).Return(existingItem, nil)
 	mockRepo.On("GetValarmsByCalendarItemID", calendarItemID).Return(alarms, nil)
 
-	result, err := service.GetValarmsByCalendarItemID(calendarItemID)
+	result, err := service.GetValarmsByCalendarItemID(&userID, calendarItemID)
 
 	assert.NoError(t, err)
 	assert.Len(t, result, 2)
@@ -619,6 +1285,7 @@ func TestService_UpdateValarm_Success(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	alarmID := uint(1)
 	originalDescription := "原始提醒"
 	updatedDescription := "更新后的提醒"
@@ -636,6 +1303,7 @@ func TestService_UpdateValarm_Success(t *testing.T) {
 		Description: &updatedDescription,
 	}
 
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(&CalendarItem{ID: 1}, nil)
 	mockRepo.On("GetValarmByID", alarmID).Return(existingAlarm, nil)
 	mockRepo.On("UpdateValarm", mock.AnythingOfType("*calendar.Valarm")).
 		Return(nil).
@@ -645,7 +1313,7 @@ func TestService_UpdateValarm_Success(t *testing.T) {
 			assert.Equal(t, updatedDescription, *alarm.Description)
 		})
 
-	alarm, err := service.UpdateValarm(alarmID, req)
+	alarm, err := service.UpdateValarm(&userID, 1, alarmID, req)
 
 	assert.NoError(t, err)
 	assert.NotNil(t, alarm)
@@ -659,12 +1327,14 @@ func TestService_UpdateValarm_NotFound(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	alarmID := uint(999)
 	req := &UpdateValarmRequest{}
 
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(&CalendarItem{ID: 1}, nil)
 	mockRepo.On("GetValarmByID", alarmID).Return(nil, errors.New("not found"))
 
-	alarm, err := service.UpdateValarm(alarmID, req)
+	alarm, err := service.UpdateValarm(&userID, 1, alarmID, req)
 
 	assert.Error(t, err)
 	assert.Equal(t, ErrValarmNotFound, err)
@@ -672,11 +1342,29 @@ func TestService_UpdateValarm_NotFound(t *testing.T) {
 	mockRepo.AssertExpectations(t)
 }
 
+// TestService_UpdateValarm_InvalidTrigger 测试更新为无效的触发时间
+func TestService_UpdateValarm_InvalidTrigger(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	trigger := "tomorrow"
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(&CalendarItem{ID: 1}, nil)
+	mockRepo.On("GetValarmByID", uint(3)).Return(&Valarm{ID: 3, CalendarItemID: 1, Action: ValarmActionAudio, Trigger: "-PT5M"}, nil)
+
+	alarm, err := service.UpdateValarm(&userID, 1, 3, &UpdateValarmRequest{Trigger: &trigger})
+
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	assert.Nil(t, alarm)
+	mockRepo.AssertNotCalled(t, "UpdateValarm", mock.Anything)
+}
+
 // TestService_DeleteValarm_Success 测试删除提醒成功
 func TestService_DeleteValarm_Success(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	alarmID := uint(1)
 	existingAlarm := &Valarm{
 		ID:             alarmID,
@@ -684,10 +1372,11 @@ func TestService_DeleteValarm_Success(t *testing.T) {
 		Action:         ValarmActionDisplay,
 	}
 
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(&CalendarItem{ID: 1}, nil)
 	mockRepo.On("GetValarmByID", alarmID).Return(existingAlarm, nil)
 	mockRepo.On("DeleteValarm", alarmID).Return(nil)
 
-	err := service.DeleteValarm(alarmID)
+	err := service.DeleteValarm(&userID, 1, alarmID)
 
 	assert.NoError(t, err)
 	mockRepo.AssertExpectations(t)
@@ -698,11 +1387,13 @@ func TestService_DeleteValarm_NotFound(t *testing.T) {
 	mockRepo := new(mockRepository)
 	service := NewService(mockRepo)
 
+	userID := uint(1)
 	alarmID := uint(999)
 
+	mockRepo.On("GetCalendarItemByID", &userID, uint(1)).Return(&CalendarItem{ID: 1}, nil)
 	mockRepo.On("GetValarmByID", alarmID).Return(nil, errors.New("not found"))
 
-	err := service.DeleteValarm(alarmID)
+	err := service.DeleteValarm(&userID, 1, alarmID)
 
 	assert.Error(t, err)
 	assert.Equal(t, ErrValarmNotFound, err)
@@ -730,12 +1421,14 @@ func TestService_SearchCalendarItems_Success(t *testing.T) {
 		},
 	}
 
-	mockRepo.On("SearchCalendarItems", &userID, keyword, map[string]TimeRange(nil), 20).Return(expectedItems, nil)
+	mockRepo.On("SearchCalendarItems", &userID, keyword, map[string]TimeRange{}, PageRequest{Sort: SortByRelevance, Limit: 20}).Return(expectedItems, nil, nil)
 
-	items, err := service.SearchCalendarItems(&userID, req)
+	result, err := service.SearchCalendarItems(&userID, req)
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedItems, items)
+	assert.Equal(t, expectedItems, result.Items)
+	assert.Equal(t, len(expectedItems), result.Count)
+	assert.Empty(t, result.NextCursor)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -774,12 +1467,14 @@ func TestService_SearchCalendarItems_WithTimeRange(t *testing.T) {
 			End:   &endTime,
 		},
 	}
-	mockRepo.On("SearchCalendarItems", &userID, keyword, timeRanges, 20).Return(expectedItems, nil)
+	mockRepo.On("SearchCalendarItems", &userID, keyword, timeRanges, PageRequest{Sort: SortByDtStart}).Return(expectedItems, nil, nil)
 
-	items, err := service.SearchCalendarItems(&userID, req)
+	result, err := service.SearchCalendarItems(&userID, req)
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedItems, items)
+	assert.Equal(t, expectedItems, result.Items)
+	assert.Equal(t, len(expectedItems), result.Count)
+	assert.Empty(t, result.NextCursor)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -806,12 +1501,14 @@ func TestService_SearchCalendarItems_WithLimit(t *testing.T) {
 		},
 	}
 
-	mockRepo.On("SearchCalendarItems", &userID, keyword, map[string]TimeRange(nil), limit).Return(expectedItems, nil)
+	mockRepo.On("SearchCalendarItems", &userID, keyword, map[string]TimeRange{}, PageRequest{Sort: SortByRelevance, Limit: limit}).Return(expectedItems, nil, nil)
 
-	items, err := service.SearchCalendarItems(&userID, req)
+	result, err := service.SearchCalendarItems(&userID, req)
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedItems, items)
+	assert.Equal(t, expectedItems, result.Items)
+	assert.Equal(t, len(expectedItems), result.Count)
+	assert.Empty(t, result.NextCursor)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -846,12 +1543,14 @@ func TestService_SearchCalendarItems_OnlyTimeRange(t *testing.T) {
 			End:   &endTime,
 		},
 	}
-	mockRepo.On("SearchCalendarItems", &userID, "", timeRanges, 20).Return(expectedItems, nil)
+	mockRepo.On("SearchCalendarItems", &userID, "", timeRanges, PageRequest{Sort: SortByDtStart}).Return(expectedItems, nil, nil)
 
-	items, err := service.SearchCalendarItems(&userID, req)
+	result, err := service.SearchCalendarItems(&userID, req)
 
 	assert.NoError(t, err)
-	assert.Equal(t, expectedItems, items)
+	assert.Equal(t, expectedItems, result.Items)
+	assert.Equal(t, len(expectedItems), result.Count)
+	assert.Empty(t, result.NextCursor)
 	mockRepo.AssertExpectations(t)
 }
 
@@ -900,7 +1599,7 @@ func TestService_SearchCalendarItems_RepositoryError(t *testing.T) {
 	}
 
 	repoError := errors.New("数据库错误")
-	mockRepo.On("SearchCalendarItems", &userID, keyword, map[string]TimeRange(nil), 20).Return(nil, repoError)
+	mockRepo.On("SearchCalendarItems", &userID, keyword, map[string]TimeRange{}, PageRequest{Sort: SortByRelevance, Limit: 20}).Return(nil, nil, repoError)
 
 	_, err := service.SearchCalendarItems(&userID, req)
 
@@ -908,3 +1607,115 @@ func TestService_SearchCalendarItems_RepositoryError(t *testing.T) {
 	assert.Contains(t, err.Error(), "搜索日历项失败")
 	mockRepo.AssertExpectations(t)
 }
+
+// TestService_SearchCalendarItems_ExpandRecurring 测试搜索时展开重复日历项
+func TestService_SearchCalendarItems_ExpandRecurring(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	keyword := "周会"
+	rrule := "FREQ=WEEKLY;BYDAY=MO"
+	dtStart := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+	startTime := time.Date(2025, 1, 10, 0, 0, 0, 0, time.UTC)
+	endTime := time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)
+	req := &SearchCalendarItemsRequest{
+		Q:       &keyword,
+		DtStart: &TimeRange{Start: &startTime, End: &endTime},
+	}
+
+	items := []*CalendarItem{
+		{ID: 1, UID: "weekly", Type: CalendarItemTypeEvent, Summary: &keyword, DtStart: dtStart, RRule: &rrule},
+	}
+	timeRanges := map[string]TimeRange{"dtstart": {Start: &startTime, End: &endTime}}
+	mockRepo.On("SearchCalendarItems", &userID, keyword, timeRanges, PageRequest{Sort: SortByDtStart}).Return(items, nil, nil)
+	mockRepo.On("ListCalendarItemOverrides", &userID, []string{"weekly"}).Return([]*CalendarItem{}, nil)
+
+	result, err := service.SearchCalendarItems(&userID, req)
+
+	assert.NoError(t, err)
+	assert.Len(t, result.Items, 3)
+	assert.Equal(t, time.Date(2025, 1, 13, 10, 0, 0, 0, time.UTC), result.Items[0].DtStart)
+	assert.Equal(t, time.Date(2025, 1, 27, 10, 0, 0, 0, time.UTC), result.Items[2].DtStart)
+	for _, item := range result.Items {
+		assert.Equal(t, uint(1), item.ID)
+		assert.NotNil(t, item.RecurrenceID)
+	}
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_SearchCalendarItems_Cursor 测试搜索的游标分页：按指定字段排序，返回 Repository 生成的下一页游标
+func TestService_SearchCalendarItems_Cursor(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	keyword := "周会"
+	sortKey := SortByLastModified
+	modified := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	cursor := formatPageCursor(&PageCursor{Sort: SortByLastModified, Time: &modified, ID: 3})
+	next := &PageCursor{Sort: SortByLastModified, Time: &modified, ID: 5}
+
+	mockRepo.On("SearchCalendarItems", &userID, keyword, map[string]TimeRange{}, mock.MatchedBy(func(page PageRequest) bool {
+		return page.Sort == SortByLastModified && page.Cursor.ID == 3 && page.Limit == 20
+	})).Return([]*CalendarItem{{ID: 5}}, next, nil)
+
+	result, err := service.SearchCalendarItems(&userID, &SearchCalendarItemsRequest{Q: &keyword, Sort: &sortKey, Cursor: &cursor})
+
+	require.NoError(t, err)
+	assert.Equal(t, 1, result.Count)
+	assert.Equal(t, formatPageCursor(next), result.NextCursor)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_SearchCalendarItems_InvalidSort 测试无效的排序：没有关键字时按相关度排序、展开重复日历项时按其他字段排序
+func TestService_SearchCalendarItems_InvalidSort(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	keyword := "周会"
+	relevance, due := SortByRelevance, SortByDue
+	startTime := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.SearchCalendarItems(&userID, &SearchCalendarItemsRequest{DtEnd: &TimeRange{Start: &startTime}, Sort: &relevance})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+
+	_, err = service.SearchCalendarItems(&userID, &SearchCalendarItemsRequest{Q: &keyword, DtStart: &TimeRange{Start: &startTime}, Sort: &due})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	mockRepo.AssertNotCalled(t, "SearchCalendarItems", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
+
+// TestService_SearchCalendarItems_Highlights 测试搜索结果包含匹配字段的高亮片段
+func TestService_SearchCalendarItems_Highlights(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	keyword := "评审"
+	summary := "项目评审会"
+	location := "3 号会议室"
+	mockRepo.On("SearchCalendarItems", &userID, keyword, map[string]TimeRange{}, PageRequest{Sort: SortByRelevance, Limit: 20}).
+		Return([]*CalendarItem{{ID: 1, Summary: &summary, Location: &location}}, nil, nil)
+
+	result, err := service.SearchCalendarItems(&userID, &SearchCalendarItemsRequest{Q: &keyword})
+
+	require.NoError(t, err)
+	require.Len(t, result.Items, 1)
+	assert.Equal(t, map[string]string{"summary": "项目<b>评审</b>会"}, result.Items[0].Highlights)
+	mockRepo.AssertExpectations(t)
+}
+
+// TestService_SearchCalendarItems_UnsearchableQ 测试关键字中没有可搜索的文字
+func TestService_SearchCalendarItems_UnsearchableQ(t *testing.T) {
+	mockRepo := new(mockRepository)
+	service := NewService(mockRepo)
+
+	userID := uint(1)
+	keyword := "!!! ***"
+
+	_, err := service.SearchCalendarItems(&userID, &SearchCalendarItemsRequest{Q: &keyword})
+
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	mockRepo.AssertNotCalled(t, "SearchCalendarItems", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+}
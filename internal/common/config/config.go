@@ -8,11 +8,15 @@ import (
 )
 
 type Config struct {
-	Server   ServerConfig   `mapstructure:"server"`
-	Database DatabaseConfig `mapstructure:"database"`
-	JWT      JWTConfig      `mapstructure:"jwt"`
-	LLM      LLMConfig      `mapstructure:"llm"`
-	Log      LogConfig      `mapstructure:"log"`
+	Server     ServerConfig     `mapstructure:"server"`
+	Database   DatabaseConfig   `mapstructure:"database"`
+	JWT        JWTConfig        `mapstructure:"jwt"`
+	LLM        LLMConfig        `mapstructure:"llm"`
+	Log        LogConfig        `mapstructure:"log"`
+	Reminder   ReminderConfig   `mapstructure:"reminder"`
+	SMTP       SMTPConfig       `mapstructure:"smtp"`
+	Webhook    WebhookConfig    `mapstructure:"webhook"`
+	Scheduling SchedulingConfig `mapstructure:"scheduling"`
 }
 
 type LogConfig struct {
@@ -25,6 +29,49 @@ type LogConfig struct {
 	LocalTime  bool   `mapstructure:"local_time,omitempty"`  // 是否使用本地时间命名轮转文件
 }
 
+// ReminderConfig 提醒调度器配置
+type ReminderConfig struct {
+	Enabled     bool          `mapstructure:"enabled"`                // 是否启动提醒调度器
+	Interval    time.Duration `mapstructure:"interval,omitempty"`     // 检查到期提醒的间隔
+	BatchSize   int           `mapstructure:"batch_size,omitempty"`   // 每次检查最多处理的提醒数量
+	MaxLateness time.Duration `mapstructure:"max_lateness,omitempty"` // 超过触发时间多久的提醒不再补发（例如服务停机期间错过的提醒）
+}
+
+// SMTPConfig 发送邮件的 SMTP 服务器配置，Host 为空时不发送邮件
+type SMTPConfig struct {
+	Host               string        `mapstructure:"host"`
+	Port               int           `mapstructure:"port,omitempty"`
+	Username           string        `mapstructure:"username,omitempty"`
+	Password           string        `mapstructure:"password,omitempty"`
+	From               string        `mapstructure:"from"`                           // 发件人，例如 "Otter <otter@example.com>"
+	TLS                string        `mapstructure:"tls,omitempty"`                  // none、starttls（默认）或 tls（隐式 TLS，通常为 465 端口）
+	InsecureSkipVerify bool          `mapstructure:"insecure_skip_verify,omitempty"` // 不验证服务器证书（仅用于测试环境）
+	Timeout            time.Duration `mapstructure:"timeout,omitempty"`              // 连接和发送的超时时间
+}
+
+// WebhookConfig Webhook 投递配置
+type WebhookConfig struct {
+	Enabled              bool          `mapstructure:"enabled"`                          // 是否启动 Webhook 投递
+	Interval             time.Duration `mapstructure:"interval,omitempty"`               // 检查待投递事件的间隔
+	BatchSize            int           `mapstructure:"batch_size,omitempty"`             // 每次检查最多投递的事件数量
+	Timeout              time.Duration `mapstructure:"timeout,omitempty"`                // 单次投递的 HTTP 超时时间
+	MaxAttempts          int           `mapstructure:"max_attempts,omitempty"`           // 每个事件最多尝试投递的次数
+	RetryBaseDelay       time.Duration `mapstructure:"retry_base_delay,omitempty"`       // 第一次重试的等待时间，之后每次翻倍
+	RetryMaxDelay        time.Duration `mapstructure:"retry_max_delay,omitempty"`        // 重试等待时间的上限
+	DisableAfter         int           `mapstructure:"disable_after,omitempty"`          // 连续失败多少次后自动停用 Webhook
+	AllowPrivateNetworks bool          `mapstructure:"allow_private_networks,omitempty"` // 允许投递到内网和本机地址（默认禁止）
+}
+
+// SchedulingConfig 日程邀请（iTIP/iMIP）邮件的发送配置，需要同时配置 SMTP
+type SchedulingConfig struct {
+	Enabled        bool          `mapstructure:"enabled"`                    // 是否发送邀请、更新和取消邮件
+	Interval       time.Duration `mapstructure:"interval,omitempty"`         // 检查待发送邮件的间隔
+	BatchSize      int           `mapstructure:"batch_size,omitempty"`       // 每次检查最多发送的邮件数量
+	MaxAttempts    int           `mapstructure:"max_attempts,omitempty"`     // 每封邮件最多尝试发送的次数
+	RetryBaseDelay time.Duration `mapstructure:"retry_base_delay,omitempty"` // 第一次重试的等待时间，之后每次翻倍
+	RetryMaxDelay  time.Duration `mapstructure:"retry_max_delay,omitempty"`  // 重试等待时间的上限
+}
+
 type JWTConfig struct {
 	Secret            string        `mapstructure:"secret"`
 	Expiration        time.Duration `mapstructure:"expiration"`         // Access token过期时间
@@ -46,8 +93,50 @@ type DatabaseConfig struct {
 	ConnMaxLifetime time.Duration `mapstructure:"conn_max_lifetime,omitempty"`
 }
 
+// LLM 提供方类型
+const (
+	ProviderOpenAI    = "openai"    // OpenAI 兼容的 Chat Completions API（OpenAI、DeepSeek 等）
+	ProviderGemini    = "gemini"    // Gemini API（genai）
+	ProviderAnthropic = "anthropic" // Anthropic Messages API
+	ProviderOllama    = "ollama"    // 本地 Ollama 服务（OpenAI 兼容接口，不需要 API key）
+	ProviderLlamaCpp  = "llamacpp"  // 本地 llama.cpp 服务（OpenAI 兼容接口，不需要 API key）
+)
+
+// LLMConfig 大模型配置
+// Providers 是按名称注册的提供方，Default 是默认使用的提供方，Agents 可以为某个 Agent 指定提供方，
+// 用户还可以在个人配置中选择提供方（llm_provider）。
+// 兼容旧配置：没有配置 providers 时，deepseek 作为名为 deepseek 的 openai 提供方
 type LLMConfig struct {
-	DeepSeek DeepSeekConfig `mapstructure:"deepseek"`
+	Default   string                    `mapstructure:"default"`
+	Agents    map[string]string         `mapstructure:"agents,omitempty"` // Agent 名称 -> 提供方名称
+	Providers map[string]ProviderConfig `mapstructure:"providers"`
+	Quota     QuotaConfig               `mapstructure:"quota,omitempty"`
+	DeepSeek  DeepSeekConfig            `mapstructure:"deepseek,omitempty"` // 已废弃，请使用 providers
+}
+
+// QuotaConfig 每个用户的 token 额度（按 UTC 日期和月份统计），用完后 Agent 拒绝继续对话
+type QuotaConfig struct {
+	DailyTokens   int64 `mapstructure:"daily_tokens,omitempty"`   // 每天的 token 上限，0 表示不限制
+	MonthlyTokens int64 `mapstructure:"monthly_tokens,omitempty"` // 每月的 token 上限，0 表示不限制
+}
+
+// ProviderConfig LLM 提供方配置
+type ProviderConfig struct {
+	Type        string        `mapstructure:"type"`                  // openai、gemini、anthropic、ollama 或 llamacpp
+	APIKey      string        `mapstructure:"api_key,omitempty"`     // ollama 和 llamacpp 不需要
+	Model       string        `mapstructure:"model"`                 // 模型名称
+	BaseURL     string        `mapstructure:"base_url,omitempty"`    // 为空时使用提供方的默认地址
+	Temperature *float64      `mapstructure:"temperature,omitempty"` // 未设置时使用模型的默认值
+	TopP        *float64      `mapstructure:"top_p,omitempty"`
+	MaxTokens   int           `mapstructure:"max_tokens,omitempty"` // 单次回复的最大 token 数，0 表示使用模型的默认值（anthropic 默认 4096）
+	Timeout     time.Duration `mapstructure:"timeout,omitempty"`    // 每次请求（包括流式输出）的超时时间，重试时重新计时
+
+	MaxAttempts      int           `mapstructure:"max_attempts,omitempty"`      // 网络错误、限流和服务端错误时最多请求的次数（包括第一次）
+	RetryBaseDelay   time.Duration `mapstructure:"retry_base_delay,omitempty"`  // 第一次重试的等待时间，之后每次翻倍（带随机抖动），服务端返回 Retry-After 时使用它
+	RetryMaxDelay    time.Duration `mapstructure:"retry_max_delay,omitempty"`   // 重试等待时间的上限
+	BreakerThreshold int           `mapstructure:"breaker_threshold,omitempty"` // 连续失败多少次后熔断，熔断期间直接使用备用提供方
+	BreakerCooldown  time.Duration `mapstructure:"breaker_cooldown,omitempty"`  // 熔断持续时间，之后允许一次试探请求
+	Fallbacks        []string      `mapstructure:"fallbacks,omitempty"`         // 不可用时依次尝试的备用提供方名称
 }
 
 type DeepSeekConfig struct {
@@ -56,6 +145,14 @@ type DeepSeekConfig struct {
 	BaseURL string `mapstructure:"base_url"`
 }
 
+// AgentProvider 返回 Agent 使用的提供方名称，未单独指定时使用默认提供方
+func (c *LLMConfig) AgentProvider(agentName string) string {
+	if name := c.Agents[agentName]; name != "" {
+		return name
+	}
+	return c.Default
+}
+
 // Load 加载配置文件
 // configPath: 配置文件路径，如果为空则使用默认路径查找
 func Load(configPath ...string) (*Config, error) {
@@ -89,20 +186,19 @@ func Load(configPath ...string) (*Config, error) {
 	if config.Database.URL == "" {
 		return nil, fmt.Errorf("数据库 URL 是必需的，请在配置文件中设置 database.url")
 	}
-	if config.LLM.DeepSeek.APIKey == "" {
-		return nil, fmt.Errorf("DeepSeek API key 是必需的，请在配置文件中设置 llm.deepseek.api_key")
-	}
-	if config.LLM.DeepSeek.Model == "" {
-		return nil, fmt.Errorf("DeepSeek model 是必需的，请在配置文件中设置 llm.deepseek.model")
-	}
-	if config.LLM.DeepSeek.BaseURL == "" {
-		return nil, fmt.Errorf("DeepSeek base_url 是必需的，请在配置文件中设置 llm.deepseek.base_url")
+	if err := validateLLMConfig(&config.LLM); err != nil {
+		return nil, err
 	}
 
 	// 应用默认值（如果配置文件中未设置）
 	applyServerDefaults(&config.Server)
 	applyDatabaseDefaults(&config.Database)
 	applyLogDefaults(&config.Log)
+	applyReminderDefaults(&config.Reminder)
+	applySMTPDefaults(&config.SMTP)
+	applyWebhookDefaults(&config.Webhook)
+	applySchedulingDefaults(&config.Scheduling)
+	applyLLMDefaults(&config.LLM)
 
 	return &config, nil
 }
@@ -152,7 +248,7 @@ func setDefaults() {
 	viper.SetDefault("jwt.expiration", "15m")          // Access token 15分钟
 	viper.SetDefault("jwt.refresh_expiration", "168h") // Refresh token 7天 (168小时)
 
-	// llm.deepseek 的所有字段都没有默认值，必须设置
+	// llm.providers 没有默认值，必须至少配置一个提供方（或旧的 llm.deepseek）
 
 	// log 配置默认值
 	viper.SetDefault("log.log_level", "info")
@@ -161,6 +257,35 @@ func setDefaults() {
 	viper.SetDefault("log.max_age", 30)       // 保留30天
 	viper.SetDefault("log.compress", false)   // 不压缩
 	viper.SetDefault("log.local_time", false) // 使用UTC时间
+
+	// reminder 配置默认值
+	viper.SetDefault("reminder.enabled", true)
+	viper.SetDefault("reminder.interval", "30s")
+	viper.SetDefault("reminder.batch_size", 100)
+	viper.SetDefault("reminder.max_lateness", "1h")
+
+	// smtp 配置默认值（smtp.host 没有默认值，不设置时不发送邮件）
+	viper.SetDefault("smtp.port", 587)
+	viper.SetDefault("smtp.tls", "starttls")
+	viper.SetDefault("smtp.timeout", "30s")
+
+	// webhook 配置默认值
+	viper.SetDefault("webhook.enabled", true)
+	viper.SetDefault("webhook.interval", "5s")
+	viper.SetDefault("webhook.batch_size", 50)
+	viper.SetDefault("webhook.timeout", "10s")
+	viper.SetDefault("webhook.max_attempts", 8)
+	viper.SetDefault("webhook.retry_base_delay", "30s")
+	viper.SetDefault("webhook.retry_max_delay", "6h")
+	viper.SetDefault("webhook.disable_after", 20)
+
+	// scheduling 配置默认值
+	viper.SetDefault("scheduling.enabled", true)
+	viper.SetDefault("scheduling.interval", "10s")
+	viper.SetDefault("scheduling.batch_size", 50)
+	viper.SetDefault("scheduling.max_attempts", 6)
+	viper.SetDefault("scheduling.retry_base_delay", "1m")
+	viper.SetDefault("scheduling.retry_max_delay", "1h")
 }
 
 // applyLogDefaults 应用日志配置的默认值
@@ -178,3 +303,164 @@ func applyLogDefaults(log *LogConfig) {
 		log.MaxAge = 30
 	}
 }
+
+// applyReminderDefaults 应用提醒调度器配置的默认值
+func applyReminderDefaults(reminder *ReminderConfig) {
+	if reminder.Interval == 0 {
+		reminder.Interval = 30 * time.Second
+	}
+	if reminder.BatchSize == 0 {
+		reminder.BatchSize = 100
+	}
+	if reminder.MaxLateness == 0 {
+		reminder.MaxLateness = time.Hour
+	}
+}
+
+// applySMTPDefaults 应用 SMTP 配置的默认值
+func applySMTPDefaults(smtp *SMTPConfig) {
+	if smtp.Port == 0 {
+		smtp.Port = 587
+	}
+	if smtp.TLS == "" {
+		smtp.TLS = "starttls"
+	}
+	if smtp.Timeout == 0 {
+		smtp.Timeout = 30 * time.Second
+	}
+}
+
+// applyWebhookDefaults 应用 Webhook 投递配置的默认值
+func applyWebhookDefaults(webhook *WebhookConfig) {
+	if webhook.Interval == 0 {
+		webhook.Interval = 5 * time.Second
+	}
+	if webhook.BatchSize == 0 {
+		webhook.BatchSize = 50
+	}
+	if webhook.Timeout == 0 {
+		webhook.Timeout = 10 * time.Second
+	}
+	if webhook.MaxAttempts == 0 {
+		webhook.MaxAttempts = 8
+	}
+	if webhook.RetryBaseDelay == 0 {
+		webhook.RetryBaseDelay = 30 * time.Second
+	}
+	if webhook.RetryMaxDelay == 0 {
+		webhook.RetryMaxDelay = 6 * time.Hour
+	}
+	if webhook.DisableAfter == 0 {
+		webhook.DisableAfter = 20
+	}
+}
+
+// applySchedulingDefaults 应用日程邀请邮件发送配置的默认值
+func applySchedulingDefaults(scheduling *SchedulingConfig) {
+	if scheduling.Interval == 0 {
+		scheduling.Interval = 10 * time.Second
+	}
+	if scheduling.BatchSize == 0 {
+		scheduling.BatchSize = 50
+	}
+	if scheduling.MaxAttempts == 0 {
+		scheduling.MaxAttempts = 6
+	}
+	if scheduling.RetryBaseDelay == 0 {
+		scheduling.RetryBaseDelay = time.Minute
+	}
+	if scheduling.RetryMaxDelay == 0 {
+		scheduling.RetryMaxDelay = time.Hour
+	}
+}
+
+// validateLLMConfig 验证大模型配置
+// 没有配置 providers 时把旧的 llm.deepseek 转换为名为 deepseek 的提供方；只有一个提供方时它就是默认提供方
+func validateLLMConfig(llm *LLMConfig) error {
+	if len(llm.Providers) == 0 {
+		if llm.DeepSeek.APIKey == "" {
+			return fmt.Errorf("LLM 提供方是必需的，请在配置文件中设置 llm.providers")
+		}
+		if llm.DeepSeek.Model == "" {
+			return fmt.Errorf("DeepSeek model 是必需的，请在配置文件中设置 llm.deepseek.model")
+		}
+		if llm.DeepSeek.BaseURL == "" {
+			return fmt.Errorf("DeepSeek base_url 是必需的，请在配置文件中设置 llm.deepseek.base_url")
+		}
+		llm.Providers = map[string]ProviderConfig{
+			"deepseek": {
+				Type:    ProviderOpenAI,
+				APIKey:  llm.DeepSeek.APIKey,
+				Model:   llm.DeepSeek.Model,
+				BaseURL: llm.DeepSeek.BaseURL,
+			},
+		}
+	}
+
+	if llm.Default == "" {
+		if len(llm.Providers) > 1 {
+			return fmt.Errorf("配置了多个 LLM 提供方，请在配置文件中设置 llm.default")
+		}
+		for name := range llm.Providers {
+			llm.Default = name
+		}
+	}
+	if _, ok := llm.Providers[llm.Default]; !ok {
+		return fmt.Errorf("llm.default 指定的提供方 %q 不存在", llm.Default)
+	}
+	for agentName, name := range llm.Agents {
+		if _, ok := llm.Providers[name]; !ok {
+			return fmt.Errorf("llm.agents.%s 指定的提供方 %q 不存在", agentName, name)
+		}
+	}
+
+	if llm.Quota.DailyTokens < 0 || llm.Quota.MonthlyTokens < 0 {
+		return fmt.Errorf("llm.quota 的 token 上限不能为负数")
+	}
+
+	for name, p := range llm.Providers {
+		switch p.Type {
+		case ProviderOpenAI, ProviderGemini, ProviderAnthropic:
+			if p.APIKey == "" {
+				return fmt.Errorf("LLM 提供方 %s 的 api_key 是必需的，请在配置文件中设置 llm.providers.%s.api_key", name, name)
+			}
+		case ProviderOllama, ProviderLlamaCpp:
+		default:
+			return fmt.Errorf("LLM 提供方 %s 的类型 %q 无效，可选值: openai、gemini、anthropic、ollama、llamacpp", name, p.Type)
+		}
+		if p.Model == "" {
+			return fmt.Errorf("LLM 提供方 %s 的 model 是必需的，请在配置文件中设置 llm.providers.%s.model", name, name)
+		}
+		if p.MaxTokens < 0 || p.Timeout < 0 || p.MaxAttempts < 0 || p.BreakerThreshold < 0 {
+			return fmt.Errorf("LLM 提供方 %s 的 max_tokens、timeout、max_attempts 和 breaker_threshold 不能为负数", name)
+		}
+		for _, fallback := range p.Fallbacks {
+			if _, ok := llm.Providers[fallback]; !ok || fallback == name {
+				return fmt.Errorf("LLM 提供方 %s 的备用提供方 %q 不存在", name, fallback)
+			}
+		}
+	}
+	return nil
+}
+
+// applyLLMDefaults 应用 LLM 提供方重试和熔断配置的默认值
+func applyLLMDefaults(llm *LLMConfig) {
+	for name, p := range llm.Providers {
+		if p.MaxAttempts == 0 {
+			p.MaxAttempts = 3
+		}
+		if p.RetryBaseDelay == 0 {
+			p.RetryBaseDelay = time.Second
+		}
+		if p.RetryMaxDelay == 0 {
+			p.RetryMaxDelay = 30 * time.Second
+		}
+		if p.BreakerThreshold == 0 {
+			p.BreakerThreshold = 5
+		}
+		if p.BreakerCooldown == 0 {
+			p.BreakerCooldown = 30 * time.Second
+		}
+		llm.Providers[name] = p
+	}
+}
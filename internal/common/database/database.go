@@ -10,7 +10,12 @@ import (
 	"github.com/galilio/otter/internal/calendar"
 	"github.com/galilio/otter/internal/common/config"
 	"github.com/galilio/otter/internal/common/utils"
+	"github.com/galilio/otter/internal/reminder"
+	"github.com/galilio/otter/internal/scheduling"
+	"github.com/galilio/otter/internal/session"
+	"github.com/galilio/otter/internal/usage"
 	"github.com/galilio/otter/internal/user"
+	"github.com/galilio/otter/internal/webhook"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 	"gorm.io/gorm/logger"
@@ -52,6 +57,18 @@ func Migrate(db *gorm.DB) error {
 		&auth.RefreshToken{},
 		&calendar.CalendarItem{},
 		&calendar.Valarm{},
+		&calendar.Attendee{},
+		&calendar.CalendarFeedToken{},
+		&reminder.AlarmSchedule{},
+		&reminder.DigestDelivery{},
+		&webhook.Webhook{},
+		&webhook.WebhookDelivery{},
+		&scheduling.Message{},
+		&session.Session{},
+		&session.Event{},
+		&session.AppState{},
+		&session.UserState{},
+		&usage.Usage{},
 	); err != nil {
 		return fmt.Errorf("自动迁移失败: %w", err)
 	}
@@ -62,6 +79,11 @@ func Migrate(db *gorm.DB) error {
 		return fmt.Errorf("创建部分唯一索引失败: %w", err)
 	}
 
+	// 日历项全文搜索
+	if err := createCalendarSearchVector(db); err != nil {
+		return fmt.Errorf("创建全文搜索索引失败: %w", err)
+	}
+
 	return nil
 }
 
@@ -80,6 +102,10 @@ func createPartialUniqueIndexes(db *gorm.DB) error {
 		"DROP INDEX IF EXISTS idx_users_email",
 		"DROP INDEX IF EXISTS idx_users_username_unique",
 		"DROP INDEX IF EXISTS idx_users_email_unique",
+		// 日历项 UID 旧的全局唯一索引，例外实例需要与主日历项共用 UID，不同用户也可能导入或收到同一个 UID
+		"DROP INDEX IF EXISTS idx_uid",
+		"DROP INDEX IF EXISTS idx_calendar_items_uid_master",
+		"DROP INDEX IF EXISTS idx_calendar_items_uid_recurrence",
 	}
 
 	for _, sql := range dropIndexes {
@@ -92,6 +118,10 @@ func createPartialUniqueIndexes(db *gorm.DB) error {
 	indexes := []string{
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_unique ON users(username) WHERE deleted_at IS NULL`,
 		`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_unique ON users(email) WHERE deleted_at IS NULL`,
+		// 同一用户的主日历项 UID 唯一；例外实例按 (user_id, uid, recurrence_id) 唯一
+		// UID 可能来自客户端（导入、CalDAV、iTIP 邀请），不同用户可以持有相同的 UID
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_items_user_uid_master ON calendar_items(user_id, uid) WHERE recurrence_id IS NULL AND deleted_at IS NULL`,
+		`CREATE UNIQUE INDEX IF NOT EXISTS idx_calendar_items_user_uid_recurrence ON calendar_items(user_id, uid, recurrence_id) WHERE recurrence_id IS NOT NULL AND deleted_at IS NULL`,
 	}
 
 	for _, sql := range indexes {
@@ -104,6 +134,49 @@ func createPartialUniqueIndexes(db *gorm.DB) error {
 	return nil
 }
 
+// createCalendarSearchVector 创建日历项全文搜索使用的 search_vector 生成列和 GIN 索引
+// PostgreSQL 自带的分词器不能切分中文，calendar_search_tokens 将汉字拆成相邻两字的二元组和单字，
+// 其余文本去掉标点后交给 simple 配置分词；查询时的切分规则见 calendar 包的 parseSearchQuery，两者需要保持一致
+// 字段权重：summary (A) > location (B) > description (C) > 其他可搜索字段 (D)
+func createCalendarSearchVector(db *gorm.DB) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return fmt.Errorf("获取数据库连接失败: %w", err)
+	}
+
+	statements := []string{
+		`CREATE OR REPLACE FUNCTION calendar_search_tokens(t text) RETURNS text
+		LANGUAGE sql IMMUTABLE PARALLEL SAFE AS $$
+			SELECT regexp_replace(regexp_replace(coalesce(t, ''), '[\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff]+', ' ', 'g'), '[[:punct:]]+', ' ', 'g')
+				|| ' ' || coalesce((
+					SELECT string_agg(substr(t, i, 2), ' ' ORDER BY i)
+					FROM generate_series(1, length(t) - 1) AS i
+					WHERE substr(t, i, 2) ~ '^[\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff]{2}$'
+				), '')
+				|| ' ' || coalesce((
+					SELECT string_agg(m[1], ' ')
+					FROM regexp_matches(t, '([\u3400-\u4dbf\u4e00-\u9fff\uf900-\ufaff])', 'g') AS m
+				), '')
+		$$`,
+		`ALTER TABLE calendar_items ADD COLUMN IF NOT EXISTS search_vector tsvector GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', calendar_search_tokens(summary)), 'A') ||
+			setweight(to_tsvector('simple', calendar_search_tokens(location)), 'B') ||
+			setweight(to_tsvector('simple', calendar_search_tokens(description)), 'C') ||
+			setweight(to_tsvector('simple', calendar_search_tokens(
+				coalesce(organizer, '') || ' ' || coalesce(comment, '') || ' ' || coalesce(contact, '') || ' ' ||
+				coalesce(categories::text, '') || ' ' || coalesce(resources::text, ''))), 'D')
+		) STORED`,
+		`CREATE INDEX IF NOT EXISTS idx_calendar_items_search_vector ON calendar_items USING GIN (search_vector)`,
+	}
+
+	for _, sql := range statements {
+		if _, err := sqlDB.Exec(sql); err != nil {
+			return fmt.Errorf("执行 SQL 失败: %w, SQL: %s", err, sql)
+		}
+	}
+	return nil
+}
+
 // generateRandomPassword 生成随机密码
 // 长度16个字符，包含大小写字母、数字
 func generateRandomPassword(length int) (string, error) {
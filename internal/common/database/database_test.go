@@ -0,0 +1,43 @@
+package database
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 创建测试用的数据库连接（使用sqlmock）
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{Conn: db}), &gorm.Config{})
+	require.NoError(t, err)
+	return gormDB, mock
+}
+
+// TestCreatePartialUniqueIndexes_CalendarUIDPerUser 测试日历项 UID 的唯一索引按用户区分：
+// 两个用户导入或收到同一个 UID 的日历项时不会违反唯一约束，旧的全局索引会被删除
+func TestCreatePartialUniqueIndexes_CalendarUIDPerUser(t *testing.T) {
+	db, mock := setupTestDB(t)
+
+	for range 7 {
+		mock.ExpectExec("DROP INDEX IF EXISTS").WillReturnResult(sqlmock.NewResult(0, 0))
+	}
+	mock.ExpectExec("idx_users_username_unique").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("idx_users_email_unique").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("ON calendar_items(user_id, uid) WHERE recurrence_id IS NULL")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(regexp.QuoteMeta("ON calendar_items(user_id, uid, recurrence_id) WHERE recurrence_id IS NOT NULL")).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	err := createPartialUniqueIndexes(db)
+
+	require.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -10,32 +10,40 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-// AuthRequired JWT认证中间件
-func AuthRequired(jwtConfig *config.JWTConfig) gin.HandlerFunc {
-	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "未提供认证token"})
-			c.Abort()
-			return
-		}
+var (
+	ErrMissingToken   = errors.New("未提供认证token")
+	ErrMalformedToken = errors.New("认证格式错误，应为: Bearer <token>")
+)
 
-		// 提取Bearer token
-		parts := strings.SplitN(authHeader, " ", 2)
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "认证格式错误，应为: Bearer <token>"})
-			c.Abort()
-			return
+// ParseAuthorization 解析 Authorization 请求头中的 Bearer token 并校验
+// 返回的错误可以直接作为响应中的错误提示
+func ParseAuthorization(authHeader string, jwtConfig *config.JWTConfig) (*auth.Claims, error) {
+	if authHeader == "" {
+		return nil, ErrMissingToken
+	}
+
+	// 提取Bearer token
+	parts := strings.SplitN(authHeader, " ", 2)
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		return nil, ErrMalformedToken
+	}
+
+	claims, err := auth.ValidateToken(parts[1], jwtConfig.Secret)
+	if err != nil {
+		if err == auth.ErrExpiredToken {
+			return nil, auth.ErrExpiredToken
 		}
+		return nil, auth.ErrInvalidToken
+	}
+	return claims, nil
+}
 
-		token := parts[1]
-		claims, err := auth.ValidateToken(token, jwtConfig.Secret)
+// AuthRequired JWT认证中间件
+func AuthRequired(jwtConfig *config.JWTConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		claims, err := ParseAuthorization(c.GetHeader("Authorization"), jwtConfig)
 		if err != nil {
-			if err == auth.ErrExpiredToken {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "token已过期"})
-			} else {
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "无效的token"})
-			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": err.Error()})
 			c.Abort()
 			return
 		}
@@ -6,14 +6,22 @@ import (
 	"time"
 )
 
-// ParseDateTime 解析日期时间字符串，支持多种格式
+// ParseDateTime 解析日期时间字符串，没有时区信息的值按 UTC 处理，支持的格式见 ParseDateTimeIn
+func ParseDateTime(timeStr string) (time.Time, error) {
+	return ParseDateTimeIn(timeStr, time.UTC)
+}
+
+// ParseDateTimeIn 解析日期时间字符串，没有时区信息的值按 loc 解释（loc 为 nil 时使用 UTC）
 // 支持的格式：
 // 1. RFC3339 格式（包含时区）：2006-01-02T15:04:05Z07:00, 2006-01-02T15:04:05Z
 // 2. RFC3339Nano 格式：2006-01-02T15:04:05.999999999Z07:00
-// 3. ISO 8601 格式（无时区，假设为 UTC）：2006-01-02T15:04:05, 2006-01-02T15:04
-// 4. 日期时间格式（空格分隔，假设为 UTC）：2006-01-02 15:04:05, 2006-01-02 15:04
-// 5. 日期格式（假设为 UTC 00:00:00）：2006-01-02
-func ParseDateTime(timeStr string) (time.Time, error) {
+// 3. ISO 8601 格式（无时区）：2006-01-02T15:04:05, 2006-01-02T15:04
+// 4. 日期时间格式（空格分隔，无时区）：2006-01-02 15:04:05, 2006-01-02 15:04
+// 5. 日期格式（当天 00:00:00）：2006-01-02
+func ParseDateTimeIn(timeStr string, loc *time.Location) (time.Time, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
 	if timeStr == "" {
 		return time.Time{}, fmt.Errorf("时间字符串不能为空")
 	}
@@ -40,8 +48,8 @@ func ParseDateTime(timeStr string) (time.Time, error) {
 			"2006-01-02T15:04",
 		}
 		for _, layout := range layouts {
-			if t, err := time.Parse(layout, timeStr); err == nil {
-				return t.UTC(), nil
+			if t, err := time.ParseInLocation(layout, timeStr, loc); err == nil {
+				return t, nil
 			}
 		}
 	}
@@ -53,17 +61,23 @@ func ParseDateTime(timeStr string) (time.Time, error) {
 			"2006-01-02 15:04",
 		}
 		for _, layout := range layouts {
-			if t, err := time.Parse(layout, timeStr); err == nil {
-				return t.UTC(), nil
+			if t, err := time.ParseInLocation(layout, timeStr, loc); err == nil {
+				return t, nil
 			}
 		}
 	}
 
 	// 尝试日期格式：2006-01-02
-	if t, err := time.Parse("2006-01-02", timeStr); err == nil {
-		return t.UTC(), nil
+	if t, err := time.ParseInLocation("2006-01-02", timeStr, loc); err == nil {
+		return t, nil
 	}
 
 	// 如果所有格式都失败，返回错误
 	return time.Time{}, fmt.Errorf("无法解析时间字符串 '%s'，支持的格式：RFC3339 (2006-01-02T15:04:05Z07:00)、ISO 8601 (2006-01-02T15:04:05)、日期时间 (2006-01-02 15:04:05)、日期 (2006-01-02)", timeStr)
 }
+
+// IsDateOnly 判断字符串是否只有日期（2006-01-02），例如全天日程的开始和结束时间
+func IsDateOnly(timeStr string) bool {
+	_, err := time.Parse("2006-01-02", timeStr)
+	return err == nil
+}
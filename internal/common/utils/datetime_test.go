@@ -0,0 +1,54 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseDateTimeIn 测试没有时区信息的值按指定时区解释，带时区的值保持不变
+func TestParseDateTimeIn(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name  string
+		input string
+		want  time.Time
+	}{
+		{name: "ISO 8601 无时区", input: "2025-01-06T15:00:00", want: time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)},
+		{name: "ISO 8601 无秒", input: "2025-01-06T15:00", want: time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)},
+		{name: "空格分隔", input: "2025-01-06 15:00", want: time.Date(2025, 1, 6, 7, 0, 0, 0, time.UTC)},
+		{name: "日期", input: "2025-01-06", want: time.Date(2025, 1, 5, 16, 0, 0, 0, time.UTC)},
+		{name: "UTC", input: "2025-01-06T15:00:00Z", want: time.Date(2025, 1, 6, 15, 0, 0, 0, time.UTC)},
+		{name: "带偏移", input: "2025-01-06T15:00:00-05:00", want: time.Date(2025, 1, 6, 20, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDateTimeIn(tt.input, shanghai)
+			require.NoError(t, err)
+			assert.True(t, got.Equal(tt.want), "got %s", got)
+		})
+	}
+
+	_, err = ParseDateTimeIn("明天下午三点", shanghai)
+	assert.Error(t, err)
+}
+
+// TestParseDateTime 测试没有时区信息的值按 UTC 处理
+func TestParseDateTime(t *testing.T) {
+	got, err := ParseDateTime("2025-01-06T15:00:00")
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2025, 1, 6, 15, 0, 0, 0, time.UTC), got)
+}
+
+// TestIsDateOnly 测试判断只有日期的字符串
+func TestIsDateOnly(t *testing.T) {
+	assert.True(t, IsDateOnly("2024-05-06"))
+	assert.False(t, IsDateOnly("2024-05-06T00:00:00Z"))
+	assert.False(t, IsDateOnly("2024-05-06 09:00"))
+	assert.False(t, IsDateOnly(""))
+}
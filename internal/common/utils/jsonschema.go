@@ -127,6 +127,19 @@ func SchemaFromStruct(v interface{}) *jsonschema.Schema {
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
+
+		// 没有 JSON 标签的嵌入结构体：与 encoding/json 一致，字段提升到外层
+		if field.Anonymous && field.Tag.Get("json") == "" && getElemType(field.Type).Kind() == reflect.Struct {
+			embedded := SchemaFromStruct(reflect.New(getElemType(field.Type)).Elem().Interface())
+			for name, fieldSchema := range embedded.Properties {
+				if _, exists := schema.Properties[name]; !exists {
+					schema.Properties[name] = fieldSchema
+				}
+			}
+			required = append(required, embedded.Required...)
+			continue
+		}
+
 		jsonName, isOptional := parseJSONTag(field.Tag.Get("json"), field.Name)
 		if jsonName == "" {
 			continue
@@ -111,6 +111,26 @@ func TestSchemaFromStruct_PointerToStruct(t *testing.T) {
 	assert.Contains(t, schema.Properties, "age")
 }
 
+// TestSchemaFromStruct_EmbeddedStruct 测试嵌入结构体的字段提升到外层
+func TestSchemaFromStruct_EmbeddedStruct(t *testing.T) {
+	type Base struct {
+		Summary  *string `json:"summary,omitempty"`
+		Priority int     `json:"priority"`
+	}
+	type TestStruct struct {
+		ID uint `json:"id"`
+		Base
+	}
+
+	schema := SchemaFromStruct(TestStruct{})
+
+	assert.Contains(t, schema.Properties, "id")
+	assert.Contains(t, schema.Properties, "summary")
+	assert.Contains(t, schema.Properties, "priority")
+	assert.NotContains(t, schema.Properties, "base")
+	assert.ElementsMatch(t, []string{"id", "priority"}, schema.Required)
+}
+
 // TestSchemaFromStruct_ArrayAndSlice 测试数组和切片类型
 func TestSchemaFromStruct_ArrayAndSlice(t *testing.T) {
 	type TestStruct struct {
@@ -0,0 +1,1394 @@
+package utils
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrUnrecognizedDateTime 无法识别的时间表达式
+var ErrUnrecognizedDateTime = errors.New("无法识别的时间表达式")
+
+// maxRelativeYears 相对时间（"3 天后"、"in 2 hours"）最多偏移的年数，超过时视为无法识别
+const maxRelativeYears = 100
+
+// DateTimeKind 自然语言时间表达式的解析结果类型
+type DateTimeKind string
+
+const (
+	DateTimeKindInstant    DateTimeKind = "instant"    // 时刻，例如 "明天下午三点"、"in 2 hours"
+	DateTimeKindDate       DateTimeKind = "date"       // 某一天（全天），例如 "下周三"、"月底"
+	DateTimeKindRange      DateTimeKind = "range"      // 时间段，例如 "下周"、"明天 9 点到 11 点"
+	DateTimeKindRecurrence DateTimeKind = "recurrence" // 重复规则，例如 "每隔一周的周五"、"every weekday at 9am"
+)
+
+// ResolvedDateTime 时间表达式的解析结果，时间都在解析时指定的时区中
+type ResolvedDateTime struct {
+	Kind   DateTimeKind
+	Start  time.Time  // 时刻、时间段的开始；重复规则为第一次发生的时间
+	End    *time.Time // 结束时间（不包含在内），时刻没有结束时间；全天为最后一天的次日零点
+	AllDay bool       // 只有日期有意义（Start、End 为零点）
+	RRule  string     // 重复规则（RFC 5545 RRULE，不含 "RRULE:" 前缀），仅 Kind 为 recurrence 时有值
+}
+
+// ParseNaturalDateTime 解析中文或英文的自然语言时间表达式，相对时间按 ref 计算，结果在 loc 时区中（nil 表示 UTC）
+//
+// 支持的表达式（不区分大小写，中文数字和全角字符会先转换）：
+//   - 相对日期：今天/明天/后天/大后天/昨天/前天、今晚/明早、3 天后、过两天、today/tomorrow/tonight、in 3 days、2 weeks ago
+//   - 星期：周三/星期三/礼拜三、这周三/下周三/下下周三/上周三、friday/this friday/next friday/last friday
+//   - 周、月、年：本周/下周/周末/下周末、本月/下个月/月初/月底/下个月底、今年/明年、this week/next month/next weekend、
+//     end of next month、beginning of the year
+//   - 某月第 N 个星期几：下个月的第一个周一、本月最后一个周五、the first monday of next month、the last friday of may
+//   - 具体日期：2025年5月6日、5月6号、6号、2025-05-06、may 6th, 2025、6 may、the 15th
+//   - 钟点和时段：下午三点半、晚上8点、3点一刻、15:30、凌晨、上午、3pm、at 10:30、noon、midnight、in the afternoon
+//   - 相对时刻：现在、2小时后、半小时后、30分钟前、now、in 2 hours、in half an hour、45 minutes ago
+//   - 时间段：明天9点到11点、下午3点-5点、5月6日至8日、下周一到周三、from 3 to 5pm、between monday and wednesday
+//   - 重复：每天、每隔一天、每周一三五、每隔一周的周五、隔周周五、每个工作日、每月15号、每月最后一天、
+//     每月第一个周一、每年5月6日、every day、every other friday、every 2 weeks on monday and wednesday、
+//     every weekday at 9am、monthly on the 15th、every last friday、yearly on may 6，
+//     可以带钟点或时段（每周三下午3点到4点），以及次数（共10次、10 times）或截止日期（直到6月底、until june 30）
+//
+// 解析规则：
+//   - 一周从周一开始："这周三" 为本周的周三，"下周三" 为下周的周三，没有修饰的 "周三" 为今天或之后最近的周三
+//   - 没有年份的日期、没有月份的 "6号" 取今天或之后最近的一个；没有日期的钟点已经过去时取明天
+//   - 没有上午/下午的 1 点到 5 点按下午处理（"三点" 为 15:00），24 小时制的 "01:30" 不受影响
+//   - 时间段第二部分的日期相对于第一部分计算，省略的日期和上午/下午沿用第一部分；全天的时间段包含最后一天
+//   - 农历不在支持范围内
+func ParseNaturalDateTime(expr string, ref time.Time, loc *time.Location) (*ResolvedDateTime, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+	normalized := normalizeNaturalDateTime(expr)
+	if normalized == "" {
+		return nil, fmt.Errorf("%w: 表达式为空", ErrUnrecognizedDateTime)
+	}
+
+	for _, parse := range []func(*nlParser) (*ResolvedDateTime, bool){(*nlParser).recurrence, (*nlParser).expression} {
+		p := &nlParser{s: normalized, ref: ref.In(loc), loc: loc}
+		if result, ok := parse(p); ok && p.done() {
+			return result, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnrecognizedDateTime, expr)
+}
+
+// nlReplacer 统一全角字符、同义词和带 "半"、"刻" 的写法
+var nlReplacer = strings.NewReplacer(
+	"０", "0", "１", "1", "２", "2", "３", "3", "４", "4", "５", "5", "６", "6", "７", "7", "８", "8", "９", "9",
+	"：", ":", "，", ",", "。", "", "～", "~", "〜", "~", "—", "-", "–", "-",
+	"星期日", "周日", "星期天", "周日", "礼拜日", "周日", "礼拜天", "周日", "周天", "周日",
+	"星期", "周", "礼拜", "周",
+	"一刻", "15分", "三刻", "45分",
+	"个半小时", "个半小时", "个半钟头", "个半小时", "半个小时", "30分钟", "半小时", "30分钟", "半个钟头", "30分钟", "钟头", "小时",
+	"a.m.", "am", "p.m.", "pm",
+)
+
+// normalizeNaturalDateTime 转换为小写，统一写法，将 "周X" 替换为星期标记，再将中文数字转换为阿拉伯数字
+func normalizeNaturalDateTime(expr string) string {
+	s := nlReplacer.Replace(strings.ToLower(strings.TrimSpace(expr)))
+	return strings.TrimSpace(convertZhNumbers(tokenizeZhWeekdays(s)))
+}
+
+// zhWeekdays 中文星期几到星期标记（RRULE 的 BYDAY 代码，小写）
+var zhWeekdays = map[rune]string{'一': "mo", '二': "tu", '三': "we", '四': "th", '五': "fr", '六': "sa", '日': "su"}
+
+// tokenizeZhWeekdays 将 "周X" 替换为 "@mo" 这样的星期标记，"周一三五"、"周六、日" 替换为 "@mo@we@fr"、"@sa@su"
+// 后面紧跟 "点"、"时"、":" 的数字是钟点而不是星期："周五六点" 为周五 6 点
+func tokenizeZhWeekdays(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(rs); i++ {
+		code, ok := "", false
+		if rs[i] == '周' && i+1 < len(rs) {
+			code, ok = zhWeekdays[rs[i+1]]
+		}
+		if !ok {
+			b.WriteRune(rs[i])
+			continue
+		}
+		b.WriteString("@" + code)
+		i++
+		for {
+			j := i + 1
+			if j < len(rs) && strings.ContainsRune("、,和及与", rs[j]) {
+				j++
+			}
+			if j >= len(rs) {
+				break
+			}
+			next, ok := zhWeekdays[rs[j]]
+			if !ok || (j+1 < len(rs) && strings.ContainsRune("点时:", rs[j+1])) {
+				break
+			}
+			b.WriteString("@" + next)
+			i = j
+		}
+	}
+	return b.String()
+}
+
+// zhDigits 中文数字
+var zhDigits = map[rune]int{'零': 0, '〇': 0, '一': 1, '二': 2, '两': 2, '三': 3, '四': 4, '五': 5, '六': 6, '七': 7, '八': 8, '九': 9}
+
+// convertZhNumbers 将中文数字转换为阿拉伯数字，例如 "十二" → "12"、"二十三" → "23"、"二〇二五" → "2025"
+func convertZhNumbers(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+	for i := 0; i < len(rs); {
+		j := i
+		for j < len(rs) && (rs[j] == '十' || rs[j] == '百' || zhDigits[rs[j]] > 0 || rs[j] == '零' || rs[j] == '〇') {
+			j++
+		}
+		if j == i {
+			b.WriteRune(rs[i])
+			i++
+			continue
+		}
+		b.WriteString(zhNumber(rs[i:j]))
+		i = j
+	}
+	return b.String()
+}
+
+// zhNumber 转换一段中文数字：带 "十"、"百" 的按数值计算，否则逐位转换
+func zhNumber(rs []rune) string {
+	if !strings.ContainsAny(string(rs), "十百") {
+		var b strings.Builder
+		for _, r := range rs {
+			b.WriteString(strconv.Itoa(zhDigits[r]))
+		}
+		return b.String()
+	}
+	total, current := 0, 0
+	for _, r := range rs {
+		switch r {
+		case '十':
+			total += max(current, 1) * 10
+			current = 0
+		case '百':
+			total += max(current, 1) * 100
+			current = 0
+		default:
+			current = zhDigits[r]
+		}
+	}
+	return strconv.Itoa(total + current)
+}
+
+// 英文单词
+const (
+	enNum       = `(\d+|a|an|one|two|three|four|five|six|seven|eight|nine|ten|eleven|twelve)`
+	enWeekday   = `(monday|mon|tuesday|tues|tue|wednesday|wed|thursday|thurs|thur|thu|friday|fri|saturday|sat|sunday|sun)`
+	enMonth     = `(january|jan|february|feb|march|mar|april|apr|may|june|jun|july|jul|august|aug|september|sept|sep|october|oct|november|nov|december|dec)`
+	enOrdinal   = `(first|second|third|fourth|fifth|last|1st|2nd|3rd|4th|5th)`
+	enDaySuffix = `(?:st|nd|rd|th)?`
+)
+
+var enNumbers = map[string]int{
+	"a": 1, "an": 1, "one": 1, "two": 2, "three": 3, "four": 4, "five": 5, "six": 6,
+	"seven": 7, "eight": 8, "nine": 9, "ten": 10, "eleven": 11, "twelve": 12,
+}
+
+var enWeekdays = map[string]string{
+	"monday": "mo", "mon": "mo", "tuesday": "tu", "tues": "tu", "tue": "tu", "wednesday": "we", "wed": "we",
+	"thursday": "th", "thurs": "th", "thur": "th", "thu": "th", "friday": "fr", "fri": "fr",
+	"saturday": "sa", "sat": "sa", "sunday": "su", "sun": "su",
+}
+
+var enOrdinals = map[string]int{
+	"first": 1, "1st": 1, "second": 2, "2nd": 2, "third": 3, "3rd": 3, "fourth": 4, "4th": 4, "fifth": 5, "5th": 5, "last": -1,
+}
+
+var weekdayCodes = map[string]time.Weekday{
+	"su": time.Sunday, "mo": time.Monday, "tu": time.Tuesday, "we": time.Wednesday, "th": time.Thursday, "fr": time.Friday, "sa": time.Saturday,
+}
+
+// enMonthNumber 英文月份名对应的月份
+func enMonthNumber(name string) time.Month {
+	for m := time.January; m <= time.December; m++ {
+		full := strings.ToLower(m.String())
+		if name == full || (len(name) >= 3 && strings.HasPrefix(full, name)) {
+			return m
+		}
+	}
+	return 0
+}
+
+// number 解析阿拉伯数字或英文数字单词
+func number(s string) int {
+	if n, ok := enNumbers[s]; ok {
+		return n
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+var (
+	reSkip = regexp.MustCompile(`^(?:[\s,的]+|(?:on|the)\s+)`)
+	reNow  = regexp.MustCompile(`^(?:right\s+now|now|现在|此刻|马上|立刻)`)
+
+	// 相对时刻
+	reZhRelHours   = regexp.MustCompile(`^(\d+)个?(半)?小时(后|以后|之后|前|以前|之前)`)
+	reZhRelMinutes = regexp.MustCompile(`^(\d+)分钟?(后|以后|之后|前|以前|之前)`)
+	reEnInMinutes  = regexp.MustCompile(`^in\s+(?:` + enNum + `|(half)\s+an?)\s+(minutes?|mins?|hours?|hrs?)\b`)
+	reEnRelMinutes = regexp.MustCompile(`^` + enNum + `\s+(minutes?|mins?|hours?|hrs?)\s+(from\s+now|later|ago)\b`)
+
+	// 日期
+	reZhRelDay    = regexp.MustCompile(`^(大后天|后天|明天|明日|明儿|今天|今日|今儿|大前天|前天|昨天|昨日|今晚|今早|明晚|明早|昨晚)`)
+	reZhOffset    = regexp.MustCompile(`^(\d+)个?(天|日|周|月|年)(后|以后|之后|前|以前|之前)`)
+	reZhAfterDays = regexp.MustCompile(`^过(\d+)天`)
+	reZhWeekday   = regexp.MustCompile(`^(这个?|本|下下个?|下个?|上上个?|上个?)?@([a-z]{2})`)
+	reZhWeekend   = regexp.MustCompile(`^(这个?|本|下个?|上个?)?周末`)
+	reZhWeek      = regexp.MustCompile(`^(这个?|本|下下个?|下个?|上上个?|上个?)周`)
+	reZhMonth     = regexp.MustCompile(`^(这个?|本|下下个?|下个?|上个?)月`)
+	reZhMonthEdge = regexp.MustCompile(`^月(底|末|初)`)
+	reZhYear      = regexp.MustCompile(`^(今|明|去|前|后)年`)
+	reZhFullYear  = regexp.MustCompile(`^(\d{4})年`)
+	reZhMonthDay  = regexp.MustCompile(`^(\d{1,2})月(\d{1,2})([日号])?`)
+	reZhMonthOnly = regexp.MustCompile(`^(\d{1,2})月份?`)
+	reZhDay       = regexp.MustCompile(`^(\d{1,2})[日号]`)
+	reZhMonthPart = regexp.MustCompile(`^(?:的)?(?:(底|末)|(初)|最后1天|第(\d)个?@([a-z]{2})|最后1?个?@([a-z]{2})|(\d{1,2})[日号])`)
+	reISODate     = regexp.MustCompile(`^(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\b`)
+
+	reEnRelDay    = regexp.MustCompile(`^(today|tonight|tomorrow|tmr|yesterday|day\s+after\s+tomorrow|day\s+before\s+yesterday)\b`)
+	reEnOffset    = regexp.MustCompile(`^in\s+` + enNum + `\s+(days?|weeks?|months?|years?)\b`)
+	reEnRelOffset = regexp.MustCompile(`^` + enNum + `\s+(days?|weeks?|months?|years?)\s+(from\s+now|later|ago)\b`)
+	reEnWeekday   = regexp.MustCompile(`^(?:(this|next|last|coming)\s+)?` + enWeekday + `\b`)
+	reEnSpan      = regexp.MustCompile(`^(?:(this|next|last)\s+)?(weekend|week|month|year)\b`)
+	reEnEdge      = regexp.MustCompile(`^(end|beginning|start)\s+of\s+(?:the\s+)?(?:(this|next|last)\s+)?(week|month|year)\b`)
+	reEnNth       = regexp.MustCompile(`^` + enOrdinal + `\s+` + enWeekday + `\s+(?:of|in)\s+(?:the\s+)?(?:(?:(this|next|last)\s+)?month|` + enMonth + `(?:\s+(\d{4}))?)\b`)
+	reEnMonthDay  = regexp.MustCompile(`^` + enMonth + `\.?\s+(\d{1,2})` + enDaySuffix + `\b(?:,?\s*(\d{4})\b)?`)
+	reEnDayMonth  = regexp.MustCompile(`^(\d{1,2})` + enDaySuffix + `\s+(?:of\s+)?` + enMonth + `\b(?:,?\s*(\d{4})\b)?`)
+	reEnOrdinDay  = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)\b`)
+
+	// 钟点和时段
+	reZhPeriod   = regexp.MustCompile(`^(凌晨|早上|早晨|清晨|上午|中午|正午|下午|午后|傍晚|晚上|晚间|夜里|夜间|深夜|半夜)`)
+	reZhClock    = regexp.MustCompile(`^(\d{1,2})(?:点钟?|时)(?:(\d{1,2})分?|(半)|整)?`)
+	reColonClock = regexp.MustCompile(`^(?:at\s+)?(\d{1,2}):(\d{2})(?:\s*(am|pm)\b)?`)
+	reEnClock    = regexp.MustCompile(`^(?:at\s+)?(\d{1,2})\s*(am|pm)\b`)
+	reEnAtClock  = regexp.MustCompile(`^(?:at\s+(\d{1,2})(?:\s*o'?clock)?|(\d{1,2})\s*o'?clock)\b`)
+	reBareHour   = regexp.MustCompile(`^(\d{1,2})\b`)
+	reEnNoon     = regexp.MustCompile(`^(?:at\s+)?(noon|midday|midnight)\b`)
+	reEnPeriod   = regexp.MustCompile(`^(?:in\s+the\s+|this\s+|at\s+)?(morning|afternoon|evening|night)\b`)
+
+	// 时间段
+	reFrom     = regexp.MustCompile(`^(?:from|从)\s*`)
+	reBetween  = regexp.MustCompile(`^between\s+`)
+	reRangeSep = regexp.MustCompile(`^(?:到|至|~|-|(?:to|until|till|through|thru)\b)`)
+	reAnd      = regexp.MustCompile(`^and\b`)
+)
+
+// nlPeriodKind 时段内钟点的换算方式
+type nlPeriodKind int
+
+const (
+	periodAM    nlPeriodKind = iota // 上午：钟点不变
+	periodNoon                      // 中午：1 点到 3 点为下午
+	periodPM                        // 下午、晚上：12 点以前加 12
+	periodNight                     // 夜里：6 点以前为次日凌晨
+)
+
+// nlPeriod 一天中的时段，[from, to) 为小时
+type nlPeriod struct {
+	from, to int
+	kind     nlPeriodKind
+}
+
+var nlPeriods = map[string]nlPeriod{
+	"凌晨": {0, 6, periodAM}, "早上": {6, 9, periodAM}, "早晨": {6, 9, periodAM}, "清晨": {6, 9, periodAM},
+	"上午": {8, 12, periodAM}, "中午": {11, 13, periodNoon}, "正午": {11, 13, periodNoon},
+	"下午": {13, 18, periodPM}, "午后": {13, 18, periodPM}, "傍晚": {17, 19, periodPM},
+	"晚上": {18, 24, periodPM}, "晚间": {18, 24, periodPM},
+	"夜里": {21, 24, periodNight}, "夜间": {21, 24, periodNight}, "深夜": {22, 24, periodNight}, "半夜": {22, 24, periodNight},
+	"morning": {6, 12, periodAM}, "afternoon": {12, 18, periodPM}, "evening": {18, 24, periodPM}, "night": {20, 24, periodNight},
+}
+
+// hour 将时段内的钟点换算为 24 小时制，超过 24 表示次日
+func (pd nlPeriod) hour(h int) int {
+	switch pd.kind {
+	case periodAM:
+		// 凌晨 12 点是当天的 0 点
+		if h == 12 && pd.from == 0 {
+			return 0
+		}
+	case periodNoon:
+		if h <= 3 {
+			return h + 12
+		}
+	case periodPM:
+		if h < 12 {
+			return h + 12
+		}
+		if h == 12 && pd.from >= 17 {
+			return 24
+		}
+	case periodNight:
+		switch {
+		case h == 12:
+			return 24
+		case h >= 6 && h < 12:
+			return h + 12
+		case h < 6:
+			return h + 24
+		}
+	}
+	return h
+}
+
+// nlDays 日期部分：[start, end) 为一天或多天（周、月、周末等），period 为日期隐含的时段（"今晚"）
+type nlDays struct {
+	start, end time.Time
+	period     *nlPeriod
+	explicit   bool // 由表达式给出（而不是从时间段的第一部分沿用）
+}
+
+// nlTime 钟点或时段
+type nlTime struct {
+	hour, minute int
+	hasClock     bool
+	meridiem     string // am、pm
+	twentyFour   bool   // "15:30"、"01:30" 这样的 24 小时制写法
+	period       *nlPeriod
+}
+
+// hour24 换算为 24 小时制，超过 24 表示次日
+func (t *nlTime) hour24() int {
+	h := t.hour
+	switch {
+	case t.meridiem == "pm" && h < 12:
+		return h + 12
+	case t.meridiem == "am" && h == 12:
+		return 0
+	case t.meridiem != "":
+		return h
+	case t.period != nil:
+		return t.period.hour(h)
+	case !t.twentyFour && h >= 1 && h <= 5:
+		return h + 12
+	}
+	return h
+}
+
+// nlMoment 单个时间点：日期和时间至少有一个，或者是相对时刻
+type nlMoment struct {
+	days    *nlDays
+	tm      *nlTime
+	instant *time.Time
+}
+
+// nlParser 从左到右解析规范化后的表达式，s 为尚未解析的部分
+type nlParser struct {
+	s   string
+	ref time.Time
+	loc *time.Location
+}
+
+func (p *nlParser) skip() {
+	for {
+		m := reSkip.FindString(p.s)
+		if m == "" {
+			return
+		}
+		p.s = p.s[len(m):]
+	}
+}
+
+func (p *nlParser) done() bool {
+	p.skip()
+	return p.s == ""
+}
+
+// consume 匹配并去掉开头的表达式
+func (p *nlParser) consume(re *regexp.Regexp) []string {
+	p.skip()
+	m := re.FindStringSubmatch(p.s)
+	if m == nil {
+		return nil
+	}
+	p.s = p.s[len(m[0]):]
+	return m
+}
+
+func (p *nlParser) today() time.Time {
+	return p.date(p.ref.Year(), p.ref.Month(), p.ref.Day())
+}
+
+func (p *nlParser) date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, p.loc)
+}
+
+// validDate 返回日期，日期不存在（例如 2 月 30 日）时返回 false
+func (p *nlParser) validDate(year int, month time.Month, day int) (time.Time, bool) {
+	t := p.date(year, month, day)
+	return t, t.Month() == month && t.Day() == day
+}
+
+func addDays(t time.Time, n int) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day()+n, 0, 0, 0, 0, t.Location())
+}
+
+func singleDay(t time.Time) *nlDays {
+	return &nlDays{start: t, end: addDays(t, 1), explicit: true}
+}
+
+// weekStart 返回 t 所在周的周一
+func weekStart(t time.Time) time.Time {
+	return addDays(t, -((int(t.Weekday()) + 6) % 7))
+}
+
+// relativeShift 这/下/上 等修饰对应的周期偏移
+func relativeShift(word string) int {
+	switch strings.TrimSuffix(word, "个") {
+	case "下", "next":
+		return 1
+	case "下下":
+		return 2
+	case "上", "last":
+		return -1
+	case "上上":
+		return -2
+	}
+	return 0
+}
+
+// nthWeekday 返回某月第 n 个星期几（n 为 -1 表示最后一个）
+func (p *nlParser) nthWeekday(year int, month time.Month, n int, wd time.Weekday) (time.Time, bool) {
+	if n < 0 {
+		last := p.date(year, month+1, 0)
+		return addDays(last, -((int(last.Weekday()) - int(wd) + 7) % 7)), true
+	}
+	first := p.date(year, month, 1)
+	t := addDays(first, (int(wd)-int(first.Weekday())+7)%7+7*(n-1))
+	return t, t.Month() == first.Month()
+}
+
+// upcomingDay 返回今天或之后最近的 day 号（跳过没有该日期的月份）
+func (p *nlParser) upcomingDay(day int) (time.Time, bool) {
+	today := p.today()
+	for i := 0; i < 12; i++ {
+		if t, ok := p.validDate(today.Year(), today.Month()+time.Month(i), day); ok && !t.Before(today) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// upcomingMonthDay 返回今天或之后最近的某月某日（2 月 29 日取最近的闰年）
+func (p *nlParser) upcomingMonthDay(month time.Month, day int) (time.Time, bool) {
+	today := p.today()
+	for i := 0; i < 8; i++ {
+		if t, ok := p.validDate(today.Year()+i, month, day); ok && !t.Before(today) {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// monthDays 某月的日期部分：没有后缀时为整月，否则为 "底"、"初"、"第N个周X"、"N号" 指定的一天
+func (p *nlParser) monthDays(year int, month time.Month) (*nlDays, bool) {
+	first := p.date(year, month, 1)
+	m := p.consume(reZhMonthPart)
+	switch {
+	case m == nil:
+		return &nlDays{start: first, end: first.AddDate(0, 1, 0), explicit: true}, true
+	case m[1] != "" || strings.Contains(m[0], "最后1天"):
+		return singleDay(p.date(year, month+1, 0)), true
+	case m[2] != "":
+		return singleDay(first), true
+	case m[4] != "":
+		n, _ := strconv.Atoi(m[3])
+		t, ok := p.nthWeekday(year, month, n, weekdayCodes[m[4]])
+		return singleDay(t), ok
+	case m[5] != "":
+		t, _ := p.nthWeekday(year, month, -1, weekdayCodes[m[5]])
+		return singleDay(t), true
+	default:
+		day, _ := strconv.Atoi(m[6])
+		t, ok := p.validDate(year, month, day)
+		return singleDay(t), ok
+	}
+}
+
+// relativeInstant 解析相对时刻："现在"、"2小时后"、"in 30 minutes"、"2 hours ago"
+func (p *nlParser) relativeInstant() (time.Time, bool) {
+	now := p.ref.Truncate(time.Minute)
+	if p.consume(reNow) != nil {
+		return now, true
+	}
+
+	var minutes int
+	sign := 1
+	if m := p.consume(reZhRelHours); m != nil {
+		minutes = number(m[1]) * 60
+		if m[2] != "" {
+			minutes += 30
+		}
+		if strings.HasSuffix(m[3], "前") {
+			sign = -1
+		}
+	} else if m := p.consume(reZhRelMinutes); m != nil {
+		minutes = number(m[1])
+		if strings.HasSuffix(m[2], "前") {
+			sign = -1
+		}
+	} else if m := p.consume(reEnInMinutes); m != nil {
+		minutes = number(m[1])
+		if m[2] != "" {
+			minutes = 0
+			if strings.HasPrefix(m[3], "h") {
+				minutes = 30
+			}
+		} else if strings.HasPrefix(m[3], "h") {
+			minutes *= 60
+		}
+	} else if m := p.consume(reEnRelMinutes); m != nil {
+		minutes = number(m[1])
+		if strings.HasPrefix(m[2], "h") {
+			minutes *= 60
+		}
+		if m[3] == "ago" {
+			sign = -1
+		}
+	} else {
+		return time.Time{}, false
+	}
+	if minutes <= 0 || minutes > maxRelativeYears*366*24*60 {
+		return time.Time{}, false
+	}
+	return now.Add(time.Duration(sign*minutes) * time.Minute), true
+}
+
+// offsetDate 按单位平移今天的日期，偏移超过 maxRelativeYears 年时返回 false
+func (p *nlParser) offsetDate(n int, unit string) (time.Time, bool) {
+	today := p.today()
+	if n > maxRelativeYears*366 || n < -maxRelativeYears*366 {
+		return time.Time{}, false
+	}
+	var t time.Time
+	switch {
+	case strings.HasPrefix(unit, "周"), strings.HasPrefix(unit, "week"):
+		t = addDays(today, 7*n)
+	case strings.HasPrefix(unit, "月"), strings.HasPrefix(unit, "month"):
+		t = today.AddDate(0, n, 0)
+	case strings.HasPrefix(unit, "年"), strings.HasPrefix(unit, "year"):
+		t = today.AddDate(n, 0, 0)
+	default:
+		t = addDays(today, n)
+	}
+	if t.After(today.AddDate(maxRelativeYears, 0, 0)) || t.Before(today.AddDate(-maxRelativeYears, 0, 0)) {
+		return time.Time{}, false
+	}
+	return t, true
+}
+
+// days 解析日期部分
+func (p *nlParser) days() (*nlDays, bool) {
+	today := p.today()
+	save := p.s
+
+	if m := p.consume(reZhRelDay); m != nil {
+		offsets := map[string]int{"大后天": 3, "后天": 2, "明天": 1, "明日": 1, "明儿": 1, "明晚": 1, "明早": 1,
+			"昨天": -1, "昨日": -1, "昨晚": -1, "前天": -2, "大前天": -3}
+		d := singleDay(addDays(today, offsets[m[1]]))
+		switch m[1] {
+		case "今晚", "明晚", "昨晚":
+			pd := nlPeriods["晚上"]
+			d.period = &pd
+		case "今早", "明早":
+			pd := nlPeriods["早上"]
+			d.period = &pd
+		}
+		return d, true
+	}
+	if m := p.consume(reEnRelDay); m != nil {
+		word := strings.Join(strings.Fields(m[1]), " ")
+		offsets := map[string]int{"tomorrow": 1, "tmr": 1, "yesterday": -1, "day after tomorrow": 2, "day before yesterday": -2}
+		d := singleDay(addDays(today, offsets[word]))
+		if word == "tonight" {
+			pd := nlPeriods["evening"]
+			d.period = &pd
+		}
+		return d, true
+	}
+	if m := p.consume(reZhOffset); m != nil {
+		n := number(m[1])
+		if strings.HasSuffix(m[3], "前") {
+			n = -n
+		}
+		t, ok := p.offsetDate(n, m[2])
+		return singleDay(t), ok
+	}
+	if m := p.consume(reZhAfterDays); m != nil {
+		t, ok := p.offsetDate(number(m[1]), "天")
+		return singleDay(t), ok
+	}
+	if m := p.consume(reEnOffset); m != nil {
+		t, ok := p.offsetDate(number(m[1]), m[2])
+		return singleDay(t), ok
+	}
+	if m := p.consume(reEnRelOffset); m != nil {
+		n := number(m[1])
+		if m[3] == "ago" {
+			n = -n
+		}
+		t, ok := p.offsetDate(n, m[2])
+		return singleDay(t), ok
+	}
+
+	if m := p.consume(reZhWeekend); m != nil {
+		start := addDays(weekStart(today), 7*relativeShift(m[1])+5)
+		return &nlDays{start: start, end: addDays(start, 2), explicit: true}, true
+	}
+	if m := p.consume(reZhWeekday); m != nil {
+		// 只接受一个星期几，"@mo@we" 这样的列表只用于重复规则
+		if strings.HasPrefix(p.s, "@") {
+			p.s = save
+			return nil, false
+		}
+		return singleDay(p.weekday(m[1], weekdayCodes[m[2]])), true
+	}
+	if m := p.consume(reZhWeek); m != nil {
+		start := addDays(weekStart(today), 7*relativeShift(m[1]))
+		return &nlDays{start: start, end: addDays(start, 7), explicit: true}, true
+	}
+	if m := p.consume(reZhMonth); m != nil {
+		month := today.AddDate(0, relativeShift(m[1]), 1-today.Day())
+		return p.monthDays(month.Year(), month.Month())
+	}
+	if m := p.consume(reZhMonthEdge); m != nil {
+		if m[1] == "初" {
+			return singleDay(p.date(today.Year(), today.Month(), 1)), true
+		}
+		return singleDay(p.date(today.Year(), today.Month()+1, 0)), true
+	}
+	if m := p.consume(reISODate); m != nil {
+		t, ok := p.validDate(number(m[1]), time.Month(number(m[2])), number(m[3]))
+		return singleDay(t), ok
+	}
+
+	year, hasYear := 0, false
+	if m := p.consume(reZhYear); m != nil {
+		year, hasYear = today.Year()+map[string]int{"明": 1, "去": -1, "前": -2, "后": 2}[m[1]], true
+	} else if m := p.consume(reZhFullYear); m != nil {
+		year, hasYear = number(m[1]), true
+	}
+	if m := p.consume(reZhMonthDay); m != nil && (m[3] != "" || !strings.ContainsAny(firstRune(p.s), "点时:")) {
+		month, day := time.Month(number(m[1])), number(m[2])
+		if hasYear {
+			t, ok := p.validDate(year, month, day)
+			return singleDay(t), ok
+		}
+		t, ok := p.upcomingMonthDay(month, day)
+		return singleDay(t), ok
+	} else if m != nil {
+		p.s = m[0] + p.s
+	}
+	if m := p.consume(reZhMonthOnly); m != nil {
+		month := time.Month(number(m[1]))
+		if month < time.January || month > time.December {
+			return nil, false
+		}
+		if !hasYear {
+			year = today.Year()
+			if month < today.Month() {
+				year++
+			}
+		}
+		return p.monthDays(year, month)
+	}
+	if hasYear {
+		switch {
+		case strings.HasPrefix(p.s, "底"), strings.HasPrefix(p.s, "末"):
+			p.s = p.s[len("底"):]
+			return singleDay(p.date(year, time.December, 31)), true
+		case strings.HasPrefix(p.s, "初"):
+			p.s = p.s[len("初"):]
+			return singleDay(p.date(year, time.January, 1)), true
+		}
+		start := p.date(year, time.January, 1)
+		return &nlDays{start: start, end: start.AddDate(1, 0, 0), explicit: true}, true
+	}
+	if m := p.consume(reZhDay); m != nil {
+		t, ok := p.upcomingDay(number(m[1]))
+		return singleDay(t), ok
+	}
+
+	if m := p.consume(reEnEdge); m != nil {
+		return p.enEdge(m[1], m[2], m[3]), true
+	}
+	if m := p.consume(reEnNth); m != nil {
+		year, month := today.Year(), today.Month()
+		if m[4] != "" {
+			month = enMonthNumber(m[4])
+			if m[5] != "" {
+				year = number(m[5])
+			} else if month < today.Month() {
+				year++
+			}
+		} else {
+			shifted := today.AddDate(0, relativeShift(m[3]), 1-today.Day())
+			year, month = shifted.Year(), shifted.Month()
+		}
+		t, ok := p.nthWeekday(year, month, enOrdinals[m[1]], weekdayCodes[enWeekdays[m[2]]])
+		return singleDay(t), ok
+	}
+	if m := p.consume(reEnWeekday); m != nil {
+		return singleDay(p.weekday(m[1], weekdayCodes[enWeekdays[m[2]]])), true
+	}
+	if m := p.consume(reEnSpan); m != nil {
+		shift := relativeShift(m[1])
+		switch m[2] {
+		case "weekend":
+			start := addDays(weekStart(today), 7*shift+5)
+			return &nlDays{start: start, end: addDays(start, 2), explicit: true}, true
+		case "week":
+			if m[1] == "" {
+				break
+			}
+			start := addDays(weekStart(today), 7*shift)
+			return &nlDays{start: start, end: addDays(start, 7), explicit: true}, true
+		case "month":
+			if m[1] == "" {
+				break
+			}
+			start := today.AddDate(0, shift, 1-today.Day())
+			return &nlDays{start: start, end: start.AddDate(0, 1, 0), explicit: true}, true
+		case "year":
+			if m[1] == "" {
+				break
+			}
+			start := p.date(today.Year()+shift, time.January, 1)
+			return &nlDays{start: start, end: start.AddDate(1, 0, 0), explicit: true}, true
+		}
+		p.s = save
+		return nil, false
+	}
+	if m := p.consume(reEnMonthDay); m != nil {
+		return p.enDate(enMonthNumber(m[1]), number(m[2]), m[3])
+	}
+	if m := p.consume(reEnDayMonth); m != nil {
+		return p.enDate(enMonthNumber(m[2]), number(m[1]), m[3])
+	}
+	if m := p.consume(reEnOrdinDay); m != nil {
+		t, ok := p.upcomingDay(number(m[1]))
+		return singleDay(t), ok
+	}
+
+	p.s = save
+	return nil, false
+}
+
+func firstRune(s string) string {
+	for _, r := range s {
+		return string(r)
+	}
+	return ""
+}
+
+// weekday 按修饰解析星期几：这/this 为本周，下/next 为下周，上/last 为上周，没有修饰或 coming 为今天或之后最近的一天
+func (p *nlParser) weekday(qualifier string, wd time.Weekday) time.Time {
+	today := p.today()
+	if qualifier == "" || qualifier == "coming" {
+		return addDays(today, (int(wd)-int(today.Weekday())+7)%7)
+	}
+	return addDays(weekStart(today), 7*relativeShift(qualifier)+(int(wd)+6)%7)
+}
+
+// enEdge 解析 "end of next month"、"beginning of the week" 等
+func (p *nlParser) enEdge(edge, qualifier, unit string) *nlDays {
+	today := p.today()
+	shift := relativeShift(qualifier)
+	var first, last time.Time
+	switch unit {
+	case "week":
+		first = addDays(weekStart(today), 7*shift)
+		last = addDays(first, 6)
+	case "month":
+		first = today.AddDate(0, shift, 1-today.Day())
+		last = first.AddDate(0, 1, -1)
+	default:
+		first = p.date(today.Year()+shift, time.January, 1)
+		last = p.date(today.Year()+shift, time.December, 31)
+	}
+	if edge == "end" {
+		return singleDay(last)
+	}
+	return singleDay(first)
+}
+
+// enDate 英文的月、日和可选的年份
+func (p *nlParser) enDate(month time.Month, day int, year string) (*nlDays, bool) {
+	if year != "" {
+		t, ok := p.validDate(number(year), month, day)
+		return singleDay(t), ok
+	}
+	t, ok := p.upcomingMonthDay(month, day)
+	return singleDay(t), ok
+}
+
+// timeOfDay 解析钟点和时段，时段可以在钟点之前（"下午3点"）或之后（"3 in the afternoon"）
+func (p *nlParser) timeOfDay() (*nlTime, bool) {
+	t := &nlTime{}
+	period := func() {
+		if t.period != nil {
+			return
+		}
+		if m := p.consume(reZhPeriod); m != nil {
+			pd := nlPeriods[m[1]]
+			t.period = &pd
+		} else if m := p.consume(reEnPeriod); m != nil {
+			pd := nlPeriods[m[1]]
+			t.period = &pd
+		}
+	}
+
+	period()
+	if m := p.consume(reZhClock); m != nil {
+		t.hour, t.hasClock = number(m[1]), true
+		if m[2] != "" {
+			t.minute = number(m[2])
+		} else if m[3] != "" {
+			t.minute = 30
+		}
+	} else if m := p.consume(reColonClock); m != nil {
+		t.hour, t.minute, t.meridiem, t.hasClock = number(m[1]), number(m[2]), m[3], true
+		t.twentyFour = m[3] == "" && (len(m[1]) == 2 || t.hour == 0)
+	} else if m := p.consume(reEnClock); m != nil {
+		t.hour, t.meridiem, t.hasClock = number(m[1]), m[2], true
+	} else if m := p.consume(reEnAtClock); m != nil {
+		t.hour, t.hasClock = number(m[1]+m[2]), true
+	} else if m := p.consume(reBareHour); m != nil {
+		// 单独的数字只在后面是时间段分隔符或时段时作为钟点："3 to 5pm"、"3 in the afternoon"
+		rest := strings.TrimLeft(p.s, " ")
+		if !reRangeSep.MatchString(rest) && !reEnPeriod.MatchString(rest) {
+			p.s = m[0] + p.s
+		} else {
+			t.hour, t.hasClock = number(m[1]), true
+		}
+	} else if m := p.consume(reEnNoon); m != nil {
+		t.hour, t.hasClock, t.twentyFour = 12, true, true
+		if m[1] == "midnight" {
+			t.hour = 24
+		}
+	}
+	if t.hasClock {
+		period()
+	}
+
+	if t.hasClock && (t.hour > 24 || t.minute > 59 || (t.meridiem != "" && (t.hour == 0 || t.hour > 12))) {
+		return nil, false
+	}
+	return t, t.hasClock || t.period != nil
+}
+
+// moment 解析单个时间点：相对时刻，或日期和时间（顺序不限，至少有一个）
+func (p *nlParser) moment() (*nlMoment, bool) {
+	if t, ok := p.relativeInstant(); ok {
+		return &nlMoment{instant: &t}, true
+	}
+
+	m := &nlMoment{}
+	for progress := true; progress; {
+		progress = false
+		if m.days == nil {
+			if d, ok := p.days(); ok {
+				m.days, progress = d, true
+			} else if d != nil {
+				return nil, false
+			}
+		}
+		if m.tm == nil {
+			if t, ok := p.timeOfDay(); ok {
+				m.tm, progress = t, true
+			}
+		}
+	}
+	return m, m.days != nil || m.tm != nil
+}
+
+// resolve 计算时间点对应的时刻、日期或时间段
+func (p *nlParser) resolve(m *nlMoment) (*ResolvedDateTime, bool) {
+	if m.instant != nil {
+		return &ResolvedDateTime{Kind: DateTimeKindInstant, Start: *m.instant}, true
+	}
+
+	days := m.days
+	if days == nil {
+		days = singleDay(p.today())
+		days.explicit = false
+	}
+	tm := m.tm
+	if tm == nil && days.period != nil {
+		tm = &nlTime{}
+	}
+	if tm != nil && tm.period == nil {
+		tm.period = days.period
+	}
+
+	if tm == nil {
+		kind := DateTimeKindDate
+		if !days.end.Equal(addDays(days.start, 1)) {
+			kind = DateTimeKindRange
+		}
+		end := days.end
+		return &ResolvedDateTime{Kind: kind, Start: days.start, End: &end, AllDay: true}, true
+	}
+	// 钟点和时段只能用于某一天
+	if !days.end.Equal(addDays(days.start, 1)) {
+		return nil, false
+	}
+
+	day := days.start
+	if tm.hasClock {
+		start := time.Date(day.Year(), day.Month(), day.Day(), tm.hour24(), tm.minute, 0, 0, p.loc)
+		if !days.explicit && start.Before(p.ref) {
+			start = time.Date(day.Year(), day.Month(), day.Day()+1, tm.hour24(), tm.minute, 0, 0, p.loc)
+		}
+		return &ResolvedDateTime{Kind: DateTimeKindInstant, Start: start}, true
+	}
+	start := time.Date(day.Year(), day.Month(), day.Day(), tm.period.from, 0, 0, 0, p.loc)
+	end := time.Date(day.Year(), day.Month(), day.Day(), tm.period.to, 0, 0, 0, p.loc)
+	return &ResolvedDateTime{Kind: DateTimeKindRange, Start: start, End: &end}, true
+}
+
+// expression 解析时间点或时间段："明天下午3点"、"下午3点到5点"、"5月6日至8日"、"between monday and wednesday"
+func (p *nlParser) expression() (*ResolvedDateTime, bool) {
+	between := p.consume(reBetween) != nil
+	if !between {
+		p.consume(reFrom)
+	}
+	first, ok := p.moment()
+	if !ok {
+		return nil, false
+	}
+	firstResult, ok := p.resolve(first)
+	if !ok {
+		return nil, false
+	}
+	if p.consume(reRangeSep) == nil && !(between && p.consume(reAnd) != nil) {
+		return firstResult, !between
+	}
+
+	// 第二部分的日期相对于第一部分计算："下周一到周三"、"5月6日到8日"
+	sub := &nlParser{s: p.s, ref: firstResult.Start, loc: p.loc}
+	second, ok := sub.moment()
+	if !ok {
+		return nil, false
+	}
+	p.s = sub.s
+	p.inherit(first, second, firstResult)
+	if firstResult, ok = p.resolve(first); !ok {
+		return nil, false
+	}
+	secondResult, ok := sub.resolve(second)
+	if !ok {
+		return nil, false
+	}
+
+	result := &ResolvedDateTime{Kind: DateTimeKindRange, Start: firstResult.Start, AllDay: firstResult.AllDay && secondResult.AllDay}
+	end := secondResult.Start
+	if secondResult.AllDay || (secondResult.Kind == DateTimeKindRange && second.tm != nil && !second.tm.hasClock) {
+		end = *secondResult.End
+	}
+	// "晚上11点到凌晨1点"：结束时间没有指定日期时顺延到次日
+	if !end.After(result.Start) && second.days != nil && !second.days.explicit {
+		end = end.AddDate(0, 0, 1)
+	}
+	if !end.After(result.Start) {
+		return nil, false
+	}
+	result.End = &end
+	return result, true
+}
+
+// inherit 时间段的第二部分沿用第一部分的日期和上午/下午；"3 to 5pm" 的第一部分沿用第二部分的 pm
+func (p *nlParser) inherit(first, second *nlMoment, firstResult *ResolvedDateTime) {
+	if second.days == nil && second.instant == nil && second.tm != nil {
+		if first.days != nil && first.days.end.Equal(addDays(first.days.start, 1)) {
+			second.days = &nlDays{start: first.days.start, end: first.days.end, period: first.days.period}
+		} else {
+			day := p.date(firstResult.Start.Year(), firstResult.Start.Month(), firstResult.Start.Day())
+			second.days = &nlDays{start: day, end: addDays(day, 1)}
+		}
+	}
+	if first.tm == nil || second.tm == nil || !first.tm.hasClock || !second.tm.hasClock {
+		return
+	}
+	if second.tm.meridiem == "" && second.tm.period == nil && !second.tm.twentyFour {
+		if first.tm.meridiem != "" {
+			second.tm.meridiem = first.tm.meridiem
+		} else if first.tm.period != nil {
+			second.tm.period = first.tm.period
+		}
+	}
+	if first.tm.meridiem == "" && first.tm.period == nil && !first.tm.twentyFour && second.tm.meridiem != "" {
+		candidate := *first.tm
+		candidate.meridiem = second.tm.meridiem
+		if candidate.hour24() <= second.tm.hour24() {
+			first.tm.meridiem = second.tm.meridiem
+		}
+	}
+}
+
+var (
+	reZhEveryDay     = regexp.MustCompile(`^每(?:隔(\d+)|(\d+))?个?[天日]`)
+	reZhEveryWorkday = regexp.MustCompile(`^(?:每个?工作日|工作日每天)`)
+	reZhEveryWeekend = regexp.MustCompile(`^每个?周末`)
+	reZhEveryWeek    = regexp.MustCompile(`^(?:每隔(\d+)个?周|每(\d+)个?周|隔周|每个?周|每个?)(?:的)?((?:@[a-z]{2})*)`)
+	reZhEveryMonth   = regexp.MustCompile(`^每(?:隔(\d+)|(\d+))?个?月`)
+	reZhEveryYear    = regexp.MustCompile(`^每年(?:的)?(?:(\d{1,2})月(\d{1,2})[日号]?)?`)
+	reZhCount        = regexp.MustCompile(`^(?:共|一共|重复)?(\d+)次`)
+	reZhUntil        = regexp.MustCompile(`^(?:直到|截止到?|截至)`)
+	reZhUntilEnd     = regexp.MustCompile(`^为止`)
+
+	reEnEveryDay     = regexp.MustCompile(`^(?:every\s+(?:(other)\s+|` + enNum + `\s+)?days?|daily|everyday)\b`)
+	reEnEveryWorkday = regexp.MustCompile(`^(?:every\s+(?:weekday|working\s+day|business\s+day)|weekdays)\b`)
+	reEnEveryWeekend = regexp.MustCompile(`^every\s+weekend\b`)
+	reEnEveryWeek    = regexp.MustCompile(`^(?:every\s+(?:(other)\s+|` + enNum + `\s+)?weeks?|weekly|(biweekly|fortnightly))\b(?:\s+on\b)?`)
+	reEnEveryDays    = regexp.MustCompile(`^every\s+(?:(other)\s+)?` + enWeekday + `\b`)
+	reEnListWeekday  = regexp.MustCompile(`^(?:,|and\b|&|/|or\b)?\s*` + enWeekday + `\b`)
+	reEnEveryNth     = regexp.MustCompile(`^every\s+` + enOrdinal + `\s+` + enWeekday + `\b(?:\s+of\s+(?:the|every)\s+month\b)?`)
+	reEnEveryMonth   = regexp.MustCompile(`^(?:every\s+(?:(other)\s+|` + enNum + `\s+)?months?|monthly)\b(?:\s+on\b)?`)
+	reEnMonthPart    = regexp.MustCompile(`^(?:(last)\s+day|` + enOrdinal + `\s+` + enWeekday + `|(\d{1,2})` + enDaySuffix + `)\b`)
+	reEnEveryYear    = regexp.MustCompile(`^(?:every\s+year|yearly|annually)\b(?:\s+on\b)?`)
+	reEnCount        = regexp.MustCompile(`^(?:for\s+)?` + enNum + `\s+(?:times|occurrences)\b`)
+	reEnUntil        = regexp.MustCompile(`^(?:until|till|through)\b`)
+)
+
+// nlRule 重复规则
+type nlRule struct {
+	freq       string
+	interval   int
+	byMonth    int
+	byMonthDay int // -1 表示最后一天
+	byDay      []string
+	count      int
+	until      *time.Time // 截止日期（包含当天）
+	matches    func(day time.Time) bool
+}
+
+// String 生成 RRULE，属性顺序与日历的 RRule 一致
+func (r *nlRule) String(allDay bool, loc *time.Location) string {
+	parts := []string{"FREQ=" + r.freq}
+	if r.interval > 1 {
+		parts = append(parts, fmt.Sprintf("INTERVAL=%d", r.interval))
+	}
+	if r.count > 0 {
+		parts = append(parts, fmt.Sprintf("COUNT=%d", r.count))
+	}
+	if r.until != nil {
+		if allDay {
+			parts = append(parts, "UNTIL="+r.until.Format("20060102"))
+		} else {
+			end := time.Date(r.until.Year(), r.until.Month(), r.until.Day(), 23, 59, 59, 0, loc)
+			parts = append(parts, "UNTIL="+end.UTC().Format("20060102T150405Z"))
+		}
+	}
+	if r.byMonth > 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTH=%d", r.byMonth))
+	}
+	if r.byMonthDay != 0 {
+		parts = append(parts, fmt.Sprintf("BYMONTHDAY=%d", r.byMonthDay))
+	}
+	if len(r.byDay) > 0 {
+		parts = append(parts, "BYDAY="+strings.ToUpper(strings.Join(r.byDay, ",")))
+	}
+	return strings.Join(parts, ";")
+}
+
+// weeklyRule 每隔 interval 周的某几天，没有指定时按第一次发生的星期几重复
+func weeklyRule(interval int, days []string) *nlRule {
+	rule := &nlRule{freq: "WEEKLY", interval: interval, byDay: days}
+	if len(days) > 0 {
+		rule.matches = func(day time.Time) bool {
+			for _, d := range days {
+				if weekdayCodes[d] == day.Weekday() {
+					return true
+				}
+			}
+			return false
+		}
+	}
+	return rule
+}
+
+// monthlyRule 每隔 interval 个月的某一天（-1 为最后一天）或第 n 个星期几（n 为 -1 表示最后一个）
+func monthlyRule(interval, monthDay, n int, weekday string) *nlRule {
+	rule := &nlRule{freq: "MONTHLY", interval: interval, byMonthDay: monthDay}
+	switch {
+	case weekday != "":
+		rule.byDay = []string{strconv.Itoa(n) + weekday}
+		rule.matches = func(day time.Time) bool {
+			if day.Weekday() != weekdayCodes[weekday] {
+				return false
+			}
+			if n < 0 {
+				return addDays(day, 7).Month() != day.Month()
+			}
+			return (day.Day()-1)/7+1 == n
+		}
+	case monthDay < 0:
+		rule.matches = func(day time.Time) bool { return addDays(day, 1).Day() == 1 }
+	case monthDay > 0:
+		rule.matches = func(day time.Time) bool { return day.Day() == monthDay }
+	}
+	return rule
+}
+
+// recurrence 解析重复规则："每周三下午3点"、"every other friday until june 30"
+func (p *nlParser) recurrence() (*ResolvedDateTime, bool) {
+	rule, ok := p.rule()
+	if !ok {
+		return nil, false
+	}
+
+	// 每次发生的钟点或时段，可以是 "下午3点到4点" 这样的时间段
+	var tm, endTm *nlTime
+	if t, ok := p.timeOfDay(); ok {
+		tm = t
+		save := p.s
+		if p.consume(reRangeSep) != nil {
+			if t, ok := p.timeOfDay(); ok && t.hasClock && tm.hasClock {
+				endTm = t
+				p.inherit(&nlMoment{tm: tm}, &nlMoment{tm: endTm}, &ResolvedDateTime{})
+			} else {
+				p.s = save
+			}
+		}
+	}
+
+	for progress := true; progress; {
+		progress = false
+		if m := p.consume(reZhCount); m != nil && rule.count == 0 {
+			rule.count, progress = number(m[1]), true
+		} else if m := p.consume(reEnCount); m != nil && rule.count == 0 {
+			rule.count, progress = number(m[1]), true
+		} else if p.consume(reZhUntil) != nil || p.consume(reEnUntil) != nil {
+			days, ok := p.days()
+			if !ok || rule.until != nil {
+				return nil, false
+			}
+			last := addDays(days.end, -1)
+			rule.until, progress = &last, true
+			p.consume(reZhUntilEnd)
+		}
+	}
+	if rule.count < 0 {
+		return nil, false
+	}
+
+	allDay := tm == nil
+	start, ok := p.firstOccurrence(rule, tm)
+	if !ok || (rule.until != nil && rule.until.Before(p.date(start.Year(), start.Month(), start.Day()))) {
+		return nil, false
+	}
+	result := &ResolvedDateTime{Kind: DateTimeKindRecurrence, Start: start, AllDay: allDay, RRule: rule.String(allDay, p.loc)}
+	switch {
+	case allDay:
+		end := addDays(start, 1)
+		result.End = &end
+	case endTm != nil:
+		end := p.at(p.date(start.Year(), start.Month(), start.Day()), endTm)
+		if !end.After(start) {
+			end = end.AddDate(0, 0, 1)
+		}
+		result.End = &end
+	case !tm.hasClock:
+		end := time.Date(start.Year(), start.Month(), start.Day(), tm.period.to, 0, 0, 0, p.loc)
+		result.End = &end
+	}
+	return result, true
+}
+
+// at 返回某天的钟点或时段开始
+func (p *nlParser) at(day time.Time, tm *nlTime) time.Time {
+	if !tm.hasClock {
+		return time.Date(day.Year(), day.Month(), day.Day(), tm.period.from, 0, 0, 0, p.loc)
+	}
+	return time.Date(day.Year(), day.Month(), day.Day(), tm.hour24(), tm.minute, 0, 0, p.loc)
+}
+
+// firstOccurrence 返回今天或之后第一次符合规则的时间，带钟点时跳过已经过去的时刻
+func (p *nlParser) firstOccurrence(rule *nlRule, tm *nlTime) (time.Time, bool) {
+	today := p.today()
+	for i := 0; i < 8*366; i++ {
+		day := addDays(today, i)
+		if rule.matches != nil && !rule.matches(day) {
+			continue
+		}
+		if tm == nil {
+			return day, true
+		}
+		if start := p.at(day, tm); !start.Before(p.ref) {
+			return start, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// rule 解析重复规则的频率部分
+func (p *nlParser) rule() (*nlRule, bool) {
+	save := p.s
+	if m := p.consume(reZhEveryDay); m != nil {
+		interval := 1
+		if m[1] != "" {
+			interval = number(m[1]) + 1
+		} else if m[2] != "" {
+			interval = number(m[2])
+		}
+		return &nlRule{freq: "DAILY", interval: interval}, interval > 0
+	}
+	if p.consume(reZhEveryWorkday) != nil || p.consume(reEnEveryWorkday) != nil {
+		return weeklyRule(1, []string{"mo", "tu", "we", "th", "fr"}), true
+	}
+	if p.consume(reZhEveryWeekend) != nil || p.consume(reEnEveryWeekend) != nil {
+		return weeklyRule(1, []string{"sa", "su"}), true
+	}
+	if m := p.consume(reZhEveryMonth); m != nil {
+		interval := 1
+		if m[1] != "" {
+			interval = number(m[1]) + 1
+		} else if m[2] != "" {
+			interval = number(m[2])
+		}
+		if interval <= 0 {
+			return nil, false
+		}
+		part := p.consume(reZhMonthPart)
+		switch {
+		case part == nil:
+			return monthlyRule(interval, 0, 0, ""), true
+		case part[1] != "" || strings.Contains(part[0], "最后1天"):
+			return monthlyRule(interval, -1, 0, ""), true
+		case part[2] != "":
+			return monthlyRule(interval, 1, 0, ""), true
+		case part[4] != "":
+			return monthlyRule(interval, 0, number(part[3]), part[4]), number(part[3]) >= 1 && number(part[3]) <= 5
+		case part[5] != "":
+			return monthlyRule(interval, 0, -1, part[5]), true
+		default:
+			day := number(part[6])
+			return monthlyRule(interval, day, 0, ""), day >= 1 && day <= 31
+		}
+	}
+	if m := p.consume(reZhEveryYear); m != nil {
+		return p.yearlyRule(m[1], m[2])
+	}
+	if m := p.consume(reZhEveryWeek); m != nil {
+		days := strings.Split(m[3], "@")[1:]
+		interval := 1
+		switch {
+		case m[1] != "":
+			interval = number(m[1]) + 1
+		case m[2] != "":
+			interval = number(m[2])
+		case strings.HasPrefix(m[0], "隔"):
+			interval = 2
+		}
+		// "每" 后面既没有 "周" 也没有星期几时不是每周
+		if (len(days) == 0 && !strings.Contains(m[0], "周")) || interval <= 0 {
+			p.s = save
+			return nil, false
+		}
+		return weeklyRule(interval, days), true
+	}
+
+	if m := p.consume(reEnEveryDay); m != nil {
+		interval := 1
+		if m[1] != "" {
+			interval = 2
+		} else if m[2] != "" {
+			interval = number(m[2])
+		}
+		return &nlRule{freq: "DAILY", interval: interval}, interval > 0
+	}
+	if m := p.consume(reEnEveryNth); m != nil {
+		return monthlyRule(1, 0, enOrdinals[m[1]], enWeekdays[m[2]]), true
+	}
+	if m := p.consume(reEnEveryDays); m != nil {
+		interval := 1
+		if m[1] != "" {
+			interval = 2
+		}
+		return weeklyRule(interval, p.enWeekdayList(enWeekdays[m[2]])), true
+	}
+	if m := p.consume(reEnEveryWeek); m != nil {
+		interval := 1
+		if m[1] != "" || m[3] != "" {
+			interval = 2
+		} else if m[2] != "" {
+			interval = number(m[2])
+		}
+		var days []string
+		if d := p.consume(reEnListWeekday); d != nil {
+			days = p.enWeekdayList(enWeekdays[d[1]])
+		}
+		return weeklyRule(interval, days), interval > 0
+	}
+	if m := p.consume(reEnEveryMonth); m != nil {
+		interval := 1
+		if m[1] != "" {
+			interval = 2
+		} else if m[2] != "" {
+			interval = number(m[2])
+		}
+		if interval <= 0 {
+			return nil, false
+		}
+		part := p.consume(reEnMonthPart)
+		switch {
+		case part == nil:
+			return monthlyRule(interval, 0, 0, ""), true
+		case part[1] != "":
+			return monthlyRule(interval, -1, 0, ""), true
+		case part[3] != "":
+			return monthlyRule(interval, 0, enOrdinals[part[2]], enWeekdays[part[3]]), true
+		default:
+			day := number(part[4])
+			return monthlyRule(interval, day, 0, ""), day >= 1 && day <= 31
+		}
+	}
+	if p.consume(reEnEveryYear) != nil {
+		if m := p.consume(reEnMonthDay); m != nil {
+			return p.yearlyRule(strconv.Itoa(int(enMonthNumber(m[1]))), m[2])
+		}
+		if m := p.consume(reEnDayMonth); m != nil {
+			return p.yearlyRule(strconv.Itoa(int(enMonthNumber(m[2]))), m[1])
+		}
+		return p.yearlyRule("", "")
+	}
+
+	p.s = save
+	return nil, false
+}
+
+// yearlyRule 每年的某月某日，没有指定时按第一次发生的日期重复
+func (p *nlParser) yearlyRule(month, day string) (*nlRule, bool) {
+	rule := &nlRule{freq: "YEARLY", interval: 1}
+	if month == "" {
+		return rule, true
+	}
+	rule.byMonth, rule.byMonthDay = number(month), number(day)
+	if _, ok := p.validDate(2024, time.Month(rule.byMonth), rule.byMonthDay); !ok {
+		return nil, false
+	}
+	rule.matches = func(d time.Time) bool {
+		return int(d.Month()) == rule.byMonth && d.Day() == rule.byMonthDay
+	}
+	return rule, true
+}
+
+// enWeekdayList 解析 "monday, wednesday and friday" 这样的星期列表，first 为已经解析的第一个
+func (p *nlParser) enWeekdayList(first string) []string {
+	days := []string{first}
+	for {
+		m := p.consume(reEnListWeekday)
+		if m == nil {
+			return days
+		}
+		days = append(days, enWeekdays[m[1]])
+	}
+}
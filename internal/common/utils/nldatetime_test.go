@@ -0,0 +1,200 @@
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestParseNaturalDateTime 测试中英文时间表达式，参考时间为 2025-03-12（周三）10:30
+func TestParseNaturalDateTime(t *testing.T) {
+	shanghai, err := time.LoadLocation("Asia/Shanghai")
+	require.NoError(t, err)
+	ref := time.Date(2025, 3, 12, 10, 30, 0, 0, shanghai)
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2025, month, day, hour, minute, 0, 0, shanghai)
+	}
+	day := func(month time.Month, d int) time.Time { return at(month, d, 0, 0) }
+	ptr := func(t time.Time) *time.Time { return &t }
+
+	tests := []struct {
+		expr   string
+		kind   DateTimeKind
+		start  time.Time
+		end    *time.Time
+		allDay bool
+		rrule  string
+	}{
+		// 相对日期和星期
+		{expr: "今天", kind: DateTimeKindDate, start: day(3, 12), end: ptr(day(3, 13)), allDay: true},
+		{expr: "大后天", kind: DateTimeKindDate, start: day(3, 15), end: ptr(day(3, 16)), allDay: true},
+		{expr: "昨天", kind: DateTimeKindDate, start: day(3, 11), end: ptr(day(3, 12)), allDay: true},
+		{expr: "周五", kind: DateTimeKindDate, start: day(3, 14), end: ptr(day(3, 15)), allDay: true},
+		{expr: "星期三", kind: DateTimeKindDate, start: day(3, 12), end: ptr(day(3, 13)), allDay: true},
+		{expr: "周一", kind: DateTimeKindDate, start: day(3, 17), end: ptr(day(3, 18)), allDay: true},
+		{expr: "这周一", kind: DateTimeKindDate, start: day(3, 10), end: ptr(day(3, 11)), allDay: true},
+		{expr: "下周三", kind: DateTimeKindDate, start: day(3, 19), end: ptr(day(3, 20)), allDay: true},
+		{expr: "下个礼拜二", kind: DateTimeKindDate, start: day(3, 18), end: ptr(day(3, 19)), allDay: true},
+		{expr: "下下周一", kind: DateTimeKindDate, start: day(3, 24), end: ptr(day(3, 25)), allDay: true},
+		{expr: "上周五", kind: DateTimeKindDate, start: day(3, 7), end: ptr(day(3, 8)), allDay: true},
+		{expr: "星期天", kind: DateTimeKindDate, start: day(3, 16), end: ptr(day(3, 17)), allDay: true},
+		{expr: "3天后", kind: DateTimeKindDate, start: day(3, 15), end: ptr(day(3, 16)), allDay: true},
+		{expr: "两周后", kind: DateTimeKindDate, start: day(3, 26), end: ptr(day(3, 27)), allDay: true},
+		{expr: "一个月后", kind: DateTimeKindDate, start: day(4, 12), end: ptr(day(4, 13)), allDay: true},
+		{expr: "过两天", kind: DateTimeKindDate, start: day(3, 14), end: ptr(day(3, 15)), allDay: true},
+		{expr: "三天前", kind: DateTimeKindDate, start: day(3, 9), end: ptr(day(3, 10)), allDay: true},
+
+		// 周、月、年
+		{expr: "本周", kind: DateTimeKindRange, start: day(3, 10), end: ptr(day(3, 17)), allDay: true},
+		{expr: "下周", kind: DateTimeKindRange, start: day(3, 17), end: ptr(day(3, 24)), allDay: true},
+		{expr: "周末", kind: DateTimeKindRange, start: day(3, 15), end: ptr(day(3, 17)), allDay: true},
+		{expr: "下周末", kind: DateTimeKindRange, start: day(3, 22), end: ptr(day(3, 24)), allDay: true},
+		{expr: "下个月", kind: DateTimeKindRange, start: day(4, 1), end: ptr(day(5, 1)), allDay: true},
+		{expr: "月底", kind: DateTimeKindDate, start: day(3, 31), end: ptr(day(4, 1)), allDay: true},
+		{expr: "下个月底", kind: DateTimeKindDate, start: day(4, 30), end: ptr(day(5, 1)), allDay: true},
+		{expr: "月初", kind: DateTimeKindDate, start: day(3, 1), end: ptr(day(3, 2)), allDay: true},
+		{expr: "下个月的第一个周一", kind: DateTimeKindDate, start: day(4, 7), end: ptr(day(4, 8)), allDay: true},
+		{expr: "本月最后一个周五", kind: DateTimeKindDate, start: day(3, 28), end: ptr(day(3, 29)), allDay: true},
+		{expr: "下个月5号", kind: DateTimeKindDate, start: day(4, 5), end: ptr(day(4, 6)), allDay: true},
+		{expr: "今年", kind: DateTimeKindRange, start: day(1, 1), end: ptr(time.Date(2026, 1, 1, 0, 0, 0, 0, shanghai)), allDay: true},
+		{expr: "明年底", kind: DateTimeKindDate, start: time.Date(2026, 12, 31, 0, 0, 0, 0, shanghai), end: ptr(time.Date(2027, 1, 1, 0, 0, 0, 0, shanghai)), allDay: true},
+
+		// 具体日期
+		{expr: "2025年5月6日", kind: DateTimeKindDate, start: day(5, 6), end: ptr(day(5, 7)), allDay: true},
+		{expr: "二〇二五年十二月二十五日", kind: DateTimeKindDate, start: day(12, 25), end: ptr(day(12, 26)), allDay: true},
+		{expr: "5月6号", kind: DateTimeKindDate, start: day(5, 6), end: ptr(day(5, 7)), allDay: true},
+		{expr: "1月1日", kind: DateTimeKindDate, start: time.Date(2026, 1, 1, 0, 0, 0, 0, shanghai), end: ptr(time.Date(2026, 1, 2, 0, 0, 0, 0, shanghai)), allDay: true},
+		{expr: "6号", kind: DateTimeKindDate, start: day(4, 6), end: ptr(day(4, 7)), allDay: true},
+		{expr: "20号", kind: DateTimeKindDate, start: day(3, 20), end: ptr(day(3, 21)), allDay: true},
+		{expr: "2025-05-06", kind: DateTimeKindDate, start: day(5, 6), end: ptr(day(5, 7)), allDay: true},
+
+		// 钟点
+		{expr: "明天下午三点", kind: DateTimeKindInstant, start: at(3, 13, 15, 0)},
+		{expr: "后天上午10点半", kind: DateTimeKindInstant, start: at(3, 14, 10, 30)},
+		{expr: "今晚8点", kind: DateTimeKindInstant, start: at(3, 12, 20, 0)},
+		{expr: "明早7点", kind: DateTimeKindInstant, start: at(3, 13, 7, 0)},
+		{expr: "三点", kind: DateTimeKindInstant, start: at(3, 12, 15, 0)},
+		{expr: "9点", kind: DateTimeKindInstant, start: at(3, 13, 9, 0)},
+		{expr: "15:30", kind: DateTimeKindInstant, start: at(3, 12, 15, 30)},
+		{expr: "晚上12点", kind: DateTimeKindInstant, start: at(3, 13, 0, 0)},
+		{expr: "3点一刻", kind: DateTimeKindInstant, start: at(3, 12, 15, 15)},
+		{expr: "中午1点", kind: DateTimeKindInstant, start: at(3, 12, 13, 0)},
+		{expr: "下周三下午三点", kind: DateTimeKindInstant, start: at(3, 19, 15, 0)},
+		{expr: "周五下午六点", kind: DateTimeKindInstant, start: at(3, 14, 18, 0)},
+		{expr: "5月6日下午2点", kind: DateTimeKindInstant, start: at(5, 6, 14, 0)},
+		{expr: "2小时后", kind: DateTimeKindInstant, start: at(3, 12, 12, 30)},
+		{expr: "半小时后", kind: DateTimeKindInstant, start: at(3, 12, 11, 0)},
+		{expr: "一个半小时后", kind: DateTimeKindInstant, start: at(3, 12, 12, 0)},
+		{expr: "30分钟前", kind: DateTimeKindInstant, start: at(3, 12, 10, 0)},
+		{expr: "现在", kind: DateTimeKindInstant, start: at(3, 12, 10, 30)},
+
+		// 时间段
+		{expr: "明天上午", kind: DateTimeKindRange, start: at(3, 13, 8, 0), end: ptr(at(3, 13, 12, 0))},
+		{expr: "今晚", kind: DateTimeKindRange, start: at(3, 12, 18, 0), end: ptr(at(3, 13, 0, 0))},
+		{expr: "明天9点到11点", kind: DateTimeKindRange, start: at(3, 13, 9, 0), end: ptr(at(3, 13, 11, 0))},
+		{expr: "下午3点-5点", kind: DateTimeKindRange, start: at(3, 12, 15, 0), end: ptr(at(3, 12, 17, 0))},
+		{expr: "晚上11点到凌晨1点", kind: DateTimeKindRange, start: at(3, 12, 23, 0), end: ptr(at(3, 13, 1, 0))},
+		{expr: "晚上11点到凌晨12点", kind: DateTimeKindRange, start: at(3, 12, 23, 0), end: ptr(at(3, 13, 0, 0))},
+		{expr: "明天凌晨12点", kind: DateTimeKindInstant, start: at(3, 13, 0, 0)},
+		{expr: "5月6日至8日", kind: DateTimeKindRange, start: day(5, 6), end: ptr(day(5, 9)), allDay: true},
+		{expr: "下周一到周三", kind: DateTimeKindRange, start: day(3, 17), end: ptr(day(3, 20)), allDay: true},
+
+		// 英文
+		{expr: "tomorrow at 3pm", kind: DateTimeKindInstant, start: at(3, 13, 15, 0)},
+		{expr: "3pm tomorrow", kind: DateTimeKindInstant, start: at(3, 13, 15, 0)},
+		{expr: "Friday 10:30am", kind: DateTimeKindInstant, start: at(3, 14, 10, 30)},
+		{expr: "next Friday", kind: DateTimeKindDate, start: day(3, 21), end: ptr(day(3, 22)), allDay: true},
+		{expr: "this friday", kind: DateTimeKindDate, start: day(3, 14), end: ptr(day(3, 15)), allDay: true},
+		{expr: "in 2 hours", kind: DateTimeKindInstant, start: at(3, 12, 12, 30)},
+		{expr: "in half an hour", kind: DateTimeKindInstant, start: at(3, 12, 11, 0)},
+		{expr: "45 minutes ago", kind: DateTimeKindInstant, start: at(3, 12, 9, 45)},
+		{expr: "in 3 days", kind: DateTimeKindDate, start: day(3, 15), end: ptr(day(3, 16)), allDay: true},
+		{expr: "two weeks from now", kind: DateTimeKindDate, start: day(3, 26), end: ptr(day(3, 27)), allDay: true},
+		{expr: "next week", kind: DateTimeKindRange, start: day(3, 17), end: ptr(day(3, 24)), allDay: true},
+		{expr: "this weekend", kind: DateTimeKindRange, start: day(3, 15), end: ptr(day(3, 17)), allDay: true},
+		{expr: "end of next month", kind: DateTimeKindDate, start: day(4, 30), end: ptr(day(5, 1)), allDay: true},
+		{expr: "the first Monday of next month", kind: DateTimeKindDate, start: day(4, 7), end: ptr(day(4, 8)), allDay: true},
+		{expr: "the last friday of may", kind: DateTimeKindDate, start: day(5, 30), end: ptr(day(5, 31)), allDay: true},
+		{expr: "May 6th, 2026", kind: DateTimeKindDate, start: time.Date(2026, 5, 6, 0, 0, 0, 0, shanghai), end: ptr(time.Date(2026, 5, 7, 0, 0, 0, 0, shanghai)), allDay: true},
+		{expr: "6 may", kind: DateTimeKindDate, start: day(5, 6), end: ptr(day(5, 7)), allDay: true},
+		{expr: "the 15th", kind: DateTimeKindDate, start: day(3, 15), end: ptr(day(3, 16)), allDay: true},
+		{expr: "noon", kind: DateTimeKindInstant, start: at(3, 12, 12, 0)},
+		{expr: "midnight", kind: DateTimeKindInstant, start: at(3, 13, 0, 0)},
+		{expr: "at 9", kind: DateTimeKindInstant, start: at(3, 13, 9, 0)},
+		{expr: "3 in the afternoon", kind: DateTimeKindInstant, start: at(3, 12, 15, 0)},
+		{expr: "tonight", kind: DateTimeKindRange, start: at(3, 12, 18, 0), end: ptr(at(3, 13, 0, 0))},
+		{expr: "tomorrow morning", kind: DateTimeKindRange, start: at(3, 13, 6, 0), end: ptr(at(3, 13, 12, 0))},
+		{expr: "from 3 to 5pm", kind: DateTimeKindRange, start: at(3, 12, 15, 0), end: ptr(at(3, 12, 17, 0))},
+		{expr: "between monday and wednesday", kind: DateTimeKindRange, start: day(3, 17), end: ptr(day(3, 20)), allDay: true},
+
+		// 重复
+		{expr: "每天", kind: DateTimeKindRecurrence, start: day(3, 12), end: ptr(day(3, 13)), allDay: true, rrule: "FREQ=DAILY"},
+		{expr: "每天早上8点", kind: DateTimeKindRecurrence, start: at(3, 13, 8, 0), rrule: "FREQ=DAILY"},
+		{expr: "每隔一天", kind: DateTimeKindRecurrence, start: day(3, 12), end: ptr(day(3, 13)), allDay: true, rrule: "FREQ=DAILY;INTERVAL=2"},
+		{expr: "每周一三五", kind: DateTimeKindRecurrence, start: day(3, 12), end: ptr(day(3, 13)), allDay: true, rrule: "FREQ=WEEKLY;BYDAY=MO,WE,FR"},
+		{expr: "每隔一周的周五下午3点", kind: DateTimeKindRecurrence, start: at(3, 14, 15, 0), rrule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR"},
+		{expr: "隔周周五", kind: DateTimeKindRecurrence, start: day(3, 14), end: ptr(day(3, 15)), allDay: true, rrule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR"},
+		{expr: "每个工作日上午9点", kind: DateTimeKindRecurrence, start: at(3, 13, 9, 0), rrule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{expr: "每周三下午3点到4点", kind: DateTimeKindRecurrence, start: at(3, 12, 15, 0), end: ptr(at(3, 12, 16, 0)), rrule: "FREQ=WEEKLY;BYDAY=WE"},
+		{expr: "每月15号", kind: DateTimeKindRecurrence, start: day(3, 15), end: ptr(day(3, 16)), allDay: true, rrule: "FREQ=MONTHLY;BYMONTHDAY=15"},
+		{expr: "每月最后一天", kind: DateTimeKindRecurrence, start: day(3, 31), end: ptr(day(4, 1)), allDay: true, rrule: "FREQ=MONTHLY;BYMONTHDAY=-1"},
+		{expr: "每月第一个周一", kind: DateTimeKindRecurrence, start: day(4, 7), end: ptr(day(4, 8)), allDay: true, rrule: "FREQ=MONTHLY;BYDAY=1MO"},
+		{expr: "每年5月6日", kind: DateTimeKindRecurrence, start: day(5, 6), end: ptr(day(5, 7)), allDay: true, rrule: "FREQ=YEARLY;BYMONTH=5;BYMONTHDAY=6"},
+		{expr: "每天晚上9点，共10次", kind: DateTimeKindRecurrence, start: at(3, 12, 21, 0), rrule: "FREQ=DAILY;COUNT=10"},
+		{expr: "每周五直到6月底", kind: DateTimeKindRecurrence, start: day(3, 14), end: ptr(day(3, 15)), allDay: true, rrule: "FREQ=WEEKLY;UNTIL=20250630;BYDAY=FR"},
+		{expr: "每周五下午3点直到6月底为止", kind: DateTimeKindRecurrence, start: at(3, 14, 15, 0), rrule: "FREQ=WEEKLY;UNTIL=20250630T155959Z;BYDAY=FR"},
+		{expr: "every day", kind: DateTimeKindRecurrence, start: day(3, 12), end: ptr(day(3, 13)), allDay: true, rrule: "FREQ=DAILY"},
+		{expr: "every other Friday", kind: DateTimeKindRecurrence, start: day(3, 14), end: ptr(day(3, 15)), allDay: true, rrule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR"},
+		{expr: "every 2 weeks on monday and wednesday", kind: DateTimeKindRecurrence, start: day(3, 12), end: ptr(day(3, 13)), allDay: true, rrule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,WE"},
+		{expr: "every weekday at 9am", kind: DateTimeKindRecurrence, start: at(3, 13, 9, 0), rrule: "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR"},
+		{expr: "monthly on the 15th", kind: DateTimeKindRecurrence, start: day(3, 15), end: ptr(day(3, 16)), allDay: true, rrule: "FREQ=MONTHLY;BYMONTHDAY=15"},
+		{expr: "every last friday", kind: DateTimeKindRecurrence, start: day(3, 28), end: ptr(day(3, 29)), allDay: true, rrule: "FREQ=MONTHLY;BYDAY=-1FR"},
+		{expr: "yearly on may 6", kind: DateTimeKindRecurrence, start: day(5, 6), end: ptr(day(5, 7)), allDay: true, rrule: "FREQ=YEARLY;BYMONTH=5;BYMONTHDAY=6"},
+		{expr: "every monday until april 30", kind: DateTimeKindRecurrence, start: day(3, 17), end: ptr(day(3, 18)), allDay: true, rrule: "FREQ=WEEKLY;UNTIL=20250430;BYDAY=MO"},
+		{expr: "daily at 8:00 for 5 times", kind: DateTimeKindRecurrence, start: at(3, 13, 8, 0), rrule: "FREQ=DAILY;COUNT=5"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			got, err := ParseNaturalDateTime(tt.expr, ref, shanghai)
+			require.NoError(t, err)
+			assert.Equal(t, tt.kind, got.Kind)
+			assert.True(t, got.Start.Equal(tt.start), "start %s", got.Start)
+			if tt.end == nil {
+				assert.Nil(t, got.End)
+			} else if assert.NotNil(t, got.End) {
+				assert.True(t, got.End.Equal(*tt.end), "end %s", got.End)
+			}
+			assert.Equal(t, tt.allDay, got.AllDay)
+			assert.Equal(t, tt.rrule, got.RRule)
+		})
+	}
+}
+
+// TestParseNaturalDateTime_TimeZone 测试按指定时区计算日期：夏令时开始当天的钟点和全天
+func TestParseNaturalDateTime_TimeZone(t *testing.T) {
+	newYork, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	ref := time.Date(2025, 3, 8, 23, 0, 0, 0, time.UTC) // 纽约 3 月 8 日 18:00
+
+	got, err := ParseNaturalDateTime("明天下午3点", ref, newYork)
+	require.NoError(t, err)
+	assert.True(t, got.Start.Equal(time.Date(2025, 3, 9, 19, 0, 0, 0, time.UTC)), "got %s", got.Start)
+
+	got, err = ParseNaturalDateTime("tomorrow", ref, newYork)
+	require.NoError(t, err)
+	assert.True(t, got.Start.Equal(time.Date(2025, 3, 9, 5, 0, 0, 0, time.UTC)), "got %s", got.Start)
+	assert.True(t, got.End.Equal(time.Date(2025, 3, 10, 4, 0, 0, 0, time.UTC)), "got %s", got.End)
+}
+
+// TestParseNaturalDateTime_Invalid 测试无法识别的表达式，相对时间偏移超过 100 年也视为无法识别
+func TestParseNaturalDateTime_Invalid(t *testing.T) {
+	ref := time.Date(2025, 3, 12, 10, 30, 0, 0, time.UTC)
+	for _, expr := range []string{"", "随便什么时候", "25点", "2月30日", "下周三点钟开会", "every blah", "下周每天",
+		"101年后", "过99999999天", "1000000小时后", "in 6000 weeks", "99999999999999999999 days ago", "in 9999999999 minutes"} {
+		_, err := ParseNaturalDateTime(expr, ref, time.UTC)
+		assert.ErrorIs(t, err, ErrUnrecognizedDateTime, expr)
+	}
+}
@@ -0,0 +1,31 @@
+package worker
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Backoff 失败重试的指数退避策略
+type Backoff struct {
+	Base   time.Duration // 第一次重试的等待时间，之后每次翻倍
+	Max    time.Duration // 等待时间的上限，为 0 时不限制
+	Jitter bool          // 是否在计算出的等待时间的一半到全部之间随机取值，避免大量重试同时发生
+}
+
+// Delay 第 attempts 次失败后的重试等待时间：Base * 2^(attempts-1)，不超过 Max
+func (b Backoff) Delay(attempts int) time.Duration {
+	limit := b.Max
+	if limit <= 0 {
+		limit = math.MaxInt64 / 2
+	}
+	d := b.Base
+	for i := 1; i < attempts && d < limit; i++ {
+		d *= 2
+	}
+	d = min(d, limit)
+	if b.Jitter && d > 0 {
+		d = d/2 + rand.N(d/2+1)
+	}
+	return d
+}
@@ -0,0 +1,36 @@
+package worker
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Run 启动时和之后每隔 interval 调用一次 tick，直到 ctx 被取消。
+// tick 返回的错误以 errMsg 记录日志后继续，下次检查时重试
+func Run(ctx context.Context, interval time.Duration, errMsg string, tick func(context.Context) error) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := tick(ctx); err != nil {
+			slog.ErrorContext(ctx, errMsg, "error", err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Drain 逐个处理到期的任务，最多 batchSize 个：next 处理一个任务，没有到期的任务时返回 false
+func Drain(batchSize int, next func() (bool, error)) error {
+	for i := 0; i < batchSize; i++ {
+		found, err := next()
+		if err != nil || !found {
+			return err
+		}
+	}
+	return nil
+}
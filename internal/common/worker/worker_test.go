@@ -0,0 +1,58 @@
+package worker
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestDrain 测试处理到没有到期的任务、达到批量上限或出错时停止
+func TestDrain(t *testing.T) {
+	calls := 0
+	err := Drain(10, func() (bool, error) {
+		calls++
+		return calls < 3, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	err = Drain(5, func() (bool, error) {
+		calls++
+		return true, nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, 5, calls)
+
+	calls = 0
+	failed := errors.New("database is down")
+	err = Drain(5, func() (bool, error) {
+		calls++
+		return true, failed
+	})
+	assert.ErrorIs(t, err, failed)
+	assert.Equal(t, 1, calls)
+}
+
+// TestBackoff_Delay 测试等待时间翻倍且不超过上限，抖动在一半到全部之间
+func TestBackoff_Delay(t *testing.T) {
+	b := Backoff{Base: 30 * time.Second, Max: 6 * time.Hour}
+	assert.Equal(t, 30*time.Second, b.Delay(1))
+	assert.Equal(t, time.Minute, b.Delay(2))
+	assert.Equal(t, 4*time.Minute, b.Delay(4))
+	assert.Equal(t, 6*time.Hour, b.Delay(20))
+	assert.Equal(t, 6*time.Hour, b.Delay(100))
+
+	unlimited := Backoff{Base: time.Second}
+	assert.Positive(t, unlimited.Delay(100))
+
+	jitter := Backoff{Base: time.Second, Max: 10 * time.Second, Jitter: true}
+	for range 20 {
+		d := jitter.Delay(3)
+		assert.GreaterOrEqual(t, d, 2*time.Second)
+		assert.LessOrEqual(t, d, 4*time.Second)
+	}
+	assert.LessOrEqual(t, jitter.Delay(10), 10*time.Second)
+}
@@ -0,0 +1,495 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/google/uuid"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+const (
+	defaultAnthropicBaseURL   = "https://api.anthropic.com/v1"
+	defaultAnthropicMaxTokens = 4096
+	anthropicVersion          = "2023-06-01"
+)
+
+// anthropicModel Anthropic Messages API 模型
+type anthropicModel struct {
+	apiKey     string
+	baseUrl    string
+	modelName  string
+	httpClient *http.Client
+	retry      RetryPolicy
+
+	temperature *float64
+	topP        *float64
+	maxTokens   int
+}
+
+// NewAnthropicModel 创建 Anthropic 模型实例，max_tokens 是 Messages API 的必需参数，未配置时使用 4096
+func NewAnthropicModel(cfg *config.ProviderConfig) (model.LLM, error) {
+	if cfg.APIKey == "" {
+		return nil, fmt.Errorf("API key is required. Please set the API key in the configuration")
+	}
+
+	m := &anthropicModel{
+		apiKey:      cfg.APIKey,
+		baseUrl:     cfg.BaseURL,
+		modelName:   cfg.Model,
+		httpClient:  newHTTPClient(cfg.Timeout),
+		retry:       retryPolicy(cfg),
+		temperature: cfg.Temperature,
+		topP:        cfg.TopP,
+		maxTokens:   cfg.MaxTokens,
+	}
+	if m.baseUrl == "" {
+		m.baseUrl = defaultAnthropicBaseURL
+	}
+	if m.maxTokens == 0 {
+		m.maxTokens = defaultAnthropicMaxTokens
+	}
+	return m, nil
+}
+
+func (m *anthropicModel) Name() string {
+	return m.modelName
+}
+
+func (m *anthropicModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	maybeAppendUserContent(req)
+
+	anthropicReq, err := m.convertRequest(req)
+	if err != nil {
+		return func(yield func(*model.LLMResponse, error) bool) {
+			yield(nil, fmt.Errorf("failed to convert request: %w", err))
+		}
+	}
+
+	if stream {
+		return m.generateStream(ctx, anthropicReq)
+	}
+
+	return m.generate(ctx, anthropicReq)
+}
+
+type anthropicRequest struct {
+	Model         string             `json:"model"`
+	MaxTokens     int                `json:"max_tokens"`
+	System        string             `json:"system,omitempty"`
+	Messages      []anthropicMessage `json:"messages"`
+	Tools         []anthropicTool    `json:"tools,omitempty"`
+	Temperature   *float64           `json:"temperature,omitempty"`
+	TopP          *float64           `json:"top_p,omitempty"`
+	StopSequences []string           `json:"stop_sequences,omitempty"`
+	Stream        bool               `json:"stream,omitempty"`
+}
+
+type anthropicMessage struct {
+	Role    string                  `json:"role"` // user 或 assistant
+	Content []anthropicContentBlock `json:"content"`
+}
+
+type anthropicContentBlock struct {
+	Type      string                `json:"type"` // text、image、tool_use、tool_result、thinking
+	Text      string                `json:"text,omitempty"`
+	Source    *anthropicImageSource `json:"source,omitempty"`
+	ID        string                `json:"id,omitempty"`
+	Name      string                `json:"name,omitempty"`
+	Input     json.RawMessage       `json:"input,omitempty"`
+	ToolUseID string                `json:"tool_use_id,omitempty"`
+	Content   string                `json:"content,omitempty"`
+	Thinking  string                `json:"thinking,omitempty"`
+}
+
+type anthropicImageSource struct {
+	Type      string `json:"type"` // base64
+	MediaType string `json:"media_type"`
+	Data      string `json:"data"`
+}
+
+type anthropicTool struct {
+	Name        string         `json:"name"`
+	Description string         `json:"description,omitempty"`
+	InputSchema map[string]any `json:"input_schema"`
+}
+
+type anthropicResponse struct {
+	ID         string                  `json:"id"`
+	Type       string                  `json:"type"`
+	Role       string                  `json:"role"`
+	Content    []anthropicContentBlock `json:"content"`
+	StopReason string                  `json:"stop_reason,omitempty"`
+	Usage      *anthropicUsage         `json:"usage,omitempty"`
+}
+
+type anthropicUsage struct {
+	InputTokens          int `json:"input_tokens"`
+	OutputTokens         int `json:"output_tokens"`
+	CacheReadInputTokens int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// anthropicStreamEvent 流式响应的事件，不同类型的事件使用不同的字段
+type anthropicStreamEvent struct {
+	Type         string                 `json:"type"`
+	Index        int                    `json:"index"`
+	Message      *anthropicResponse     `json:"message,omitempty"`       // message_start
+	ContentBlock *anthropicContentBlock `json:"content_block,omitempty"` // content_block_start
+	Delta        *anthropicStreamDelta  `json:"delta,omitempty"`         // content_block_delta、message_delta
+	Usage        *anthropicUsage        `json:"usage,omitempty"`         // message_delta
+	Error        *anthropicError        `json:"error,omitempty"`         // error
+}
+
+type anthropicStreamDelta struct {
+	Type        string `json:"type,omitempty"` // text_delta、input_json_delta、thinking_delta
+	Text        string `json:"text,omitempty"`
+	PartialJSON string `json:"partial_json,omitempty"`
+	Thinking    string `json:"thinking,omitempty"`
+	StopReason  string `json:"stop_reason,omitempty"`
+}
+
+type anthropicError struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func (m *anthropicModel) convertRequest(req *model.LLMRequest) (*anthropicRequest, error) {
+	anthropicReq := &anthropicRequest{
+		Model:     m.modelName,
+		MaxTokens: m.maxTokens,
+		Messages:  make([]anthropicMessage, 0),
+	}
+
+	if req.Config != nil && req.Config.SystemInstruction != nil {
+		anthropicReq.System = extractTextFromContent(req.Config.SystemInstruction)
+	}
+
+	for _, content := range req.Contents {
+		msg, err := convertAnthropicContent(content)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert content: %w", err)
+		}
+		if msg == nil {
+			continue
+		}
+		// Messages API 要求 user 和 assistant 交替出现，合并相同角色的连续消息
+		if n := len(anthropicReq.Messages); n > 0 && anthropicReq.Messages[n-1].Role == msg.Role {
+			anthropicReq.Messages[n-1].Content = append(anthropicReq.Messages[n-1].Content, msg.Content...)
+			continue
+		}
+		anthropicReq.Messages = append(anthropicReq.Messages, *msg)
+	}
+
+	if req.Config != nil {
+		for _, tool := range req.Config.Tools {
+			for _, fn := range tool.FunctionDeclarations {
+				params := convertFunctionParameters(fn)
+				if len(params) == 0 {
+					params = map[string]any{"type": "object"}
+				}
+				anthropicReq.Tools = append(anthropicReq.Tools, anthropicTool{
+					Name:        fn.Name,
+					Description: fn.Description,
+					InputSchema: params,
+				})
+			}
+		}
+
+		if req.Config.Temperature != nil {
+			temp := float64(*req.Config.Temperature)
+			anthropicReq.Temperature = &temp
+		}
+		if req.Config.TopP != nil {
+			topP := float64(*req.Config.TopP)
+			anthropicReq.TopP = &topP
+		}
+		if req.Config.MaxOutputTokens > 0 {
+			anthropicReq.MaxTokens = int(req.Config.MaxOutputTokens)
+		}
+		if len(req.Config.StopSequences) > 0 {
+			anthropicReq.StopSequences = req.Config.StopSequences
+		}
+	}
+
+	if anthropicReq.Temperature == nil {
+		anthropicReq.Temperature = m.temperature
+	}
+	if anthropicReq.TopP == nil {
+		anthropicReq.TopP = m.topP
+	}
+	return anthropicReq, nil
+}
+
+// convertAnthropicContent 转换一条消息，没有可发送的内容时返回 nil
+func convertAnthropicContent(content *genai.Content) (*anthropicMessage, error) {
+	if content == nil || len(content.Parts) == 0 {
+		return nil, nil
+	}
+
+	role := "user"
+	if content.Role == genai.RoleModel {
+		role = "assistant"
+	}
+
+	var blocks []anthropicContentBlock
+	for _, part := range content.Parts {
+		switch {
+		case part.Thought:
+			// 思考内容需要签名才能回传，省略
+		case part.Text != "":
+			blocks = append(blocks, anthropicContentBlock{Type: "text", Text: part.Text})
+		case part.InlineData != nil && len(part.InlineData.Data) > 0:
+			mimeType := part.InlineData.MIMEType
+			if strings.HasPrefix(mimeType, "image/") {
+				blocks = append(blocks, anthropicContentBlock{
+					Type: "image",
+					Source: &anthropicImageSource{
+						Type:      "base64",
+						MediaType: mimeType,
+						Data:      base64.StdEncoding.EncodeToString(part.InlineData.Data),
+					},
+				})
+			} else if strings.HasPrefix(mimeType, "text/") {
+				blocks = append(blocks, anthropicContentBlock{Type: "text", Text: string(part.InlineData.Data)})
+			}
+		case part.FunctionCall != nil:
+			input, err := json.Marshal(part.FunctionCall.Args)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal function call arguments: %w", err)
+			}
+			if part.FunctionCall.Args == nil {
+				input = []byte("{}")
+			}
+			callID := part.FunctionCall.ID
+			if callID == "" {
+				callID = "toolu_" + uuid.New().String()[:8]
+			}
+			blocks = append(blocks, anthropicContentBlock{
+				Type:  "tool_use",
+				ID:    callID,
+				Name:  part.FunctionCall.Name,
+				Input: input,
+			})
+		case part.FunctionResponse != nil:
+			responseJSON, err := json.Marshal(part.FunctionResponse.Response)
+			if err != nil {
+				return nil, fmt.Errorf("failed to marshal function response: %w", err)
+			}
+			blocks = append(blocks, anthropicContentBlock{
+				Type:      "tool_result",
+				ToolUseID: part.FunctionResponse.ID,
+				Content:   string(responseJSON),
+			})
+		}
+	}
+
+	if len(blocks) == 0 {
+		return nil, nil
+	}
+	return &anthropicMessage{Role: role, Content: blocks}, nil
+}
+
+func (m *anthropicModel) generate(ctx context.Context, anthropicReq *anthropicRequest) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		httpResp, err := m.sendRequest(ctx, anthropicReq)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer httpResp.Body.Close()
+
+		var resp anthropicResponse
+		if err := json.NewDecoder(httpResp.Body).Decode(&resp); err != nil {
+			slog.Error("failed to decode response: ", "error", err)
+			yield(nil, fmt.Errorf("failed to decode response: %w", err))
+			return
+		}
+
+		yield(buildAnthropicResponse(resp.Content, resp.Usage, resp.StopReason), nil)
+	}
+}
+
+func (m *anthropicModel) generateStream(ctx context.Context, anthropicReq *anthropicRequest) iter.Seq2[*model.LLMResponse, error] {
+	anthropicReq.Stream = true
+
+	return func(yield func(*model.LLMResponse, error) bool) {
+		httpResp, err := m.sendRequest(ctx, anthropicReq)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer httpResp.Body.Close()
+
+		var blocks []anthropicContentBlock
+		var partialJSON []strings.Builder
+		usage := &anthropicUsage{}
+		stopReason := ""
+
+		scanner := bufio.NewScanner(httpResp.Body)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if !strings.HasPrefix(line, "data:") {
+				continue
+			}
+
+			var event anthropicStreamEvent
+			if err := json.Unmarshal([]byte(strings.TrimSpace(strings.TrimPrefix(line, "data:"))), &event); err != nil {
+				continue
+			}
+
+			switch event.Type {
+			case "message_start":
+				if event.Message != nil && event.Message.Usage != nil {
+					usage = event.Message.Usage
+				}
+			case "content_block_start":
+				for len(blocks) <= event.Index {
+					blocks = append(blocks, anthropicContentBlock{})
+					partialJSON = append(partialJSON, strings.Builder{})
+				}
+				if event.ContentBlock != nil {
+					blocks[event.Index] = *event.ContentBlock
+				}
+			case "content_block_delta":
+				if event.Delta == nil || event.Index >= len(blocks) {
+					continue
+				}
+				var part *genai.Part
+				switch event.Delta.Type {
+				case "text_delta":
+					blocks[event.Index].Text += event.Delta.Text
+					part = &genai.Part{Text: event.Delta.Text}
+				case "thinking_delta":
+					blocks[event.Index].Thinking += event.Delta.Thinking
+					part = &genai.Part{Text: event.Delta.Thinking, Thought: true}
+				case "input_json_delta":
+					partialJSON[event.Index].WriteString(event.Delta.PartialJSON)
+				}
+				if part != nil && part.Text != "" {
+					llmResp := &model.LLMResponse{
+						Content: &genai.Content{Role: genai.RoleModel, Parts: []*genai.Part{part}},
+						Partial: true,
+					}
+					if !yield(llmResp, nil) {
+						return
+					}
+				}
+			case "message_delta":
+				if event.Delta != nil && event.Delta.StopReason != "" {
+					stopReason = event.Delta.StopReason
+				}
+				if event.Usage != nil {
+					usage.OutputTokens = event.Usage.OutputTokens
+				}
+			case "error":
+				msg := "unknown error"
+				if event.Error != nil {
+					msg = event.Error.Type + ": " + event.Error.Message
+				}
+				yield(nil, fmt.Errorf("stream error: %s", msg))
+				return
+			case "message_stop":
+				for i := range blocks {
+					if blocks[i].Type == "tool_use" && partialJSON[i].Len() > 0 {
+						blocks[i].Input = json.RawMessage(partialJSON[i].String())
+					}
+				}
+				yield(buildAnthropicResponse(blocks, usage, stopReason), nil)
+				return
+			}
+		}
+
+		if err := scanner.Err(); err != nil {
+			yield(nil, fmt.Errorf("stream error: %w", err))
+			return
+		}
+		yield(nil, fmt.Errorf("stream error: connection closed before message_stop"))
+	}
+}
+
+func (m *anthropicModel) sendRequest(ctx context.Context, anthropicReq *anthropicRequest) (*http.Response, error) {
+	reqBody, err := json.Marshal(anthropicReq)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	baseURL := strings.TrimSuffix(m.baseUrl, "/")
+	return doWithRetry(ctx, m.httpClient, m.retry, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/messages", bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("x-api-key", m.apiKey)
+		req.Header.Set("anthropic-version", anthropicVersion)
+		return req, nil
+	})
+}
+
+// buildAnthropicResponse 把响应的内容块转换为 LLMResponse
+func buildAnthropicResponse(blocks []anthropicContentBlock, usage *anthropicUsage, stopReason string) *model.LLMResponse {
+	var parts []*genai.Part
+	for _, block := range blocks {
+		switch block.Type {
+		case "thinking":
+			if block.Thinking != "" {
+				parts = append(parts, &genai.Part{Text: block.Thinking, Thought: true})
+			}
+		case "text":
+			if block.Text != "" {
+				parts = append(parts, genai.NewPartFromText(block.Text))
+			}
+		case "tool_use":
+			args := map[string]any{}
+			if len(block.Input) > 0 {
+				if err := json.Unmarshal(block.Input, &args); err != nil {
+					slog.Error("failed to unmarshal tool input: ", "error", err)
+					continue
+				}
+			}
+			part := genai.NewPartFromFunctionCall(block.Name, args)
+			part.FunctionCall.ID = block.ID
+			parts = append(parts, part)
+		}
+	}
+
+	llmResp := &model.LLMResponse{
+		Content: &genai.Content{
+			Role:  genai.RoleModel,
+			Parts: parts,
+		},
+		FinishReason: mapAnthropicStopReason(stopReason),
+	}
+	if usage != nil {
+		llmResp.UsageMetadata = &genai.GenerateContentResponseUsageMetadata{
+			PromptTokenCount:        int32(usage.InputTokens),
+			CandidatesTokenCount:    int32(usage.OutputTokens),
+			TotalTokenCount:         int32(usage.InputTokens + usage.OutputTokens),
+			CachedContentTokenCount: int32(usage.CacheReadInputTokens),
+		}
+	}
+	return llmResp
+}
+
+func mapAnthropicStopReason(reason string) genai.FinishReason {
+	switch reason {
+	case "end_turn", "stop_sequence", "tool_use":
+		return genai.FinishReasonStop
+	case "max_tokens":
+		return genai.FinishReasonMaxTokens
+	case "refusal":
+		return genai.FinishReasonSafety
+	default:
+		return genai.FinishReasonOther
+	}
+}
@@ -0,0 +1,137 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// newAnthropicTestRequest 包含系统指令、工具和一轮工具调用的请求
+func newAnthropicTestRequest() *model.LLMRequest {
+	call := genai.NewPartFromFunctionCall("list_calendar_items", map[string]any{"date": "2025-01-01"})
+	call.FunctionCall.ID = "toolu_1"
+	result := genai.NewPartFromFunctionResponse("list_calendar_items", map[string]any{"items": []any{}})
+	result.FunctionResponse.ID = "toolu_1"
+
+	return &model.LLMRequest{
+		Contents: []*genai.Content{
+			genai.NewContentFromText("明天有什么安排？", genai.RoleUser),
+			genai.NewContentFromParts([]*genai.Part{call}, genai.RoleModel),
+			genai.NewContentFromParts([]*genai.Part{result}, genai.RoleUser),
+		},
+		Config: &genai.GenerateContentConfig{
+			SystemInstruction: genai.NewContentFromText("你是日历助手", genai.RoleUser),
+			Tools: []*genai.Tool{{FunctionDeclarations: []*genai.FunctionDeclaration{{
+				Name:                 "list_calendar_items",
+				Description:          "列出日历项",
+				ParametersJsonSchema: map[string]any{"type": "object"},
+			}}}},
+		},
+	}
+}
+
+// TestAnthropicModel_Generate 测试请求转换为 Messages API 格式，tool_use 转换为函数调用
+func TestAnthropicModel_Generate(t *testing.T) {
+	var got anthropicRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/messages", r.URL.Path)
+		assert.Equal(t, "sk-ant-test", r.Header.Get("x-api-key"))
+		assert.Equal(t, anthropicVersion, r.Header.Get("anthropic-version"))
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&got))
+
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","content":[
+			{"type":"text","text":"我来创建日程"},
+			{"type":"tool_use","id":"toolu_2","name":"create_calendar_item","input":{"summary":"开会"}}
+		],"stop_reason":"tool_use","usage":{"input_tokens":10,"output_tokens":5}}`)
+	}))
+	defer server.Close()
+
+	m, err := NewAnthropicModel(&config.ProviderConfig{
+		APIKey:      "sk-ant-test",
+		Model:       "claude-sonnet-4-5",
+		BaseURL:     server.URL + "/v1",
+		Temperature: ptr(0.3),
+	})
+	require.NoError(t, err)
+
+	var responses []*model.LLMResponse
+	for resp, err := range m.GenerateContent(context.Background(), newAnthropicTestRequest(), false) {
+		require.NoError(t, err)
+		responses = append(responses, resp)
+	}
+
+	assert.Equal(t, "你是日历助手", got.System)
+	assert.Equal(t, defaultAnthropicMaxTokens, got.MaxTokens)
+	assert.Equal(t, 0.3, *got.Temperature)
+	require.Len(t, got.Tools, 1)
+	assert.Equal(t, "list_calendar_items", got.Tools[0].Name)
+	// 工具结果之后追加的用户消息与工具结果合并，user 和 assistant 交替出现
+	require.Len(t, got.Messages, 3)
+	assert.Equal(t, []string{"user", "assistant", "user"}, []string{got.Messages[0].Role, got.Messages[1].Role, got.Messages[2].Role})
+	assert.Equal(t, "tool_use", got.Messages[1].Content[0].Type)
+	assert.Equal(t, "tool_result", got.Messages[2].Content[0].Type)
+	assert.Equal(t, "toolu_1", got.Messages[2].Content[0].ToolUseID)
+
+	require.Len(t, responses, 1)
+	parts := responses[0].Content.Parts
+	require.Len(t, parts, 2)
+	assert.Equal(t, "我来创建日程", parts[0].Text)
+	assert.Equal(t, "create_calendar_item", parts[1].FunctionCall.Name)
+	assert.Equal(t, "toolu_2", parts[1].FunctionCall.ID)
+	assert.Equal(t, map[string]any{"summary": "开会"}, parts[1].FunctionCall.Args)
+	assert.Equal(t, int32(15), responses[0].UsageMetadata.TotalTokenCount)
+}
+
+// TestAnthropicModel_GenerateStream 测试流式响应逐段输出文本，结束时汇总文本和工具调用
+func TestAnthropicModel_GenerateStream(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, data := range []string{
+			`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","content":[],"usage":{"input_tokens":10,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"好的，"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"已创建"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"toolu_2","name":"create_calendar_item","input":{}}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"summary\":"}}`,
+			`{"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"开会\"}"}}`,
+			`{"type":"content_block_stop","index":1}`,
+			`{"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":8}}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "event: x\ndata: %s\n\n", data)
+		}
+	}))
+	defer server.Close()
+
+	m, err := NewAnthropicModel(&config.ProviderConfig{APIKey: "sk-ant-test", Model: "claude-sonnet-4-5", BaseURL: server.URL})
+	require.NoError(t, err)
+
+	var partials []string
+	var final *model.LLMResponse
+	for resp, err := range m.GenerateContent(context.Background(), newAnthropicTestRequest(), true) {
+		require.NoError(t, err)
+		if resp.Partial {
+			partials = append(partials, resp.Content.Parts[0].Text)
+			continue
+		}
+		final = resp
+	}
+
+	assert.Equal(t, []string{"好的，", "已创建"}, partials)
+	require.NotNil(t, final)
+	require.Len(t, final.Content.Parts, 2)
+	assert.Equal(t, "好的，已创建", final.Content.Parts[0].Text)
+	assert.Equal(t, map[string]any{"summary": "开会"}, final.Content.Parts[1].FunctionCall.Args)
+	assert.Equal(t, genai.FinishReasonStop, final.FinishReason)
+	assert.Equal(t, int32(8), final.UsageMetadata.CandidatesTokenCount)
+}
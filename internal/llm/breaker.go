@@ -0,0 +1,59 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// breaker 提供方的熔断器
+// 连续失败 threshold 次后打开，cooldown 内拒绝请求；之后允许一次试探请求（半开），成功则关闭，失败则重新打开
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+
+	mu        sync.Mutex
+	failures  int
+	openUntil time.Time
+	probing   bool
+}
+
+// newBreaker 创建熔断器，threshold 为 0 时不熔断
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	return &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+}
+
+// allow 检查是否可以向提供方发送请求，返回 true 后必须调用 record
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if b.probing || b.now().Before(b.openUntil) {
+		return false
+	}
+	b.probing = true
+	return true
+}
+
+// record 记录请求结果：ErrProviderUnavailable 计为失败；调用方取消的请求不计入；其他结果说明提供方可用
+func (b *breaker) record(ctx context.Context, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.probing = false
+	switch {
+	case errors.Is(err, ErrProviderUnavailable):
+		b.failures++
+		if b.threshold > 0 && b.failures >= b.threshold {
+			b.openUntil = b.now().Add(b.cooldown)
+		}
+	case err != nil && ctx.Err() != nil:
+	default:
+		b.failures = 0
+	}
+}
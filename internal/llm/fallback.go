@@ -0,0 +1,93 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"slices"
+
+	"google.golang.org/adk/model"
+)
+
+// 模型回复的 CustomMetadata 中记录实际使用的提供方和模型（可能是备用提供方）
+const (
+	MetadataProvider = "llm_provider"
+	MetadataModel    = "llm_model"
+)
+
+// provider 注册的提供方：模型和它的熔断器
+type provider struct {
+	name    string
+	model   model.LLM
+	breaker *breaker
+}
+
+// fallbackModel 按顺序尝试主提供方和备用提供方的模型
+// 提供方已熔断，或请求返回 ErrProviderUnavailable 时切换到下一个；已经输出回复（例如流式的中间结果）后不再切换
+type fallbackModel struct {
+	chain []*provider
+}
+
+func (m *fallbackModel) Name() string {
+	return m.chain[0].model.Name()
+}
+
+func (m *fallbackModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var lastErr error
+		for _, p := range m.chain {
+			if !p.breaker.allow() {
+				lastErr = fmt.Errorf("%w: %s 已熔断", ErrProviderUnavailable, p.name)
+				continue
+			}
+			if lastErr != nil {
+				slog.Warn("LLM provider unavailable, falling back", "provider", p.name, "error", lastErr)
+			}
+
+			responded := false
+			var err error
+			for resp, e := range p.model.GenerateContent(ctx, cloneRequest(req), stream) {
+				if e != nil {
+					err = e
+					break
+				}
+				responded = true
+				if resp != nil && !resp.Partial {
+					if resp.CustomMetadata == nil {
+						resp.CustomMetadata = map[string]any{}
+					}
+					resp.CustomMetadata[MetadataProvider] = p.name
+					resp.CustomMetadata[MetadataModel] = p.model.Name()
+				}
+				if !yield(resp, nil) {
+					p.breaker.record(ctx, nil)
+					return
+				}
+			}
+			p.breaker.record(ctx, err)
+
+			if err == nil {
+				return
+			}
+			if responded || ctx.Err() != nil || !errors.Is(err, ErrProviderUnavailable) {
+				yield(nil, err)
+				return
+			}
+			lastErr = err
+		}
+		yield(nil, lastErr)
+	}
+}
+
+// cloneRequest 复制请求中模型会修改的部分（追加的消息和生成参数的默认值），切换提供方时每个提供方使用原始请求
+func cloneRequest(req *model.LLMRequest) *model.LLMRequest {
+	clone := *req
+	clone.Contents = slices.Clone(req.Contents)
+	if req.Config != nil {
+		config := *req.Config
+		clone.Config = &config
+	}
+	return &clone
+}
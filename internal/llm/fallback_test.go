@@ -0,0 +1,107 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// stubModel 按顺序返回 errs 中的错误，nil 表示回复模型名称
+type stubModel struct {
+	name  string
+	errs  []error
+	calls int
+}
+
+func (m *stubModel) Name() string {
+	return m.name
+}
+
+func (m *stubModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	return func(yield func(*model.LLMResponse, error) bool) {
+		var err error
+		if m.calls < len(m.errs) {
+			err = m.errs[m.calls]
+		}
+		m.calls++
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		yield(&model.LLMResponse{Content: genai.NewContentFromText(m.name, genai.RoleModel)}, nil)
+	}
+}
+
+func unavailable() error {
+	return fmt.Errorf("%w: API error: 503", ErrProviderUnavailable)
+}
+
+// TestFallbackModel 测试主提供方不可用时切换到备用提供方，其他错误不切换
+func TestFallbackModel(t *testing.T) {
+	primary := &stubModel{name: "primary", errs: []error{unavailable(), errors.New("API error: 400")}}
+	backup := &stubModel{name: "backup"}
+	m := &fallbackModel{chain: []*provider{
+		{name: "primary", model: primary, breaker: newBreaker(5, time.Minute)},
+		{name: "backup", model: backup, breaker: newBreaker(5, time.Minute)},
+	}}
+	assert.Equal(t, "primary", m.Name())
+
+	resp, err := generate(m)
+	require.NoError(t, err)
+	assert.Equal(t, "backup", resp.Content.Parts[0].Text)
+
+	_, err = generate(m)
+	assert.EqualError(t, err, "API error: 400")
+	assert.Equal(t, 1, backup.calls)
+
+	resp, err = generate(m)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", resp.Content.Parts[0].Text)
+
+	// 所有提供方都不可用
+	m.chain[0].model = &stubModel{name: "primary", errs: []error{unavailable()}}
+	m.chain[1].model = &stubModel{name: "backup", errs: []error{unavailable()}}
+	_, err = generate(m)
+	assert.ErrorIs(t, err, ErrProviderUnavailable)
+}
+
+// TestBreaker 测试连续失败后熔断，熔断期间跳过提供方，冷却后允许一次试探请求
+func TestBreaker(t *testing.T) {
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	b := newBreaker(2, time.Minute)
+	b.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	primary := &stubModel{name: "primary", errs: []error{unavailable(), unavailable()}}
+	m := &fallbackModel{chain: []*provider{
+		{name: "primary", model: primary, breaker: b},
+		{name: "backup", model: &stubModel{name: "backup"}, breaker: newBreaker(2, time.Minute)},
+	}}
+
+	for range 3 {
+		resp, err := generate(m)
+		require.NoError(t, err)
+		assert.Equal(t, "backup", resp.Content.Parts[0].Text)
+	}
+	assert.Equal(t, 2, primary.calls, "熔断后不再请求主提供方")
+
+	now = now.Add(time.Minute)
+	require.True(t, b.allow(), "冷却后允许试探请求")
+	assert.False(t, b.allow(), "试探期间只允许一个请求")
+	b.record(ctx, unavailable())
+	assert.False(t, b.allow(), "试探失败后重新熔断")
+
+	now = now.Add(time.Minute)
+	resp, err := generate(m)
+	require.NoError(t, err)
+	assert.Equal(t, "primary", resp.Content.Parts[0].Text)
+	assert.True(t, b.allow(), "试探成功后关闭")
+}
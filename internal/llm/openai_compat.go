@@ -7,7 +7,6 @@ import (
 	"encoding/base64"
 	"encoding/json"
 	"fmt"
-	"io"
 	"iter"
 	"log/slog"
 	"net/http"
@@ -44,11 +43,55 @@ func WithHTTPClient(httpClient *http.Client) Option {
 	}
 }
 
+// WithoutAPIKey 不需要 API key（例如本地的 Ollama、llama.cpp 服务），未设置 API key 时不发送 Authorization 头
+func WithoutAPIKey() Option {
+	return func(cfg *openAIModel) {
+		cfg.apiKeyOptional = true
+	}
+}
+
+// WithTemperature 请求未设置 temperature 时使用的默认值
+func WithTemperature(temperature float64) Option {
+	return func(cfg *openAIModel) {
+		cfg.temperature = &temperature
+	}
+}
+
+// WithTopP 请求未设置 top_p 时使用的默认值
+func WithTopP(topP float64) Option {
+	return func(cfg *openAIModel) {
+		cfg.topP = &topP
+	}
+}
+
+// WithMaxTokens 请求未设置最大输出 token 数时使用的默认值
+func WithMaxTokens(maxTokens int) Option {
+	return func(cfg *openAIModel) {
+		if maxTokens > 0 {
+			cfg.maxTokens = &maxTokens
+		}
+	}
+}
+
+// WithRetryPolicy 设置请求失败时的重试策略，默认不重试
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(cfg *openAIModel) {
+		cfg.retry = policy
+	}
+}
+
 type openAIModel struct {
-	apiKey     string
-	baseUrl    string
-	modelName  string
-	httpClient *http.Client
+	apiKey         string
+	apiKeyOptional bool
+	baseUrl        string
+	modelName      string
+	httpClient     *http.Client
+	retry          RetryPolicy
+
+	// 生成参数的默认值，请求中设置的值优先
+	temperature *float64
+	topP        *float64
+	maxTokens   *int
 }
 
 func NewOpenAICompatModel(opts ...Option) (model.LLM, error) {
@@ -60,7 +103,7 @@ func NewOpenAICompatModel(opts ...Option) (model.LLM, error) {
 		opt(model)
 	}
 
-	if model.apiKey == "" {
+	if model.apiKey == "" && !model.apiKeyOptional {
 		return nil, fmt.Errorf("API key is required. Please set the API key in the configuration")
 	}
 
@@ -80,7 +123,7 @@ func (m *openAIModel) Name() string {
 }
 
 func (m *openAIModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
-	m.maybeAppendUserContent(req)
+	maybeAppendUserContent(req)
 
 	openaiReq, err := m.convertRequest(req)
 	if err != nil {
@@ -230,6 +273,16 @@ func (m *openAIModel) convertRequest(req *model.LLMRequest) (*openAIRequest, err
 			}
 		}
 	}
+
+	if openaiReq.Temperature == nil {
+		openaiReq.Temperature = m.temperature
+	}
+	if openaiReq.TopP == nil {
+		openaiReq.TopP = m.topP
+	}
+	if openaiReq.MaxTokens == nil {
+		openaiReq.MaxTokens = m.maxTokens
+	}
 	return openaiReq, nil
 }
 
@@ -601,30 +654,17 @@ func (m *openAIModel) sendRequest(ctx context.Context, openaiReq *openAIRequest)
 	}
 
 	baseURL := strings.TrimSuffix(m.baseUrl, "/")
-	req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/chat/completions", bytes.NewReader(reqBody))
-	if err != nil {
-		slog.Error("failed to create HTTP request: ", "error", err)
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
-	}
-
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+m.apiKey)
-
-	httpResp, err := m.httpClient.Do(req)
-	if err != nil {
-		slog.Error("failed to send request: ", "error", err)
-		return nil, fmt.Errorf("failed to send request: %w", err)
-	}
-
-	if httpResp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(httpResp.Body)
-		httpResp.Body.Close()
-
-		slog.Error("API error: ", "status", httpResp.StatusCode, "body", string(body))
-		return nil, fmt.Errorf("API error: %d - %s", httpResp.StatusCode, string(body))
-	}
-
-	return httpResp, nil
+	return doWithRetry(ctx, m.httpClient, m.retry, func() (*http.Request, error) {
+		req, err := http.NewRequestWithContext(ctx, "POST", baseURL+"/chat/completions", bytes.NewReader(reqBody))
+		if err != nil {
+			return nil, err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if m.apiKey != "" {
+			req.Header.Set("Authorization", "Bearer "+m.apiKey)
+		}
+		return req, nil
+	})
 }
 
 func (m *openAIModel) doRequest(ctx context.Context, openaiReq *openAIRequest) (*openAIResponse, error) {
@@ -869,7 +909,8 @@ func mapFinishReason(reason string) genai.FinishReason {
 	}
 }
 
-func (m *openAIModel) maybeAppendUserContent(req *model.LLMRequest) {
+// maybeAppendUserContent 保证请求以用户消息结束，模型才会回复
+func maybeAppendUserContent(req *model.LLMRequest) {
 	if len(req.Contents) == 0 {
 		req.Contents = append(req.Contents, genai.NewContentFromText("Handle the requests as specified in the System Instruction.", "user"))
 		return
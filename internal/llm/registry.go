@@ -0,0 +1,266 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/galilio/otter/internal/common/config"
+	"google.golang.org/adk/model"
+	"google.golang.org/adk/model/gemini"
+	"google.golang.org/genai"
+)
+
+var (
+	ErrProviderNotFound = errors.New("LLM 提供方不存在")
+	ErrInvalidProvider  = errors.New("LLM 提供方配置无效")
+)
+
+// 本地服务的默认地址
+const (
+	defaultOllamaBaseURL   = "http://localhost:11434/v1"
+	defaultLlamaCppBaseURL = "http://localhost:8080/v1"
+)
+
+// Registry 按名称注册的 LLM 提供方
+// 每个提供方有自己的熔断器，Get 等方法返回的模型在提供方不可用时依次切换到它的备用提供方（fallbacks）
+type Registry struct {
+	models      map[string]model.LLM
+	defaultName string
+	agents      map[string]string
+}
+
+// NewRegistry 根据配置创建所有提供方的模型，配置应已通过 config.Load 验证
+func NewRegistry(ctx context.Context, cfg *config.LLMConfig) (*Registry, error) {
+	providers := make(map[string]*provider, len(cfg.Providers))
+	for name, p := range cfg.Providers {
+		m, err := NewModel(ctx, &p)
+		if err != nil {
+			return nil, fmt.Errorf("创建 LLM 提供方 %s 失败: %w", name, err)
+		}
+		providers[name] = &provider{name: name, model: m, breaker: newBreaker(p.BreakerThreshold, p.BreakerCooldown)}
+	}
+
+	r := &Registry{
+		models:      make(map[string]model.LLM, len(providers)),
+		defaultName: cfg.Default,
+		agents:      cfg.Agents,
+	}
+	for name, p := range cfg.Providers {
+		chain := []*provider{providers[name]}
+		for _, fallback := range p.Fallbacks {
+			fp, ok := providers[fallback]
+			if !ok {
+				return nil, fmt.Errorf("%w: %s 的备用提供方 %q", ErrProviderNotFound, name, fallback)
+			}
+			chain = append(chain, fp)
+		}
+		r.models[name] = &fallbackModel{chain: chain}
+	}
+	if _, ok := r.models[r.defaultName]; !ok {
+		return nil, fmt.Errorf("%w: 默认提供方 %q", ErrProviderNotFound, r.defaultName)
+	}
+	return r, nil
+}
+
+// NewModel 根据提供方配置创建模型（不包含熔断和备用提供方）
+func NewModel(ctx context.Context, cfg *config.ProviderConfig) (model.LLM, error) {
+	switch cfg.Type {
+	case config.ProviderOpenAI:
+		return NewOpenAICompatModel(openAIOptions(cfg, cfg.BaseURL)...)
+	case config.ProviderOllama, config.ProviderLlamaCpp:
+		baseURL := cfg.BaseURL
+		if baseURL == "" {
+			baseURL = defaultOllamaBaseURL
+			if cfg.Type == config.ProviderLlamaCpp {
+				baseURL = defaultLlamaCppBaseURL
+			}
+		}
+		return NewOpenAICompatModel(append(openAIOptions(cfg, baseURL), WithoutAPIKey())...)
+	case config.ProviderAnthropic:
+		return NewAnthropicModel(cfg)
+	case config.ProviderGemini:
+		return NewGeminiModel(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("%w: 未知的类型 %q", ErrInvalidProvider, cfg.Type)
+	}
+}
+
+// openAIOptions 把提供方配置转换为 OpenAI 兼容模型的选项
+func openAIOptions(cfg *config.ProviderConfig, baseURL string) []Option {
+	opts := []Option{
+		WithAPIKey(cfg.APIKey),
+		WithBaseURL(baseURL),
+		WithModelName(cfg.Model),
+		WithHTTPClient(newHTTPClient(cfg.Timeout)),
+		WithMaxTokens(cfg.MaxTokens),
+		WithRetryPolicy(retryPolicy(cfg)),
+	}
+	if cfg.Temperature != nil {
+		opts = append(opts, WithTemperature(*cfg.Temperature))
+	}
+	if cfg.TopP != nil {
+		opts = append(opts, WithTopP(*cfg.TopP))
+	}
+	return opts
+}
+
+// retryPolicy 提供方配置中的重试策略
+func retryPolicy(cfg *config.ProviderConfig) RetryPolicy {
+	return RetryPolicy{
+		MaxAttempts: cfg.MaxAttempts,
+		BaseDelay:   cfg.RetryBaseDelay,
+		MaxDelay:    cfg.RetryMaxDelay,
+	}
+}
+
+// newHTTPClient 创建请求模型的 HTTP 客户端，timeout 为 0 时不限制
+func newHTTPClient(timeout time.Duration) *http.Client {
+	if timeout == 0 {
+		return http.DefaultClient
+	}
+	return &http.Client{Timeout: timeout}
+}
+
+// Get 返回指定名称的提供方的模型
+func (r *Registry) Get(name string) (model.LLM, error) {
+	m, ok := r.models[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", ErrProviderNotFound, name)
+	}
+	return m, nil
+}
+
+// Has 检查是否注册了指定名称的提供方
+func (r *Registry) Has(name string) bool {
+	_, ok := r.models[name]
+	return ok
+}
+
+// Default 返回默认提供方的模型
+func (r *Registry) Default() model.LLM {
+	return r.models[r.defaultName]
+}
+
+// ForAgent 返回 Agent 使用的模型：llm.agents 中为它指定了提供方时使用该提供方，否则使用默认提供方
+func (r *Registry) ForAgent(agentName string) model.LLM {
+	if m, ok := r.models[r.agents[agentName]]; ok {
+		return m
+	}
+	return r.Default()
+}
+
+// Names 返回所有提供方的名称（按名称排序）
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.models))
+	for name := range r.models {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// geminiModel 使用提供方配置中的生成参数默认值和重试策略的 Gemini 模型
+type geminiModel struct {
+	model.LLM
+	retry       RetryPolicy
+	temperature *float64
+	topP        *float64
+	maxTokens   int
+}
+
+// NewGeminiModel 创建 Gemini API 模型实例
+func NewGeminiModel(ctx context.Context, cfg *config.ProviderConfig) (model.LLM, error) {
+	clientConfig := &genai.ClientConfig{
+		APIKey:  cfg.APIKey,
+		Backend: genai.BackendGeminiAPI,
+		HTTPOptions: genai.HTTPOptions{
+			BaseURL: cfg.BaseURL,
+		},
+	}
+	if cfg.Timeout > 0 {
+		clientConfig.HTTPOptions.Timeout = &cfg.Timeout
+	}
+
+	m, err := gemini.NewModel(ctx, cfg.Model, clientConfig)
+	if err != nil {
+		return nil, err
+	}
+	return &geminiModel{
+		LLM:         m,
+		retry:       retryPolicy(cfg),
+		temperature: cfg.Temperature,
+		topP:        cfg.TopP,
+		maxTokens:   cfg.MaxTokens,
+	}, nil
+}
+
+// GenerateContent 调用 Gemini API，还没有输出回复时遇到临时性错误按重试策略重试
+func (m *geminiModel) GenerateContent(ctx context.Context, req *model.LLMRequest, stream bool) iter.Seq2[*model.LLMResponse, error] {
+	if req.Config == nil {
+		req.Config = &genai.GenerateContentConfig{}
+	}
+	if req.Config.Temperature == nil && m.temperature != nil {
+		req.Config.Temperature = genai.Ptr(float32(*m.temperature))
+	}
+	if req.Config.TopP == nil && m.topP != nil {
+		req.Config.TopP = genai.Ptr(float32(*m.topP))
+	}
+	if req.Config.MaxOutputTokens == 0 && m.maxTokens > 0 {
+		req.Config.MaxOutputTokens = int32(m.maxTokens)
+	}
+
+	return func(yield func(*model.LLMResponse, error) bool) {
+		for attempt := 1; ; attempt++ {
+			responded := false
+			var err error
+			for resp, e := range m.LLM.GenerateContent(ctx, cloneRequest(req), stream) {
+				if e != nil {
+					err = e
+					break
+				}
+				responded = true
+				if !yield(resp, nil) {
+					return
+				}
+			}
+			if err == nil {
+				return
+			}
+			if responded || ctx.Err() != nil || !isGeminiTemporary(err) {
+				yield(nil, err)
+				return
+			}
+			if attempt >= m.retry.MaxAttempts {
+				yield(nil, fmt.Errorf("%w: %w", ErrProviderUnavailable, err))
+				return
+			}
+
+			slog.Error("Gemini API error: ", "error", err, "attempt", attempt)
+			timer := time.NewTimer(m.retry.delay(attempt, 0))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				yield(nil, ctx.Err())
+				return
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// isGeminiTemporary 网络错误、限流和服务端错误是临时性的
+func isGeminiTemporary(err error) bool {
+	var apiErr genai.APIError
+	if errors.As(err, &apiErr) {
+		return isTemporaryStatus(apiErr.Code)
+	}
+	var urlErr *url.Error
+	return errors.As(err, &urlErr)
+}
@@ -0,0 +1,80 @@
+package llm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+// TestNewRegistry 测试按配置创建提供方，Agent 未指定提供方时使用默认提供方
+func TestNewRegistry(t *testing.T) {
+	cfg := &config.LLMConfig{
+		Default: "deepseek",
+		Agents:  map[string]string{"planner": "local"},
+		Providers: map[string]config.ProviderConfig{
+			"deepseek": {Type: config.ProviderOpenAI, APIKey: "sk-test", Model: "deepseek-chat", BaseURL: "https://api.deepseek.com/v1"},
+			"claude":   {Type: config.ProviderAnthropic, APIKey: "sk-ant-test", Model: "claude-sonnet-4-5"},
+			"local":    {Type: config.ProviderOllama, Model: "qwen2.5"},
+		},
+	}
+
+	r, err := NewRegistry(context.Background(), cfg)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"claude", "deepseek", "local"}, r.Names())
+	assert.Equal(t, "deepseek-chat", r.Default().Name())
+	assert.Equal(t, "qwen2.5", r.ForAgent("planner").Name())
+	assert.Equal(t, "deepseek-chat", r.ForAgent("calendar_agent").Name())
+
+	local, err := r.Get("local")
+	require.NoError(t, err)
+	assert.Equal(t, defaultOllamaBaseURL, local.(*fallbackModel).chain[0].model.(*openAIModel).baseUrl)
+	assert.True(t, r.Has("claude"))
+
+	_, err = r.Get("missing")
+	assert.ErrorIs(t, err, ErrProviderNotFound)
+
+	cfg.Providers["broken"] = config.ProviderConfig{Type: "unknown", Model: "x"}
+	_, err = NewRegistry(context.Background(), cfg)
+	assert.ErrorIs(t, err, ErrInvalidProvider)
+}
+
+// TestOpenAIModel_ProviderDefaults 测试提供方配置的生成参数作为请求的默认值，请求中设置的值优先
+func TestOpenAIModel_ProviderDefaults(t *testing.T) {
+	m, err := NewModel(context.Background(), &config.ProviderConfig{
+		Type:        config.ProviderOpenAI,
+		APIKey:      "sk-test",
+		Model:       "gpt-4o-mini",
+		Temperature: ptr(0.2),
+		TopP:        ptr(0.9),
+		MaxTokens:   512,
+	})
+	require.NoError(t, err)
+
+	req, err := m.(*openAIModel).convertRequest(&model.LLMRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, 0.2, *req.Temperature)
+	assert.Equal(t, 0.9, *req.TopP)
+	assert.Equal(t, 512, *req.MaxTokens)
+
+	req, err = m.(*openAIModel).convertRequest(&model.LLMRequest{
+		Config: &genai.GenerateContentConfig{Temperature: genai.Ptr[float32](1), MaxOutputTokens: 64},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, 1.0, *req.Temperature)
+	assert.Equal(t, 64, *req.MaxTokens)
+
+	// 本地服务不需要 API key
+	_, err = NewModel(context.Background(), &config.ProviderConfig{Type: config.ProviderLlamaCpp, Model: "llama"})
+	assert.NoError(t, err)
+	_, err = NewModel(context.Background(), &config.ProviderConfig{Type: config.ProviderOpenAI, Model: "gpt-4o-mini"})
+	assert.Error(t, err)
+}
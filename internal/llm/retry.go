@@ -0,0 +1,129 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/galilio/otter/internal/common/worker"
+)
+
+// ErrProviderUnavailable 提供方暂时不可用：网络错误、限流或服务端错误（重试后仍失败），或熔断器处于打开状态。
+// 这类错误计入熔断器，并切换到备用模型
+var ErrProviderUnavailable = errors.New("LLM 提供方暂时不可用")
+
+// RetryPolicy 请求失败时的重试策略
+type RetryPolicy struct {
+	MaxAttempts int           // 最多请求次数（包括第一次），小于 2 时不重试
+	BaseDelay   time.Duration // 第一次重试的等待时间，之后每次翻倍
+	MaxDelay    time.Duration // 等待时间的上限（包括 Retry-After）
+}
+
+// APIError 提供方返回的非 200 响应
+type APIError struct {
+	StatusCode int
+	Body       string
+	RetryAfter time.Duration // 响应中的 Retry-After，没有时为 0
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("API error: %d - %s", e.StatusCode, e.Body)
+}
+
+// Temporary 限流和服务端错误是临时性的，可以重试
+func (e *APIError) Temporary() bool {
+	return isTemporaryStatus(e.StatusCode)
+}
+
+// isTemporaryStatus 429、408 和 5xx（包括 Anthropic 过载时返回的 529）是临时性的错误
+func isTemporaryStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status == http.StatusRequestTimeout || status >= 500
+}
+
+// doWithRetry 发送请求，遇到网络错误、限流和服务端错误时按 policy 以指数退避（带随机抖动）重试，优先使用响应中的 Retry-After。
+// newRequest 每次请求都会调用，返回新的请求；非 200 响应返回 *APIError，重试后仍失败的临时性错误包装为 ErrProviderUnavailable
+func doWithRetry(ctx context.Context, client *http.Client, policy RetryPolicy, newRequest func() (*http.Request, error)) (*http.Response, error) {
+	var lastErr error
+	for attempt := 1; ; attempt++ {
+		req, err := newRequest()
+		if err != nil {
+			slog.Error("failed to create HTTP request: ", "error", err)
+			return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		}
+
+		var retryAfter time.Duration
+		httpResp, err := client.Do(req)
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return nil, fmt.Errorf("failed to send request: %w", err)
+			}
+			slog.Error("failed to send request: ", "error", err, "attempt", attempt)
+			lastErr = fmt.Errorf("failed to send request: %w", err)
+		case httpResp.StatusCode != http.StatusOK:
+			body, _ := io.ReadAll(httpResp.Body)
+			httpResp.Body.Close()
+
+			slog.Error("API error: ", "status", httpResp.StatusCode, "body", string(body), "attempt", attempt)
+			apiErr := &APIError{
+				StatusCode: httpResp.StatusCode,
+				Body:       string(body),
+				RetryAfter: parseRetryAfter(httpResp.Header.Get("Retry-After"), time.Now()),
+			}
+			if !apiErr.Temporary() {
+				return nil, apiErr
+			}
+			lastErr = apiErr
+			retryAfter = apiErr.RetryAfter
+		default:
+			return httpResp, nil
+		}
+
+		if attempt >= policy.MaxAttempts {
+			return nil, fmt.Errorf("%w: %w", ErrProviderUnavailable, lastErr)
+		}
+
+		timer := time.NewTimer(policy.delay(attempt, retryAfter))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, fmt.Errorf("failed to send request: %w", ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// delay 第 attempt 次请求失败后的等待时间：BaseDelay * 2^(attempt-1)，取其一半到全部之间的随机值；
+// 服务端指定了 Retry-After 时使用它。都不超过 MaxDelay
+func (p RetryPolicy) delay(attempt int, retryAfter time.Duration) time.Duration {
+	d := retryAfter
+	if d <= 0 {
+		d = worker.Backoff{Base: p.BaseDelay, Max: p.MaxDelay, Jitter: true}.Delay(attempt)
+	}
+	if p.MaxDelay > 0 && d > p.MaxDelay {
+		d = p.MaxDelay
+	}
+	return d
+}
+
+// parseRetryAfter 解析 Retry-After 头（秒数或 HTTP 日期），无效时返回 0
+func parseRetryAfter(value string, now time.Time) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0
+		}
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
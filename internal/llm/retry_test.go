@@ -0,0 +1,105 @@
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/adk/model"
+	"google.golang.org/genai"
+)
+
+// newRetryTestModel 创建请求 handler 的 OpenAI 兼容模型，重试不等待
+func newRetryTestModel(t *testing.T, handler http.HandlerFunc) model.LLM {
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	m, err := NewOpenAICompatModel(
+		WithAPIKey("sk-test"),
+		WithBaseURL(server.URL),
+		WithModelName("gpt-4o-mini"),
+		WithRetryPolicy(RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond}),
+	)
+	require.NoError(t, err)
+	return m
+}
+
+func generate(m model.LLM) (*model.LLMResponse, error) {
+	req := &model.LLMRequest{Contents: []*genai.Content{genai.NewContentFromText("你好", genai.RoleUser)}}
+	for resp, err := range m.GenerateContent(context.Background(), req, false) {
+		return resp, err
+	}
+	return nil, nil
+}
+
+// TestDoWithRetry 测试限流和服务端错误重试，客户端错误不重试，重试后仍失败时返回 ErrProviderUnavailable
+func TestDoWithRetry(t *testing.T) {
+	t.Run("重试后成功", func(t *testing.T) {
+		attempts := 0
+		m := newRetryTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			if attempts < 3 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusTooManyRequests)
+				return
+			}
+			fmt.Fprint(w, `{"choices":[{"message":{"role":"assistant","content":"你好"},"finish_reason":"stop"}]}`)
+		})
+
+		resp, err := generate(m)
+		require.NoError(t, err)
+		assert.Equal(t, "你好", resp.Content.Parts[0].Text)
+		assert.Equal(t, 3, attempts)
+	})
+
+	t.Run("客户端错误不重试", func(t *testing.T) {
+		attempts := 0
+		m := newRetryTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusBadRequest)
+		})
+
+		_, err := generate(m)
+		var apiErr *APIError
+		require.ErrorAs(t, err, &apiErr)
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+		assert.NotErrorIs(t, err, ErrProviderUnavailable)
+		assert.Equal(t, 1, attempts)
+	})
+
+	t.Run("重试次数用完", func(t *testing.T) {
+		attempts := 0
+		m := newRetryTestModel(t, func(w http.ResponseWriter, r *http.Request) {
+			attempts++
+			w.WriteHeader(http.StatusServiceUnavailable)
+		})
+
+		_, err := generate(m)
+		assert.ErrorIs(t, err, ErrProviderUnavailable)
+		assert.Equal(t, 3, attempts)
+	})
+}
+
+// TestRetryPolicy_Delay 测试退避时间翻倍并带随机抖动，Retry-After 优先，都不超过上限
+func TestRetryPolicy_Delay(t *testing.T) {
+	p := RetryPolicy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: 10 * time.Second}
+
+	for range 20 {
+		d := p.delay(3, 0)
+		assert.GreaterOrEqual(t, d, 2*time.Second)
+		assert.LessOrEqual(t, d, 4*time.Second)
+	}
+	assert.LessOrEqual(t, p.delay(10, 0), 10*time.Second)
+	assert.Equal(t, 7*time.Second, p.delay(1, 7*time.Second))
+	assert.Equal(t, 10*time.Second, p.delay(1, time.Minute))
+
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, 5*time.Second, parseRetryAfter("5", now))
+	assert.Equal(t, 30*time.Second, parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now))
+	assert.Equal(t, time.Duration(0), parseRetryAfter("soon", now))
+}
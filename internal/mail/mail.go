@@ -0,0 +1,203 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoRecipients = errors.New("没有收件人")
+	ErrDisabled     = errors.New("未配置邮件服务器")
+)
+
+// Attachment 邮件附件
+type Attachment struct {
+	Filename    string
+	ContentType string // 例如 "text/calendar; charset=UTF-8; method=PUBLISH"
+	Data        []byte
+}
+
+// CalendarPart iMIP 邮件（RFC 6047）中与正文并列的 text/calendar 内容
+type CalendarPart struct {
+	Method string // iTIP METHOD，例如 REQUEST、CANCEL
+	Data   []byte
+}
+
+// Message 邮件内容
+type Message struct {
+	From        string // 为空时使用 Mailer 配置的发件人
+	ReplyTo     string // 为空时不设置 Reply-To
+	To          []string
+	Subject     string
+	Text        string
+	Calendar    *CalendarPart // 不为空时正文为 multipart/alternative（text/plain 和 text/calendar）
+	Attachments []Attachment
+}
+
+// Mailer 邮件发送器
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// MailerFunc 函数形式的 Mailer
+type MailerFunc func(ctx context.Context, msg *Message) error
+
+// Send 实现 Mailer 接口
+func (f MailerFunc) Send(ctx context.Context, msg *Message) error {
+	return f(ctx, msg)
+}
+
+// encode 将邮件编码为 RFC 5322 格式：只有正文时为 text/plain，带日程时为 multipart/alternative，
+// 有附件时为 multipart/mixed（第一部分为正文）
+func (msg *Message) encode(from, domain string, now time.Time) ([]byte, error) {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		buf.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from)
+	if msg.ReplyTo != "" {
+		header("Reply-To", msg.ReplyTo)
+	}
+	header("To", strings.Join(msg.To, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header("Date", now.Format(time.RFC1123Z))
+	header("Message-ID", "<"+randomID()+"@"+domain+">")
+	header("MIME-Version", "1.0")
+
+	if len(msg.Attachments) == 0 && msg.Calendar == nil {
+		header("Content-Type", "text/plain; charset=UTF-8")
+		header("Content-Transfer-Encoding", "quoted-printable")
+		buf.WriteString("\r\n")
+		if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	if len(msg.Attachments) == 0 {
+		aw := multipart.NewWriter(&buf)
+		header("Content-Type", `multipart/alternative; boundary="`+aw.Boundary()+`"`)
+		buf.WriteString("\r\n")
+		if err := msg.writeAlternatives(aw); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	mw := multipart.NewWriter(&buf)
+	header("Content-Type", `multipart/mixed; boundary="`+mw.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	if msg.Calendar != nil {
+		boundary := randomID()
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type": {`multipart/alternative; boundary="` + boundary + `"`},
+		})
+		if err != nil {
+			return nil, err
+		}
+		aw := multipart.NewWriter(part)
+		if err := aw.SetBoundary(boundary); err != nil {
+			return nil, err
+		}
+		if err := msg.writeAlternatives(aw); err != nil {
+			return nil, err
+		}
+	} else {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {"text/plain; charset=UTF-8"},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(part, msg.Text); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, a := range msg.Attachments {
+		part, err := mw.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {a.ContentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeBase64(part, a.Data); err != nil {
+			return nil, err
+		}
+	}
+	if err := mw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeAlternatives 写入 multipart/alternative 的各部分：纯文本正文在前，text/calendar 在后（RFC 6047 2.4）
+func (msg *Message) writeAlternatives(aw *multipart.Writer) error {
+	part, err := aw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=UTF-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeQuotedPrintable(part, msg.Text); err != nil {
+		return err
+	}
+
+	part, err = aw.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {mime.FormatMediaType("text/calendar", map[string]string{"charset": "UTF-8", "method": msg.Calendar.Method})},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return err
+	}
+	if err := writeBase64(part, msg.Calendar.Data); err != nil {
+		return err
+	}
+	return aw.Close()
+}
+
+// writeQuotedPrintable 以 quoted-printable 编码写入正文（换行统一为 CRLF）
+func writeQuotedPrintable(w io.Writer, text string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(strings.ReplaceAll(text, "\r\n", "\n"))); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+// writeBase64 以 base64 编码写入附件，每行 76 个字符（RFC 2045 6.8）
+func writeBase64(w io.Writer, data []byte) error {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 0 {
+		n := min(76, len(encoded))
+		if _, err := fmt.Fprintf(w, "%s\r\n", encoded[:n]); err != nil {
+			return err
+		}
+		encoded = encoded[n:]
+	}
+	return nil
+}
+
+// randomID 生成 Message-ID 的本地部分
+func randomID() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,149 @@
+package mail
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	netmail "net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/galilio/otter/internal/common/config"
+)
+
+// SMTPMailer 通过 SMTP 服务器发送邮件
+type SMTPMailer struct {
+	cfg  config.SMTPConfig
+	from *netmail.Address
+	now  func() time.Time
+}
+
+// NewSMTPMailer 创建 SMTP 邮件发送器
+func NewSMTPMailer(cfg *config.SMTPConfig) (*SMTPMailer, error) {
+	if cfg.Host == "" {
+		return nil, ErrDisabled
+	}
+	from, err := netmail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("无效的发件人 %q: %w", cfg.From, err)
+	}
+	switch cfg.TLS {
+	case "", "none", "starttls", "tls":
+	default:
+		return nil, fmt.Errorf("无效的 SMTP TLS 模式 %q", cfg.TLS)
+	}
+	return &SMTPMailer{cfg: *cfg, from: from, now: time.Now}, nil
+}
+
+// Send 发送邮件
+func (m *SMTPMailer) Send(ctx context.Context, msg *Message) error {
+	if len(msg.To) == 0 {
+		return ErrNoRecipients
+	}
+	from := m.from
+	if msg.From != "" {
+		addr, err := netmail.ParseAddress(msg.From)
+		if err != nil {
+			return fmt.Errorf("无效的发件人 %q: %w", msg.From, err)
+		}
+		from = addr
+	}
+	recipients := make([]string, 0, len(msg.To))
+	for _, to := range msg.To {
+		addr, err := netmail.ParseAddress(to)
+		if err != nil {
+			return fmt.Errorf("无效的收件人 %q: %w", to, err)
+		}
+		recipients = append(recipients, addr.Address)
+	}
+
+	data, err := msg.encode(from.String(), domainOf(from.Address), m.now())
+	if err != nil {
+		return fmt.Errorf("编码邮件失败: %w", err)
+	}
+
+	client, err := m.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("SMTP MAIL 失败: %w", err)
+	}
+	for _, rcpt := range recipients {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("SMTP RCPT %s 失败: %w", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP DATA 失败: %w", err)
+	}
+	if _, err := w.Write(data); err != nil {
+		return fmt.Errorf("发送邮件内容失败: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件内容失败: %w", err)
+	}
+	return client.Quit()
+}
+
+// dial 连接 SMTP 服务器，按配置建立 TLS 并认证
+func (m *SMTPMailer) dial(ctx context.Context) (*smtp.Client, error) {
+	timeout := m.cfg.Timeout
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return nil, fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	deadline := time.Now().Add(timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	_ = conn.SetDeadline(deadline)
+
+	tlsConfig := &tls.Config{ServerName: m.cfg.Host, InsecureSkipVerify: m.cfg.InsecureSkipVerify}
+	if m.cfg.TLS == "tls" {
+		conn = tls.Client(conn, tlsConfig)
+	}
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("连接 SMTP 服务器失败: %w", err)
+	}
+	if m.cfg.TLS == "" || m.cfg.TLS == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			client.Close()
+			return nil, fmt.Errorf("SMTP 服务器不支持 STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("SMTP STARTTLS 失败: %w", err)
+		}
+	}
+	if m.cfg.Username != "" {
+		auth := smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)
+		if err := client.Auth(auth); err != nil {
+			client.Close()
+			return nil, fmt.Errorf("SMTP 认证失败: %w", err)
+		}
+	}
+	return client, nil
+}
+
+// domainOf 返回邮件地址的域名部分，用于生成 Message-ID
+func domainOf(address string) string {
+	if i := strings.LastIndex(address, "@"); i >= 0 {
+		return address[i+1:]
+	}
+	return "localhost"
+}
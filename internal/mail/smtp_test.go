@@ -0,0 +1,216 @@
+package mail
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	netmail "net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// receivedMail 测试 SMTP 服务器收到的邮件
+type receivedMail struct {
+	From string
+	To   []string
+	Data string
+}
+
+// startTestSMTPServer 启动一个只支持最基本命令的本地 SMTP 服务器，返回端口和收到的邮件
+func startTestSMTPServer(t *testing.T) (int, <-chan receivedMail) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan receivedMail, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		r := bufio.NewReader(conn)
+		reply := func(s string) { io.WriteString(conn, s+"\r\n") }
+		reply("220 localhost ESMTP test")
+
+		var mail receivedMail
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			cmd := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(cmd, "EHLO"), strings.HasPrefix(cmd, "HELO"):
+				reply("250 localhost")
+			case strings.HasPrefix(cmd, "MAIL FROM:"):
+				mail.From = strings.Trim(strings.TrimSpace(line)[len("MAIL FROM:"):], "<>")
+				reply("250 OK")
+			case strings.HasPrefix(cmd, "RCPT TO:"):
+				mail.To = append(mail.To, strings.Trim(strings.TrimSpace(line)[len("RCPT TO:"):], "<>"))
+				reply("250 OK")
+			case cmd == "DATA":
+				reply("354 End data with <CR><LF>.<CR><LF>")
+				var data strings.Builder
+				for {
+					l, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					if l == ".\r\n" {
+						break
+					}
+					data.WriteString(l)
+				}
+				mail.Data = data.String()
+				reply("250 OK")
+			case cmd == "QUIT":
+				reply("221 Bye")
+				received <- mail
+				return
+			default:
+				reply("502 Command not implemented")
+			}
+		}
+	}()
+
+	return ln.Addr().(*net.TCPAddr).Port, received
+}
+
+// TestSMTPMailer_Send 测试通过本地 SMTP 服务器发送带附件的邮件
+func TestSMTPMailer_Send(t *testing.T) {
+	port, received := startTestSMTPServer(t)
+	mailer, err := NewSMTPMailer(&config.SMTPConfig{
+		Host: "127.0.0.1", Port: port, From: "Otter <otter@example.com>", TLS: "none", Timeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), &Message{
+		To:      []string{"Alice <alice@example.com>"},
+		Subject: "提醒：团队会议",
+		Text:    "团队会议将在 15 分钟后开始",
+		Attachments: []Attachment{{
+			Filename:    "event.ics",
+			ContentType: "text/calendar; charset=UTF-8; method=PUBLISH",
+			Data:        []byte("BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n"),
+		}},
+	})
+	require.NoError(t, err)
+
+	mail := <-received
+	assert.Equal(t, "otter@example.com", mail.From)
+	assert.Equal(t, []string{"alice@example.com"}, mail.To)
+
+	msg, err := netmail.ReadMessage(strings.NewReader(mail.Data))
+	require.NoError(t, err)
+	subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+	require.NoError(t, err)
+	assert.Equal(t, "提醒：团队会议", subject)
+
+	mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/mixed", mediaType)
+
+	mr := multipart.NewReader(msg.Body, params["boundary"])
+	text, err := mr.NextPart()
+	require.NoError(t, err)
+	body, _ := io.ReadAll(text)
+	assert.Equal(t, "团队会议将在 15 分钟后开始", string(body))
+
+	attachment, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "event.ics", attachment.FileName())
+	assert.Equal(t, "base64", attachment.Header.Get("Content-Transfer-Encoding"))
+	data, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, attachment))
+	assert.Equal(t, "BEGIN:VCALENDAR\r\nEND:VCALENDAR\r\n", string(data))
+}
+
+// TestMessage_EncodeCalendar 测试 iMIP 邮件的结构：正文和 text/calendar 作为 multipart/alternative
+func TestMessage_EncodeCalendar(t *testing.T) {
+	ics := "BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nEND:VCALENDAR\r\n"
+	msg := &Message{
+		ReplyTo:  "alice@example.com",
+		To:       []string{"bob@example.com"},
+		Subject:  "邀请：周会",
+		Text:     "周会",
+		Calendar: &CalendarPart{Method: "REQUEST", Data: []byte(ics)},
+	}
+
+	data, err := msg.encode("otter@example.com", "example.com", time.Now())
+	require.NoError(t, err)
+	parsed, err := netmail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	assert.Equal(t, "alice@example.com", parsed.Header.Get("Reply-To"))
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	text, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "text/plain; charset=UTF-8", text.Header.Get("Content-Type"))
+
+	calendar, err := mr.NextPart()
+	require.NoError(t, err)
+	mediaType, params, err = mime.ParseMediaType(calendar.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "text/calendar", mediaType)
+	assert.Equal(t, "REQUEST", params["method"])
+	body, _ := io.ReadAll(base64.NewDecoder(base64.StdEncoding, calendar))
+	assert.Equal(t, ics, string(body))
+
+	// 有附件时 multipart/alternative 嵌套在 multipart/mixed 中
+	msg.Attachments = []Attachment{{Filename: "invite.ics", ContentType: "application/ics", Data: []byte(ics)}}
+	data, err = msg.encode("otter@example.com", "example.com", time.Now())
+	require.NoError(t, err)
+	parsed, err = netmail.ReadMessage(strings.NewReader(string(data)))
+	require.NoError(t, err)
+	_, params, err = mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+
+	mr = multipart.NewReader(parsed.Body, params["boundary"])
+	alternative, err := mr.NextPart()
+	require.NoError(t, err)
+	mediaType, _, err = mime.ParseMediaType(alternative.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+	attachment, err := mr.NextPart()
+	require.NoError(t, err)
+	assert.Equal(t, "invite.ics", attachment.FileName())
+}
+
+// TestSMTPMailer_StartTLSRequired 测试服务器不支持 STARTTLS 时拒绝以明文发送
+func TestSMTPMailer_StartTLSRequired(t *testing.T) {
+	port, _ := startTestSMTPServer(t)
+	mailer, err := NewSMTPMailer(&config.SMTPConfig{
+		Host: "127.0.0.1", Port: port, From: "otter@example.com", TLS: "starttls", Timeout: 5 * time.Second,
+	})
+	require.NoError(t, err)
+
+	err = mailer.Send(context.Background(), &Message{To: []string{"alice@example.com"}, Subject: "hi", Text: "hi"})
+
+	assert.ErrorContains(t, err, "STARTTLS")
+}
+
+// TestNewSMTPMailer_Invalid 测试无效的配置
+func TestNewSMTPMailer_Invalid(t *testing.T) {
+	_, err := NewSMTPMailer(&config.SMTPConfig{})
+	assert.ErrorIs(t, err, ErrDisabled)
+
+	_, err = NewSMTPMailer(&config.SMTPConfig{Host: "localhost", From: "not an address"})
+	assert.Error(t, err)
+
+	_, err = NewSMTPMailer(&config.SMTPConfig{Host: "localhost", From: "otter@example.com", TLS: "ssl"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package reminder
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/mail"
+	"github.com/galilio/otter/internal/user"
+)
+
+// digestResendGuard 距上次发送不足该时长的用户不再检查，减少每次检查的查询量
+const digestResendGuard = 12 * time.Hour
+
+// DigestSender 每日日程摘要发送器
+// 用户本地时间到达配置的小时后，发送第二天（用户时区）的日程和到期待办。
+// 发送记录保存在数据库中，每个用户每天最多发送一次，多个实例同时运行时也不会重复
+type DigestSender struct {
+	repo            Repository
+	calendarService calendar.Service
+	mailer          mail.Mailer
+
+	interval time.Duration
+	next     time.Time
+	now      func() time.Time
+}
+
+// NewDigestSender 创建每日摘要发送器，interval 为检查是否需要发送的间隔
+func NewDigestSender(repo Repository, calendarService calendar.Service, mailer mail.Mailer, interval time.Duration) *DigestSender {
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	return &DigestSender{
+		repo:            repo,
+		calendarService: calendarService,
+		mailer:          mailer,
+		interval:        interval,
+		now:             time.Now,
+	}
+}
+
+// digestEntry 摘要中的一条日程或待办
+type digestEntry struct {
+	Time     string
+	Summary  string
+	Location string
+}
+
+// digestEmail 摘要邮件模板的数据
+type digestEmail struct {
+	Name   string
+	Date   string
+	Events []digestEntry
+	Todos  []digestEntry
+}
+
+// Tick 距上次检查超过检查间隔时，为到达发送时间的用户发送摘要
+func (d *DigestSender) Tick(ctx context.Context) error {
+	now := d.now()
+	if now.Before(d.next) {
+		return nil
+	}
+	d.next = now.Add(d.interval)
+
+	users, err := d.repo.ListDigestSubscribers(now.Add(-digestResendGuard))
+	if err != nil {
+		return fmt.Errorf("获取每日摘要订阅用户失败: %w", err)
+	}
+	for _, u := range users {
+		if err := d.sendIfDue(ctx, u, now); err != nil {
+			slog.ErrorContext(ctx, "发送每日摘要失败", "user_id", u.ID, "error", err)
+		}
+	}
+	return nil
+}
+
+// sendIfDue 用户本地时间已到发送时间且当天还没有发送时，发送第二天的摘要
+func (d *DigestSender) sendIfDue(ctx context.Context, u *user.User, now time.Time) error {
+	if u.Profile == nil || u.Email == "" {
+		return nil
+	}
+	local := now.In(u.Profile.Location())
+	if local.Hour() < u.Profile.DigestHour {
+		return nil
+	}
+	start := time.Date(local.Year(), local.Month(), local.Day()+1, 0, 0, 0, 0, local.Location())
+	end := start.AddDate(0, 0, 1)
+
+	delivery := &DigestDelivery{UserID: u.ID, DigestDate: start.Format(time.DateOnly)}
+	claimed, err := d.repo.ClaimDigest(delivery)
+	if err != nil || !claimed {
+		return err
+	}
+
+	if err := d.send(ctx, u, start, end); err != nil {
+		if releaseErr := d.repo.ReleaseDigest(delivery.ID); releaseErr != nil {
+			slog.ErrorContext(ctx, "删除每日摘要发送记录失败", "user_id", u.ID, "error", releaseErr)
+		}
+		return err
+	}
+	return nil
+}
+
+// send 收集 [start, end) 内的日程和到期待办并发送摘要邮件
+func (d *DigestSender) send(ctx context.Context, u *user.User, start, end time.Time) error {
+	events, err := d.listEvents(u.ID, start, end)
+	if err != nil {
+		return err
+	}
+	todos, err := d.calendarService.ListDueTodos(&u.ID, start, end)
+	if err != nil {
+		return err
+	}
+
+	data := &digestEmail{Name: u.Username, Date: start.Format(time.DateOnly)}
+	if u.FirstName != "" || u.LastName != "" {
+		data.Name = u.LastName + u.FirstName
+	}
+	for _, item := range events {
+		// 前一天开始、持续到当天的日程显示为 00:00
+		data.Events = append(data.Events, newDigestEntry(item, maxTime(item.DtStart, start).In(start.Location())))
+	}
+	for _, item := range todos {
+		data.Todos = append(data.Todos, newDigestEntry(item, item.Due.In(start.Location())))
+	}
+
+	subject, body, err := render(digestTemplate, data)
+	if err != nil {
+		return err
+	}
+	return d.mailer.Send(ctx, &mail.Message{To: []string{u.Email}, Subject: subject, Text: body})
+}
+
+// listEvents 列出与 [start, end) 重叠的日程，重复日程展开为实例
+func (d *DigestSender) listEvents(userID uint, start, end time.Time) ([]*calendar.CalendarItem, error) {
+	eventType := calendar.CalendarItemTypeEvent
+	var events []*calendar.CalendarItem
+	var cursor string
+	for {
+		resp, err := d.calendarService.ListCalendarItems(&userID, &calendar.ListCalendarItemsRequest{
+			PageSize: 100, StartTime: &start, EndTime: &end, Type: &eventType, Cursor: cursor,
+		})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range resp.Items {
+			if item.DtStart.Before(end) {
+				events = append(events, item)
+			}
+		}
+		if resp.NextCursor == "" {
+			break
+		}
+		cursor = resp.NextCursor
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].DtStart.Before(events[j].DtStart) })
+	return events, nil
+}
+
+// newDigestEntry 摘要中的一行，只显示时分
+func newDigestEntry(item *calendar.CalendarItem, at time.Time) digestEntry {
+	summary := calendar.StringValue(item.Summary)
+	if summary == "" {
+		summary = "（无标题）"
+	}
+	return digestEntry{Time: at.Format("15:04"), Summary: summary, Location: calendar.StringValue(item.Location)}
+}
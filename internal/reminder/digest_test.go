@@ -0,0 +1,143 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockCalendarService 模拟日历服务，只实现摘要用到的方法
+type mockCalendarService struct {
+	calendar.Service
+	mock.Mock
+}
+
+func (m *mockCalendarService) ListCalendarItems(userID *uint, req *calendar.ListCalendarItemsRequest) (*calendar.CalendarItemListResponse, error) {
+	args := m.Called(userID, req)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*calendar.CalendarItemListResponse), args.Error(1)
+}
+
+func (m *mockCalendarService) ListDueTodos(userID *uint, startTime, endTime time.Time) ([]*calendar.CalendarItem, error) {
+	args := m.Called(userID, startTime, endTime)
+	return args.Get(0).([]*calendar.CalendarItem), args.Error(1)
+}
+
+// digestSubscriber 时区为上海、18 点接收摘要的用户
+func digestSubscriber() *user.User {
+	return &user.User{
+		ID: 1, Username: "alice", Email: "alice@example.com",
+		Profile: &user.UserProfile{UserID: 1, Timezone: "Asia/Shanghai", DailyDigest: true, DigestHour: 18},
+	}
+}
+
+// TestDigestSender_Tick 测试到达发送时间后发送第二天的日程和待办
+func TestDigestSender_Tick(t *testing.T) {
+	repo := new(mockRepository)
+	calendarService := new(mockCalendarService)
+	mailer, sent := recordMailer(nil)
+	shanghai, _ := time.LoadLocation("Asia/Shanghai")
+	now := time.Date(2025, 1, 6, 18, 5, 0, 0, shanghai)
+	start := time.Date(2025, 1, 7, 0, 0, 0, 0, shanghai)
+	end := start.AddDate(0, 0, 1)
+
+	meeting, lunch, report := "周会", "午餐", "提交周报"
+	location := "3 号会议室"
+	repo.On("ListDigestSubscribers", now.Add(-digestResendGuard)).Return([]*user.User{digestSubscriber()}, nil)
+	repo.On("ClaimDigest", &DigestDelivery{UserID: 1, DigestDate: "2025-01-07"}).Return(true, nil)
+	calendarService.On("ListCalendarItems", mock.Anything, mock.MatchedBy(func(req *calendar.ListCalendarItemsRequest) bool {
+		return req.StartTime.Equal(start) && req.EndTime.Equal(end) && *req.Type == calendar.CalendarItemTypeEvent
+	})).Return(&calendar.CalendarItemListResponse{
+		Items: []*calendar.CalendarItem{
+			{Summary: &lunch, DtStart: start.Add(12 * time.Hour)},
+			{Summary: &meeting, Location: &location, DtStart: start.Add(9 * time.Hour)},
+			{Summary: &meeting, DtStart: end}, // 窗口结束时开始的日程不属于当天
+		},
+		TotalPages: 1,
+	}, nil)
+	due := start.Add(17 * time.Hour)
+	calendarService.On("ListDueTodos", mock.Anything, start, end).
+		Return([]*calendar.CalendarItem{{Type: calendar.CalendarItemTypeTodo, Summary: &report, Due: &due}}, nil)
+
+	d := NewDigestSender(repo, calendarService, mailer, time.Minute)
+	d.now = func() time.Time { return now }
+
+	require.NoError(t, d.Tick(context.Background()))
+
+	require.Len(t, *sent, 1)
+	msg := (*sent)[0]
+	assert.Equal(t, []string{"alice@example.com"}, msg.To)
+	assert.Equal(t, "2025-01-07 日程摘要", msg.Subject)
+	assert.Contains(t, msg.Text, "日程（2）\n  09:00  周会 @ 3 号会议室\n  12:00  午餐\n")
+	assert.Contains(t, msg.Text, "待办（1）\n  17:00 截止  提交周报\n")
+	repo.AssertExpectations(t)
+
+	// 检查间隔内不再查询
+	require.NoError(t, d.Tick(context.Background()))
+	repo.AssertNumberOfCalls(t, "ListDigestSubscribers", 1)
+}
+
+// TestDigestSender_Tick_NotYet 测试用户本地时间未到发送时间时不发送
+func TestDigestSender_Tick_NotYet(t *testing.T) {
+	repo := new(mockRepository)
+	mailer, sent := recordMailer(nil)
+	now := time.Date(2025, 1, 6, 9, 59, 0, 0, time.UTC) // 上海 17:59
+
+	repo.On("ListDigestSubscribers", mock.Anything).Return([]*user.User{digestSubscriber()}, nil)
+
+	d := NewDigestSender(repo, new(mockCalendarService), mailer, time.Minute)
+	d.now = func() time.Time { return now }
+
+	require.NoError(t, d.Tick(context.Background()))
+	assert.Empty(t, *sent)
+	repo.AssertNotCalled(t, "ClaimDigest", mock.Anything)
+}
+
+// TestDigestSender_Tick_AlreadyClaimed 测试其它实例已发送时跳过
+func TestDigestSender_Tick_AlreadyClaimed(t *testing.T) {
+	repo := new(mockRepository)
+	mailer, sent := recordMailer(nil)
+	now := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	repo.On("ListDigestSubscribers", mock.Anything).Return([]*user.User{digestSubscriber()}, nil)
+	repo.On("ClaimDigest", mock.Anything).Return(false, nil)
+
+	d := NewDigestSender(repo, new(mockCalendarService), mailer, time.Minute)
+	d.now = func() time.Time { return now }
+
+	require.NoError(t, d.Tick(context.Background()))
+	assert.Empty(t, *sent)
+}
+
+// TestDigestSender_Tick_SendError 测试发送失败时删除发送记录以便重试
+func TestDigestSender_Tick_SendError(t *testing.T) {
+	repo := new(mockRepository)
+	calendarService := new(mockCalendarService)
+	mailer, _ := recordMailer(errors.New("connection refused"))
+	now := time.Date(2025, 1, 6, 10, 0, 0, 0, time.UTC)
+
+	repo.On("ListDigestSubscribers", mock.Anything).Return([]*user.User{digestSubscriber()}, nil)
+	repo.On("ClaimDigest", mock.Anything).Run(func(args mock.Arguments) {
+		args.Get(0).(*DigestDelivery).ID = 7
+	}).Return(true, nil)
+	repo.On("ReleaseDigest", uint(7)).Return(nil)
+	calendarService.On("ListCalendarItems", mock.Anything, mock.Anything).
+		Return(&calendar.CalendarItemListResponse{TotalPages: 0}, nil)
+	calendarService.On("ListDueTodos", mock.Anything, mock.Anything, mock.Anything).
+		Return([]*calendar.CalendarItem{}, nil)
+
+	d := NewDigestSender(repo, calendarService, mailer, time.Minute)
+	d.now = func() time.Time { return now }
+
+	require.NoError(t, d.Tick(context.Background()))
+	repo.AssertCalled(t, "ReleaseDigest", uint(7))
+}
@@ -0,0 +1,167 @@
+package reminder
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/mail"
+	"github.com/galilio/otter/internal/user"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// 每个模板文件定义 subject 和 body 两个模板
+var (
+	alarmTemplate  = template.Must(template.ParseFS(templateFS, "templates/alarm.tmpl"))
+	digestTemplate = template.Must(template.ParseFS(templateFS, "templates/digest.tmpl"))
+)
+
+// emailTimeLayout 邮件中显示时间的格式
+const emailTimeLayout = "2006-01-02 15:04 MST"
+
+// UserLookup 查询提醒所属用户的邮箱和时区，user.Service 实现了该接口
+type UserLookup interface {
+	GetUserByID(id uint) (*user.User, error)
+	GetUserProfile(userID uint) (*user.UserProfile, error)
+}
+
+// EmailNotifier 发送 EMAIL 提醒的发送器
+// 收件人为提醒的 ATTENDEE，没有时发送给日历项所属用户；邮件附带日历项的 .ics 文件
+type EmailNotifier struct {
+	mailer mail.Mailer
+	users  UserLookup
+}
+
+// NewEmailNotifier 创建邮件提醒发送器
+func NewEmailNotifier(mailer mail.Mailer, users UserLookup) *EmailNotifier {
+	return &EmailNotifier{mailer: mailer, users: users}
+}
+
+// alarmEmail 提醒邮件模板的数据
+type alarmEmail struct {
+	Summary          string
+	AlarmSummary     string
+	AlarmDescription string
+	Start            string
+	End              string
+	Due              string
+	Location         string
+	Description      string
+	URL              string
+}
+
+// Notify 实现 Notifier 接口
+func (e *EmailNotifier) Notify(ctx context.Context, n *Notification) error {
+	to := alarmRecipients(n.Alarm)
+	loc := time.UTC
+	if n.UserID != nil {
+		owner, err := e.users.GetUserByID(*n.UserID)
+		if err != nil {
+			return fmt.Errorf("获取提醒所属用户失败: %w", err)
+		}
+		if len(to) == 0 && owner.Email != "" {
+			to = []string{owner.Email}
+		}
+		if profile, err := e.users.GetUserProfile(*n.UserID); err == nil {
+			loc = profile.Location()
+		}
+	}
+	if len(to) == 0 {
+		// 没有收件人时重试也无法发送
+		slog.WarnContext(ctx, "邮件提醒没有收件人，跳过", "valarm_id", n.Alarm.ID)
+		return nil
+	}
+
+	subject, body, err := render(alarmTemplate, newAlarmEmail(n, loc))
+	if err != nil {
+		return err
+	}
+	attachment, err := icsAttachment(n.Item)
+	if err != nil {
+		return err
+	}
+
+	return e.mailer.Send(ctx, &mail.Message{
+		To:          to,
+		Subject:     subject,
+		Text:        body,
+		Attachments: []mail.Attachment{attachment},
+	})
+}
+
+// newAlarmEmail 准备提醒邮件模板的数据，时间按用户时区显示
+func newAlarmEmail(n *Notification, loc *time.Location) *alarmEmail {
+	item := n.Item
+	data := &alarmEmail{
+		Summary:          calendar.StringValue(item.Summary),
+		AlarmSummary:     calendar.StringValue(n.Alarm.Summary),
+		AlarmDescription: calendar.StringValue(n.Alarm.Description),
+		Location:         calendar.StringValue(item.Location),
+		Description:      calendar.StringValue(item.Description),
+		URL:              calendar.StringValue(item.URL),
+		Start:            item.DtStart.In(loc).Format(emailTimeLayout),
+	}
+	if data.Summary == "" {
+		data.Summary = "（无标题）"
+	}
+	if item.Type == calendar.CalendarItemTypeTodo && item.Due != nil {
+		data.Due = item.Due.In(loc).Format(emailTimeLayout)
+	} else if item.DtEnd != nil {
+		data.End = item.DtEnd.In(loc).Format(emailTimeLayout)
+	}
+	return data
+}
+
+// icsAttachment 将日历项导出为 .ics 附件；重复日历项的实例导出主日历项
+func icsAttachment(item *calendar.CalendarItem) (mail.Attachment, error) {
+	if item.Master != nil {
+		item = item.Master
+	}
+	var buf bytes.Buffer
+	if err := calendar.WriteICalendar(&buf, "Otter", []*calendar.CalendarItem{item}); err != nil {
+		return mail.Attachment{}, fmt.Errorf("导出日历项失败: %w", err)
+	}
+	return mail.Attachment{
+		Filename:    "invite.ics",
+		ContentType: "text/calendar; charset=UTF-8; method=PUBLISH",
+		Data:        buf.Bytes(),
+	}, nil
+}
+
+// alarmRecipients 解析提醒的 ATTENDEE（逗号分隔，可带 mailto: 前缀）
+func alarmRecipients(alarm *calendar.Valarm) []string {
+	if alarm.Attendee == nil {
+		return nil
+	}
+	var to []string
+	for _, a := range strings.Split(*alarm.Attendee, ",") {
+		a = strings.TrimSpace(a)
+		if len(a) >= len("mailto:") && strings.EqualFold(a[:len("mailto:")], "mailto:") {
+			a = a[len("mailto:"):]
+		}
+		if a != "" {
+			to = append(to, a)
+		}
+	}
+	return to
+}
+
+// render 渲染邮件的 subject 和 body
+func render(tmpl *template.Template, data any) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", fmt.Errorf("渲染邮件标题失败: %w", err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", fmt.Errorf("渲染邮件正文失败: %w", err)
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
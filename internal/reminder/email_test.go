@@ -0,0 +1,132 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/mail"
+	"github.com/galilio/otter/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockUserLookup 模拟用户查询
+type mockUserLookup struct {
+	mock.Mock
+}
+
+func (m *mockUserLookup) GetUserByID(id uint) (*user.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *mockUserLookup) GetUserProfile(userID uint) (*user.UserProfile, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.UserProfile), args.Error(1)
+}
+
+// recordMailer 记录发送的邮件
+func recordMailer(err error) (mail.Mailer, *[]*mail.Message) {
+	var sent []*mail.Message
+	return mail.MailerFunc(func(ctx context.Context, msg *mail.Message) error {
+		sent = append(sent, msg)
+		return err
+	}), &sent
+}
+
+// emailNotification 每周重复事件某次实例的 EMAIL 提醒
+func emailNotification(attendee *string) *Notification {
+	master, alarm := weeklyItem()
+	summary, location := "周会", "3 号会议室"
+	master.Summary, master.Location = &summary, &location
+	end := master.DtStart.Add(time.Hour)
+	master.DtEnd = &end
+	alarm.Action = calendar.ValarmActionEmail
+	alarm.Attendee = attendee
+
+	occurrence := master.OccurrenceAt(utcTime(13, 9, 0))
+	return &Notification{
+		UserID:  master.UserID,
+		Item:    occurrence,
+		Alarm:   alarm,
+		Trigger: calendar.AlarmTrigger{At: utcTime(13, 8, 45), Occurrence: utcTime(13, 9, 0)},
+		FiredAt: utcTime(13, 8, 45),
+	}
+}
+
+// TestEmailNotifier_Notify 测试发送给日历项所属用户，时间按用户时区显示并附带主日历项的 .ics
+func TestEmailNotifier_Notify(t *testing.T) {
+	users := new(mockUserLookup)
+	users.On("GetUserByID", uint(1)).Return(&user.User{ID: 1, Email: "alice@example.com"}, nil)
+	users.On("GetUserProfile", uint(1)).Return(&user.UserProfile{UserID: 1, Timezone: "Asia/Shanghai"}, nil)
+	mailer, sent := recordMailer(nil)
+
+	err := NewEmailNotifier(mailer, users).Notify(context.Background(), emailNotification(nil))
+
+	require.NoError(t, err)
+	require.Len(t, *sent, 1)
+	msg := (*sent)[0]
+	assert.Equal(t, []string{"alice@example.com"}, msg.To)
+	assert.Equal(t, "提醒：周会", msg.Subject)
+	assert.Contains(t, msg.Text, "时间：2025-01-13 17:00 CST - 2025-01-13 18:00 CST")
+	assert.Contains(t, msg.Text, "地点：3 号会议室")
+
+	require.Len(t, msg.Attachments, 1)
+	ics := string(msg.Attachments[0].Data)
+	assert.Equal(t, "text/calendar; charset=UTF-8; method=PUBLISH", msg.Attachments[0].ContentType)
+	assert.Contains(t, ics, "UID:weekly")
+	assert.Contains(t, ics, "RRULE:FREQ=WEEKLY")
+	assert.NotContains(t, ics, "RECURRENCE-ID")
+}
+
+// TestEmailNotifier_Notify_Attendees 测试发送给提醒的 ATTENDEE
+func TestEmailNotifier_Notify_Attendees(t *testing.T) {
+	users := new(mockUserLookup)
+	users.On("GetUserByID", uint(1)).Return(&user.User{ID: 1, Email: "alice@example.com"}, nil)
+	users.On("GetUserProfile", uint(1)).Return(nil, errors.New("not found"))
+	mailer, sent := recordMailer(nil)
+	attendee := "mailto:bob@example.com, MAILTO:carol@example.com"
+
+	err := NewEmailNotifier(mailer, users).Notify(context.Background(), emailNotification(&attendee))
+
+	require.NoError(t, err)
+	require.Len(t, *sent, 1)
+	assert.Equal(t, []string{"bob@example.com", "carol@example.com"}, (*sent)[0].To)
+	assert.Contains(t, (*sent)[0].Text, "2025-01-13 09:00 UTC")
+}
+
+// TestEmailNotifier_Notify_MailerError 测试发送失败时返回错误以便调度器重试
+func TestEmailNotifier_Notify_MailerError(t *testing.T) {
+	users := new(mockUserLookup)
+	users.On("GetUserByID", uint(1)).Return(&user.User{ID: 1, Email: "alice@example.com"}, nil)
+	users.On("GetUserProfile", uint(1)).Return(&user.UserProfile{UserID: 1}, nil)
+	mailer, _ := recordMailer(errors.New("connection refused"))
+
+	err := NewEmailNotifier(mailer, users).Notify(context.Background(), emailNotification(nil))
+
+	assert.ErrorContains(t, err, "connection refused")
+}
+
+// TestRender_AlarmSummary 测试提醒设置了 SUMMARY 时作为邮件标题
+func TestRender_AlarmSummary(t *testing.T) {
+	n := emailNotification(nil)
+	summary, description := "别忘了带电脑", "需要演示新功能"
+	n.Alarm.Summary, n.Alarm.Description = &summary, &description
+
+	subject, body, err := render(alarmTemplate, newAlarmEmail(n, time.UTC))
+
+	require.NoError(t, err)
+	assert.Equal(t, "别忘了带电脑", subject)
+	assert.True(t, strings.HasPrefix(body, "需要演示新功能\n\n周会\n"))
+}
@@ -0,0 +1,62 @@
+package reminder
+
+import (
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+)
+
+// AlarmSchedule 提醒的触发状态，每个 VALARM 一行
+// 保存下一次触发时间和上一次触发的计划时刻，服务重启后从这里继续，不会重复或遗漏提醒
+type AlarmSchedule struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	ValarmID       uint       `json:"valarm_id" gorm:"uniqueIndex;not null"`
+	CalendarItemID uint       `json:"calendar_item_id" gorm:"not null;index"`
+	UserID         *uint      `json:"user_id" gorm:"index"`
+	NextTriggerAt  *time.Time `json:"next_trigger_at"`           // 下一次触发的计划时刻，为空表示没有后续触发
+	NextFireAt     *time.Time `json:"next_fire_at" gorm:"index"` // 下一次尝试发送的时间，发送失败重试时晚于 NextTriggerAt
+	OccurrenceAt   *time.Time `json:"occurrence_at"`             // 下一次触发对应实例的开始时间
+	RepeatIndex    int        `json:"repeat_index"`              // 下一次触发是第几次重复，0 表示首次触发
+	LastTriggerAt  *time.Time `json:"last_trigger_at"`           // 上一次已处理的触发的计划时刻
+	LastFiredAt    *time.Time `json:"last_fired_at"`             // 上一次实际发送的时间
+	Attempts       int        `json:"attempts"`                  // 当前触发发送失败的次数
+	LastError      *string    `json:"last_error" gorm:"type:text"`
+	PlannedAt      time.Time  `json:"planned_at" gorm:"not null"` // 计算下一次触发的时间，早于日历项或提醒的修改时间时重新计算
+}
+
+// setNext 设置下一次触发，trigger 为空表示没有后续触发
+func (s *AlarmSchedule) setNext(trigger *calendar.AlarmTrigger) {
+	s.Attempts = 0
+	if trigger == nil {
+		s.NextTriggerAt, s.NextFireAt, s.OccurrenceAt, s.RepeatIndex = nil, nil, nil, 0
+		return
+	}
+	at, occurrence := trigger.At, trigger.Occurrence
+	s.NextTriggerAt, s.NextFireAt, s.OccurrenceAt, s.RepeatIndex = &at, &at, &occurrence, trigger.Repeat
+}
+
+// markProcessed 记录已处理（发送、放弃或跳过）的触发
+func (s *AlarmSchedule) markProcessed(triggerAt time.Time) {
+	s.LastTriggerAt = &triggerAt
+}
+
+func (AlarmSchedule) TableName() string {
+	return "alarm_schedules"
+}
+
+// DigestDelivery 每日摘要的发送记录，每个用户每天一行
+// 多个实例同时运行时，先插入成功的实例负责发送；发送失败时删除记录以便稍后重试
+type DigestDelivery struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+
+	UserID     uint   `json:"user_id" gorm:"not null;uniqueIndex:idx_digest_deliveries_user_date"`
+	DigestDate string `json:"digest_date" gorm:"not null;size:10;uniqueIndex:idx_digest_deliveries_user_date"` // 摘要覆盖的日期（用户时区，YYYY-MM-DD）
+}
+
+func (DigestDelivery) TableName() string {
+	return "digest_deliveries"
+}
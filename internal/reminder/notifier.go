@@ -0,0 +1,53 @@
+package reminder
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+)
+
+// Notification 一次需要发送的提醒
+type Notification struct {
+	UserID  *uint
+	Item    *calendar.CalendarItem // 触发的实例（重复日历项为展开后的实例，Master 指向主日历项）
+	Alarm   *calendar.Valarm
+	Trigger calendar.AlarmTrigger
+	FiredAt time.Time // 实际发送时间，可能晚于 Trigger.At（例如服务重启后补发）
+}
+
+// Notifier 提醒发送器，按 ValarmAction 注册到调度器
+// 返回错误时调度器会稍后重试
+type Notifier interface {
+	Notify(ctx context.Context, n *Notification) error
+}
+
+// NotifierFunc 函数形式的 Notifier
+type NotifierFunc func(ctx context.Context, n *Notification) error
+
+// Notify 实现 Notifier 接口
+func (f NotifierFunc) Notify(ctx context.Context, n *Notification) error {
+	return f(ctx, n)
+}
+
+// LogNotifier 只写日志的发送器，用于没有注册发送器的动作类型
+type LogNotifier struct{}
+
+// Notify 实现 Notifier 接口
+func (LogNotifier) Notify(ctx context.Context, n *Notification) error {
+	summary := ""
+	if n.Item.Summary != nil {
+		summary = *n.Item.Summary
+	}
+	slog.InfoContext(ctx, "提醒",
+		"action", n.Alarm.Action,
+		"valarm_id", n.Alarm.ID,
+		"calendar_item_id", n.Alarm.CalendarItemID,
+		"summary", summary,
+		"occurrence", n.Trigger.Occurrence,
+		"trigger_at", n.Trigger.At,
+		"repeat", n.Trigger.Repeat,
+	)
+	return nil
+}
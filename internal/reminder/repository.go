@@ -0,0 +1,141 @@
+package reminder
+
+import (
+	"time"
+
+	"github.com/galilio/otter/internal/user"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository 提醒触发状态仓库
+// Lock 开头的方法使用 SELECT ... FOR UPDATE SKIP LOCKED，只能在 Transaction 中调用；
+// 多个实例同时运行时，被其它实例锁定的行会被跳过，保证同一次提醒只发送一次
+type Repository interface {
+	Transaction(fn func(repo Repository) error) error
+
+	ListAlarmsToPlan(idleBefore time.Time, limit int) ([]uint, error)
+	LockSchedule(valarmID uint) (*AlarmSchedule, error)
+	LockDueSchedule(now time.Time) (*AlarmSchedule, error)
+	CreateSchedule(schedule *AlarmSchedule) error
+	UpdateSchedule(schedule *AlarmSchedule) error
+	DeleteSchedule(id uint) error
+	DeleteOrphanedSchedules() (int64, error)
+
+	// 每日摘要
+	ListDigestSubscribers(sentBefore time.Time) ([]*user.User, error)
+	ClaimDigest(delivery *DigestDelivery) (bool, error)
+	ReleaseDigest(id uint) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建提醒触发状态仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Transaction 在事务中执行 fn，fn 的参数是绑定到该事务的仓库
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+// ListAlarmsToPlan 列出需要（重新）计算下一次触发时间的提醒ID：
+// 还没有触发状态的提醒、计算之后提醒或日历项被修改过的提醒，
+// 以及 idleBefore 之前计算时没有后续触发的提醒（重复日历项可能在查找范围之后还有实例）
+func (r *repository) ListAlarmsToPlan(idleBefore time.Time, limit int) ([]uint, error) {
+	var ids []uint
+	err := r.db.Table("valarms").
+		Joins("JOIN calendar_items ON calendar_items.id = valarms.calendar_item_id AND calendar_items.deleted_at IS NULL").
+		Joins("LEFT JOIN alarm_schedules ON alarm_schedules.valarm_id = valarms.id").
+		Where("valarms.deleted_at IS NULL").
+		Where("alarm_schedules.id IS NULL OR alarm_schedules.planned_at < valarms.updated_at OR alarm_schedules.planned_at < calendar_items.updated_at OR (alarm_schedules.next_fire_at IS NULL AND alarm_schedules.planned_at < ?)", idleBefore).
+		Order("valarms.id ASC").
+		Limit(limit).
+		Pluck("valarms.id", &ids).Error
+	return ids, err
+}
+
+// LockSchedule 锁定提醒的触发状态，不存在或已被其它事务锁定时返回 nil
+func (r *repository) LockSchedule(valarmID uint) (*AlarmSchedule, error) {
+	var schedules []*AlarmSchedule
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("valarm_id = ?", valarmID).
+		Limit(1).
+		Find(&schedules).Error
+	if err != nil || len(schedules) == 0 {
+		return nil, err
+	}
+	return schedules[0], nil
+}
+
+// LockDueSchedule 锁定一个已到触发时间的触发状态（最早的优先），没有时返回 nil
+func (r *repository) LockDueSchedule(now time.Time) (*AlarmSchedule, error) {
+	var schedules []*AlarmSchedule
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("next_fire_at <= ?", now).
+		Order("next_fire_at ASC").
+		Limit(1).
+		Find(&schedules).Error
+	if err != nil || len(schedules) == 0 {
+		return nil, err
+	}
+	return schedules[0], nil
+}
+
+// CreateSchedule 创建触发状态，已存在时（其它实例同时创建）忽略
+func (r *repository) CreateSchedule(schedule *AlarmSchedule) error {
+	return r.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "valarm_id"}}, DoNothing: true}).
+		Create(schedule).Error
+}
+
+// UpdateSchedule 更新触发状态
+func (r *repository) UpdateSchedule(schedule *AlarmSchedule) error {
+	return r.db.Save(schedule).Error
+}
+
+// DeleteSchedule 删除触发状态
+func (r *repository) DeleteSchedule(id uint) error {
+	return r.db.Delete(&AlarmSchedule{}, id).Error
+}
+
+// DeleteOrphanedSchedules 删除提醒或日历项已被删除的触发状态
+func (r *repository) DeleteOrphanedSchedules() (int64, error) {
+	result := r.db.Where(`NOT EXISTS (
+		SELECT 1 FROM valarms
+		JOIN calendar_items ON calendar_items.id = valarms.calendar_item_id AND calendar_items.deleted_at IS NULL
+		WHERE valarms.id = alarm_schedules.valarm_id AND valarms.deleted_at IS NULL)`).
+		Delete(&AlarmSchedule{})
+	return result.RowsAffected, result.Error
+}
+
+// ListDigestSubscribers 列出开启了每日摘要的有效用户（包含用户配置），
+// 跳过 sentBefore 之后已经发送过摘要的用户
+func (r *repository) ListDigestSubscribers(sentBefore time.Time) ([]*user.User, error) {
+	var users []*user.User
+	err := r.db.Preload("Profile").
+		Joins("JOIN user_profiles ON user_profiles.user_id = users.id AND user_profiles.deleted_at IS NULL").
+		Where("user_profiles.daily_digest = ? AND users.status = ?", true, "active").
+		Where("NOT EXISTS (SELECT 1 FROM digest_deliveries WHERE digest_deliveries.user_id = users.id AND digest_deliveries.created_at > ?)", sentBefore).
+		Order("users.id ASC").
+		Find(&users).Error
+	return users, err
+}
+
+// ClaimDigest 记录即将发送的摘要，同一用户同一天的记录已存在时返回 false
+func (r *repository) ClaimDigest(delivery *DigestDelivery) (bool, error) {
+	result := r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "user_id"}, {Name: "digest_date"}},
+		DoNothing: true,
+	}).Create(delivery)
+	return result.RowsAffected == 1, result.Error
+}
+
+// ReleaseDigest 删除摘要的发送记录，以便稍后重新发送
+func (r *repository) ReleaseDigest(id uint) error {
+	return r.db.Delete(&DigestDelivery{}, id).Error
+}
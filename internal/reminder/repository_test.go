@@ -0,0 +1,119 @@
+package reminder
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 创建测试用的数据库连接（使用sqlmock）
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("创建sqlmock失败: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建GORM连接失败: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// TestRepository_LockDueSchedule 测试锁定到期的触发状态时跳过已被锁定的行
+func TestRepository_LockDueSchedule(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT \* FROM "alarm_schedules" WHERE next_fire_at <= \$1 ORDER BY next_fire_at ASC LIMIT \$2 FOR UPDATE SKIP LOCKED`).
+		WithArgs(now, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "valarm_id", "next_fire_at"}).AddRow(3, 20, now))
+	mock.ExpectCommit()
+
+	var schedule *AlarmSchedule
+	err := repo.Transaction(func(tx Repository) error {
+		var err error
+		schedule, err = tx.LockDueSchedule(now)
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(3), schedule.ID)
+	assert.Equal(t, uint(20), schedule.ValarmID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_LockSchedule_None 测试没有触发状态时返回 nil
+func TestRepository_LockSchedule_None(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	mock.ExpectQuery(`SELECT \* FROM "alarm_schedules" WHERE valarm_id = \$1 LIMIT \$2 FOR UPDATE SKIP LOCKED`).
+		WithArgs(20, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	schedule, err := repo.LockSchedule(20)
+
+	assert.NoError(t, err)
+	assert.Nil(t, schedule)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_CreateSchedule 测试创建触发状态时忽略已存在的行
+func TestRepository_CreateSchedule(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "alarm_schedules" .* ON CONFLICT \("valarm_id"\) DO NOTHING RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := repo.CreateSchedule(&AlarmSchedule{ValarmID: 20, CalendarItemID: 10, PlannedAt: time.Now()})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_DeleteOrphanedSchedules 测试删除提醒或日历项已被删除的触发状态
+func TestRepository_DeleteOrphanedSchedules(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "alarm_schedules" WHERE NOT EXISTS`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectCommit()
+
+	n, err := repo.DeleteOrphanedSchedules()
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_ClaimDigest 测试同一用户同一天的摘要只能被记录一次
+func TestRepository_ClaimDigest(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "digest_deliveries" .* ON CONFLICT \("user_id","digest_date"\) DO NOTHING RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+	mock.ExpectCommit()
+
+	claimed, err := repo.ClaimDigest(&DigestDelivery{UserID: 1, DigestDate: "2025-01-07"})
+
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
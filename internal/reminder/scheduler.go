@@ -0,0 +1,321 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/galilio/otter/internal/common/worker"
+	"gorm.io/gorm"
+)
+
+// Scheduler 提醒调度器
+// 每次检查先为新的或修改过的提醒计算下一次触发时间，再逐个锁定到期的触发状态并发送提醒。
+// 触发状态保存在数据库中，服务重启后继续；多个实例同时运行时通过行锁保证同一次提醒只发送一次
+type Scheduler struct {
+	repo         Repository
+	calendarRepo calendar.Repository
+	notifiers    map[calendar.ValarmAction]Notifier
+	digest       *DigestSender
+
+	interval    time.Duration
+	batchSize   int
+	maxLateness time.Duration
+	retryDelay  time.Duration
+	maxAttempts int
+	replanIdle  time.Duration
+	now         func() time.Time
+}
+
+// Option 调度器选项函数
+type Option func(*Scheduler)
+
+// WithNotifier 设置某种提醒动作的发送器
+func WithNotifier(action calendar.ValarmAction, notifier Notifier) Option {
+	return func(s *Scheduler) {
+		s.notifiers[action] = notifier
+	}
+}
+
+// WithDigestSender 设置每日摘要发送器，每次检查时一并发送到时间的摘要
+func WithDigestSender(digest *DigestSender) Option {
+	return func(s *Scheduler) {
+		s.digest = digest
+	}
+}
+
+// WithInterval 设置检查到期提醒的间隔
+func WithInterval(interval time.Duration) Option {
+	return func(s *Scheduler) {
+		s.interval = interval
+	}
+}
+
+// WithBatchSize 设置每次检查最多处理的提醒数量
+func WithBatchSize(batchSize int) Option {
+	return func(s *Scheduler) {
+		s.batchSize = batchSize
+	}
+}
+
+// WithMaxLateness 设置补发提醒的最大延迟，超过的提醒被跳过
+func WithMaxLateness(maxLateness time.Duration) Option {
+	return func(s *Scheduler) {
+		s.maxLateness = maxLateness
+	}
+}
+
+// WithRetry 设置发送失败后的重试间隔和最多尝试次数
+func WithRetry(delay time.Duration, maxAttempts int) Option {
+	return func(s *Scheduler) {
+		s.retryDelay = delay
+		s.maxAttempts = maxAttempts
+	}
+}
+
+// WithConfig 使用配置文件中的调度器设置
+func WithConfig(cfg *config.ReminderConfig) Option {
+	return func(s *Scheduler) {
+		if cfg.Interval > 0 {
+			s.interval = cfg.Interval
+		}
+		if cfg.BatchSize > 0 {
+			s.batchSize = cfg.BatchSize
+		}
+		if cfg.MaxLateness > 0 {
+			s.maxLateness = cfg.MaxLateness
+		}
+	}
+}
+
+// NewScheduler 创建提醒调度器，没有注册发送器的动作类型只写日志
+func NewScheduler(repo Repository, calendarRepo calendar.Repository, opts ...Option) *Scheduler {
+	s := &Scheduler{
+		repo:         repo,
+		calendarRepo: calendarRepo,
+		notifiers:    map[calendar.ValarmAction]Notifier{},
+		interval:     30 * time.Second,
+		batchSize:    100,
+		maxLateness:  time.Hour,
+		retryDelay:   time.Minute,
+		maxAttempts:  3,
+		replanIdle:   24 * time.Hour,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Run 定期检查并发送提醒，直到 ctx 被取消
+func (s *Scheduler) Run(ctx context.Context) error {
+	return worker.Run(ctx, s.interval, "提醒调度失败", s.Tick)
+}
+
+// Tick 执行一次检查：计算新的或修改过的提醒的触发时间，然后发送到期的提醒和每日摘要
+func (s *Scheduler) Tick(ctx context.Context) error {
+	if err := s.plan(ctx); err != nil {
+		return err
+	}
+	if err := s.fire(ctx); err != nil {
+		return err
+	}
+	if s.digest != nil {
+		return s.digest.Tick(ctx)
+	}
+	return nil
+}
+
+// plan 为需要的提醒（重新）计算下一次触发时间
+func (s *Scheduler) plan(ctx context.Context) error {
+	now := s.now()
+	if n, err := s.repo.DeleteOrphanedSchedules(); err != nil {
+		return fmt.Errorf("清理提醒触发状态失败: %w", err)
+	} else if n > 0 {
+		slog.DebugContext(ctx, "清理已删除提醒的触发状态", "count", n)
+	}
+
+	ids, err := s.repo.ListAlarmsToPlan(now.Add(-s.replanIdle), s.batchSize)
+	if err != nil {
+		return fmt.Errorf("获取待计算的提醒失败: %w", err)
+	}
+	for _, id := range ids {
+		err := s.repo.Transaction(func(repo Repository) error {
+			return s.planAlarm(repo, id, now)
+		})
+		if err != nil {
+			slog.ErrorContext(ctx, "计算提醒触发时间失败", "valarm_id", id, "error", err)
+		}
+	}
+	return nil
+}
+
+// planAlarm 计算单个提醒的下一次触发时间
+func (s *Scheduler) planAlarm(repo Repository, valarmID uint, now time.Time) error {
+	schedule, err := repo.LockSchedule(valarmID)
+	if err != nil {
+		return err
+	}
+	isNew := schedule == nil
+	if isNew {
+		schedule = &AlarmSchedule{ValarmID: valarmID}
+	}
+
+	alarm, item, overridden, err := s.load(valarmID)
+	if err != nil {
+		return err
+	}
+	schedule.CalendarItemID = item.ID
+	schedule.UserID = item.UserID
+	schedule.PlannedAt = now
+
+	s.advance(schedule, item, alarm, overridden, s.resumeAfter(schedule, now))
+
+	if isNew {
+		return repo.CreateSchedule(schedule)
+	}
+	return repo.UpdateSchedule(schedule)
+}
+
+// fire 逐个锁定并发送到期的提醒
+func (s *Scheduler) fire(ctx context.Context) error {
+	err := worker.Drain(s.batchSize, func() (bool, error) {
+		found := false
+		err := s.repo.Transaction(func(repo Repository) error {
+			schedule, err := repo.LockDueSchedule(s.now())
+			if err != nil || schedule == nil {
+				return err
+			}
+			found = true
+			return s.fireSchedule(ctx, repo, schedule)
+		})
+		return found, err
+	})
+	if err != nil {
+		return fmt.Errorf("发送提醒失败: %w", err)
+	}
+	return nil
+}
+
+// fireSchedule 发送一个已锁定的到期提醒并计算下一次触发
+func (s *Scheduler) fireSchedule(ctx context.Context, repo Repository, schedule *AlarmSchedule) error {
+	now := s.now()
+	alarm, item, overridden, err := s.load(schedule.ValarmID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return repo.DeleteSchedule(schedule.ID)
+	}
+	if err != nil {
+		return err
+	}
+	schedule.PlannedAt = now
+
+	// 以当前的日历项为准重新确认触发时间：计算之后日历项可能已被修改（例如新增了例外实例）
+	var trigger *calendar.AlarmTrigger
+	if schedule.NextTriggerAt != nil {
+		trigger, err = calendar.NextAlarmTrigger(item, alarm, schedule.NextTriggerAt.Add(-time.Nanosecond), overridden)
+	}
+	if err != nil || trigger == nil || !trigger.At.Equal(*schedule.NextTriggerAt) {
+		s.advance(schedule, item, alarm, overridden, s.resumeAfter(schedule, now))
+		return repo.UpdateSchedule(schedule)
+	}
+
+	if late := now.Sub(trigger.At); late > s.maxLateness {
+		slog.WarnContext(ctx, "提醒已错过太久，跳过", "valarm_id", alarm.ID, "trigger_at", trigger.At, "late", late)
+		schedule.markProcessed(trigger.At)
+		s.advance(schedule, item, alarm, overridden, maxTime(trigger.At, now.Add(-s.maxLateness)))
+		return repo.UpdateSchedule(schedule)
+	}
+
+	n := &Notification{
+		UserID:  item.UserID,
+		Item:    item.OccurrenceAt(trigger.Occurrence),
+		Alarm:   alarm,
+		Trigger: *trigger,
+		FiredAt: now,
+	}
+	if err := s.notifier(alarm.Action).Notify(ctx, n); err != nil {
+		schedule.Attempts++
+		msg := err.Error()
+		schedule.LastError = &msg
+		if schedule.Attempts < s.maxAttempts {
+			retryAt := now.Add(time.Duration(schedule.Attempts) * s.retryDelay)
+			schedule.NextFireAt = &retryAt
+			slog.WarnContext(ctx, "发送提醒失败，稍后重试", "valarm_id", alarm.ID, "attempts", schedule.Attempts, "error", err)
+			return repo.UpdateSchedule(schedule)
+		}
+		slog.ErrorContext(ctx, "发送提醒失败，放弃本次提醒", "valarm_id", alarm.ID, "attempts", schedule.Attempts, "error", err)
+	} else {
+		schedule.LastFiredAt = &now
+		schedule.LastError = nil
+	}
+
+	// 补发时跳过已经过去的重复提醒，避免一次发送多条
+	schedule.markProcessed(trigger.At)
+	s.advance(schedule, item, alarm, overridden, maxTime(trigger.At, now))
+	return repo.UpdateSchedule(schedule)
+}
+
+// resumeAfter 重新计算触发时间的起点：上一次已处理的触发之后，但最多回溯 maxLateness，
+// 这样刚创建的、稍微过了触发时间的提醒仍会发送
+func (s *Scheduler) resumeAfter(schedule *AlarmSchedule, now time.Time) time.Time {
+	after := now.Add(-s.maxLateness)
+	if schedule.LastTriggerAt != nil && schedule.LastTriggerAt.After(after) {
+		after = *schedule.LastTriggerAt
+	}
+	return after
+}
+
+// advance 计算 after 之后的下一次触发；提醒数据无效时不再触发并记录错误
+func (s *Scheduler) advance(schedule *AlarmSchedule, item *calendar.CalendarItem, alarm *calendar.Valarm, overridden []time.Time, after time.Time) {
+	next, err := calendar.NextAlarmTrigger(item, alarm, after, overridden)
+	if err != nil {
+		msg := err.Error()
+		schedule.LastError = &msg
+		next = nil
+	}
+	schedule.setNext(next)
+}
+
+// load 加载提醒、所属日历项，以及重复日历项已有例外实例的 RECURRENCE-ID
+func (s *Scheduler) load(valarmID uint) (*calendar.Valarm, *calendar.CalendarItem, []time.Time, error) {
+	alarm, err := s.calendarRepo.GetValarmByID(valarmID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	item, err := s.calendarRepo.GetCalendarItemByID(nil, alarm.CalendarItemID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	var overridden []time.Time
+	if item.RecurrenceID == nil && item.IsRecurring() {
+		overrides, err := s.calendarRepo.ListCalendarItemOverrides(item.UserID, []string{item.UID})
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		for _, override := range overrides {
+			overridden = append(overridden, *override.RecurrenceID)
+		}
+	}
+	return alarm, item, overridden, nil
+}
+
+// notifier 返回动作类型对应的发送器
+func (s *Scheduler) notifier(action calendar.ValarmAction) Notifier {
+	if n, ok := s.notifiers[action]; ok {
+		return n
+	}
+	return LogNotifier{}
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
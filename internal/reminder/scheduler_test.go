@@ -0,0 +1,282 @@
+package reminder
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// mockRepository 模拟提醒触发状态仓库，Transaction 直接使用自身
+type mockRepository struct {
+	mock.Mock
+}
+
+func (m *mockRepository) Transaction(fn func(repo Repository) error) error {
+	return fn(m)
+}
+
+func (m *mockRepository) ListAlarmsToPlan(idleBefore time.Time, limit int) ([]uint, error) {
+	args := m.Called(idleBefore, limit)
+	return args.Get(0).([]uint), args.Error(1)
+}
+
+func (m *mockRepository) LockSchedule(valarmID uint) (*AlarmSchedule, error) {
+	args := m.Called(valarmID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AlarmSchedule), args.Error(1)
+}
+
+func (m *mockRepository) LockDueSchedule(now time.Time) (*AlarmSchedule, error) {
+	args := m.Called(now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AlarmSchedule), args.Error(1)
+}
+
+func (m *mockRepository) CreateSchedule(schedule *AlarmSchedule) error {
+	return m.Called(schedule).Error(0)
+}
+
+func (m *mockRepository) UpdateSchedule(schedule *AlarmSchedule) error {
+	return m.Called(schedule).Error(0)
+}
+
+func (m *mockRepository) DeleteSchedule(id uint) error {
+	return m.Called(id).Error(0)
+}
+
+func (m *mockRepository) DeleteOrphanedSchedules() (int64, error) {
+	args := m.Called()
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRepository) ListDigestSubscribers(sentBefore time.Time) ([]*user.User, error) {
+	args := m.Called(sentBefore)
+	return args.Get(0).([]*user.User), args.Error(1)
+}
+
+func (m *mockRepository) ClaimDigest(delivery *DigestDelivery) (bool, error) {
+	args := m.Called(delivery)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *mockRepository) ReleaseDigest(id uint) error {
+	return m.Called(id).Error(0)
+}
+
+// mockCalendarRepository 模拟日历仓库，只实现调度器用到的方法
+type mockCalendarRepository struct {
+	calendar.Repository
+	mock.Mock
+}
+
+func (m *mockCalendarRepository) GetValarmByID(id uint) (*calendar.Valarm, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*calendar.Valarm), args.Error(1)
+}
+
+func (m *mockCalendarRepository) GetCalendarItemByID(userID *uint, id uint) (*calendar.CalendarItem, error) {
+	args := m.Called(userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*calendar.CalendarItem), args.Error(1)
+}
+
+func (m *mockCalendarRepository) ListCalendarItemOverrides(userID *uint, uids []string) ([]*calendar.CalendarItem, error) {
+	args := m.Called(userID, uids)
+	return args.Get(0).([]*calendar.CalendarItem), args.Error(1)
+}
+
+func utcTime(day, hour, min int) time.Time {
+	return time.Date(2025, 1, day, hour, min, 0, 0, time.UTC)
+}
+
+// newTestScheduler 创建使用固定时间的调度器，记录发送的提醒
+func newTestScheduler(repo Repository, calendarRepo calendar.Repository, now time.Time, notifyErr error) (*Scheduler, *[]*Notification) {
+	var sent []*Notification
+	notifier := NotifierFunc(func(ctx context.Context, n *Notification) error {
+		sent = append(sent, n)
+		return notifyErr
+	})
+	s := NewScheduler(repo, calendarRepo, WithNotifier(calendar.ValarmActionDisplay, notifier), WithBatchSize(10))
+	s.now = func() time.Time { return now }
+	return s, &sent
+}
+
+// weeklyItem 每周一 9:00 的重复事件，提醒提前 15 分钟，重复一次
+func weeklyItem() (*calendar.CalendarItem, *calendar.Valarm) {
+	userID := uint(1)
+	rrule := "FREQ=WEEKLY"
+	repeat, interval := 1, "PT5M"
+	item := &calendar.CalendarItem{ID: 10, UID: "weekly", UserID: &userID, DtStart: utcTime(6, 9, 0), RRule: &rrule}
+	alarm := &calendar.Valarm{ID: 20, CalendarItemID: 10, Action: calendar.ValarmActionDisplay, Trigger: "-PT15M", RepeatCount: &repeat, Duration: &interval}
+	return item, alarm
+}
+
+// TestScheduler_PlanNewAlarm 测试为新提醒计算触发时间
+func TestScheduler_PlanNewAlarm(t *testing.T) {
+	repo := new(mockRepository)
+	calendarRepo := new(mockCalendarRepository)
+	now := utcTime(7, 12, 0)
+	s, _ := newTestScheduler(repo, calendarRepo, now, nil)
+	item, alarm := weeklyItem()
+
+	repo.On("DeleteOrphanedSchedules").Return(int64(0), nil)
+	repo.On("ListAlarmsToPlan", now.Add(-24*time.Hour), 10).Return([]uint{20}, nil)
+	repo.On("LockSchedule", uint(20)).Return(nil, nil)
+	calendarRepo.On("GetValarmByID", uint(20)).Return(alarm, nil)
+	calendarRepo.On("GetCalendarItemByID", (*uint)(nil), uint(10)).Return(item, nil)
+	calendarRepo.On("ListCalendarItemOverrides", item.UserID, []string{"weekly"}).Return([]*calendar.CalendarItem{}, nil)
+	repo.On("CreateSchedule", mock.MatchedBy(func(schedule *AlarmSchedule) bool {
+		return schedule.ValarmID == 20 && schedule.CalendarItemID == 10 && *schedule.UserID == 1 &&
+			schedule.NextFireAt.Equal(utcTime(13, 8, 45)) && schedule.OccurrenceAt.Equal(utcTime(13, 9, 0)) &&
+			schedule.PlannedAt.Equal(now)
+	})).Return(nil)
+
+	require.NoError(t, s.plan(context.Background()))
+	repo.AssertExpectations(t)
+	calendarRepo.AssertExpectations(t)
+}
+
+// dueSchedule 到期的触发状态
+func dueSchedule(at, occurrence time.Time, repeat int) *AlarmSchedule {
+	userID := uint(1)
+	return &AlarmSchedule{
+		ID: 1, ValarmID: 20, CalendarItemID: 10, UserID: &userID,
+		NextTriggerAt: &at, NextFireAt: &at, OccurrenceAt: &occurrence, RepeatIndex: repeat,
+	}
+}
+
+// TestScheduler_Fire 测试发送到期的提醒并计算下一次重复
+func TestScheduler_Fire(t *testing.T) {
+	repo := new(mockRepository)
+	calendarRepo := new(mockCalendarRepository)
+	now := utcTime(13, 8, 45).Add(10 * time.Second)
+	s, sent := newTestScheduler(repo, calendarRepo, now, nil)
+	item, alarm := weeklyItem()
+
+	repo.On("LockDueSchedule", now).Return(dueSchedule(utcTime(13, 8, 45), utcTime(13, 9, 0), 0), nil).Once()
+	repo.On("LockDueSchedule", now).Return(nil, nil).Once()
+	calendarRepo.On("GetValarmByID", uint(20)).Return(alarm, nil)
+	calendarRepo.On("GetCalendarItemByID", (*uint)(nil), uint(10)).Return(item, nil)
+	calendarRepo.On("ListCalendarItemOverrides", item.UserID, []string{"weekly"}).Return([]*calendar.CalendarItem{}, nil)
+	repo.On("UpdateSchedule", mock.MatchedBy(func(schedule *AlarmSchedule) bool {
+		return schedule.LastTriggerAt.Equal(utcTime(13, 8, 45)) && schedule.LastFiredAt.Equal(now) &&
+			schedule.NextTriggerAt.Equal(utcTime(13, 8, 50)) && schedule.RepeatIndex == 1 && schedule.Attempts == 0
+	})).Return(nil)
+
+	require.NoError(t, s.fire(context.Background()))
+
+	require.Len(t, *sent, 1)
+	n := (*sent)[0]
+	assert.Equal(t, utcTime(13, 9, 0), n.Item.DtStart)
+	assert.Equal(t, item, n.Item.Master)
+	assert.Equal(t, utcTime(13, 8, 45), n.Trigger.At)
+	assert.Equal(t, now, n.FiredAt)
+	repo.AssertExpectations(t)
+}
+
+// TestScheduler_FireRetry 测试发送失败后推迟重试，计划时刻不变
+func TestScheduler_FireRetry(t *testing.T) {
+	repo := new(mockRepository)
+	calendarRepo := new(mockCalendarRepository)
+	now := utcTime(13, 8, 45)
+	s, sent := newTestScheduler(repo, calendarRepo, now, errors.New("smtp down"))
+	item, alarm := weeklyItem()
+
+	repo.On("LockDueSchedule", now).Return(dueSchedule(utcTime(13, 8, 45), utcTime(13, 9, 0), 0), nil).Once()
+	repo.On("LockDueSchedule", now).Return(nil, nil).Once()
+	calendarRepo.On("GetValarmByID", uint(20)).Return(alarm, nil)
+	calendarRepo.On("GetCalendarItemByID", (*uint)(nil), uint(10)).Return(item, nil)
+	calendarRepo.On("ListCalendarItemOverrides", item.UserID, []string{"weekly"}).Return([]*calendar.CalendarItem{}, nil)
+	repo.On("UpdateSchedule", mock.MatchedBy(func(schedule *AlarmSchedule) bool {
+		return schedule.Attempts == 1 && *schedule.LastError == "smtp down" && schedule.LastTriggerAt == nil &&
+			schedule.NextTriggerAt.Equal(utcTime(13, 8, 45)) && schedule.NextFireAt.Equal(now.Add(time.Minute))
+	})).Return(nil)
+
+	require.NoError(t, s.fire(context.Background()))
+
+	assert.Len(t, *sent, 1)
+	repo.AssertExpectations(t)
+}
+
+// TestScheduler_FireTooLate 测试错过太久的提醒被跳过
+func TestScheduler_FireTooLate(t *testing.T) {
+	repo := new(mockRepository)
+	calendarRepo := new(mockCalendarRepository)
+	now := utcTime(13, 12, 0)
+	s, sent := newTestScheduler(repo, calendarRepo, now, nil)
+	item, alarm := weeklyItem()
+
+	repo.On("LockDueSchedule", now).Return(dueSchedule(utcTime(13, 8, 45), utcTime(13, 9, 0), 0), nil).Once()
+	repo.On("LockDueSchedule", now).Return(nil, nil).Once()
+	calendarRepo.On("GetValarmByID", uint(20)).Return(alarm, nil)
+	calendarRepo.On("GetCalendarItemByID", (*uint)(nil), uint(10)).Return(item, nil)
+	calendarRepo.On("ListCalendarItemOverrides", item.UserID, []string{"weekly"}).Return([]*calendar.CalendarItem{}, nil)
+	repo.On("UpdateSchedule", mock.MatchedBy(func(schedule *AlarmSchedule) bool {
+		return schedule.LastFiredAt == nil && schedule.NextTriggerAt.Equal(utcTime(20, 8, 45))
+	})).Return(nil)
+
+	require.NoError(t, s.fire(context.Background()))
+
+	assert.Empty(t, *sent)
+	repo.AssertExpectations(t)
+}
+
+// TestScheduler_FireOverridden 测试计算之后新增了例外实例时不发送主日历项的提醒
+func TestScheduler_FireOverridden(t *testing.T) {
+	repo := new(mockRepository)
+	calendarRepo := new(mockCalendarRepository)
+	now := utcTime(13, 8, 45)
+	s, sent := newTestScheduler(repo, calendarRepo, now, nil)
+	item, alarm := weeklyItem()
+	recurrenceID := utcTime(13, 9, 0)
+
+	repo.On("LockDueSchedule", now).Return(dueSchedule(utcTime(13, 8, 45), recurrenceID, 0), nil).Once()
+	repo.On("LockDueSchedule", now).Return(nil, nil).Once()
+	calendarRepo.On("GetValarmByID", uint(20)).Return(alarm, nil)
+	calendarRepo.On("GetCalendarItemByID", (*uint)(nil), uint(10)).Return(item, nil)
+	calendarRepo.On("ListCalendarItemOverrides", item.UserID, []string{"weekly"}).
+		Return([]*calendar.CalendarItem{{ID: 11, UID: "weekly", RecurrenceID: &recurrenceID}}, nil)
+	repo.On("UpdateSchedule", mock.MatchedBy(func(schedule *AlarmSchedule) bool {
+		return schedule.NextTriggerAt.Equal(utcTime(20, 8, 45))
+	})).Return(nil)
+
+	require.NoError(t, s.fire(context.Background()))
+
+	assert.Empty(t, *sent)
+	repo.AssertExpectations(t)
+}
+
+// TestScheduler_FireDeletedAlarm 测试提醒已删除时删除触发状态
+func TestScheduler_FireDeletedAlarm(t *testing.T) {
+	repo := new(mockRepository)
+	calendarRepo := new(mockCalendarRepository)
+	now := utcTime(13, 8, 45)
+	s, sent := newTestScheduler(repo, calendarRepo, now, nil)
+
+	repo.On("LockDueSchedule", now).Return(dueSchedule(utcTime(13, 8, 45), utcTime(13, 9, 0), 0), nil).Once()
+	repo.On("LockDueSchedule", now).Return(nil, nil).Once()
+	calendarRepo.On("GetValarmByID", uint(20)).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("DeleteSchedule", uint(1)).Return(nil)
+
+	require.NoError(t, s.fire(context.Background()))
+
+	assert.Empty(t, *sent)
+	repo.AssertExpectations(t)
+}
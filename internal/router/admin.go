@@ -3,6 +3,7 @@ package router
 import (
 	"github.com/galilio/otter/internal/admin"
 	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/galilio/otter/internal/usage"
 	"github.com/gin-gonic/gin"
 )
 
@@ -26,5 +27,9 @@ func setupAdminRoutes(api *gin.RouterGroup, opts *Options) {
 			users.PUT("/:id", adminHandler.UpdateUser)
 			users.DELETE("/:id", adminHandler.DeleteUser)
 		}
+
+		// GET /api/v1/admin/usage - 获取所有用户的 token 用量报告（管理员）
+		usageHandler := usage.NewHandler(opts.UsageService)
+		adminAPI.GET("/usage", usageHandler.Report)
 	}
 }
@@ -0,0 +1,34 @@
+package router
+
+import (
+	"github.com/galilio/otter/internal/agent"
+	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/galilio/otter/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// setupAgentRoutes 设置 Agent 对话相关路由
+// 对话就是 calendar_agent 应用的会话，创建和列出对话由会话处理器完成，获取和删除通过 /api/v1/apps/calendar_agent/sessions
+func setupAgentRoutes(api *gin.RouterGroup, opts *Options) {
+	agentHandler := agent.NewHandler(opts.AgentService)
+	sessionHandler := session.NewHandler(opts.SessionService)
+	sessions := api.Group("/agent/sessions")
+	sessions.Use(middleware.AuthRequired(opts.JWTConfig))
+
+	// POST /api/v1/agent/sessions - 创建对话
+	sessions.POST("", withAppName(agent.AppName, sessionHandler.CreateSession))
+	// GET /api/v1/agent/sessions - 列出对话
+	sessions.GET("", withAppName(agent.AppName, sessionHandler.ListSessions))
+	// POST /api/v1/agent/sessions/:id/messages - 发送消息，以 SSE 推送 Agent 的回复
+	sessions.POST("/:id/messages", agentHandler.SendMessage)
+	// POST /api/v1/agent/sessions/:id/cancel - 中止正在进行的对话
+	sessions.POST("/:id/cancel", agentHandler.CancelRun)
+}
+
+// withAppName 以固定的 app_name 路径参数调用会话处理器
+func withAppName(appName string, handler gin.HandlerFunc) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Params = append(c.Params, gin.Param{Key: "app_name", Value: appName})
+		handler(c)
+	}
+}
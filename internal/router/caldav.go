@@ -0,0 +1,21 @@
+package router
+
+import (
+	"github.com/galilio/otter/internal/caldav"
+	"github.com/gin-gonic/gin"
+)
+
+// setupCalDAVRoutes 设置 CalDAV 路由（RFC 4791），供原生日历客户端同步
+// CalDAV 客户端不支持 JWT，使用 HTTP Basic 认证
+func setupCalDAVRoutes(router *gin.Engine, opts *Options) {
+	caldavHandler := caldav.NewHandler(opts.CalendarService, opts.UserService)
+
+	// GET/PROPFIND /.well-known/caldav - 服务发现，重定向到 /dav/
+	router.GET("/.well-known/caldav", caldavHandler.WellKnown)
+	router.Handle("PROPFIND", "/.well-known/caldav", caldavHandler.WellKnown)
+
+	// /dav/*path - CalDAV 资源（主体、日历集合、日历对象）
+	for _, method := range caldav.Methods {
+		router.Handle(method, caldav.Prefix+"/*path", caldavHandler.BasicAuth(), caldavHandler.ServeDAV)
+	}
+}
@@ -26,4 +26,39 @@ func setupCalendarRoutes(api *gin.RouterGroup, opts *Options) {
 	items.PUT("/:id", calendarHandler.UpdateCalendarItem)
 	// DELETE /api/v1/calendar/items/:id - 删除日历项
 	items.DELETE("/:id", calendarHandler.DeleteCalendarItem)
+	// GET /api/v1/calendar/items/:id/alarms - 列出日历项的提醒
+	items.GET("/:id/alarms", calendarHandler.ListValarms)
+	// POST /api/v1/calendar/items/:id/alarms - 为日历项创建提醒
+	items.POST("/:id/alarms", calendarHandler.CreateValarm)
+	// GET /api/v1/calendar/items/:id/alarms/:alarmId - 获取提醒
+	items.GET("/:id/alarms/:alarmId", calendarHandler.GetValarm)
+	// PUT /api/v1/calendar/items/:id/alarms/:alarmId - 更新提醒
+	items.PUT("/:id/alarms/:alarmId", calendarHandler.UpdateValarm)
+	// DELETE /api/v1/calendar/items/:id/alarms/:alarmId - 删除提醒
+	items.DELETE("/:id/alarms/:alarmId", calendarHandler.DeleteValarm)
+	// GET /api/v1/calendar/items/:id/attendees - 列出日历项的参与者
+	items.GET("/:id/attendees", calendarHandler.ListAttendees)
+	// PUT /api/v1/calendar/items/:id/attendees/:attendeeId/partstat - 修改参与者的参与状态
+	items.PUT("/:id/attendees/:attendeeId/partstat", calendarHandler.UpdateAttendeeStatus)
+
+	calendarGroup := api.Group("/calendar")
+	calendarGroup.Use(middleware.AuthRequired(opts.JWTConfig))
+
+	// POST /api/v1/calendar/import - 导入 iCalendar (.ics) 数据
+	calendarGroup.POST("/import", calendarHandler.ImportCalendar)
+	// GET /api/v1/calendar/export.ics - 导出 iCalendar (.ics) 数据
+	calendarGroup.GET("/export.ics", calendarHandler.ExportCalendar)
+	// POST /api/v1/calendar/itip - 处理参与者回复的 iTIP 消息（REPLY/COUNTER）
+	calendarGroup.POST("/itip", calendarHandler.ProcessITIPMessage)
+	// POST /api/v1/calendar/freebusy - 查询用户的忙闲时段
+	calendarGroup.POST("/freebusy", calendarHandler.GetFreeBusy)
+	// POST /api/v1/calendar/feeds - 创建订阅令牌
+	calendarGroup.POST("/feeds", calendarHandler.CreateFeedToken)
+	// GET /api/v1/calendar/feeds - 列出订阅令牌
+	calendarGroup.GET("/feeds", calendarHandler.ListFeedTokens)
+	// DELETE /api/v1/calendar/feeds/:id - 撤销订阅令牌
+	calendarGroup.DELETE("/feeds/:id", calendarHandler.RevokeFeedToken)
+
+	// GET /api/v1/calendar/feed/:token - 通过订阅令牌获取 iCalendar 数据（不需要 JWT）
+	api.GET("/calendar/feed/:token", calendarHandler.CalendarFeed)
 }
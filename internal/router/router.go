@@ -1,20 +1,26 @@
 package router
 
 import (
+	"github.com/galilio/otter/internal/agent"
 	"github.com/galilio/otter/internal/auth"
 	"github.com/galilio/otter/internal/calendar"
 	"github.com/galilio/otter/internal/common/config"
 	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/galilio/otter/internal/usage"
 	"github.com/galilio/otter/internal/user"
+	"github.com/galilio/otter/internal/webhook"
 	"github.com/gin-gonic/gin"
-	"google.golang.org/adk/session"
+	adksession "google.golang.org/adk/session"
 )
 
 // Options 路由选项
 type Options struct {
-	SessionService   session.Service
+	SessionService   adksession.Service
+	AgentService     agent.Service
 	UserService      user.Service
 	CalendarService  calendar.Service
+	WebhookService   webhook.Service
+	UsageService     usage.Service
 	RefreshTokenRepo auth.RefreshTokenRepository
 	JWTConfig        *config.JWTConfig
 }
@@ -22,6 +28,20 @@ type Options struct {
 // Option 路由选项函数
 type Option func(*Options)
 
+// WithSessionService 设置 Agent 会话服务
+func WithSessionService(sessionService adksession.Service) Option {
+	return func(opts *Options) {
+		opts.SessionService = sessionService
+	}
+}
+
+// WithAgentService 设置 Agent 对话服务
+func WithAgentService(agentService agent.Service) Option {
+	return func(opts *Options) {
+		opts.AgentService = agentService
+	}
+}
+
 // WithUserService 设置用户服务
 func WithUserService(userService user.Service) Option {
 	return func(opts *Options) {
@@ -50,6 +70,20 @@ func WithCalendarService(calendarService calendar.Service) Option {
 	}
 }
 
+// WithWebhookService 设置 Webhook 服务
+func WithWebhookService(webhookService webhook.Service) Option {
+	return func(opts *Options) {
+		opts.WebhookService = webhookService
+	}
+}
+
+// WithUsageService 设置 token 用量服务
+func WithUsageService(usageService usage.Service) Option {
+	return func(opts *Options) {
+		opts.UsageService = usageService
+	}
+}
+
 // NewRouter 使用选项创建路由
 func NewRouter(opts ...Option) *gin.Engine {
 	options := &Options{}
@@ -78,8 +112,12 @@ func NewRouter(opts ...Option) *gin.Engine {
 		setupUserRoutes(api, options)
 		setupAdminRoutes(api, options)
 		setupCalendarRoutes(api, options)
-
+		setupWebhookRoutes(api, options)
+		setupSessionRoutes(api, options)
+		setupAgentRoutes(api, options)
 	}
 
+	setupCalDAVRoutes(router, options)
+
 	return router
 }
@@ -0,0 +1,23 @@
+package router
+
+import (
+	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/galilio/otter/internal/session"
+	"github.com/gin-gonic/gin"
+)
+
+// setupSessionRoutes 设置 Agent 对话会话相关路由，会话属于当前登录用户
+func setupSessionRoutes(api *gin.RouterGroup, opts *Options) {
+	sessionHandler := session.NewHandler(opts.SessionService)
+	sessions := api.Group("/apps/:app_name/sessions")
+	sessions.Use(middleware.AuthRequired(opts.JWTConfig))
+
+	// POST /api/v1/apps/:app_name/sessions - 创建会话
+	sessions.POST("", sessionHandler.CreateSession)
+	// GET /api/v1/apps/:app_name/sessions - 列出会话
+	sessions.GET("", sessionHandler.ListSessions)
+	// GET /api/v1/apps/:app_name/sessions/:session_id - 获取会话及其消息
+	sessions.GET("/:session_id", sessionHandler.GetSession)
+	// DELETE /api/v1/apps/:app_name/sessions/:session_id - 删除会话
+	sessions.DELETE("/:session_id", sessionHandler.DeleteSession)
+}
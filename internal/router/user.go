@@ -2,6 +2,7 @@ package router
 
 import (
 	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/galilio/otter/internal/usage"
 	"github.com/galilio/otter/internal/user"
 	"github.com/gin-gonic/gin"
 )
@@ -23,5 +24,9 @@ func setupUserRoutes(api *gin.RouterGroup, opts *Options) {
 		// PUT /api/v1/users/me/profile - 更新当前用户配置
 		userAPI.GET("/me/profile", userHandler.GetCurrentUserProfile)
 		userAPI.PUT("/me/profile", userHandler.UpdateCurrentUserProfile)
+
+		// GET /api/v1/users/me/usage - 获取当前用户的 token 用量和额度
+		usageHandler := usage.NewHandler(opts.UsageService)
+		userAPI.GET("/me/usage", usageHandler.GetCurrentUserUsage)
 	}
 }
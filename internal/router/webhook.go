@@ -0,0 +1,27 @@
+package router
+
+import (
+	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/galilio/otter/internal/webhook"
+	"github.com/gin-gonic/gin"
+)
+
+// setupWebhookRoutes 设置 Webhook 相关路由
+func setupWebhookRoutes(api *gin.RouterGroup, opts *Options) {
+	webhookHandler := webhook.NewHandler(opts.WebhookService)
+	webhooks := api.Group("/webhooks")
+	webhooks.Use(middleware.AuthRequired(opts.JWTConfig))
+
+	// POST /api/v1/webhooks - 创建 Webhook
+	webhooks.POST("", webhookHandler.CreateWebhook)
+	// GET /api/v1/webhooks - 列出 Webhook
+	webhooks.GET("", webhookHandler.ListWebhooks)
+	// GET /api/v1/webhooks/:id - 获取 Webhook
+	webhooks.GET("/:id", webhookHandler.GetWebhook)
+	// PUT /api/v1/webhooks/:id - 更新 Webhook（包括重新启用）
+	webhooks.PUT("/:id", webhookHandler.UpdateWebhook)
+	// DELETE /api/v1/webhooks/:id - 删除 Webhook
+	webhooks.DELETE("/:id", webhookHandler.DeleteWebhook)
+	// GET /api/v1/webhooks/:id/deliveries - 列出 Webhook 的投递记录
+	webhooks.GET("/:id/deliveries", webhookHandler.ListDeliveries)
+}
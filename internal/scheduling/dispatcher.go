@@ -0,0 +1,137 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/galilio/otter/internal/common/worker"
+	"github.com/galilio/otter/internal/mail"
+)
+
+// Dispatcher 邀请邮件发送器
+// 每次检查逐个锁定到期的 iTIP 消息，以 iMIP 邮件（RFC 6047）发给参与者：正文和 text/calendar 作为
+// multipart/alternative，并附带 invite.ics。失败时按指数退避重试，重试次数用尽后放弃。
+// 消息保存在数据库中，服务重启后继续；多个实例同时运行时通过行锁保证同一封邮件只发送一次
+type Dispatcher struct {
+	repo   Repository
+	mailer mail.Mailer
+
+	interval    time.Duration
+	batchSize   int
+	maxAttempts int
+	retry       worker.Backoff
+	now         func() time.Time
+}
+
+// Option 发送器选项函数
+type Option func(*Dispatcher)
+
+// WithConfig 使用配置文件中的发送设置
+func WithConfig(cfg *config.SchedulingConfig) Option {
+	return func(d *Dispatcher) {
+		if cfg.Interval > 0 {
+			d.interval = cfg.Interval
+		}
+		if cfg.BatchSize > 0 {
+			d.batchSize = cfg.BatchSize
+		}
+		if cfg.MaxAttempts > 0 {
+			d.maxAttempts = cfg.MaxAttempts
+		}
+		if cfg.RetryBaseDelay > 0 {
+			d.retry.Base = cfg.RetryBaseDelay
+		}
+		if cfg.RetryMaxDelay > 0 {
+			d.retry.Max = cfg.RetryMaxDelay
+		}
+	}
+}
+
+// NewDispatcher 创建邀请邮件发送器
+func NewDispatcher(repo Repository, mailer mail.Mailer, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		repo:        repo,
+		mailer:      mailer,
+		interval:    10 * time.Second,
+		batchSize:   50,
+		maxAttempts: 6,
+		retry:       worker.Backoff{Base: time.Minute, Max: time.Hour},
+		now:         time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Run 定期检查并发送邀请邮件，直到 ctx 被取消
+func (d *Dispatcher) Run(ctx context.Context) error {
+	return worker.Run(ctx, d.interval, "发送邀请邮件失败", d.Tick)
+}
+
+// Tick 执行一次检查：逐个锁定并发送到期的消息
+func (d *Dispatcher) Tick(ctx context.Context) error {
+	err := worker.Drain(d.batchSize, func() (bool, error) {
+		found := false
+		err := d.repo.Transaction(func(repo Repository) error {
+			msg, err := repo.LockDueMessage(d.now())
+			if err != nil || msg == nil {
+				return err
+			}
+			found = true
+			return d.send(ctx, repo, msg)
+		})
+		return found, err
+	})
+	if err != nil {
+		return fmt.Errorf("发送邀请邮件失败: %w", err)
+	}
+	return nil
+}
+
+// send 发送一条已锁定的消息并记录结果
+func (d *Dispatcher) send(ctx context.Context, repo Repository, msg *Message) error {
+	payload := []byte(msg.Payload)
+	err := d.mailer.Send(ctx, &mail.Message{
+		ReplyTo:  msg.Organizer,
+		To:       msg.Recipients,
+		Subject:  msg.Subject,
+		Text:     msg.Body,
+		Calendar: &mail.CalendarPart{Method: string(msg.Method), Data: payload},
+		Attachments: []mail.Attachment{{
+			Filename:    "invite.ics",
+			ContentType: "application/ics",
+			Data:        payload,
+		}},
+	})
+
+	now := d.now()
+	msg.Attempts++
+	msg.LastAttemptAt = &now
+	if err == nil {
+		msg.Status = MessageStatusSent
+		msg.SentAt = &now
+		msg.NextAttemptAt = nil
+		msg.LastError = nil
+		return repo.UpdateMessage(msg)
+	}
+
+	errMsg := err.Error()
+	msg.LastError = &errMsg
+	// 没有收件人或没有配置邮件服务器时重试也无法发送
+	if msg.Attempts >= d.maxAttempts || errors.Is(err, mail.ErrNoRecipients) || errors.Is(err, mail.ErrDisabled) {
+		msg.Status = MessageStatusFailed
+		msg.NextAttemptAt = nil
+		slog.WarnContext(ctx, "邀请邮件发送失败，放弃发送", "message_id", msg.ID, "uid", msg.UID, "attempts", msg.Attempts, "error", errMsg)
+		return repo.UpdateMessage(msg)
+	}
+
+	next := now.Add(d.retry.Delay(msg.Attempts))
+	msg.NextAttemptAt = &next
+	slog.DebugContext(ctx, "邀请邮件发送失败，稍后重试", "message_id", msg.ID, "uid", msg.UID, "attempts", msg.Attempts, "next_attempt_at", next, "error", errMsg)
+	return repo.UpdateMessage(msg)
+}
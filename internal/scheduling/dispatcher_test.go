@@ -0,0 +1,95 @@
+package scheduling
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/mail"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordMailer 记录发送的邮件
+func recordMailer(err error) (mail.Mailer, *[]*mail.Message) {
+	var sent []*mail.Message
+	return mail.MailerFunc(func(ctx context.Context, msg *mail.Message) error {
+		sent = append(sent, msg)
+		return err
+	}), &sent
+}
+
+// newTestDispatcher 创建使用固定时间的发送器
+func newTestDispatcher(repo Repository, mailer mail.Mailer, now time.Time) *Dispatcher {
+	d := NewDispatcher(repo, mailer)
+	d.now = func() time.Time { return now }
+	return d
+}
+
+func pendingMessage(now time.Time) *Message {
+	return &Message{
+		ID: 7, UserID: 1, UID: "meeting", Method: calendar.ITIPMethodRequest, Organizer: "alice@example.com",
+		Recipients: calendar.StringArray{"bob@example.com"}, Subject: "邀请：周会", Body: "周会",
+		Payload: "BEGIN:VCALENDAR\r\nMETHOD:REQUEST\r\nEND:VCALENDAR\r\n", Status: MessageStatusPending, NextAttemptAt: &now,
+	}
+}
+
+// TestDispatcher_Tick_Success 测试以 iMIP 邮件发送消息并记录结果
+func TestDispatcher_Tick_Success(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	mailer, sent := recordMailer(nil)
+
+	repo := new(mockRepository)
+	msg := pendingMessage(now)
+	repo.On("LockDueMessage", now).Return(msg, nil).Once()
+	repo.On("LockDueMessage", now).Return(nil, nil).Once()
+	repo.On("UpdateMessage", msg).Return(nil)
+
+	require.NoError(t, newTestDispatcher(repo, mailer, now).Tick(context.Background()))
+
+	require.Len(t, *sent, 1)
+	email := (*sent)[0]
+	assert.Equal(t, []string{"bob@example.com"}, email.To)
+	assert.Equal(t, "alice@example.com", email.ReplyTo)
+	assert.Equal(t, "REQUEST", email.Calendar.Method)
+	assert.Equal(t, msg.Payload, string(email.Calendar.Data))
+	assert.Equal(t, "invite.ics", email.Attachments[0].Filename)
+
+	assert.Equal(t, MessageStatusSent, msg.Status)
+	assert.Equal(t, 1, msg.Attempts)
+	assert.Equal(t, now, *msg.SentAt)
+	assert.Nil(t, msg.NextAttemptAt)
+	repo.AssertExpectations(t)
+}
+
+// TestDispatcher_Tick_Retry 测试发送失败时按指数退避重试，次数用尽后放弃
+func TestDispatcher_Tick_Retry(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	mailer, _ := recordMailer(errors.New("421 service not available"))
+
+	repo := new(mockRepository)
+	msg := pendingMessage(now)
+	msg.Attempts = 2
+	repo.On("LockDueMessage", now).Return(msg, nil).Once()
+	repo.On("LockDueMessage", now).Return(nil, nil).Once()
+	repo.On("UpdateMessage", msg).Return(nil)
+
+	require.NoError(t, newTestDispatcher(repo, mailer, now).Tick(context.Background()))
+
+	assert.Equal(t, MessageStatusPending, msg.Status)
+	assert.Equal(t, 3, msg.Attempts)
+	assert.Equal(t, now.Add(4*time.Minute), *msg.NextAttemptAt)
+	assert.Equal(t, "421 service not available", *msg.LastError)
+
+	msg.Attempts = 5
+	repo.On("LockDueMessage", now).Return(msg, nil).Once()
+	repo.On("LockDueMessage", now).Return(nil, nil).Once()
+
+	require.NoError(t, newTestDispatcher(repo, mailer, now).Tick(context.Background()))
+
+	assert.Equal(t, MessageStatusFailed, msg.Status)
+	assert.Nil(t, msg.NextAttemptAt)
+	repo.AssertExpectations(t)
+}
@@ -0,0 +1,46 @@
+package scheduling
+
+import (
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+)
+
+// MessageStatus 邀请邮件的发送状态
+type MessageStatus string
+
+const (
+	MessageStatusPending MessageStatus = "pending" // 等待发送或等待重试
+	MessageStatusSent    MessageStatus = "sent"    // 已交给 SMTP 服务器
+	MessageStatusFailed  MessageStatus = "failed"  // 重试次数用尽，不再发送
+)
+
+// Message 组织者发给参与者的 iTIP 消息（以 iMIP 邮件发送），同时作为持久化的发送队列
+// 同一日程（UID + RECURRENCE-ID）最近一条消息的 SEQUENCE 和收件人用于判断之后的修改是否需要重新通知
+type Message struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	UserID       uint                 `json:"user_id" gorm:"not null;index:idx_scheduling_messages_item"`
+	UID          string               `json:"uid" gorm:"not null;size:255;index:idx_scheduling_messages_item"`
+	RecurrenceID *time.Time           `json:"recurrence_id,omitempty"`
+	Method       calendar.ITIPMethod  `json:"method" gorm:"not null;type:varchar(20)"`
+	Sequence     int                  `json:"sequence" gorm:"not null;default:0"`
+	Organizer    string               `json:"organizer" gorm:"not null;size:500"` // 组织者邮箱，作为邮件的 Reply-To
+	Recipients   calendar.StringArray `json:"recipients" gorm:"type:jsonb"`       // 收件的参与者邮箱
+	Subject      string               `json:"subject" gorm:"not null;size:500"`
+	Body         string               `json:"body" gorm:"type:text;not null"`    // 邮件正文
+	Payload      string               `json:"payload" gorm:"type:text;not null"` // iTIP 消息（text/calendar）
+
+	Status        MessageStatus `json:"status" gorm:"not null;type:varchar(20);index"`
+	Attempts      int           `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt *time.Time    `json:"next_attempt_at" gorm:"index"` // 下一次尝试发送的时间，不再发送时为空
+	LastAttemptAt *time.Time    `json:"last_attempt_at"`
+	SentAt        *time.Time    `json:"sent_at"`
+	LastError     *string       `json:"last_error" gorm:"type:text"`
+}
+
+func (Message) TableName() string {
+	return "scheduling_messages"
+}
@@ -0,0 +1,76 @@
+package scheduling
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository iTIP 消息仓库
+// LockDueMessage 使用 SELECT ... FOR UPDATE SKIP LOCKED，只能在 Transaction 中调用；
+// 多个实例同时运行时，被其它实例锁定的消息会被跳过，保证同一封邮件只发送一次
+type Repository interface {
+	Transaction(fn func(repo Repository) error) error
+
+	CreateMessage(msg *Message) error
+	GetLatestMessage(userID uint, uid string, recurrenceID *time.Time) (*Message, error)
+	LockDueMessage(now time.Time) (*Message, error)
+	UpdateMessage(msg *Message) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建 iTIP 消息仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Transaction 在事务中执行 fn，fn 的参数是绑定到该事务的仓库
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+// CreateMessage 创建待发送的消息
+func (r *repository) CreateMessage(msg *Message) error {
+	return r.db.Create(msg).Error
+}
+
+// GetLatestMessage 获取用户某个日程（主日程或例外实例）最近一条消息，没有时返回 nil
+func (r *repository) GetLatestMessage(userID uint, uid string, recurrenceID *time.Time) (*Message, error) {
+	query := r.db.Where("user_id = ? AND uid = ?", userID, uid)
+	if recurrenceID != nil {
+		query = query.Where("recurrence_id = ?", *recurrenceID)
+	} else {
+		query = query.Where("recurrence_id IS NULL")
+	}
+
+	var messages []*Message
+	if err := query.Order("id DESC").Limit(1).Find(&messages).Error; err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages[0], nil
+}
+
+// LockDueMessage 锁定一条已到发送时间的消息（最早的优先），没有时返回 nil
+func (r *repository) LockDueMessage(now time.Time) (*Message, error) {
+	var messages []*Message
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", MessageStatusPending, now).
+		Order("next_attempt_at ASC").
+		Limit(1).
+		Find(&messages).Error
+	if err != nil || len(messages) == 0 {
+		return nil, err
+	}
+	return messages[0], nil
+}
+
+// UpdateMessage 更新消息
+func (r *repository) UpdateMessage(msg *Message) error {
+	return r.db.Save(msg).Error
+}
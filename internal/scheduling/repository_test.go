@@ -0,0 +1,68 @@
+package scheduling
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 创建测试用的数据库连接（使用sqlmock）
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("创建sqlmock失败: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建GORM连接失败: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// TestRepository_LockDueMessage 测试锁定到期的消息时跳过已被锁定的行
+func TestRepository_LockDueMessage(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT \* FROM "scheduling_messages" WHERE status = \$1 AND next_attempt_at <= \$2 ORDER BY next_attempt_at ASC LIMIT \$3 FOR UPDATE SKIP LOCKED`).
+		WithArgs(MessageStatusPending, now, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "uid", "status"}).AddRow(7, "meeting", "pending"))
+
+	msg, err := repo.LockDueMessage(now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), msg.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_GetLatestMessage 测试按 UID 和 RECURRENCE-ID 查找最近一条消息，没有时返回 nil
+func TestRepository_GetLatestMessage(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+	recurrenceID := time.Date(2025, 1, 9, 2, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT \* FROM "scheduling_messages" WHERE \(user_id = \$1 AND uid = \$2\) AND recurrence_id IS NULL ORDER BY id DESC LIMIT \$3`).
+		WithArgs(1, "meeting", 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "method", "sequence"}).AddRow(9, "REQUEST", 2))
+	mock.ExpectQuery(`SELECT \* FROM "scheduling_messages" WHERE \(user_id = \$1 AND uid = \$2\) AND recurrence_id = \$3 ORDER BY id DESC LIMIT \$4`).
+		WithArgs(1, "meeting", recurrenceID, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}))
+
+	msg, err := repo.GetLatestMessage(1, "meeting", nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, msg.Sequence)
+
+	msg, err = repo.GetLatestMessage(1, "meeting", &recurrenceID)
+	assert.NoError(t, err)
+	assert.Nil(t, msg)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
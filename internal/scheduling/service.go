@@ -0,0 +1,303 @@
+package scheduling
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"log/slog"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/user"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+// 每个模板文件定义 subject 和 body 两个模板
+var (
+	requestTemplate = template.Must(template.ParseFS(templateFS, "templates/request.tmpl"))
+	cancelTemplate  = template.Must(template.ParseFS(templateFS, "templates/cancel.tmpl"))
+)
+
+// emailTimeLayout 邮件中显示时间的格式
+const emailTimeLayout = "2006-01-02 15:04 MST"
+
+// UserLookup 查询日程所属用户（组织者）的邮箱、名字和时区，user.Service 实现了该接口
+type UserLookup interface {
+	GetUserByID(id uint) (*user.User, error)
+	GetUserProfile(userID uint) (*user.UserProfile, error)
+}
+
+// Service 日程邀请服务，作为日历项事件的监听器为用户组织的事件生成 iTIP 消息（RFC 5546）：
+// 创建或修改（SEQUENCE 增加）时向参与者发送 REQUEST，删除或取消时发送 CANCEL，
+// 被移出参与者列表的人也会收到 CANCEL。消息写入发送队列，由 Dispatcher 在后台以 iMIP 邮件发送
+type Service interface {
+	calendar.EventListener
+}
+
+type service struct {
+	repo  Repository
+	users UserLookup
+	now   func() time.Time
+}
+
+// NewService 创建日程邀请服务
+func NewService(repo Repository, users UserLookup) Service {
+	return &service{repo: repo, users: users, now: time.Now}
+}
+
+// invitation 邀请邮件模板的数据
+type invitation struct {
+	Organizer   string
+	Summary     string
+	Start       string
+	End         string
+	Location    string
+	Description string
+	Recurring   bool
+	Updated     bool // 之前已经发送过邀请
+	Removed     bool // CANCEL 只发给被移出参与者列表的人
+}
+
+// OnCalendarEvent 为用户组织的事件生成发给参与者的消息
+// 只处理 ORGANIZER 为用户自己（或没有 ORGANIZER）的 VEVENT；SEQUENCE 没有增加的修改
+// （例如记录参与者的回复）不重新通知。写入失败只记录日志，不影响日历项的修改
+func (s *service) OnCalendarEvent(event *calendar.Event) {
+	item := event.Item
+	if event.UserID == nil || item == nil || item.Type != calendar.CalendarItemTypeEvent {
+		return
+	}
+	userID := *event.UserID
+
+	owner, err := s.users.GetUserByID(userID)
+	if err != nil {
+		slog.Error("获取日程组织者失败", "user_id", userID, "uid", item.UID, "error", err)
+		return
+	}
+	if owner.Email == "" {
+		return
+	}
+	if item.Organizer != nil && *item.Organizer != "" && !sameAddress(*item.Organizer, owner.Email) {
+		// 别人组织的日程（例如导入的邀请）由组织者负责通知
+		return
+	}
+
+	latest, err := s.repo.GetLatestMessage(userID, item.UID, item.RecurrenceID)
+	if err != nil {
+		slog.Error("获取上一次发送的邀请失败", "user_id", userID, "uid", item.UID, "error", err)
+		return
+	}
+	var notified []string
+	if latest != nil && latest.Method == calendar.ITIPMethodRequest {
+		notified = latest.Recipients
+	}
+	current := recipients(item, owner.Email)
+	sequence := 0
+	if item.Sequence != nil {
+		sequence = *item.Sequence
+	}
+
+	switch {
+	case event.Type == calendar.EventCalendarItemDeleted:
+		if item.RecurrenceID != nil {
+			// 删除例外实例后该实例恢复为主日程的内容，不是取消
+			return
+		}
+		if latest != nil && latest.Sequence >= sequence {
+			sequence = latest.Sequence
+		}
+		s.enqueue(owner, item, calendar.ITIPMethodCancel, union(current, notified), sequence+1, latest != nil, false)
+	case latest != nil && sequence <= latest.Sequence:
+		return
+	case item.Status != nil && strings.EqualFold(*item.Status, "CANCELLED"):
+		s.enqueue(owner, item, calendar.ITIPMethodCancel, union(current, notified), sequence, true, false)
+	default:
+		if removed := difference(notified, current); len(removed) > 0 {
+			s.enqueue(owner, item, calendar.ITIPMethodCancel, removed, sequence, true, true)
+		}
+		s.enqueue(owner, item, calendar.ITIPMethodRequest, current, sequence, latest != nil, false)
+	}
+}
+
+// enqueue 生成一条 iTIP 消息并写入发送队列，没有收件人时不生成
+func (s *service) enqueue(owner *user.User, item *calendar.CalendarItem, method calendar.ITIPMethod, to []string, sequence int, updated, removed bool) {
+	if len(to) == 0 {
+		return
+	}
+
+	var payload bytes.Buffer
+	if err := calendar.WriteITIP(&payload, method, itipItem(item, owner.Email, method, to, sequence)); err != nil {
+		slog.Error("生成 iTIP 消息失败", "user_id", owner.ID, "uid", item.UID, "method", method, "error", err)
+		return
+	}
+
+	tmpl := requestTemplate
+	if method == calendar.ITIPMethodCancel {
+		tmpl = cancelTemplate
+	}
+	subject, body, err := render(tmpl, s.newInvitation(owner, item, updated, removed))
+	if err != nil {
+		slog.Error("生成邀请邮件失败", "user_id", owner.ID, "uid", item.UID, "method", method, "error", err)
+		return
+	}
+
+	now := s.now()
+	msg := &Message{
+		UserID:        owner.ID,
+		UID:           item.UID,
+		RecurrenceID:  item.RecurrenceID,
+		Method:        method,
+		Sequence:      sequence,
+		Organizer:     owner.Email,
+		Recipients:    calendar.StringArray(to),
+		Subject:       subject,
+		Body:          body,
+		Payload:       payload.String(),
+		Status:        MessageStatusPending,
+		NextAttemptAt: &now,
+	}
+	if err := s.repo.CreateMessage(msg); err != nil {
+		slog.Error("创建邀请邮件失败", "user_id", owner.ID, "uid", item.UID, "method", method, "error", err)
+	}
+}
+
+// itipItem 准备写入 iTIP 消息的日历项：ORGANIZER 为组织者，SEQUENCE 为本次的版本。
+// REQUEST 列出全部参与者，尚未回复的参与者标记 RSVP=TRUE；CANCEL 只列出收件人
+func itipItem(item *calendar.CalendarItem, organizer string, method calendar.ITIPMethod, to []string, sequence int) *calendar.CalendarItem {
+	msg := *item
+	address := "mailto:" + organizer
+	msg.Organizer = &address
+	msg.Sequence = &sequence
+
+	if method == calendar.ITIPMethodRequest {
+		msg.Attendees = make([]calendar.Attendee, len(item.Attendees))
+		for i, a := range item.Attendees {
+			if a.PartStat == calendar.PartStatNeedsAction && !sameAddress(a.Address, organizer) {
+				a.RSVP = true
+			}
+			msg.Attendees[i] = a
+		}
+		return &msg
+	}
+
+	msg.Attendees = make([]calendar.Attendee, 0, len(to))
+	for _, email := range to {
+		attendee := calendar.Attendee{Address: "mailto:" + email, Role: calendar.AttendeeRoleRequired, PartStat: calendar.PartStatNeedsAction}
+		for _, a := range item.Attendees {
+			if sameAddress(a.Address, email) {
+				attendee = a
+				break
+			}
+		}
+		msg.Attendees = append(msg.Attendees, attendee)
+	}
+	return &msg
+}
+
+// newInvitation 准备邀请邮件模板的数据，时间按组织者的时区显示
+func (s *service) newInvitation(owner *user.User, item *calendar.CalendarItem, updated, removed bool) *invitation {
+	loc := time.UTC
+	if profile, err := s.users.GetUserProfile(owner.ID); err == nil {
+		loc = profile.Location()
+	}
+
+	data := &invitation{
+		Organizer:   owner.Username,
+		Summary:     calendar.StringValue(item.Summary),
+		Location:    calendar.StringValue(item.Location),
+		Description: calendar.StringValue(item.Description),
+		Start:       item.DtStart.In(loc).Format(emailTimeLayout),
+		Recurring:   item.IsRecurring(),
+		Updated:     updated,
+		Removed:     removed,
+	}
+	if owner.FirstName != "" || owner.LastName != "" {
+		data.Organizer = owner.LastName + owner.FirstName
+	}
+	if data.Summary == "" {
+		data.Summary = "（无标题）"
+	}
+	if end := item.OccurrenceDuration(); end > 0 {
+		data.End = item.DtStart.Add(end).In(loc).Format(emailTimeLayout)
+	}
+	return data
+}
+
+// recipients 日历项中需要通知的参与者邮箱：mailto: 地址，不包括组织者自己
+func recipients(item *calendar.CalendarItem, organizer string) []string {
+	var to []string
+	for _, a := range item.Attendees {
+		email, ok := mailtoAddress(a.Address)
+		if !ok || sameAddress(email, organizer) || contains(to, email) {
+			continue
+		}
+		to = append(to, email)
+	}
+	return to
+}
+
+// mailtoAddress 取出 mailto: 地址中的邮箱
+func mailtoAddress(address string) (string, bool) {
+	scheme, email, ok := strings.Cut(strings.TrimSpace(address), ":")
+	if !ok || !strings.EqualFold(scheme, "mailto") || email == "" {
+		return "", false
+	}
+	return email, true
+}
+
+// sameAddress 比较两个邮箱（可以带 mailto: 前缀），不区分大小写
+func sameAddress(a, b string) bool {
+	if email, ok := mailtoAddress(a); ok {
+		a = email
+	}
+	if email, ok := mailtoAddress(b); ok {
+		b = email
+	}
+	return strings.EqualFold(a, b)
+}
+
+func contains(list []string, email string) bool {
+	for _, e := range list {
+		if sameAddress(e, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// union 合并两个邮箱列表（去重，保持顺序）
+func union(a, b []string) []string {
+	result := append([]string{}, a...)
+	for _, email := range b {
+		if !contains(result, email) {
+			result = append(result, email)
+		}
+	}
+	return result
+}
+
+// difference 在 a 中但不在 b 中的邮箱
+func difference(a, b []string) []string {
+	var result []string
+	for _, email := range a {
+		if !contains(b, email) {
+			result = append(result, email)
+		}
+	}
+	return result
+}
+
+// render 渲染邮件的 subject 和 body
+func render(tmpl *template.Template, data any) (string, string, error) {
+	var subject, body bytes.Buffer
+	if err := tmpl.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return "", "", fmt.Errorf("渲染邮件标题失败: %w", err)
+	}
+	if err := tmpl.ExecuteTemplate(&body, "body", data); err != nil {
+		return "", "", fmt.Errorf("渲染邮件正文失败: %w", err)
+	}
+	return strings.TrimSpace(subject.String()), body.String(), nil
+}
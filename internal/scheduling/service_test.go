@@ -0,0 +1,194 @@
+package scheduling
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/galilio/otter/internal/user"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// mockRepository 模拟 iTIP 消息仓库，Transaction 直接使用自身
+type mockRepository struct {
+	mock.Mock
+}
+
+func (m *mockRepository) Transaction(fn func(repo Repository) error) error {
+	return fn(m)
+}
+
+func (m *mockRepository) CreateMessage(msg *Message) error {
+	return m.Called(msg).Error(0)
+}
+
+func (m *mockRepository) GetLatestMessage(userID uint, uid string, recurrenceID *time.Time) (*Message, error) {
+	args := m.Called(userID, uid, recurrenceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Message), args.Error(1)
+}
+
+func (m *mockRepository) LockDueMessage(now time.Time) (*Message, error) {
+	args := m.Called(now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Message), args.Error(1)
+}
+
+func (m *mockRepository) UpdateMessage(msg *Message) error {
+	return m.Called(msg).Error(0)
+}
+
+// mockUserLookup 模拟用户查询
+type mockUserLookup struct {
+	mock.Mock
+}
+
+func (m *mockUserLookup) GetUserByID(id uint) (*user.User, error) {
+	args := m.Called(id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.User), args.Error(1)
+}
+
+func (m *mockUserLookup) GetUserProfile(userID uint) (*user.UserProfile, error) {
+	args := m.Called(userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*user.UserProfile), args.Error(1)
+}
+
+// newTestService 创建组织者为 alice@example.com 的测试服务
+func newTestService() (*service, *mockRepository) {
+	repo := new(mockRepository)
+	users := new(mockUserLookup)
+	users.On("GetUserByID", uint(1)).Return(&user.User{ID: 1, Username: "alice", Email: "alice@example.com"}, nil)
+	users.On("GetUserProfile", uint(1)).Return(&user.UserProfile{Timezone: "Asia/Shanghai"}, nil)
+	return NewService(repo, users).(*service), repo
+}
+
+func meeting(sequence int, attendees ...string) *calendar.CalendarItem {
+	summary := "周会"
+	start := time.Date(2025, 1, 9, 2, 0, 0, 0, time.UTC)
+	end := start.Add(time.Hour)
+	item := &calendar.CalendarItem{
+		ID: 1, UID: "meeting", Type: calendar.CalendarItemTypeEvent,
+		Summary: &summary, DtStart: start, DtEnd: &end, Sequence: &sequence,
+	}
+	for _, a := range attendees {
+		item.Attendees = append(item.Attendees, calendar.Attendee{Address: "mailto:" + a, PartStat: calendar.PartStatNeedsAction})
+	}
+	return item
+}
+
+func event(eventType calendar.EventType, item *calendar.CalendarItem) *calendar.Event {
+	userID := uint(1)
+	return &calendar.Event{Type: eventType, UserID: &userID, Item: item, OccurredAt: time.Now()}
+}
+
+// TestService_OnCalendarEvent_Request 测试创建事件时向参与者（不包括组织者）发送 REQUEST
+func TestService_OnCalendarEvent_Request(t *testing.T) {
+	s, repo := newTestService()
+	item := meeting(0, "bob@example.com", "ALICE@example.com", "carol@example.com")
+
+	var created *Message
+	repo.On("GetLatestMessage", uint(1), "meeting", (*time.Time)(nil)).Return(nil, nil)
+	repo.On("CreateMessage", mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(0).(*Message)
+	}).Return(nil).Once()
+
+	s.OnCalendarEvent(event(calendar.EventCalendarItemCreated, item))
+
+	require.NotNil(t, created)
+	assert.Equal(t, calendar.ITIPMethodRequest, created.Method)
+	assert.Equal(t, calendar.StringArray{"bob@example.com", "carol@example.com"}, created.Recipients)
+	assert.Equal(t, "alice@example.com", created.Organizer)
+	assert.Equal(t, MessageStatusPending, created.Status)
+	assert.Equal(t, "邀请：周会", created.Subject)
+	assert.Contains(t, created.Body, "时间：2025-01-09 10:00 CST - 2025-01-09 11:00 CST")
+
+	payload := strings.ReplaceAll(created.Payload, "\r\n ", "")
+	assert.Contains(t, payload, "METHOD:REQUEST\r\n")
+	assert.Contains(t, payload, "ORGANIZER:mailto:alice@example.com\r\n")
+	assert.Contains(t, payload, "ATTENDEE;PARTSTAT=NEEDS-ACTION;RSVP=TRUE:mailto:bob@example.com\r\n")
+	assert.Contains(t, payload, "SEQUENCE:0\r\n")
+	repo.AssertExpectations(t)
+}
+
+// TestService_OnCalendarEvent_Update 测试修改后的重新通知：SEQUENCE 没有增加时不发送，被移除的参与者收到 CANCEL
+func TestService_OnCalendarEvent_Update(t *testing.T) {
+	s, repo := newTestService()
+	sent := &Message{Method: calendar.ITIPMethodRequest, Sequence: 1, Recipients: calendar.StringArray{"bob@example.com", "carol@example.com"}}
+	repo.On("GetLatestMessage", uint(1), "meeting", (*time.Time)(nil)).Return(sent, nil)
+
+	// 只记录了参与者的回复
+	s.OnCalendarEvent(event(calendar.EventCalendarItemUpdated, meeting(1, "bob@example.com", "carol@example.com")))
+	repo.AssertNotCalled(t, "CreateMessage", mock.Anything)
+
+	var created []*Message
+	repo.On("CreateMessage", mock.Anything).Run(func(args mock.Arguments) {
+		created = append(created, args.Get(0).(*Message))
+	}).Return(nil)
+
+	s.OnCalendarEvent(event(calendar.EventCalendarItemUpdated, meeting(2, "bob@example.com", "dave@example.com")))
+
+	require.Len(t, created, 2)
+	assert.Equal(t, calendar.ITIPMethodCancel, created[0].Method)
+	assert.Equal(t, calendar.StringArray{"carol@example.com"}, created[0].Recipients)
+	assert.Contains(t, created[0].Payload, "ATTENDEE;PARTSTAT=NEEDS-ACTION:mailto:carol@example.com")
+	assert.Contains(t, created[0].Body, "已将你从以下日程的参与者中移除")
+	assert.Equal(t, calendar.ITIPMethodRequest, created[1].Method)
+	assert.Equal(t, calendar.StringArray{"bob@example.com", "dave@example.com"}, created[1].Recipients)
+	assert.Equal(t, 2, created[1].Sequence)
+	assert.Equal(t, "邀请已更新：周会", created[1].Subject)
+}
+
+// TestService_OnCalendarEvent_Delete 测试删除事件时向所有通知过的参与者发送 CANCEL，SEQUENCE 加一
+func TestService_OnCalendarEvent_Delete(t *testing.T) {
+	s, repo := newTestService()
+	sent := &Message{Method: calendar.ITIPMethodRequest, Sequence: 3, Recipients: calendar.StringArray{"carol@example.com"}}
+	repo.On("GetLatestMessage", uint(1), "meeting", (*time.Time)(nil)).Return(sent, nil)
+
+	var created *Message
+	repo.On("CreateMessage", mock.Anything).Run(func(args mock.Arguments) {
+		created = args.Get(0).(*Message)
+	}).Return(nil).Once()
+
+	s.OnCalendarEvent(event(calendar.EventCalendarItemDeleted, meeting(3, "bob@example.com")))
+
+	require.NotNil(t, created)
+	assert.Equal(t, calendar.ITIPMethodCancel, created.Method)
+	assert.Equal(t, 4, created.Sequence)
+	assert.Equal(t, calendar.StringArray{"bob@example.com", "carol@example.com"}, created.Recipients)
+	assert.Equal(t, "已取消：周会", created.Subject)
+	assert.Contains(t, created.Payload, "STATUS:CANCELLED\r\n")
+	assert.Contains(t, created.Payload, "SEQUENCE:4\r\n")
+	repo.AssertExpectations(t)
+}
+
+// TestService_OnCalendarEvent_Ignored 测试不需要通知的事件：别人组织的日程、待办和没有参与者的事件
+func TestService_OnCalendarEvent_Ignored(t *testing.T) {
+	s, repo := newTestService()
+	repo.On("GetLatestMessage", uint(1), "meeting", (*time.Time)(nil)).Return(nil, nil)
+
+	invited := meeting(0, "alice@example.com", "bob@example.com")
+	organizer := "mailto:bob@example.com"
+	invited.Organizer = &organizer
+	s.OnCalendarEvent(event(calendar.EventCalendarItemCreated, invited))
+
+	todo := meeting(0, "bob@example.com")
+	todo.Type = calendar.CalendarItemTypeTodo
+	s.OnCalendarEvent(event(calendar.EventCalendarItemCreated, todo))
+
+	s.OnCalendarEvent(event(calendar.EventCalendarItemCreated, meeting(0)))
+
+	repo.AssertNotCalled(t, "CreateMessage", mock.Anything)
+}
@@ -0,0 +1,221 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/gin-gonic/gin"
+	adksession "google.golang.org/adk/session"
+	"google.golang.org/genai"
+)
+
+type Handler struct {
+	service adksession.Service
+}
+
+func NewHandler(service adksession.Service) *Handler {
+	return &Handler{service: service}
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// CreateSessionRequest 创建会话请求，请求体可以为空；State 不能包含 app: 和 temp: 前缀的键
+type CreateSessionRequest struct {
+	SessionID string         `json:"session_id"`
+	State     map[string]any `json:"state"`
+}
+
+// SessionResponse 会话响应
+type SessionResponse struct {
+	ID             string           `json:"id"`
+	AppName        string           `json:"app_name"`
+	UserID         string           `json:"user_id"`
+	State          map[string]any   `json:"state"`
+	Events         []*EventResponse `json:"events,omitempty"`
+	LastUpdateTime time.Time        `json:"last_update_time"`
+}
+
+// EventResponse 会话事件响应
+type EventResponse struct {
+	ID           string         `json:"id"`
+	InvocationID string         `json:"invocation_id"`
+	Author       string         `json:"author"`
+	Branch       string         `json:"branch,omitempty"`
+	Timestamp    time.Time      `json:"timestamp"`
+	Content      *genai.Content `json:"content,omitempty"`
+	Partial      bool           `json:"partial,omitempty"` // 流式输出的中间事件，不保存在会话中
+	StateDelta   map[string]any `json:"state_delta,omitempty"`
+	ErrorCode    string         `json:"error_code,omitempty"`
+	ErrorMessage string         `json:"error_message,omitempty"`
+}
+
+// CreateSession 为当前用户创建会话
+// POST /api/v1/apps/:app_name/sessions
+// POST /api/v1/agent/sessions（app_name 为 calendar_agent）
+func (h *Handler) CreateSession(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	var req CreateSessionRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+	if err := ValidateClientState(req.State); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	resp, err := h.service.Create(c.Request.Context(), &adksession.CreateRequest{
+		AppName:   c.Param("app_name"),
+		UserID:    UserKey(*userID),
+		SessionID: req.SessionID,
+		State:     req.State,
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, NewSessionResponse(resp.Session))
+}
+
+// ListSessions 列出当前用户的会话（不包含事件）
+// GET /api/v1/apps/:app_name/sessions
+// GET /api/v1/agent/sessions（app_name 为 calendar_agent）
+func (h *Handler) ListSessions(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	resp, err := h.service.List(c.Request.Context(), &adksession.ListRequest{
+		AppName: c.Param("app_name"),
+		UserID:  UserKey(*userID),
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	sessions := make([]*SessionResponse, 0, len(resp.Sessions))
+	for _, s := range resp.Sessions {
+		sessions = append(sessions, NewSessionResponse(s))
+	}
+	c.JSON(http.StatusOK, sessions)
+}
+
+// GetSession 获取当前用户的会话及其事件
+// GET /api/v1/apps/:app_name/sessions/:session_id
+func (h *Handler) GetSession(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	resp, err := h.service.Get(c.Request.Context(), &adksession.GetRequest{
+		AppName:   c.Param("app_name"),
+		UserID:    UserKey(*userID),
+		SessionID: c.Param("session_id"),
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, NewSessionResponse(resp.Session))
+}
+
+// DeleteSession 删除当前用户的会话
+// DELETE /api/v1/apps/:app_name/sessions/:session_id
+func (h *Handler) DeleteSession(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	err = h.service.Delete(c.Request.Context(), &adksession.DeleteRequest{
+		AppName:   c.Param("app_name"),
+		UserID:    UserKey(*userID),
+		SessionID: c.Param("session_id"),
+	})
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "会话已删除"})
+}
+
+// NewSessionResponse 转换 ADK 会话为响应
+func NewSessionResponse(s adksession.Session) *SessionResponse {
+	resp := &SessionResponse{
+		ID:             s.ID(),
+		AppName:        s.AppName(),
+		UserID:         s.UserID(),
+		State:          maps.Collect(s.State().All()),
+		LastUpdateTime: s.LastUpdateTime(),
+	}
+	for event := range s.Events().All() {
+		resp.Events = append(resp.Events, NewEventResponse(event))
+	}
+	return resp
+}
+
+// NewEventResponse 转换 ADK 事件为响应
+func NewEventResponse(event *adksession.Event) *EventResponse {
+	return &EventResponse{
+		ID:           event.ID,
+		InvocationID: event.InvocationID,
+		Author:       event.Author,
+		Branch:       event.Branch,
+		Timestamp:    event.Timestamp,
+		Content:      event.Content,
+		Partial:      event.Partial,
+		StateDelta:   event.Actions.StateDelta,
+		ErrorCode:    event.ErrorCode,
+		ErrorMessage: event.ErrorMessage,
+	}
+}
+
+// ValidateClientState 检查客户端提交的状态（初始状态或状态变更）：app: 前缀的应用状态由所有用户共享，
+// temp: 前缀的临时状态只在一次运行中有效，都不允许客户端设置
+func ValidateClientState(state map[string]any) error {
+	for key := range state {
+		for _, prefix := range []string{adksession.KeyPrefixApp, adksession.KeyPrefixTemp} {
+			if strings.HasPrefix(key, prefix) {
+				return fmt.Errorf("%w: 不能设置 %s 前缀的状态 %s", ErrInvalidRequest, prefix, key)
+			}
+		}
+	}
+	return nil
+}
+
+// writeError 根据错误类型返回对应的状态码
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrSessionNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrSessionExists), errors.Is(err, ErrStaleSession):
+		c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrInvalidRequest):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
@@ -0,0 +1,67 @@
+package session
+
+import (
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+)
+
+// Session Agent 对话会话
+// 以 (AppName, UserID, ID) 为主键，与 ADK 的会话标识一致；UserID 是用户 ID 的十进制字符串。
+// State 只保存会话范围的状态，app: 和 user: 前缀的状态分别保存在 AppState 和 UserState 中
+type Session struct {
+	AppName   string         `json:"app_name" gorm:"primaryKey;size:100"`
+	UserID    string         `json:"user_id" gorm:"primaryKey;size:100"`
+	ID        string         `json:"id" gorm:"primaryKey;size:100"`
+	State     calendar.JSONB `json:"state" gorm:"type:jsonb"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at" gorm:"index"` // 最后一次追加事件的时间，用于检测过期的会话
+}
+
+func (Session) TableName() string {
+	return "agent_sessions"
+}
+
+// Event 会话中的一个事件（用户消息、模型回复、工具调用等）
+// Data 保存完整的 ADK 事件（JSON），其余字段用于查询
+type Event struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"-"`
+
+	EventID      string    `json:"id" gorm:"not null;size:100"`
+	AppName      string    `json:"app_name" gorm:"not null;size:100;index:idx_agent_session_events_session"`
+	UserID       string    `json:"user_id" gorm:"not null;size:100;index:idx_agent_session_events_session"`
+	SessionID    string    `json:"session_id" gorm:"not null;size:100;index:idx_agent_session_events_session"`
+	InvocationID string    `json:"invocation_id" gorm:"size:100"`
+	Author       string    `json:"author" gorm:"size:100"`
+	Branch       string    `json:"branch" gorm:"size:255"`
+	Timestamp    time.Time `json:"timestamp" gorm:"not null;index"`
+	Data         string    `json:"-" gorm:"type:jsonb;not null"`
+}
+
+func (Event) TableName() string {
+	return "agent_session_events"
+}
+
+// AppState 应用范围的状态（app: 前缀），同一应用的所有会话共享
+type AppState struct {
+	AppName   string         `gorm:"primaryKey;size:100"`
+	State     calendar.JSONB `gorm:"type:jsonb"`
+	UpdatedAt time.Time
+}
+
+func (AppState) TableName() string {
+	return "agent_app_states"
+}
+
+// UserState 用户范围的状态（user: 前缀），同一用户在同一应用中的所有会话共享
+type UserState struct {
+	AppName   string         `gorm:"primaryKey;size:100"`
+	UserID    string         `gorm:"primaryKey;size:100"`
+	State     calendar.JSONB `gorm:"type:jsonb"`
+	UpdatedAt time.Time
+}
+
+func (UserState) TableName() string {
+	return "agent_user_states"
+}
@@ -0,0 +1,154 @@
+package session
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository 会话仓库
+// LockSession 使用 SELECT ... FOR UPDATE，只能在 Transaction 中调用，保证同一会话的事件依次追加
+type Repository interface {
+	Transaction(fn func(repo Repository) error) error
+
+	// 会话相关方法
+	CreateSession(session *Session) error
+	GetSession(appName, userID, id string) (*Session, error)
+	LockSession(appName, userID, id string) (*Session, error)
+	ListSessions(appName, userID string) ([]*Session, error)
+	UpdateSession(session *Session) error
+	DeleteSession(appName, userID, id string) error
+
+	// 事件相关方法
+	CreateEvent(event *Event) error
+	ListEvents(appName, userID, sessionID string, after time.Time, limit int) ([]*Event, error)
+
+	// 状态相关方法
+	GetAppState(appName string) (*AppState, error)
+	SaveAppState(state *AppState) error
+	GetUserState(appName, userID string) (*UserState, error)
+	SaveUserState(state *UserState) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建会话仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Transaction 在事务中执行 fn，fn 的参数是绑定到该事务的仓库
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+// CreateSession 创建会话
+func (r *repository) CreateSession(session *Session) error {
+	return r.db.Create(session).Error
+}
+
+// GetSession 获取会话，不存在时返回 gorm.ErrRecordNotFound
+func (r *repository) GetSession(appName, userID, id string) (*Session, error) {
+	var session Session
+	err := r.db.Where("app_name = ? AND user_id = ? AND id = ?", appName, userID, id).First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// LockSession 锁定并获取会话，不存在时返回 gorm.ErrRecordNotFound
+func (r *repository) LockSession(appName, userID, id string) (*Session, error) {
+	var session Session
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("app_name = ? AND user_id = ? AND id = ?", appName, userID, id).
+		First(&session).Error
+	if err != nil {
+		return nil, err
+	}
+	return &session, nil
+}
+
+// ListSessions 列出应用的会话（最近更新的优先），userID 为空时列出所有用户的会话
+func (r *repository) ListSessions(appName, userID string) ([]*Session, error) {
+	query := r.db.Where("app_name = ?", appName)
+	if userID != "" {
+		query = query.Where("user_id = ?", userID)
+	}
+
+	var sessions []*Session
+	err := query.Order("updated_at DESC").Find(&sessions).Error
+	return sessions, err
+}
+
+// UpdateSession 更新会话
+func (r *repository) UpdateSession(session *Session) error {
+	return r.db.Save(session).Error
+}
+
+// DeleteSession 删除会话及其事件
+func (r *repository) DeleteSession(appName, userID, id string) error {
+	if err := r.db.Where("app_name = ? AND user_id = ? AND session_id = ?", appName, userID, id).Delete(&Event{}).Error; err != nil {
+		return err
+	}
+	return r.db.Where("app_name = ? AND user_id = ? AND id = ?", appName, userID, id).Delete(&Session{}).Error
+}
+
+// CreateEvent 创建事件
+func (r *repository) CreateEvent(event *Event) error {
+	return r.db.Create(event).Error
+}
+
+// ListEvents 按时间顺序列出会话的事件
+// after 不为零时只返回该时间及之后的事件；limit 大于 0 时只返回最近的 limit 个事件
+func (r *repository) ListEvents(appName, userID, sessionID string, after time.Time, limit int) ([]*Event, error) {
+	query := r.db.Where("app_name = ? AND user_id = ? AND session_id = ?", appName, userID, sessionID)
+	if !after.IsZero() {
+		query = query.Where("timestamp >= ?", after)
+	}
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+
+	var events []*Event
+	if err := query.Order("timestamp DESC, id DESC").Find(&events).Error; err != nil {
+		return nil, err
+	}
+	for i, j := 0, len(events)-1; i < j; i, j = i+1, j-1 {
+		events[i], events[j] = events[j], events[i]
+	}
+	return events, nil
+}
+
+// GetAppState 获取应用状态，没有时返回 nil
+func (r *repository) GetAppState(appName string) (*AppState, error) {
+	var states []*AppState
+	if err := r.db.Where("app_name = ?", appName).Limit(1).Find(&states).Error; err != nil || len(states) == 0 {
+		return nil, err
+	}
+	return states[0], nil
+}
+
+// SaveAppState 保存应用状态（不存在时创建）
+func (r *repository) SaveAppState(state *AppState) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(state).Error
+}
+
+// GetUserState 获取用户状态，没有时返回 nil
+func (r *repository) GetUserState(appName, userID string) (*UserState, error) {
+	var states []*UserState
+	if err := r.db.Where("app_name = ? AND user_id = ?", appName, userID).Limit(1).Find(&states).Error; err != nil || len(states) == 0 {
+		return nil, err
+	}
+	return states[0], nil
+}
+
+// SaveUserState 保存用户状态（不存在时创建）
+func (r *repository) SaveUserState(state *UserState) error {
+	return r.db.Clauses(clause.OnConflict{UpdateAll: true}).Create(state).Error
+}
@@ -0,0 +1,85 @@
+package session
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 创建测试用的数据库连接（使用sqlmock）
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("创建sqlmock失败: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建GORM连接失败: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// TestRepository_ListEvents 测试只取最近的事件并按时间顺序返回
+func TestRepository_ListEvents(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+	after := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT \* FROM "agent_session_events" WHERE \(app_name = \$1 AND user_id = \$2 AND session_id = \$3\) AND timestamp >= \$4 ORDER BY timestamp DESC, id DESC LIMIT \$5`).
+		WithArgs("calendar_agent", "1", "s1", after, 2).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "event_id"}).AddRow(3, "e3").AddRow(2, "e2"))
+
+	events, err := repo.ListEvents("calendar_agent", "1", "s1", after, 2)
+
+	assert.NoError(t, err)
+	assert.Len(t, events, 2)
+	assert.Equal(t, "e2", events[0].EventID)
+	assert.Equal(t, "e3", events[1].EventID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_DeleteSession 测试删除会话时一并删除其事件
+func TestRepository_DeleteSession(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`DELETE FROM "agent_session_events" WHERE app_name = \$1 AND user_id = \$2 AND session_id = \$3`).
+		WithArgs("calendar_agent", "1", "s1").
+		WillReturnResult(sqlmock.NewResult(0, 4))
+	mock.ExpectExec(`DELETE FROM "agent_sessions" WHERE app_name = \$1 AND user_id = \$2 AND id = \$3`).
+		WithArgs("calendar_agent", "1", "s1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.Transaction(func(repo Repository) error {
+		return repo.DeleteSession("calendar_agent", "1", "s1")
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_SaveUserState 测试保存用户状态时使用 upsert
+func TestRepository_SaveUserState(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`INSERT INTO "agent_user_states" .* ON CONFLICT \("app_name","user_id"\) DO UPDATE SET`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	err := repo.SaveUserState(&UserState{AppName: "calendar_agent", UserID: "1", State: map[string]interface{}{"timezone": "Asia/Shanghai"}})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,322 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/google/uuid"
+	adksession "google.golang.org/adk/session"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrSessionNotFound = errors.New("会话不存在")
+	ErrSessionExists   = errors.New("会话已存在")
+	ErrStaleSession    = errors.New("会话已被更新，请重新获取")
+	ErrInvalidRequest  = errors.New("请求参数无效")
+	ErrInvalidUserKey  = errors.New("无效的用户标识")
+)
+
+// service 基于数据库的 ADK 会话服务
+// 语义与 ADK 的内存实现一致：状态按 app: / user: 前缀分别保存在应用、用户和会话范围，
+// temp: 前缀的临时状态和流式输出的中间事件（Partial）不保存
+type service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+// NewService 创建会话服务
+func NewService(repo Repository) adksession.Service {
+	return &service{repo: repo, now: time.Now}
+}
+
+// UserKey ADK 会话中的用户标识：用户 ID 的十进制字符串
+func UserKey(userID uint) string {
+	return strconv.FormatUint(uint64(userID), 10)
+}
+
+// ParseUserKey 从 ADK 会话中的用户标识解析用户 ID
+func ParseUserKey(key string) (uint, error) {
+	id, err := strconv.ParseUint(key, 10, 0)
+	if err != nil || id == 0 {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidUserKey, key)
+	}
+	return uint(id), nil
+}
+
+// Create 创建会话，SessionID 为空时自动生成
+func (s *service) Create(ctx context.Context, req *adksession.CreateRequest) (*adksession.CreateResponse, error) {
+	if req.AppName == "" || req.UserID == "" {
+		return nil, fmt.Errorf("%w: app_name 和 user_id 不能为空", ErrInvalidRequest)
+	}
+	sessionID := req.SessionID
+	if sessionID == "" {
+		sessionID = uuid.NewString()
+	}
+
+	appDelta, userDelta, sessionState := splitState(req.State)
+	record := &Session{AppName: req.AppName, UserID: req.UserID, ID: sessionID, State: calendar.JSONB(sessionState)}
+
+	var appState, userState map[string]any
+	err := s.repo.Transaction(func(repo Repository) error {
+		if _, err := repo.GetSession(req.AppName, req.UserID, sessionID); err == nil {
+			return fmt.Errorf("%w: %s", ErrSessionExists, sessionID)
+		} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		var err error
+		if appState, err = s.applyAppDelta(repo, req.AppName, appDelta); err != nil {
+			return err
+		}
+		if userState, err = s.applyUserDelta(repo, req.AppName, req.UserID, userDelta); err != nil {
+			return err
+		}
+		return repo.CreateSession(record)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("创建会话失败: %w", err)
+	}
+
+	return &adksession.CreateResponse{Session: &localSession{
+		appName:   record.AppName,
+		userID:    record.UserID,
+		sessionID: record.ID,
+		state:     mergeState(appState, userState, sessionState),
+		updatedAt: record.UpdatedAt,
+	}}, nil
+}
+
+// Get 获取会话及其事件，NumRecentEvents 和 After 用于只加载最近的事件
+func (s *service) Get(ctx context.Context, req *adksession.GetRequest) (*adksession.GetResponse, error) {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return nil, fmt.Errorf("%w: app_name、user_id 和 session_id 不能为空", ErrInvalidRequest)
+	}
+
+	record, err := s.repo.GetSession(req.AppName, req.UserID, req.SessionID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", ErrSessionNotFound, req.SessionID)
+		}
+		return nil, fmt.Errorf("获取会话失败: %w", err)
+	}
+
+	records, err := s.repo.ListEvents(req.AppName, req.UserID, req.SessionID, req.After, req.NumRecentEvents)
+	if err != nil {
+		return nil, fmt.Errorf("获取会话事件失败: %w", err)
+	}
+	evts := make([]*adksession.Event, 0, len(records))
+	for _, r := range records {
+		var event adksession.Event
+		if err := json.Unmarshal([]byte(r.Data), &event); err != nil {
+			return nil, fmt.Errorf("解析会话事件 %s 失败: %w", r.EventID, err)
+		}
+		evts = append(evts, &event)
+	}
+
+	sess, err := s.loadSession(record, map[string]map[string]any{})
+	if err != nil {
+		return nil, err
+	}
+	sess.events = evts
+	return &adksession.GetResponse{Session: sess}, nil
+}
+
+// List 列出应用的会话（不包含事件），UserID 为空时列出所有用户的会话
+func (s *service) List(ctx context.Context, req *adksession.ListRequest) (*adksession.ListResponse, error) {
+	if req.AppName == "" {
+		return nil, fmt.Errorf("%w: app_name 不能为空", ErrInvalidRequest)
+	}
+
+	records, err := s.repo.ListSessions(req.AppName, req.UserID)
+	if err != nil {
+		return nil, fmt.Errorf("列出会话失败: %w", err)
+	}
+
+	userStates := map[string]map[string]any{}
+	sessions := make([]adksession.Session, 0, len(records))
+	for _, record := range records {
+		sess, err := s.loadSession(record, userStates)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, sess)
+	}
+	return &adksession.ListResponse{Sessions: sessions}, nil
+}
+
+// Delete 删除会话及其事件，会话不存在时不报错
+func (s *service) Delete(ctx context.Context, req *adksession.DeleteRequest) error {
+	if req.AppName == "" || req.UserID == "" || req.SessionID == "" {
+		return fmt.Errorf("%w: app_name、user_id 和 session_id 不能为空", ErrInvalidRequest)
+	}
+
+	err := s.repo.Transaction(func(repo Repository) error {
+		return repo.DeleteSession(req.AppName, req.UserID, req.SessionID)
+	})
+	if err != nil {
+		return fmt.Errorf("删除会话失败: %w", err)
+	}
+	return nil
+}
+
+// AppendEvent 保存事件并应用其中的状态变更
+// 会话在获取之后被其它请求更新过时返回 ErrStaleSession
+func (s *service) AppendEvent(ctx context.Context, curSession adksession.Session, event *adksession.Event) error {
+	if curSession == nil || event == nil {
+		return fmt.Errorf("%w: 会话和事件不能为空", ErrInvalidRequest)
+	}
+	if event.Partial {
+		return nil
+	}
+	sess, ok := curSession.(*localSession)
+	if !ok {
+		return fmt.Errorf("%w: 不支持的会话类型 %T", ErrInvalidRequest, curSession)
+	}
+
+	trimTempState(event)
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = s.now()
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("序列化会话事件失败: %w", err)
+	}
+
+	var updatedAt time.Time
+	err = s.repo.Transaction(func(repo Repository) error {
+		record, err := repo.LockSession(sess.appName, sess.userID, sess.sessionID)
+		if err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return fmt.Errorf("%w: %s", ErrSessionNotFound, sess.sessionID)
+			}
+			return err
+		}
+		if record.UpdatedAt.After(sess.LastUpdateTime()) {
+			return ErrStaleSession
+		}
+
+		appDelta, userDelta, sessionDelta := splitState(event.Actions.StateDelta)
+		if _, err := s.applyAppDelta(repo, sess.appName, appDelta); err != nil {
+			return err
+		}
+		if _, err := s.applyUserDelta(repo, sess.appName, sess.userID, userDelta); err != nil {
+			return err
+		}
+
+		if err := repo.CreateEvent(&Event{
+			EventID:      event.ID,
+			AppName:      sess.appName,
+			UserID:       sess.userID,
+			SessionID:    sess.sessionID,
+			InvocationID: event.InvocationID,
+			Author:       event.Author,
+			Branch:       event.Branch,
+			Timestamp:    event.Timestamp,
+			Data:         string(data),
+		}); err != nil {
+			return err
+		}
+
+		if record.State == nil {
+			record.State = calendar.JSONB{}
+		}
+		for k, v := range sessionDelta {
+			record.State[k] = v
+		}
+		if err := repo.UpdateSession(record); err != nil {
+			return err
+		}
+		updatedAt = record.UpdatedAt
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("保存会话事件失败: %w", err)
+	}
+
+	sess.appendEvent(event, updatedAt)
+	return nil
+}
+
+// loadSession 把会话记录转换为 ADK 会话，合并应用和用户状态
+// userStates 缓存已经查询过的用户状态，列出多个会话时避免重复查询
+func (s *service) loadSession(record *Session, userStates map[string]map[string]any) (*localSession, error) {
+	appState, err := s.repo.GetAppState(record.AppName)
+	if err != nil {
+		return nil, fmt.Errorf("获取应用状态失败: %w", err)
+	}
+
+	userState, ok := userStates[record.UserID]
+	if !ok {
+		us, err := s.repo.GetUserState(record.AppName, record.UserID)
+		if err != nil {
+			return nil, fmt.Errorf("获取用户状态失败: %w", err)
+		}
+		if us != nil {
+			userState = us.State
+		}
+		userStates[record.UserID] = userState
+	}
+
+	var app map[string]any
+	if appState != nil {
+		app = appState.State
+	}
+	return &localSession{
+		appName:   record.AppName,
+		userID:    record.UserID,
+		sessionID: record.ID,
+		state:     mergeState(app, userState, record.State),
+		updatedAt: record.UpdatedAt,
+	}, nil
+}
+
+// applyAppDelta 把变更合并到应用状态并保存，返回合并后的状态
+func (s *service) applyAppDelta(repo Repository, appName string, delta map[string]any) (map[string]any, error) {
+	st, err := repo.GetAppState(appName)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		st = &AppState{AppName: appName}
+	}
+	if st.State == nil {
+		st.State = calendar.JSONB{}
+	}
+	if len(delta) == 0 {
+		return st.State, nil
+	}
+	for k, v := range delta {
+		st.State[k] = v
+	}
+	return st.State, repo.SaveAppState(st)
+}
+
+// applyUserDelta 把变更合并到用户状态并保存，返回合并后的状态
+func (s *service) applyUserDelta(repo Repository, appName, userID string, delta map[string]any) (map[string]any, error) {
+	st, err := repo.GetUserState(appName, userID)
+	if err != nil {
+		return nil, err
+	}
+	if st == nil {
+		st = &UserState{AppName: appName, UserID: userID}
+	}
+	if st.State == nil {
+		st.State = calendar.JSONB{}
+	}
+	if len(delta) == 0 {
+		return st.State, nil
+	}
+	for k, v := range delta {
+		st.State[k] = v
+	}
+	return st.State, repo.SaveUserState(st)
+}
@@ -0,0 +1,216 @@
+package session
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/adk/model"
+	adksession "google.golang.org/adk/session"
+	"google.golang.org/genai"
+	"gorm.io/gorm"
+)
+
+// mockRepository 模拟会话仓库，Transaction 直接使用自身
+type mockRepository struct {
+	mock.Mock
+}
+
+func (m *mockRepository) Transaction(fn func(repo Repository) error) error {
+	return fn(m)
+}
+
+func (m *mockRepository) CreateSession(session *Session) error {
+	return m.Called(session).Error(0)
+}
+
+func (m *mockRepository) GetSession(appName, userID, id string) (*Session, error) {
+	args := m.Called(appName, userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Session), args.Error(1)
+}
+
+func (m *mockRepository) LockSession(appName, userID, id string) (*Session, error) {
+	args := m.Called(appName, userID, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*Session), args.Error(1)
+}
+
+func (m *mockRepository) ListSessions(appName, userID string) ([]*Session, error) {
+	args := m.Called(appName, userID)
+	return args.Get(0).([]*Session), args.Error(1)
+}
+
+func (m *mockRepository) UpdateSession(session *Session) error {
+	return m.Called(session).Error(0)
+}
+
+func (m *mockRepository) DeleteSession(appName, userID, id string) error {
+	return m.Called(appName, userID, id).Error(0)
+}
+
+func (m *mockRepository) CreateEvent(event *Event) error {
+	return m.Called(event).Error(0)
+}
+
+func (m *mockRepository) ListEvents(appName, userID, sessionID string, after time.Time, limit int) ([]*Event, error) {
+	args := m.Called(appName, userID, sessionID, after, limit)
+	return args.Get(0).([]*Event), args.Error(1)
+}
+
+func (m *mockRepository) GetAppState(appName string) (*AppState, error) {
+	args := m.Called(appName)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*AppState), args.Error(1)
+}
+
+func (m *mockRepository) SaveAppState(state *AppState) error {
+	return m.Called(state).Error(0)
+}
+
+func (m *mockRepository) GetUserState(appName, userID string) (*UserState, error) {
+	args := m.Called(appName, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*UserState), args.Error(1)
+}
+
+func (m *mockRepository) SaveUserState(state *UserState) error {
+	return m.Called(state).Error(0)
+}
+
+// TestService_Create 测试初始状态按前缀拆分到应用、用户和会话范围，temp: 状态不保存
+func TestService_Create(t *testing.T) {
+	repo := new(mockRepository)
+	service := NewService(repo)
+
+	repo.On("GetSession", "calendar_agent", "1", "s1").Return(nil, gorm.ErrRecordNotFound)
+	repo.On("GetAppState", "calendar_agent").Return(nil, nil)
+	repo.On("SaveAppState", mock.MatchedBy(func(s *AppState) bool {
+		return s.State["version"] == "v2"
+	})).Return(nil)
+	repo.On("GetUserState", "calendar_agent", "1").Return(&UserState{State: calendar.JSONB{"name": "alice"}}, nil)
+	repo.On("CreateSession", mock.MatchedBy(func(s *Session) bool {
+		return s.ID == "s1" && len(s.State) == 1 && s.State["topic"] == "周会"
+	})).Return(nil)
+
+	resp, err := service.Create(context.Background(), &adksession.CreateRequest{
+		AppName: "calendar_agent", UserID: "1", SessionID: "s1",
+		State: map[string]any{"app:version": "v2", "topic": "周会", "temp:draft": true},
+	})
+
+	require.NoError(t, err)
+	state := resp.Session.State()
+	value, err := state.Get("app:version")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", value)
+	value, err = state.Get("user:name")
+	require.NoError(t, err)
+	assert.Equal(t, "alice", value)
+	_, err = state.Get("temp:draft")
+	assert.ErrorIs(t, err, adksession.ErrStateKeyNotExist)
+	repo.AssertNotCalled(t, "SaveUserState", mock.Anything)
+	repo.AssertExpectations(t)
+
+	// 已存在的会话不能重复创建
+	repo.On("GetSession", "calendar_agent", "1", "s2").Return(&Session{ID: "s2"}, nil)
+	_, err = service.Create(context.Background(), &adksession.CreateRequest{AppName: "calendar_agent", UserID: "1", SessionID: "s2"})
+	assert.ErrorIs(t, err, ErrSessionExists)
+}
+
+// TestService_Get 测试加载会话事件并合并应用和用户状态
+func TestService_Get(t *testing.T) {
+	repo := new(mockRepository)
+	service := NewService(repo)
+
+	data, err := json.Marshal(&adksession.Event{
+		ID: "e1", Author: "user", Timestamp: time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC),
+		LLMResponse: textResponse("明天下午开会"),
+	})
+	require.NoError(t, err)
+
+	repo.On("GetSession", "calendar_agent", "1", "s1").Return(&Session{AppName: "calendar_agent", UserID: "1", ID: "s1", State: calendar.JSONB{"topic": "周会"}}, nil)
+	repo.On("ListEvents", "calendar_agent", "1", "s1", time.Time{}, 10).Return([]*Event{{EventID: "e1", Data: string(data)}}, nil)
+	repo.On("GetAppState", "calendar_agent").Return(&AppState{State: calendar.JSONB{"version": "v2"}}, nil)
+	repo.On("GetUserState", "calendar_agent", "1").Return(nil, nil)
+
+	resp, err := service.Get(context.Background(), &adksession.GetRequest{AppName: "calendar_agent", UserID: "1", SessionID: "s1", NumRecentEvents: 10})
+
+	require.NoError(t, err)
+	require.Equal(t, 1, resp.Session.Events().Len())
+	event := resp.Session.Events().At(0)
+	assert.Equal(t, "e1", event.ID)
+	assert.Equal(t, "明天下午开会", event.Content.Parts[0].Text)
+	value, err := resp.Session.State().Get("app:version")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", value)
+
+	repo.On("GetSession", "calendar_agent", "1", "missing").Return(nil, gorm.ErrRecordNotFound)
+	_, err = service.Get(context.Background(), &adksession.GetRequest{AppName: "calendar_agent", UserID: "1", SessionID: "missing"})
+	assert.ErrorIs(t, err, ErrSessionNotFound)
+}
+
+// TestService_AppendEvent 测试保存事件并按范围应用状态变更，流式输出的中间事件不保存
+func TestService_AppendEvent(t *testing.T) {
+	repo := new(mockRepository)
+	service := NewService(repo)
+
+	loadedAt := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	sess := &localSession{appName: "calendar_agent", userID: "1", sessionID: "s1", state: map[string]any{}, updatedAt: loadedAt}
+
+	partial := &adksession.Event{LLMResponse: textResponse("明")}
+	partial.Partial = true
+	require.NoError(t, service.AppendEvent(context.Background(), sess, partial))
+	assert.Equal(t, 0, sess.Events().Len())
+
+	record := &Session{AppName: "calendar_agent", UserID: "1", ID: "s1", UpdatedAt: loadedAt}
+	repo.On("LockSession", "calendar_agent", "1", "s1").Return(record, nil).Once()
+	repo.On("GetAppState", "calendar_agent").Return(nil, nil)
+	repo.On("GetUserState", "calendar_agent", "1").Return(nil, nil)
+	repo.On("SaveUserState", mock.MatchedBy(func(s *UserState) bool {
+		return s.UserID == "1" && s.State["timezone"] == "Asia/Shanghai"
+	})).Return(nil)
+	repo.On("CreateEvent", mock.MatchedBy(func(e *Event) bool {
+		return e.EventID == "e1" && e.SessionID == "s1" && e.Author == "calendar_agent"
+	})).Return(nil)
+	repo.On("UpdateSession", record).Run(func(args mock.Arguments) {
+		record.UpdatedAt = loadedAt.Add(time.Second)
+	}).Return(nil)
+
+	event := &adksession.Event{ID: "e1", Author: "calendar_agent", LLMResponse: textResponse("好的")}
+	event.Actions.StateDelta = map[string]any{"user:timezone": "Asia/Shanghai", "last_item": "meeting", "temp:step": 1}
+	require.NoError(t, service.AppendEvent(context.Background(), sess, event))
+
+	assert.Equal(t, calendar.JSONB{"last_item": "meeting"}, record.State)
+	assert.NotContains(t, event.Actions.StateDelta, "temp:step")
+	assert.Equal(t, 1, sess.Events().Len())
+	assert.Equal(t, loadedAt.Add(time.Second), sess.LastUpdateTime())
+	value, err := sess.State().Get("user:timezone")
+	require.NoError(t, err)
+	assert.Equal(t, "Asia/Shanghai", value)
+	repo.AssertNotCalled(t, "SaveAppState", mock.Anything)
+	repo.AssertExpectations(t)
+
+	// 会话在加载后被其它请求更新过
+	stale := &localSession{appName: "calendar_agent", userID: "1", sessionID: "s1", state: map[string]any{}, updatedAt: loadedAt}
+	repo.On("LockSession", "calendar_agent", "1", "s1").Return(record, nil).Once()
+	err = service.AppendEvent(context.Background(), stale, &adksession.Event{ID: "e2", LLMResponse: textResponse("好的")})
+	assert.ErrorIs(t, err, ErrStaleSession)
+}
+
+// textResponse 构造文本消息
+func textResponse(text string) model.LLMResponse {
+	return model.LLMResponse{Content: genai.NewContentFromText(text, genai.RoleUser)}
+}
@@ -0,0 +1,180 @@
+package session
+
+import (
+	"iter"
+	"strings"
+	"sync"
+	"time"
+
+	adksession "google.golang.org/adk/session"
+)
+
+// localSession 从数据库加载的会话，实现 ADK 的 session.Session
+// State 包含会话状态以及带 app: / user: 前缀的应用和用户状态；追加事件后同步更新
+type localSession struct {
+	appName   string
+	userID    string
+	sessionID string
+
+	mu        sync.RWMutex
+	events    []*adksession.Event
+	state     map[string]any
+	updatedAt time.Time
+}
+
+func (s *localSession) ID() string {
+	return s.sessionID
+}
+
+func (s *localSession) AppName() string {
+	return s.appName
+}
+
+func (s *localSession) UserID() string {
+	return s.userID
+}
+
+func (s *localSession) State() adksession.State {
+	return &state{mu: &s.mu, state: s.state}
+}
+
+func (s *localSession) Events() adksession.Events {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return events(s.events)
+}
+
+func (s *localSession) LastUpdateTime() time.Time {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.updatedAt
+}
+
+// appendEvent 在内存中追加已保存的事件并应用状态变更
+func (s *localSession) appendEvent(event *adksession.Event, updatedAt time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for key, value := range event.Actions.StateDelta {
+		s.state[key] = value
+	}
+	s.events = append(s.events, event)
+	s.updatedAt = updatedAt
+}
+
+// events 会话事件列表，实现 ADK 的 session.Events
+type events []*adksession.Event
+
+func (e events) All() iter.Seq[*adksession.Event] {
+	return func(yield func(*adksession.Event) bool) {
+		for _, event := range e {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+func (e events) Len() int {
+	return len(e)
+}
+
+func (e events) At(i int) *adksession.Event {
+	if i >= 0 && i < len(e) {
+		return e[i]
+	}
+	return nil
+}
+
+// state 会话状态，实现 ADK 的 session.State
+// Set 只修改内存中的状态，需要持久化的修改应通过事件的 StateDelta 提交
+type state struct {
+	mu    *sync.RWMutex
+	state map[string]any
+}
+
+func (s *state) Get(key string) (any, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	value, ok := s.state[key]
+	if !ok {
+		return nil, adksession.ErrStateKeyNotExist
+	}
+	return value, nil
+}
+
+func (s *state) All() iter.Seq2[string, any] {
+	return func(yield func(key string, value any) bool) {
+		s.mu.RLock()
+		snapshot := make(map[string]any, len(s.state))
+		for k, v := range s.state {
+			snapshot[k] = v
+		}
+		s.mu.RUnlock()
+
+		for k, v := range snapshot {
+			if !yield(k, v) {
+				return
+			}
+		}
+	}
+}
+
+func (s *state) Set(key string, value any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.state[key] = value
+	return nil
+}
+
+// splitState 按前缀把状态拆分为应用、用户和会话三个范围，去掉前缀，忽略 temp: 前缀的临时状态
+func splitState(delta map[string]any) (app, user, session map[string]any) {
+	app, user, session = map[string]any{}, map[string]any{}, map[string]any{}
+	for key, value := range delta {
+		if k, ok := strings.CutPrefix(key, adksession.KeyPrefixApp); ok {
+			app[k] = value
+		} else if k, ok := strings.CutPrefix(key, adksession.KeyPrefixUser); ok {
+			user[k] = value
+		} else if !strings.HasPrefix(key, adksession.KeyPrefixTemp) {
+			session[key] = value
+		}
+	}
+	return app, user, session
+}
+
+// mergeState 合并三个范围的状态，应用和用户状态加回前缀
+func mergeState(app, user, session map[string]any) map[string]any {
+	merged := make(map[string]any, len(app)+len(user)+len(session))
+	for k, v := range session {
+		merged[k] = v
+	}
+	for k, v := range app {
+		merged[adksession.KeyPrefixApp+k] = v
+	}
+	for k, v := range user {
+		merged[adksession.KeyPrefixUser+k] = v
+	}
+	return merged
+}
+
+// trimTempState 去掉事件中 temp: 前缀的状态变更，临时状态不保存
+func trimTempState(event *adksession.Event) {
+	if len(event.Actions.StateDelta) == 0 {
+		return
+	}
+	delta := make(map[string]any, len(event.Actions.StateDelta))
+	for key, value := range event.Actions.StateDelta {
+		if !strings.HasPrefix(key, adksession.KeyPrefixTemp) {
+			delta[key] = value
+		}
+	}
+	event.Actions.StateDelta = delta
+}
+
+var (
+	_ adksession.Session = (*localSession)(nil)
+	_ adksession.Events  = events(nil)
+	_ adksession.State   = (*state)(nil)
+)
@@ -0,0 +1,73 @@
+package usage
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// GetCurrentUserUsage 获取当前用户的 token 用量和额度
+// GET /api/v1/users/me/usage?from=2025-01-01&to=2025-01-31
+func (h *Handler) GetCurrentUserUsage(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	var req UsageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	resp, err := h.service.GetUserUsage(*userID, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// Report 获取所有用户的 token 用量报告（管理员）
+// GET /api/v1/admin/usage?from=2025-01-01&to=2025-01-31
+func (h *Handler) Report(c *gin.Context) {
+	var req UsageRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	resp, err := h.service.Report(&req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
+
+// writeError 将服务层错误转换为 HTTP 响应
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
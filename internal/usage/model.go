@@ -0,0 +1,57 @@
+package usage
+
+import (
+	"time"
+
+	"google.golang.org/genai"
+)
+
+// Totals token 用量合计
+type Totals struct {
+	Requests         int64 `json:"requests" gorm:"not null;default:0"` // 模型调用次数
+	PromptTokens     int64 `json:"prompt_tokens" gorm:"not null;default:0"`
+	CompletionTokens int64 `json:"completion_tokens" gorm:"not null;default:0"`
+	CachedTokens     int64 `json:"cached_tokens" gorm:"not null;default:0"` // 命中缓存的输入 token（包含在 PromptTokens 中）
+	TotalTokens      int64 `json:"total_tokens" gorm:"not null;default:0"`
+}
+
+// FromMetadata 把一次模型调用返回的用量转换为合计
+func FromMetadata(metadata *genai.GenerateContentResponseUsageMetadata) Totals {
+	totals := Totals{
+		Requests:         1,
+		PromptTokens:     int64(metadata.PromptTokenCount),
+		CompletionTokens: int64(metadata.CandidatesTokenCount),
+		CachedTokens:     int64(metadata.CachedContentTokenCount),
+		TotalTokens:      int64(metadata.TotalTokenCount),
+	}
+	if totals.TotalTokens == 0 {
+		totals.TotalTokens = totals.PromptTokens + totals.CompletionTokens
+	}
+	return totals
+}
+
+// Usage LLM token 用量，按用户、日期（UTC）、会话、提供方和模型汇总
+type Usage struct {
+	ID        uint      `json:"-" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+
+	UserID    uint      `json:"user_id" gorm:"not null;uniqueIndex:idx_llm_usages_key,priority:1"`
+	Day       time.Time `json:"day" gorm:"type:date;not null;uniqueIndex:idx_llm_usages_key,priority:2;index"`
+	SessionID string    `json:"session_id" gorm:"not null;size:128;uniqueIndex:idx_llm_usages_key,priority:3"`
+	Provider  string    `json:"provider" gorm:"not null;size:50;uniqueIndex:idx_llm_usages_key,priority:4"` // llm.providers 中的名称
+	Model     string    `json:"model" gorm:"not null;size:100;uniqueIndex:idx_llm_usages_key,priority:5"`
+
+	Totals
+}
+
+func (Usage) TableName() string {
+	return "llm_usages"
+}
+
+// UserTotals 用户在一段时间内的用量合计
+type UserTotals struct {
+	UserID   uint   `json:"user_id"`
+	Username string `json:"username"`
+	Totals
+}
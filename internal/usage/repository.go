@@ -0,0 +1,80 @@
+package usage
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// totalsColumns 汇总查询中合计字段的表达式
+const totalsColumns = "SUM(requests) AS requests, SUM(prompt_tokens) AS prompt_tokens, SUM(completion_tokens) AS completion_tokens, " +
+	"SUM(cached_tokens) AS cached_tokens, SUM(total_tokens) AS total_tokens"
+
+// Repository token 用量仓库，时间范围 [from, to) 按日期比较
+type Repository interface {
+	// Add 把用量累加到同一用户、日期、会话、提供方和模型的记录上
+	Add(usage *Usage) error
+	SumTokens(userID uint, from, to time.Time) (int64, error)
+	ListDaily(userID uint, from, to time.Time) ([]*Usage, error)
+	ListUserTotals(from, to time.Time) ([]*UserTotals, error)
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建 token 用量仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Add 插入用量记录，记录已存在时累加
+func (r *repository) Add(usage *Usage) error {
+	return r.db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "user_id"}, {Name: "day"}, {Name: "session_id"}, {Name: "provider"}, {Name: "model"}},
+		DoUpdates: clause.Assignments(map[string]any{
+			"requests":          gorm.Expr("llm_usages.requests + EXCLUDED.requests"),
+			"prompt_tokens":     gorm.Expr("llm_usages.prompt_tokens + EXCLUDED.prompt_tokens"),
+			"completion_tokens": gorm.Expr("llm_usages.completion_tokens + EXCLUDED.completion_tokens"),
+			"cached_tokens":     gorm.Expr("llm_usages.cached_tokens + EXCLUDED.cached_tokens"),
+			"total_tokens":      gorm.Expr("llm_usages.total_tokens + EXCLUDED.total_tokens"),
+			"updated_at":        gorm.Expr("EXCLUDED.updated_at"),
+		}),
+	}).Create(usage).Error
+}
+
+// SumTokens 统计用户在时间范围内使用的 token 总数
+func (r *repository) SumTokens(userID uint, from, to time.Time) (int64, error) {
+	var total int64
+	err := r.db.Model(&Usage{}).
+		Select("COALESCE(SUM(total_tokens), 0)").
+		Where("user_id = ? AND day >= ? AND day < ?", userID, from, to).
+		Scan(&total).Error
+	return total, err
+}
+
+// ListDaily 按日期、提供方和模型汇总用户的用量（不区分会话）
+func (r *repository) ListDaily(userID uint, from, to time.Time) ([]*Usage, error) {
+	var usages []*Usage
+	err := r.db.Model(&Usage{}).
+		Select("day, provider, model, "+totalsColumns).
+		Where("user_id = ? AND day >= ? AND day < ?", userID, from, to).
+		Group("day, provider, model").
+		Order("day ASC, provider ASC, model ASC").
+		Scan(&usages).Error
+	return usages, err
+}
+
+// ListUserTotals 按用户汇总用量，按 token 总数从多到少排序
+func (r *repository) ListUserTotals(from, to time.Time) ([]*UserTotals, error) {
+	var totals []*UserTotals
+	err := r.db.Table("llm_usages").
+		Select("llm_usages.user_id, users.username, "+totalsColumns).
+		Joins("LEFT JOIN users ON users.id = llm_usages.user_id").
+		Where("llm_usages.day >= ? AND llm_usages.day < ?", from, to).
+		Group("llm_usages.user_id, users.username").
+		Order("total_tokens DESC, llm_usages.user_id ASC").
+		Scan(&totals).Error
+	return totals, err
+}
@@ -0,0 +1,70 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 创建测试用的数据库连接（使用sqlmock）
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("创建sqlmock失败: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建GORM连接失败: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// TestRepository_Add 测试同一用户、日期、会话和模型的用量累加到已有记录上
+func TestRepository_Add(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+	day := time.Date(2025, 1, 6, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`INSERT INTO "llm_usages" .* ON CONFLICT \("user_id","day","session_id","provider","model"\) DO UPDATE SET .*"total_tokens"=llm_usages.total_tokens \+ EXCLUDED.total_tokens.* RETURNING "id"`).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+	mock.ExpectCommit()
+
+	err := repo.Add(&Usage{
+		UserID:    7,
+		Day:       day,
+		SessionID: "s1",
+		Provider:  "deepseek",
+		Model:     "deepseek-chat",
+		Totals:    Totals{Requests: 1, PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120},
+	})
+
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_SumTokens 测试统计时间范围内的 token 总数
+func TestRepository_SumTokens(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+	from := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 1, 7, 0, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT COALESCE\(SUM\(total_tokens\), 0\) FROM "llm_usages" WHERE user_id = \$1 AND day >= \$2 AND day < \$3`).
+		WithArgs(7, from, to).
+		WillReturnRows(sqlmock.NewRows([]string{"coalesce"}).AddRow(1500))
+
+	total, err := repo.SumTokens(7, from, to)
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1500), total)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,233 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/galilio/otter/internal/common/config"
+)
+
+var (
+	ErrInvalidInput         = errors.New("输入参数无效")
+	ErrDailyQuotaExceeded   = errors.New("今日 token 额度已用完")
+	ErrMonthlyQuotaExceeded = errors.New("本月 token 额度已用完")
+)
+
+// dateLayout 请求和响应中日期的格式
+const dateLayout = "2006-01-02"
+
+// maxRangeDays 查询用量的最大天数
+const maxRangeDays = 366
+
+// Service token 用量服务，日期和月份都按 UTC 计算
+type Service interface {
+	// Record 记录一次模型调用的用量
+	Record(userID uint, sessionID, provider, model string, totals Totals) error
+	// CheckQuota 检查用户的额度，用完时返回 ErrDailyQuotaExceeded 或 ErrMonthlyQuotaExceeded
+	CheckQuota(userID uint) error
+	GetUserUsage(userID uint, req *UsageRequest) (*UserUsageResponse, error)
+	Report(req *UsageRequest) (*ReportResponse, error)
+}
+
+// UsageRequest 查询用量请求，日期格式为 2006-01-02（包含 from 和 to 当天），默认为本月 1 日到今天
+type UsageRequest struct {
+	From string `form:"from"`
+	To   string `form:"to"`
+}
+
+// DailyUsage 一天中某个模型的用量
+type DailyUsage struct {
+	Day      string `json:"day"`
+	Provider string `json:"provider"`
+	Model    string `json:"model"`
+	Totals
+}
+
+// QuotaStatus 用户的额度和已用量，上限为 0 表示不限制
+type QuotaStatus struct {
+	DailyLimit   int64 `json:"daily_limit"`
+	DailyUsed    int64 `json:"daily_used"`
+	MonthlyLimit int64 `json:"monthly_limit"`
+	MonthlyUsed  int64 `json:"monthly_used"`
+}
+
+// UserUsageResponse 用户的用量
+type UserUsageResponse struct {
+	From  string        `json:"from"`
+	To    string        `json:"to"`
+	Total Totals        `json:"total"`
+	Daily []*DailyUsage `json:"daily"`
+	Quota QuotaStatus   `json:"quota"`
+}
+
+// ReportResponse 所有用户的用量报告
+type ReportResponse struct {
+	From  string        `json:"from"`
+	To    string        `json:"to"`
+	Total Totals        `json:"total"`
+	Users []*UserTotals `json:"users"`
+}
+
+type service struct {
+	repo  Repository
+	quota config.QuotaConfig
+	now   func() time.Time
+}
+
+// NewService 创建 token 用量服务，quota 为 nil 时不限制用量
+func NewService(repo Repository, quota *config.QuotaConfig) Service {
+	s := &service{repo: repo, now: time.Now}
+	if quota != nil {
+		s.quota = *quota
+	}
+	return s
+}
+
+// Record 记录一次模型调用的用量，累加到当天的记录上
+func (s *service) Record(userID uint, sessionID, provider, model string, totals Totals) error {
+	err := s.repo.Add(&Usage{
+		UserID:    userID,
+		Day:       startOfDay(s.now()),
+		SessionID: sessionID,
+		Provider:  provider,
+		Model:     model,
+		Totals:    totals,
+	})
+	if err != nil {
+		return fmt.Errorf("记录 token 用量失败: %w", err)
+	}
+	return nil
+}
+
+// CheckQuota 检查用户今天和本月的用量是否达到上限
+func (s *service) CheckQuota(userID uint) error {
+	status, err := s.quotaStatus(userID)
+	if err != nil {
+		return err
+	}
+	if status.DailyLimit > 0 && status.DailyUsed >= status.DailyLimit {
+		return ErrDailyQuotaExceeded
+	}
+	if status.MonthlyLimit > 0 && status.MonthlyUsed >= status.MonthlyLimit {
+		return ErrMonthlyQuotaExceeded
+	}
+	return nil
+}
+
+// GetUserUsage 获取用户在时间范围内每天的用量和当前的额度
+func (s *service) GetUserUsage(userID uint, req *UsageRequest) (*UserUsageResponse, error) {
+	from, to, err := s.parseRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	usages, err := s.repo.ListDaily(userID, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("查询 token 用量失败: %w", err)
+	}
+	status, err := s.quotaStatus(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := &UserUsageResponse{
+		From:  from.Format(dateLayout),
+		To:    to.AddDate(0, 0, -1).Format(dateLayout),
+		Daily: make([]*DailyUsage, 0, len(usages)),
+		Quota: *status,
+	}
+	for _, u := range usages {
+		resp.Daily = append(resp.Daily, &DailyUsage{
+			Day:      u.Day.Format(dateLayout),
+			Provider: u.Provider,
+			Model:    u.Model,
+			Totals:   u.Totals,
+		})
+		resp.Total.add(u.Totals)
+	}
+	return resp, nil
+}
+
+// Report 按用户汇总时间范围内的用量
+func (s *service) Report(req *UsageRequest) (*ReportResponse, error) {
+	from, to, err := s.parseRange(req)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := s.repo.ListUserTotals(from, to)
+	if err != nil {
+		return nil, fmt.Errorf("查询 token 用量失败: %w", err)
+	}
+
+	resp := &ReportResponse{
+		From:  from.Format(dateLayout),
+		To:    to.AddDate(0, 0, -1).Format(dateLayout),
+		Users: users,
+	}
+	if resp.Users == nil {
+		resp.Users = []*UserTotals{}
+	}
+	for _, u := range users {
+		resp.Total.add(u.Totals)
+	}
+	return resp, nil
+}
+
+// quotaStatus 查询用户今天和本月的用量
+func (s *service) quotaStatus(userID uint) (*QuotaStatus, error) {
+	today := startOfDay(s.now())
+	month := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	tomorrow := today.AddDate(0, 0, 1)
+
+	status := &QuotaStatus{DailyLimit: s.quota.DailyTokens, MonthlyLimit: s.quota.MonthlyTokens}
+	var err error
+	if status.DailyUsed, err = s.repo.SumTokens(userID, today, tomorrow); err != nil {
+		return nil, fmt.Errorf("查询 token 用量失败: %w", err)
+	}
+	if status.MonthlyUsed, err = s.repo.SumTokens(userID, month, tomorrow); err != nil {
+		return nil, fmt.Errorf("查询 token 用量失败: %w", err)
+	}
+	return status, nil
+}
+
+// parseRange 解析查询的日期范围，返回 [from, to) 的 UTC 日期
+func (s *service) parseRange(req *UsageRequest) (time.Time, time.Time, error) {
+	today := startOfDay(s.now())
+	from := time.Date(today.Year(), today.Month(), 1, 0, 0, 0, 0, time.UTC)
+	to := today
+
+	var err error
+	if req.From != "" {
+		if from, err = time.Parse(dateLayout, req.From); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: 无效的开始日期 %q", ErrInvalidInput, req.From)
+		}
+	}
+	if req.To != "" {
+		if to, err = time.Parse(dateLayout, req.To); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("%w: 无效的结束日期 %q", ErrInvalidInput, req.To)
+		}
+	}
+	if to.Before(from) {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: 结束日期不能早于开始日期", ErrInvalidInput)
+	}
+	if to.Sub(from) >= maxRangeDays*24*time.Hour {
+		return time.Time{}, time.Time{}, fmt.Errorf("%w: 查询范围不能超过 %d 天", ErrInvalidInput, maxRangeDays)
+	}
+	return from, to.AddDate(0, 0, 1), nil
+}
+
+func (t *Totals) add(other Totals) {
+	t.Requests += other.Requests
+	t.PromptTokens += other.PromptTokens
+	t.CompletionTokens += other.CompletionTokens
+	t.CachedTokens += other.CachedTokens
+	t.TotalTokens += other.TotalTokens
+}
+
+// startOfDay 返回 t 所在的 UTC 日期
+func startOfDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,121 @@
+package usage
+
+import (
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+type mockRepository struct {
+	mock.Mock
+}
+
+func (m *mockRepository) Add(usage *Usage) error {
+	args := m.Called(usage)
+	return args.Error(0)
+}
+
+func (m *mockRepository) SumTokens(userID uint, from, to time.Time) (int64, error) {
+	args := m.Called(userID, from, to)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+func (m *mockRepository) ListDaily(userID uint, from, to time.Time) ([]*Usage, error) {
+	args := m.Called(userID, from, to)
+	return args.Get(0).([]*Usage), args.Error(1)
+}
+
+func (m *mockRepository) ListUserTotals(from, to time.Time) ([]*UserTotals, error) {
+	args := m.Called(from, to)
+	return args.Get(0).([]*UserTotals), args.Error(1)
+}
+
+var (
+	testNow      = time.Date(2025, 1, 15, 23, 30, 0, 0, time.UTC)
+	testToday    = time.Date(2025, 1, 15, 0, 0, 0, 0, time.UTC)
+	testTomorrow = time.Date(2025, 1, 16, 0, 0, 0, 0, time.UTC)
+	testMonth    = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+)
+
+func newTestService(repo Repository, quota *config.QuotaConfig) *service {
+	s := NewService(repo, quota).(*service)
+	s.now = func() time.Time { return testNow }
+	return s
+}
+
+// TestService_CheckQuota 测试今天或本月的用量达到上限时拒绝，上限为 0 时不限制
+func TestService_CheckQuota(t *testing.T) {
+	tests := []struct {
+		name    string
+		quota   *config.QuotaConfig
+		daily   int64
+		monthly int64
+		wantErr error
+	}{
+		{name: "未超出", quota: &config.QuotaConfig{DailyTokens: 1000, MonthlyTokens: 10000}, daily: 999, monthly: 5000},
+		{name: "今日额度用完", quota: &config.QuotaConfig{DailyTokens: 1000, MonthlyTokens: 10000}, daily: 1000, monthly: 5000, wantErr: ErrDailyQuotaExceeded},
+		{name: "本月额度用完", quota: &config.QuotaConfig{DailyTokens: 1000, MonthlyTokens: 10000}, daily: 10, monthly: 10000, wantErr: ErrMonthlyQuotaExceeded},
+		{name: "不限制", daily: 1 << 40, monthly: 1 << 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := new(mockRepository)
+			repo.On("SumTokens", uint(7), testToday, testTomorrow).Return(tt.daily, nil)
+			repo.On("SumTokens", uint(7), testMonth, testTomorrow).Return(tt.monthly, nil)
+
+			err := newTestService(repo, tt.quota).CheckQuota(7)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+// TestService_Record 测试用量记录在当天（UTC）
+func TestService_Record(t *testing.T) {
+	repo := new(mockRepository)
+	repo.On("Add", mock.MatchedBy(func(u *Usage) bool {
+		return u.UserID == 7 && u.Day.Equal(testToday) && u.SessionID == "s1" &&
+			u.Provider == "claude" && u.Model == "claude-sonnet-4-5" && u.TotalTokens == 120
+	})).Return(nil)
+
+	err := newTestService(repo, nil).Record(7, "s1", "claude", "claude-sonnet-4-5", Totals{Requests: 1, PromptTokens: 100, CompletionTokens: 20, TotalTokens: 120})
+
+	assert.NoError(t, err)
+	repo.AssertExpectations(t)
+}
+
+// TestService_GetUserUsage 测试默认查询本月的用量并汇总，日期范围无效时返回错误
+func TestService_GetUserUsage(t *testing.T) {
+	repo := new(mockRepository)
+	repo.On("ListDaily", uint(7), testMonth, testTomorrow).Return([]*Usage{
+		{Day: testMonth, Provider: "deepseek", Model: "deepseek-chat", Totals: Totals{Requests: 2, TotalTokens: 300}},
+		{Day: testToday, Provider: "claude", Model: "claude-sonnet-4-5", Totals: Totals{Requests: 1, TotalTokens: 200}},
+	}, nil)
+	repo.On("SumTokens", uint(7), testToday, testTomorrow).Return(int64(200), nil)
+	repo.On("SumTokens", uint(7), testMonth, testTomorrow).Return(int64(500), nil)
+	s := newTestService(repo, &config.QuotaConfig{DailyTokens: 1000})
+
+	resp, err := s.GetUserUsage(7, &UsageRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, "2025-01-01", resp.From)
+	assert.Equal(t, "2025-01-15", resp.To)
+	require.Len(t, resp.Daily, 2)
+	assert.Equal(t, "2025-01-15", resp.Daily[1].Day)
+	assert.Equal(t, Totals{Requests: 3, TotalTokens: 500}, resp.Total)
+	assert.Equal(t, QuotaStatus{DailyLimit: 1000, DailyUsed: 200, MonthlyUsed: 500}, resp.Quota)
+
+	_, err = s.GetUserUsage(7, &UsageRequest{From: "2025-02-01", To: "2025-01-01"})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = s.GetUserUsage(7, &UsageRequest{From: "2023-01-01", To: "2025-01-01"})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+	_, err = s.GetUserUsage(7, &UsageRequest{From: "yesterday"})
+	assert.ErrorIs(t, err, ErrInvalidInput)
+}
@@ -38,6 +38,22 @@ type UserProfile struct {
 
 	UserID                 uint   `json:"user_id" gorm:"not null;uniqueIndex"`
 	PreferredCharacterCode string `json:"preferred_character_code" gorm:"size:50"` // 偏好角色代号
+	Timezone               string `json:"timezone" gorm:"size:64"`                 // IANA 时区名，为空表示 UTC
+	DailyDigest            bool   `json:"daily_digest" gorm:"default:false"`       // 是否接收每日日程摘要邮件
+	DigestHour             int    `json:"digest_hour" gorm:"default:18"`           // 发送每日摘要的本地时间（小时）
+	LLMProvider            string `json:"llm_provider" gorm:"size:50"`             // 偏好的 LLM 提供方（llm.providers 中的名称），为空表示使用 Agent 的提供方
+}
+
+// Location 返回用户配置的时区，未设置或无效时返回 UTC
+func (p *UserProfile) Location() *time.Location {
+	if p == nil || p.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
 }
 
 func (UserProfile) TableName() string {
@@ -3,6 +3,8 @@ package user
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"time"
 
 	"github.com/galilio/otter/internal/common/utils"
 )
@@ -14,6 +16,9 @@ var (
 	ErrInvalidPassword   = errors.New("密码错误")
 )
 
+// defaultDigestHour 每日摘要默认在本地时间 18 点发送
+const defaultDigestHour = 18
+
 type Service interface {
 	CreateUser(req *CreateUserRequest) (*User, error)
 	GetUserByID(id uint) (*User, error)
@@ -51,6 +56,10 @@ type UpdateUserRequest struct {
 
 type UpdateUserProfileRequest struct {
 	PreferredCharacterCode *string `json:"preferred_character_code" binding:"omitempty,max=50"`
+	Timezone               *string `json:"timezone" binding:"omitempty,max=64"`
+	DailyDigest            *bool   `json:"daily_digest"`
+	DigestHour             *int    `json:"digest_hour" binding:"omitempty,min=0,max=23"`
+	LLMProvider            *string `json:"llm_provider" binding:"omitempty,max=50"` // 空字符串表示恢复使用 Agent 的提供方
 }
 
 type UserListResponse struct {
@@ -62,11 +71,26 @@ type UserListResponse struct {
 }
 
 type service struct {
-	repo Repository
+	repo         Repository
+	llmProviders []string
+}
+
+// ServiceOption 用户服务选项
+type ServiceOption func(*service)
+
+// WithLLMProviders 设置可选的 LLM 提供方，用户配置只能选择其中之一；未设置时不检查
+func WithLLMProviders(names ...string) ServiceOption {
+	return func(s *service) {
+		s.llmProviders = names
+	}
 }
 
-func NewService(repo Repository) Service {
-	return &service{repo: repo}
+func NewService(repo Repository, opts ...ServiceOption) Service {
+	s := &service{repo: repo}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
 func (s *service) CreateUser(req *CreateUserRequest) (*User, error) {
@@ -223,6 +247,7 @@ func (s *service) GetUserProfile(userID uint) (*UserProfile, error) {
 		return &UserProfile{
 			UserID:                 userID,
 			PreferredCharacterCode: "",
+			DigestHour:             defaultDigestHour,
 		}, nil
 	}
 
@@ -244,6 +269,7 @@ func (s *service) UpdateUserProfile(userID uint, req *UpdateUserProfileRequest)
 		profile = &UserProfile{
 			UserID:                 userID,
 			PreferredCharacterCode: "",
+			DigestHour:             defaultDigestHour,
 		}
 	}
 
@@ -251,6 +277,24 @@ func (s *service) UpdateUserProfile(userID uint, req *UpdateUserProfileRequest)
 	if req.PreferredCharacterCode != nil {
 		profile.PreferredCharacterCode = *req.PreferredCharacterCode
 	}
+	if req.Timezone != nil {
+		if _, err := time.LoadLocation(*req.Timezone); err != nil {
+			return nil, fmt.Errorf("%w: 无效的时区 %q", ErrInvalidInput, *req.Timezone)
+		}
+		profile.Timezone = *req.Timezone
+	}
+	if req.DailyDigest != nil {
+		profile.DailyDigest = *req.DailyDigest
+	}
+	if req.DigestHour != nil {
+		profile.DigestHour = *req.DigestHour
+	}
+	if req.LLMProvider != nil {
+		if *req.LLMProvider != "" && s.llmProviders != nil && !slices.Contains(s.llmProviders, *req.LLMProvider) {
+			return nil, fmt.Errorf("%w: 无效的 LLM 提供方 %q", ErrInvalidInput, *req.LLMProvider)
+		}
+		profile.LLMProvider = *req.LLMProvider
+	}
 
 	// 保存配置
 	if err := s.repo.CreateOrUpdateProfile(profile); err != nil {
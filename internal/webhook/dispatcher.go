@@ -0,0 +1,269 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/galilio/otter/internal/common/worker"
+	"gorm.io/gorm"
+)
+
+// ErrForbiddenAddress 目标地址为内网或本机地址
+var ErrForbiddenAddress = errors.New("不允许投递到内网或本机地址")
+
+// maxResponseBody 投递记录中保存的响应内容上限
+const maxResponseBody = 1 << 10
+
+// Dispatcher Webhook 投递器
+// 每次检查逐个锁定到期的投递记录并 POST 到 Webhook 地址；失败时按指数退避重试，
+// 重试次数用尽后放弃。Webhook 连续失败达到上限时被自动停用。
+// 投递记录保存在数据库中，服务重启后继续；多个实例同时运行时通过行锁保证同一次投递只发送一次
+type Dispatcher struct {
+	repo   Repository
+	client *http.Client
+
+	interval             time.Duration
+	batchSize            int
+	timeout              time.Duration
+	maxAttempts          int
+	retry                worker.Backoff
+	disableAfter         int
+	allowPrivateNetworks bool
+	now                  func() time.Time
+}
+
+// Option 投递器选项函数
+type Option func(*Dispatcher)
+
+// WithConfig 使用配置文件中的投递设置
+func WithConfig(cfg *config.WebhookConfig) Option {
+	return func(d *Dispatcher) {
+		if cfg.Interval > 0 {
+			d.interval = cfg.Interval
+		}
+		if cfg.BatchSize > 0 {
+			d.batchSize = cfg.BatchSize
+		}
+		if cfg.Timeout > 0 {
+			d.timeout = cfg.Timeout
+		}
+		if cfg.MaxAttempts > 0 {
+			d.maxAttempts = cfg.MaxAttempts
+		}
+		if cfg.RetryBaseDelay > 0 {
+			d.retry.Base = cfg.RetryBaseDelay
+		}
+		if cfg.RetryMaxDelay > 0 {
+			d.retry.Max = cfg.RetryMaxDelay
+		}
+		if cfg.DisableAfter > 0 {
+			d.disableAfter = cfg.DisableAfter
+		}
+		d.allowPrivateNetworks = cfg.AllowPrivateNetworks
+	}
+}
+
+// WithHTTPClient 设置投递使用的 HTTP 客户端（不再限制目标地址）
+func WithHTTPClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// NewDispatcher 创建 Webhook 投递器
+func NewDispatcher(repo Repository, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		repo:         repo,
+		interval:     5 * time.Second,
+		batchSize:    50,
+		timeout:      10 * time.Second,
+		maxAttempts:  8,
+		retry:        worker.Backoff{Base: 30 * time.Second, Max: 6 * time.Hour},
+		disableAfter: 20,
+		now:          time.Now,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.client == nil {
+		d.client = newHTTPClient(d.timeout, d.allowPrivateNetworks)
+	}
+	return d
+}
+
+// newHTTPClient 创建投递使用的 HTTP 客户端：不跟随重定向、不使用代理，
+// 并在建立连接时检查解析后的地址，防止通过 Webhook 访问内网服务
+func newHTTPClient(timeout time.Duration, allowPrivateNetworks bool) *http.Client {
+	dialer := &net.Dialer{Timeout: timeout}
+	if !allowPrivateNetworks {
+		dialer.Control = func(network, address string, _ syscall.RawConn) error {
+			host, _, err := net.SplitHostPort(address)
+			if err != nil {
+				return err
+			}
+			if ip := net.ParseIP(host); ip == nil || isPrivateIP(ip) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, host)
+			}
+			return nil
+		}
+	}
+	return &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+		},
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// carrierGradeNAT 运营商级 NAT 地址段（RFC 6598）
+var carrierGradeNAT = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+// isPrivateIP 检查是否为本机、内网、链路本地或组播地址
+func isPrivateIP(ip net.IP) bool {
+	return ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+		ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || carrierGradeNAT.Contains(ip)
+}
+
+// Run 定期检查并投递事件，直到 ctx 被取消
+func (d *Dispatcher) Run(ctx context.Context) error {
+	return worker.Run(ctx, d.interval, "Webhook 投递失败", d.Tick)
+}
+
+// Tick 执行一次检查：逐个锁定并投递到期的事件
+func (d *Dispatcher) Tick(ctx context.Context) error {
+	err := worker.Drain(d.batchSize, func() (bool, error) {
+		found := false
+		err := d.repo.Transaction(func(repo Repository) error {
+			delivery, err := repo.LockDueDelivery(d.now())
+			if err != nil || delivery == nil {
+				return err
+			}
+			found = true
+			return d.deliver(ctx, repo, delivery)
+		})
+		return found, err
+	})
+	if err != nil {
+		return fmt.Errorf("投递 Webhook 事件失败: %w", err)
+	}
+	return nil
+}
+
+// deliver 投递一个已锁定的事件并记录结果
+func (d *Dispatcher) deliver(ctx context.Context, repo Repository, delivery *WebhookDelivery) error {
+	webhook, err := repo.GetWebhookByID(delivery.WebhookID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return d.abandon(repo, delivery, "Webhook 已删除")
+	}
+	if err != nil {
+		return err
+	}
+	if !webhook.Enabled {
+		return d.abandon(repo, delivery, "Webhook 已停用")
+	}
+
+	now := d.now()
+	status, body, elapsed, err := d.post(ctx, webhook, delivery, now)
+	delivery.Attempts++
+	delivery.LastAttemptAt = &now
+	delivery.DurationMs = &elapsed
+	delivery.ResponseStatus = status
+	delivery.ResponseBody = body
+
+	if err == nil && status != nil && *status >= 200 && *status < 300 {
+		delivery.Status = DeliveryStatusSucceeded
+		delivery.DeliveredAt = &now
+		delivery.NextAttemptAt = nil
+		delivery.LastError = nil
+		if webhook.ConsecutiveFailures > 0 {
+			if err := repo.ResetWebhookFailures(webhook.ID); err != nil {
+				return err
+			}
+		}
+		return repo.UpdateDelivery(delivery)
+	}
+
+	msg := ""
+	if err != nil {
+		msg = err.Error()
+	} else {
+		msg = fmt.Sprintf("HTTP %d", *status)
+	}
+	delivery.LastError = &msg
+
+	reason := fmt.Sprintf("连续 %d 次投递失败，最后一次: %s", d.disableAfter, msg)
+	disabled, err := repo.RecordWebhookFailure(webhook.ID, d.disableAfter, now, reason)
+	if err != nil {
+		return err
+	}
+	if disabled {
+		slog.WarnContext(ctx, "Webhook 连续投递失败，已停用", "webhook_id", webhook.ID, "user_id", webhook.UserID)
+	}
+
+	if disabled || delivery.Attempts >= d.maxAttempts {
+		delivery.Status = DeliveryStatusFailed
+		delivery.NextAttemptAt = nil
+		slog.WarnContext(ctx, "Webhook 投递失败，放弃本次投递", "webhook_id", webhook.ID, "delivery_id", delivery.ID, "attempts", delivery.Attempts, "error", msg)
+		return repo.UpdateDelivery(delivery)
+	}
+
+	next := now.Add(d.retry.Delay(delivery.Attempts))
+	delivery.NextAttemptAt = &next
+	slog.DebugContext(ctx, "Webhook 投递失败，稍后重试", "webhook_id", webhook.ID, "delivery_id", delivery.ID, "attempts", delivery.Attempts, "next_attempt_at", next, "error", msg)
+	return repo.UpdateDelivery(delivery)
+}
+
+// post 发送签名的请求，返回状态码、截断的响应内容和耗时（毫秒）
+func (d *Dispatcher) post(ctx context.Context, webhook *Webhook, delivery *WebhookDelivery, now time.Time) (*int, *string, int64, error) {
+	body := []byte(delivery.Payload)
+	timestamp := now.Unix()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(body))
+	if err != nil {
+		return nil, nil, 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "Otter-Webhook/1.0")
+	req.Header.Set("X-Otter-Event", delivery.EventType)
+	req.Header.Set("X-Otter-Event-Id", delivery.EventID)
+	req.Header.Set("X-Otter-Delivery", strconv.FormatUint(uint64(delivery.ID), 10))
+	req.Header.Set("X-Otter-Timestamp", strconv.FormatInt(timestamp, 10))
+	req.Header.Set("X-Otter-Signature", Sign(webhook.Secret, timestamp, body))
+
+	start := time.Now()
+	resp, err := d.client.Do(req)
+	elapsed := time.Since(start).Milliseconds()
+	if err != nil {
+		return nil, nil, elapsed, err
+	}
+	defer resp.Body.Close()
+
+	respBody, _ := io.ReadAll(io.LimitReader(resp.Body, maxResponseBody))
+	status := resp.StatusCode
+	text := string(respBody)
+	return &status, &text, elapsed, nil
+}
+
+// abandon 不再投递：Webhook 已删除或已停用
+func (d *Dispatcher) abandon(repo Repository, delivery *WebhookDelivery, reason string) error {
+	delivery.Status = DeliveryStatusFailed
+	delivery.NextAttemptAt = nil
+	delivery.LastError = &reason
+	return repo.UpdateDelivery(delivery)
+}
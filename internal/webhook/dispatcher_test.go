@@ -0,0 +1,191 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/galilio/otter/internal/common/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+)
+
+// newTestDispatcher 创建使用固定时间的投递器
+func newTestDispatcher(repo Repository, now time.Time) *Dispatcher {
+	d := NewDispatcher(repo, WithHTTPClient(http.DefaultClient))
+	d.now = func() time.Time { return now }
+	return d
+}
+
+func pendingDelivery(webhookID uint, now time.Time) *WebhookDelivery {
+	return &WebhookDelivery{
+		ID: 5, WebhookID: webhookID, EventID: "evt-1", EventType: "calendar_item.created",
+		Payload: `{"id":"evt-1"}`, Status: DeliveryStatusPending, NextAttemptAt: &now,
+	}
+}
+
+// TestDispatcher_Tick_Success 测试投递签名的请求并记录结果
+func TestDispatcher_Tick_Success(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	var received *http.Request
+	var body []byte
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received = r
+		body, _ = io.ReadAll(r.Body)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	repo := new(mockRepository)
+	webhook := &Webhook{ID: 1, URL: server.URL, Secret: "secret", Enabled: true, ConsecutiveFailures: 3}
+	delivery := pendingDelivery(1, now)
+	repo.On("LockDueDelivery", now).Return(delivery, nil).Once()
+	repo.On("LockDueDelivery", now).Return(nil, nil).Once()
+	repo.On("GetWebhookByID", uint(1)).Return(webhook, nil)
+	repo.On("ResetWebhookFailures", uint(1)).Return(nil)
+	repo.On("UpdateDelivery", delivery).Return(nil)
+
+	require.NoError(t, newTestDispatcher(repo, now).Tick(context.Background()))
+
+	require.NotNil(t, received)
+	assert.Equal(t, `{"id":"evt-1"}`, string(body))
+	assert.Equal(t, "calendar_item.created", received.Header.Get("X-Otter-Event"))
+	assert.Equal(t, "evt-1", received.Header.Get("X-Otter-Event-Id"))
+	assert.Equal(t, "5", received.Header.Get("X-Otter-Delivery"))
+	assert.Equal(t, strconv.FormatInt(now.Unix(), 10), received.Header.Get("X-Otter-Timestamp"))
+	assert.Equal(t, Sign("secret", now.Unix(), body), received.Header.Get("X-Otter-Signature"))
+
+	assert.Equal(t, DeliveryStatusSucceeded, delivery.Status)
+	assert.Equal(t, 1, delivery.Attempts)
+	assert.Equal(t, http.StatusNoContent, *delivery.ResponseStatus)
+	assert.Nil(t, delivery.NextAttemptAt)
+	assert.Equal(t, now, *delivery.DeliveredAt)
+	repo.AssertExpectations(t)
+}
+
+// TestDispatcher_Tick_Retry 测试投递失败时按指数退避重试
+func TestDispatcher_Tick_Retry(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	repo := new(mockRepository)
+	delivery := pendingDelivery(1, now)
+	delivery.Attempts = 2
+	repo.On("LockDueDelivery", now).Return(delivery, nil).Once()
+	repo.On("LockDueDelivery", now).Return(nil, nil).Once()
+	repo.On("GetWebhookByID", uint(1)).Return(&Webhook{ID: 1, URL: server.URL, Enabled: true}, nil)
+	repo.On("RecordWebhookFailure", uint(1), 20, now, mock.Anything).Return(false, nil)
+	repo.On("UpdateDelivery", delivery).Return(nil)
+
+	require.NoError(t, newTestDispatcher(repo, now).Tick(context.Background()))
+
+	assert.Equal(t, DeliveryStatusPending, delivery.Status)
+	assert.Equal(t, 3, delivery.Attempts)
+	assert.Equal(t, "HTTP 500", *delivery.LastError)
+	assert.Equal(t, "boom\n", *delivery.ResponseBody)
+	// 第三次失败后等待 30s * 2^2
+	assert.Equal(t, now.Add(2*time.Minute), *delivery.NextAttemptAt)
+}
+
+// TestDispatcher_Tick_GiveUp 测试重试次数用尽后放弃
+func TestDispatcher_Tick_GiveUp(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	defer server.Close()
+
+	repo := new(mockRepository)
+	delivery := pendingDelivery(1, now)
+	delivery.Attempts = 7
+	repo.On("LockDueDelivery", now).Return(delivery, nil).Once()
+	repo.On("LockDueDelivery", now).Return(nil, nil).Once()
+	repo.On("GetWebhookByID", uint(1)).Return(&Webhook{ID: 1, URL: server.URL, Enabled: true}, nil)
+	repo.On("RecordWebhookFailure", uint(1), 20, now, mock.Anything).Return(false, nil)
+	repo.On("UpdateDelivery", delivery).Return(nil)
+
+	require.NoError(t, newTestDispatcher(repo, now).Tick(context.Background()))
+
+	assert.Equal(t, DeliveryStatusFailed, delivery.Status)
+	assert.Equal(t, 8, delivery.Attempts)
+	assert.Nil(t, delivery.NextAttemptAt)
+}
+
+// TestDispatcher_Tick_Disabled 测试连续失败达到上限时停用 Webhook 并放弃投递
+func TestDispatcher_Tick_Disabled(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	repo := new(mockRepository)
+	delivery := pendingDelivery(1, now)
+	repo.On("LockDueDelivery", now).Return(delivery, nil).Once()
+	repo.On("LockDueDelivery", now).Return(nil, nil).Once()
+	repo.On("GetWebhookByID", uint(1)).Return(&Webhook{ID: 1, URL: server.URL, Enabled: true, ConsecutiveFailures: 19}, nil)
+	repo.On("RecordWebhookFailure", uint(1), 20, now, "连续 20 次投递失败，最后一次: HTTP 404").Return(true, nil)
+	repo.On("UpdateDelivery", delivery).Return(nil)
+
+	require.NoError(t, newTestDispatcher(repo, now).Tick(context.Background()))
+
+	assert.Equal(t, DeliveryStatusFailed, delivery.Status)
+	assert.Nil(t, delivery.NextAttemptAt)
+	repo.AssertExpectations(t)
+}
+
+// TestDispatcher_Tick_WebhookDeleted 测试 Webhook 已删除或已停用时不再投递
+func TestDispatcher_Tick_WebhookDeleted(t *testing.T) {
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+	repo := new(mockRepository)
+	deleted, disabled := pendingDelivery(1, now), pendingDelivery(2, now)
+	repo.On("LockDueDelivery", now).Return(deleted, nil).Once()
+	repo.On("LockDueDelivery", now).Return(disabled, nil).Once()
+	repo.On("LockDueDelivery", now).Return(nil, nil).Once()
+	repo.On("GetWebhookByID", uint(1)).Return(nil, gorm.ErrRecordNotFound)
+	repo.On("GetWebhookByID", uint(2)).Return(&Webhook{ID: 2, Enabled: false}, nil)
+	repo.On("UpdateDelivery", mock.Anything).Return(nil)
+
+	require.NoError(t, newTestDispatcher(repo, now).Tick(context.Background()))
+
+	assert.Equal(t, DeliveryStatusFailed, deleted.Status)
+	assert.Equal(t, "Webhook 已删除", *deleted.LastError)
+	assert.Equal(t, DeliveryStatusFailed, disabled.Status)
+	assert.Equal(t, "Webhook 已停用", *disabled.LastError)
+}
+
+// TestDispatcher_PrivateNetwork 测试默认不允许投递到本机地址
+func TestDispatcher_PrivateNetwork(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	d := NewDispatcher(new(mockRepository))
+	_, _, _, err := d.post(context.Background(), &Webhook{URL: server.URL}, pendingDelivery(1, time.Now()), time.Now())
+	assert.ErrorIs(t, err, ErrForbiddenAddress)
+
+	d = NewDispatcher(new(mockRepository), WithConfig(&config.WebhookConfig{AllowPrivateNetworks: true}))
+	status, _, _, err := d.post(context.Background(), &Webhook{URL: server.URL}, pendingDelivery(1, time.Now()), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, *status)
+}
+
+// TestDispatcher_Backoff 测试重试等待时间翻倍且不超过上限
+func TestDispatcher_Backoff(t *testing.T) {
+	d := NewDispatcher(new(mockRepository))
+
+	assert.Equal(t, 30*time.Second, d.retry.Delay(1))
+	assert.Equal(t, time.Minute, d.retry.Delay(2))
+	assert.Equal(t, 4*time.Minute, d.retry.Delay(4))
+	assert.Equal(t, 6*time.Hour, d.retry.Delay(20))
+}
@@ -0,0 +1,172 @@
+package webhook
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/galilio/otter/internal/common/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+type Handler struct {
+	service Service
+}
+
+func NewHandler(service Service) *Handler {
+	return &Handler{service: service}
+}
+
+type ErrorResponse struct {
+	Error string `json:"error"`
+}
+
+// CreateWebhook 创建 Webhook
+// POST /api/v1/webhooks
+func (h *Handler) CreateWebhook(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	var req CreateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := h.service.CreateWebhook(*userID, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusCreated, result)
+}
+
+// ListWebhooks 列出 Webhook
+// GET /api/v1/webhooks
+func (h *Handler) ListWebhooks(c *gin.Context) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return
+	}
+
+	webhooks, err := h.service.ListWebhooks(*userID)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhooks)
+}
+
+// GetWebhook 获取 Webhook
+// GET /api/v1/webhooks/:id
+func (h *Handler) GetWebhook(c *gin.Context) {
+	userID, id, ok := parseWebhookPath(c)
+	if !ok {
+		return
+	}
+
+	webhook, err := h.service.GetWebhook(userID, id)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// UpdateWebhook 更新 Webhook，可用于重新启用被自动停用的 Webhook
+// PUT /api/v1/webhooks/:id
+func (h *Handler) UpdateWebhook(c *gin.Context) {
+	userID, id, ok := parseWebhookPath(c)
+	if !ok {
+		return
+	}
+
+	var req UpdateWebhookRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	webhook, err := h.service.UpdateWebhook(userID, id, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook)
+}
+
+// DeleteWebhook 删除 Webhook
+// DELETE /api/v1/webhooks/:id
+func (h *Handler) DeleteWebhook(c *gin.Context) {
+	userID, id, ok := parseWebhookPath(c)
+	if !ok {
+		return
+	}
+
+	if err := h.service.DeleteWebhook(userID, id); err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Webhook 已删除"})
+}
+
+// ListDeliveries 列出 Webhook 的投递记录
+// GET /api/v1/webhooks/:id/deliveries
+func (h *Handler) ListDeliveries(c *gin.Context) {
+	userID, id, ok := parseWebhookPath(c)
+	if !ok {
+		return
+	}
+
+	var req ListDeliveriesRequest
+	if err := c.ShouldBindQuery(&req); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	result, err := h.service.ListDeliveries(userID, id, &req)
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// parseWebhookPath 获取当前用户和路径中的 Webhook ID，失败时已写入响应
+func parseWebhookPath(c *gin.Context) (uint, uint, bool) {
+	userID, err := middleware.GetUserIDFromContext(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "未认证"})
+		return 0, 0, false
+	}
+
+	id, err := strconv.ParseUint(c.Param("id"), 10, 32)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "无效的ID"})
+		return 0, 0, false
+	}
+
+	return *userID, uint(id), true
+}
+
+// writeError 将服务层错误转换为 HTTP 响应
+func writeError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+	case errors.Is(err, ErrInvalidInput):
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+	}
+}
@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"gorm.io/gorm"
+)
+
+// DeliveryStatus 事件投递状态
+type DeliveryStatus string
+
+const (
+	DeliveryStatusPending   DeliveryStatus = "pending"   // 等待投递或等待重试
+	DeliveryStatusSucceeded DeliveryStatus = "succeeded" // 已投递（对方返回 2xx）
+	DeliveryStatusFailed    DeliveryStatus = "failed"    // 重试次数用尽或 Webhook 已停用，不再投递
+)
+
+// Webhook 用户注册的 Webhook 订阅
+// 订阅的日历项事件发生后，以 JSON 格式 POST 到 URL，并用 Secret 做 HMAC-SHA256 签名
+type Webhook struct {
+	ID        uint           `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time      `json:"created_at"`
+	UpdatedAt time.Time      `json:"updated_at"`
+	DeletedAt gorm.DeletedAt `json:"-" gorm:"index"`
+
+	UserID      uint                 `json:"user_id" gorm:"not null;index"`
+	URL         string               `json:"url" gorm:"not null;size:1000"`
+	Events      calendar.StringArray `json:"events" gorm:"type:jsonb"` // 订阅的事件类型
+	Description *string              `json:"description" gorm:"size:255"`
+	Secret      string               `json:"-" gorm:"not null;size:255"` // 签名密钥，只在创建时返回
+	Enabled     bool                 `json:"enabled" gorm:"not null;default:true"`
+
+	ConsecutiveFailures int        `json:"consecutive_failures" gorm:"not null;default:0"` // 连续投递失败次数，投递成功后清零
+	DisabledAt          *time.Time `json:"disabled_at"`                                    // 因连续失败被自动停用的时间
+	DisabledReason      *string    `json:"disabled_reason" gorm:"size:500"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+// Subscribes 检查 Webhook 是否订阅了某种事件
+func (w *Webhook) Subscribes(eventType calendar.EventType) bool {
+	for _, e := range w.Events {
+		if e == string(eventType) {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookDelivery 一个事件对一个 Webhook 的投递记录，同时作为持久化的投递队列
+type WebhookDelivery struct {
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+
+	WebhookID     uint           `json:"webhook_id" gorm:"not null;index"`
+	EventID       string         `json:"event_id" gorm:"not null;size:64"` // 同一事件投递到多个 Webhook 时相同，接收方可用于去重
+	EventType     string         `json:"event_type" gorm:"not null;size:50"`
+	Payload       string         `json:"payload" gorm:"type:text;not null"`
+	Status        DeliveryStatus `json:"status" gorm:"not null;type:varchar(20);index"`
+	Attempts      int            `json:"attempts" gorm:"not null;default:0"`
+	NextAttemptAt *time.Time     `json:"next_attempt_at" gorm:"index"` // 下一次尝试投递的时间，不再投递时为空
+	LastAttemptAt *time.Time     `json:"last_attempt_at"`
+	DeliveredAt   *time.Time     `json:"delivered_at"`
+
+	ResponseStatus *int    `json:"response_status"`                // 最后一次投递的 HTTP 状态码
+	ResponseBody   *string `json:"response_body" gorm:"type:text"` // 最后一次投递的响应（截断）
+	LastError      *string `json:"last_error" gorm:"type:text"`    // 最后一次投递的错误
+	DurationMs     *int64  `json:"duration_ms"`                    // 最后一次投递的耗时
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
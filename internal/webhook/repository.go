@@ -0,0 +1,167 @@
+package webhook
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Repository Webhook 仓库
+// LockDueDelivery 使用 SELECT ... FOR UPDATE SKIP LOCKED，只能在 Transaction 中调用；
+// 多个实例同时运行时，被其它实例锁定的投递会被跳过，保证同一次投递只发送一次
+type Repository interface {
+	Transaction(fn func(repo Repository) error) error
+
+	// Webhook 相关方法
+	CreateWebhook(webhook *Webhook) error
+	GetWebhook(userID uint, id uint) (*Webhook, error)
+	GetWebhookByID(id uint) (*Webhook, error)
+	ListWebhooks(userID uint) ([]*Webhook, error)
+	ListEnabledWebhooks(userID uint) ([]*Webhook, error)
+	UpdateWebhook(webhook *Webhook) error
+	DeleteWebhook(userID uint, id uint) error
+	ResetWebhookFailures(id uint) error
+	RecordWebhookFailure(id uint, disableAfter int, now time.Time, reason string) (bool, error)
+
+	// 投递相关方法
+	CreateDeliveries(deliveries []*WebhookDelivery) error
+	ListDeliveries(webhookID uint, offset, limit int) ([]*WebhookDelivery, int64, error)
+	LockDueDelivery(now time.Time) (*WebhookDelivery, error)
+	UpdateDelivery(delivery *WebhookDelivery) error
+}
+
+type repository struct {
+	db *gorm.DB
+}
+
+// NewRepository 创建 Webhook 仓库
+func NewRepository(db *gorm.DB) Repository {
+	return &repository{db: db}
+}
+
+// Transaction 在事务中执行 fn，fn 的参数是绑定到该事务的仓库
+func (r *repository) Transaction(fn func(repo Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&repository{db: tx})
+	})
+}
+
+// CreateWebhook 创建 Webhook
+func (r *repository) CreateWebhook(webhook *Webhook) error {
+	return r.db.Create(webhook).Error
+}
+
+// GetWebhook 获取用户的 Webhook
+func (r *repository) GetWebhook(userID uint, id uint) (*Webhook, error) {
+	var webhook Webhook
+	if err := r.db.Where("user_id = ?", userID).First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// GetWebhookByID 根据ID获取 Webhook（不限用户，供投递使用）
+func (r *repository) GetWebhookByID(id uint) (*Webhook, error) {
+	var webhook Webhook
+	if err := r.db.First(&webhook, id).Error; err != nil {
+		return nil, err
+	}
+	return &webhook, nil
+}
+
+// ListWebhooks 列出用户的 Webhook
+func (r *repository) ListWebhooks(userID uint) ([]*Webhook, error) {
+	var webhooks []*Webhook
+	err := r.db.Where("user_id = ?", userID).Order("id ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// ListEnabledWebhooks 列出用户已启用的 Webhook
+func (r *repository) ListEnabledWebhooks(userID uint) ([]*Webhook, error) {
+	var webhooks []*Webhook
+	err := r.db.Where("user_id = ? AND enabled = ?", userID, true).Order("id ASC").Find(&webhooks).Error
+	return webhooks, err
+}
+
+// UpdateWebhook 更新 Webhook
+func (r *repository) UpdateWebhook(webhook *Webhook) error {
+	return r.db.Save(webhook).Error
+}
+
+// DeleteWebhook 删除用户的 Webhook（软删除），不存在时返回 gorm.ErrRecordNotFound
+func (r *repository) DeleteWebhook(userID uint, id uint) error {
+	result := r.db.Where("user_id = ?", userID).Delete(&Webhook{}, id)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+// ResetWebhookFailures 投递成功后清零连续失败次数
+func (r *repository) ResetWebhookFailures(id uint) error {
+	return r.db.Model(&Webhook{}).
+		Where("id = ? AND consecutive_failures > 0", id).
+		Update("consecutive_failures", 0).Error
+}
+
+// RecordWebhookFailure 连续失败次数加一，达到 disableAfter 时停用 Webhook，返回是否因此停用
+// 使用原子更新，多个实例同时投递同一个 Webhook 时计数不会丢失
+func (r *repository) RecordWebhookFailure(id uint, disableAfter int, now time.Time, reason string) (bool, error) {
+	err := r.db.Model(&Webhook{}).
+		Where("id = ?", id).
+		Update("consecutive_failures", gorm.Expr("consecutive_failures + 1")).Error
+	if err != nil {
+		return false, err
+	}
+
+	result := r.db.Model(&Webhook{}).
+		Where("id = ? AND enabled = ? AND consecutive_failures >= ?", id, true, disableAfter).
+		Updates(map[string]any{"enabled": false, "disabled_at": now, "disabled_reason": reason})
+	return result.RowsAffected > 0, result.Error
+}
+
+// CreateDeliveries 批量创建投递记录
+func (r *repository) CreateDeliveries(deliveries []*WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	return r.db.Create(deliveries).Error
+}
+
+// ListDeliveries 分页列出 Webhook 的投递记录（最新的在前）
+func (r *repository) ListDeliveries(webhookID uint, offset, limit int) ([]*WebhookDelivery, int64, error) {
+	var deliveries []*WebhookDelivery
+	var total int64
+
+	query := r.db.Model(&WebhookDelivery{}).Where("webhook_id = ?", webhookID)
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := query.Order("id DESC").Offset(offset).Limit(limit).Find(&deliveries).Error; err != nil {
+		return nil, 0, err
+	}
+	return deliveries, total, nil
+}
+
+// LockDueDelivery 锁定一个已到投递时间的投递记录（最早的优先），没有时返回 nil
+func (r *repository) LockDueDelivery(now time.Time) (*WebhookDelivery, error) {
+	var deliveries []*WebhookDelivery
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+		Where("status = ? AND next_attempt_at <= ?", DeliveryStatusPending, now).
+		Order("next_attempt_at ASC").
+		Limit(1).
+		Find(&deliveries).Error
+	if err != nil || len(deliveries) == 0 {
+		return nil, err
+	}
+	return deliveries[0], nil
+}
+
+// UpdateDelivery 更新投递记录
+func (r *repository) UpdateDelivery(delivery *WebhookDelivery) error {
+	return r.db.Save(delivery).Error
+}
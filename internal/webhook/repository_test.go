@@ -0,0 +1,83 @@
+package webhook
+
+import (
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// setupTestDB 创建测试用的数据库连接（使用sqlmock）
+func setupTestDB(t *testing.T) (*gorm.DB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("创建sqlmock失败: %v", err)
+	}
+
+	gormDB, err := gorm.Open(postgres.New(postgres.Config{
+		Conn: db,
+	}), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("创建GORM连接失败: %v", err)
+	}
+
+	return gormDB, mock
+}
+
+// TestRepository_LockDueDelivery 测试锁定到期的投递记录时跳过已被锁定的行
+func TestRepository_LockDueDelivery(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectQuery(`SELECT \* FROM "webhook_deliveries" WHERE status = \$1 AND next_attempt_at <= \$2 ORDER BY next_attempt_at ASC LIMIT \$3 FOR UPDATE SKIP LOCKED`).
+		WithArgs(DeliveryStatusPending, now, 1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "webhook_id", "status"}).AddRow(5, 1, "pending"))
+
+	delivery, err := repo.LockDueDelivery(now)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(5), delivery.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_RecordWebhookFailure 测试连续失败次数达到上限时停用 Webhook
+func TestRepository_RecordWebhookFailure(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+	now := time.Date(2025, 1, 6, 9, 0, 0, 0, time.UTC)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "webhooks" SET "consecutive_failures"=consecutive_failures \+ 1,"updated_at"=\$1 WHERE id = \$2`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "webhooks" SET "disabled_at"=\$1,"disabled_reason"=\$2,"enabled"=\$3,"updated_at"=\$4 WHERE \(id = \$5 AND enabled = \$6 AND consecutive_failures >= \$7\)`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	disabled, err := repo.RecordWebhookFailure(1, 20, now, "连续失败")
+
+	assert.NoError(t, err)
+	assert.True(t, disabled)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+// TestRepository_DeleteWebhook_NotFound 测试删除其它用户的 Webhook
+func TestRepository_DeleteWebhook_NotFound(t *testing.T) {
+	db, mock := setupTestDB(t)
+	repo := NewRepository(db)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "webhooks" SET "deleted_at"=\$1 WHERE user_id = \$2 AND "webhooks"."id" = \$3`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+
+	err := repo.DeleteWebhook(2, 1)
+
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
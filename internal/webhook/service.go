@@ -0,0 +1,326 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/galilio/otter/internal/calendar"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+)
+
+var (
+	ErrWebhookNotFound = errors.New("Webhook 不存在")
+	ErrInvalidInput    = errors.New("输入参数无效")
+)
+
+// Service Webhook 服务，同时作为日历项事件的监听器，将事件写入投递队列
+type Service interface {
+	CreateWebhook(userID uint, req *CreateWebhookRequest) (*CreateWebhookResponse, error)
+	ListWebhooks(userID uint) ([]*Webhook, error)
+	GetWebhook(userID uint, id uint) (*Webhook, error)
+	UpdateWebhook(userID uint, id uint, req *UpdateWebhookRequest) (*Webhook, error)
+	DeleteWebhook(userID uint, id uint) error
+	ListDeliveries(userID uint, id uint, req *ListDeliveriesRequest) (*DeliveryListResponse, error)
+
+	calendar.EventListener
+}
+
+// CreateWebhookRequest 创建 Webhook 请求
+type CreateWebhookRequest struct {
+	URL         string   `json:"url" binding:"required,max=1000"`
+	Events      []string `json:"events" binding:"required,min=1"`
+	Secret      *string  `json:"secret" binding:"omitempty,min=16,max=255"` // 为空时自动生成
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+}
+
+// CreateWebhookResponse 创建 Webhook 响应，签名密钥只在此时返回
+type CreateWebhookResponse struct {
+	*Webhook
+	Secret string `json:"secret"`
+}
+
+// UpdateWebhookRequest 更新 Webhook 请求
+// 重新启用（enabled 为 true）时清零连续失败次数
+type UpdateWebhookRequest struct {
+	URL         *string  `json:"url" binding:"omitempty,max=1000"`
+	Events      []string `json:"events" binding:"omitempty,min=1"`
+	Secret      *string  `json:"secret" binding:"omitempty,min=16,max=255"`
+	Description *string  `json:"description" binding:"omitempty,max=255"`
+	Enabled     *bool    `json:"enabled"`
+}
+
+// ListDeliveriesRequest 列出投递记录请求
+type ListDeliveriesRequest struct {
+	Page     int `form:"page" binding:"omitempty,min=1"`
+	PageSize int `form:"page_size" binding:"omitempty,min=1,max=100"`
+}
+
+// DeliveryListResponse 投递记录列表响应
+type DeliveryListResponse struct {
+	Deliveries []*WebhookDelivery `json:"deliveries"`
+	Total      int64              `json:"total"`
+	Page       int                `json:"page"`
+	PageSize   int                `json:"page_size"`
+	TotalPages int                `json:"total_pages"`
+}
+
+// Payload 投递给 Webhook 的 JSON 内容
+type Payload struct {
+	ID        string             `json:"id"` // 事件ID，重试时不变
+	Type      calendar.EventType `json:"type"`
+	CreatedAt time.Time          `json:"created_at"`
+	Data      PayloadData        `json:"data"`
+}
+
+// PayloadData 事件数据：变更后的日历项（删除事件为删除前的日历项）
+type PayloadData struct {
+	Item *calendar.CalendarItem `json:"item"`
+}
+
+type service struct {
+	repo Repository
+	now  func() time.Time
+}
+
+// NewService 创建 Webhook 服务
+func NewService(repo Repository) Service {
+	return &service{repo: repo, now: time.Now}
+}
+
+// CreateWebhook 创建 Webhook
+func (s *service) CreateWebhook(userID uint, req *CreateWebhookRequest) (*CreateWebhookResponse, error) {
+	if err := validateURL(req.URL); err != nil {
+		return nil, err
+	}
+	if err := validateEvents(req.Events); err != nil {
+		return nil, err
+	}
+
+	secret := ""
+	if req.Secret != nil {
+		secret = *req.Secret
+	} else {
+		var err error
+		if secret, err = generateSecret(); err != nil {
+			return nil, fmt.Errorf("生成签名密钥失败: %w", err)
+		}
+	}
+
+	webhook := &Webhook{
+		UserID:      userID,
+		URL:         req.URL,
+		Events:      calendar.StringArray(req.Events),
+		Description: req.Description,
+		Secret:      secret,
+		Enabled:     true,
+	}
+	if err := s.repo.CreateWebhook(webhook); err != nil {
+		return nil, fmt.Errorf("创建 Webhook 失败: %w", err)
+	}
+
+	return &CreateWebhookResponse{Webhook: webhook, Secret: secret}, nil
+}
+
+// ListWebhooks 列出用户的 Webhook
+func (s *service) ListWebhooks(userID uint) ([]*Webhook, error) {
+	webhooks, err := s.repo.ListWebhooks(userID)
+	if err != nil {
+		return nil, fmt.Errorf("获取 Webhook 列表失败: %w", err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook 获取用户的 Webhook
+func (s *service) GetWebhook(userID uint, id uint) (*Webhook, error) {
+	webhook, err := s.repo.GetWebhook(userID, id)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+	return webhook, nil
+}
+
+// UpdateWebhook 更新 Webhook
+func (s *service) UpdateWebhook(userID uint, id uint, req *UpdateWebhookRequest) (*Webhook, error) {
+	webhook, err := s.repo.GetWebhook(userID, id)
+	if err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	if req.URL != nil {
+		if err := validateURL(*req.URL); err != nil {
+			return nil, err
+		}
+		webhook.URL = *req.URL
+	}
+	if req.Events != nil {
+		if err := validateEvents(req.Events); err != nil {
+			return nil, err
+		}
+		webhook.Events = calendar.StringArray(req.Events)
+	}
+	if req.Secret != nil {
+		webhook.Secret = *req.Secret
+	}
+	if req.Description != nil {
+		webhook.Description = req.Description
+	}
+	if req.Enabled != nil {
+		if *req.Enabled && !webhook.Enabled {
+			webhook.ConsecutiveFailures = 0
+			webhook.DisabledAt = nil
+			webhook.DisabledReason = nil
+		}
+		webhook.Enabled = *req.Enabled
+	}
+
+	if err := s.repo.UpdateWebhook(webhook); err != nil {
+		return nil, fmt.Errorf("更新 Webhook 失败: %w", err)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook 删除 Webhook，尚未投递的事件不再投递
+func (s *service) DeleteWebhook(userID uint, id uint) error {
+	if err := s.repo.DeleteWebhook(userID, id); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return ErrWebhookNotFound
+		}
+		return fmt.Errorf("删除 Webhook 失败: %w", err)
+	}
+	return nil
+}
+
+// ListDeliveries 分页列出 Webhook 的投递记录
+func (s *service) ListDeliveries(userID uint, id uint, req *ListDeliveriesRequest) (*DeliveryListResponse, error) {
+	if _, err := s.repo.GetWebhook(userID, id); err != nil {
+		return nil, ErrWebhookNotFound
+	}
+
+	page := req.Page
+	if page < 1 {
+		page = 1
+	}
+	pageSize := req.PageSize
+	if pageSize < 1 || pageSize > 100 {
+		pageSize = 20
+	}
+
+	deliveries, total, err := s.repo.ListDeliveries(id, (page-1)*pageSize, pageSize)
+	if err != nil {
+		return nil, fmt.Errorf("获取投递记录失败: %w", err)
+	}
+
+	return &DeliveryListResponse{
+		Deliveries: deliveries,
+		Total:      total,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalPages: int((total + int64(pageSize) - 1) / int64(pageSize)),
+	}, nil
+}
+
+// OnCalendarEvent 为订阅了该事件的 Webhook 创建投递记录，由 Dispatcher 在后台投递
+// 写入失败只记录日志，不影响日历项的修改
+func (s *service) OnCalendarEvent(event *calendar.Event) {
+	if event.UserID == nil {
+		return
+	}
+	webhooks, err := s.repo.ListEnabledWebhooks(*event.UserID)
+	if err != nil {
+		slog.Error("获取 Webhook 失败", "user_id", *event.UserID, "event", event.Type, "error", err)
+		return
+	}
+
+	var subscribed []*Webhook
+	for _, webhook := range webhooks {
+		if webhook.Subscribes(event.Type) {
+			subscribed = append(subscribed, webhook)
+		}
+	}
+	if len(subscribed) == 0 {
+		return
+	}
+
+	payload := &Payload{
+		ID:        uuid.New().String(),
+		Type:      event.Type,
+		CreatedAt: event.OccurredAt.UTC(),
+		Data:      PayloadData{Item: event.Item},
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		slog.Error("序列化 Webhook 事件失败", "event", event.Type, "error", err)
+		return
+	}
+
+	now := s.now()
+	deliveries := make([]*WebhookDelivery, 0, len(subscribed))
+	for _, webhook := range subscribed {
+		deliveries = append(deliveries, &WebhookDelivery{
+			WebhookID:     webhook.ID,
+			EventID:       payload.ID,
+			EventType:     string(event.Type),
+			Payload:       string(body),
+			Status:        DeliveryStatusPending,
+			NextAttemptAt: &now,
+		})
+	}
+	if err := s.repo.CreateDeliveries(deliveries); err != nil {
+		slog.Error("创建 Webhook 投递记录失败", "user_id", *event.UserID, "event", event.Type, "error", err)
+	}
+}
+
+// Sign 计算投递的签名：对 "时间戳.请求体" 做 HMAC-SHA256，结果为 "sha256=<hex>"
+// 接收方用相同的方法计算并与 X-Otter-Signature 比较，同时检查 X-Otter-Timestamp 防止重放
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// validateURL 检查 Webhook 地址：只允许 http 和 https
+func validateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: 无效的 Webhook 地址 %q", ErrInvalidInput, raw)
+	}
+	if u.User != nil {
+		return fmt.Errorf("%w: Webhook 地址不能包含用户名和密码", ErrInvalidInput)
+	}
+	return nil
+}
+
+// validateEvents 检查订阅的事件类型
+func validateEvents(events []string) error {
+	if len(events) == 0 {
+		return fmt.Errorf("%w: 至少需要订阅一种事件", ErrInvalidInput)
+	}
+	for _, e := range events {
+		if !calendar.EventType(e).IsValid() {
+			return fmt.Errorf("%w: 无效的事件类型 %q", ErrInvalidInput, e)
+		}
+	}
+	return nil
+}
+
+// generateSecret 生成随机的签名密钥
+func generateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return "whsec_" + base64.RawURLEncoding.EncodeToString(b), nil
+}